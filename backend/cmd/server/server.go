@@ -20,9 +20,14 @@ import (
 	"control_page/internal/repository"
 	"control_page/internal/usecase"
 	"control_page/pkg/connection"
+	"control_page/pkg/secretbox"
 )
 
 func Run(cfg *config.Config) error {
+	if err := httpDelivery.SetLogLevel(cfg.Log.Level); err != nil {
+		return fmt.Errorf("set log level %q: %w", cfg.Log.Level, err)
+	}
+
 	// Initialize MongoDB
 	mongoClient, err := connection.NewMongo(cfg.MongoDB.URI, cfg.MongoDB.Database)
 	if err != nil {
@@ -38,36 +43,172 @@ func Run(cfg *config.Config) error {
 	apiKeyRepo := repository.NewAPIKeyMongoRepository(mongoClient.Database)
 	switcherRepo := repository.NewSwitcherMongoRepository(mongoClient.Database)
 	settingRepo := repository.NewSettingMongoRepository(mongoClient.Database)
+	auditRepo := repository.NewAuditMongoRepository(mongoClient.Database)
+	loginEventRepo := repository.NewLoginEventMongoRepository(mongoClient.Database)
+	preferencesRepo := repository.NewUserPreferencesMongoRepository(mongoClient.Database)
+
+	if err := userRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("Warning: failed to create user email index: %v", err)
+	}
+	if err := loginEventRepo.EnsureCollection(context.Background()); err != nil {
+		log.Printf("Warning: failed to prepare login history collection: %v", err)
+	}
+
+	if n, err := apiKeyRepo.BackfillTimestamps(context.Background()); err != nil {
+		log.Printf("Warning: failed to backfill api key timestamps: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled timestamps on %d api keys", n)
+	}
+	if n, err := apiKeyRepo.BackfillScopes(context.Background()); err != nil {
+		log.Printf("Warning: failed to backfill api key scopes: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled read-only scopes on %d api keys", n)
+	}
 
 	// Create default admin user and roles
 	if err := createDefaultAdminMongo(userRepo, roleRepo, userRoleRepo); err != nil {
 		log.Printf("Warning: failed to create default admin: %v", err)
 	}
 
+	passwordPolicy := model.PasswordPolicy{
+		MinLength:     cfg.Auth.PasswordPolicy.MinLength,
+		RequireUpper:  cfg.Auth.PasswordPolicy.RequireUpper,
+		RequireLower:  cfg.Auth.PasswordPolicy.RequireLower,
+		RequireDigit:  cfg.Auth.PasswordPolicy.RequireDigit,
+		RequireSymbol: cfg.Auth.PasswordPolicy.RequireSymbol,
+		RejectCommon:  cfg.Auth.PasswordPolicy.RejectCommon,
+	}
+
+	lockoutPolicy := model.LockoutPolicy{
+		MaxAttempts: cfg.Auth.Lockout.MaxAttempts,
+		Duration:    cfg.Auth.Lockout.Duration,
+	}
+
+	credentialBox, err := secretbox.New(cfg.APIKey.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("init credential encryption: %w", err)
+	}
+
+	var notificationUseCase adaptor.NotificationUseCase
+	if cfg.Notifications.WebhookURL != "" {
+		events := make([]model.NotificationType, 0, len(cfg.Notifications.Events))
+		for _, e := range cfg.Notifications.Events {
+			t := model.NotificationType(e)
+			if !t.IsValid() {
+				return fmt.Errorf("invalid notifications.events entry %q", e)
+			}
+			events = append(events, t)
+		}
+		notifier := usecase.NewNotificationUseCase(usecase.NotificationOptions{
+			WebhookURL: cfg.Notifications.WebhookURL,
+			Secret:     cfg.Notifications.Secret,
+			Events:     events,
+			QueueSize:  cfg.Notifications.QueueSize,
+			MaxRetries: cfg.Notifications.MaxRetries,
+		})
+		defer notifier.Close()
+		notificationUseCase = notifier
+		log.Printf("Sending notifications to the configured webhook")
+	}
+
+	// Exchange endpoints shared by the trading manager, kline and market state lookups
+	exchangeEndpoints := cfg.Trading.ExchangeEndpoints()
+
 	// Initialize use cases
 	authUseCase := usecase.NewAuthUseCase(
 		userRepo,
 		roleRepo,
 		userRoleRepo,
+		loginEventRepo,
 		cfg.JWT.Secret,
 		cfg.JWT.Expiration,
+		passwordPolicy,
+		lockoutPolicy,
+		cfg.Auth.TOTPQRSize,
+		cfg.Auth.RememberDevice,
+		notificationUseCase,
 	)
-	klineUseCase := usecase.NewKlineUseCase()
-	roleUseCase := usecase.NewRoleUseCase(roleRepo)
-	userUseCase := usecase.NewUserUseCase(userRepo, roleRepo, userRoleRepo)
-	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo)
+	klineUseCase, err := usecase.NewKlineUseCase(cfg.Kline.Symbols, cfg.Kline.Intervals, exchangeEndpoints)
+	if err != nil {
+		return fmt.Errorf("init kline usecase: %w", err)
+	}
+	roleUseCase := usecase.NewRoleUseCase(roleRepo, userRoleRepo, auditRepo)
+	userUseCase := usecase.NewUserUseCase(userRepo, roleRepo, userRoleRepo, auditRepo, passwordPolicy, cfg.Auth.TOTPQRSize)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo, credentialBox, cfg.APIKey.RotationGracePeriod)
 	switcherUseCase := usecase.NewSwitcherUseCase(switcherRepo)
 	settingUseCase := usecase.NewSettingUseCase(settingRepo)
+	preferencesUseCase := usecase.NewPreferencesUseCase(preferencesRepo)
+	marketStateUseCase := usecase.NewMarketStateUseCase(apiKeyRepo, exchangeEndpoints)
+
+	var recordingUseCase adaptor.RecordingUseCase
+	if cfg.Recording.Enabled {
+		recordRepo := repository.NewMarketRecordMongoRepository(mongoClient.Database)
+		if err := recordRepo.EnsureCollection(context.Background(), cfg.Recording.Retention); err != nil {
+			return fmt.Errorf("init market record collection: %w", err)
+		}
+		recorder := usecase.NewRecordingUseCase(recordRepo, usecase.RecordingOptions{
+			BufferSize:    cfg.Recording.BufferSize,
+			BatchSize:     cfg.Recording.BatchSize,
+			FlushInterval: cfg.Recording.FlushInterval,
+		})
+		defer recorder.Close()
+		recordingUseCase = recorder
+		log.Printf("Recording klines and trades (retention %s)", cfg.Recording.Retention)
+	}
+
+	var orderEventUseCase adaptor.OrderEventUseCase
+	var tradingReportUseCase adaptor.TradingReportUseCase
+	if cfg.OrderEvents.Enabled {
+		orderEventRepo := repository.NewOrderEventMongoRepository(mongoClient.Database)
+		if err := orderEventRepo.EnsureCollection(context.Background(), cfg.OrderEvents.Retention); err != nil {
+			return fmt.Errorf("init order event collection: %w", err)
+		}
+		orderEvents := usecase.NewOrderEventUseCase(orderEventRepo, apiKeyRepo, usecase.RecordingOptions{
+			BufferSize: cfg.OrderEvents.BufferSize,
+		})
+		defer orderEvents.Close()
+		orderEventUseCase = orderEvents
+		tradingReportUseCase = usecase.NewTradingReportUseCase(orderEventRepo, apiKeyRepo)
+		log.Printf("Persisting order events (retention %s)", cfg.OrderEvents.Retention)
+	}
+
+	alertRepo := repository.NewAlertRuleMongoRepository(mongoClient.Database)
+	alertUseCase := usecase.NewAlertUseCase(alertRepo, apiKeyRepo, cfg.Alerts.WebhookURL)
+	if err := alertUseCase.Load(context.Background()); err != nil {
+		return fmt.Errorf("load alert rules: %w", err)
+	}
+
+	// Kline stream follows the same Binance environment as the trading manager unless overridden
+	binanceURL := cfg.Binance.WebSocketURL
+	if binanceURL == "" {
+		binanceURL = exchangeEndpoints.Config(model.PlatformBinance, cfg.Binance.Testnet).BaseWSURL
+	}
+	if cfg.Server.BasePath != "" {
+		log.Printf("Serving all routes under %s", cfg.Server.BasePath)
+	}
+	log.Printf("Binance kline stream: %s (testnet=%v)", binanceURL, cfg.Binance.Testnet)
+
+	exchangeLimits := httpDelivery.ExchangeConnLimits{
+		Global:  cfg.Trading.MaxExchangeConnections,
+		PerUser: cfg.Trading.MaxExchangeConnectionsPerUser,
+	}
+
+	requestTimeouts := httpDelivery.RequestTimeouts{
+		Default: cfg.Server.RequestTimeout,
+		Routes:  cfg.Server.RouteTimeouts,
+	}
 
 	// Initialize router
-	router := httpDelivery.NewRouter(authUseCase, klineUseCase, roleUseCase, userUseCase, apiKeyUseCase, apiKeyRepo, switcherUseCase, settingUseCase, cfg.Binance.WebSocketURL)
+	router := httpDelivery.NewRouter(authUseCase, klineUseCase, roleUseCase, userUseCase, apiKeyUseCase, apiKeyRepo, auditRepo, switcherUseCase, settingUseCase, preferencesUseCase, binanceURL, cfg.Trading.EnforceTokenExpiry, cfg.Trading.ExchangeIdleTimeout, exchangeLimits, exchangeEndpoints, !cfg.Trading.DisableRawBalanceEvents, recordingUseCase, orderEventUseCase, tradingReportUseCase, marketStateUseCase, alertUseCase, notificationUseCase, cfg.Trading.ClientSendQueue, cfg.Server.MaxWebSocketClients, requestTimeouts, cfg.Server.BasePath, cfg.Server.TrustedProxyHeader)
 
 	// Create HTTP server
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	server := &http.Server{
-		Addr:         addr,
-		Handler:      router.Setup(),
-		ReadTimeout:  15 * time.Second,
+		Addr:        addr,
+		Handler:     router.Setup(),
+		ReadTimeout: 15 * time.Second,
+		// WebSocket routes and /api groups with a longer server.route_timeouts
+		// move or clear this deadline per connection
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
@@ -149,6 +290,25 @@ func createDefaultAdminMongo(
 			return fmt.Errorf("set admin permissions: %w", err)
 		}
 		log.Printf("Added %d permissions to admin role", len(allPermissions))
+	} else {
+		// Grant permissions introduced since the admin role was created
+		granted, err := roleRepo.GetPermissions(ctx, adminRole.ID)
+		if err != nil {
+			return fmt.Errorf("get admin permissions: %w", err)
+		}
+		has := make(map[enum.Permission]bool, len(granted))
+		for _, p := range granted {
+			has[p] = true
+		}
+		for _, p := range enum.AllPermissions() {
+			if has[p] {
+				continue
+			}
+			if err := roleRepo.AddPermission(ctx, adminRole.ID, p); err != nil {
+				return fmt.Errorf("add admin permission %s: %w", p, err)
+			}
+			log.Printf("Added permission %s to admin role", p)
+		}
 	}
 
 	// Check if admin user already exists
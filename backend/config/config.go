@@ -1,18 +1,131 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"control_page/internal/model"
+
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	MongoDB  MongoDBConfig  `yaml:"mongodb"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Binance  BinanceConfig  `yaml:"binance"`
+	Server      ServerConfig     `yaml:"server"`
+	Database    DatabaseConfig   `yaml:"database"`
+	MongoDB     MongoDBConfig    `yaml:"mongodb"`
+	JWT         JWTConfig        `yaml:"jwt"`
+	Binance     BinanceConfig    `yaml:"binance"`
+	Auth        AuthConfig       `yaml:"auth"`
+	APIKey      APIKeyConfig     `yaml:"api_key"`
+	Trading     TradingConfig    `yaml:"trading"`
+	Log         LogConfig        `yaml:"log"`
+	Kline       KlineConfig      `yaml:"kline"`
+	Recording   RecordingConfig  `yaml:"recording"`
+	OrderEvents OrderEventConfig `yaml:"order_events"`
+	Alerts      AlertConfig      `yaml:"alerts"`
+
+	Notifications NotificationConfig `yaml:"notifications"`
+}
+
+// NotificationConfig posts critical backend events (private stream auth lost,
+// account locked, API key verification failed) to a Slack/Discord-compatible
+// webhook; notifications are off while webhook_url is empty
+type NotificationConfig struct {
+	WebhookURL string   `yaml:"webhook_url"`
+	Secret     string   `yaml:"secret"`      // signs the body as X-Signature-256: sha256=<hex HMAC>
+	Events     []string `yaml:"events"`      // event types to send, empty sends all
+	QueueSize  int      `yaml:"queue_size"`  // pending notifications before new ones are dropped
+	MaxRetries int      `yaml:"max_retries"` // retries of a failed delivery with exponential backoff, default 3, -1 disables
+}
+
+// AlertConfig configures delivery of alert rule state changes
+type AlertConfig struct {
+	WebhookURL string `yaml:"webhook_url"` // alerts are also POSTed here as JSON when set
+}
+
+// OrderEventConfig persists order updates from the trading stream to the order_event collection
+type OrderEventConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Retention  time.Duration `yaml:"retention"`   // TTL of stored events, default 30 days
+	BufferSize int           `yaml:"buffer_size"` // queued events before the oldest are dropped
+}
+
+// RecordingConfig persists received klines and trades to a Mongo time-series collection
+type RecordingConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Retention     time.Duration `yaml:"retention"`      // TTL of recorded events, default 7 days
+	BufferSize    int           `yaml:"buffer_size"`    // queued events before the oldest are dropped
+	BatchSize     int           `yaml:"batch_size"`     // events per insert
+	FlushInterval time.Duration `yaml:"flush_interval"` // maximum delay before a partial batch is written
+}
+
+// KlineConfig overrides the dashboard's default symbols and intervals (built-in lists when empty)
+type KlineConfig struct {
+	Symbols   []string `yaml:"symbols"`
+	Intervals []string `yaml:"intervals"`
+}
+
+// LogConfig sets the initial log level, which can be changed at runtime via the API
+type LogConfig struct {
+	Level string `yaml:"level"` // debug, info, warn or error
+}
+
+type TradingConfig struct {
+	// EnforceTokenExpiry closes /ws/trading connections when their token expires
+	// unless the client sends a reauth message first
+	EnforceTokenExpiry bool `yaml:"enforce_token_expiry"`
+	// ExchangeIdleTimeout closes exchange connections that have had no
+	// subscriptions for this long, even with clients attached (0 disables)
+	ExchangeIdleTimeout time.Duration `yaml:"exchange_idle_timeout"`
+	// MaxExchangeConnections caps simultaneous exchange connections (0 disables)
+	MaxExchangeConnections int `yaml:"max_exchange_connections"`
+	// MaxExchangeConnectionsPerUser caps the distinct API keys one user can
+	// be connected to at once (0 disables)
+	MaxExchangeConnectionsPerUser int `yaml:"max_exchange_connections_per_user"`
+	// DisableRawBalanceEvents stops forwarding the platform specific "account"
+	// (Binance) and "asset" (BTCC) frames; clients use "balance" instead
+	DisableRawBalanceEvents bool `yaml:"disable_raw_balance_events"`
+	// ClientSendQueue is the number of frames buffered per client; once full,
+	// the oldest orderbook and kline frames are dropped (default 256)
+	ClientSendQueue int `yaml:"client_send_queue"`
+	// ExchangeURLs sets exchange endpoints per platform (binance, btcc) and
+	// network, e.g. to route through a proxy or at a local fake exchange
+	ExchangeURLs map[string]ExchangeNetworkURLs `yaml:"exchange_urls"`
+}
+
+// ExchangeNetworkURLs holds the endpoints of a platform's production and testnet networks
+type ExchangeNetworkURLs struct {
+	Mainnet ExchangeURLConfig `yaml:"mainnet"`
+	Testnet ExchangeURLConfig `yaml:"testnet"`
+}
+
+// ExchangeURLConfig sets an exchange's base URLs; empty fields keep the
+// default from model.DefaultExchangeURLs
+type ExchangeURLConfig struct {
+	WSURL   string `yaml:"ws_url"`
+	RESTURL string `yaml:"rest_url"`
+}
+
+// ExchangeEndpoints resolves the configured exchange URLs over the defaults
+func (c TradingConfig) ExchangeEndpoints() *model.ExchangeEndpoints {
+	overrides := make(map[model.ExchangeNetwork]model.ExchangeURLs, 2*len(c.ExchangeURLs))
+	for platform, networks := range c.ExchangeURLs {
+		mainnet := model.ExchangeNetwork{Platform: model.Platform(platform)}
+		testnet := model.ExchangeNetwork{Platform: model.Platform(platform), IsTestnet: true}
+		overrides[mainnet] = model.ExchangeURLs{WSURL: networks.Mainnet.WSURL, RESTURL: networks.Mainnet.RESTURL}
+		overrides[testnet] = model.ExchangeURLs{WSURL: networks.Testnet.WSURL, RESTURL: networks.Testnet.RESTURL}
+	}
+	return model.NewExchangeEndpoints(overrides)
+}
+
+type APIKeyConfig struct {
+	EncryptionKey       string        `yaml:"encryption_key"`        // required; encrypts stored API secrets
+	RotationGracePeriod time.Duration `yaml:"rotation_grace_period"` // how long rotated credentials can be rolled back
 }
 
 type MongoDBConfig struct {
@@ -23,6 +136,18 @@ type MongoDBConfig struct {
 type ServerConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+	// BasePath mounts every route (/api, /ws, /health) under a prefix such as
+	// /admin for hosting behind a reverse proxy; empty serves them at the root
+	BasePath string `yaml:"base_path"`
+	// MaxWebSocketClients caps concurrent kline and trading WebSocket clients combined (0 disables)
+	MaxWebSocketClients int `yaml:"max_websocket_clients"`
+	// RequestTimeout bounds /api handlers (0 disables); RouteTimeouts overrides it
+	// per path prefix such as /api/btcc, where 0 exempts that route group
+	RequestTimeout time.Duration            `yaml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts"`
+	// TrustedProxyHeader, such as X-Forwarded-For, carries the client address
+	// set by a reverse proxy; leave empty unless every request passes one
+	TrustedProxyHeader string `yaml:"trusted_proxy_header"`
 }
 
 type DatabaseConfig struct {
@@ -33,10 +158,40 @@ type DatabaseConfig struct {
 type JWTConfig struct {
 	Secret     string        `yaml:"secret"`
 	Expiration time.Duration `yaml:"expiration"`
+	// RoleExpiration is the removed per role name override, superseded by the
+	// roles' max_token_ttl; it is only read so a config still setting it fails
+	// to load instead of being ignored
+	RoleExpiration map[string]time.Duration `yaml:"role_expiration"`
+}
+
+type AuthConfig struct {
+	PasswordPolicy PasswordPolicyConfig `yaml:"password_policy"`
+	Lockout        LockoutConfig        `yaml:"lockout"`
+	TOTPQRSize     int                  `yaml:"totp_qr_size"` // QR code width and height in pixels, 128-1024
+	// RememberDevice is how long a device that passed TOTP may skip it on
+	// later password logins, when the user asks for it; 0 always requires TOTP
+	RememberDevice time.Duration `yaml:"remember_device"`
+}
+
+// LockoutConfig locks an account after MaxAttempts consecutive failures (0 disables)
+type LockoutConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	Duration    time.Duration `yaml:"duration"`
+}
+
+// PasswordPolicyConfig defaults to a 6-character minimum with no other rules
+type PasswordPolicyConfig struct {
+	MinLength     int  `yaml:"min_length"`
+	RequireUpper  bool `yaml:"require_upper"`
+	RequireLower  bool `yaml:"require_lower"`
+	RequireDigit  bool `yaml:"require_digit"`
+	RequireSymbol bool `yaml:"require_symbol"`
+	RejectCommon  bool `yaml:"reject_common"`
 }
 
 type BinanceConfig struct {
-	WebSocketURL string `yaml:"websocket_url"`
+	WebSocketURL string `yaml:"websocket_url"` // optional, derived from testnet when empty
+	Testnet      bool   `yaml:"testnet"`
 }
 
 func Load(path string) (*Config, error) {
@@ -50,5 +205,124 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.Auth.PasswordPolicy.MinLength <= 0 {
+		cfg.Auth.PasswordPolicy.MinLength = 6
+	}
+	if cfg.APIKey.RotationGracePeriod <= 0 {
+		cfg.APIKey.RotationGracePeriod = 24 * time.Hour
+	}
+	if cfg.Server.BasePath != "" {
+		cfg.Server.BasePath = "/" + strings.Trim(cfg.Server.BasePath, "/")
+		if cfg.Server.BasePath == "/" {
+			cfg.Server.BasePath = ""
+		}
+	}
+	if cfg.Log.Level == "" {
+		cfg.Log.Level = "info"
+	}
+	if cfg.Auth.Lockout.Duration <= 0 {
+		cfg.Auth.Lockout.Duration = 15 * time.Minute
+	}
+	if cfg.Auth.TOTPQRSize == 0 {
+		cfg.Auth.TOTPQRSize = 256
+	}
+	if cfg.Recording.Retention <= 0 {
+		cfg.Recording.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.OrderEvents.Retention <= 0 {
+		cfg.OrderEvents.Retention = 30 * 24 * time.Hour
+	}
+	if cfg.Trading.ClientSendQueue <= 0 {
+		cfg.Trading.ClientSendQueue = 256
+	}
+	if cfg.Notifications.MaxRetries == 0 {
+		cfg.Notifications.MaxRetries = 3
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+func (c *Config) validate() error {
+	if c.APIKey.EncryptionKey == "" {
+		return errors.New("api_key.encryption_key is required")
+	}
+
+	if len(c.JWT.RoleExpiration) > 0 {
+		return errors.New("jwt.role_expiration is no longer supported, set max_token_ttl on the roles instead")
+	}
+
+	if c.Binance.WebSocketURL != "" {
+		u, err := url.Parse(c.Binance.WebSocketURL)
+		if err != nil {
+			return fmt.Errorf("invalid binance.websocket_url: %w", err)
+		}
+		if u.Scheme != "ws" && u.Scheme != "wss" {
+			return fmt.Errorf("invalid binance.websocket_url %q: scheme must be ws or wss", c.Binance.WebSocketURL)
+		}
+	}
+
+	if c.Auth.TOTPQRSize < 128 || c.Auth.TOTPQRSize > 1024 {
+		return fmt.Errorf("invalid auth.totp_qr_size %d: must be between 128 and 1024", c.Auth.TOTPQRSize)
+	}
+	if c.Auth.RememberDevice < 0 {
+		return fmt.Errorf("invalid auth.remember_device %s: must not be negative", c.Auth.RememberDevice)
+	}
+
+	if err := validateWebhookURL("alerts.webhook_url", c.Alerts.WebhookURL); err != nil {
+		return err
+	}
+	if err := validateWebhookURL("notifications.webhook_url", c.Notifications.WebhookURL); err != nil {
+		return err
+	}
+
+	for platform, networks := range c.Trading.ExchangeURLs {
+		if !model.Platform(platform).IsValid() {
+			return fmt.Errorf("invalid trading.exchange_urls platform %q", platform)
+		}
+		for network, urls := range map[string]ExchangeURLConfig{"mainnet": networks.Mainnet, "testnet": networks.Testnet} {
+			field := "trading.exchange_urls." + platform + "." + network
+			if err := validateURL(field+".ws_url", urls.WSURL, "ws", "wss"); err != nil {
+				return err
+			}
+			if err := validateURL(field+".rest_url", urls.RESTURL, "http", "https"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for prefix := range c.Server.RouteTimeouts {
+		if prefix != "/api" && !strings.HasPrefix(prefix, "/api/") {
+			return fmt.Errorf("invalid server.route_timeouts prefix %q: must be under /api", prefix)
+		}
+	}
+
+	return nil
+}
+
+// validateURL accepts an empty value (keep the default) or an absolute URL with one of schemes
+func validateURL(field, raw string, schemes ...string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || !slices.Contains(schemes, u.Scheme) || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: scheme must be %s", field, raw, strings.Join(schemes, " or "))
+	}
+	return nil
+}
+
+// validateWebhookURL accepts an empty value (webhook disabled) or an absolute http(s) URL
+func validateWebhookURL(field, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid %s %q: must be an http(s) URL", field, raw)
+	}
+	return nil
+}
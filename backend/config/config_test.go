@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+// requiredYAML holds the settings Load refuses to start without
+const requiredYAML = "api_key:\n  encryption_key: test-encryption-key\n"
+
+// loadYAML writes the required settings and content to a config file and loads it
+func loadYAML(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	return loadRawYAML(t, requiredYAML+content)
+}
+
+// loadRawYAML writes content alone to a config file and loads it
+func loadRawYAML(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return Load(path)
+}
+
+func TestAPIKeyEncryptionKeyRequired(t *testing.T) {
+	// jwt.secret is not used in its place
+	_, err := loadRawYAML(t, "jwt:\n  secret: jwt-secret\napi_key:\n  encryption_key: ''\n")
+	if err == nil || !strings.Contains(err.Error(), "api_key.encryption_key") {
+		t.Fatalf("Load() error = %v, want one naming api_key.encryption_key", err)
+	}
+
+	cfg, err := loadYAML(t, "jwt:\n  secret: jwt-secret\n")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.APIKey.EncryptionKey != "test-encryption-key" {
+		t.Fatalf("encryption key = %q, want the configured one", cfg.APIKey.EncryptionKey)
+	}
+}
+
+func TestRoleExpirationRejected(t *testing.T) {
+	_, err := loadYAML(t, "jwt:\n  role_expiration:\n    admin: 1h\n")
+	if err == nil || !strings.Contains(err.Error(), "max_token_ttl") {
+		t.Fatalf("Load() error = %v, want one pointing at max_token_ttl", err)
+	}
+
+	if _, err := loadYAML(t, "jwt:\n  role_expiration: {}\n"); err != nil {
+		t.Fatalf("Load() with an empty role_expiration error = %v", err)
+	}
+}
+
+func TestTradingExchangeEndpoints(t *testing.T) {
+	cfg, err := loadYAML(t, `
+trading:
+  exchange_urls:
+    binance:
+      testnet:
+        ws_url: ws://127.0.0.1:9000/ws
+        rest_url: http://127.0.0.1:9000/api
+    btcc:
+      mainnet:
+        ws_url: wss://btcc.example.com/ws
+`)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	endpoints := cfg.Trading.ExchangeEndpoints()
+
+	tests := []struct {
+		name      string
+		platform  model.Platform
+		isTestnet bool
+		ws, rest  string
+	}{
+		{
+			name:     "binance mainnet keeps the defaults",
+			platform: model.PlatformBinance,
+			ws:       model.DefaultExchangeURLs[model.ExchangeNetwork{Platform: model.PlatformBinance}].WSURL,
+			rest:     model.DefaultExchangeURLs[model.ExchangeNetwork{Platform: model.PlatformBinance}].RESTURL,
+		},
+		{
+			name:      "binance testnet overridden",
+			platform:  model.PlatformBinance,
+			isTestnet: true,
+			ws:        "ws://127.0.0.1:9000/ws",
+			rest:      "http://127.0.0.1:9000/api",
+		},
+		{
+			name:     "btcc mainnet overrides only the websocket",
+			platform: model.PlatformBTCC,
+			ws:       "wss://btcc.example.com/ws",
+			rest:     model.DefaultExchangeURLs[model.ExchangeNetwork{Platform: model.PlatformBTCC}].RESTURL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := endpoints.Config(tt.platform, tt.isTestnet)
+			if got.Platform != tt.platform || got.IsTestnet != tt.isTestnet || got.BaseWSURL != tt.ws || got.BaseRESTURL != tt.rest {
+				t.Fatalf("Config(%s, %v) = %+v, want ws %s and rest %s", tt.platform, tt.isTestnet, got, tt.ws, tt.rest)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidExchangeURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		urls string
+		want string
+	}{
+		{
+			name: "unknown platform",
+			urls: "kraken:\n      mainnet:\n        ws_url: wss://kraken.example.com/ws",
+			want: `platform "kraken"`,
+		},
+		{
+			name: "websocket url with an http scheme",
+			urls: "binance:\n      testnet:\n        ws_url: http://127.0.0.1:9000/ws",
+			want: "trading.exchange_urls.binance.testnet.ws_url",
+		},
+		{
+			name: "rest url with a websocket scheme",
+			urls: "btcc:\n      mainnet:\n        rest_url: wss://btcc.example.com",
+			want: "trading.exchange_urls.btcc.mainnet.rest_url",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadYAML(t, "trading:\n  exchange_urls:\n    "+tt.urls+"\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Load() error = %v, want one naming %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	cfg, err := loadYAML(t, "auth:\n  lockout:\n    max_attempts: 5\n")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := (PasswordPolicyConfig{MinLength: 6}); cfg.Auth.PasswordPolicy != want {
+		t.Fatalf("default password policy = %+v, want %+v", cfg.Auth.PasswordPolicy, want)
+	}
+
+	cfg, err = loadYAML(t, `
+auth:
+  password_policy:
+    min_length: 12
+    require_upper: true
+    require_lower: true
+    require_digit: true
+    require_symbol: true
+    reject_common: true
+`)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := PasswordPolicyConfig{MinLength: 12, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true, RejectCommon: true}
+	if cfg.Auth.PasswordPolicy != want {
+		t.Fatalf("password policy = %+v, want %+v", cfg.Auth.PasswordPolicy, want)
+	}
+}
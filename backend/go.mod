@@ -12,12 +12,14 @@ require (
 	github.com/pquerna/otp v1.4.0
 	github.com/yanun0323/logs v1.4.12
 	go.mongodb.org/mongo-driver v1.17.6
+	go.uber.org/goleak v1.3.0
 	golang.org/x/crypto v0.29.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/klauspost/compress v1.16.7 // indirect
 	github.com/montanaflynn/stats v0.7.1 // indirect
@@ -2,20 +2,39 @@ package adaptor
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"control_page/internal/model"
 	"control_page/internal/model/enum"
 )
 
+// ErrInvalidID is returned by repositories for an ID that is not a well-formed
+// MongoDB ObjectID, as opposed to a well-formed ID with no matching document.
+//
+// Every GetByID follows the same contract:
+//   - (nil, ErrInvalidID) for a malformed ID, wrapped with the offending value
+//   - (nil, nil) for a well-formed ID that matches no document
+//   - (nil, err) for any other database failure
+//
+// Use cases map the first to a 400 and the second to their NotFound error.
+var ErrInvalidID = errors.New("invalid id format")
+
+// ErrDuplicateEmail is returned by UserRepository writes that would give two
+// users the same non-empty email, which a unique index enforces
+var ErrDuplicateEmail = errors.New("email is already used by another user")
+
 // UserRepository defines the interface for user data access
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	GetByID(ctx context.Context, id string) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context) ([]model.User, error)
 	UpdatePassword(ctx context.Context, id string, hashedPassword string) error
+	SetTemporaryPassword(ctx context.Context, id string, hashedPassword string) error
 	UpdateUsername(ctx context.Context, id string, username string) error
 	UpdateRegistration(ctx context.Context, id string, hashedPassword, totpSecret string) error
 	SetTOTPSecret(ctx context.Context, id string, secret string) error
@@ -24,6 +43,15 @@ type UserRepository interface {
 	SetPendingTOTPSecret(ctx context.Context, id string, secret string) error
 	ConfirmTOTPRebind(ctx context.Context, id string) error
 	ClearPendingTOTPSecret(ctx context.Context, id string) error
+	IncrementFailedAttempts(ctx context.Context, id string) (int, error)
+	LockUntil(ctx context.Context, id string, until time.Time) error
+	ResetLockout(ctx context.Context, id string) error
+	RecordLogin(ctx context.Context, id string, at time.Time) error
+	SetIPAllowlist(ctx context.Context, id string, cidrs []string) error
+	AddTrustedDevice(ctx context.Context, id string, device model.TrustedDevice) error
+	TouchTrustedDevice(ctx context.Context, id string, deviceID string, at time.Time) error
+	RemoveTrustedDevice(ctx context.Context, id string, deviceID string) (bool, error)
+	ClearTrustedDevices(ctx context.Context, id string) error
 }
 
 // RoleRepository defines the interface for role data access
@@ -38,6 +66,7 @@ type RoleRepository interface {
 	AddPermission(ctx context.Context, roleID string, permission enum.Permission) error
 	RemovePermission(ctx context.Context, roleID string, permission enum.Permission) error
 	GetPermissions(ctx context.Context, roleID string) ([]enum.Permission, error)
+	GetPermissionsForRoles(ctx context.Context, roleIDs []string) (map[string][]enum.Permission, error)
 	SetPermissions(ctx context.Context, roleID string, permissions []enum.Permission) error
 }
 
@@ -46,6 +75,8 @@ type UserRoleRepository interface {
 	AssignRole(ctx context.Context, userID, roleID string) error
 	RemoveRole(ctx context.Context, userID, roleID string) error
 	GetUserPermissions(ctx context.Context, userID string) ([]enum.Permission, error)
+	GetRoleIDsForUsers(ctx context.Context, userIDs []string) (map[string][]string, error)
+	GetUsersByRoleID(ctx context.Context, roleID string) ([]model.User, error)
 }
 
 // APIKeyRepository defines the interface for API key data access
@@ -53,7 +84,9 @@ type APIKeyRepository interface {
 	Create(ctx context.Context, apiKey *model.APIKey) error
 	GetByID(ctx context.Context, id string) (*model.APIKey, error)
 	List(ctx context.Context) ([]model.APIKey, error)
+	Search(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, int64, error)
 	Update(ctx context.Context, apiKey *model.APIKey) error
+	Rotate(ctx context.Context, apiKey *model.APIKey, expectedAPIKey string) (bool, error)
 	Delete(ctx context.Context, id string) error
 	GetByPlatform(ctx context.Context, platform model.Platform) ([]model.APIKey, error)
 	GetActiveByPlatform(ctx context.Context, platform model.Platform, isTestnet bool) ([]model.APIKey, error)
@@ -65,7 +98,7 @@ type SwitcherRepository interface {
 	GetByID(ctx context.Context, id string) (*model.Switcher, error)
 	Create(ctx context.Context, switcher *model.Switcher) error
 	Update(ctx context.Context, switcher *model.Switcher) error
-	UpdatePair(ctx context.Context, id string, pair string, enable bool) error
+	UpdatePair(ctx context.Context, id string, pair string, enable bool, updatedBy string, updatedAt time.Time) error
 	Delete(ctx context.Context, id string) error
 }
 
@@ -76,6 +109,51 @@ type SettingRepository interface {
 	GetByBaseQuote(ctx context.Context, base, quote string) (*model.Setting, error)
 	Create(ctx context.Context, setting *model.Setting) error
 	Update(ctx context.Context, setting *model.Setting) error
-	UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}) error
+	UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}, updatedBy string, updatedAt time.Time) error
 	Delete(ctx context.Context, id string) error
 }
+
+// MarketRecordRepository defines the interface for recorded market data access
+type MarketRecordRepository interface {
+	EnsureCollection(ctx context.Context, retention time.Duration) error
+	InsertMany(ctx context.Context, records []model.MarketRecord) error
+	Query(ctx context.Context, query model.MarketRecordQuery) ([]model.MarketRecord, error)
+}
+
+// OrderEventRepository defines the interface for persisted order updates
+type OrderEventRepository interface {
+	EnsureCollection(ctx context.Context, retention time.Duration) error
+	InsertMany(ctx context.Context, events []model.OrderEvent) error
+	Query(ctx context.Context, query model.OrderEventQuery) (*model.OrderEventPage, error)
+	// Timeline returns the API key's events received before until, oldest first
+	Timeline(ctx context.Context, apiKeyID string, until time.Time) ([]model.OrderEvent, error)
+}
+
+// AlertRuleRepository defines the interface for alert rule data access
+type AlertRuleRepository interface {
+	GetAll(ctx context.Context) ([]model.AlertRule, error)
+	GetByID(ctx context.Context, id string) (*model.AlertRule, error)
+	Create(ctx context.Context, rule *model.AlertRule) error
+	Update(ctx context.Context, rule *model.AlertRule) error
+	Delete(ctx context.Context, id string) error
+}
+
+// LoginEventRepository defines the interface for login history data access
+type LoginEventRepository interface {
+	EnsureCollection(ctx context.Context) error
+	Create(ctx context.Context, event *model.LoginEvent) error
+	ListByUser(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error)
+}
+
+// UserPreferencesRepository defines the interface for per-user UI preferences
+type UserPreferencesRepository interface {
+	Get(ctx context.Context, userID string) (*model.UserPreferences, error)
+	// Save is a compare-and-swap on prefs.Version; false means the stored
+	// version moved on and nothing was written
+	Save(ctx context.Context, prefs *model.UserPreferences) (bool, error)
+}
+
+// AuditRepository defines the interface for audit log data access
+type AuditRepository interface {
+	Create(ctx context.Context, entry *model.AuditEntry) error
+}
@@ -2,6 +2,7 @@ package adaptor
 
 import (
 	"context"
+	"time"
 
 	"control_page/internal/model"
 	"control_page/internal/model/enum"
@@ -11,14 +12,24 @@ import (
 type AuthUseCase interface {
 	Register(ctx context.Context, username, password string) (*model.RegisterResult, error)
 	ActivateAccount(ctx context.Context, userID string, code string) error
-	Login(ctx context.Context, username, password string) (*model.LoginResult, error)
-	VerifyTOTP(ctx context.Context, userID string, code string) (string, *model.UserWithRoles, error)
+	Login(ctx context.Context, username, password string, client model.ClientInfo) (*model.LoginResult, error)
+	VerifyTOTP(ctx context.Context, userID string, code string, rememberDevice bool, client model.ClientInfo) (*model.LoginResult, error)
 	ValidateToken(ctx context.Context, token string) (*model.UserWithRoles, error)
 	HasPermission(ctx context.Context, userID string, permission enum.Permission) (bool, error)
 	ChangePassword(ctx context.Context, userID string, currentPassword, newPassword string) error
 	SetupTOTPRebind(ctx context.Context, userID string, password string) (*model.TOTPSetup, error)
 	ConfirmTOTPRebind(ctx context.Context, userID string, code string) error
 	CancelTOTPRebind(ctx context.Context, userID string) error
+	GetProfile(ctx context.Context, userID string) (*model.Profile, error)
+	ListLogins(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error)
+	ListTrustedDevices(ctx context.Context, userID string) ([]model.TrustedDevice, error)
+	RevokeTrustedDevice(ctx context.Context, userID, deviceID string) error
+}
+
+// PreferencesUseCase defines the interface for the signed-in user's UI preferences
+type PreferencesUseCase interface {
+	GetPreferences(ctx context.Context, userID string) (*model.UserPreferences, error)
+	SavePreferences(ctx context.Context, userID string, data []byte, version int64) (*model.UserPreferences, error)
 }
 
 // UserUseCase defines the interface for user management operations
@@ -30,16 +41,23 @@ type UserUseCase interface {
 	DeleteUser(ctx context.Context, id string) error
 	AssignRole(ctx context.Context, userID, roleID string) error
 	RemoveRole(ctx context.Context, userID, roleID string) error
+	ValidateRoles(ctx context.Context, roleIDs []string) error
+	SetRoles(ctx context.Context, userID string, roleIDs []string) error
 	ResetUserTOTP(ctx context.Context, userID string) (*model.TOTPSetup, error)
+	ValidatePassword(password string) error
+	UnlockUser(ctx context.Context, actorID, userID string) (*model.UserWithRoles, error)
+	ResetPassword(ctx context.Context, actorID, userID string) (string, error)
+	SetIPAllowlist(ctx context.Context, actorID, userID string, cidrs []string) (*model.UserWithRoles, error)
 }
 
 // RoleUseCase defines the interface for role management operations
 type RoleUseCase interface {
-	CreateRole(ctx context.Context, name, description string, permissions []enum.Permission) (*model.RoleWithPermissions, error)
+	CreateRole(ctx context.Context, name, description string, permissions []enum.Permission, maxTokenTTL int64) (*model.RoleWithPermissions, error)
 	GetRole(ctx context.Context, id string) (*model.RoleWithPermissions, error)
 	ListRoles(ctx context.Context) ([]model.RoleWithPermissions, error)
-	UpdateRole(ctx context.Context, id string, name, description string) (*model.RoleWithPermissions, error)
-	DeleteRole(ctx context.Context, id string) error
+	UpdateRole(ctx context.Context, id string, name, description string, maxTokenTTL *int64) (*model.RoleWithPermissions, error)
+	GetRoleUsers(ctx context.Context, id string) ([]model.User, error)
+	DeleteRole(ctx context.Context, actorID, id string, force bool) ([]model.User, error)
 	SetPermissions(ctx context.Context, roleID string, permissions []enum.Permission) error
 	GetPermissions(ctx context.Context, roleID string) ([]enum.Permission, error)
 	GetAllPermissions() []enum.Permission
@@ -49,25 +67,32 @@ type RoleUseCase interface {
 type KlineUseCase interface {
 	GetAvailableSymbols(ctx context.Context) ([]string, error)
 	GetAvailableIntervals(ctx context.Context) ([]string, error)
+	SearchSymbols(ctx context.Context, platform model.Platform, query string) ([]model.SymbolInfo, error)
 }
 
-// APIKeyUseCase defines the interface for API key management operations
+// APIKeyUseCase defines the interface for API key management operations. Keys
+// are identified by their ObjectID and have no owner: they are shared by every
+// user, and access is governed by the view:api_keys and manage:api_keys
+// permissions rather than by a user ID.
 type APIKeyUseCase interface {
 	Create(ctx context.Context, req *model.CreateAPIKeyRequest) (*model.APIKeyResponse, error)
 	GetByID(ctx context.Context, id string) (*model.APIKeyResponse, error)
-	List(ctx context.Context) ([]model.APIKeyResponse, error)
+	List(ctx context.Context, filter model.APIKeyFilter) (*model.APIKeyListResult, error)
 	Update(ctx context.Context, id string, req *model.UpdateAPIKeyRequest) (*model.APIKeyResponse, error)
 	Delete(ctx context.Context, id string) error
+	Rotate(ctx context.Context, id string, req *model.RotateAPIKeyRequest) (*model.APIKeyResponse, error)
+	Rollback(ctx context.Context, id string) (*model.APIKeyResponse, error)
 	GetPlatforms() []model.Platform
+	GetPlatformCapabilities() []model.PlatformCapabilities
 }
 
 // SwitcherUseCase defines the interface for switcher management operations
 type SwitcherUseCase interface {
 	List(ctx context.Context) ([]model.SwitcherResponse, error)
 	GetByID(ctx context.Context, id string) (*model.SwitcherResponse, error)
-	Create(ctx context.Context, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error)
-	Update(ctx context.Context, id string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error)
-	UpdatePair(ctx context.Context, id string, pair string, enable bool) (*model.SwitcherResponse, error)
+	Create(ctx context.Context, actorID string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error)
+	Update(ctx context.Context, actorID, id string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error)
+	UpdatePair(ctx context.Context, actorID, id string, pair string, enable bool) (*model.SwitcherResponse, error)
 	Delete(ctx context.Context, id string) error
 }
 
@@ -76,8 +101,56 @@ type SettingUseCase interface {
 	List(ctx context.Context) ([]model.SettingResponse, error)
 	GetByID(ctx context.Context, id string) (*model.SettingResponse, error)
 	GetByBaseQuote(ctx context.Context, base, quote string) (*model.SettingResponse, error)
-	Create(ctx context.Context, req *model.CreateSettingRequest) (*model.SettingResponse, error)
-	Update(ctx context.Context, id string, req *model.UpdateSettingRequest) (*model.SettingResponse, error)
-	UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}) (*model.SettingResponse, error)
+	Create(ctx context.Context, actorID string, req *model.CreateSettingRequest) (*model.SettingResponse, error)
+	Update(ctx context.Context, actorID, id string, req *model.UpdateSettingRequest) (*model.SettingResponse, error)
+	UpdateParameters(ctx context.Context, actorID, id string, strategy string, parameters map[string]interface{}) (*model.SettingResponse, error)
 	Delete(ctx context.Context, id string) error
 }
+
+// RecordingUseCase defines the interface for persisting and querying market data
+type RecordingUseCase interface {
+	// Record queues an event for writing and never blocks
+	Record(record model.MarketRecord)
+	Query(ctx context.Context, query model.MarketRecordQuery) ([]model.MarketRecord, error)
+	Stats() model.RecordingStats
+	Close()
+}
+
+// OrderEventUseCase defines the interface for persisting and querying order updates
+type OrderEventUseCase interface {
+	// Record queues an order update for writing and never blocks
+	Record(event model.OrderEvent)
+	Query(ctx context.Context, query model.OrderEventQuery) (*model.OrderEventPage, error)
+	Close()
+}
+
+// AlertUseCase defines the interface for alert rules and their evaluation
+type AlertUseCase interface {
+	List(ctx context.Context) ([]model.AlertRule, error)
+	GetByID(ctx context.Context, id string) (*model.AlertRule, error)
+	Create(ctx context.Context, actorID string, req *model.AlertRuleRequest) (*model.AlertRule, error)
+	Update(ctx context.Context, actorID, id string, req *model.AlertRuleRequest) (*model.AlertRule, error)
+	Delete(ctx context.Context, id string) error
+	// Evaluate feeds one observed spread or price to the matching rules and
+	// returns the alerts of rules that changed state; it never blocks on I/O
+	Evaluate(apiKeyID, symbol string, condition model.AlertCondition, value float64, at time.Time) []model.Alert
+}
+
+// NotificationUseCase defines the interface for pushing critical backend
+// events to external channels
+type NotificationUseCase interface {
+	// Notify queues the notification and returns immediately
+	Notify(n model.Notification)
+}
+
+// TradingReportUseCase defines the interface for summaries of persisted order events
+type TradingReportUseCase interface {
+	// DailyReport summarizes the fills of the UTC day containing date
+	DailyReport(ctx context.Context, apiKeyID string, date time.Time) (*model.TradingReport, error)
+}
+
+// MarketStateUseCase defines the interface for querying a market's trading status
+type MarketStateUseCase interface {
+	// State queries the exchange of the API key for the symbol's current state
+	State(ctx context.Context, apiKeyID, symbol string) (*model.MarketState, error)
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/usecase"
+)
+
+type AlertHandler struct {
+	alertUseCase adaptor.AlertUseCase
+}
+
+func NewAlertHandler(alertUseCase adaptor.AlertUseCase) *AlertHandler {
+	return &AlertHandler{alertUseCase: alertUseCase}
+}
+
+func (h *AlertHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.alertUseCase.List(r.Context())
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to list alert rules"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: rules})
+}
+
+func (h *AlertHandler) Get(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.alertUseCase.GetByID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAlertRuleNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to get alert rule"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: rule})
+}
+
+func (h *AlertHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	rule, err := h.alertUseCase.Create(r.Context(), actor.ID, &req)
+	if err != nil {
+		h.writeSaveError(w, err, "failed to create alert rule")
+		return
+	}
+
+	WriteJSON(w, http.StatusCreated, SuccessResponse{
+		Message: "alert rule created successfully",
+		Data:    rule,
+	})
+}
+
+func (h *AlertHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req model.AlertRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	rule, err := h.alertUseCase.Update(r.Context(), actor.ID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.writeSaveError(w, err, "failed to update alert rule")
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{
+		Message: "alert rule updated successfully",
+		Data:    rule,
+	})
+}
+
+func (h *AlertHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	err := h.alertUseCase.Delete(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAlertRuleNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to delete alert rule"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "alert rule deleted successfully"})
+}
+
+// writeSaveError maps Create and Update errors; an invalid api_key_id in the
+// body is a bad request rather than a missing rule
+func (h *AlertHandler) writeSaveError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidAlertRule):
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, usecase.ErrAlertRuleNotFound):
+		WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, usecase.ErrInvalidID):
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+	case errors.Is(err, usecase.ErrAPIKeyNotFound):
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: fallback})
+	}
+}
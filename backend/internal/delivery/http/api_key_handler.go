@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -13,21 +15,81 @@ import (
 )
 
 type APIKeyHandler struct {
-	apiKeyUseCase adaptor.APIKeyUseCase
+	apiKeyUseCase        adaptor.APIKeyUseCase
+	tradingStreamManager *TradingStreamManager
 }
 
-func NewAPIKeyHandler(apiKeyUseCase adaptor.APIKeyUseCase) *APIKeyHandler {
-	return &APIKeyHandler{apiKeyUseCase: apiKeyUseCase}
+func NewAPIKeyHandler(apiKeyUseCase adaptor.APIKeyUseCase, tradingStreamManager *TradingStreamManager) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase:        apiKeyUseCase,
+		tradingStreamManager: tradingStreamManager,
+	}
 }
 
 func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
-	apiKeys, err := h.apiKeyUseCase.List(r.Context())
+	filter, err := parseAPIKeyFilter(r)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to list api keys"})
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, SuccessResponse{Data: apiKeys})
+	result, err := h.apiKeyUseCase.List(r.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPlatform):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid platform"})
+		case errors.Is(err, usecase.ErrInvalidPagination):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to list api keys"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, ListResponse{Data: result.Items, Total: result.Total, Filters: filter})
+}
+
+// parseAPIKeyFilter reads platform, is_testnet, is_active, q, limit and offset query parameters
+func parseAPIKeyFilter(r *http.Request) (model.APIKeyFilter, error) {
+	q := r.URL.Query()
+	filter := model.APIKeyFilter{
+		Query: strings.TrimSpace(q.Get("q")),
+	}
+
+	if v := q.Get("platform"); v != "" {
+		platform := model.Platform(v)
+		filter.Platform = &platform
+	}
+	if v := q.Get("is_testnet"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("invalid is_testnet")
+		}
+		filter.IsTestnet = &b
+	}
+	if v := q.Get("is_active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("invalid is_active")
+		}
+		filter.IsActive = &b
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid limit")
+		}
+		filter.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return filter, errors.New("invalid offset")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
 }
 
 func (h *APIKeyHandler) Get(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +102,8 @@ func (h *APIKeyHandler) Get(w http.ResponseWriter, r *http.Request) {
 	apiKey, err := h.apiKeyUseCase.GetByID(r.Context(), id)
 	if err != nil {
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
 		case errors.Is(err, usecase.ErrAPIKeyNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
 		default:
@@ -69,6 +133,10 @@ func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "api key is required"})
 		case errors.Is(err, usecase.ErrAPISecretEmpty):
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "api secret is required"})
+		case errors.Is(err, usecase.ErrAPIKeyDuplicate):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrInvalidAPIKeyScope), errors.Is(err, usecase.ErrInvalidDefaultSubscription):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to create api key"})
 		}
@@ -97,6 +165,8 @@ func (h *APIKeyHandler) Update(w http.ResponseWriter, r *http.Request) {
 	apiKey, err := h.apiKeyUseCase.Update(r.Context(), id, &req)
 	if err != nil {
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
 		case errors.Is(err, usecase.ErrAPIKeyNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
 		case errors.Is(err, usecase.ErrAPIKeyNameEmpty):
@@ -105,12 +175,23 @@ func (h *APIKeyHandler) Update(w http.ResponseWriter, r *http.Request) {
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "api key cannot be empty"})
 		case errors.Is(err, usecase.ErrAPISecretEmpty):
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "api secret cannot be empty"})
+		case errors.Is(err, usecase.ErrInvalidAPIKeyScope), errors.Is(err, usecase.ErrInvalidDefaultSubscription):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to update api key"})
 		}
 		return
 	}
 
+	switch {
+	case !apiKey.IsActive:
+		h.tradingStreamManager.DisconnectAPIKey(id, CloseCodePermission, CloseReasonAPIKeyInactive)
+	case h.tradingStreamManager.exchangeNetworkChanged(id, apiKey.IsTestnet):
+		// Streams, orders and balances of the old network must not be reused;
+		// clients reconnect and attach to a connection on the new one
+		h.tradingStreamManager.DisconnectAPIKey(id, CloseCodeRestart, CloseReasonNetworkChanged)
+	}
+
 	WriteJSON(w, http.StatusOK, SuccessResponse{
 		Message: "api key updated successfully",
 		Data:    apiKey,
@@ -127,6 +208,8 @@ func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	err := h.apiKeyUseCase.Delete(r.Context(), id)
 	if err != nil {
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
 		case errors.Is(err, usecase.ErrAPIKeyNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
 		default:
@@ -135,6 +218,8 @@ func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.tradingStreamManager.DisconnectAPIKey(id, CloseCodePermission, CloseReasonAPIKeyRemoved)
+
 	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "api key deleted successfully"})
 }
 
@@ -146,3 +231,98 @@ func (h *APIKeyHandler) GetPlatforms(w http.ResponseWriter, r *http.Request) {
 	}
 	WriteJSON(w, http.StatusOK, SuccessResponse{Data: platformStrings})
 }
+
+func (h *APIKeyHandler) GetPlatformCapabilities(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: h.apiKeyUseCase.GetPlatformCapabilities()})
+}
+
+// Rotate verifies new credentials against the exchange, swaps them in and
+// rebuilds live trading streams for the key in the background
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid api key id"})
+		return
+	}
+
+	var req model.RotateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	if req.APIKey == "" || req.APISecret == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "api_key and api_secret are required"})
+		return
+	}
+
+	current, err := h.apiKeyUseCase.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
+		if errors.Is(err, usecase.ErrAPIKeyNotFound) {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to get api key"})
+		return
+	}
+
+	if err := h.tradingStreamManager.VerifyCredentials(r.Context(), current.Platform, current.IsTestnet, req.APIKey, req.APISecret); err != nil {
+		h.tradingStreamManager.notifyVerificationFailed(current, err)
+		WriteJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Error: "credential verification failed: " + err.Error()})
+		return
+	}
+
+	apiKey, err := h.apiKeyUseCase.Rotate(r.Context(), id, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAPIKeyNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
+		case errors.Is(err, usecase.ErrAPIKeyUnchanged):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrAPIKeyConflict):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to rotate api key"})
+		}
+		return
+	}
+
+	go h.tradingStreamManager.RebuildExchangeConn(id)
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "api key rotated", Data: apiKey})
+}
+
+// Rollback restores the credentials replaced by the last rotation
+func (h *APIKeyHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid api key id"})
+		return
+	}
+
+	apiKey, err := h.apiKeyUseCase.Rollback(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAPIKeyNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "api key not found"})
+		case errors.Is(err, usecase.ErrNoRollback):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrAPIKeyConflict):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to roll back api key"})
+		}
+		return
+	}
+
+	go h.tradingStreamManager.RebuildExchangeConn(id)
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "api key rolled back", Data: apiKey})
+}
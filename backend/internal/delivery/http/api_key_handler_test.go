@@ -0,0 +1,115 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"control_page/internal/exchangetest"
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+)
+
+// updateAPIKey sends an update through APIKeyHandler.Update, with the use case
+// answering with the key as stored in h.keys
+func updateAPIKey(t *testing.T, h *tradingHarness, id string) {
+	t.Helper()
+	apiKeys := &mocks.APIKeyUseCase{
+		UpdateFunc: func(ctx context.Context, id string, _ *model.UpdateAPIKeyRequest) (*model.APIKeyResponse, error) {
+			key, err := h.keys.GetByID(ctx, id)
+			if err != nil || key == nil {
+				return nil, errors.New("api key not found")
+			}
+			resp := key.ToResponse()
+			return &resp, nil
+		},
+	}
+	router := chi.NewRouter()
+	router.Put("/api-keys/{id}", NewAPIKeyHandler(apiKeys, h.manager).Update)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api-keys/"+id, strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+}
+
+// expectClose reads until the server closes the connection and returns the close frame
+func (c *tradingClient) expectClose() (*websocket.CloseError, closeReason) {
+	c.t.Helper()
+	for {
+		_, err := c.read(testFrameTimeout)
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			c.t.Fatalf("waiting for a close frame: %v", err)
+		}
+		var reason closeReason
+		if err := json.Unmarshal([]byte(closeErr.Text), &reason); err != nil {
+			c.t.Fatalf("close payload %q: %v", closeErr.Text, err)
+		}
+		return closeErr, reason
+	}
+}
+
+func TestAPIKeyUpdateMovesStreamsToTheNewNetwork(t *testing.T) {
+	production := exchangetest.NewBinance("binance-api-key")
+	t.Cleanup(production.Close)
+	testnet := exchangetest.NewBinance("binance-api-key")
+	t.Cleanup(testnet.Close)
+
+	key := testAPIKey(testBinanceKeyID, model.PlatformBinance, "binance-api-key", "binance-secret")
+	h := newTradingHarness(t,
+		map[model.ExchangeNetwork]model.ExchangeURLs{
+			{Platform: model.PlatformBinance}:                  {WSURL: production.WSURL(), RESTURL: production.RESTURL()},
+			{Platform: model.PlatformBinance, IsTestnet: true}: {WSURL: testnet.WSURL(), RESTURL: testnet.RESTURL()},
+		},
+		key,
+	)
+	kline := model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"}
+
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(kline)
+	waitFor(t, "the production kline stream", func(ctx context.Context) error {
+		return production.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+
+	// An update that keeps the network leaves the session alone
+	updateAPIKey(t, h, testBinanceKeyID)
+	if ec := h.exchangeConn(testBinanceKeyID); ec == nil || ec.IsTestnet {
+		t.Fatalf("exchange connection = %+v, want the production one kept", ec)
+	}
+
+	key.IsTestnet = true
+	h.keys.set(key)
+	updateAPIKey(t, h, testBinanceKeyID)
+
+	closeErr, reason := client.expectClose()
+	if closeErr.Code != CloseCodeRestart || reason.Reason != CloseReasonNetworkChanged {
+		t.Fatalf("close = %d %+v, want %d %s", closeErr.Code, reason, CloseCodeRestart, CloseReasonNetworkChanged)
+	}
+	if ec := h.exchangeConn(testBinanceKeyID); ec != nil {
+		t.Fatalf("exchange connection to testnet=%v kept after the network changed", ec.IsTestnet)
+	}
+
+	// Reconnecting attaches to a connection on the new network
+	client = h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(kline)
+	waitFor(t, "the testnet kline stream", func(ctx context.Context) error {
+		return testnet.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	if ec := h.exchangeConn(testBinanceKeyID); ec == nil || !ec.IsTestnet {
+		t.Fatalf("exchange connection = %+v, want one on testnet", ec)
+	}
+}
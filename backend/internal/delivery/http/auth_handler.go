@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
 
 	"control_page/internal/adaptor"
+	"control_page/internal/model"
 	"control_page/internal/usecase"
 )
 
@@ -28,8 +32,9 @@ type ActivateAccountRequest struct {
 }
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	DeviceToken string `json:"device_token,omitempty"` // from an earlier verify-totp with remember_device
 }
 
 type ChangePasswordRequest struct {
@@ -38,8 +43,9 @@ type ChangePasswordRequest struct {
 }
 
 type VerifyTOTPRequest struct {
-	UserID string `json:"user_id"`
-	Code   string `json:"code"`
+	UserID         string `json:"user_id"`
+	Code           string `json:"code"`
+	RememberDevice bool   `json:"remember_device,omitempty"`
 }
 
 type SetupTOTPRebindRequest struct {
@@ -54,9 +60,11 @@ type LoginResponse struct {
 	RequiresTOTP      bool   `json:"requires_totp"`
 	RequiresTOTPSetup bool   `json:"requires_totp_setup"`
 	Token             string `json:"token,omitempty"`
+	ExpiresIn         int64  `json:"expires_in,omitempty"` // token lifetime in seconds
 	User              any    `json:"user,omitempty"`
 	TempUserID        string `json:"temp_user_id,omitempty"`
 	TOTPSetup         any    `json:"totp_setup,omitempty"`
+	DeviceToken       string `json:"device_token,omitempty"`
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +85,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: "user already exists"})
 			return
 		}
+		if errors.Is(err, usecase.ErrWeakPassword) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to register user"})
 		return
 	}
@@ -102,6 +114,8 @@ func (h *AuthHandler) ActivateAccount(w http.ResponseWriter, r *http.Request) {
 	err := h.authUseCase.ActivateAccount(r.Context(), req.UserID, req.Code)
 	if err != nil {
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
 		case errors.Is(err, usecase.ErrUserNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
 		case errors.Is(err, usecase.ErrInvalidTOTPCode):
@@ -129,13 +143,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.authUseCase.Login(r.Context(), req.Username, req.Password)
+	client := clientInfo(r)
+	client.DeviceToken = req.DeviceToken
+	result, err := h.authUseCase.Login(r.Context(), req.Username, req.Password, client)
 	if err != nil {
 		switch {
 		case errors.Is(err, usecase.ErrUserNotFound), errors.Is(err, usecase.ErrInvalidCredentials):
 			WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid username or password"})
 		case errors.Is(err, usecase.ErrUserInactive):
-			WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "user account is inactive"})
+			WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "user account is inactive", Code: errorCodeUserInactive})
+		case errors.Is(err, usecase.ErrUserNotActivated):
+			WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "user account is not activated, finish registration first", Code: errorCodeUserNotActivated})
+		case errors.Is(err, usecase.ErrAccountLocked):
+			WriteJSON(w, http.StatusLocked, ErrorResponse{Error: "account is temporarily locked due to too many failed attempts"})
 		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to login"})
 		}
@@ -146,6 +166,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		RequiresTOTP:      result.RequiresTOTP,
 		RequiresTOTPSetup: result.RequiresTOTPSetup,
 		Token:             result.Token,
+		ExpiresIn:         result.ExpiresIn,
 		User:              result.User,
 		TempUserID:        result.TempUserID,
 		TOTPSetup:         result.TOTPSetup,
@@ -164,15 +185,17 @@ func (h *AuthHandler) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, user, err := h.authUseCase.VerifyTOTP(r.Context(), req.UserID, req.Code)
+	result, err := h.authUseCase.VerifyTOTP(r.Context(), req.UserID, req.Code, req.RememberDevice, clientInfo(r))
 	if err != nil {
 		switch {
-		case errors.Is(err, usecase.ErrUserNotFound):
+		case errors.Is(err, usecase.ErrUserNotFound), errors.Is(err, usecase.ErrInvalidID):
 			WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid user"})
 		case errors.Is(err, usecase.ErrInvalidTOTPCode):
 			WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "invalid verification code"})
 		case errors.Is(err, usecase.ErrTOTPNotSetup):
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "2FA is not enabled"})
+		case errors.Is(err, usecase.ErrAccountLocked):
+			WriteJSON(w, http.StatusLocked, ErrorResponse{Error: "account is temporarily locked due to too many failed attempts"})
 		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to verify code"})
 		}
@@ -181,11 +204,147 @@ func (h *AuthHandler) VerifyTOTP(w http.ResponseWriter, r *http.Request) {
 
 	WriteJSON(w, http.StatusOK, LoginResponse{
 		RequiresTOTP: false,
-		Token:        token,
-		User:         user,
+		Token:        result.Token,
+		ExpiresIn:    result.ExpiresIn,
+		User:         result.User,
+		DeviceToken:  result.DeviceToken,
 	})
 }
 
+// clientInfo extracts the peer address and user agent recorded in login history
+func clientInfo(r *http.Request) model.ClientInfo {
+	return model.ClientInfo{IP: clientIP(r), UserAgent: r.UserAgent()}
+}
+
+// MyLogins returns the current user's recent login history
+func (h *AuthHandler) MyLogins(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	h.writeLogins(w, r, user.ID)
+}
+
+// UserLogins returns the recent login history of any user (admin)
+func (h *AuthHandler) UserLogins(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	h.writeLogins(w, r, id)
+}
+
+func (h *AuthHandler) writeLogins(w http.ResponseWriter, r *http.Request, userID string) {
+	var limit int64
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	events, err := h.authUseCase.ListLogins(r.Context(), userID, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrUserNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to list logins"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: events})
+}
+
+// MyTrustedDevices lists the devices that skip TOTP for the current user
+func (h *AuthHandler) MyTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	h.writeTrustedDevices(w, r, user.ID)
+}
+
+// RevokeMyTrustedDevice makes one of the current user's devices require TOTP again
+func (h *AuthHandler) RevokeMyTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	h.revokeTrustedDevice(w, r, user.ID)
+}
+
+// UserTrustedDevices lists the devices that skip TOTP for any user (admin)
+func (h *AuthHandler) UserTrustedDevices(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	h.writeTrustedDevices(w, r, id)
+}
+
+// RevokeUserTrustedDevice makes one of any user's devices require TOTP again (admin)
+func (h *AuthHandler) RevokeUserTrustedDevice(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	h.revokeTrustedDevice(w, r, id)
+}
+
+func (h *AuthHandler) writeTrustedDevices(w http.ResponseWriter, r *http.Request, userID string) {
+	devices, err := h.authUseCase.ListTrustedDevices(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrUserNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to list trusted devices"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: devices})
+}
+
+func (h *AuthHandler) revokeTrustedDevice(w http.ResponseWriter, r *http.Request, userID string) {
+	err := h.authUseCase.RevokeTrustedDevice(r.Context(), userID, chi.URLParam(r, "deviceId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrUserNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		case errors.Is(err, usecase.ErrTrustedDeviceNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "trusted device not found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to revoke trusted device"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "trusted device revoked"})
+}
+
 func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	user := GetUserFromContext(r.Context())
 	if user == nil {
@@ -193,7 +352,17 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, user)
+	profile, err := h.authUseCase.GetProfile(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to load profile"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, profile)
 }
 
 func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
@@ -221,6 +390,8 @@ func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "current password is incorrect"})
 		case errors.Is(err, usecase.ErrPasswordSameAsOld):
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "new password cannot be the same as current password"})
+		case errors.Is(err, usecase.ErrWeakPassword):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to change password"})
 		}
@@ -0,0 +1,360 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+	"control_page/internal/usecase"
+)
+
+var errAuthBackend = errors.New("backend down")
+
+var testAuthUser = &model.UserWithRoles{User: model.User{ID: "user-1", Username: "alice", IsActive: true}}
+
+// serveAuth runs handler on a POST of body, with user set in the request
+// context when not nil, and returns the recorded response
+func serveAuth(handler http.HandlerFunc, body string, user *model.UserWithRoles) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("User-Agent", "auth-test")
+	if user != nil {
+		r = r.WithContext(context.WithValue(r.Context(), userContextKey, user))
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func decodeErrorResponse(t *testing.T, w *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var body ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode error body: %v", err)
+	}
+	return body
+}
+
+func TestAuthHandlerLogin(t *testing.T) {
+	var got model.ClientInfo
+	auth := &mocks.AuthUseCase{
+		LoginFunc: func(_ context.Context, username, password string, client model.ClientInfo) (*model.LoginResult, error) {
+			got = client
+			if username != "alice" || password != "Secret123!" {
+				return nil, fmt.Errorf("login %s: %w", username, usecase.ErrInvalidCredentials)
+			}
+			return &model.LoginResult{Token: "jwt", ExpiresIn: 3600, User: testAuthUser}, nil
+		},
+	}
+
+	w := serveAuth(NewAuthHandler(auth).Login, `{"username":"alice","password":"Secret123!","device_token":"dev-1"}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode login response: %v", err)
+	}
+	if resp.Token != "jwt" || resp.ExpiresIn != 3600 || resp.RequiresTOTP {
+		t.Fatalf("login response = %+v, want token jwt expiring in 3600s", resp)
+	}
+	if got.DeviceToken != "dev-1" || got.UserAgent != "auth-test" || got.IP == "" {
+		t.Fatalf("client info = %+v, want the device token, user agent and peer address", got)
+	}
+}
+
+func TestAuthHandlerLoginErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "invalid body", body: `{`, status: http.StatusBadRequest},
+		{name: "missing password", body: `{"username":"alice"}`, status: http.StatusBadRequest},
+		{name: "missing username", body: `{"password":"Secret123!"}`, status: http.StatusBadRequest},
+		{name: "user not found", err: usecase.ErrUserNotFound, status: http.StatusUnauthorized},
+		{name: "invalid credentials", err: usecase.ErrInvalidCredentials, status: http.StatusUnauthorized},
+		{name: "inactive", err: usecase.ErrUserInactive, status: http.StatusForbidden, code: errorCodeUserInactive},
+		{name: "not activated", err: usecase.ErrUserNotActivated, status: http.StatusForbidden, code: errorCodeUserNotActivated},
+		{name: "locked", err: usecase.ErrAccountLocked, status: http.StatusLocked},
+		{name: "unexpected", err: errAuthBackend, status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &mocks.AuthUseCase{
+				LoginFunc: func(context.Context, string, string, model.ClientInfo) (*model.LoginResult, error) {
+					return nil, fmt.Errorf("login: %w", tt.err)
+				},
+			}
+			body := tt.body
+			if body == "" {
+				body = `{"username":"alice","password":"Secret123!"}`
+			}
+
+			w := serveAuth(NewAuthHandler(auth).Login, body, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if resp := decodeErrorResponse(t, w); resp.Error == "" || resp.Code != tt.code {
+				t.Fatalf("error response = %+v, want a message and code %q", resp, tt.code)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerVerifyTOTP(t *testing.T) {
+	auth := &mocks.AuthUseCase{
+		VerifyTOTPFunc: func(_ context.Context, userID, code string, rememberDevice bool, _ model.ClientInfo) (*model.LoginResult, error) {
+			if userID != "user-1" || code != "123456" || !rememberDevice {
+				return nil, usecase.ErrInvalidTOTPCode
+			}
+			return &model.LoginResult{Token: "jwt", ExpiresIn: 3600, User: testAuthUser, DeviceToken: "dev-1"}, nil
+		},
+	}
+
+	w := serveAuth(NewAuthHandler(auth).VerifyTOTP, `{"user_id":"user-1","code":"123456","remember_device":true}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode verify response: %v", err)
+	}
+	if resp.Token != "jwt" || resp.DeviceToken != "dev-1" || resp.RequiresTOTP {
+		t.Fatalf("verify response = %+v, want token jwt and device token dev-1", resp)
+	}
+}
+
+func TestAuthHandlerVerifyTOTPErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{name: "invalid body", body: `{`, status: http.StatusBadRequest},
+		{name: "missing code", body: `{"user_id":"user-1"}`, status: http.StatusBadRequest},
+		{name: "missing user id", body: `{"code":"123456"}`, status: http.StatusBadRequest},
+		{name: "user not found", err: usecase.ErrUserNotFound, status: http.StatusUnauthorized},
+		{name: "invalid id", err: usecase.ErrInvalidID, status: http.StatusUnauthorized},
+		{name: "invalid code", err: usecase.ErrInvalidTOTPCode, status: http.StatusUnauthorized},
+		{name: "totp not set up", err: usecase.ErrTOTPNotSetup, status: http.StatusBadRequest},
+		{name: "locked", err: usecase.ErrAccountLocked, status: http.StatusLocked},
+		{name: "unexpected", err: errAuthBackend, status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &mocks.AuthUseCase{
+				VerifyTOTPFunc: func(context.Context, string, string, bool, model.ClientInfo) (*model.LoginResult, error) {
+					return nil, fmt.Errorf("verify: %w", tt.err)
+				},
+			}
+			body := tt.body
+			if body == "" {
+				body = `{"user_id":"user-1","code":"123456"}`
+			}
+
+			w := serveAuth(NewAuthHandler(auth).VerifyTOTP, body, nil)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if resp := decodeErrorResponse(t, w); resp.Error == "" {
+				t.Fatalf("error response = %+v, want a message", resp)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerChangePassword(t *testing.T) {
+	var gotUserID string
+	auth := &mocks.AuthUseCase{
+		ChangePasswordFunc: func(_ context.Context, userID, currentPassword, newPassword string) error {
+			gotUserID = userID
+			if currentPassword != "Secret123!" || newPassword != "Secret456!" {
+				return usecase.ErrIncorrectPassword
+			}
+			return nil
+		},
+	}
+
+	w := serveAuth(NewAuthHandler(auth).ChangePassword, `{"current_password":"Secret123!","new_password":"Secret456!"}`, testAuthUser)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if gotUserID != testAuthUser.ID {
+		t.Fatalf("changed password of %q, want the context user %q", gotUserID, testAuthUser.ID)
+	}
+}
+
+func TestAuthHandlerChangePasswordErrors(t *testing.T) {
+	weak := fmt.Errorf("%w: must contain a digit", usecase.ErrWeakPassword)
+
+	tests := []struct {
+		name    string
+		body    string
+		noUser  bool
+		err     error
+		status  int
+		message string
+	}{
+		{name: "no user", noUser: true, status: http.StatusUnauthorized},
+		{name: "invalid body", body: `{`, status: http.StatusBadRequest},
+		{name: "missing new password", body: `{"current_password":"Secret123!"}`, status: http.StatusBadRequest},
+		{name: "missing current password", body: `{"new_password":"Secret456!"}`, status: http.StatusBadRequest},
+		{name: "incorrect password", err: usecase.ErrIncorrectPassword, status: http.StatusBadRequest},
+		{name: "same as old", err: usecase.ErrPasswordSameAsOld, status: http.StatusBadRequest},
+		{name: "weak password", err: weak, status: http.StatusBadRequest, message: weak.Error()},
+		{name: "unexpected", err: errAuthBackend, status: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &mocks.AuthUseCase{
+				ChangePasswordFunc: func(context.Context, string, string, string) error {
+					return tt.err
+				},
+			}
+			body := tt.body
+			if body == "" {
+				body = `{"current_password":"Secret123!","new_password":"Secret456!"}`
+			}
+			user := testAuthUser
+			if tt.noUser {
+				user = nil
+			}
+
+			w := serveAuth(NewAuthHandler(auth).ChangePassword, body, user)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			resp := decodeErrorResponse(t, w)
+			if resp.Error == "" || (tt.message != "" && resp.Error != tt.message) {
+				t.Fatalf("error response = %+v, want message %q", resp, tt.message)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerUserLogins(t *testing.T) {
+	const (
+		validID   = "6650f1d2e4b0a1b2c3d4e5f6"
+		missingID = "6650f1d2e4b0a1b2c3d4e5f7"
+	)
+	auth := &mocks.AuthUseCase{
+		ListLoginsFunc: func(_ context.Context, userID string, _ int64) ([]model.LoginEvent, error) {
+			switch userID {
+			case validID:
+				return []model.LoginEvent{{UserID: validID, Username: "alice", Success: true}}, nil
+			case missingID:
+				return nil, usecase.ErrUserNotFound
+			case "boom":
+				return nil, errAuthBackend
+			default:
+				return nil, fmt.Errorf("%w: %q", usecase.ErrInvalidID, userID)
+			}
+		},
+	}
+	router := chi.NewRouter()
+	router.Get("/users/{id}/logins", NewAuthHandler(auth).UserLogins)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "malformed id", path: "/users/not-an-id/logins", status: http.StatusBadRequest},
+		{name: "well-formed but missing", path: "/users/" + missingID + "/logins", status: http.StatusNotFound},
+		{name: "valid id", path: "/users/" + validID + "/logins", status: http.StatusOK},
+		{name: "invalid limit", path: "/users/" + validID + "/logins?limit=ten", status: http.StatusBadRequest},
+		{name: "unexpected", path: "/users/boom/logins", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("GET %s status = %d, want %d: %s", tt.path, w.Code, tt.status, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data []model.LoginEvent `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || len(resp.Data) != 1 {
+				t.Fatalf("GET %s body = %+v (%v), want one login event", tt.path, resp, err)
+			}
+		})
+	}
+}
+
+func TestAuthHandlerMe(t *testing.T) {
+	changed := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	auth := &mocks.AuthUseCase{
+		GetProfileFunc: func(_ context.Context, userID string) (*model.Profile, error) {
+			switch userID {
+			case testAuthUser.ID:
+				return &model.Profile{
+					ID: userID, Username: "alice", TOTPEnabled: true, PendingRebind: true,
+					PasswordChangedAt: &changed, Permissions: []enum.Permission{enum.PermissionViewKline},
+				}, nil
+			case "gone":
+				return nil, usecase.ErrUserNotFound
+			}
+			return nil, errAuthBackend
+		},
+	}
+	handler := NewAuthHandler(auth).Me
+
+	w := serveAuth(handler, "", testAuthUser)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("decode profile: %v", err)
+	}
+	if body["totp_enabled"] != true || body["pending_rebind"] != true || body["password_changed_at"] != "2026-05-01T12:00:00Z" {
+		t.Fatalf("profile = %v, want TOTP enabled, a pending rebind and the password change time", body)
+	}
+	for _, field := range []string{"password", "totp_secret", "pending_totp_secret"} {
+		if _, ok := body[field]; ok {
+			t.Fatalf("profile = %v, want no %s field", body, field)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		user   *model.UserWithRoles
+		status int
+	}{
+		{name: "no user", status: http.StatusUnauthorized},
+		{name: "deleted user", user: &model.UserWithRoles{User: model.User{ID: "gone"}}, status: http.StatusNotFound},
+		{name: "unexpected", user: &model.UserWithRoles{User: model.User{ID: "other"}}, status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAuth(handler, "", tt.user)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if resp := decodeErrorResponse(t, w); resp.Error == "" {
+				t.Fatalf("error response = %+v, want a message", resp)
+			}
+		})
+	}
+}
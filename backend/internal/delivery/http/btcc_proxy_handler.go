@@ -5,11 +5,8 @@ import (
 	"io"
 	"net/http"
 	"time"
-)
 
-const (
-	btccMarketListProd = "https://spotapi2.btcccdn.com/btcc_api_trade/market/list"
-	btccMarketListDev  = "https://spot.cryptouat.com:9910/btcc_api_trade/market/list"
+	"control_page/pkg/btcc"
 )
 
 type BTCCProxyHandler struct {
@@ -29,16 +26,17 @@ func (h *BTCCProxyHandler) GetMarketList(w http.ResponseWriter, r *http.Request)
 	// Check if testnet parameter is provided
 	testnet := r.URL.Query().Get("testnet") == "true"
 
-	var targetURL string
-	if testnet {
-		targetURL = btccMarketListDev
-	} else {
-		targetURL = btccMarketListProd
+	targetURL := btcc.MarketListURL(testnet)
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, targetURL, nil)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to build market list request"})
+		return
 	}
 
-	resp, err := h.httpClient.Get(targetURL)
+	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		WriteJSON(w, http.StatusBadGateway, ErrorResponse{Error: "failed to fetch market list: " + err.Error()})
+		WriteError(w, r, http.StatusBadGateway, "failed to fetch market list", err)
 		return
 	}
 	defer resp.Body.Close()
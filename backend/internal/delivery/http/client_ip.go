@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const clientIPContextKey contextKey = "client_ip"
+
+// ResolveClientIP stores the caller's address for clientIP. With
+// trustedProxyHeader set, such as X-Forwarded-For or X-Real-IP, the right-most
+// address in that header is used, which is the one appended by the trusted
+// proxy; a missing or malformed header falls back to the peer address.
+func ResolveClientIP(trustedProxyHeader string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := peerIP(r)
+			if trustedProxyHeader != "" {
+				if forwarded := forwardedIP(r.Header.Get(trustedProxyHeader)); forwarded != "" {
+					ip = forwarded
+				}
+			}
+			ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP returns the address resolved by ResolveClientIP, or the peer
+// address for requests that did not pass through it
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+func peerIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// forwardedIP returns the last address of a comma separated header value, or
+// "" when it is not an IP address
+func forwardedIP(value string) string {
+	if i := strings.LastIndexByte(value, ','); i >= 0 {
+		value = value[i+1:]
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().String()
+}
@@ -0,0 +1,44 @@
+package http
+
+import "sync/atomic"
+
+// clientLimiter caps the WebSocket clients connected across all stream
+// managers. A nil limiter or a zero max allows any number of clients.
+type clientLimiter struct {
+	max     int64
+	current atomic.Int64
+}
+
+func newClientLimiter(max int) *clientLimiter {
+	return &clientLimiter{max: int64(max)}
+}
+
+// acquire reserves a slot, returning false when the limit is reached
+func (l *clientLimiter) acquire() bool {
+	if l == nil {
+		return true
+	}
+	for {
+		n := l.current.Load()
+		if l.max > 0 && n >= l.max {
+			return false
+		}
+		if l.current.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+func (l *clientLimiter) release() {
+	if l != nil {
+		l.current.Add(-1)
+	}
+}
+
+// limit returns the configured maximum (0 when unlimited)
+func (l *clientLimiter) limit() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.max
+}
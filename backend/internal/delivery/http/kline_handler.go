@@ -1,9 +1,12 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/usecase"
 )
 
 type KlineHandler struct {
@@ -37,3 +40,25 @@ func (h *KlineHandler) GetIntervals(w http.ResponseWriter, r *http.Request) {
 		Data: intervals,
 	})
 }
+
+// SearchSymbols finds tradable symbols on the live exchange
+func (h *KlineHandler) SearchSymbols(w http.ResponseWriter, r *http.Request) {
+	platform := model.Platform(r.URL.Query().Get("platform"))
+	if platform == "" {
+		platform = model.PlatformBinance
+	}
+
+	symbols, err := h.klineUseCase.SearchSymbols(r.Context(), platform, r.URL.Query().Get("q"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrSymbolSearchPlatform) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusBadGateway, ErrorResponse{Error: "failed to search symbols"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{
+		Data: symbols,
+	})
+}
@@ -0,0 +1,85 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/yanun0323/logs"
+)
+
+var ErrInvalidLogLevel = errors.New("invalid log level, must be one of debug, info, warn, error")
+
+// logLevelNames lists the levels that can be selected at runtime
+var logLevelNames = map[string]logs.Level{
+	"debug": logs.LevelDebug,
+	"info":  logs.LevelInfo,
+	"warn":  logs.LevelWarn,
+	"error": logs.LevelError,
+}
+
+var currentLogLevel atomic.Value // string
+
+func init() {
+	currentLogLevel.Store("info")
+}
+
+// SetLogLevel replaces the default logger with one at the given level
+func SetLogLevel(level string) error {
+	level = strings.ToLower(strings.TrimSpace(level))
+	lvl, ok := logLevelNames[level]
+	if !ok {
+		return ErrInvalidLogLevel
+	}
+
+	logs.SetDefault(logs.New(lvl))
+	currentLogLevel.Store(level)
+	return nil
+}
+
+// LogLevel returns the name of the active log level
+func LogLevel() string {
+	return currentLogLevel.Load().(string)
+}
+
+// redactPayload returns the payload only when debug logging is enabled,
+// so message bodies (orders, balances) never reach the logs at info and above
+func redactPayload(payload []byte) string {
+	if LogLevel() == "debug" {
+		return string(payload)
+	}
+	return fmt.Sprintf("<redacted %d bytes>", len(payload))
+}
+
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type LogLevelHandler struct{}
+
+func NewLogLevelHandler() *LogLevelHandler {
+	return &LogLevelHandler{}
+}
+
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: LogLevelRequest{Level: LogLevel()}})
+}
+
+func (h *LogLevelHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	if err := SetLogLevel(req.Level); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	logs.Warnf("log level changed to %s", LogLevel())
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "log level updated", Data: LogLevelRequest{Level: LogLevel()}})
+}
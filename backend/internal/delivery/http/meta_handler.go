@@ -0,0 +1,108 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"control_page/internal/model/enum"
+)
+
+// RouteInfo describes one endpoint and what it takes to call it
+type RouteInfo struct {
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	Authenticated bool            `json:"authenticated"`
+	Permission    enum.Permission `json:"permission,omitempty"`
+}
+
+// MetaHandler lets the frontend build its menus from the routes the signed-in
+// user can actually call instead of a hardcoded permission table
+type MetaHandler struct {
+	routes []RouteInfo
+}
+
+func NewMetaHandler() *MetaHandler {
+	return &MetaHandler{}
+}
+
+// setRouter catalogs the routes of the finished router; it must be called
+// before the server starts
+func (h *MetaHandler) setRouter(router chi.Routes) {
+	h.routes = catalogRoutes(router)
+}
+
+// Routes lists the routes the current user is allowed to call
+func (h *MetaHandler) Routes(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	routes := make([]RouteInfo, 0, len(h.routes))
+	for _, route := range h.routes {
+		if route.Permission == "" || user.HasPermission(route.Permission) {
+			routes = append(routes, route)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: routes})
+}
+
+// catalogRoutes walks router and reads each route's access rules from the
+// routeGate handlers that the AuthMiddleware methods wrap it in
+func catalogRoutes(router chi.Routes) []RouteInfo {
+	probe := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+	var routes []RouteInfo
+	walkRoutes(router, "", nil, func(method, path string, middlewares []func(http.Handler) http.Handler) {
+		if method == http.MethodOptions || method == http.MethodHead {
+			return
+		}
+		info := RouteInfo{Method: method, Path: path}
+		for _, mw := range middlewares {
+			if gate, ok := mw(probe).(*routeGate); ok {
+				info.Authenticated = info.Authenticated || gate.authenticated
+				if gate.permission != "" {
+					info.Permission = gate.permission
+				}
+			}
+		}
+		routes = append(routes, info)
+	})
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// walkRoutes is chi.Walk plus the middlewares of r.Group blocks that mount a
+// subrouter: chi wraps the mount handler in those, and chi.Walk skips them
+func walkRoutes(router chi.Routes, prefix string, parent []func(http.Handler) http.Handler, fn func(method, path string, middlewares []func(http.Handler) http.Handler)) {
+	for _, route := range router.Routes() {
+		middlewares := append(append([]func(http.Handler) http.Handler{}, parent...), router.Middlewares()...)
+
+		if route.SubRoutes != nil {
+			if chain, ok := route.Handlers["*"].(*chi.ChainHandler); ok {
+				middlewares = append(middlewares, chain.Middlewares...)
+			}
+			walkRoutes(route.SubRoutes, prefix+strings.TrimSuffix(route.Pattern, "/*"), middlewares, fn)
+			continue
+		}
+
+		for method, handler := range route.Handlers {
+			routeMiddlewares := middlewares
+			if chain, ok := handler.(*chi.ChainHandler); ok {
+				routeMiddlewares = append(append([]func(http.Handler) http.Handler{}, middlewares...), chain.Middlewares...)
+			}
+			fn(method, prefix+route.Pattern, routeMiddlewares)
+		}
+	}
+}
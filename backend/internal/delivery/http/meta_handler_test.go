@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+// newTestRouter builds the application router mounted under basePath; no
+// handler is called, so the use cases are left nil
+func newTestRouter(t *testing.T, basePath string) *chi.Mux {
+	t.Helper()
+	rt := NewRouter(
+		&mocks.AuthUseCase{}, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		"", false, 0, ExchangeConnLimits{}, model.NewExchangeEndpoints(nil), false,
+		nil, nil, nil, nil, nil, nil, 0, 0, RequestTimeouts{}, basePath, "",
+	)
+	t.Cleanup(rt.Close)
+	return rt.Setup()
+}
+
+func TestCatalogRoutes(t *testing.T) {
+	catalog := make(map[string]RouteInfo)
+	for _, route := range catalogRoutes(newTestRouter(t, "/admin")) {
+		catalog[route.Method+" "+route.Path] = route
+	}
+
+	tests := []struct {
+		route         string
+		authenticated bool
+		permission    enum.Permission
+	}{
+		{route: "GET /admin/health"},
+		{route: "POST /admin/api/auth/login"},
+		// Group middlewares apply through nested r.Route and r.Group blocks
+		{route: "GET /admin/api/auth/me", authenticated: true},
+		{route: "POST /admin/api/auth/register", authenticated: true, permission: enum.PermissionManageUsers},
+		{route: "GET /admin/api/kline/symbols", authenticated: true, permission: enum.PermissionViewKline},
+		{route: "GET /admin/api/trading/status", authenticated: true, permission: enum.PermissionViewDashboard},
+		{route: "GET /admin/api/trading/{apiKeyId}/report", authenticated: true, permission: enum.PermissionViewTrading},
+		{route: "PUT /admin/api/settings/log-level", authenticated: true, permission: enum.PermissionManageSettings},
+		// WebSockets authenticate in their handlers
+		{route: "GET /admin/ws/kline"},
+		{route: "GET /admin/ws/trading", authenticated: true, permission: enum.PermissionViewTrading},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			got, ok := catalog[tt.route]
+			if !ok {
+				t.Fatalf("route missing from the catalog")
+			}
+			if got.Authenticated != tt.authenticated || got.Permission != tt.permission {
+				t.Fatalf("route = %+v, want authenticated %v with permission %q", got, tt.authenticated, tt.permission)
+			}
+		})
+	}
+
+	for key := range catalog {
+		if strings.HasPrefix(key, "OPTIONS ") || strings.HasPrefix(key, "HEAD ") || strings.Contains(key, "/*") {
+			t.Errorf("catalog lists %s", key)
+		}
+	}
+}
+
+// The catalog reports view:trading for /ws/trading, so the upgrade checks it
+func TestTradingRequiresViewTrading(t *testing.T) {
+	h, _ := newBinanceHarness(t)
+	h.manager.authUseCase = &mocks.AuthUseCase{
+		ValidateTokenFunc: func(context.Context, string) (*model.UserWithRoles, error) {
+			return &model.UserWithRoles{
+				User:        model.User{ID: "user-1", Username: "viewer", IsActive: true},
+				Permissions: []enum.Permission{enum.PermissionViewKline},
+			}, nil
+		},
+	}
+
+	url := "ws" + strings.TrimPrefix(h.server.URL, "http") + "?token=" + testTradingToken
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		conn.Close()
+		t.Fatal("dial succeeded without view:trading")
+	}
+	if !errors.Is(err, websocket.ErrBadHandshake) || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial error = %v, want %d", err, http.StatusForbidden)
+	}
+}
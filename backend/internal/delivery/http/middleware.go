@@ -14,6 +14,15 @@ type contextKey string
 
 const userContextKey contextKey = "user"
 
+const (
+	// errorCodeIPNotAllowed marks requests from outside the user's IP allowlist
+	errorCodeIPNotAllowed = "ip_not_allowed"
+	// errorCodeUserInactive and errorCodeUserNotActivated tell a deactivated
+	// account apart from a registration that was never activated
+	errorCodeUserInactive     = "user_inactive"
+	errorCodeUserNotActivated = "user_not_activated"
+)
+
 func GetUserFromContext(ctx context.Context) *model.UserWithRoles {
 	user, ok := ctx.Value(userContextKey).(*model.UserWithRoles)
 	if !ok {
@@ -30,8 +39,21 @@ func NewAuthMiddleware(authUseCase adaptor.AuthUseCase) *AuthMiddleware {
 	return &AuthMiddleware{authUseCase: authUseCase}
 }
 
+// routeGate is the handler type returned by the AuthMiddleware methods. It
+// serves like a plain HandlerFunc, but lets the route catalog tell which
+// routes require a login or a permission.
+type routeGate struct {
+	serve         http.HandlerFunc
+	authenticated bool
+	permission    enum.Permission
+}
+
+func (g *routeGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.serve(w, r)
+}
+
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return &routeGate{authenticated: true, serve: func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "missing authorization header"})
@@ -51,34 +73,59 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		if !user.AllowsIP(clientIP(r)) {
+			WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "ip address not allowed", Code: errorCodeIPNotAllowed})
+			return
+		}
+
 		ctx := context.WithValue(r.Context(), userContextKey, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
+	}}
+}
+
+// RequirePasswordChanged blocks users whose password was reset by an admin
+// until they set their own
+func (m *AuthMiddleware) RequirePasswordChanged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUserFromContext(r.Context())
+		if user == nil {
+			WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+			return
+		}
+
+		if user.MustChangePassword {
+			WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "password change required"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
 func (m *AuthMiddleware) RequirePermission(permission enum.Permission) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return &routeGate{authenticated: true, permission: permission, serve: func(w http.ResponseWriter, r *http.Request) {
 			user := GetUserFromContext(r.Context())
 			if user == nil {
 				WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
 				return
 			}
 
-			hasPermission := false
-			for _, p := range user.Permissions {
-				if p == permission {
-					hasPermission = true
-					break
-				}
-			}
-
-			if !hasPermission {
+			if !user.HasPermission(permission) {
 				WriteJSON(w, http.StatusForbidden, ErrorResponse{Error: "permission denied"})
 				return
 			}
 
 			next.ServeHTTP(w, r)
-		})
+		}}
+	}
+}
+
+// Describe records the access rules of a handler authenticated outside this
+// middleware, such as a WebSocket taking its token as a query parameter, so
+// the route catalog can report them. It does not check anything.
+func (m *AuthMiddleware) Describe(permission enum.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return &routeGate{authenticated: true, permission: permission, serve: next.ServeHTTP}
 	}
 }
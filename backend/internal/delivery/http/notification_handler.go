@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+type NotificationHandler struct {
+	notificationUseCase adaptor.NotificationUseCase // nil when notifications are disabled
+}
+
+func NewNotificationHandler(notificationUseCase adaptor.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{notificationUseCase: notificationUseCase}
+}
+
+// Test queues a test notification, bypassing the event filter. Delivery is
+// asynchronous, so a 202 does not mean the webhook accepted it.
+func (h *NotificationHandler) Test(w http.ResponseWriter, r *http.Request) {
+	if h.notificationUseCase == nil {
+		WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "notifications are not configured"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	h.notificationUseCase.Notify(model.Notification{
+		Type:    model.NotificationTest,
+		Message: "test notification",
+		Fields:  map[string]string{"requested_by": actor.Username},
+	})
+
+	WriteJSON(w, http.StatusAccepted, SuccessResponse{Message: "test notification queued"})
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+	"control_page/internal/usecase"
+)
+
+const (
+	testValidID   = "6650f1d2e4b0a1b2c3d4e5f6"
+	testMissingID = "6650f1d2e4b0a1b2c3d4e5f7"
+)
+
+// lookupByID answers like a use case GetByID: nil for testValidID, notFound
+// for testMissingID and ErrInvalidID for anything else
+func lookupByID(id string, notFound error) error {
+	switch id {
+	case testValidID:
+		return nil
+	case testMissingID:
+		return notFound
+	default:
+		return fmt.Errorf("%w: %q", usecase.ErrInvalidID, id)
+	}
+}
+
+type settingLookup struct{ adaptor.SettingUseCase }
+
+func (settingLookup) GetByID(_ context.Context, id string) (*model.SettingResponse, error) {
+	if err := lookupByID(id, usecase.ErrSettingNotFound); err != nil {
+		return nil, err
+	}
+	return &model.SettingResponse{}, nil
+}
+
+type switcherLookup struct{ adaptor.SwitcherUseCase }
+
+func (switcherLookup) GetByID(_ context.Context, id string) (*model.SwitcherResponse, error) {
+	if err := lookupByID(id, usecase.ErrSwitcherNotFound); err != nil {
+		return nil, err
+	}
+	return &model.SwitcherResponse{}, nil
+}
+
+type alertLookup struct{ adaptor.AlertUseCase }
+
+func (alertLookup) GetByID(_ context.Context, id string) (*model.AlertRule, error) {
+	if err := lookupByID(id, usecase.ErrAlertRuleNotFound); err != nil {
+		return nil, err
+	}
+	return &model.AlertRule{}, nil
+}
+
+func TestGetByIDStatuses(t *testing.T) {
+	manager := newTradingHarness(t, nil).manager
+	roles := &mocks.RoleUseCase{
+		GetRoleFunc: func(_ context.Context, id string) (*model.RoleWithPermissions, error) {
+			if err := lookupByID(id, usecase.ErrRoleNotFound); err != nil {
+				return nil, err
+			}
+			return &model.RoleWithPermissions{}, nil
+		},
+	}
+	users := &mocks.UserUseCase{
+		GetUserFunc: func(_ context.Context, id string) (*model.UserWithRoles, error) {
+			if err := lookupByID(id, usecase.ErrUserNotFound); err != nil {
+				return nil, err
+			}
+			return &model.UserWithRoles{User: model.User{ID: id}}, nil
+		},
+	}
+	apiKeys := &mocks.APIKeyUseCase{
+		GetByIDFunc: func(_ context.Context, id string) (*model.APIKeyResponse, error) {
+			if err := lookupByID(id, usecase.ErrAPIKeyNotFound); err != nil {
+				return nil, err
+			}
+			return &model.APIKeyResponse{}, nil
+		},
+	}
+	rbac := NewRBACHandler(roles, users, apiKeys, manager)
+
+	handlers := map[string]http.HandlerFunc{
+		"role":       rbac.GetRole,
+		"user":       rbac.GetUser,
+		"api key":    NewAPIKeyHandler(apiKeys, manager).Get,
+		"setting":    NewSettingHandler(settingLookup{}).Get,
+		"switcher":   NewSwitcherHandler(switcherLookup{}).Get,
+		"alert rule": NewAlertHandler(alertLookup{}).Get,
+	}
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "malformed id", id: "not-an-object-id", status: http.StatusBadRequest},
+		{name: "well-formed but missing", id: testMissingID, status: http.StatusNotFound},
+		{name: "valid id", id: testValidID, status: http.StatusOK},
+	}
+
+	for resource, handler := range handlers {
+		router := chi.NewRouter()
+		router.Get("/{id}", handler)
+		for _, tt := range tests {
+			t.Run(resource+"/"+tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+tt.id, nil))
+				if w.Code != tt.status {
+					t.Fatalf("GET %s %s status = %d, want %d: %s", resource, tt.id, w.Code, tt.status, w.Body)
+				}
+			})
+		}
+	}
+}
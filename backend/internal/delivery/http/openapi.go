@@ -0,0 +1,479 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	Name        string
+	Type        string // string, integer or boolean
+	Required    bool
+	Description string
+}
+
+// apiOperation describes one REST route for the generated OpenAPI spec.
+// Request and Response hold zero values of the body types; Response is the
+// type of SuccessResponse.Data unless Raw is set, in which case it is the
+// whole body.
+type apiOperation struct {
+	Method     string
+	Path       string
+	Tag        string
+	Summary    string
+	Public     bool
+	Permission enum.Permission // empty when any authenticated user may call it
+	Query      []apiParam
+	Request    any
+	Response   any
+	Raw        bool
+	List       bool // body is a ListResponse instead of a SuccessResponse
+	Status     int  // success status, 200 when zero
+}
+
+// apiOperations mirrors the routes registered in Router.Setup; keep both in
+// sync when adding endpoints
+var apiOperations = []apiOperation{
+	// Auth
+	{Method: "POST", Path: "/api/auth/login", Tag: "auth", Summary: "Log in with username and password", Public: true, Request: LoginRequest{}, Response: LoginResponse{}, Raw: true},
+	{Method: "POST", Path: "/api/auth/verify-totp", Tag: "auth", Summary: "Complete login with a TOTP code", Public: true, Request: VerifyTOTPRequest{}, Response: LoginResponse{}, Raw: true},
+	{Method: "GET", Path: "/api/auth/me", Tag: "auth", Summary: "Current user's profile", Response: model.Profile{}, Raw: true},
+	{Method: "GET", Path: "/api/auth/me/logins", Tag: "auth", Summary: "Current user's login history", Query: []apiParam{{Name: "limit", Type: "integer", Description: "maximum events, capped at 100"}}, Response: []model.LoginEvent{}},
+	{Method: "GET", Path: "/api/auth/me/devices", Tag: "auth", Summary: "Current user's devices that skip TOTP", Response: []model.TrustedDevice{}},
+	{Method: "DELETE", Path: "/api/auth/me/devices/{deviceId}", Tag: "auth", Summary: "Make one of the current user's devices require TOTP again"},
+	{Method: "GET", Path: "/api/auth/me/preferences", Tag: "auth", Summary: "Current user's UI preferences; the ETag header carries the version", Response: model.UserPreferences{}},
+	{Method: "PUT", Path: "/api/auth/me/preferences", Tag: "auth", Summary: "Replace the current user's UI preferences (If-Match required)", Request: map[string]any{}, Response: model.UserPreferences{}},
+	{Method: "POST", Path: "/api/auth/change-password", Tag: "auth", Summary: "Change the current user's password", Request: ChangePasswordRequest{}},
+	{Method: "POST", Path: "/api/auth/register", Tag: "auth", Summary: "Register a user pending 2FA setup", Permission: enum.PermissionManageUsers, Request: RegisterRequest{}, Response: model.RegisterResult{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/api/auth/activate", Tag: "auth", Summary: "Activate a registered user with a TOTP code", Permission: enum.PermissionManageUsers, Request: ActivateAccountRequest{}},
+	{Method: "POST", Path: "/api/auth/totp/rebind", Tag: "auth", Summary: "Start rebinding the current user's 2FA", Request: SetupTOTPRebindRequest{}, Response: model.TOTPSetup{}},
+	{Method: "POST", Path: "/api/auth/totp/rebind/confirm", Tag: "auth", Summary: "Confirm a pending 2FA rebind", Request: ConfirmTOTPRebindRequest{}},
+	{Method: "POST", Path: "/api/auth/totp/rebind/cancel", Tag: "auth", Summary: "Cancel a pending 2FA rebind"},
+
+	// Meta
+	{Method: "GET", Path: "/api/meta/routes", Tag: "meta", Summary: "Routes the current user can call, with their required permission", Response: []RouteInfo{}},
+
+	// Kline
+	{Method: "GET", Path: "/api/kline/symbols", Tag: "kline", Summary: "Dashboard symbols", Permission: enum.PermissionViewKline, Response: []string{}},
+	{Method: "GET", Path: "/api/kline/symbols/search", Tag: "kline", Summary: "Search tradable symbols on an exchange", Permission: enum.PermissionViewKline, Query: []apiParam{{Name: "platform", Type: "string", Description: "defaults to binance"}, {Name: "q", Type: "string"}}, Response: []model.SymbolInfo{}},
+	{Method: "GET", Path: "/api/kline/intervals", Tag: "kline", Summary: "Dashboard intervals", Permission: enum.PermissionViewKline, Response: []string{}},
+	{Method: "GET", Path: "/api/btcc/markets", Tag: "kline", Summary: "Proxied BTCC market list", Permission: enum.PermissionViewKline, Query: []apiParam{{Name: "testnet", Type: "boolean"}}, Response: map[string]any{}, Raw: true},
+
+	// Trading
+	{Method: "GET", Path: "/api/trading/status", Tag: "trading", Summary: "Trading stream connection and bandwidth metrics", Permission: enum.PermissionViewDashboard, Response: model.TradingStreamStats{}},
+	{Method: "GET", Path: "/api/trading/recorded", Tag: "trading", Summary: "Recorded klines and trades", Permission: enum.PermissionViewDashboard, Query: []apiParam{
+		{Name: "symbol", Type: "string", Required: true},
+		{Name: "type", Type: "string", Description: "kline or trades"},
+		{Name: "from", Type: "string", Description: "RFC 3339 or Unix milliseconds"},
+		{Name: "to", Type: "string", Description: "RFC 3339 or Unix milliseconds"},
+		{Name: "limit", Type: "integer"},
+	}, Response: []model.MarketRecord{}},
+	{Method: "GET", Path: "/api/trading/{apiKeyId}/order-events", Tag: "trading", Summary: "Persisted order updates of an API key, newest first", Permission: enum.PermissionViewTrading, Query: []apiParam{
+		{Name: "symbol", Type: "string"},
+		{Name: "from", Type: "string", Description: "RFC 3339 or Unix milliseconds"},
+		{Name: "to", Type: "string", Description: "RFC 3339 or Unix milliseconds"},
+		{Name: "limit", Type: "integer", Description: "default 100, max 1000"},
+		{Name: "offset", Type: "integer"},
+	}, Response: model.OrderEventPage{}},
+	{Method: "GET", Path: "/api/trading/{apiKeyId}/report", Tag: "trading", Summary: "Daily fills, volume and realized PnL per symbol", Permission: enum.PermissionViewTrading, Query: []apiParam{
+		{Name: "date", Type: "string", Description: "YYYY-MM-DD (UTC), default today"},
+	}, Response: model.TradingReport{}},
+	{Method: "GET", Path: "/api/trading/{apiKeyId}/state", Tag: "trading", Summary: "Trading status and 24h statistics of a market on the API key's exchange", Permission: enum.PermissionViewTrading, Query: []apiParam{
+		{Name: "symbol", Type: "string", Required: true, Description: "Trading pair, e.g. BTCUSDT"},
+	}, Response: model.MarketState{}},
+	{Method: "GET", Path: "/api/trading/sessions", Tag: "trading", Summary: "Connected trading and kline WebSocket clients", Permission: enum.PermissionViewSessions, Response: model.WebSocketSessions{}},
+	{Method: "POST", Path: "/api/trading/disconnect", Tag: "trading", Summary: "Force-disconnect a trading session or every client of an API key", Permission: enum.PermissionManageSessions, Request: model.TradingDisconnectRequest{}, Response: model.TradingDisconnectResult{}},
+	{Method: "GET", Path: "/api/trading/alerts", Tag: "trading", Summary: "List alert rules", Permission: enum.PermissionManageSettings, Response: []model.AlertRule{}},
+	{Method: "POST", Path: "/api/trading/alerts", Tag: "trading", Summary: "Create an alert rule", Permission: enum.PermissionManageSettings, Request: model.AlertRuleRequest{}, Response: model.AlertRule{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/trading/alerts/{id}", Tag: "trading", Summary: "Get an alert rule", Permission: enum.PermissionManageSettings, Response: model.AlertRule{}},
+	{Method: "PUT", Path: "/api/trading/alerts/{id}", Tag: "trading", Summary: "Replace an alert rule", Permission: enum.PermissionManageSettings, Request: model.AlertRuleRequest{}, Response: model.AlertRule{}},
+	{Method: "DELETE", Path: "/api/trading/alerts/{id}", Tag: "trading", Summary: "Delete an alert rule", Permission: enum.PermissionManageSettings},
+
+	// RBAC roles
+	{Method: "GET", Path: "/api/rbac/roles", Tag: "rbac", Summary: "List roles", Permission: enum.PermissionManageRoles, Response: []model.RoleWithPermissions{}},
+	{Method: "POST", Path: "/api/rbac/roles", Tag: "rbac", Summary: "Create a role", Permission: enum.PermissionManageRoles, Request: CreateRoleRequest{}, Response: model.RoleWithPermissions{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/rbac/roles/{id}", Tag: "rbac", Summary: "Get a role", Permission: enum.PermissionManageRoles, Response: model.RoleWithPermissions{}},
+	{Method: "PUT", Path: "/api/rbac/roles/{id}", Tag: "rbac", Summary: "Update a role", Permission: enum.PermissionManageRoles, Request: UpdateRoleRequest{}, Response: model.RoleWithPermissions{}},
+	{Method: "DELETE", Path: "/api/rbac/roles/{id}", Tag: "rbac", Summary: "Delete a role; 409 while assigned unless force=true", Permission: enum.PermissionManageRoles, Query: []apiParam{{Name: "force", Type: "boolean"}}, Response: []model.User{}},
+	{Method: "GET", Path: "/api/rbac/roles/{id}/users", Tag: "rbac", Summary: "Users assigned to a role", Permission: enum.PermissionManageRoles, Response: []model.User{}},
+	{Method: "PUT", Path: "/api/rbac/roles/{id}/permissions", Tag: "rbac", Summary: "Replace a role's permissions", Permission: enum.PermissionManageRoles, Request: SetPermissionsRequest{}},
+	{Method: "GET", Path: "/api/rbac/permissions", Tag: "rbac", Summary: "All permissions", Permission: enum.PermissionManageRoles, Response: []enum.Permission{}},
+
+	// RBAC users
+	{Method: "GET", Path: "/api/rbac/users", Tag: "rbac", Summary: "List users", Permission: enum.PermissionManageUsers, Response: []model.UserWithRoles{}},
+	{Method: "POST", Path: "/api/rbac/users", Tag: "rbac", Summary: "Create a user", Permission: enum.PermissionManageUsers, Request: CreateUserRequest{}, Response: model.UserWithRoles{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/rbac/users/{id}", Tag: "rbac", Summary: "Get a user with live sessions", Permission: enum.PermissionManageUsers, Response: model.UserDetail{}},
+	{Method: "PUT", Path: "/api/rbac/users/{id}", Tag: "rbac", Summary: "Update a user", Permission: enum.PermissionManageUsers, Request: UpdateUserRequest{}, Response: model.UserWithRoles{}},
+	{Method: "DELETE", Path: "/api/rbac/users/{id}", Tag: "rbac", Summary: "Delete a user", Permission: enum.PermissionManageUsers},
+	{Method: "POST", Path: "/api/rbac/users/{id}/roles", Tag: "rbac", Summary: "Assign a role to a user", Permission: enum.PermissionManageUsers, Request: AssignRoleRequest{}},
+	{Method: "DELETE", Path: "/api/rbac/users/{id}/roles/{roleId}", Tag: "rbac", Summary: "Remove a role from a user", Permission: enum.PermissionManageUsers},
+	{Method: "POST", Path: "/api/rbac/users/{id}/totp/reset", Tag: "rbac", Summary: "Reset a user's 2FA", Permission: enum.PermissionManageUsers, Response: model.TOTPSetup{}},
+	{Method: "POST", Path: "/api/rbac/users/{id}/unlock", Tag: "rbac", Summary: "Clear a user's lockout", Permission: enum.PermissionManageUsers, Response: model.UserWithRoles{}},
+	{Method: "POST", Path: "/api/rbac/users/{id}/reset-password", Tag: "rbac", Summary: "Issue a temporary password that must be changed at next login", Permission: enum.PermissionManageUsers, Response: ResetPasswordResponse{}},
+	{Method: "GET", Path: "/api/rbac/users/{id}/ip-allowlist", Tag: "rbac", Summary: "Get the CIDR ranges a user may connect from", Permission: enum.PermissionManageUsers, Response: IPAllowlistResponse{}},
+	{Method: "PUT", Path: "/api/rbac/users/{id}/ip-allowlist", Tag: "rbac", Summary: "Replace the CIDR ranges a user may connect from", Permission: enum.PermissionManageUsers, Request: IPAllowlistRequest{}, Response: IPAllowlistResponse{}},
+	{Method: "GET", Path: "/api/rbac/users/{id}/logins", Tag: "rbac", Summary: "A user's login history", Permission: enum.PermissionManageUsers, Query: []apiParam{{Name: "limit", Type: "integer"}}, Response: []model.LoginEvent{}},
+	{Method: "GET", Path: "/api/rbac/users/{id}/devices", Tag: "rbac", Summary: "A user's devices that skip TOTP", Permission: enum.PermissionManageUsers, Response: []model.TrustedDevice{}},
+	{Method: "DELETE", Path: "/api/rbac/users/{id}/devices/{deviceId}", Tag: "rbac", Summary: "Make one of a user's devices require TOTP again", Permission: enum.PermissionManageUsers},
+
+	// API keys
+	{Method: "GET", Path: "/api/api-keys", Tag: "api-keys", Summary: "Search API keys", Permission: enum.PermissionViewAPIKeys, Query: []apiParam{
+		{Name: "q", Type: "string", Description: "case-insensitive name substring"},
+		{Name: "platform", Type: "string"},
+		{Name: "is_testnet", Type: "boolean"},
+		{Name: "is_active", Type: "boolean"},
+		{Name: "limit", Type: "integer"},
+		{Name: "offset", Type: "integer"},
+	}, Response: []model.APIKeyResponse{}, List: true},
+	{Method: "GET", Path: "/api/api-keys/platforms", Tag: "api-keys", Summary: "Supported platforms", Permission: enum.PermissionViewAPIKeys, Response: []string{}},
+	{Method: "GET", Path: "/api/api-keys/platforms/capabilities", Tag: "api-keys", Summary: "Per-platform capabilities", Permission: enum.PermissionViewAPIKeys, Response: []model.PlatformCapabilities{}},
+	{Method: "GET", Path: "/api/api-keys/{id}", Tag: "api-keys", Summary: "Get an API key", Permission: enum.PermissionViewAPIKeys, Response: model.APIKeyResponse{}},
+	{Method: "POST", Path: "/api/api-keys", Tag: "api-keys", Summary: "Create an API key", Permission: enum.PermissionManageAPIKeys, Request: model.CreateAPIKeyRequest{}, Response: model.APIKeyResponse{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/api-keys/{id}", Tag: "api-keys", Summary: "Update an API key", Permission: enum.PermissionManageAPIKeys, Request: model.UpdateAPIKeyRequest{}, Response: model.APIKeyResponse{}},
+	{Method: "DELETE", Path: "/api/api-keys/{id}", Tag: "api-keys", Summary: "Delete an API key", Permission: enum.PermissionManageAPIKeys},
+	{Method: "POST", Path: "/api/api-keys/{id}/rotate", Tag: "api-keys", Summary: "Rotate an API key's credentials", Permission: enum.PermissionManageAPIKeys, Request: model.RotateAPIKeyRequest{}, Response: model.APIKeyResponse{}},
+	{Method: "POST", Path: "/api/api-keys/{id}/rollback", Tag: "api-keys", Summary: "Restore the previous credentials", Permission: enum.PermissionManageAPIKeys, Response: model.APIKeyResponse{}},
+
+	// Switchers
+	{Method: "GET", Path: "/api/switchers", Tag: "switchers", Summary: "List switchers", Permission: enum.PermissionViewSettings, Response: []model.SwitcherResponse{}},
+	{Method: "GET", Path: "/api/switchers/{id}", Tag: "switchers", Summary: "Get a switcher", Permission: enum.PermissionViewSettings, Response: model.SwitcherResponse{}},
+	{Method: "POST", Path: "/api/switchers", Tag: "switchers", Summary: "Create a switcher", Permission: enum.PermissionManageSettings, Request: model.UpdateSwitcherRequest{}, Response: model.SwitcherResponse{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/switchers/{id}", Tag: "switchers", Summary: "Replace a switcher's pairs", Permission: enum.PermissionManageSettings, Request: model.UpdateSwitcherRequest{}, Response: model.SwitcherResponse{}},
+	{Method: "PUT", Path: "/api/switchers/{id}/pairs/{pair}", Tag: "switchers", Summary: "Enable or disable one pair", Permission: enum.PermissionManageSettings, Request: UpdatePairRequest{}, Response: model.SwitcherResponse{}},
+	{Method: "DELETE", Path: "/api/switchers/{id}", Tag: "switchers", Summary: "Delete a switcher", Permission: enum.PermissionManageSettings},
+
+	// Settings
+	{Method: "GET", Path: "/api/settings", Tag: "settings", Summary: "List settings", Permission: enum.PermissionViewSettings, Response: []model.SettingResponse{}},
+	{Method: "GET", Path: "/api/settings/search", Tag: "settings", Summary: "Find a setting by base and quote", Permission: enum.PermissionViewSettings, Query: []apiParam{{Name: "base", Type: "string", Required: true}, {Name: "quote", Type: "string", Required: true}}, Response: model.SettingResponse{}},
+	{Method: "GET", Path: "/api/settings/log-level", Tag: "settings", Summary: "Current log level", Permission: enum.PermissionViewSettings, Response: LogLevelRequest{}},
+	{Method: "GET", Path: "/api/settings/{id}", Tag: "settings", Summary: "Get a setting", Permission: enum.PermissionViewSettings, Response: model.SettingResponse{}},
+	{Method: "POST", Path: "/api/settings", Tag: "settings", Summary: "Create a setting", Permission: enum.PermissionManageSettings, Request: model.CreateSettingRequest{}, Response: model.SettingResponse{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/api/settings/log-level", Tag: "settings", Summary: "Change the log level", Permission: enum.PermissionManageSettings, Request: LogLevelRequest{}, Response: LogLevelRequest{}},
+	{Method: "POST", Path: "/api/settings/notifications/test", Tag: "settings", Summary: "Queue a test notification to the configured webhook", Permission: enum.PermissionManageSettings, Status: http.StatusAccepted},
+	{Method: "PUT", Path: "/api/settings/{id}", Tag: "settings", Summary: "Update a setting", Permission: enum.PermissionManageSettings, Request: model.UpdateSettingRequest{}, Response: model.SettingResponse{}},
+	{Method: "PUT", Path: "/api/settings/{id}/parameters/{strategy}", Tag: "settings", Summary: "Replace one strategy's parameters", Permission: enum.PermissionManageSettings, Request: UpdateParametersRequest{}, Response: model.SettingResponse{}},
+	{Method: "DELETE", Path: "/api/settings/{id}", Tag: "settings", Summary: "Delete a setting", Permission: enum.PermissionManageSettings},
+}
+
+type OpenAPIHandler struct {
+	basePath string
+	once     sync.Once
+	spec     map[string]any
+}
+
+func NewOpenAPIHandler(basePath string) *OpenAPIHandler {
+	return &OpenAPIHandler{basePath: basePath}
+}
+
+// Spec serves the OpenAPI 3 document generated from apiOperations
+func (h *OpenAPIHandler) Spec(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(func() {
+		h.spec = buildOpenAPISpec(apiOperations)
+		if h.basePath != "" {
+			// Paths are documented without the prefix the routes are mounted under
+			h.spec["servers"] = []map[string]any{{"url": h.basePath}}
+		}
+	})
+	WriteJSON(w, http.StatusOK, h.spec)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+func buildOpenAPISpec(ops []apiOperation) map[string]any {
+	sb := newSchemaBuilder()
+	errorSchema := sb.schema(reflect.TypeOf(ErrorResponse{}))
+
+	paths := make(map[string]any)
+	for _, op := range ops {
+		operation := map[string]any{
+			"tags":        []string{op.Tag},
+			"summary":     op.Summary,
+			"operationId": operationID(op),
+		}
+
+		var params []map[string]any
+		for _, match := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+			params = append(params, map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+		for _, p := range op.Query {
+			param := map[string]any{
+				"name":     p.Name,
+				"in":       "query",
+				"required": p.Required,
+				"schema":   map[string]any{"type": p.Type},
+			}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(sb.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+
+		responses := map[string]any{
+			strconv.Itoa(successStatus(op)): map[string]any{
+				"description": http.StatusText(successStatus(op)),
+				"content":     jsonContent(responseSchema(sb, op)),
+			},
+			"default": map[string]any{
+				"description": "Error",
+				"content":     jsonContent(errorSchema),
+			},
+		}
+		operation["responses"] = responses
+
+		if !op.Public {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+			if op.Permission != "" {
+				operation["description"] = "Requires the `" + string(op.Permission) + "` permission."
+				operation["x-permission"] = string(op.Permission)
+			} else {
+				operation["description"] = "Requires authentication."
+			}
+		}
+
+		item, _ := paths[op.Path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Control Page API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": sb.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+}
+
+func successStatus(op apiOperation) int {
+	if op.Status == 0 {
+		return http.StatusOK
+	}
+	return op.Status
+}
+
+// responseSchema wraps the payload type in the envelope the handler writes
+func responseSchema(sb *schemaBuilder, op apiOperation) map[string]any {
+	var data map[string]any
+	if op.Response != nil {
+		data = sb.schema(reflect.TypeOf(op.Response))
+	}
+	if op.Raw {
+		return data
+	}
+	if op.List {
+		return map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"message": map[string]any{"type": "string"},
+				"data":    data,
+				"total":   map[string]any{"type": "integer", "format": "int64"},
+				"filters": map[string]any{"type": "object"},
+			},
+			"required": []string{"message", "data", "total"},
+		}
+	}
+
+	properties := map[string]any{"message": map[string]any{"type": "string"}}
+	if data != nil {
+		properties["data"] = data
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"message"},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	if schema == nil {
+		schema = map[string]any{"type": "object"}
+	}
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// operationID derives a stable camelCase ID such as deleteRbacRolesById
+func operationID(op apiOperation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.Method))
+	for _, segment := range strings.Split(strings.TrimPrefix(op.Path, "/api/"), "/") {
+		if strings.HasPrefix(segment, "{") {
+			segment = "by-" + strings.Trim(segment, "{}")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	permissionType = reflect.TypeOf(enum.Permission(""))
+	rawJSONType    = reflect.TypeOf(json.RawMessage(nil))
+
+	// orderEnumValues lists the canonical values of the order and market status enums
+	orderEnumValues = map[reflect.Type][]string{
+		reflect.TypeOf(model.OrderSide("")):    enumStrings(model.AllOrderSides()),
+		reflect.TypeOf(model.OrderStatus("")):  enumStrings(model.AllOrderStatuses()),
+		reflect.TypeOf(model.OrderType("")):    enumStrings(model.AllOrderTypes()),
+		reflect.TypeOf(model.TimeInForce("")):  enumStrings(model.AllTimeInForces()),
+		reflect.TypeOf(model.MarketStatus("")): enumStrings(model.AllMarketStatuses()),
+	}
+)
+
+func enumStrings[T ~string](values []T) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = string(v)
+	}
+	return out
+}
+
+// schemaBuilder converts Go types to JSON schemas using their json tags.
+// Named structs are emitted once under components and referenced by $ref.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]any)}
+}
+
+func (sb *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawJSONType:
+		return map[string]any{} // any JSON value
+	case t == permissionType:
+		values := make([]string, 0, len(enum.AllPermissions()))
+		for _, p := range enum.AllPermissions() {
+			values = append(values, string(p))
+		}
+		return map[string]any{"type": "string", "enum": values}
+	case orderEnumValues[t] != nil:
+		return map[string]any{"type": "string", "enum": orderEnumValues[t]}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": sb.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sb.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sb.structSchema(t)
+		}
+		if _, ok := sb.components[t.Name()]; !ok {
+			sb.components[t.Name()] = map[string]any{} // placeholder for recursive types
+			sb.components[t.Name()] = sb.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]any{} // interface{}: any JSON value
+	}
+}
+
+func (sb *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	sb.collectFields(t, properties, &required)
+	sort.Strings(required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields adds t's JSON fields, flattening embedded structs like encoding/json does
+func (sb *schemaBuilder) collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sb.collectFields(ft, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = sb.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
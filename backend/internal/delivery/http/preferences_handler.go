@@ -0,0 +1,86 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/usecase"
+)
+
+// PreferencesHandler serves the signed-in user's UI preferences. Writes use
+// the ETag from the last read as If-Match, so two tabs cannot silently
+// overwrite each other.
+type PreferencesHandler struct {
+	preferencesUseCase adaptor.PreferencesUseCase
+}
+
+func NewPreferencesHandler(preferencesUseCase adaptor.PreferencesUseCase) *PreferencesHandler {
+	return &PreferencesHandler{preferencesUseCase: preferencesUseCase}
+}
+
+func (h *PreferencesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	prefs, err := h.preferencesUseCase.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to load preferences"})
+		return
+	}
+
+	w.Header().Set("ETag", prefs.ETag())
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: prefs})
+}
+
+// Put replaces the preferences with the request body, which must be a JSON
+// object, if If-Match still names the stored version
+func (h *PreferencesHandler) Put(w http.ResponseWriter, r *http.Request) {
+	user := GetUserFromContext(r.Context())
+	if user == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		WriteJSON(w, http.StatusPreconditionRequired, ErrorResponse{Error: "If-Match header is required"})
+		return
+	}
+	version, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+	if err != nil || version < 0 {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid If-Match header"})
+		return
+	}
+
+	// Read one byte past the limit so oversized bodies are reported as such
+	body, err := io.ReadAll(io.LimitReader(r.Body, usecase.MaxPreferencesBytes+1))
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+		return
+	}
+
+	prefs, err := h.preferencesUseCase.SavePreferences(r.Context(), user.ID, body, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrPreferencesTooLarge):
+			WriteJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrInvalidPreferences):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrPreferencesConflict):
+			WriteJSON(w, http.StatusPreconditionFailed, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to save preferences"})
+		}
+		return
+	}
+
+	w.Header().Set("ETag", prefs.ETag())
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "preferences saved", Data: prefs})
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -15,14 +16,23 @@ import (
 )
 
 type RBACHandler struct {
-	roleUseCase adaptor.RoleUseCase
-	userUseCase adaptor.UserUseCase
+	roleUseCase          adaptor.RoleUseCase
+	userUseCase          adaptor.UserUseCase
+	apiKeyUseCase        adaptor.APIKeyUseCase
+	tradingStreamManager *TradingStreamManager
 }
 
-func NewRBACHandler(roleUseCase adaptor.RoleUseCase, userUseCase adaptor.UserUseCase) *RBACHandler {
+func NewRBACHandler(
+	roleUseCase adaptor.RoleUseCase,
+	userUseCase adaptor.UserUseCase,
+	apiKeyUseCase adaptor.APIKeyUseCase,
+	tradingStreamManager *TradingStreamManager,
+) *RBACHandler {
 	return &RBACHandler{
-		roleUseCase: roleUseCase,
-		userUseCase: userUseCase,
+		roleUseCase:          roleUseCase,
+		userUseCase:          userUseCase,
+		apiKeyUseCase:        apiKeyUseCase,
+		tradingStreamManager: tradingStreamManager,
 	}
 }
 
@@ -32,11 +42,13 @@ type CreateRoleRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Permissions []string `json:"permissions"`
+	MaxTokenTTL int64    `json:"max_token_ttl"` // seconds, 0 uses the global expiry
 }
 
 type UpdateRoleRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	MaxTokenTTL *int64 `json:"max_token_ttl"` // omitted keeps the current TTL
 }
 
 type SetPermissionsRequest struct {
@@ -62,6 +74,10 @@ func (h *RBACHandler) GetRole(w http.ResponseWriter, r *http.Request) {
 
 	role, err := h.roleUseCase.GetRole(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrRoleNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "role not found"})
 			return
@@ -90,13 +106,16 @@ func (h *RBACHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
 		permissions[i] = enum.Permission(p)
 	}
 
-	role, err := h.roleUseCase.CreateRole(r.Context(), req.Name, req.Description, permissions)
+	role, err := h.roleUseCase.CreateRole(r.Context(), req.Name, req.Description, permissions, req.MaxTokenTTL)
 	if err != nil {
-		if errors.Is(err, usecase.ErrRoleAlreadyExists) {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPermission), errors.Is(err, usecase.ErrInvalidTokenTTL):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrRoleAlreadyExists):
 			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: "role already exists"})
-			return
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to create role"})
 		}
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to create role"})
 		return
 	}
 
@@ -124,9 +143,13 @@ func (h *RBACHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.roleUseCase.UpdateRole(r.Context(), id, req.Name, req.Description)
+	role, err := h.roleUseCase.UpdateRole(r.Context(), id, req.Name, req.Description, req.MaxTokenTTL)
 	if err != nil {
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrInvalidTokenTTL):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		case errors.Is(err, usecase.ErrRoleNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "role not found"})
 		case errors.Is(err, usecase.ErrRoleAlreadyExists):
@@ -150,16 +173,55 @@ func (h *RBACHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.roleUseCase.DeleteRole(r.Context(), id); err != nil {
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	users, err := h.roleUseCase.DeleteRole(r.Context(), actor.ID, id, force)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrRoleNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "role not found"})
+		case errors.Is(err, usecase.ErrRoleInUse):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{
+				Error: fmt.Sprintf("role is assigned to %d user(s); pass force=true to remove it from them and delete", len(users)),
+			})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to delete role"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "role deleted successfully", Data: users})
+}
+
+func (h *RBACHandler) GetRoleUsers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid role id"})
+		return
+	}
+
+	users, err := h.roleUseCase.GetRoleUsers(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrRoleNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "role not found"})
 			return
 		}
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to delete role"})
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to get role users"})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "role deleted successfully"})
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: users})
 }
 
 func (h *RBACHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
@@ -181,11 +243,16 @@ func (h *RBACHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.roleUseCase.SetPermissions(r.Context(), id, permissions); err != nil {
-		if errors.Is(err, usecase.ErrRoleNotFound) {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrInvalidPermission):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrRoleNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "role not found"})
-			return
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to set permissions"})
 		}
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to set permissions"})
 		return
 	}
 
@@ -205,6 +272,7 @@ type AssignRoleRequest struct {
 
 type CreateUserRequest struct {
 	Username string   `json:"username"`
+	Email    string   `json:"email"`
 	Password string   `json:"password"`
 	IsActive bool     `json:"is_active"`
 	Roles    []string `json:"roles"`
@@ -212,10 +280,28 @@ type CreateUserRequest struct {
 
 type UpdateUserRequest struct {
 	Username string   `json:"username"`
+	Email    *string  `json:"email"` // omitted keeps the current email
 	IsActive bool     `json:"is_active"`
 	Roles    []string `json:"roles"`
 }
 
+// UnknownRolesResponse is the 400 body for role IDs that name no existing role
+type UnknownRolesResponse struct {
+	Error   string   `json:"error"`
+	RoleIDs []string `json:"unknown_role_ids"`
+}
+
+// writeUnknownRoles answers 400 with the offending IDs if err is an
+// *usecase.UnknownRolesError, reporting whether it did
+func writeUnknownRoles(w http.ResponseWriter, err error) bool {
+	var unknown *usecase.UnknownRolesError
+	if !errors.As(err, &unknown) {
+		return false
+	}
+	WriteJSON(w, http.StatusBadRequest, UnknownRolesResponse{Error: "unknown role ids", RoleIDs: unknown.RoleIDs})
+	return true
+}
+
 func (h *RBACHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	users, err := h.userUseCase.ListUsers(r.Context())
 	if err != nil {
@@ -235,6 +321,10 @@ func (h *RBACHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userUseCase.GetUser(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
 			return
@@ -243,7 +333,30 @@ func (h *RBACHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, SuccessResponse{Data: user})
+	detail := model.UserDetail{
+		UserWithRoles: *user,
+		Sessions:      h.tradingStreamManager.SessionsForUser(user.ID),
+	}
+
+	// API keys have no owner yet, so report the keys the user's live sessions are attached to
+	if caller := GetUserFromContext(r.Context()); caller != nil && caller.HasPermission(enum.PermissionViewAPIKeys) {
+		detail.APIKeys = make([]model.APIKeyResponse, 0)
+		seen := make(map[string]bool)
+		for _, session := range detail.Sessions {
+			if session.APIKeyID == "" || seen[session.APIKeyID] {
+				continue
+			}
+			seen[session.APIKeyID] = true
+
+			apiKey, err := h.apiKeyUseCase.GetByID(r.Context(), session.APIKeyID)
+			if err != nil {
+				continue
+			}
+			detail.APIKeys = append(detail.APIKeys, *apiKey)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: detail})
 }
 
 func (h *RBACHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -258,6 +371,11 @@ func (h *RBACHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := h.userUseCase.ValidatePassword(req.Password); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to hash password"})
@@ -266,13 +384,27 @@ func (h *RBACHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	user := &model.User{
 		Username: req.Username,
+		Email:    req.Email,
 		Password: string(hashed),
 		IsActive: req.IsActive,
 	}
 
 	created, err := h.userUseCase.CreateUser(r.Context(), user, req.Roles)
 	if err != nil {
-		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		if writeUnknownRoles(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, usecase.ErrUserAlreadyExists):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: "username already exists"})
+		case errors.Is(err, usecase.ErrEmailAlreadyExists):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrInvalidEmail), errors.Is(err, usecase.ErrWeakPassword),
+			errors.Is(err, usecase.ErrUsernamePasswordRequired):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			WriteError(w, r, http.StatusInternalServerError, "failed to create user", err)
+		}
 		return
 	}
 
@@ -294,6 +426,10 @@ func (h *RBACHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.userUseCase.GetUser(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
 			return
@@ -302,36 +438,48 @@ func (h *RBACHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject unknown roles before changing anything
+	if err := h.userUseCase.ValidateRoles(r.Context(), req.Roles); err != nil {
+		if !writeUnknownRoles(w, err) {
+			WriteError(w, r, http.StatusInternalServerError, "failed to validate roles", err)
+		}
+		return
+	}
+
+	wasActive := user.IsActive
+	previousRoles := user.Roles
+
 	user.Username = req.Username
 	user.IsActive = req.IsActive
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
 
 	if err := h.userUseCase.UpdateUser(r.Context(), &user.User); err != nil {
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to update user"})
+		switch {
+		case errors.Is(err, usecase.ErrInvalidEmail):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrEmailAlreadyExists):
+			WriteJSON(w, http.StatusConflict, ErrorResponse{Error: err.Error()})
+		default:
+			WriteError(w, r, http.StatusInternalServerError, "failed to update user", err)
+		}
 		return
 	}
 
-	// Sync roles
-	currentRoleIDs := make(map[string]struct{})
-	for _, role := range user.Roles {
-		currentRoleIDs[role.ID] = struct{}{}
-	}
-	targetRoleIDs := make(map[string]struct{})
-	for _, id := range req.Roles {
-		targetRoleIDs[id] = struct{}{}
-		if _, ok := currentRoleIDs[id]; !ok {
-			if err := h.userUseCase.AssignRole(r.Context(), user.ID, id); err != nil {
-				WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to assign role"})
-				return
-			}
+	if err := h.userUseCase.SetRoles(r.Context(), user.ID, req.Roles); err != nil {
+		if !writeUnknownRoles(w, err) {
+			WriteError(w, r, http.StatusInternalServerError, "failed to update roles", err)
 		}
+		return
 	}
-	for roleID := range currentRoleIDs {
-		if _, ok := targetRoleIDs[roleID]; !ok {
-			if err := h.userUseCase.RemoveRole(r.Context(), user.ID, roleID); err != nil {
-				WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to remove role"})
-				return
-			}
-		}
+
+	// Trading streams were authorized against the old account state
+	switch {
+	case wasActive && !req.IsActive:
+		h.tradingStreamManager.DisconnectUser(id, CloseCodeAuth, CloseReasonUserInactive)
+	case rolesRemoved(previousRoles, req.Roles):
+		h.tradingStreamManager.DisconnectUser(id, CloseCodePermission, CloseReasonRolesChanged)
 	}
 
 	updated, err := h.userUseCase.GetUser(r.Context(), id)
@@ -343,6 +491,20 @@ func (h *RBACHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	WriteJSON(w, http.StatusOK, SuccessResponse{Data: updated})
 }
 
+// rolesRemoved reports whether any of the roles held before is missing from roleIDs
+func rolesRemoved(before []model.Role, roleIDs []string) bool {
+	kept := make(map[string]bool, len(roleIDs))
+	for _, id := range roleIDs {
+		kept[id] = true
+	}
+	for _, role := range before {
+		if !kept[role.ID] {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *RBACHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -351,10 +513,16 @@ func (h *RBACHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userUseCase.DeleteUser(r.Context(), id); err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to delete user"})
 		return
 	}
 
+	h.tradingStreamManager.DisconnectUser(id, CloseCodeAuth, CloseReasonUserDeleted)
+
 	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "user deleted successfully"})
 }
 
@@ -377,6 +545,13 @@ func (h *RBACHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userUseCase.AssignRole(r.Context(), id, req.RoleID); err != nil {
+		if writeUnknownRoles(w, err) {
+			return
+		}
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to assign role"})
 		return
 	}
@@ -398,10 +573,16 @@ func (h *RBACHandler) RemoveRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.userUseCase.RemoveRole(r.Context(), userID, roleID); err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to remove role"})
 		return
 	}
 
+	h.tradingStreamManager.DisconnectUser(userID, CloseCodePermission, CloseReasonRolesChanged)
+
 	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "role removed successfully"})
 }
 
@@ -414,6 +595,10 @@ func (h *RBACHandler) ResetUserTOTP(w http.ResponseWriter, r *http.Request) {
 
 	setup, err := h.userUseCase.ResetUserTOTP(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrUserNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
 			return
@@ -424,3 +609,163 @@ func (h *RBACHandler) ResetUserTOTP(w http.ResponseWriter, r *http.Request) {
 
 	WriteJSON(w, http.StatusOK, SuccessResponse{Data: setup})
 }
+
+// ResetPasswordResponse carries the temporary password; it is shown only once
+type ResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+func (h *RBACHandler) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	password, err := h.userUseCase.ResetPassword(r.Context(), actor.ID, id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to reset password"})
+		return
+	}
+
+	// The reset revokes the user's tokens; open trading streams go with them
+	h.tradingStreamManager.DisconnectUser(id, CloseCodeAuth, CloseReasonPasswordReset)
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{
+		Message: "temporary password issued; it will not be shown again",
+		Data:    ResetPasswordResponse{TemporaryPassword: password},
+	})
+}
+
+func (h *RBACHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	user, err := h.userUseCase.UnlockUser(r.Context(), actor.ID, id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to unlock user"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Message: "user unlocked successfully", Data: user})
+}
+
+// IPAllowlistRequest replaces a user's allowlist; an empty list removes it
+type IPAllowlistRequest struct {
+	CIDRs []string `json:"cidrs"`
+}
+
+// IPAllowlistResponse is a user's allowlist, empty when unrestricted
+type IPAllowlistResponse struct {
+	CIDRs []string `json:"cidrs"`
+}
+
+// InvalidCIDRResponse is the 400 body for allowlist entries that are not CIDR ranges
+type InvalidCIDRResponse struct {
+	Error   string   `json:"error"`
+	Entries []string `json:"invalid_cidrs"`
+}
+
+func (h *RBACHandler) GetIPAllowlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	user, err := h.userUseCase.GetUser(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
+		if errors.Is(err, usecase.ErrUserNotFound) {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to get user"})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: IPAllowlistResponse{CIDRs: ipAllowlist(user.IPAllowlist)}})
+}
+
+func (h *RBACHandler) SetIPAllowlist(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid user id"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	var req IPAllowlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	user, err := h.userUseCase.SetIPAllowlist(r.Context(), actor.ID, id, req.CIDRs)
+	if err != nil {
+		var invalid *usecase.InvalidCIDRError
+		switch {
+		case errors.As(err, &invalid):
+			WriteJSON(w, http.StatusBadRequest, InvalidCIDRResponse{Error: "invalid cidr entries", Entries: invalid.Entries})
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrUserNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		default:
+			WriteError(w, r, http.StatusInternalServerError, "failed to update ip allowlist", err)
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{
+		Message: "ip allowlist updated successfully",
+		Data:    IPAllowlistResponse{CIDRs: ipAllowlist(user.IPAllowlist)},
+	})
+}
+
+// ipAllowlist reports an unrestricted user as an empty list rather than null
+func ipAllowlist(cidrs []string) []string {
+	if cidrs == nil {
+		return []string{}
+	}
+	return cidrs
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+)
+
+// serveRBAC sends a request through an RBACHandler route, acting as an admin
+func serveRBAC(h *tradingHarness, users *mocks.UserUseCase, method, pattern, target, body string, handler func(*RBACHandler) http.HandlerFunc) *httptest.ResponseRecorder {
+	rbac := NewRBACHandler(&mocks.RoleUseCase{}, users, &mocks.APIKeyUseCase{}, h.manager)
+	router := chi.NewRouter()
+	router.MethodFunc(method, pattern, handler(rbac))
+
+	admin := &model.UserWithRoles{User: model.User{ID: "admin-1", Username: "admin", IsActive: true}}
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), userContextKey, admin))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestResetUserPasswordClosesTradingStreams(t *testing.T) {
+	h, _ := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+
+	var reset string
+	users := &mocks.UserUseCase{
+		ResetPasswordFunc: func(_ context.Context, actorID, id string) (string, error) {
+			reset = id
+			return "Temp-Password-1", nil
+		},
+	}
+	w := serveRBAC(h, users, http.MethodPost, "/users/{id}/reset-password", "/users/user-1/reset-password", "",
+		func(rbac *RBACHandler) http.HandlerFunc { return rbac.ResetUserPassword })
+	if w.Code != http.StatusOK || reset != "user-1" {
+		t.Fatalf("reset status = %d for %q, want %d for user-1: %s", w.Code, reset, http.StatusOK, w.Body)
+	}
+
+	closeErr, reason := client.expectClose()
+	if closeErr.Code != CloseCodeAuth || reason.Reason != CloseReasonPasswordReset {
+		t.Fatalf("close = %d %+v, want %d %s", closeErr.Code, reason, CloseCodeAuth, CloseReasonPasswordReset)
+	}
+}
+
+// tradingUser is user-1 of the trading harness holding the given roles
+func tradingUser(roleIDs ...string) *model.UserWithRoles {
+	user := &model.UserWithRoles{User: model.User{ID: "user-1", Username: "trader", IsActive: true}}
+	for _, id := range roleIDs {
+		user.Roles = append(user.Roles, model.Role{ID: id, Name: id})
+	}
+	return user
+}
+
+func TestUserChangesCloseTradingStreams(t *testing.T) {
+	update := func(body string) func(*mocks.UserUseCase) (string, string, string, string) {
+		return func(users *mocks.UserUseCase) (string, string, string, string) {
+			users.GetUserFunc = func(context.Context, string) (*model.UserWithRoles, error) {
+				return tradingUser("trader", "viewer"), nil
+			}
+			users.ValidateRolesFunc = func(context.Context, []string) error { return nil }
+			users.UpdateUserFunc = func(context.Context, *model.User) error { return nil }
+			users.SetRolesFunc = func(context.Context, string, []string) error { return nil }
+			return http.MethodPut, "/users/{id}", "/users/user-1", body
+		}
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(*mocks.UserUseCase) (method, pattern, target, body string)
+		handler    func(*RBACHandler) http.HandlerFunc
+		wantCode   int
+		wantReason string
+	}{
+		{
+			name:       "deactivated",
+			setup:      update(`{"username":"trader","is_active":false,"roles":["trader","viewer"]}`),
+			handler:    func(rbac *RBACHandler) http.HandlerFunc { return rbac.UpdateUser },
+			wantCode:   CloseCodeAuth,
+			wantReason: CloseReasonUserInactive,
+		},
+		{
+			name:       "role dropped by an update",
+			setup:      update(`{"username":"trader","is_active":true,"roles":["viewer","auditor"]}`),
+			handler:    func(rbac *RBACHandler) http.HandlerFunc { return rbac.UpdateUser },
+			wantCode:   CloseCodePermission,
+			wantReason: CloseReasonRolesChanged,
+		},
+		{
+			name: "role removed",
+			setup: func(users *mocks.UserUseCase) (string, string, string, string) {
+				users.RemoveRoleFunc = func(context.Context, string, string) error { return nil }
+				return http.MethodDelete, "/users/{id}/roles/{roleId}", "/users/user-1/roles/trader", ""
+			},
+			handler:    func(rbac *RBACHandler) http.HandlerFunc { return rbac.RemoveRole },
+			wantCode:   CloseCodePermission,
+			wantReason: CloseReasonRolesChanged,
+		},
+		{
+			name: "deleted",
+			setup: func(users *mocks.UserUseCase) (string, string, string, string) {
+				users.DeleteUserFunc = func(context.Context, string) error { return nil }
+				return http.MethodDelete, "/users/{id}", "/users/user-1", ""
+			},
+			handler:    func(rbac *RBACHandler) http.HandlerFunc { return rbac.DeleteUser },
+			wantCode:   CloseCodeAuth,
+			wantReason: CloseReasonUserDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newBinanceHarness(t)
+			client := h.dial(t)
+			client.connect(testBinanceKeyID)
+
+			users := &mocks.UserUseCase{}
+			method, pattern, target, body := tt.setup(users)
+			if w := serveRBAC(h, users, method, pattern, target, body, tt.handler); w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+			}
+
+			closeErr, reason := client.expectClose()
+			if closeErr.Code != tt.wantCode || reason.Reason != tt.wantReason {
+				t.Fatalf("close = %d %+v, want %d %s", closeErr.Code, reason, tt.wantCode, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestUserUpdateKeepingRolesLeavesTradingStreams(t *testing.T) {
+	h, _ := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+
+	users := &mocks.UserUseCase{
+		GetUserFunc: func(context.Context, string) (*model.UserWithRoles, error) {
+			return tradingUser("trader"), nil
+		},
+		ValidateRolesFunc: func(context.Context, []string) error { return nil },
+		UpdateUserFunc:    func(context.Context, *model.User) error { return nil },
+		SetRolesFunc:      func(context.Context, string, []string) error { return nil },
+	}
+	body := `{"username":"renamed","is_active":true,"roles":["trader","viewer"]}`
+	w := serveRBAC(h, users, http.MethodPut, "/users/{id}", "/users/user-1", body,
+		func(rbac *RBACHandler) http.HandlerFunc { return rbac.UpdateUser })
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// The connection still answers
+	client.subscribe(model.TradingWebSocketMessage{Type: "balance"})
+}
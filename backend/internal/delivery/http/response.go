@@ -3,10 +3,14 @@ package http
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/yanun0323/logs"
 )
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"` // machine readable reason, set where clients must tell errors apart
 }
 
 type SuccessResponse struct {
@@ -14,8 +18,23 @@ type SuccessResponse struct {
 	Data    any    `json:"data,omitempty"`
 }
 
+// ListResponse is a SuccessResponse for paginated lists, echoing the applied filters
+type ListResponse struct {
+	Message string `json:"message"`
+	Data    any    `json:"data"`
+	Total   int64  `json:"total"`
+	Filters any    `json:"filters,omitempty"`
+}
+
 func WriteJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
+
+// WriteError answers with a generic message and logs the underlying error with
+// the request ID, so driver or upstream details never reach the client
+func WriteError(w http.ResponseWriter, r *http.Request, status int, message string, err error) {
+	logs.Errorf("%s %s failed (request_id=%s): %s: %v", r.Method, r.URL.Path, middleware.GetReqID(r.Context()), message, err)
+	WriteJSON(w, status, ErrorResponse{Error: message})
+}
@@ -2,12 +2,14 @@ package http
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
 	"control_page/internal/adaptor"
+	"control_page/internal/model"
 	"control_page/internal/model/enum"
 )
 
@@ -19,9 +21,19 @@ type Router struct {
 	switcherHandler      *SwitcherHandler
 	settingHandler       *SettingHandler
 	btccProxyHandler     *BTCCProxyHandler
+	tradingHandler       *TradingHandler
+	preferencesHandler   *PreferencesHandler
+	alertHandler         *AlertHandler
+	notificationHandler  *NotificationHandler
+	metaHandler          *MetaHandler
+	logLevelHandler      *LogLevelHandler
+	openAPIHandler       *OpenAPIHandler
 	wsManager            *BinanceStreamManager
 	tradingStreamManager *TradingStreamManager
 	authMiddleware       *AuthMiddleware
+	requestTimeouts      RequestTimeouts
+	basePath             string
+	trustedProxyHeader   string
 }
 
 func NewRouter(
@@ -31,40 +43,82 @@ func NewRouter(
 	userUseCase adaptor.UserUseCase,
 	apiKeyUseCase adaptor.APIKeyUseCase,
 	apiKeyRepo adaptor.APIKeyRepository,
+	auditRepo adaptor.AuditRepository,
 	switcherUseCase adaptor.SwitcherUseCase,
 	settingUseCase adaptor.SettingUseCase,
+	preferencesUseCase adaptor.PreferencesUseCase,
 	binanceURL string,
+	enforceTokenExpiry bool,
+	exchangeIdleTimeout time.Duration,
+	exchangeLimits ExchangeConnLimits,
+	exchangeEndpoints *model.ExchangeEndpoints,
+	rawBalanceEvents bool,
+	recordingUseCase adaptor.RecordingUseCase,
+	orderEventUseCase adaptor.OrderEventUseCase,
+	tradingReportUseCase adaptor.TradingReportUseCase,
+	marketStateUseCase adaptor.MarketStateUseCase,
+	alertUseCase adaptor.AlertUseCase,
+	notificationUseCase adaptor.NotificationUseCase,
+	clientSendQueue int,
+	maxWebSocketClients int,
+	requestTimeouts RequestTimeouts,
+	basePath string,
+	trustedProxyHeader string,
 ) *Router {
+	// One limiter across both managers so the cap covers every WebSocket client
+	limiter := newClientLimiter(maxWebSocketClients)
+	requestTimeouts.basePath = basePath
+	wsManager := NewBinanceStreamManager(binanceURL, limiter)
+	tradingStreamManager := NewTradingStreamManager(apiKeyUseCase, authUseCase, apiKeyRepo, enforceTokenExpiry, exchangeIdleTimeout, exchangeLimits, exchangeEndpoints, rawBalanceEvents, recordingUseCase, orderEventUseCase, alertUseCase, notificationUseCase, clientSendQueue, limiter)
+
 	return &Router{
 		authHandler:          NewAuthHandler(authUseCase),
 		klineHandler:         NewKlineHandler(klineUseCase),
-		rbacHandler:          NewRBACHandler(roleUseCase, userUseCase),
-		apiKeyHandler:        NewAPIKeyHandler(apiKeyUseCase),
+		rbacHandler:          NewRBACHandler(roleUseCase, userUseCase, apiKeyUseCase, tradingStreamManager),
+		apiKeyHandler:        NewAPIKeyHandler(apiKeyUseCase, tradingStreamManager),
 		switcherHandler:      NewSwitcherHandler(switcherUseCase),
 		settingHandler:       NewSettingHandler(settingUseCase),
 		btccProxyHandler:     NewBTCCProxyHandler(),
-		wsManager:            NewBinanceStreamManager(binanceURL),
-		tradingStreamManager: NewTradingStreamManager(apiKeyUseCase, authUseCase, apiKeyRepo),
+		tradingHandler:       NewTradingHandler(tradingStreamManager, wsManager, recordingUseCase, orderEventUseCase, tradingReportUseCase, marketStateUseCase, auditRepo),
+		preferencesHandler:   NewPreferencesHandler(preferencesUseCase),
+		alertHandler:         NewAlertHandler(alertUseCase),
+		notificationHandler:  NewNotificationHandler(notificationUseCase),
+		metaHandler:          NewMetaHandler(),
+		logLevelHandler:      NewLogLevelHandler(),
+		openAPIHandler:       NewOpenAPIHandler(basePath),
+		wsManager:            wsManager,
+		tradingStreamManager: tradingStreamManager,
 		authMiddleware:       NewAuthMiddleware(authUseCase),
+		requestTimeouts:      requestTimeouts,
+		basePath:             basePath,
+		trustedProxyHeader:   trustedProxyHeader,
 	}
 }
 
 func (rt *Router) Setup() *chi.Mux {
-	r := chi.NewRouter()
+	root := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(cors.Handler(cors.Options{
+	root.Use(middleware.Logger)
+	root.Use(middleware.Recoverer)
+	root.Use(middleware.RequestID)
+	root.Use(ResolveClientIP(rt.trustedProxyHeader))
+	root.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:8888"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Every route lives under the configured base path, if any
+	r := root
+	if rt.basePath != "" {
+		r = chi.NewRouter()
+		root.Mount(rt.basePath, r)
+	}
+
 	// Health check
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
@@ -72,6 +126,12 @@ func (rt *Router) Setup() *chi.Mux {
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
+		// Per-route-group deadline; the WebSocket routes below live outside /api
+		r.Use(rt.requestTimeouts.Middleware)
+
+		// Generated API description (public so clients can be generated from it)
+		r.Get("/openapi.json", rt.openAPIHandler.Spec)
+
 		// Auth routes (public + protected)
 		r.Route("/auth", func(r chi.Router) {
 			// Public
@@ -82,31 +142,47 @@ func (rt *Router) Setup() *chi.Mux {
 			r.Group(func(r chi.Router) {
 				r.Use(rt.authMiddleware.Authenticate)
 
+				// Reachable while a password change is pending
 				r.Get("/me", rt.authHandler.Me)
+				r.Get("/me/logins", rt.authHandler.MyLogins)
 				r.Post("/change-password", rt.authHandler.ChangePassword)
 
-				// Registration flows (only admins with manage:users)
 				r.Group(func(r chi.Router) {
-					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionManageUsers))
-					r.Post("/register", rt.authHandler.Register)
-					r.Post("/activate", rt.authHandler.ActivateAccount)
-				})
+					r.Use(rt.authMiddleware.RequirePasswordChanged)
 
-				// 2FA rebind routes
-				r.Post("/totp/rebind", rt.authHandler.SetupTOTPRebind)
-				r.Post("/totp/rebind/confirm", rt.authHandler.ConfirmTOTPRebind)
-				r.Post("/totp/rebind/cancel", rt.authHandler.CancelTOTPRebind)
+					r.Get("/me/preferences", rt.preferencesHandler.Get)
+					r.Put("/me/preferences", rt.preferencesHandler.Put)
+					r.Get("/me/devices", rt.authHandler.MyTrustedDevices)
+					r.Delete("/me/devices/{deviceId}", rt.authHandler.RevokeMyTrustedDevice)
+
+					// Registration flows (only admins with manage:users)
+					r.Group(func(r chi.Router) {
+						r.Use(rt.authMiddleware.RequirePermission(enum.PermissionManageUsers))
+						r.Post("/register", rt.authHandler.Register)
+						r.Post("/activate", rt.authHandler.ActivateAccount)
+					})
+
+					// 2FA rebind routes
+					r.Post("/totp/rebind", rt.authHandler.SetupTOTPRebind)
+					r.Post("/totp/rebind/confirm", rt.authHandler.ConfirmTOTPRebind)
+					r.Post("/totp/rebind/cancel", rt.authHandler.CancelTOTPRebind)
+				})
 			})
 		})
 
 		// Protected routes (requires authentication)
 		r.Group(func(r chi.Router) {
 			r.Use(rt.authMiddleware.Authenticate)
+			r.Use(rt.authMiddleware.RequirePasswordChanged)
+
+			// Routes the current user can call, for building frontend menus
+			r.Get("/meta/routes", rt.metaHandler.Routes)
 
 			// Kline routes (require view:kline permission)
 			r.Route("/kline", func(r chi.Router) {
 				r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewKline))
 				r.Get("/symbols", rt.klineHandler.GetSymbols)
+				r.Get("/symbols/search", rt.klineHandler.SearchSymbols)
 				r.Get("/intervals", rt.klineHandler.GetIntervals)
 			})
 
@@ -116,6 +192,46 @@ func (rt *Router) Setup() *chi.Mux {
 				r.Get("/markets", rt.btccProxyHandler.GetMarketList)
 			})
 
+			// Trading stream routes
+			r.Route("/trading", func(r chi.Router) {
+				// Stream metrics and recorded market data (require view:dashboard permission)
+				r.Group(func(r chi.Router) {
+					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewDashboard))
+					r.Get("/status", rt.tradingHandler.Status)
+					r.Get("/recorded", rt.tradingHandler.Recorded)
+				})
+
+				// Persisted order updates, reports and market state (require view:trading permission)
+				r.Group(func(r chi.Router) {
+					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewTrading))
+					r.Get("/{apiKeyId}/order-events", rt.tradingHandler.OrderEvents)
+					r.Get("/{apiKeyId}/report", rt.tradingHandler.Report)
+					r.Get("/{apiKeyId}/state", rt.tradingHandler.MarketState)
+				})
+
+				// Connected WebSocket clients (require view:sessions permission)
+				r.Group(func(r chi.Router) {
+					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewSessions))
+					r.Get("/sessions", rt.tradingHandler.Sessions)
+				})
+
+				// Force-disconnect clients (require manage:sessions permission)
+				r.Group(func(r chi.Router) {
+					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionManageSessions))
+					r.Post("/disconnect", rt.tradingHandler.Disconnect)
+				})
+
+				// Alert rules (require manage:settings permission)
+				r.Route("/alerts", func(r chi.Router) {
+					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionManageSettings))
+					r.Get("/", rt.alertHandler.List)
+					r.Post("/", rt.alertHandler.Create)
+					r.Get("/{id}", rt.alertHandler.Get)
+					r.Put("/{id}", rt.alertHandler.Update)
+					r.Delete("/{id}", rt.alertHandler.Delete)
+				})
+			})
+
 			// RBAC routes
 			r.Route("/rbac", func(r chi.Router) {
 				// Roles (require manage:roles)
@@ -126,6 +242,7 @@ func (rt *Router) Setup() *chi.Mux {
 					r.Get("/roles/{id}", rt.rbacHandler.GetRole)
 					r.Put("/roles/{id}", rt.rbacHandler.UpdateRole)
 					r.Delete("/roles/{id}", rt.rbacHandler.DeleteRole)
+					r.Get("/roles/{id}/users", rt.rbacHandler.GetRoleUsers)
 					r.Put("/roles/{id}/permissions", rt.rbacHandler.SetRolePermissions)
 					r.Get("/permissions", rt.rbacHandler.GetAllPermissions)
 				})
@@ -141,6 +258,13 @@ func (rt *Router) Setup() *chi.Mux {
 					r.Post("/users/{id}/roles", rt.rbacHandler.AssignRole)
 					r.Delete("/users/{id}/roles/{roleId}", rt.rbacHandler.RemoveRole)
 					r.Post("/users/{id}/totp/reset", rt.rbacHandler.ResetUserTOTP)
+					r.Post("/users/{id}/unlock", rt.rbacHandler.UnlockUser)
+					r.Post("/users/{id}/reset-password", rt.rbacHandler.ResetUserPassword)
+					r.Get("/users/{id}/ip-allowlist", rt.rbacHandler.GetIPAllowlist)
+					r.Put("/users/{id}/ip-allowlist", rt.rbacHandler.SetIPAllowlist)
+					r.Get("/users/{id}/logins", rt.authHandler.UserLogins)
+					r.Get("/users/{id}/devices", rt.authHandler.UserTrustedDevices)
+					r.Delete("/users/{id}/devices/{deviceId}", rt.authHandler.RevokeUserTrustedDevice)
 				})
 			})
 
@@ -151,6 +275,7 @@ func (rt *Router) Setup() *chi.Mux {
 					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewAPIKeys))
 					r.Get("/", rt.apiKeyHandler.List)
 					r.Get("/platforms", rt.apiKeyHandler.GetPlatforms)
+					r.Get("/platforms/capabilities", rt.apiKeyHandler.GetPlatformCapabilities)
 					r.Get("/{id}", rt.apiKeyHandler.Get)
 				})
 				// Manage routes (require manage:api_keys permission)
@@ -159,6 +284,8 @@ func (rt *Router) Setup() *chi.Mux {
 					r.Post("/", rt.apiKeyHandler.Create)
 					r.Put("/{id}", rt.apiKeyHandler.Update)
 					r.Delete("/{id}", rt.apiKeyHandler.Delete)
+					r.Post("/{id}/rotate", rt.apiKeyHandler.Rotate)
+					r.Post("/{id}/rollback", rt.apiKeyHandler.Rollback)
 				})
 			})
 
@@ -187,12 +314,15 @@ func (rt *Router) Setup() *chi.Mux {
 					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionViewSettings))
 					r.Get("/", rt.settingHandler.List)
 					r.Get("/search", rt.settingHandler.GetByBaseQuote)
+					r.Get("/log-level", rt.logLevelHandler.Get)
 					r.Get("/{id}", rt.settingHandler.Get)
 				})
 				// Manage routes (require manage:settings permission)
 				r.Group(func(r chi.Router) {
 					r.Use(rt.authMiddleware.RequirePermission(enum.PermissionManageSettings))
 					r.Post("/", rt.settingHandler.Create)
+					r.Put("/log-level", rt.logLevelHandler.Update)
+					r.Post("/notifications/test", rt.notificationHandler.Test)
 					r.Put("/{id}", rt.settingHandler.Update)
 					r.Put("/{id}/parameters/{strategy}", rt.settingHandler.UpdateParameters)
 					r.Delete("/{id}", rt.settingHandler.Delete)
@@ -201,11 +331,14 @@ func (rt *Router) Setup() *chi.Mux {
 		})
 	})
 
-	// WebSocket routes (handled separately, auth via query param)
-	r.Get("/ws/kline", rt.wsManager.HandleWebSocket)
-	r.Get("/ws/trading", rt.tradingStreamManager.HandleWebSocket)
+	// WebSocket routes (handled separately, auth via query param); they live
+	// for hours, so the server write deadline is lifted. /ws/kline is anonymous;
+	// /ws/trading checks view:trading itself, at upgrade and on reauth
+	r.With(NoWriteDeadline).Get("/ws/kline", rt.wsManager.HandleWebSocket)
+	r.With(NoWriteDeadline, rt.authMiddleware.Describe(enum.PermissionViewTrading)).Get("/ws/trading", rt.tradingStreamManager.HandleWebSocket)
 
-	return r
+	rt.metaHandler.setRouter(root)
+	return root
 }
 
 func (rt *Router) Close() {
@@ -35,6 +35,10 @@ func (h *SettingHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	setting, err := h.settingUseCase.GetByID(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrSettingNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "setting not found"})
 		} else {
@@ -68,6 +72,23 @@ func (h *SettingHandler) GetByBaseQuote(w http.ResponseWriter, r *http.Request)
 	WriteJSON(w, http.StatusOK, SuccessResponse{Data: setting})
 }
 
+// ParametersErrorResponse is the 400 body listing invalid setting parameters
+type ParametersErrorResponse struct {
+	Error  string                   `json:"error"`
+	Fields []usecase.ParameterError `json:"fields"`
+}
+
+// writeParametersError answers 400 with the invalid fields if err is a
+// *usecase.ParametersError, reporting whether it did
+func writeParametersError(w http.ResponseWriter, err error) bool {
+	var paramsErr *usecase.ParametersError
+	if !errors.As(err, &paramsErr) {
+		return false
+	}
+	WriteJSON(w, http.StatusBadRequest, ParametersErrorResponse{Error: "invalid parameters", Fields: paramsErr.Fields})
+	return true
+}
+
 func (h *SettingHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req model.CreateSettingRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -75,8 +96,17 @@ func (h *SettingHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	setting, err := h.settingUseCase.Create(r.Context(), &req)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	setting, err := h.settingUseCase.Create(r.Context(), actor.ID, &req)
 	if err != nil {
+		if writeParametersError(w, err) {
+			return
+		}
 		switch {
 		case errors.Is(err, usecase.ErrSettingBaseEmpty):
 			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "base is required"})
@@ -105,9 +135,20 @@ func (h *SettingHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	setting, err := h.settingUseCase.Update(r.Context(), id, &req)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	setting, err := h.settingUseCase.Update(r.Context(), actor.ID, id, &req)
 	if err != nil {
+		if writeParametersError(w, err) {
+			return
+		}
 		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
 		case errors.Is(err, usecase.ErrSettingNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "setting not found"})
 		case errors.Is(err, usecase.ErrSettingBaseEmpty):
@@ -143,8 +184,21 @@ func (h *SettingHandler) UpdateParameters(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	setting, err := h.settingUseCase.UpdateParameters(r.Context(), id, strategy, req.Parameters)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	setting, err := h.settingUseCase.UpdateParameters(r.Context(), actor.ID, id, strategy, req.Parameters)
 	if err != nil {
+		if writeParametersError(w, err) {
+			return
+		}
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrSettingNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "setting not found"})
 		} else {
@@ -164,6 +218,10 @@ func (h *SettingHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	err := h.settingUseCase.Delete(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrSettingNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "setting not found"})
 		} else {
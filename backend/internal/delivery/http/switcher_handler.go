@@ -35,6 +35,10 @@ func (h *SwitcherHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	switcher, err := h.switcherUseCase.GetByID(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrSwitcherNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "switcher not found"})
 		} else {
@@ -53,9 +57,20 @@ func (h *SwitcherHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switcher, err := h.switcherUseCase.Create(r.Context(), &req)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	switcher, err := h.switcherUseCase.Create(r.Context(), actor.ID, &req)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to create switcher"})
+		switch {
+		case errors.Is(err, usecase.ErrSwitcherPairsEmpty), errors.Is(err, usecase.ErrSwitcherInvalidPair):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to create switcher"})
+		}
 		return
 	}
 
@@ -74,11 +89,22 @@ func (h *SwitcherHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switcher, err := h.switcherUseCase.Update(r.Context(), id, &req)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	switcher, err := h.switcherUseCase.Update(r.Context(), actor.ID, id, &req)
 	if err != nil {
-		if errors.Is(err, usecase.ErrSwitcherNotFound) {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrSwitcherNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "switcher not found"})
-		} else {
+		case errors.Is(err, usecase.ErrSwitcherPairsEmpty), errors.Is(err, usecase.ErrSwitcherInvalidPair):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to update switcher"})
 		}
 		return
@@ -105,11 +131,22 @@ func (h *SwitcherHandler) UpdatePair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	switcher, err := h.switcherUseCase.UpdatePair(r.Context(), id, pair, req.Enable)
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	switcher, err := h.switcherUseCase.UpdatePair(r.Context(), actor.ID, id, pair, req.Enable)
 	if err != nil {
-		if errors.Is(err, usecase.ErrSwitcherNotFound) {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrSwitcherNotFound):
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "switcher not found"})
-		} else {
+		case errors.Is(err, usecase.ErrSwitcherInvalidPair):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
 			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to update pair"})
 		}
 		return
@@ -126,6 +163,10 @@ func (h *SwitcherHandler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	err := h.switcherUseCase.Delete(r.Context(), id)
 	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidID) {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+			return
+		}
 		if errors.Is(err, usecase.ErrSwitcherNotFound) {
 			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "switcher not found"})
 		} else {
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yanun0323/logs"
+)
+
+// writeDeadlineGrace is how long after a route's timeout its 504 may still be written
+const writeDeadlineGrace = 5 * time.Second
+
+// RequestTimeouts bounds how long /api handlers may run. Routes overrides
+// Default per path prefix (e.g. /api/btcc); the longest matching prefix wins
+// and a zero or negative timeout exempts the route group.
+type RequestTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+
+	// basePath is stripped from request paths before matching Routes
+	basePath string
+}
+
+func (t RequestTimeouts) forPath(path string) time.Duration {
+	path = strings.TrimPrefix(path, t.basePath)
+	timeout, matched := t.Default, ""
+	for prefix, d := range t.Routes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if len(prefix) <= len(matched) {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			timeout, matched = d, prefix
+		}
+	}
+	return timeout
+}
+
+// Middleware cancels the request context once the route's timeout elapses, so
+// repository and upstream calls abort, and answers 504 unless the handler had
+// already started its response. The server write deadline is moved past the
+// route's timeout, or cleared for exempt groups.
+func (t RequestTimeouts) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := t.forPath(r.URL.Path)
+		if timeout <= 0 {
+			setWriteDeadline(w, time.Time{})
+			next.ServeHTTP(w, r)
+			return
+		}
+		setWriteDeadline(w, time.Now().Add(timeout+writeDeadlineGrace))
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+
+		if tw.timedOut || (!tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			WriteJSON(w, http.StatusGatewayTimeout, ErrorResponse{Error: "request timed out"})
+		}
+	})
+}
+
+// NoWriteDeadline clears the server write deadline for long-lived responses
+// such as WebSocket upgrades, which would otherwise be cut off after the
+// server's WriteTimeout
+func NoWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setWriteDeadline(w, time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setWriteDeadline changes the write deadline of w's connection; a zero time
+// removes it
+func setWriteDeadline(w http.ResponseWriter, deadline time.Time) {
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+		logs.Warnf("set write deadline error: %v", err)
+	}
+}
+
+// timeoutWriter drops a response that is only started after the deadline,
+// which is usually the 500 a handler writes for its canceled repository call
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
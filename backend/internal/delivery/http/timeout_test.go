@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newWriteTimeoutServer serves handler with a server write timeout of writeTimeout
+func newWriteTimeoutServer(t *testing.T, handler http.Handler, writeTimeout time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// slowHandler answers after delay, or gives up once the request is canceled
+func slowHandler(delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		case <-r.Context().Done():
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "canceled"})
+		}
+	})
+}
+
+func getStatus(t *testing.T, url string) (int, error) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func TestServerWriteTimeoutCutsSlowResponses(t *testing.T) {
+	srv := newWriteTimeoutServer(t, slowHandler(200*time.Millisecond), 50*time.Millisecond)
+
+	if _, err := getStatus(t, srv.URL); err == nil {
+		t.Fatal("GET succeeded past the server write timeout")
+	}
+}
+
+func TestNoWriteDeadlineLiftsServerWriteTimeout(t *testing.T) {
+	srv := newWriteTimeoutServer(t, NoWriteDeadline(slowHandler(200*time.Millisecond)), 50*time.Millisecond)
+
+	status, err := getStatus(t, srv.URL)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestRequestTimeoutsMiddleware(t *testing.T) {
+	timeouts := RequestTimeouts{
+		Default: 100 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"/api/slow":   time.Second,
+			"/api/export": 0,
+		},
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "default deadline hit", path: "/api/other", want: http.StatusGatewayTimeout},
+		{name: "longer group past the server write timeout", path: "/api/slow", want: http.StatusOK},
+		{name: "exempt group past the server write timeout", path: "/api/export", want: http.StatusOK},
+	}
+
+	srv := newWriteTimeoutServer(t, timeouts.Middleware(slowHandler(300*time.Millisecond)), 150*time.Millisecond)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == http.StatusGatewayTimeout {
+				var body ErrorResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+					t.Fatalf("504 body = %+v (%v), want an error message", body, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yanun0323/logs"
+
+	"control_page/internal/model"
+)
+
+// checkAlerts feeds the spread of orderbook frames and the last price of
+// trade frames to the alert rules, and sends any resulting alert to every
+// client attached to the exchange connection. Diff frames only carry changed
+// levels, so spreads come from partial and maintained-book frames.
+func (m *TradingStreamManager) checkAlerts(ec *ExchangeConnection, response model.TradingWebSocketResponse) {
+	if m.alerts == nil || response.Symbol == "" {
+		return
+	}
+
+	var (
+		condition model.AlertCondition
+		value     float64
+		ok        bool
+	)
+	switch response.Type {
+	case "orderbook":
+		if response.Mode == depthModeDiff {
+			return
+		}
+		condition = model.AlertConditionSpreadAbove
+		value, ok = orderBookSpread(response.Data)
+	case "trades":
+		condition = model.AlertConditionPriceOutside
+		value, ok = lastTradePrice(response.Data)
+	}
+	if !ok {
+		return
+	}
+
+	for _, alert := range m.alerts.Evaluate(ec.APIKeyID, response.Symbol, condition, value, time.UnixMilli(response.Timestamp)) {
+		m.sendAlert(ec, alert)
+	}
+}
+
+func (m *TradingStreamManager) sendAlert(ec *ExchangeConnection, alert model.Alert) {
+	payload, err := json.Marshal(model.TradingWebSocketResponse{
+		Type:      "alert",
+		Data:      alert,
+		Platform:  ec.Platform.String(),
+		Symbol:    alert.Symbol,
+		Timestamp: alert.Time.UnixMilli(),
+	})
+	if err != nil {
+		logs.Errorf("marshal alert error: %v", err)
+		return
+	}
+
+	ec.mu.RLock()
+	clients := make([]*websocket.Conn, 0, len(ec.Clients))
+	for client := range ec.Clients {
+		clients = append(clients, client)
+	}
+	ec.mu.RUnlock()
+
+	for _, client := range clients {
+		m.sendRaw(client, payload)
+	}
+}
+
+func orderBookSpread(data interface{}) (float64, bool) {
+	ob, ok := data.(*model.OrderBook)
+	if !ok || ob == nil || ob.Spread == "" {
+		return 0, false
+	}
+	spread, err := strconv.ParseFloat(ob.Spread, 64)
+	return spread, err == nil
+}
+
+// lastTradePrice reads the price of a Binance trade event ({"p": "..."}) or
+// of the newest deal of a BTCC deals.update ([{"price": "..."}, ...])
+func lastTradePrice(data interface{}) (float64, bool) {
+	var price string
+	switch d := data.(type) {
+	case map[string]interface{}:
+		price, _ = d["p"].(string)
+	case json.RawMessage:
+		var deals []struct {
+			Price string `json:"price"`
+		}
+		if err := json.Unmarshal(d, &deals); err != nil || len(deals) == 0 {
+			return 0, false
+		}
+		price = deals[0].Price
+	}
+	if price == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(price, 64)
+	return v, err == nil && v > 0
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+// alertObservation is one value handed to AlertUseCase.Evaluate
+type alertObservation struct {
+	apiKeyID  string
+	symbol    string
+	condition model.AlertCondition
+	value     float64
+	at        time.Time
+}
+
+// recordingAlerts records the observations of checkAlerts without firing any rule
+type recordingAlerts struct {
+	adaptor.AlertUseCase
+	observations []alertObservation
+}
+
+func (a *recordingAlerts) Evaluate(apiKeyID, symbol string, condition model.AlertCondition, value float64, at time.Time) []model.Alert {
+	a.observations = append(a.observations, alertObservation{apiKeyID, symbol, condition, value, at})
+	return nil
+}
+
+func TestCheckAlertsObservations(t *testing.T) {
+	const ts = 1700000000000
+
+	tests := []struct {
+		name     string
+		response model.TradingWebSocketResponse
+		want     *alertObservation
+	}{
+		{
+			name: "partial book spread",
+			response: model.TradingWebSocketResponse{
+				Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModePartial, Timestamp: ts,
+				Data: &model.OrderBook{Spread: "12.5"},
+			},
+			want: &alertObservation{condition: model.AlertConditionSpreadAbove, value: 12.5},
+		},
+		{
+			name: "diff frames carry no spread",
+			response: model.TradingWebSocketResponse{
+				Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeDiff, Timestamp: ts,
+				Data: &model.OrderBook{Spread: "12.5"},
+			},
+		},
+		{
+			name: "one-sided book",
+			response: model.TradingWebSocketResponse{
+				Type: "orderbook", Symbol: "BTCUSDT", Timestamp: ts,
+				Data: &model.OrderBook{},
+			},
+		},
+		{
+			name: "binance trade price",
+			response: model.TradingWebSocketResponse{
+				Type: "trades", Symbol: "BTCUSDT", Timestamp: ts,
+				Data: map[string]interface{}{"p": "43000.10", "q": "0.5"},
+			},
+			want: &alertObservation{condition: model.AlertConditionPriceOutside, value: 43000.10},
+		},
+		{
+			name: "btcc newest deal price",
+			response: model.TradingWebSocketResponse{
+				Type: "trades", Symbol: "BTCUSDT", Timestamp: ts,
+				Data: json.RawMessage(`[{"price":"43001.5"},{"price":"42000"}]`),
+			},
+			want: &alertObservation{condition: model.AlertConditionPriceOutside, value: 43001.5},
+		},
+		{
+			name: "btcc empty deals",
+			response: model.TradingWebSocketResponse{
+				Type: "trades", Symbol: "BTCUSDT", Timestamp: ts,
+				Data: json.RawMessage(`[]`),
+			},
+		},
+		{
+			name: "unwatched frame type",
+			response: model.TradingWebSocketResponse{
+				Type: "ticker", Symbol: "BTCUSDT", Timestamp: ts,
+				Data: map[string]interface{}{"c": "43000"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := &recordingAlerts{}
+			m := &TradingStreamManager{alerts: alerts}
+			m.checkAlerts(&ExchangeConnection{APIKeyID: "key-1", Platform: model.PlatformBinance}, tt.response)
+
+			if tt.want == nil {
+				if len(alerts.observations) != 0 {
+					t.Fatalf("observations = %+v, want none", alerts.observations)
+				}
+				return
+			}
+			if len(alerts.observations) != 1 {
+				t.Fatalf("observations = %+v, want one", alerts.observations)
+			}
+			got := alerts.observations[0]
+			if got.apiKeyID != "key-1" || got.symbol != "BTCUSDT" || got.condition != tt.want.condition ||
+				got.value != tt.want.value || !got.at.Equal(time.UnixMilli(ts)) {
+				t.Fatalf("observation = %+v, want %s %g for key-1 BTCUSDT at %d", got, tt.want.condition, tt.want.value, ts)
+			}
+		})
+	}
+}
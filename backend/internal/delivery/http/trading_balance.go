@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"sort"
+
+	"control_page/internal/model"
+)
+
+// parseBinanceBalances normalizes the "B" array of a Binance outboundAccountPosition event:
+// {"e":"outboundAccountPosition","B":[{"a":"BTC","f":"1.5","l":"0.1"}]}
+func parseBinanceBalances(data map[string]interface{}) []model.Balance {
+	entries, _ := data["B"].([]interface{})
+	balances := make([]model.Balance, 0, len(entries))
+	for _, entry := range entries {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		asset, _ := item["a"].(string)
+		if asset == "" {
+			continue
+		}
+		free, _ := item["f"].(string)
+		locked, _ := item["l"].(string)
+		balances = append(balances, model.Balance{Asset: asset, Free: free, Locked: locked})
+	}
+	return balances
+}
+
+// btccAssetAmount is one asset of a BTCC asset.update push; the locked amount
+// is reported as "frozen" or "freeze" depending on the API version
+type btccAssetAmount struct {
+	Available string `json:"available"`
+	Frozen    string `json:"frozen"`
+	Freeze    string `json:"freeze"`
+}
+
+// parseBTCCBalances normalizes the params of a BTCC asset.update push, a list of
+// asset maps ([{"BTC":{"available":"1.5","frozen":"0.1"}}]) or a single map
+func parseBTCCBalances(params json.RawMessage) ([]model.Balance, error) {
+	var updates []map[string]btccAssetAmount
+	if err := json.Unmarshal(params, &updates); err != nil {
+		var update map[string]btccAssetAmount
+		if err := json.Unmarshal(params, &update); err != nil {
+			return nil, err
+		}
+		updates = []map[string]btccAssetAmount{update}
+	}
+
+	balances := make([]model.Balance, 0)
+	for _, update := range updates {
+		for asset, amount := range update {
+			locked := amount.Frozen
+			if locked == "" {
+				locked = amount.Freeze
+			}
+			balances = append(balances, model.Balance{Asset: asset, Free: amount.Available, Locked: locked})
+		}
+	}
+	// Map iteration order is random, keep frames stable for the client
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+	return balances, nil
+}
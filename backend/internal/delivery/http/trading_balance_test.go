@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"control_page/internal/model"
+)
+
+func TestParseBinanceBalances(t *testing.T) {
+	var event map[string]interface{}
+	payload := `{"e":"outboundAccountPosition","E":1564034571105,"u":1564034571073,"B":[` +
+		`{"a":"ETH","f":"10000.000000","l":"0.000000"},` +
+		`{"a":"BTC","f":"1.5","l":"0.1"},` +
+		`{"f":"1"},"junk"]}`
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	want := []model.Balance{
+		{Asset: "ETH", Free: "10000.000000", Locked: "0.000000"},
+		{Asset: "BTC", Free: "1.5", Locked: "0.1"},
+	}
+	if got := parseBinanceBalances(event); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseBinanceBalances() = %+v, want %+v", got, want)
+	}
+	if got := parseBinanceBalances(map[string]interface{}{"e": "outboundAccountPosition"}); got == nil || len(got) != 0 {
+		t.Fatalf("parseBinanceBalances(no B) = %#v, want an empty list", got)
+	}
+}
+
+func TestParseBTCCBalances(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    []model.Balance
+		wantErr bool
+	}{
+		{
+			name:   "list of asset maps",
+			params: `[{"USDT":{"available":"500.25","frozen":"20"},"BTC":{"available":"1.5","frozen":"0.1"}}]`,
+			want: []model.Balance{
+				{Asset: "BTC", Free: "1.5", Locked: "0.1"},
+				{Asset: "USDT", Free: "500.25", Locked: "20"},
+			},
+		},
+		{
+			name:   "single map with freeze",
+			params: `{"ETH":{"available":"3","freeze":"0.5"}}`,
+			want:   []model.Balance{{Asset: "ETH", Free: "3", Locked: "0.5"}},
+		},
+		{
+			name:   "empty list",
+			params: `[]`,
+			want:   []model.Balance{},
+		},
+		{
+			name:    "malformed",
+			params:  `"BTC"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBTCCBalances(json.RawMessage(tt.params))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBTCCBalances() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseBTCCBalances() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTradingBinanceBalance(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "balance"})
+	waitFor(t, "the user data stream", func(ctx context.Context) error {
+		return exchange.WaitUserStream(ctx)
+	})
+
+	exchange.PushUserEvent(map[string]any{
+		"e": "outboundAccountPosition",
+		"E": time.Now().UnixMilli(),
+		"B": []map[string]any{{"a": "BTC", "f": "1.5", "l": "0.1"}},
+	})
+
+	frame := client.expect("balance")
+	if frame.Platform != model.PlatformBinance.String() {
+		t.Fatalf("balance platform = %s, want %s", frame.Platform, model.PlatformBinance)
+	}
+	var balances []model.Balance
+	frame.decode(t, &balances)
+	if want := []model.Balance{{Asset: "BTC", Free: "1.5", Locked: "0.1"}}; !reflect.DeepEqual(balances, want) {
+		t.Fatalf("balances = %+v, want %+v", balances, want)
+	}
+	// Raw balance events are off in the harness
+	client.expectNone("account", 200*time.Millisecond)
+}
+
+func TestTradingBTCCBalance(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "balance"})
+	waitFor(t, "the asset subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "asset")
+	})
+
+	exchange.PushAsset(map[string]map[string]string{
+		"USDT": {"available": "500.25", "frozen": "20"},
+		"BTC":  {"available": "1.5", "frozen": "0.1"},
+	})
+
+	frame := client.expect("balance")
+	if frame.Platform != model.PlatformBTCC.String() {
+		t.Fatalf("balance platform = %s, want %s", frame.Platform, model.PlatformBTCC)
+	}
+	var balances []model.Balance
+	frame.decode(t, &balances)
+	want := []model.Balance{
+		{Asset: "BTC", Free: "1.5", Locked: "0.1"},
+		{Asset: "USDT", Free: "500.25", Locked: "20"},
+	}
+	if !reflect.DeepEqual(balances, want) {
+		t.Fatalf("balances = %+v, want %+v", balances, want)
+	}
+	client.expectNone("asset", 200*time.Millisecond)
+}
+
+func TestTradingRawAssetSubscriptionDisabled(t *testing.T) {
+	h, _ := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.send(model.TradingWebSocketMessage{Action: "subscribe", Type: "asset"})
+
+	frame := client.expect("error")
+	if frame.Error != "asset subscription is disabled, subscribe to balance instead" {
+		t.Fatalf("error = %q, want the disabled asset subscription", frame.Error)
+	}
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/gorilla/websocket"
+)
+
+// Close codes sent to /ws/trading and /ws/kline clients so they can tell why
+// the server closed the connection. 4xxx codes mirror the matching HTTP statuses.
+const (
+	CloseCodeNormal      = websocket.CloseNormalClosure  // 1000
+	CloseCodeShutdown    = websocket.CloseGoingAway      // 1001
+	CloseCodeRestart     = websocket.CloseServiceRestart // 1012: reconnect to pick up changed settings
+	CloseCodeProtocol    = 4400                          // handshake skipped or unsupported protocol version
+	CloseCodeAuth        = 4401                          // token missing, expired or rejected
+	CloseCodePermission  = 4403                          // authenticated but not allowed
+	CloseCodeRateLimited = 4429                          // client exceeded a rate limit
+)
+
+// Close reasons carried in the JSON close payload
+const (
+	CloseReasonClientGone      = "client_gone"
+	CloseReasonShutdown        = "server_shutdown"
+	CloseReasonTokenExpired    = "token_expired"
+	CloseReasonReauthFailed    = "reauth_failed"
+	CloseReasonAdminDisconnect = "disconnected_by_admin"
+	CloseReasonAPIKeyRemoved   = "api_key_removed"
+	CloseReasonAPIKeyInactive  = "api_key_inactive"
+	CloseReasonNetworkChanged  = "api_key_network_changed"
+	CloseReasonProtocolError   = "protocol_error"
+	CloseReasonPasswordReset   = "password_reset"
+	CloseReasonUserInactive    = "user_deactivated"
+	CloseReasonUserDeleted     = "user_deleted"
+	CloseReasonRolesChanged    = "roles_changed"
+	CloseReasonSendQueueFull   = "send_queue_full"
+	CloseReasonTokenRevoked    = "token_revoked"
+	CloseReasonAccessRevoked   = "access_revoked"
+)
+
+// closeReason is the JSON body of a close frame; it must stay under the
+// 123-byte control frame payload limit
+type closeReason struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+func formatCloseMessage(code int, reason string) []byte {
+	body, _ := json.Marshal(closeReason{Code: code, Reason: reason})
+	return websocket.FormatCloseMessage(code, string(body))
+}
@@ -0,0 +1,296 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yanun0323/logs"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/usecase"
+)
+
+type TradingHandler struct {
+	tradingStreamManager *TradingStreamManager
+	klineStreamManager   *BinanceStreamManager
+	recordingUseCase     adaptor.RecordingUseCase     // nil when recording is disabled
+	orderEventUseCase    adaptor.OrderEventUseCase    // nil when order events are not persisted
+	reportUseCase        adaptor.TradingReportUseCase // nil when order events are not persisted
+	marketStateUseCase   adaptor.MarketStateUseCase
+	auditRepo            adaptor.AuditRepository
+}
+
+func NewTradingHandler(tradingStreamManager *TradingStreamManager, klineStreamManager *BinanceStreamManager, recordingUseCase adaptor.RecordingUseCase, orderEventUseCase adaptor.OrderEventUseCase, reportUseCase adaptor.TradingReportUseCase, marketStateUseCase adaptor.MarketStateUseCase, auditRepo adaptor.AuditRepository) *TradingHandler {
+	return &TradingHandler{
+		tradingStreamManager: tradingStreamManager,
+		klineStreamManager:   klineStreamManager,
+		recordingUseCase:     recordingUseCase,
+		orderEventUseCase:    orderEventUseCase,
+		reportUseCase:        reportUseCase,
+		marketStateUseCase:   marketStateUseCase,
+		auditRepo:            auditRepo,
+	}
+}
+
+// Status reports trading WebSocket connection and bandwidth metrics
+func (h *TradingHandler) Status(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: h.tradingStreamManager.Stats()})
+}
+
+// Sessions lists every connected trading and kline WebSocket client
+func (h *TradingHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: model.WebSocketSessions{
+		Trading: h.tradingStreamManager.Sessions(),
+		Kline:   h.klineStreamManager.Sessions(),
+	}})
+}
+
+// Disconnect force-closes a single trading connection by session ID, or every
+// connection of an API key together with its exchange connection
+func (h *TradingHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	var req model.TradingDisconnectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	if (req.SessionID == "") == (req.APIKeyID == "") {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "exactly one of sessionId and apiKeyId is required"})
+		return
+	}
+
+	actor := GetUserFromContext(r.Context())
+	if actor == nil {
+		WriteJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+
+	entry := &model.AuditEntry{ActorID: actor.ID, Action: "trading.disconnect"}
+	var result model.TradingDisconnectResult
+	if req.SessionID != "" {
+		session := h.tradingStreamManager.DisconnectSession(req.SessionID, CloseCodePermission, CloseReasonAdminDisconnect)
+		if session == nil {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "session not found"})
+			return
+		}
+		result.ClosedClients = 1
+		entry.TargetType = "trading_session"
+		entry.TargetID = req.SessionID
+		entry.Details = map[string]any{
+			"user_id":     session.UserID,
+			"api_key_id":  session.APIKeyID,
+			"remote_addr": session.RemoteAddr,
+		}
+	} else {
+		result.ClosedClients, result.ExchangeClosed = h.tradingStreamManager.DisconnectAPIKey(req.APIKeyID, CloseCodePermission, CloseReasonAdminDisconnect)
+		if result.ClosedClients == 0 && !result.ExchangeClosed {
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "no connections for api key"})
+			return
+		}
+		entry.TargetType = "api_key"
+		entry.TargetID = req.APIKeyID
+		entry.Details = map[string]any{
+			"closed_clients":  result.ClosedClients,
+			"exchange_closed": result.ExchangeClosed,
+		}
+	}
+
+	// Failures are logged rather than failing the action, which already happened
+	if err := h.auditRepo.Create(r.Context(), entry); err != nil {
+		logs.Errorf("failed to write audit entry %s for %s: %v", entry.Action, entry.TargetID, err)
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{
+		Message: "disconnected successfully",
+		Data:    result,
+	})
+}
+
+// Recorded returns persisted kline and trade events for a symbol, oldest first
+func (h *TradingHandler) Recorded(w http.ResponseWriter, r *http.Request) {
+	if h.recordingUseCase == nil {
+		WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "recording is not enabled"})
+		return
+	}
+
+	query, err := parseRecordQuery(r.URL.Query())
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	records, err := h.recordingUseCase.Query(r.Context(), query)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrRecordSymbolRequired), errors.Is(err, usecase.ErrRecordInvalidRange):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to query recorded data"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: records})
+}
+
+// OrderEvents returns persisted order updates of an API key, newest first
+func (h *TradingHandler) OrderEvents(w http.ResponseWriter, r *http.Request) {
+	if h.orderEventUseCase == nil {
+		WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "order event persistence is not enabled"})
+		return
+	}
+
+	query, err := parseOrderEventQuery(r.URL.Query())
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	query.APIKeyID = chi.URLParam(r, "apiKeyId")
+
+	page, err := h.orderEventUseCase.Query(r.Context(), query)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAPIKeyNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrOrderEventInvalidRange):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to query order events"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: page})
+}
+
+// Report summarizes an API key's fills for one UTC day (date=YYYY-MM-DD, default today)
+func (h *TradingHandler) Report(w http.ResponseWriter, r *http.Request) {
+	if h.reportUseCase == nil {
+		WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: "order event persistence is not enabled"})
+		return
+	}
+
+	date := time.Now().UTC()
+	if v := r.URL.Query().Get("date"); v != "" {
+		var err error
+		if date, err = time.Parse(time.DateOnly, v); err != nil {
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	report, err := h.reportUseCase.DailyReport(r.Context(), chi.URLParam(r, "apiKeyId"), date)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrAPIKeyNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to build trading report"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: report})
+}
+
+// MarketState queries the exchange of an API key for a symbol's trading status
+// (symbol=BTCUSDT), in the shape of the trading stream's "state" frames
+func (h *TradingHandler) MarketState(w http.ResponseWriter, r *http.Request) {
+	state, err := h.marketStateUseCase.State(r.Context(), chi.URLParam(r, "apiKeyId"), r.URL.Query().Get("symbol"))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidID):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid id format"})
+		case errors.Is(err, usecase.ErrMarketSymbolRequired), errors.Is(err, usecase.ErrMarketStatePlatform):
+			WriteJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, usecase.ErrAPIKeyNotFound), errors.Is(err, usecase.ErrMarketNotFound):
+			WriteJSON(w, http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		default:
+			logs.Warnf("market state query failed: %v", err)
+			WriteJSON(w, http.StatusBadGateway, ErrorResponse{Error: "failed to query market state"})
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, SuccessResponse{Data: state})
+}
+
+// parseOrderEventQuery reads symbol, from/to (RFC 3339 or Unix milliseconds), limit and offset
+func parseOrderEventQuery(q url.Values) (model.OrderEventQuery, error) {
+	query := model.OrderEventQuery{Symbol: q.Get("symbol")}
+
+	var err error
+	if query.From, err = parseRecordTime(q.Get("from")); err != nil {
+		return query, errors.New("invalid from")
+	}
+	if query.To, err = parseRecordTime(q.Get("to")); err != nil {
+		return query, errors.New("invalid to")
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return query, errors.New("invalid offset")
+		}
+		query.Offset = n
+	}
+
+	return query, nil
+}
+
+// parseRecordQuery reads symbol, type, limit and from/to (RFC 3339 or Unix milliseconds)
+func parseRecordQuery(q url.Values) (model.MarketRecordQuery, error) {
+	query := model.MarketRecordQuery{
+		Symbol: strings.ToUpper(strings.TrimSpace(q.Get("symbol"))),
+		Type:   q.Get("type"),
+	}
+
+	switch query.Type {
+	case "", model.RecordTypeKline, model.RecordTypeTrades:
+	default:
+		return query, errors.New("invalid type, expected kline or trades")
+	}
+
+	var err error
+	if query.From, err = parseRecordTime(q.Get("from")); err != nil {
+		return query, errors.New("invalid from")
+	}
+	if query.To, err = parseRecordTime(q.Get("to")); err != nil {
+		return query, errors.New("invalid to")
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return query, errors.New("invalid limit")
+		}
+		query.Limit = n
+	}
+
+	return query, nil
+}
+
+func parseRecordTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,285 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+const (
+	testTradingToken = "trading-token"
+	testFrameTimeout = 5 * time.Second
+)
+
+// fakeAPIKeyRepo serves API keys from memory; the stream manager only reads
+// them, so every other method of the embedded interface is left unimplemented
+type fakeAPIKeyRepo struct {
+	adaptor.APIKeyRepository
+
+	mu   sync.Mutex
+	keys map[string]model.APIKey
+}
+
+func newFakeAPIKeyRepo(keys ...model.APIKey) *fakeAPIKeyRepo {
+	r := &fakeAPIKeyRepo{keys: make(map[string]model.APIKey)}
+	for _, key := range keys {
+		r.set(key)
+	}
+	return r
+}
+
+func (r *fakeAPIKeyRepo) set(key model.APIKey) {
+	r.mu.Lock()
+	r.keys[key.ID] = key
+	r.mu.Unlock()
+}
+
+func (r *fakeAPIKeyRepo) GetByID(_ context.Context, id string) (*model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+// testAPIKey is an active key of platform with the given credentials
+func testAPIKey(id string, platform model.Platform, apiKey, secret string) model.APIKey {
+	return model.APIKey{
+		ID:        id,
+		Name:      id,
+		Platform:  platform,
+		APIKey:    apiKey,
+		APISecret: secret,
+		IsActive:  true,
+		Scopes:    []model.APIKeyScope{model.APIKeyScopeRead},
+	}
+}
+
+// tradingHarness serves a TradingStreamManager over httptest, pointed at fake
+// exchanges through the endpoint overrides
+type tradingHarness struct {
+	manager *TradingStreamManager
+	server  *httptest.Server
+	keys    *fakeAPIKeyRepo
+}
+
+func newTradingHarness(t *testing.T, endpoints map[model.ExchangeNetwork]model.ExchangeURLs, keys ...model.APIKey) *tradingHarness {
+	t.Helper()
+
+	auth := &mocks.AuthUseCase{
+		ValidateTokenFunc: func(_ context.Context, token string) (*model.UserWithRoles, error) {
+			if token != testTradingToken {
+				return nil, errors.New("invalid token")
+			}
+			return &model.UserWithRoles{
+				User:        model.User{ID: "user-1", Username: "trader", IsActive: true},
+				Permissions: []enum.Permission{enum.PermissionViewTrading},
+			}, nil
+		},
+	}
+	h := &tradingHarness{keys: newFakeAPIKeyRepo(keys...)}
+	h.manager = NewTradingStreamManager(
+		&mocks.APIKeyUseCase{}, auth, h.keys,
+		false, 0, ExchangeConnLimits{}, model.NewExchangeEndpoints(endpoints),
+		false, nil, nil, nil, nil, 0, nil,
+	)
+	h.server = httptest.NewServer(http.HandlerFunc(h.manager.HandleWebSocket))
+	t.Cleanup(func() {
+		h.manager.Close()
+		h.server.Close()
+	})
+	return h
+}
+
+// exchangeConn returns the live exchange connection of apiKeyID, or nil
+func (h *tradingHarness) exchangeConn(apiKeyID string) *ExchangeConnection {
+	h.manager.exchangeMu.RLock()
+	defer h.manager.exchangeMu.RUnlock()
+	return h.manager.exchangeConns[apiKeyID]
+}
+
+// publicStreams returns the public exchange streams of apiKeyID's connection
+// and the symbols of its maintained books
+func (h *tradingHarness) publicStreams(apiKeyID string) (streams, books map[string]bool) {
+	streams, books = make(map[string]bool), make(map[string]bool)
+	ec := h.exchangeConn(apiKeyID)
+	if ec == nil {
+		return streams, books
+	}
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	for stream := range ec.PublicSubs {
+		streams[stream] = true
+	}
+	for symbol := range ec.books {
+		books[symbol] = true
+	}
+	return streams, books
+}
+
+// waitBookSynced waits until the maintained book of symbol has loaded its REST snapshot
+func (h *tradingHarness) waitBookSynced(t *testing.T, apiKeyID, symbol string) {
+	t.Helper()
+	for deadline := time.Now().Add(testFrameTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		ec := h.exchangeConn(apiKeyID)
+		if ec == nil {
+			continue
+		}
+		ec.mu.RLock()
+		mb := ec.books[symbol]
+		ec.mu.RUnlock()
+		if mb != nil && mb.book.Synced() {
+			return
+		}
+	}
+	t.Fatalf("maintained book %s never synced", symbol)
+}
+
+// tradingFrame is a frame received from /ws/trading with its data left raw
+type tradingFrame struct {
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+	Platform string          `json:"platform"`
+	Symbol   string          `json:"symbol"`
+	Interval string          `json:"interval"`
+	Error    string          `json:"error"`
+	Mode     string          `json:"mode"`
+}
+
+func (f tradingFrame) decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(f.Data, v); err != nil {
+		t.Fatalf("decode %s data %s: %v", f.Type, f.Data, err)
+	}
+}
+
+// tradingClient is one /ws/trading connection that completed the hello handshake
+type tradingClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func (h *tradingHarness) dial(t *testing.T) *tradingClient {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(h.server.URL, "http") + "?token=" + testTradingToken
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial /ws/trading: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	c := &tradingClient{t: t, conn: conn}
+	c.expect("hello")
+	c.send(model.TradingWebSocketMessage{Action: "hello"})
+	c.expect("ready")
+	return c
+}
+
+// connect attaches the client to apiKeyID and waits for the snapshot of the
+// key's default subscriptions that follows
+func (c *tradingClient) connect(apiKeyID string) {
+	c.t.Helper()
+	c.send(model.TradingWebSocketMessage{Action: "connect", APIKeyID: apiKeyID})
+	c.expect("connected")
+	c.expect("subscriptions")
+}
+
+// subscribe sends msg as a subscribe action and waits for the acknowledging snapshot
+func (c *tradingClient) subscribe(msg model.TradingWebSocketMessage) {
+	c.t.Helper()
+	msg.Action = "subscribe"
+	c.send(msg)
+	c.expect("subscriptions")
+}
+
+// unsubscribe sends msg as an unsubscribe action and waits for the acknowledging snapshot
+func (c *tradingClient) unsubscribe(msg model.TradingWebSocketMessage) {
+	c.t.Helper()
+	msg.Action = "unsubscribe"
+	c.send(msg)
+	c.expect("subscriptions")
+}
+
+func (c *tradingClient) send(msg model.TradingWebSocketMessage) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("send %s: %v", msg.Action, err)
+	}
+}
+
+// read returns the next frame, or an error once timeout passes without one
+func (c *tradingClient) read(timeout time.Duration) (tradingFrame, error) {
+	var frame tradingFrame
+	if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return frame, err
+	}
+	_, payload, err := c.conn.ReadMessage()
+	if err != nil {
+		return frame, err
+	}
+	err = json.Unmarshal(payload, &frame)
+	return frame, err
+}
+
+// expect skips frames until one of type typ arrives, failing on error frames
+func (c *tradingClient) expect(typ string) tradingFrame {
+	c.t.Helper()
+	deadline := time.Now().Add(testFrameTimeout)
+	for {
+		frame, err := c.read(time.Until(deadline))
+		if err != nil {
+			c.t.Fatalf("waiting for a %s frame: %v", typ, err)
+		}
+		if frame.Type == typ {
+			return frame
+		}
+		if frame.Type == "error" {
+			c.t.Fatalf("waiting for a %s frame: got error %q", typ, frame.Error)
+		}
+	}
+}
+
+// expectNone fails if a frame of type typ arrives within wait
+func (c *tradingClient) expectNone(typ string, wait time.Duration) {
+	c.t.Helper()
+	deadline := time.Now().Add(wait)
+	for {
+		frame, err := c.read(time.Until(deadline))
+		if err != nil {
+			// Only the timeout is expected; it also leaves the connection unusable
+			var netErr interface{ Timeout() bool }
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				c.t.Fatalf("waiting out %s frames: %v", typ, err)
+			}
+			return
+		}
+		if frame.Type == typ {
+			c.t.Fatalf("got %s frame %s, want none", typ, frame.Data)
+		}
+	}
+}
+
+// waitFor fails the test unless ready returns nil before the frame timeout
+func waitFor(t *testing.T, what string, ready func(ctx context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), testFrameTimeout)
+	defer cancel()
+	if err := ready(ctx); err != nil {
+		t.Fatalf("waiting for %s: %v", what, err)
+	}
+}
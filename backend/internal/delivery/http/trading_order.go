@@ -0,0 +1,98 @@
+package http
+
+import (
+	"strconv"
+
+	"control_page/internal/model"
+)
+
+// Exchange order fields are mapped into the canonical model enums here, so
+// clients see one vocabulary regardless of platform.
+
+func binanceOrderSide(v string) model.OrderSide {
+	return model.OrderSide(v) // BUY or SELL
+}
+
+// binanceOrderType keeps types outside the canonical set as reported, since
+// dropping them would hide what kind of order filled
+func binanceOrderType(v string) model.OrderType {
+	return model.OrderType(v)
+}
+
+func binanceOrderStatus(v string) model.OrderStatus {
+	switch v {
+	case "EXPIRED_IN_MATCH":
+		// expired by self-trade prevention
+		return model.OrderStatusExpired
+	}
+	if status := model.OrderStatus(v); status.IsValid() {
+		return status
+	}
+	return model.OrderStatusUnknown
+}
+
+func binanceTimeInForce(v string) model.TimeInForce {
+	if tif := model.TimeInForce(v); tif.IsValid() {
+		return tif
+	}
+	return ""
+}
+
+// btccOrderSide maps BTCC's 1=buy, 2=sell
+func btccOrderSide(v int) model.OrderSide {
+	if v == 1 {
+		return model.OrderSideBuy
+	}
+	return model.OrderSideSell
+}
+
+// btccOrderType maps BTCC's 1=limit, 2=market. BTCC has no separate stop
+// order types: a trigger price makes a limit order STOP_LOSS_LIMIT and a
+// market order STOP_LOSS
+func btccOrderType(v int, hasStop bool) model.OrderType {
+	switch {
+	case v == 1 && hasStop:
+		return model.OrderTypeStopLossLimit
+	case v == 1:
+		return model.OrderTypeLimit
+	case hasStop:
+		return model.OrderTypeStopLoss
+	default:
+		return model.OrderTypeMarket
+	}
+}
+
+// btccOrderStatus maps the order.update event (1=put, 2=update, 3=finish).
+// A finished order is FILLED when nothing is left and CANCELED otherwise.
+func btccOrderStatus(event int, left string, hasLeft bool) model.OrderStatus {
+	switch event {
+	case 1:
+		return model.OrderStatusNew
+	case 2:
+		return model.OrderStatusPartiallyFilled
+	case 3:
+		if !hasLeft {
+			return model.OrderStatusFilled
+		}
+		if leftQty, _ := strconv.ParseFloat(left, 64); leftQty == 0 {
+			return model.OrderStatusFilled
+		}
+		return model.OrderStatusCanceled
+	default:
+		return model.OrderStatusUnknown
+	}
+}
+
+// btccTimeInForce maps BTCC's option flags 0=GTC, 8=IOC, 16=FOK
+func btccTimeInForce(option int) model.TimeInForce {
+	switch option {
+	case 0:
+		return model.TimeInForceGTC
+	case 8:
+		return model.TimeInForceIOC
+	case 16:
+		return model.TimeInForceFOK
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+func TestOrderEnumsMatchAcrossExchanges(t *testing.T) {
+	m := &TradingStreamManager{}
+	type canonical struct {
+		side   model.OrderSide
+		typ    model.OrderType
+		status model.OrderStatus
+		tif    model.TimeInForce
+	}
+
+	tests := []struct {
+		name    string
+		binance map[string]any // executionReport fields
+		btcc    map[string]any // order.update order
+		event   int            // order.update event: 1 put, 2 update, 3 finish
+		want    canonical
+	}{
+		{
+			name:    "new limit buy",
+			binance: map[string]any{"S": "BUY", "o": "LIMIT", "X": "NEW", "f": "GTC"},
+			btcc:    map[string]any{"side": 1.0, "type": 1.0, "option": 0.0},
+			event:   1,
+			want:    canonical{model.OrderSideBuy, model.OrderTypeLimit, model.OrderStatusNew, model.TimeInForceGTC},
+		},
+		{
+			name:    "partially filled market sell",
+			binance: map[string]any{"S": "SELL", "o": "MARKET", "X": "PARTIALLY_FILLED", "f": "IOC"},
+			btcc:    map[string]any{"side": 2.0, "type": 2.0, "option": 8.0, "left": "0.5"},
+			event:   2,
+			want:    canonical{model.OrderSideSell, model.OrderTypeMarket, model.OrderStatusPartiallyFilled, model.TimeInForceIOC},
+		},
+		{
+			name:    "filled stop limit",
+			binance: map[string]any{"S": "BUY", "o": "STOP_LOSS_LIMIT", "X": "FILLED", "f": "FOK", "P": "95.0"},
+			btcc:    map[string]any{"side": 1.0, "type": 1.0, "option": 16.0, "stop_price": "95.0", "left": "0"},
+			event:   3,
+			want:    canonical{model.OrderSideBuy, model.OrderTypeStopLossLimit, model.OrderStatusFilled, model.TimeInForceFOK},
+		},
+		{
+			name:    "canceled stop market",
+			binance: map[string]any{"S": "SELL", "o": "STOP_LOSS", "X": "CANCELED", "f": "GTC", "P": "95.0"},
+			btcc:    map[string]any{"side": 2.0, "type": 2.0, "option": 0.0, "stop_price": "95.0", "left": "1.5"},
+			event:   3,
+			want:    canonical{model.OrderSideSell, model.OrderTypeStopLoss, model.OrderStatusCanceled, model.TimeInForceGTC},
+		},
+		{
+			name:    "unmapped status and time in force",
+			binance: map[string]any{"S": "BUY", "o": "LIMIT", "X": "SOMETHING_NEW", "f": "GTX"},
+			btcc:    map[string]any{"side": 1.0, "type": 1.0, "option": 4.0},
+			event:   9,
+			want:    canonical{model.OrderSideBuy, model.OrderTypeLimit, model.OrderStatusUnknown, ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for platform, order := range map[string]*model.Order{
+				"binance": m.parseBinanceOrder(tt.binance),
+				"btcc":    m.parseBTCCOrder(tt.btcc, tt.event),
+			} {
+				got := canonical{order.Side, order.Type, order.Status, order.TimeInForce}
+				if got != tt.want {
+					t.Errorf("%s order = %+v, want %+v", platform, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBinanceSelfTradePreventionExpires(t *testing.T) {
+	if got := binanceOrderStatus("EXPIRED_IN_MATCH"); got != model.OrderStatusExpired {
+		t.Fatalf("binanceOrderStatus(EXPIRED_IN_MATCH) = %s, want %s", got, model.OrderStatusExpired)
+	}
+}
+
+func TestOrderEnumsJSON(t *testing.T) {
+	order := model.Order{
+		Side:        model.OrderSideSell,
+		Type:        model.OrderTypeLimitMaker,
+		Status:      model.OrderStatusPendingCancel,
+		TimeInForce: model.TimeInForceIOC,
+	}
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var decoded model.Order
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", data, err)
+	}
+	if decoded.Side != order.Side || decoded.Type != order.Type || decoded.Status != order.Status || decoded.TimeInForce != order.TimeInForce {
+		t.Fatalf("round trip = %+v, want %+v", decoded, order)
+	}
+
+	// Values are case-insensitive, an empty one is kept and unknown ones are rejected
+	var lower model.Order
+	if err := json.Unmarshal([]byte(`{"side":"buy","status":"filled","timeInForce":""}`), &lower); err != nil {
+		t.Fatalf("Unmarshal() lower case error = %v", err)
+	}
+	if lower.Side != model.OrderSideBuy || lower.Status != model.OrderStatusFilled || lower.TimeInForce != "" {
+		t.Fatalf("lower case order = %+v, want BUY and FILLED", lower)
+	}
+	var side model.OrderSide
+	if err := json.Unmarshal([]byte(`"HOLD"`), &side); err == nil {
+		t.Fatalf("Unmarshal(HOLD) = %s, want an error", side)
+	}
+}
+
+func TestStopOrderParsing(t *testing.T) {
+	m := &TradingStreamManager{}
+
+	binance := []struct {
+		name          string
+		orderType     string
+		stopPrice     string
+		wantType      model.OrderType
+		wantStopPrice string
+	}{
+		{"stop loss limit", "STOP_LOSS_LIMIT", "95.00000000", model.OrderTypeStopLossLimit, "95.00000000"},
+		{"stop loss", "STOP_LOSS", "95.00000000", model.OrderTypeStopLoss, "95.00000000"},
+		{"take profit", "TAKE_PROFIT", "120.50000000", model.OrderTypeTakeProfit, "120.50000000"},
+		{"take profit limit", "TAKE_PROFIT_LIMIT", "120.50000000", model.OrderTypeTakeProfitLimit, "120.50000000"},
+		{"limit with the zero placeholder", "LIMIT", "0.00000000", model.OrderTypeLimit, ""},
+	}
+	for _, tt := range binance {
+		t.Run("binance "+tt.name, func(t *testing.T) {
+			var report map[string]any
+			payload := `{"e":"executionReport","E":1700000000000,"s":"BTCUSDT","c":"web_1","S":"SELL","o":"` + tt.orderType +
+				`","f":"GTC","q":"1.00000000","p":"94.00000000","P":"` + tt.stopPrice + `","X":"NEW","i":4293153,"z":"0.00000000","T":1700000000000}`
+			if err := json.Unmarshal([]byte(payload), &report); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			order := m.parseBinanceOrder(report)
+			if order.Type != tt.wantType || order.StopPrice != tt.wantStopPrice {
+				t.Fatalf("order type %s stop price %q, want %s %q", order.Type, order.StopPrice, tt.wantType, tt.wantStopPrice)
+			}
+		})
+	}
+
+	btcc := []struct {
+		name          string
+		orderType     int // 1 limit, 2 market
+		stopPrice     string
+		wantType      model.OrderType
+		wantStopPrice string
+	}{
+		{"triggered limit", 1, "95.0", model.OrderTypeStopLossLimit, "95.0"},
+		{"triggered market", 2, "95.0", model.OrderTypeStopLoss, "95.0"},
+		{"limit without trigger", 1, "0", model.OrderTypeLimit, ""},
+		{"market without trigger", 2, "", model.OrderTypeMarket, ""},
+	}
+	for _, tt := range btcc {
+		t.Run("btcc "+tt.name, func(t *testing.T) {
+			var update map[string]any
+			payload := fmt.Sprintf(`{"id":12345,"market":"BTCUSDT","side":2,"type":%d,"price":"94.0","amount":"1.0","stop_price":%q,"left":"1.0","option":0}`,
+				tt.orderType, tt.stopPrice)
+			if err := json.Unmarshal([]byte(payload), &update); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			order := m.parseBTCCOrder(update, 1)
+			if order.Type != tt.wantType || order.StopPrice != tt.wantStopPrice {
+				t.Fatalf("order type %s stop price %q, want %s %q", order.Type, order.StopPrice, tt.wantType, tt.wantStopPrice)
+			}
+		})
+	}
+}
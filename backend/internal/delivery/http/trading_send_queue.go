@@ -0,0 +1,166 @@
+package http
+
+import (
+	"sync"
+)
+
+// defaultClientSendQueue is used when no send queue size is configured
+const defaultClientSendQueue = 256
+
+// sendQueueHardLimit multiplies the queue size into the backlog of frames
+// without a key at which the client is given up on
+const sendQueueHardLimit = 4
+
+// queuedFrame is one encoded frame waiting for the client's writer
+type queuedFrame struct {
+	payload []byte
+	// key identifies the stream of an orderbook or kline frame, which a newer
+	// frame may replace while the queue is full; empty for frames that must
+	// always be delivered (orders, acks, errors, snapshots)
+	key string
+}
+
+// sendQueue is the bounded outbox of one trading client, drained by a
+// dedicated writer goroutine so broadcasts never block on a slow socket.
+// Once full, stream frames are coalesced or dropped oldest first; frames
+// without a key are kept past the bound, up to sendQueueHardLimit times it.
+// Beyond that the client cannot keep up with its own orders and acks, so the
+// backlog is discarded and push reports the overflow for the caller to close
+// the connection.
+type sendQueue struct {
+	size int
+
+	mu         sync.Mutex
+	frames     []queuedFrame
+	closing    []byte // close frame to send after draining, set by finish
+	closed     bool   // stop without draining
+	overflowed bool   // hard limit hit; later frames are discarded
+
+	wake chan struct{}
+	done chan struct{} // closed when the writer exits
+}
+
+func newSendQueue(size int) *sendQueue {
+	return &sendQueue{
+		size: size,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+}
+
+// push enqueues a frame and returns how many frames were dropped to make
+// room. overflow is true, once, when the hard limit is hit.
+func (q *sendQueue) push(f queuedFrame) (dropped int, overflow bool) {
+	q.mu.Lock()
+	if q.closed || q.closing != nil || q.overflowed {
+		q.mu.Unlock()
+		return 0, false
+	}
+
+	if len(q.frames) >= q.size && f.key != "" {
+		dropped = 1
+		if i := q.indexOf(func(queued queuedFrame) bool { return queued.key == f.key }); i >= 0 {
+			// A newer frame of the same stream supersedes the queued one in place
+			q.frames[i] = f
+			q.mu.Unlock()
+			return dropped, false
+		}
+		if i := q.indexOf(func(queued queuedFrame) bool { return queued.key != "" }); i >= 0 {
+			q.frames = append(q.frames[:i], q.frames[i+1:]...)
+		} else {
+			// Only undroppable frames are queued, so the new stream frame goes
+			q.mu.Unlock()
+			return dropped, false
+		}
+	}
+	if len(q.frames) >= q.size*sendQueueHardLimit {
+		dropped = len(q.frames) + 1
+		q.frames = nil
+		q.overflowed = true
+		q.mu.Unlock()
+		return dropped, true
+	}
+	q.frames = append(q.frames, f)
+	q.mu.Unlock()
+
+	q.signal()
+	return dropped, false
+}
+
+// indexOf returns the position of the oldest queued frame matching fn, or -1
+func (q *sendQueue) indexOf(fn func(queuedFrame) bool) int {
+	for i, queued := range q.frames {
+		if fn(queued) {
+			return i
+		}
+	}
+	return -1
+}
+
+// next blocks until a frame is queued; ok is false once the queue is closed,
+// or finished and drained
+func (q *sendQueue) next() (queuedFrame, bool) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return queuedFrame{}, false
+		}
+		if len(q.frames) > 0 {
+			f := q.frames[0]
+			q.frames[0] = queuedFrame{}
+			q.frames = q.frames[1:]
+			q.mu.Unlock()
+			return f, true
+		}
+		if q.closing != nil {
+			q.mu.Unlock()
+			return queuedFrame{}, false
+		}
+		q.mu.Unlock()
+		<-q.wake
+	}
+}
+
+// len returns the number of frames waiting to be written
+func (q *sendQueue) len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.frames)
+}
+
+// finish lets the writer drain what is queued, then send closeMessage
+func (q *sendQueue) finish(closeMessage []byte) {
+	q.mu.Lock()
+	if q.closing == nil {
+		q.closing = closeMessage
+	}
+	q.mu.Unlock()
+	q.signal()
+}
+
+// closeMessage returns the close frame requested by finish, or nil
+func (q *sendQueue) closeMessage() []byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closing
+}
+
+// close stops the writer and discards anything still queued
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.frames = nil
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
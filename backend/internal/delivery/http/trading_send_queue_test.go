@@ -0,0 +1,124 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testFrame is a queued frame whose payload names it
+func testFrame(name, key string) queuedFrame {
+	return queuedFrame{payload: []byte(name), key: key}
+}
+
+// queuedPayloads lists the payloads waiting in q, oldest first
+func queuedPayloads(q *sendQueue) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	names := make([]string, len(q.frames))
+	for i, f := range q.frames {
+		names[i] = string(f.payload)
+	}
+	return names
+}
+
+func TestSendQueuePush(t *testing.T) {
+	tests := []struct {
+		name        string
+		fill        []queuedFrame
+		push        queuedFrame
+		wantDropped int
+		want        []string
+	}{
+		{
+			name: "room left",
+			fill: []queuedFrame{testFrame("book-1", "book:BTCUSDT")},
+			push: testFrame("book-2", "book:BTCUSDT"),
+			want: []string{"book-1", "book-2"},
+		},
+		{
+			name:        "full queue replaces the frame of the same stream in place",
+			fill:        []queuedFrame{testFrame("book-1", "book:BTCUSDT"), testFrame("kline-1", "kline:BTCUSDT"), testFrame("order-1", "")},
+			push:        testFrame("kline-2", "kline:BTCUSDT"),
+			wantDropped: 1,
+			want:        []string{"book-1", "kline-2", "order-1"},
+		},
+		{
+			name:        "full queue evicts the oldest stream frame",
+			fill:        []queuedFrame{testFrame("order-1", ""), testFrame("book-1", "book:BTCUSDT"), testFrame("kline-1", "kline:BTCUSDT")},
+			push:        testFrame("book-2", "book:ETHUSDT"),
+			wantDropped: 1,
+			want:        []string{"order-1", "kline-1", "book-2"},
+		},
+		{
+			name:        "full queue of unkeyed frames drops the stream frame",
+			fill:        []queuedFrame{testFrame("order-1", ""), testFrame("order-2", ""), testFrame("ack-1", "")},
+			push:        testFrame("book-1", "book:BTCUSDT"),
+			wantDropped: 1,
+			want:        []string{"order-1", "order-2", "ack-1"},
+		},
+		{
+			name: "unkeyed frames go past the bound",
+			fill: []queuedFrame{testFrame("book-1", "book:BTCUSDT"), testFrame("kline-1", "kline:BTCUSDT"), testFrame("order-1", "")},
+			push: testFrame("order-2", ""),
+			want: []string{"book-1", "kline-1", "order-1", "order-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newSendQueue(3)
+			for _, f := range tt.fill {
+				q.push(f)
+			}
+			dropped, overflow := q.push(tt.push)
+			if dropped != tt.wantDropped || overflow {
+				t.Fatalf("push() = %d, %v; want %d, false", dropped, overflow, tt.wantDropped)
+			}
+			if got := queuedPayloads(q); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("queue = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendQueueHardLimit(t *testing.T) {
+	q := newSendQueue(2)
+	limit := 2 * sendQueueHardLimit
+	for i := 0; i < limit; i++ {
+		if dropped, overflow := q.push(testFrame("order", "")); dropped != 0 || overflow {
+			t.Fatalf("push() #%d = %d, %v; want the frame kept", i+1, dropped, overflow)
+		}
+	}
+
+	// Stream frames are still dropped rather than counted against the limit
+	if dropped, overflow := q.push(testFrame("book", "book:BTCUSDT")); dropped != 1 || overflow {
+		t.Fatalf("push(stream frame) = %d, %v; want 1, false", dropped, overflow)
+	}
+
+	dropped, overflow := q.push(testFrame("order", ""))
+	if dropped != limit+1 || !overflow || q.len() != 0 {
+		t.Fatalf("push() past the limit = %d, %v with %d queued; want %d, true and an empty queue", dropped, overflow, q.len(), limit+1)
+	}
+
+	// The overflow is reported once; later frames are discarded
+	if dropped, overflow := q.push(testFrame("order", "")); dropped != 0 || overflow || q.len() != 0 {
+		t.Fatalf("push() after the overflow = %d, %v with %d queued; want nothing queued", dropped, overflow, q.len())
+	}
+}
+
+func TestSendQueueIgnoresPushAfterFinish(t *testing.T) {
+	q := newSendQueue(2)
+	q.push(testFrame("order-1", ""))
+	q.finish([]byte("close"))
+	q.push(testFrame("order-2", ""))
+
+	if f, ok := q.next(); !ok || string(f.payload) != "order-1" {
+		t.Fatalf("next() = %q, %v; want the frame queued before finish", f.payload, ok)
+	}
+	if _, ok := q.next(); ok {
+		t.Fatal("next() returned a frame pushed after finish")
+	}
+	if string(q.closeMessage()) != "close" {
+		t.Fatalf("closeMessage() = %q, want the finish message", q.closeMessage())
+	}
+}
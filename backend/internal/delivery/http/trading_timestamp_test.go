@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseUnixMilli(t *testing.T) {
+	m := &TradingStreamManager{}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   int64
+		wantOK bool
+	}{
+		{name: "seconds", value: float64(1700000000), want: 1700000000000, wantOK: true},
+		{name: "fractional seconds", value: 1700000000.456, want: 1700000000456, wantOK: true},
+		{name: "milliseconds", value: float64(1700000000456), want: 1700000000456, wantOK: true},
+		{name: "int seconds", value: 1700000000, want: 1700000000000, wantOK: true},
+		{name: "int64 milliseconds", value: int64(1700000000456), want: 1700000000456, wantOK: true},
+		{name: "json number", value: json.Number("1700000000.5"), want: 1700000000500, wantOK: true},
+		{name: "bad json number", value: json.Number("soon"), wantOK: false},
+		{name: "zero", value: float64(0), wantOK: false},
+		{name: "negative", value: float64(-1), wantOK: false},
+		{name: "string", value: "1700000000", wantOK: false},
+		{name: "missing", value: nil, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := m.parseUnixMilli(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("parseUnixMilli(%v) = %d, %v; want %d, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// decodeParams decodes a BTCC push payload the way the stream reader does
+func decodeParams(t *testing.T, payload string) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &data); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", payload, err)
+	}
+	return data
+}
+
+func TestParseBTCCDepthTimestamp(t *testing.T) {
+	m := &TradingStreamManager{}
+	ec := &ExchangeConnection{}
+
+	// depth.update params[1] as sent by BTCC, time in seconds
+	snapshot := decodeParams(t, `{"asks":[["37000.50","0.5"],["37001.00","1.0"]],"bids":[["36999.50","1.2"]],"time":1700000000.456}`)
+	ob := m.parseBTCCDepth(ec, "BTCUSDT", snapshot, true)
+	if ob.Timestamp != 1700000000456 {
+		t.Fatalf("snapshot timestamp = %d, want 1700000000456", ob.Timestamp)
+	}
+
+	// Millisecond times are kept as they are
+	delta := decodeParams(t, `{"asks":[["37000.50","0"]],"time":1700000001789}`)
+	ob = m.parseBTCCDepth(ec, "BTCUSDT", delta, false)
+	if ob.Timestamp != 1700000001789 {
+		t.Fatalf("delta timestamp = %d, want 1700000001789", ob.Timestamp)
+	}
+	if len(ob.Asks) != 1 || ob.Asks[0].Price != "37001.00" {
+		t.Fatalf("asks = %+v, want only 37001.00 left", ob.Asks)
+	}
+
+	// Without a time the update is stamped on arrival, in milliseconds
+	before := time.Now().UnixMilli()
+	ob = m.parseBTCCDepth(ec, "BTCUSDT", decodeParams(t, `{"bids":[["36999.00","2"]]}`), false)
+	if ob.Timestamp < before || ob.Timestamp > time.Now().UnixMilli() {
+		t.Fatalf("timestamp without time = %d, want the arrival time in milliseconds", ob.Timestamp)
+	}
+}
+
+func TestParseBTCCKlineAndOrderTimestamps(t *testing.T) {
+	m := &TradingStreamManager{}
+
+	var kline []interface{}
+	if err := json.Unmarshal([]byte(`[1700000040,"37000.5","37010.0","37020.0","36990.0","12.5","462500.0","BTCUSDT"]`), &kline); err != nil {
+		t.Fatal(err)
+	}
+	result := m.parseBTCCKline(kline)
+	if result["time"] != int64(1700000040000) || result["timestamp"] != float64(1700000040) {
+		t.Fatalf("kline time = %v, timestamp = %v; want 1700000040000 and the raw seconds", result["time"], result["timestamp"])
+	}
+
+	order := m.parseBTCCOrder(decodeParams(t, `{"id":12345,"market":"BTCUSDT","side":1,"type":1,"price":"37000.5","amount":"1","left":"1","ctime":1700000000.123,"mtime":1700000005.5}`), 1)
+	if order.CreateTime != 1700000000123 || order.UpdateTime != 1700000005500 {
+		t.Fatalf("order times = %d, %d; want 1700000000123, 1700000005500", order.CreateTime, order.UpdateTime)
+	}
+}
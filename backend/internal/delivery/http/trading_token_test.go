@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"control_page/internal/mocks"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+	"control_page/internal/usecase"
+)
+
+// switchableAuth validates the harness token as user-1 until told otherwise
+type switchableAuth struct {
+	mu   sync.Mutex
+	user *model.UserWithRoles
+	err  error
+}
+
+func newSwitchableAuth() *switchableAuth {
+	return &switchableAuth{user: &model.UserWithRoles{
+		User:        model.User{ID: "user-1", Username: "trader", IsActive: true},
+		Permissions: []enum.Permission{enum.PermissionViewTrading},
+	}}
+}
+
+func (a *switchableAuth) set(user *model.UserWithRoles, err error) {
+	a.mu.Lock()
+	a.user, a.err = user, err
+	a.mu.Unlock()
+}
+
+func (a *switchableAuth) useCase() *mocks.AuthUseCase {
+	return &mocks.AuthUseCase{
+		ValidateTokenFunc: func(context.Context, string) (*model.UserWithRoles, error) {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			return a.user, a.err
+		},
+	}
+}
+
+func TestTradingRevalidatesTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *model.UserWithRoles
+		err        error
+		wantCode   int
+		wantReason string
+	}{
+		{name: "sessions revoked", err: usecase.ErrInvalidToken, wantCode: CloseCodeAuth, wantReason: CloseReasonTokenRevoked},
+		{name: "user deactivated", err: usecase.ErrUserInactive, wantCode: CloseCodeAuth, wantReason: CloseReasonTokenRevoked},
+		{
+			name:       "permission removed",
+			user:       &model.UserWithRoles{User: model.User{ID: "user-1", IsActive: true}},
+			wantCode:   CloseCodePermission,
+			wantReason: CloseReasonAccessRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newBinanceHarness(t)
+			auth := newSwitchableAuth()
+			h.manager.authUseCase = auth.useCase()
+			client := h.dial(t)
+			client.connect(testBinanceKeyID)
+
+			auth.set(tt.user, tt.err)
+			h.manager.revalidateClients(time.Now())
+
+			closeErr, reason := client.expectClose()
+			if closeErr.Code != tt.wantCode || reason.Reason != tt.wantReason {
+				t.Fatalf("close = %d %+v, want %d %s", closeErr.Code, reason, tt.wantCode, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTradingRevalidateKeepsClients(t *testing.T) {
+	h, _ := newBinanceHarness(t)
+	auth := newSwitchableAuth()
+	h.manager.authUseCase = auth.useCase()
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	now := time.Now()
+
+	// A lookup failure is not a revocation
+	auth.set(nil, errors.New("database unavailable"))
+	h.manager.revalidateClients(now)
+
+	// An expired token is left to the expiry sweep, which is off here
+	auth.set(nil, usecase.ErrInvalidToken)
+	h.manager.mu.Lock()
+	for _, state := range h.manager.clients {
+		state.TokenExpiresAt = now.Add(-time.Minute)
+	}
+	h.manager.mu.Unlock()
+	h.manager.revalidateClients(now)
+
+	client.send(model.TradingWebSocketMessage{Action: "list_subscriptions"})
+	client.expect("subscriptions")
+}
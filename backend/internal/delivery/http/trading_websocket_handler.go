@@ -1,14 +1,15 @@
 package http
 
 import (
+	"bytes"
 	"compress/flate"
 	"context"
-	"crypto/sha256"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"sort"
@@ -18,17 +19,79 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"github.com/yanun0323/logs"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
+	"control_page/internal/model/enum"
+	"control_page/internal/usecase"
+	"control_page/pkg/binance"
+	"control_page/pkg/btcc"
 )
 
 const (
 	clientPongWait     = 60 * time.Second
 	clientPingInterval = 25 * time.Second
 	clientWriteWait    = 10 * time.Second
+
+	tokenSweepInterval      = 5 * time.Second
+	tokenExpiryWarning      = 60 * time.Second // token_expiring is sent this long before expiry
+	tokenRevalidateInterval = 30 * time.Second // how often open connections re-check their token and account
+
+	idleSweepInterval = 15 * time.Second
+
+	depthModePartial   = "partial" // top-level snapshots that replace the client's book (default)
+	depthModeDiff      = "diff"    // incremental updates merged into the client's book (Binance)
+	depthModeBook      = "book"    // snapshots of a server-maintained book, sent as partial frames (Binance)
+	partialDepthLevels = 20        // levels per side of Binance partial depth streams (5, 10 or 20)
+
+	maintainedBookLevels  = 20   // levels per side sent for maintained books
+	bookSnapshotLimit     = 1000 // REST snapshot depth used to seed maintained books
+	bookResyncDelay       = time.Second
+	bookResyncMaxAttempts = 5
+)
+
+// tradingProtocolVersion is the /ws/trading protocol spoken by this server.
+// Bump it for incompatible message changes and keep the old version listed in
+// tradingProtocolVersions while it is still served.
+const tradingProtocolVersion = 1
+
+var tradingProtocolVersions = []int{tradingProtocolVersion}
+
+// tradingActions are the client actions accepted after the handshake
+var tradingActions = []string{"connect", "subscribe", "unsubscribe", "list_subscriptions", "reauth", "place_order", "cancel_order", "ping"}
+
+// tradingUpgrader negotiates permessage-deflate with browser clients, since full
+// orderbook snapshots dominate the bandwidth of /ws/trading
+var tradingUpgrader = websocket.Upgrader{
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Allow all origins for development
+	},
+}
+
+// ExchangeConnLimits caps simultaneous exchange connections; a zero field disables that cap
+type ExchangeConnLimits struct {
+	Global  int // across all users
+	PerUser int // distinct API keys the clients of one user are connected to
+}
+
+// Error codes carried in the code field of "error" frames
+const (
+	ErrorCodeExchangeConnLimit     = "exchange_connection_limit"
+	ErrorCodeUserExchangeConnLimit = "user_exchange_connection_limit"
+)
+
+var errExchangeConnLimit = errors.New("too many exchange connections, try again later")
+
+var (
+	errTradingPasswordChange = errors.New("password change required")
+	errTradingIPNotAllowed   = errors.New(errorCodeIPNotAllowed)
+	errTradingPermission     = errors.New("permission denied")
 )
 
 // TradingStreamManager manages WebSocket connections for trading data
@@ -38,24 +101,119 @@ type TradingStreamManager struct {
 	apiKeyRepo    adaptor.APIKeyRepository
 
 	clients map[*websocket.Conn]*ClientState
-	writes  map[*websocket.Conn]*sync.Mutex
+	queues  map[*websocket.Conn]*sendQueue
 	mu      sync.RWMutex
 
+	// sendQueueSize bounds each client's outbox of orderbook and kline frames
+	sendQueueSize int
+
 	// Exchange connections per API Key
 	exchangeConns map[string]*ExchangeConnection
 	exchangeMu    sync.RWMutex
 
+	metrics tradingMetrics
+
+	// enforceTokenExpiry closes client connections once their JWT expires
+	// unless they reauthenticate first
+	enforceTokenExpiry bool
+
+	// exchangeIdleTimeout tears down exchange connections that have had no
+	// subscriptions for this long; zero disables it
+	exchangeIdleTimeout time.Duration
+
+	exchangeLimits ExchangeConnLimits
+
+	// endpoints resolves the exchange base URLs; nil uses the defaults
+	endpoints *model.ExchangeEndpoints
+
+	// rawBalanceEvents keeps forwarding the platform specific "account" and
+	// "asset" frames next to the normalized "balance" ones
+	rawBalanceEvents bool
+
+	// recorder persists kline and trade events when recording is enabled; nil otherwise
+	recorder adaptor.RecordingUseCase
+
+	// orderEvents persists order updates when enabled; nil otherwise
+	orderEvents adaptor.OrderEventUseCase
+
+	// alerts evaluates alert rules against spread and price data; nil disables alerting
+	alerts adaptor.AlertUseCase
+
+	// notifier receives critical events such as a rejected private stream; nil when disabled
+	notifier adaptor.NotificationUseCase
+
+	// limiter is shared with the kline manager to cap total WebSocket clients
+	limiter *clientLimiter
+
+	done   chan struct{}
 	closed bool
 }
 
+// tradingMetrics holds counters reported by Stats
+type tradingMetrics struct {
+	framesSent      atomic.Int64
+	bytesSent       atomic.Int64 // message payloads before compression
+	wireBytes       atomic.Int64 // bytes written to client sockets, after compression
+	framesCoalesced atomic.Int64 // orderbook and kline frames replaced by a newer one before being sent
+	bytesCoalesced  atomic.Int64
+	writeErrors     atomic.Int64 // frames lost because the write to the client failed
+	framesDropped   atomic.Int64 // orderbook/kline frames dropped from a full send queue
+}
+
+// clientMetrics holds the per-client counters reported by Stats
+type clientMetrics struct {
+	framesSent      atomic.Int64
+	bytesSent       atomic.Int64
+	wireBytes       atomic.Int64
+	framesCoalesced atomic.Int64
+	writeErrors     atomic.Int64
+	framesDropped   atomic.Int64
+}
+
 // ClientState tracks a client's subscriptions
 type ClientState struct {
-	UserID        string
-	APIKeyID      string
-	Subscriptions map[string]bool // subscription key -> active
-	BlockedSubs   map[string]bool // subscription key -> block streaming until ready (e.g. while sending history)
+	ID             string // session ID, unique per connection
+	UserID         string
+	IP             string // client address at upgrade, checked again on reauth
+	APIKeyID       string
+	ConnectedAt    time.Time
+	Subscriptions  map[string]bool // subscription key -> active
+	BlockedSubs    map[string]bool // subscription key -> block streaming until ready (e.g. while sending history)
+	Compressed     bool            // whether permessage-deflate was negotiated
+	Scopes         []model.APIKeyScope
+	User           *model.UserWithRoles // authorization from the last (re)authentication
+	TokenExpiresAt time.Time            // zero if the token has no expiry
+	expiryWarned   bool                 // token_expiring already sent for TokenExpiresAt
+	token          string               // the last (re)authentication token, re-validated periodically
+	// ProtocolVersion is the version agreed in the hello handshake; 0 until then
+	ProtocolVersion int
+
+	DepthThrottles map[string]*depthThrottle // orderbook subscription key -> per-client throttle
+	KlineThrottles map[string]*depthThrottle // kline subscription key -> per-client conflation of the open candle
+	BookSubs       map[string]bool           // orderbook subscription key -> wants the maintained book, not raw diffs
+	DepthModes     map[string]string         // orderbook subscription key -> depthModePartial or depthModeDiff frames
+	Metrics        *clientMetrics
+}
+
+// depthThrottle coalesces orderbook or kline frames for a single client and
+// stream, sending at most one frame per interval and always the latest one
+type depthThrottle struct {
+	interval time.Duration
+	key      string // subscription key, for coalescing in the send queue
+
+	mu       sync.Mutex
+	lastSent time.Time
+	pending  []byte
+	candle   int64 // open time of the pending kline frame, 0 for orderbooks
+	timer    *time.Timer
 }
 
+// Pools for decompressing BTCC frames on the hot path
+var (
+	flateReaderPool sync.Pool // io.ReadCloser implementing flate.Resetter
+	flateBufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+)
+
 // ExchangeConnection manages connection to an exchange
 type ExchangeConnection struct {
 	APIKeyID  string
@@ -76,52 +234,102 @@ type ExchangeConnection struct {
 	// Connected clients
 	Clients map[*websocket.Conn]bool
 
-	// BTCC specific
-	btccMsgID  int64 // atomic counter for BTCC message IDs
-	btccAuthed bool  // whether BTCC connection is authenticated
-	btccAuthID int64 // last auth request id (used to disambiguate auth vs subscribe ack)
-	depthCache map[string]*depthCache
+	// BTCC specific; btccAuthed and btccAuthID are guarded by mu like the
+	// sockets and subscription maps
+	btccIDs     btcc.RequestIDs // BTCC request ID allocator
+	btccAuthed  bool            // whether BTCC connection is authenticated
+	btccAuthID  int64           // last auth request id (used to disambiguate auth vs subscribe ack)
+	btccWriteMu sync.Mutex      // serializes requests on the BTCC sockets, which allow one writer
+	depthCache  map[string]*depthCache
+
+	// Binance maintained books (mode "book"), keyed by upper-case symbol
+	books map[string]*maintainedBook
+
+	idleSince time.Time // when the last subscription was removed; zero while subscribed
 
 	mu     sync.RWMutex
 	done   chan struct{}
 	closed int32 // atomic flag to prevent double close
-}
 
-// BTCCRequest represents a BTCC WebSocket request message
-type BTCCRequest struct {
-	ID     int64       `json:"id"`
-	Method string      `json:"method"`
-	Params interface{} `json:"params"`
+	// readers tracks the goroutines reading PublicWS and PrivateWS; each one
+	// owns the socket it was started with and exits when that socket fails
+	readers sync.WaitGroup
 }
 
-// BTCCResponse represents a BTCC WebSocket response message
-type BTCCResponse struct {
-	ID     *int64          `json:"id"`
-	Method string          `json:"method,omitempty"`
-	Error  *BTCCError      `json:"error"`
-	Result json.RawMessage `json:"result"`
-	Params json.RawMessage `json:"params,omitempty"`
+// startReader runs read on ws in a goroutine tracked by ec.readers
+func (ec *ExchangeConnection) startReader(ws *websocket.Conn, read func(*ExchangeConnection, *websocket.Conn)) {
+	ec.readers.Add(1)
+	go func() {
+		defer ec.readers.Done()
+		read(ec, ws)
+	}()
 }
 
-// BTCCError represents a BTCC error response
-type BTCCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+// shutdown stops the background loops, closes both sockets and waits for
+// their readers to return
+func (ec *ExchangeConnection) shutdown() {
+	if atomic.CompareAndSwapInt32(&ec.closed, 0, 1) {
+		close(ec.done)
+	}
+	ec.mu.Lock()
+	if ec.PublicWS != nil {
+		ec.PublicWS.Close()
+	}
+	if ec.PrivateWS != nil {
+		ec.PrivateWS.Close()
+	}
+	ec.mu.Unlock()
+	ec.readers.Wait()
 }
 
 func NewTradingStreamManager(
 	apiKeyUseCase adaptor.APIKeyUseCase,
 	authUseCase adaptor.AuthUseCase,
 	apiKeyRepo adaptor.APIKeyRepository,
+	enforceTokenExpiry bool,
+	exchangeIdleTimeout time.Duration,
+	exchangeLimits ExchangeConnLimits,
+	endpoints *model.ExchangeEndpoints,
+	rawBalanceEvents bool,
+	recorder adaptor.RecordingUseCase,
+	orderEvents adaptor.OrderEventUseCase,
+	alerts adaptor.AlertUseCase,
+	notifier adaptor.NotificationUseCase,
+	sendQueueSize int,
+	limiter *clientLimiter,
 ) *TradingStreamManager {
-	return &TradingStreamManager{
-		apiKeyUseCase: apiKeyUseCase,
-		authUseCase:   authUseCase,
-		apiKeyRepo:    apiKeyRepo,
-		clients:       make(map[*websocket.Conn]*ClientState),
-		writes:        make(map[*websocket.Conn]*sync.Mutex),
-		exchangeConns: make(map[string]*ExchangeConnection),
-	}
+	if sendQueueSize <= 0 {
+		sendQueueSize = defaultClientSendQueue
+	}
+	m := &TradingStreamManager{
+		apiKeyUseCase:       apiKeyUseCase,
+		authUseCase:         authUseCase,
+		apiKeyRepo:          apiKeyRepo,
+		clients:             make(map[*websocket.Conn]*ClientState),
+		queues:              make(map[*websocket.Conn]*sendQueue),
+		sendQueueSize:       sendQueueSize,
+		exchangeConns:       make(map[string]*ExchangeConnection),
+		enforceTokenExpiry:  enforceTokenExpiry,
+		exchangeIdleTimeout: exchangeIdleTimeout,
+		exchangeLimits:      exchangeLimits,
+		endpoints:           endpoints,
+		rawBalanceEvents:    rawBalanceEvents,
+		recorder:            recorder,
+		orderEvents:         orderEvents,
+		alerts:              alerts,
+		notifier:            notifier,
+		limiter:             limiter,
+		done:                make(chan struct{}),
+	}
+
+	if enforceTokenExpiry {
+		go m.tokenSweepLoop()
+	}
+	go m.revalidateLoop()
+	if exchangeIdleTimeout > 0 {
+		go m.idleSweepLoop()
+	}
+	return m
 }
 
 func (m *TradingStreamManager) isClosed() bool {
@@ -136,6 +344,13 @@ func (m *TradingStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if !m.limiter.acquire() {
+		logs.Warnf("websocket client limit (%d) reached, rejecting trading client %s", m.limiter.limit(), r.RemoteAddr)
+		http.Error(w, "too many clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer m.limiter.release()
+
 	// Authenticate via token query parameter
 	token := r.URL.Query().Get("token")
 	if token == "" {
@@ -148,17 +363,24 @@ func (m *TradingStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ip := clientIP(r)
+	if err := authorizeTradingUser(user, ip); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	metrics := &clientMetrics{}
+	wire := &wireCountingWriter{ResponseWriter: w, counters: []*atomic.Int64{&m.metrics.wireBytes, &metrics.wireBytes}}
+	conn, err := tradingUpgrader.Upgrade(wire, r, nil)
 	if err != nil {
-		log.Printf("websocket upgrade error: %v", err)
+		logs.Errorf("websocket upgrade error: %v", err)
 		return
 	}
+	compressed := strings.Contains(r.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
 
 	// Heartbeat: set read deadline + pong handler
 	conn.SetReadLimit(1 << 20) // 1MB safeguard
 	if err := conn.SetReadDeadline(time.Now().Add(clientPongWait)); err != nil {
-		log.Printf("websocket SetReadDeadline error: %v", err)
+		logs.Warnf("websocket SetReadDeadline error: %v", err)
 		conn.Close()
 		return
 	}
@@ -177,33 +399,57 @@ func (m *TradingStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		return
 	}
 	m.clients[conn] = &ClientState{
-		UserID:        user.ID,
-		Subscriptions: make(map[string]bool),
-		BlockedSubs:   make(map[string]bool),
-	}
-	m.writes[conn] = &sync.Mutex{}
+		ID:             newSessionID(),
+		UserID:         user.ID,
+		IP:             ip,
+		User:           user,
+		ConnectedAt:    time.Now(),
+		Subscriptions:  make(map[string]bool),
+		BlockedSubs:    make(map[string]bool),
+		Compressed:     compressed,
+		DepthThrottles: make(map[string]*depthThrottle),
+		KlineThrottles: make(map[string]*depthThrottle),
+		BookSubs:       make(map[string]bool),
+		DepthModes:     make(map[string]string),
+		Metrics:        metrics,
+	}
+	queue := newSendQueue(m.sendQueueSize)
+	m.queues[conn] = queue
 	m.mu.Unlock()
+	go m.writeLoop(conn, queue, metrics)
+	m.setToken(conn, token)
 
-	log.Printf("new trading client connected: %s, userID=%s", conn.RemoteAddr().String(), user.ID)
+	logs.Infof("new trading client connected: %s, userID=%s", conn.RemoteAddr().String(), user.ID)
 
-	// Send connected confirmation
+	// Open the handshake; the client must answer with a hello action before anything else
 	m.sendToClient(conn, model.TradingWebSocketResponse{
-		Type:      "connected",
+		Type: "hello",
+		Data: map[string]interface{}{
+			"protocolVersion":     tradingProtocolVersion,
+			"supportedVersions":   tradingProtocolVersions,
+			"heartbeatIntervalMs": clientPingInterval.Milliseconds(),
+			"idleTimeoutMs":       clientPongWait.Milliseconds(),
+			"actions":             tradingActions,
+		},
 		Timestamp: time.Now().UnixMilli(),
 	})
 
 	defer func() {
-		log.Printf("remove client: %s", conn.RemoteAddr().String())
+		logs.Debugf("remove client: %s", conn.RemoteAddr().String())
 		m.removeClient(conn)
 		close(stopHeartbeat)
-		conn.Close()
+		if m.isClosed() {
+			m.closeClient(conn, CloseCodeShutdown, CloseReasonShutdown)
+		} else {
+			m.closeClient(conn, CloseCodeNormal, CloseReasonClientGone)
+		}
 	}()
 
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("websocket error: %v", err)
+				logs.Warnf("websocket error: %v", err)
 			}
 			break
 		}
@@ -215,14 +461,14 @@ func (m *TradingStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 			break
 		}
 
-		log.Printf("received raw message: %s", string(message))
+		logs.Debugf("received raw message: %s", redactPayload(message))
 		var msg model.TradingWebSocketMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("invalid message format: %v", err)
+			logs.Warnf("invalid message format: %v", err)
 			m.sendError(conn, "invalid message format")
 			continue
 		}
-		log.Printf("parsed message: action=%s, apiKeyID=%s, type=%s, symbol=%s", msg.Action, msg.APIKeyID, msg.Type, msg.Symbol)
+		logs.Debugf("parsed message: action=%s, apiKeyID=%s, type=%s, symbol=%s", msg.Action, msg.APIKeyID, msg.Type, msg.Symbol)
 
 		m.handleMessage(conn, user.ID, &msg)
 	}
@@ -249,7 +495,17 @@ func (m *TradingStreamManager) clientPingLoop(conn *websocket.Conn, stop <-chan
 }
 
 func (m *TradingStreamManager) handleMessage(conn *websocket.Conn, userID string, msg *model.TradingWebSocketMessage) {
-	log.Printf("handleMessage: action=%s, apiKeyID=%s, type=%s, symbol=%s", msg.Action, msg.APIKeyID, msg.Type, msg.Symbol)
+	logs.Debugf("handleMessage: action=%s, apiKeyID=%s, type=%s, symbol=%s", msg.Action, msg.APIKeyID, msg.Type, msg.Symbol)
+	if msg.Action == "hello" {
+		m.handleHello(conn, msg.Version)
+		return
+	}
+	if m.protocolVersion(conn) == 0 {
+		m.sendError(conn, "handshake required: send hello before "+msg.Action)
+		m.closeClient(conn, CloseCodeProtocol, CloseReasonProtocolError)
+		return
+	}
+
 	switch msg.Action {
 	case "connect":
 		m.handleConnect(conn, userID, msg.APIKeyID)
@@ -257,6 +513,12 @@ func (m *TradingStreamManager) handleMessage(conn *websocket.Conn, userID string
 		m.handleSubscribe(conn, userID, msg)
 	case "unsubscribe":
 		m.handleUnsubscribe(conn, msg)
+	case "list_subscriptions":
+		m.sendSubscriptions(conn)
+	case "reauth":
+		m.handleReauth(conn, userID, msg.Token)
+	case "place_order", "cancel_order":
+		m.handleOrderAction(conn, msg)
 	case "ping":
 		m.sendToClient(conn, model.TradingWebSocketResponse{
 			Type:      "pong",
@@ -267,187 +529,845 @@ func (m *TradingStreamManager) handleMessage(conn *websocket.Conn, userID string
 	}
 }
 
-func (m *TradingStreamManager) handleConnect(conn *websocket.Conn, userID string, apiKeyID string) {
-	log.Printf("handleConnect: userID=%s, apiKeyID=%s", userID, apiKeyID)
+// handleHello completes the handshake. A zero version accepts the server's
+// current version; an unsupported one closes the connection.
+func (m *TradingStreamManager) handleHello(conn *websocket.Conn, version int) {
+	if version == 0 {
+		version = tradingProtocolVersion
+	}
+	supported := false
+	for _, v := range tradingProtocolVersions {
+		if v == version {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		m.sendError(conn, fmt.Sprintf("unsupported protocol version %d", version))
+		m.closeClient(conn, CloseCodeProtocol, CloseReasonProtocolError)
+		return
+	}
 
-	// Get the API key (full, with secret)
-	apiKey, err := m.apiKeyRepo.GetByID(context.Background(), apiKeyID)
-	if err != nil {
-		log.Printf("handleConnect: GetByID error: %v", err)
-		m.sendError(conn, "API key not found")
+	m.mu.Lock()
+	state, ok := m.clients[conn]
+	already := ok && state.ProtocolVersion != 0
+	if ok && !already {
+		state.ProtocolVersion = version
+	}
+	m.mu.Unlock()
+	if !ok {
 		return
 	}
-	if apiKey == nil {
-		log.Printf("handleConnect: API key not found for ID: %s", apiKeyID)
-		m.sendError(conn, "API key not found")
+	if already {
+		m.sendError(conn, "handshake already completed")
 		return
 	}
-	log.Printf("handleConnect: found apiKey: ID=%s, Name=%s, Platform=%s", apiKey.ID, apiKey.Name, apiKey.Platform)
 
-	// // Verify ownership
-	// if apiKey.UserID != userID {
-	// 	m.sendError(conn, "unauthorized: API key does not belong to you")
-	// 	return
-	// }
+	m.sendToClient(conn, model.TradingWebSocketResponse{
+		Type:      "ready",
+		Data:      map[string]interface{}{"protocolVersion": version},
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
 
-	if !apiKey.IsActive {
-		m.sendError(conn, "API key is not active")
+func (m *TradingStreamManager) protocolVersion(conn *websocket.Conn) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if state, ok := m.clients[conn]; ok {
+		return state.ProtocolVersion
+	}
+	return 0
+}
+
+// authorizeTradingUser checks the account rules an authenticated user must
+// pass to use the trading stream from ip, both at upgrade and on reauth
+func authorizeTradingUser(user *model.UserWithRoles, ip string) error {
+	if user.MustChangePassword {
+		return errTradingPasswordChange
+	}
+	if !user.AllowsIP(ip) {
+		return errTradingIPNotAllowed
+	}
+	if !user.HasPermission(enum.PermissionViewTrading) {
+		return errTradingPermission
+	}
+	return nil
+}
+
+// handleReauth re-validates the connection with a fresh token; the connection
+// is closed if the token is invalid, belongs to a different user or the user
+// is no longer allowed on the trading stream
+func (m *TradingStreamManager) handleReauth(conn *websocket.Conn, userID string, token string) {
+	user, err := m.authUseCase.ValidateToken(context.Background(), token)
+	if err != nil || user == nil || user.ID != userID {
+		m.sendError(conn, "reauthentication failed")
+		m.closeClient(conn, CloseCodeAuth, CloseReasonReauthFailed)
 		return
 	}
 
-	// Only support Binance and BTCC
-	if apiKey.Platform != model.PlatformBinance && apiKey.Platform != model.PlatformBTCC {
-		m.sendError(conn, "unsupported platform: "+apiKey.Platform.String())
+	var ip string
+	m.mu.RLock()
+	if state, ok := m.clients[conn]; ok {
+		ip = state.IP
+	}
+	m.mu.RUnlock()
+	if err := authorizeTradingUser(user, ip); err != nil {
+		m.sendError(conn, "reauthentication failed: "+err.Error())
+		m.closeClient(conn, CloseCodePermission, CloseReasonReauthFailed)
 		return
 	}
 
-	// Update client state
 	m.mu.Lock()
 	if state, ok := m.clients[conn]; ok {
-		state.APIKeyID = apiKeyID
+		state.User = user
 	}
 	m.mu.Unlock()
+	expiresAt := m.setToken(conn, token)
 
-	// Get or create exchange connection
-	m.getOrCreateExchangeConn(apiKey)
-
-	// Add client to exchange connection
-	m.exchangeMu.RLock()
-	if ec, ok := m.exchangeConns[apiKeyID]; ok {
-		ec.mu.Lock()
-		ec.Clients[conn] = true
-		ec.mu.Unlock()
+	data := map[string]interface{}{"userId": user.ID}
+	if !expiresAt.IsZero() {
+		data["expiresAt"] = expiresAt.UnixMilli()
 	}
-	m.exchangeMu.RUnlock()
-
-	log.Println("connected! send data back")
-	// Send confirmation
 	m.sendToClient(conn, model.TradingWebSocketResponse{
-		Type:      "connected",
-		Platform:  apiKey.Platform.String(),
+		Type:      "reauthenticated",
+		Data:      data,
 		Timestamp: time.Now().UnixMilli(),
-		Data: map[string]interface{}{
-			"apiKeyId":  apiKeyID,
-			"platform":  apiKey.Platform.String(),
-			"isTestnet": apiKey.IsTestnet,
-			"name":      apiKey.Name,
-		},
 	})
 }
 
-func (m *TradingStreamManager) getOrCreateExchangeConn(apiKey *model.APIKey) *ExchangeConnection {
-	m.exchangeMu.Lock()
-	defer m.exchangeMu.Unlock()
+// setToken records the token and its expiry on the client state for the
+// sweeps. It returns the expiry, or the zero time if the token has none.
+func (m *TradingStreamManager) setToken(conn *websocket.Conn, token string) time.Time {
+	var expiresAt time.Time
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err == nil && claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
 
-	if ec, ok := m.exchangeConns[apiKey.ID]; ok {
-		return ec
+	m.mu.Lock()
+	if state, ok := m.clients[conn]; ok {
+		state.TokenExpiresAt = expiresAt
+		state.expiryWarned = false
+		state.token = token
 	}
+	m.mu.Unlock()
 
-	config := model.GetExchangeConfig(apiKey.Platform, apiKey.IsTestnet)
+	return expiresAt
+}
 
-	ec := &ExchangeConnection{
-		APIKeyID:    apiKey.ID,
-		Platform:    apiKey.Platform,
-		IsTestnet:   apiKey.IsTestnet,
-		APIKey:      apiKey.APIKey,
-		APISecret:   apiKey.APISecret,
-		Config:      config,
-		PublicSubs:  make(map[string]bool),
-		PrivateSubs: make(map[string]bool),
-		Clients:     make(map[*websocket.Conn]bool),
-		done:        make(chan struct{}),
-	}
+// idleSweepLoop tears down exchange connections whose subscriptions have all
+// been removed for longer than exchangeIdleTimeout
+func (m *TradingStreamManager) idleSweepLoop() {
+	ticker := time.NewTicker(idleSweepInterval)
+	defer ticker.Stop()
 
-	m.exchangeConns[apiKey.ID] = ec
-	return ec
+	for {
+		select {
+		case <-ticker.C:
+			m.sweepIdleExchangeConns(time.Now())
+		case <-m.done:
+			return
+		}
+	}
 }
 
-func (m *TradingStreamManager) handleSubscribe(conn *websocket.Conn, userID string, msg *model.TradingWebSocketMessage) {
-	m.mu.RLock()
-	state, ok := m.clients[conn]
-	m.mu.RUnlock()
+func (m *TradingStreamManager) sweepIdleExchangeConns(now time.Time) {
+	type idleConn struct {
+		apiKeyID string
+		ec       *ExchangeConnection
+		clients  []*websocket.Conn
+	}
+	var idle []idleConn
 
-	if !ok || state.APIKeyID == "" {
-		m.sendError(conn, "not connected to any API key, call connect first")
-		return
+	// An idle connection leaves the map while both locks are held, so a
+	// subscribe either lands before the check and keeps it, or finds it gone
+	// and creates a new one
+	m.exchangeMu.Lock()
+	for apiKeyID, ec := range m.exchangeConns {
+		ec.mu.Lock()
+		switch {
+		case len(ec.PublicSubs) > 0 || len(ec.PrivateSubs) > 0:
+			ec.idleSince = time.Time{}
+		case ec.idleSince.IsZero():
+			ec.idleSince = now
+		case now.Sub(ec.idleSince) >= m.exchangeIdleTimeout:
+			clients := make([]*websocket.Conn, 0, len(ec.Clients))
+			for c := range ec.Clients {
+				clients = append(clients, c)
+			}
+			delete(m.exchangeConns, apiKeyID)
+			idle = append(idle, idleConn{apiKeyID: apiKeyID, ec: ec, clients: clients})
+		}
+		ec.mu.Unlock()
 	}
+	m.exchangeMu.Unlock()
 
-	m.exchangeMu.RLock()
-	ec, ok := m.exchangeConns[state.APIKeyID]
-	m.exchangeMu.RUnlock()
+	for _, ic := range idle {
+		ic.ec.shutdown()
+		for _, c := range ic.clients {
+			m.sendToClient(c, model.TradingWebSocketResponse{
+				Type:      "disconnected",
+				Platform:  ic.ec.Platform.String(),
+				Timestamp: now.UnixMilli(),
+				Data: map[string]interface{}{
+					"apiKeyId": ic.apiKeyID,
+					"reason":   "idle",
+				},
+			})
+		}
+		logs.Infof("closed idle exchange connection for apiKeyID=%s", ic.apiKeyID)
+	}
+}
 
-	if !ok {
-		m.sendError(conn, "exchange connection not found")
-		return
+// tokenSweepLoop periodically warns clients whose token is about to expire
+// and closes those whose token has expired without a reauth
+func (m *TradingStreamManager) tokenSweepLoop() {
+	ticker := time.NewTicker(tokenSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.sweepTokens(time.Now())
+		case <-m.done:
+			return
+		}
 	}
+}
+
+func (m *TradingStreamManager) sweepTokens(now time.Time) {
+	var warn, expired []*websocket.Conn
+	warnAt := make(map[*websocket.Conn]time.Time)
 
-	subKey := m.subscriptionKey(msg.Type, msg.Symbol, msg.Interval)
-	wideKey := m.subscriptionKey(msg.Type, msg.Symbol, "")
 	m.mu.Lock()
-	if s, ok := m.clients[conn]; ok {
-		s.Subscriptions[subKey] = true
-		if msg.Type == "kline" {
-			s.Subscriptions[wideKey] = true
+	for conn, state := range m.clients {
+		if state.TokenExpiresAt.IsZero() {
+			continue
+		}
+		switch {
+		case !now.Before(state.TokenExpiresAt):
+			expired = append(expired, conn)
+		case !state.expiryWarned && state.TokenExpiresAt.Sub(now) <= tokenExpiryWarning:
+			state.expiryWarned = true
+			warn = append(warn, conn)
+			warnAt[conn] = state.TokenExpiresAt
 		}
 	}
 	m.mu.Unlock()
 
-	switch msg.Type {
-	case "kline":
-		if ec.Platform == model.PlatformBTCC {
-			m.mu.Lock()
-			if s, ok := m.clients[conn]; ok {
-				s.BlockedSubs[subKey] = true
-				s.BlockedSubs[wideKey] = true
-			}
-			m.mu.Unlock()
+	for _, conn := range warn {
+		m.sendToClient(conn, model.TradingWebSocketResponse{
+			Type:      "token_expiring",
+			Data:      map[string]interface{}{"expiresAt": warnAt[conn].UnixMilli()},
+			Timestamp: now.UnixMilli(),
+		})
+	}
+	for _, conn := range expired {
+		logs.Infof("trading client token expired: %s", conn.RemoteAddr().String())
+		m.sendError(conn, "token expired")
+		m.closeClient(conn, CloseCodeAuth, CloseReasonTokenExpired)
+	}
+}
 
-			m.sendBTCCKlineHistory(conn, ec, msg.Symbol, msg.Interval)
+// revalidateLoop periodically re-checks every connection's token, so revoked
+// sessions, deactivated users and lost permissions end their streams even
+// when token expiry is not enforced
+func (m *TradingStreamManager) revalidateLoop() {
+	ticker := time.NewTicker(tokenRevalidateInterval)
+	defer ticker.Stop()
 
-			m.mu.Lock()
-			if s, ok := m.clients[conn]; ok {
-				delete(s.BlockedSubs, subKey)
-				delete(s.BlockedSubs, wideKey)
-			}
-			m.mu.Unlock()
-		}
-		m.subscribeKline(conn, ec, msg.Symbol, msg.Interval)
-	case "orderbook", "depth":
-		m.subscribeOrderBook(conn, ec, msg.Symbol)
-	case "order":
-		m.subscribeOrders(conn, ec, msg.Symbol)
-	case "asset":
-		// Asset subscription (BTCC specific)
-		if ec.Platform == model.PlatformBTCC {
-			m.subscribeAsset(conn, ec)
-		} else {
-			m.sendError(conn, "asset subscription not supported for this platform")
-		}
-	case "trades", "deals":
-		// Trade/deal subscription
-		m.subscribeTrades(conn, ec, msg.Symbol)
-	case "state":
-		// Market state subscription (BTCC specific)
-		if ec.Platform == model.PlatformBTCC {
-			m.subscribeMarketState(conn, ec)
-		} else {
-			m.sendError(conn, "state subscription not supported for this platform")
+	for {
+		select {
+		case <-ticker.C:
+			m.revalidateClients(time.Now())
+		case <-m.done:
+			return
 		}
-	default:
-		m.sendError(conn, "unknown subscription type: "+msg.Type)
 	}
 }
 
-// subscribeTrades subscribes to trade/deal updates
-func (m *TradingStreamManager) subscribeTrades(conn *websocket.Conn, ec *ExchangeConnection, symbol string) {
-	streamName := ""
-	switch ec.Platform {
-	case model.PlatformBinance:
-		streamName = strings.ToLower(symbol) + "@trade"
-	case model.PlatformBTCC:
-		streamName = "deals." + symbol
-	default:
-		streamName = strings.ToLower(symbol) + "@trade"
+func (m *TradingStreamManager) revalidateClients(now time.Time) {
+	type client struct {
+		conn   *websocket.Conn
+		userID string
+		ip     string
+		token  string
+	}
+	var clients []client
+
+	m.mu.RLock()
+	for conn, state := range m.clients {
+		// Expired tokens fail validation for their expiry alone; closing them
+		// is the expiry sweep's job, and only when it is enforced
+		if state.token == "" || (!state.TokenExpiresAt.IsZero() && !now.Before(state.TokenExpiresAt)) {
+			continue
+		}
+		clients = append(clients, client{conn: conn, userID: state.UserID, ip: state.IP, token: state.token})
+	}
+	m.mu.RUnlock()
+
+	for _, c := range clients {
+		user, err := m.authUseCase.ValidateToken(context.Background(), c.token)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidToken), errors.Is(err, usecase.ErrUserInactive),
+			errors.Is(err, usecase.ErrUserNotFound), err == nil && (user == nil || user.ID != c.userID):
+			logs.Infof("trading client token revoked: %s", c.conn.RemoteAddr().String())
+			m.sendError(c.conn, "token revoked")
+			m.closeClient(c.conn, CloseCodeAuth, CloseReasonTokenRevoked)
+			continue
+		case err != nil:
+			// A lookup failure says nothing about the token; try again next time
+			logs.Warnf("revalidate trading client %s: %v", c.conn.RemoteAddr().String(), err)
+			continue
+		}
+
+		if err := authorizeTradingUser(user, c.ip); err != nil {
+			m.sendError(c.conn, "access revoked: "+err.Error())
+			m.closeClient(c.conn, CloseCodePermission, CloseReasonAccessRevoked)
+			continue
+		}
+		m.mu.Lock()
+		if state, ok := m.clients[c.conn]; ok && state.token == c.token {
+			state.User = user
+		}
+		m.mu.Unlock()
+	}
+}
+
+// closeClient sends a close frame with a typed reason and closes the
+// connection; the read loop then exits and removes the client. Frames already
+// queued (such as the error explaining the close) are flushed first, for at
+// most clientWriteWait.
+func (m *TradingStreamManager) closeClient(conn *websocket.Conn, code int, reason string) {
+	m.mu.RLock()
+	queue := m.queues[conn]
+	m.mu.RUnlock()
+
+	if queue != nil {
+		queue.finish(formatCloseMessage(code, reason))
+		timer := time.NewTimer(clientWriteWait)
+		defer timer.Stop()
+		select {
+		case <-queue.done:
+			conn.Close()
+			return
+		case <-timer.C:
+		}
+	}
+
+	deadline := time.Now().Add(clientWriteWait)
+	_ = conn.WriteControl(websocket.CloseMessage, formatCloseMessage(code, reason), deadline)
+	conn.Close()
+}
+
+// DisconnectUser closes every trading connection of a user, returning how many were closed
+func (m *TradingStreamManager) DisconnectUser(userID string, code int, reason string) int {
+	m.mu.RLock()
+	conns := make([]*websocket.Conn, 0)
+	for conn, state := range m.clients {
+		if state.UserID == userID {
+			conns = append(conns, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range conns {
+		m.closeClient(conn, code, reason)
+	}
+	return len(conns)
+}
+
+// SessionsForUser lists the user's open trading connections
+func (m *TradingStreamManager) SessionsForUser(userID string) []model.TradingSession {
+	return m.sessions(func(state *ClientState) bool { return state.UserID == userID })
+}
+
+// Sessions returns a read-only snapshot of every open trading connection
+func (m *TradingStreamManager) Sessions() []model.TradingSession {
+	return m.sessions(func(*ClientState) bool { return true })
+}
+
+// sessions snapshots the matching clients under m.mu, then resolves their
+// exchange platforms under exchangeMu so the two locks are never nested
+func (m *TradingStreamManager) sessions(match func(*ClientState) bool) []model.TradingSession {
+	now := time.Now()
+
+	m.mu.RLock()
+	sessions := make([]model.TradingSession, 0)
+	for conn, state := range m.clients {
+		if !match(state) {
+			continue
+		}
+		session := model.TradingSession{
+			ID:            state.ID,
+			RemoteAddr:    conn.RemoteAddr().String(),
+			UserID:        state.UserID,
+			APIKeyID:      state.APIKeyID,
+			ConnectedAt:   state.ConnectedAt,
+			DurationMs:    now.Sub(state.ConnectedAt).Milliseconds(),
+			Subscriptions: m.subscriptionSnapshot(state),
+		}
+		if !state.TokenExpiresAt.IsZero() {
+			expiresAt := state.TokenExpiresAt
+			session.TokenExpiresAt = &expiresAt
+		}
+		sessions = append(sessions, session)
+	}
+	m.mu.RUnlock()
+
+	m.exchangeMu.RLock()
+	for i := range sessions {
+		if ec, ok := m.exchangeConns[sessions[i].APIKeyID]; ok {
+			sessions[i].Platform = ec.Platform
+		}
+	}
+	m.exchangeMu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt)
+	})
+	return sessions
+}
+
+// DisconnectAPIKey closes every trading connection attached to an API key and
+// drops its exchange connection, returning how many clients were closed and
+// whether an exchange connection was torn down
+func (m *TradingStreamManager) DisconnectAPIKey(apiKeyID string, code int, reason string) (int, bool) {
+	m.mu.RLock()
+	conns := make([]*websocket.Conn, 0)
+	for conn, state := range m.clients {
+		if state.APIKeyID == apiKeyID {
+			conns = append(conns, conn)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, conn := range conns {
+		m.closeClient(conn, code, reason)
+	}
+	return len(conns), m.cleanupExchangeConn(apiKeyID)
+}
+
+// exchangeNetworkChanged reports whether the live exchange connection of an
+// API key points at another network than isTestnet, as after the key's
+// testnet flag was flipped
+func (m *TradingStreamManager) exchangeNetworkChanged(apiKeyID string, isTestnet bool) bool {
+	m.exchangeMu.RLock()
+	defer m.exchangeMu.RUnlock()
+	ec, ok := m.exchangeConns[apiKeyID]
+	return ok && ec.IsTestnet != isTestnet
+}
+
+// DisconnectSession closes the trading connection with the given session ID,
+// returning its session or nil if no such connection is open
+func (m *TradingStreamManager) DisconnectSession(sessionID string, code int, reason string) *model.TradingSession {
+	var target *websocket.Conn
+	m.mu.RLock()
+	for conn, state := range m.clients {
+		if state.ID == sessionID {
+			target = conn
+			break
+		}
+	}
+	m.mu.RUnlock()
+	if target == nil {
+		return nil
+	}
+
+	sessions := m.sessions(func(state *ClientState) bool { return state.ID == sessionID })
+	m.closeClient(target, code, reason)
+	if len(sessions) == 0 {
+		// Closed by its own read loop in between; report what we know
+		return &model.TradingSession{ID: sessionID, RemoteAddr: target.RemoteAddr().String()}
+	}
+	return &sessions[0]
+}
+
+// newSessionID returns a random identifier for a trading connection
+func newSessionID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (m *TradingStreamManager) handleConnect(conn *websocket.Conn, userID string, apiKeyID string) {
+	logs.Debugf("handleConnect: userID=%s, apiKeyID=%s", userID, apiKeyID)
+
+	// Get the API key (full, with secret)
+	apiKey, err := m.apiKeyRepo.GetByID(context.Background(), apiKeyID)
+	if err != nil {
+		logs.Errorf("handleConnect: GetByID error: %v", err)
+		m.sendError(conn, "API key not found")
+		return
+	}
+	if apiKey == nil {
+		logs.Warnf("handleConnect: API key not found for ID: %s", apiKeyID)
+		m.sendError(conn, "API key not found")
+		return
+	}
+	logs.Debugf("handleConnect: found apiKey: ID=%s, Name=%s, Platform=%s", apiKey.ID, apiKey.Name, apiKey.Platform)
+
+	// // Verify ownership
+	// if apiKey.UserID != userID {
+	// 	m.sendError(conn, "unauthorized: API key does not belong to you")
+	// 	return
+	// }
+
+	if !apiKey.IsActive {
+		m.sendError(conn, "API key is not active")
+		return
+	}
+
+	if caps, ok := model.GetPlatformCapabilities(apiKey.Platform); !ok || !caps.SupportsStreaming() {
+		m.sendError(conn, "unsupported platform: "+apiKey.Platform.String())
+		return
+	}
+
+	if max := m.exchangeLimits.PerUser; max > 0 {
+		if keys := m.userAPIKeyIDs(userID, conn); !keys[apiKeyID] && len(keys) >= max {
+			logs.Warnf("handleConnect: user %s reached the limit of %d exchange connections", userID, max)
+			m.sendCodedError(conn, ErrorCodeUserExchangeConnLimit,
+				fmt.Sprintf("too many exchange connections for this user (max %d)", max))
+			return
+		}
+	}
+
+	// Get or create exchange connection
+	ec, err := m.getOrCreateExchangeConn(apiKey)
+	if err != nil {
+		logs.Warnf("handleConnect: %v", err)
+		m.sendCodedError(conn, ErrorCodeExchangeConnLimit, err.Error())
+		return
+	}
+
+	// Update client state
+	m.mu.Lock()
+	if state, ok := m.clients[conn]; ok {
+		state.APIKeyID = apiKeyID
+		state.Scopes = apiKey.Scopes
+	}
+	m.mu.Unlock()
+
+	// Add client to exchange connection
+	ec.mu.Lock()
+	ec.Clients[conn] = true
+	ec.mu.Unlock()
+
+	logs.Debugf("handleConnect: connected, sending snapshot")
+	// Send confirmation
+	m.sendToClient(conn, model.TradingWebSocketResponse{
+		Type:      "connected",
+		Platform:  apiKey.Platform.String(),
+		Timestamp: time.Now().UnixMilli(),
+		Data: map[string]interface{}{
+			"apiKeyId":  apiKeyID,
+			"platform":  apiKey.Platform.String(),
+			"isTestnet": apiKey.IsTestnet,
+			"name":      apiKey.Name,
+			"scopes":    apiKey.Scopes,
+		},
+	})
+
+	m.applyDefaultSubscriptions(conn, apiKey.DefaultSubscriptions)
+}
+
+// applyDefaultSubscriptions subscribes a freshly connected client to its API
+// key's default bundle and echoes the applied streams in one snapshot
+func (m *TradingStreamManager) applyDefaultSubscriptions(conn *websocket.Conn, defaults []model.TradingSubscription) {
+	applied := make([]model.TradingSubscription, 0, len(defaults))
+	for _, sub := range defaults {
+		ok := m.subscribe(conn, &model.TradingWebSocketMessage{
+			Action:   "subscribe",
+			Type:     sub.Type,
+			Symbol:   sub.Symbol,
+			Interval: sub.Interval,
+		})
+		if ok {
+			applied = append(applied, sub)
+		}
+	}
+
+	m.sendSubscriptionSnapshot(conn, applied)
+}
+
+// handleOrderAction rejects order placement/cancel for keys without the trade scope
+func (m *TradingStreamManager) handleOrderAction(conn *websocket.Conn, msg *model.TradingWebSocketMessage) {
+	m.mu.RLock()
+	state, ok := m.clients[conn]
+	var apiKey model.APIKey
+	if ok {
+		apiKey = model.APIKey{ID: state.APIKeyID, Scopes: state.Scopes}
+	}
+	m.mu.RUnlock()
+
+	if !ok || apiKey.ID == "" {
+		m.sendError(conn, "not connected to any API key, call connect first")
+		return
+	}
+	if !apiKey.HasScope(model.APIKeyScopeTrade) {
+		m.sendError(conn, usecase.ErrAPIKeyReadOnly.Error())
+		return
+	}
+
+	m.sendError(conn, msg.Action+" is not supported yet")
+}
+
+// userAPIKeyIDs returns the API keys that the user's clients other than
+// exclude are connected to
+func (m *TradingStreamManager) userAPIKeyIDs(userID string, exclude *websocket.Conn) map[string]bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make(map[string]bool)
+	for c, state := range m.clients {
+		if c != exclude && state.UserID == userID && state.APIKeyID != "" {
+			keys[state.APIKeyID] = true
+		}
+	}
+	return keys
+}
+
+// exchangeConfig returns the configured endpoints of platform's network
+func (m *TradingStreamManager) exchangeConfig(platform model.Platform, isTestnet bool) model.ExchangeConfig {
+	return m.endpoints.Config(platform, isTestnet)
+}
+
+// getOrCreateExchangeConn returns the exchange connection of apiKey, creating
+// it unless that would exceed the global limit
+func (m *TradingStreamManager) getOrCreateExchangeConn(apiKey *model.APIKey) (*ExchangeConnection, error) {
+	m.exchangeMu.Lock()
+	defer m.exchangeMu.Unlock()
+
+	if ec, ok := m.exchangeConns[apiKey.ID]; ok {
+		return ec, nil
+	}
+	if max := m.exchangeLimits.Global; max > 0 && len(m.exchangeConns) >= max {
+		return nil, fmt.Errorf("%w (max %d)", errExchangeConnLimit, max)
+	}
+
+	config := m.exchangeConfig(apiKey.Platform, apiKey.IsTestnet)
+
+	ec := &ExchangeConnection{
+		APIKeyID:    apiKey.ID,
+		Platform:    apiKey.Platform,
+		IsTestnet:   apiKey.IsTestnet,
+		APIKey:      apiKey.APIKey,
+		APISecret:   apiKey.APISecret,
+		Config:      config,
+		PublicSubs:  make(map[string]bool),
+		PrivateSubs: make(map[string]bool),
+		Clients:     make(map[*websocket.Conn]bool),
+		done:        make(chan struct{}),
+	}
+
+	m.exchangeConns[apiKey.ID] = ec
+	return ec, nil
+}
+
+func (m *TradingStreamManager) handleSubscribe(conn *websocket.Conn, userID string, msg *model.TradingWebSocketMessage) {
+	if m.subscribe(conn, msg) {
+		m.sendSubscriptions(conn)
+	}
+}
+
+// subscribe validates and starts one stream for the client, reporting
+// failures to it as error frames; it returns false when nothing was subscribed
+func (m *TradingStreamManager) subscribe(conn *websocket.Conn, msg *model.TradingWebSocketMessage) bool {
+	m.mu.RLock()
+	state, ok := m.clients[conn]
+	m.mu.RUnlock()
+
+	if !ok || state.APIKeyID == "" {
+		m.sendError(conn, "not connected to any API key, call connect first")
+		return false
+	}
+
+	ec, err := m.ensureExchangeConn(conn, state.APIKeyID)
+	if errors.Is(err, errExchangeConnLimit) {
+		m.sendCodedError(conn, ErrorCodeExchangeConnLimit, err.Error())
+		return false
+	}
+	if err != nil {
+		m.sendError(conn, err.Error())
+		return false
+	}
+
+	caps, _ := model.GetPlatformCapabilities(ec.Platform)
+	if !caps.SupportsSubscription(msg.Type) {
+		m.sendError(conn, msg.Type+" subscription not supported for this platform")
+		return false
+	}
+	if msg.Type == "kline" && !caps.SupportsInterval(msg.Interval) {
+		m.sendError(conn, "unsupported kline interval: "+msg.Interval)
+		return false
+	}
+	if msg.Type == "asset" && !m.rawBalanceEvents {
+		m.sendError(conn, "asset subscription is disabled, subscribe to balance instead")
+		return false
+	}
+
+	// Validate the orderbook mode before anything is subscribed
+	var mode string
+	if msg.Type == "orderbook" || msg.Type == "depth" {
+		if mode, err = orderBookMode(msg.Mode, msg.BookMode); err != nil {
+			m.sendError(conn, err.Error())
+			return false
+		}
+	}
+
+	subKey := m.subscriptionKey(msg.Type, msg.Symbol, msg.Interval)
+	wideKey := m.subscriptionKey(msg.Type, msg.Symbol, "")
+	m.mu.Lock()
+	if s, ok := m.clients[conn]; ok {
+		s.Subscriptions[subKey] = true
+		if msg.Type == "kline" {
+			s.Subscriptions[wideKey] = true
+		}
+	}
+	m.mu.Unlock()
+
+	switch msg.Type {
+	case "kline":
+		if ec.Platform == model.PlatformBTCC {
+			m.mu.Lock()
+			if s, ok := m.clients[conn]; ok {
+				s.BlockedSubs[subKey] = true
+				s.BlockedSubs[wideKey] = true
+			}
+			m.mu.Unlock()
+
+			m.sendBTCCKlineHistory(conn, ec, msg.Symbol, msg.Interval)
+
+			m.mu.Lock()
+			if s, ok := m.clients[conn]; ok {
+				delete(s.BlockedSubs, subKey)
+				delete(s.BlockedSubs, wideKey)
+			}
+			m.mu.Unlock()
+		}
+		m.setKlineThrottle(conn, subKey, msg.ConflateMs)
+		m.subscribeKline(conn, ec, msg.Symbol, msg.Interval)
+	case "orderbook", "depth":
+		// BTCC books are already maintained from its own snapshots, so "book"
+		// only changes Binance and BTCC frames are always partial
+		maintained := mode == depthModeBook && ec.Platform == model.PlatformBinance
+		if ec.Platform == model.PlatformBTCC || mode == depthModeBook {
+			mode = depthModePartial
+		}
+		// A maintained book is fed by the diff stream but sent as snapshots
+		streamMode := mode
+		if maintained {
+			mode, streamMode = depthModePartial, depthModeDiff
+		}
+		m.mu.Lock()
+		if s, ok := m.clients[conn]; ok {
+			if maintained {
+				s.BookSubs[subKey] = true
+			} else {
+				delete(s.BookSubs, subKey)
+			}
+			s.DepthModes[subKey] = mode
+		}
+		m.mu.Unlock()
+		if maintained {
+			m.ensureBinanceBook(ec, msg.Symbol)
+		}
+		throttleMs := msg.DepthThrottleMs
+		if throttleMs <= 0 {
+			throttleMs = msg.ConflateMs
+		}
+		m.setDepthThrottle(conn, subKey, throttleMs)
+		m.subscribeOrderBook(conn, ec, msg.Symbol, streamMode)
+	case "order":
+		m.subscribeOrders(conn, ec, msg.Symbol)
+	case "asset":
+		// Asset subscription (BTCC specific, see platform capabilities)
+		m.subscribeAsset(conn, ec)
+	case "balance":
+		// Normalized balances, fed by the same private stream as "asset"
+		m.subscribeAsset(conn, ec)
+	case "trades", "deals":
+		// Trade/deal subscription
+		m.subscribeTrades(conn, ec, msg.Symbol)
+	case "state":
+		// Market state subscription (BTCC specific, see platform capabilities)
+		m.subscribeMarketState(conn, ec)
+	default:
+		m.sendError(conn, "unknown subscription type: "+msg.Type)
+		return false
+	}
+
+	return true
+}
+
+// orderBookMode resolves the mode of an orderbook subscription. bookMode is
+// the deprecated spelling of mode, kept as an alias: a mode contradicting it is
+// rejected rather than one of them silently winning, except "partial", which
+// book frames are sent as.
+func orderBookMode(mode, bookMode string) (string, error) {
+	switch mode {
+	case "", depthModePartial, depthModeDiff, depthModeBook:
+	default:
+		return "", fmt.Errorf("unknown mode: %s", mode)
+	}
+
+	switch bookMode {
+	case "":
+	case depthModeDiff, depthModeBook:
+		if mode != "" && mode != bookMode && (mode != depthModePartial || bookMode != depthModeBook) {
+			return "", fmt.Errorf("bookMode %q conflicts with mode %q", bookMode, mode)
+		}
+		mode = bookMode
+	default:
+		return "", fmt.Errorf("unknown bookMode: %s", bookMode)
+	}
+
+	if mode == "" {
+		mode = depthModePartial
+	}
+	return mode, nil
+}
+
+// ensureExchangeConn returns the exchange connection of the client's API key,
+// re-creating it if it was torn down while idle
+func (m *TradingStreamManager) ensureExchangeConn(conn *websocket.Conn, apiKeyID string) (*ExchangeConnection, error) {
+	m.exchangeMu.RLock()
+	ec, ok := m.exchangeConns[apiKeyID]
+	m.exchangeMu.RUnlock()
+	if ok {
+		return ec, nil
+	}
+
+	apiKey, err := m.apiKeyRepo.GetByID(context.Background(), apiKeyID)
+	if err != nil || apiKey == nil {
+		return nil, errors.New("API key not found")
+	}
+	if !apiKey.IsActive {
+		return nil, errors.New("API key is not active")
+	}
+
+	ec, err = m.getOrCreateExchangeConn(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	ec.mu.Lock()
+	ec.Clients[conn] = true
+	ec.mu.Unlock()
+	return ec, nil
+}
+
+// subscribeTrades subscribes to trade/deal updates
+func (m *TradingStreamManager) subscribeTrades(conn *websocket.Conn, ec *ExchangeConnection, symbol string) {
+	streamName := ""
+	switch ec.Platform {
+	case model.PlatformBinance:
+		streamName = strings.ToLower(symbol) + "@trade"
+	case model.PlatformBTCC:
+		streamName = "deals." + symbol
+	default:
+		streamName = strings.ToLower(symbol) + "@trade"
 	}
 
 	ec.mu.Lock()
@@ -456,9 +1376,10 @@ func (m *TradingStreamManager) subscribeTrades(conn *websocket.Conn, ec *Exchang
 		return
 	}
 	ec.PublicSubs[streamName] = true
+	needConnect := ec.PublicWS == nil
 	ec.mu.Unlock()
 
-	if ec.Platform == model.PlatformBTCC {
+	if ec.Platform == model.PlatformBTCC && !needConnect {
 		m.sendBTCCSubscription(ec, streamName, false)
 	} else {
 		m.updatePublicConnection(ec)
@@ -475,9 +1396,14 @@ func (m *TradingStreamManager) subscribeMarketState(conn *websocket.Conn, ec *Ex
 		return
 	}
 	ec.PublicSubs[streamName] = true
+	needConnect := ec.PublicWS == nil
 	ec.mu.Unlock()
 
-	m.sendBTCCSubscription(ec, streamName, false)
+	if needConnect {
+		m.updatePublicConnection(ec)
+	} else {
+		m.sendBTCCSubscription(ec, streamName, false)
+	}
 }
 
 func (m *TradingStreamManager) subscribeKline(conn *websocket.Conn, ec *ExchangeConnection, symbol, interval string) {
@@ -505,8 +1431,8 @@ func (m *TradingStreamManager) subscribeKline(conn *websocket.Conn, ec *Exchange
 	}
 }
 
-func (m *TradingStreamManager) subscribeOrderBook(conn *websocket.Conn, ec *ExchangeConnection, symbol string) {
-	streamName := m.formatOrderBookStream(ec.Platform, symbol)
+func (m *TradingStreamManager) subscribeOrderBook(conn *websocket.Conn, ec *ExchangeConnection, symbol, mode string) {
+	streamName := m.formatOrderBookStream(ec.Platform, symbol, mode)
 
 	ec.mu.Lock()
 	if ec.PublicSubs[streamName] {
@@ -532,31 +1458,36 @@ func (m *TradingStreamManager) subscribeOrderBook(conn *websocket.Conn, ec *Exch
 
 func (m *TradingStreamManager) subscribeOrders(conn *websocket.Conn, ec *ExchangeConnection, symbol string) {
 	// Orders require private WebSocket connection
+	// Reading btccAuthed together with adding the subscription means either
+	// this call or the auth response sends it, never both or neither
 	ec.mu.Lock()
 	needConnect := ec.PrivateWS == nil
 	streamName := "order." + symbol
 	ec.PrivateSubs[streamName] = true
+	authed := ec.btccAuthed
 	ec.mu.Unlock()
 
 	if needConnect {
 		m.connectPrivateStream(ec)
-	} else if ec.Platform == model.PlatformBTCC && ec.btccAuthed {
+	} else if ec.Platform == model.PlatformBTCC && authed {
 		// Already connected and authenticated, just subscribe
 		m.sendBTCCSubscription(ec, streamName, true)
 	}
 }
 
-// subscribeAsset subscribes to asset balance updates (BTCC specific)
+// subscribeAsset subscribes to asset balance updates: the BTCC asset stream,
+// or the Binance user data stream which carries them unasked
 func (m *TradingStreamManager) subscribeAsset(conn *websocket.Conn, ec *ExchangeConnection) {
 	// Asset updates require private WebSocket connection
 	ec.mu.Lock()
 	needConnect := ec.PrivateWS == nil
 	ec.PrivateSubs["asset"] = true
+	authed := ec.btccAuthed
 	ec.mu.Unlock()
 
 	if needConnect {
 		m.connectPrivateStream(ec)
-	} else if ec.Platform == model.PlatformBTCC && ec.btccAuthed {
+	} else if ec.Platform == model.PlatformBTCC && authed {
 		// Already connected and authenticated, just subscribe
 		m.sendBTCCSubscription(ec, "asset", true)
 	}
@@ -580,10 +1511,22 @@ func (m *TradingStreamManager) handleUnsubscribe(conn *websocket.Conn, msg *mode
 	}
 
 	subKey := m.subscriptionKey(msg.Type, msg.Symbol, msg.Interval)
+	depthStreamMode := depthModePartial
 	m.mu.Lock()
 	if s, ok := m.clients[conn]; ok {
 		delete(s.Subscriptions, subKey)
 		delete(s.BlockedSubs, subKey)
+		if t, ok := s.DepthThrottles[subKey]; ok {
+			t.stop()
+			delete(s.DepthThrottles, subKey)
+		}
+		depthStreamMode = orderBookStreamMode(s, subKey)
+		delete(s.BookSubs, subKey)
+		delete(s.DepthModes, subKey)
+		if t, ok := s.KlineThrottles[subKey]; ok {
+			t.stop()
+			delete(s.KlineThrottles, subKey)
+		}
 		if msg.Type == "kline" {
 			delete(s.Subscriptions, m.subscriptionKey(msg.Type, msg.Symbol, ""))
 			delete(s.BlockedSubs, m.subscriptionKey(msg.Type, msg.Symbol, ""))
@@ -591,55 +1534,197 @@ func (m *TradingStreamManager) handleUnsubscribe(conn *websocket.Conn, msg *mode
 	}
 	m.mu.Unlock()
 
+	// Exchange streams and maintained books are shared by every client of the
+	// connection, so they are only dropped once no other client needs them
+	publicChanged := false
 	switch msg.Type {
 	case "kline":
+		if m.clientSubscribed(ec, func(s *ClientState) bool { return s.Subscriptions[subKey] }) {
+			break
+		}
 		streamName := m.formatKlineStream(ec.Platform, msg.Symbol, msg.Interval)
 		ec.mu.Lock()
 		delete(ec.PublicSubs, streamName)
 		ec.mu.Unlock()
+		publicChanged = true
 
 		// For BTCC, send unsubscription message
 		if ec.Platform == model.PlatformBTCC {
 			m.sendBTCCUnsubscription(ec, streamName, false)
 		}
 
-	case "orderbook":
-		streamName := m.formatOrderBookStream(ec.Platform, msg.Symbol)
+	case "orderbook", "depth":
+		// BTCC has one depth stream whatever the mode
+		streamInUse := m.clientSubscribed(ec, func(s *ClientState) bool {
+			return s.Subscriptions[subKey] && (ec.Platform == model.PlatformBTCC || orderBookStreamMode(s, subKey) == depthStreamMode)
+		})
+		bookInUse := m.clientSubscribed(ec, func(s *ClientState) bool { return s.BookSubs[subKey] })
+		streamName := m.formatOrderBookStream(ec.Platform, msg.Symbol, depthStreamMode)
 		ec.mu.Lock()
-		delete(ec.PublicSubs, streamName)
+		if !streamInUse {
+			delete(ec.PublicSubs, streamName)
+		}
+		if !bookInUse {
+			delete(ec.books, strings.ToUpper(msg.Symbol))
+		}
 		ec.mu.Unlock()
+		if streamInUse {
+			break
+		}
+		publicChanged = true
+
+		// For BTCC, send unsubscription message
+		if ec.Platform == model.PlatformBTCC {
+			m.sendBTCCUnsubscription(ec, streamName, false)
+		}
+
+	case "orders":
+		streamName := "orders." + msg.Symbol
+		ec.mu.Lock()
+		delete(ec.PrivateSubs, streamName)
+		ec.mu.Unlock()
+
+		// For BTCC, send unsubscription message
+		if ec.Platform == model.PlatformBTCC {
+			m.sendBTCCUnsubscription(ec, streamName, true)
+		}
+
+	case "asset", "balance":
+		// "asset" and "balance" share the exchange subscription
+		if m.balanceSubscribed(ec) {
+			break
+		}
+		ec.mu.Lock()
+		delete(ec.PrivateSubs, "asset")
+		ec.mu.Unlock()
+
+		// For BTCC, send unsubscription message
+		if ec.Platform == model.PlatformBTCC {
+			m.sendBTCCUnsubscription(ec, "asset", true)
+		}
+	}
+
+	// For non-BTCC platforms, reconnect to update subscriptions
+	if publicChanged && ec.Platform != model.PlatformBTCC {
+		m.updatePublicConnection(ec)
+	}
+
+	m.sendSubscriptions(conn)
+}
+
+// orderBookStreamMode returns the Binance depth stream an orderbook
+// subscription is fed by: diff for raw diffs and maintained books, else partial.
+// The caller must hold m.mu.
+func orderBookStreamMode(state *ClientState, subKey string) string {
+	if state.BookSubs[subKey] || state.DepthModes[subKey] == depthModeDiff {
+		return depthModeDiff
+	}
+	return depthModePartial
+}
+
+// balanceSubscribed reports whether any client of the exchange connection still
+// subscribes to "asset" or "balance"
+func (m *TradingStreamManager) balanceSubscribed(ec *ExchangeConnection) bool {
+	return m.clientSubscribed(ec, func(state *ClientState) bool {
+		return state.Subscriptions["asset"] || state.Subscriptions["balance"]
+	})
+}
+
+// clientSubscribed reports whether match holds for the state of any client of
+// the exchange connection; match runs under m.mu
+func (m *TradingStreamManager) clientSubscribed(ec *ExchangeConnection, match func(*ClientState) bool) bool {
+	ec.mu.RLock()
+	clients := make([]*websocket.Conn, 0, len(ec.Clients))
+	for client := range ec.Clients {
+		clients = append(clients, client)
+	}
+	ec.mu.RUnlock()
 
-		// For BTCC, send unsubscription message
-		if ec.Platform == model.PlatformBTCC {
-			m.sendBTCCUnsubscription(ec, streamName, false)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, client := range clients {
+		if state := m.clients[client]; state != nil && match(state) {
+			return true
 		}
+	}
+	return false
+}
 
-	case "orders":
-		streamName := "orders." + msg.Symbol
-		ec.mu.Lock()
-		delete(ec.PrivateSubs, streamName)
-		ec.mu.Unlock()
+// sendSubscriptions sends the client a snapshot of its active streams for the connected API key
+func (m *TradingStreamManager) sendSubscriptions(conn *websocket.Conn) {
+	m.sendSubscriptionSnapshot(conn, nil)
+}
 
-		// For BTCC, send unsubscription message
-		if ec.Platform == model.PlatformBTCC {
-			m.sendBTCCUnsubscription(ec, streamName, true)
-		}
+// sendSubscriptionSnapshot sends the client's active streams; defaults, when
+// non-nil, lists the API key's default subscriptions applied on connect
+func (m *TradingStreamManager) sendSubscriptionSnapshot(conn *websocket.Conn, defaults []model.TradingSubscription) {
+	m.mu.RLock()
+	state, ok := m.clients[conn]
+	if !ok {
+		m.mu.RUnlock()
+		return
+	}
+	apiKeyID := state.APIKeyID
+	subs := m.subscriptionSnapshot(state)
+	m.mu.RUnlock()
 
-	case "asset":
-		ec.mu.Lock()
-		delete(ec.PrivateSubs, "asset")
-		ec.mu.Unlock()
+	data := map[string]interface{}{
+		"apiKeyId":      apiKeyID,
+		"subscriptions": subs,
+	}
+	if defaults != nil {
+		data["defaults"] = defaults
+	}
+	m.sendToClient(conn, model.TradingWebSocketResponse{
+		Type:      "subscriptions",
+		Timestamp: time.Now().UnixMilli(),
+		Data:      data,
+	})
+}
 
-		// For BTCC, send unsubscription message
-		if ec.Platform == model.PlatformBTCC {
-			m.sendBTCCUnsubscription(ec, "asset", true)
+// subscriptionSnapshot converts the client's subscription keys back into structured streams.
+// The caller must hold m.mu.
+func (m *TradingStreamManager) subscriptionSnapshot(state *ClientState) []model.TradingSubscription {
+	// Kline subscriptions register both "kline:SYMBOL" and "kline:SYMBOL:INTERVAL";
+	// only report the symbol-wide key when no interval-specific key exists
+	klineWithInterval := make(map[string]bool)
+	for key := range state.Subscriptions {
+		parts := strings.Split(key, ":")
+		if parts[0] == "kline" && len(parts) == 3 {
+			klineWithInterval[parts[1]] = true
 		}
 	}
 
-	// For non-BTCC platforms, reconnect to update subscriptions
-	if ec.Platform != model.PlatformBTCC {
-		m.updatePublicConnection(ec)
+	subs := make([]model.TradingSubscription, 0, len(state.Subscriptions))
+	for key, active := range state.Subscriptions {
+		if !active {
+			continue
+		}
+		parts := strings.Split(key, ":")
+		sub := model.TradingSubscription{Type: parts[0]}
+		if len(parts) >= 2 {
+			sub.Symbol = parts[1]
+		}
+		if len(parts) >= 3 {
+			sub.Interval = parts[2]
+		}
+		if sub.Type == "kline" && sub.Interval == "" && klineWithInterval[sub.Symbol] {
+			continue
+		}
+		subs = append(subs, sub)
 	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Type != subs[j].Type {
+			return subs[i].Type < subs[j].Type
+		}
+		if subs[i].Symbol != subs[j].Symbol {
+			return subs[i].Symbol < subs[j].Symbol
+		}
+		return subs[i].Interval < subs[j].Interval
+	})
+
+	return subs
 }
 
 func (m *TradingStreamManager) updatePublicConnection(ec *ExchangeConnection) {
@@ -676,18 +1761,19 @@ func (m *TradingStreamManager) updatePublicConnection(ec *ExchangeConnection) {
 	}
 }
 
-// connectBinancePublic connects to Binance public WebSocket
+// connectBinancePublic connects to the Binance combined-stream endpoint so every
+// message carries the {stream, data} envelope handlePublicMessage relies on
 func (m *TradingStreamManager) connectBinancePublic(ec *ExchangeConnection, streams []string) {
-	url := ec.Config.BaseWSURL + "/" + strings.Join(streams, "/")
+	url := binance.CombinedStreamURL(ec.Config.BaseWSURL, streams)
 	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		log.Printf("Binance public ws connection error: %v", err)
+		logs.Errorf("Binance public ws connection error: %v", err)
 		return
 	}
 	ec.PublicWS = ws
 
 	// Start reading messages
-	go m.readPublicMessages(ec)
+	ec.startReader(ws, m.readPublicMessages)
 }
 
 // connectBTCCPublic connects to BTCC public WebSocket with compression support
@@ -699,31 +1785,50 @@ func (m *TradingStreamManager) connectBTCCPublic(ec *ExchangeConnection, streams
 
 	ws, _, err := dialer.Dial(ec.Config.BaseWSURL, nil)
 	if err != nil {
-		log.Printf("BTCC public ws connection error: %v", err)
+		logs.Errorf("BTCC public ws connection error: %v", err)
 		return
 	}
 	ec.PublicWS = ws
 
 	// Start reading messages first
-	go m.readBTCCPublicMessages(ec)
+	ec.startReader(ws, m.readBTCCPublicMessages)
 
 	// Start ping goroutine for BTCC
 	go m.btccPingLoop(ec, false)
 
-	// Send subscription messages for each stream
+	// Send subscription messages for each stream; the caller holds ec.mu
 	for stream := range ec.PublicSubs {
-		m.sendBTCCSubscription(ec, stream, false)
+		m.writeBTCCSubscription(ec, ws, stream)
 	}
 }
 
-// sendBTCCSubscription sends a subscription message to BTCC
-func (m *TradingStreamManager) sendBTCCSubscription(ec *ExchangeConnection, stream string, isPrivate bool) {
-	var ws *websocket.Conn
+// btccSocket returns the public or private BTCC socket; the caller holds ec.mu
+func (ec *ExchangeConnection) btccSocket(isPrivate bool) *websocket.Conn {
 	if isPrivate {
-		ws = ec.PrivateWS
-	} else {
-		ws = ec.PublicWS
+		return ec.PrivateWS
 	}
+	return ec.PublicWS
+}
+
+// writeBTCC sends a request on ws, serialized with every other BTCC write
+func (ec *ExchangeConnection) writeBTCC(ws *websocket.Conn, req any) error {
+	ec.btccWriteMu.Lock()
+	defer ec.btccWriteMu.Unlock()
+	return ws.WriteJSON(req)
+}
+
+// sendBTCCSubscription sends a subscription message to BTCC
+func (m *TradingStreamManager) sendBTCCSubscription(ec *ExchangeConnection, stream string, isPrivate bool) {
+	ec.mu.RLock()
+	ws := ec.btccSocket(isPrivate)
+	ec.mu.RUnlock()
+
+	m.writeBTCCSubscription(ec, ws, stream)
+}
+
+// writeBTCCSubscription sends the subscribe request of stream on ws, for
+// callers that already hold ec.mu or took ws from under it
+func (m *TradingStreamManager) writeBTCCSubscription(ec *ExchangeConnection, ws *websocket.Conn, stream string) {
 	if ws == nil {
 		return
 	}
@@ -734,30 +1839,41 @@ func (m *TradingStreamManager) sendBTCCSubscription(ec *ExchangeConnection, stre
 
 	var method string
 	var params []interface{}
+	var err error
 
 	switch parts[0] {
 	case "kline":
 		// kline.subscribe: [market, period]
-		method = "kline.subscribe"
+		method = btcc.MethodKlineSub
+		var market string
+		var interval int
 		if len(parts) >= 3 {
-			interval, _ := strconv.Atoi(parts[2])
-			params = []interface{}{parts[1], interval}
+			market = parts[1]
+			interval, _ = strconv.Atoi(parts[2])
+		}
+		if params, err = btcc.KlineSubscribeParams(market, interval); err != nil {
+			logs.Warnf("BTCC subscription %s: %v", stream, err)
+			return
 		}
 	case "depth":
 		// depth.subscribe: [market, limit, merge]
-		method = "depth.subscribe"
+		method = btcc.MethodDepthSub
+		var market, merge string
+		limit := 20
+		if len(parts) >= 2 {
+			market = parts[1]
+		}
+		if len(parts) >= 3 {
+			limit, _ = strconv.Atoi(parts[2])
+		}
 		if len(parts) >= 5 {
-			limit, _ := strconv.Atoi(parts[2])
-			params = []interface{}{parts[1], limit, parts[3] + "." + parts[4]}
+			merge = parts[3] + "." + parts[4]
 		} else if len(parts) >= 4 {
-			limit, _ := strconv.Atoi(parts[2])
-			params = []interface{}{parts[1], limit, parts[3]}
-		} else if len(parts) >= 3 {
-			limit, _ := strconv.Atoi(parts[2])
-			params = []interface{}{parts[1], limit, "0.00000001"}
-		} else if len(parts) >= 2 {
-			// Default: 20 levels, "0.01" merge
-			params = []interface{}{parts[1], 20, "0.00000001"}
+			merge = parts[3]
+		}
+		if params, err = btcc.DepthSubscribeParams(market, limit, merge); err != nil {
+			logs.Warnf("BTCC subscription %s: %v", stream, err)
+			return
 		}
 	case "deals":
 		// deals.subscribe: [market]
@@ -782,31 +1898,23 @@ func (m *TradingStreamManager) sendBTCCSubscription(ec *ExchangeConnection, stre
 		method = "asset.subscribe"
 		params = []interface{}{}
 	default:
-		log.Printf("unknown BTCC stream type: %s", parts[0])
+		logs.Warnf("unknown BTCC stream type: %s", parts[0])
 		return
 	}
 
-	msgID := atomic.AddInt64(&ec.btccMsgID, 1)
-	req := BTCCRequest{
-		ID:     msgID,
-		Method: method,
-		Params: params,
-	}
+	req := btcc.NewRequest(&ec.btccIDs, method, params)
 
-	log.Printf("BTCC subscription: sending method=%s, params=%v, id=%d, isPrivate=%v", method, params, msgID, isPrivate)
-	if err := ws.WriteJSON(req); err != nil {
+	logs.Debugf("BTCC subscription: sending method=%s, params=%v, id=%d", method, params, req.ID)
+	if err := ec.writeBTCC(ws, req); err != nil {
 		logs.Errorf("BTCC subscribe error for %s: %v", stream, err)
 	}
 }
 
 // sendBTCCUnsubscription sends an unsubscription message to BTCC
 func (m *TradingStreamManager) sendBTCCUnsubscription(ec *ExchangeConnection, stream string, isPrivate bool) {
-	var ws *websocket.Conn
-	if isPrivate {
-		ws = ec.PrivateWS
-	} else {
-		ws = ec.PublicWS
-	}
+	ec.mu.RLock()
+	ws := ec.btccSocket(isPrivate)
+	ec.mu.RUnlock()
 	if ws == nil {
 		return
 	}
@@ -831,15 +1939,10 @@ func (m *TradingStreamManager) sendBTCCUnsubscription(ec *ExchangeConnection, st
 		return
 	}
 
-	msgID := atomic.AddInt64(&ec.btccMsgID, 1)
-	req := BTCCRequest{
-		ID:     msgID,
-		Method: method,
-		Params: []interface{}{},
-	}
+	req := btcc.NewRequest(&ec.btccIDs, method, nil)
 
-	if err := ws.WriteJSON(req); err != nil {
-		log.Printf("BTCC unsubscribe error for %s: %v", stream, err)
+	if err := ec.writeBTCC(ws, req); err != nil {
+		logs.Warnf("BTCC unsubscribe error for %s: %v", stream, err)
 	}
 }
 
@@ -852,26 +1955,16 @@ func (m *TradingStreamManager) btccPingLoop(ec *ExchangeConnection, isPrivate bo
 		select {
 		case <-ticker.C:
 			ec.mu.RLock()
-			var ws *websocket.Conn
-			if isPrivate {
-				ws = ec.PrivateWS
-			} else {
-				ws = ec.PublicWS
-			}
+			ws := ec.btccSocket(isPrivate)
 			ec.mu.RUnlock()
 
 			if ws == nil {
 				return
 			}
 
-			msgID := atomic.AddInt64(&ec.btccMsgID, 1)
-			req := BTCCRequest{
-				ID:     msgID,
-				Method: "server.ping",
-				Params: []interface{}{},
-			}
-			if err := ws.WriteJSON(req); err != nil {
-				log.Printf("BTCC ping error: %v", err)
+			req := btcc.NewRequest(&ec.btccIDs, btcc.MethodPing, nil)
+			if err := ec.writeBTCC(ws, req); err != nil {
+				logs.Warnf("BTCC ping error: %v", err)
 				return
 			}
 		case <-ec.done:
@@ -880,20 +1973,12 @@ func (m *TradingStreamManager) btccPingLoop(ec *ExchangeConnection, isPrivate bo
 	}
 }
 
-func (m *TradingStreamManager) readPublicMessages(ec *ExchangeConnection) {
-	ec.mu.RLock()
-	ws := ec.PublicWS
-	ec.mu.RUnlock()
-
-	if ws == nil {
-		return
-	}
-
+func (m *TradingStreamManager) readPublicMessages(ec *ExchangeConnection, ws *websocket.Conn) {
 	for {
 		_, message, err := ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("public ws read error: %v", err)
+				logs.Warnf("public ws read error: %v", err)
 			}
 			return
 		}
@@ -905,20 +1990,12 @@ func (m *TradingStreamManager) readPublicMessages(ec *ExchangeConnection) {
 
 // readBTCCPublicMessages reads messages from BTCC public WebSocket
 // BTCC may send compressed messages, so we handle decompression
-func (m *TradingStreamManager) readBTCCPublicMessages(ec *ExchangeConnection) {
-	ec.mu.RLock()
-	ws := ec.PublicWS
-	ec.mu.RUnlock()
-
-	if ws == nil {
-		return
-	}
-
+func (m *TradingStreamManager) readBTCCPublicMessages(ec *ExchangeConnection, ws *websocket.Conn) {
 	for {
 		messageType, message, err := ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("BTCC public ws read error: %v", err)
+				logs.Warnf("BTCC public ws read error: %v", err)
 			}
 			return
 		}
@@ -927,7 +2004,7 @@ func (m *TradingStreamManager) readBTCCPublicMessages(ec *ExchangeConnection) {
 		if messageType == websocket.BinaryMessage {
 			decompressed, err := m.decompressFlate(message)
 			if err != nil {
-				log.Printf("BTCC decompress error: %v", err)
+				logs.Warnf("BTCC decompress error: %v", err)
 				continue
 			}
 			message = decompressed
@@ -939,31 +2016,35 @@ func (m *TradingStreamManager) readBTCCPublicMessages(ec *ExchangeConnection) {
 
 // decompressFlate decompresses a flate-compressed message
 func (m *TradingStreamManager) decompressFlate(data []byte) ([]byte, error) {
-	reader := flate.NewReader(io.NopCloser(strings.NewReader(string(data))))
-	defer reader.Close()
+	src := bytes.NewReader(data)
 
-	var result strings.Builder
-	buf := make([]byte, 1024)
-	for {
-		n, err := reader.Read(buf)
-		if n > 0 {
-			result.Write(buf[:n])
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
+	reader, ok := flateReaderPool.Get().(io.ReadCloser)
+	if ok {
+		if err := reader.(flate.Resetter).Reset(src, nil); err != nil {
 			return nil, err
 		}
+	} else {
+		reader = flate.NewReader(src)
 	}
-	return []byte(result.String()), nil
+	defer flateReaderPool.Put(reader)
+
+	buf := flateBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer flateBufferPool.Put(buf)
+
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, err
+	}
+
+	// The buffer is reused, so hand back a copy sized to the frame
+	return bytes.Clone(buf.Bytes()), nil
 }
 
 // handleBTCCPublicMessage handles messages from BTCC public WebSocket
 func (m *TradingStreamManager) handleBTCCPublicMessage(ec *ExchangeConnection, message []byte) {
-	var btccResp BTCCResponse
+	var btccResp btcc.Response
 	if err := json.Unmarshal(message, &btccResp); err != nil {
-		log.Printf("BTCC parse error: %v, message: %s", err, string(message))
+		logs.Warnf("BTCC parse error: %v, message: %s", err, redactPayload(message))
 		return
 	}
 
@@ -1007,13 +2088,31 @@ func (m *TradingStreamManager) handlePublicMessage(ec *ExchangeConnection, messa
 					if s, ok := streamData["s"].(string); ok {
 						response.Symbol = s
 					}
+					if k, ok := streamData["k"].(map[string]interface{}); ok {
+						if iv, ok := k["i"].(string); ok {
+							response.Interval = iv
+						}
+					}
+				} else if strings.Contains(stream, "@trade") {
+					response.Type = "trades"
+					response.Data = streamData
+					if s, ok := streamData["s"].(string); ok {
+						response.Symbol = s
+					}
 				} else if strings.Contains(stream, "@depth") {
 					response.Type = "orderbook"
-					response.Data = m.parseOrderBookData(streamData)
 					parts := strings.Split(stream, "@")
 					if len(parts) > 0 {
 						response.Symbol = strings.ToUpper(parts[0])
 					}
+					if isPartialDepthStream(stream) {
+						response.Mode = depthModePartial
+						response.Data = m.parsePartialDepth(response.Symbol, streamData)
+					} else {
+						response.Mode = depthModeDiff
+						response.Data = m.parseOrderBookData(streamData)
+						m.updateBinanceBook(ec, response, streamData)
+					}
 				}
 			}
 		} else if eventType, ok := data["e"].(string); ok {
@@ -1030,12 +2129,20 @@ func (m *TradingStreamManager) handlePublicMessage(ec *ExchangeConnection, messa
 						response.Interval = iv
 					}
 				}
+			case "trade":
+				response.Type = "trades"
+				response.Data = data
+				if s, ok := data["s"].(string); ok {
+					response.Symbol = s
+				}
 			case "depthUpdate":
 				response.Type = "orderbook"
+				response.Mode = depthModeDiff
 				response.Data = m.parseOrderBookData(data)
 				if s, ok := data["s"].(string); ok {
 					response.Symbol = s
 				}
+				m.updateBinanceBook(ec, response, data)
 			}
 		}
 
@@ -1060,7 +2167,7 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 		// Params: array of kline rows [[timestamp, open, close, high, low, volume, amount, market], ...]
 		var klines [][]interface{}
 		if err := json.Unmarshal(params, &klines); err != nil {
-			log.Printf("BTCC kline.update parse error: %v", err)
+			logs.Warnf("BTCC kline.update parse error: %v", err)
 			return
 		}
 		for _, kline := range klines {
@@ -1079,7 +2186,7 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 		// Params: [isFullSnapshot (bool), depthData (object), market (string)]
 		var depthParams []json.RawMessage
 		if err := json.Unmarshal(params, &depthParams); err != nil {
-			log.Printf("BTCC depth.update parse error: %v", err)
+			logs.Warnf("BTCC depth.update parse error: %v", err)
 			return
 		}
 		if len(depthParams) < 2 {
@@ -1103,14 +2210,16 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 			}
 		}
 
+		// Deltas are merged into the cached book, so clients always get the full book
 		response.Type = "orderbook"
+		response.Mode = depthModePartial
 		response.Data = m.parseBTCCDepth(ec, response.Symbol, depthData, isFullSnapshot)
 
 	case "deals.update":
 		// Params: [market, [deals...]]
 		var dealParams []json.RawMessage
 		if err := json.Unmarshal(params, &dealParams); err != nil {
-			log.Printf("BTCC deals.update parse error: %v", err)
+			logs.Warnf("BTCC deals.update parse error: %v", err)
 			return
 		}
 		if len(dealParams) < 2 {
@@ -1126,15 +2235,25 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 		response.Data = dealParams[1]
 
 	case "state.update":
-		// Market status update
-		response.Type = "state"
-		response.Data = params
+		// Market status update, normalized to one frame per market
+		states, err := btcc.ParseStateUpdate(params)
+		if err != nil {
+			logs.Warnf("BTCC state.update parse error: %v", err)
+			return
+		}
+		for _, state := range states {
+			response.Type = "state"
+			response.Symbol = state.Symbol
+			response.Data = state
+			m.broadcastToClients(ec, response)
+		}
+		return
 
 	case "order.update":
 		// Order update (private)
 		var orderParams []json.RawMessage
 		if err := json.Unmarshal(params, &orderParams); err != nil {
-			log.Printf("BTCC order.update parse error: %v", err)
+			logs.Warnf("BTCC order.update parse error: %v", err)
 			return
 		}
 		if len(orderParams) != 2 {
@@ -1146,12 +2265,12 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 			orderData map[string]interface{}
 		)
 		if err := json.Unmarshal(orderParams[0], &status); err != nil {
-			log.Printf("BTCC order.update: parse order status error: %v, params=%s", err, string(params))
+			logs.Warnf("BTCC order.update: parse order status error: %v, params=%s", err, redactPayload(params))
 			return
 		}
 
 		if err := json.Unmarshal(orderParams[1], &orderData); err != nil || orderData == nil {
-			log.Printf("BTCC order.update: parse order object error: %v, params=%s", err, string(params))
+			logs.Warnf("BTCC order.update: parse order object error: %v, params=%s", err, redactPayload(params))
 			return
 		}
 
@@ -1163,6 +2282,19 @@ func (m *TradingStreamManager) handleBTCCPushNotification(ec *ExchangeConnection
 
 	case "asset.update":
 		// Asset balance update (private)
+		if balances, err := parseBTCCBalances(params); err != nil {
+			logs.Warnf("BTCC asset.update: parse balances error: %v, params=%s", err, redactPayload(params))
+		} else {
+			m.broadcastToClients(ec, model.TradingWebSocketResponse{
+				Type:      "balance",
+				Data:      balances,
+				Platform:  response.Platform,
+				Timestamp: response.Timestamp,
+			})
+		}
+		if !m.rawBalanceEvents {
+			return
+		}
 		response.Type = "asset"
 		response.Data = params
 
@@ -1182,8 +2314,8 @@ func (m *TradingStreamManager) parseBTCCKline(kline []interface{}) map[string]in
 	result := make(map[string]interface{})
 	if len(kline) >= 8 {
 		result["timestamp"] = kline[0]
-		if sec, ok := m.parseUnixSeconds(kline[0]); ok {
-			result["time"] = sec * 1000
+		if ms, ok := m.parseUnixMilli(kline[0]); ok {
+			result["time"] = ms
 		}
 		result["open"] = kline[1]
 		result["close"] = kline[2]
@@ -1215,6 +2347,40 @@ func (m *TradingStreamManager) parseUnixSeconds(v interface{}) (int64, bool) {
 	}
 }
 
+// unixMilliThreshold separates second from millisecond timestamps; any epoch
+// value below it (before 2001-09-09 in ms) must be in seconds
+const unixMilliThreshold = 1e12
+
+// parseUnixMilli normalizes a BTCC timestamp to milliseconds. BTCC mixes
+// integer seconds (kline), fractional seconds (order ctime/mtime) and
+// seconds or milliseconds (depth time) depending on the channel.
+func (m *TradingStreamManager) parseUnixMilli(v interface{}) (int64, bool) {
+	var f float64
+	switch t := v.(type) {
+	case int64:
+		f = float64(t)
+	case int:
+		f = float64(t)
+	case float64:
+		f = t
+	case json.Number:
+		n, err := t.Float64()
+		if err != nil {
+			return 0, false
+		}
+		f = n
+	default:
+		return 0, false
+	}
+	if f <= 0 {
+		return 0, false
+	}
+	if f < unixMilliThreshold {
+		f *= 1000
+	}
+	return int64(f), true
+}
+
 func (m *TradingStreamManager) subscriptionKey(typ, symbol, interval string) string {
 	s := strings.ToUpper(strings.TrimSpace(symbol))
 	i := strings.TrimSpace(interval)
@@ -1256,7 +2422,7 @@ func (m *TradingStreamManager) sendBTCCKlineHistory(conn *websocket.Conn, ec *Ex
 
 	u, err := url.Parse(ec.Config.BaseRESTURL)
 	if err != nil {
-		log.Printf("BTCC kline history: invalid base url: %v", err)
+		logs.Errorf("BTCC kline history: invalid base url: %v", err)
 		return
 	}
 	u.Path = strings.TrimSuffix(u.Path, "/") + "/btcc_api_trade/market/kline"
@@ -1271,45 +2437,45 @@ func (m *TradingStreamManager) sendBTCCKlineHistory(conn *websocket.Conn, ec *Ex
 	client := &http.Client{Timeout: 10 * time.Second}
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
-		log.Printf("BTCC kline history: new request: %v", err)
+		logs.Errorf("BTCC kline history: new request: %v", err)
 		return
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Printf("BTCC kline history: request: %v", err)
+		logs.Errorf("BTCC kline history: request: %v", err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		log.Printf("BTCC kline history: status=%d body=%s", resp.StatusCode, string(body))
+		logs.Warnf("BTCC kline history: status=%d body=%s", resp.StatusCode, redactPayload(body))
 		return
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("ERROR: read kline history body, err: %+v", err)
+		logs.Errorf("BTCC kline history: read body: %+v", err)
 		return
 	}
 
-	log.Printf("kline history raw data: %s", string(body))
+	logs.Debugf("kline history raw data: %s", redactPayload(body))
 
 	var decoded struct {
 		Error  any             `json:"error"`
 		Result [][]interface{} `json:"result"`
 	}
 	if err := json.Unmarshal(body, &decoded); err != nil {
-		log.Printf("BTCC kline history: decode: %v", err)
+		logs.Errorf("BTCC kline history: decode: %v", err)
 		return
 	}
 	if decoded.Error != nil {
-		log.Printf("BTCC kline history: error=%+v", decoded.Error)
+		logs.Errorf("BTCC kline history: error=%+v", decoded.Error)
 		return
 	}
 
-	log.Printf("kline history decoded data: %+v", decoded)
+	logs.Debugf("kline history decoded data: %+v", decoded)
 
 	rows := decoded.Result
 	if len(rows) == 0 {
@@ -1410,8 +2576,8 @@ func (m *TradingStreamManager) parseBTCCDepth(ec *ExchangeConnection, market str
 
 	// Update timestamp
 	cache.ts = time.Now().UnixMilli()
-	if ts, ok := data["time"].(float64); ok {
-		cache.ts = int64(ts)
+	if ts, ok := m.parseUnixMilli(data["time"]); ok {
+		cache.ts = ts
 	}
 
 	// Build orderbook from cache
@@ -1469,20 +2635,7 @@ func (m *TradingStreamManager) parseBTCCOrder(data map[string]interface{}, statu
 		order.Symbol = v
 	}
 	if v, ok := data["side"].(float64); ok {
-		// BTCC: 1=buy, 2=sell
-		if int(v) == 1 {
-			order.Side = "BUY"
-		} else {
-			order.Side = "SELL"
-		}
-	}
-	if v, ok := data["type"].(float64); ok {
-		// BTCC: 1=limit, 2=market
-		if int(v) == 1 {
-			order.Type = "LIMIT"
-		} else {
-			order.Type = "MARKET"
-		}
+		order.Side = btccOrderSide(int(v))
 	}
 	if v, ok := data["price"].(string); ok {
 		order.Price = v
@@ -1493,101 +2646,241 @@ func (m *TradingStreamManager) parseBTCCOrder(data map[string]interface{}, statu
 	if v, ok := data["deal_stock"].(string); ok {
 		order.ExecutedQty = v
 	}
+	if v, ok := data["deal_money"].(string); ok {
+		order.ExecutedQuote = v
+	}
+	if v, ok := data["stop_price"].(string); ok && !isZeroDecimal(v) {
+		order.StopPrice = v
+	}
+	if v, ok := data["type"].(float64); ok {
+		order.Type = btccOrderType(int(v), order.StopPrice != "")
+	}
 
-	switch status {
-	case 1:
-		order.Status = "PLACED"
-	case 2:
-		order.Status = "PARTIALLY_FILLED"
-	case 3:
-		if v, ok := data["left"].(string); ok {
-			leftQty, _ := strconv.ParseFloat(v, 64)
-			if leftQty == 0 {
-				order.Status = "FILLED"
-			} else {
-				order.Status = "CANCELED"
+	left, hasLeft := data["left"].(string)
+	order.Status = btccOrderStatus(status, left, hasLeft)
+
+	if v, ok := data["option"].(float64); ok {
+		order.TimeInForce = btccTimeInForce(int(v))
+	}
+	if v, ok := m.parseUnixMilli(data["ctime"]); ok {
+		order.CreateTime = v
+	}
+	if v, ok := m.parseUnixMilli(data["mtime"]); ok {
+		order.UpdateTime = v
+	}
+
+	return order
+}
+
+// isZeroDecimal reports whether an exchange decimal string is empty or zero
+func isZeroDecimal(v string) bool {
+	f, err := strconv.ParseFloat(v, 64)
+	return v == "" || (err == nil && f == 0)
+}
+
+func (m *TradingStreamManager) parseOrderBookData(data map[string]interface{}) *model.OrderBook {
+	ob := &model.OrderBook{
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	if s, ok := data["s"].(string); ok {
+		ob.Symbol = s
+	}
+	if u, ok := data["u"].(float64); ok {
+		ob.LastUpdateID = int64(u)
+	}
+
+	// Parse bids
+	if bids, ok := data["b"].([]interface{}); ok {
+		ob.Bids = make([]model.OrderBookLevel, 0, len(bids))
+		for _, bid := range bids {
+			if level, ok := bid.([]interface{}); ok && len(level) >= 2 {
+				ob.Bids = append(ob.Bids, model.OrderBookLevel{
+					Price:    fmt.Sprint(level[0]),
+					Quantity: fmt.Sprint(level[1]),
+				})
 			}
-		} else {
-			order.Status = "FILLED"
 		}
-	default:
-		order.Status = "UNKNOWN"
+		if len(ob.Bids) > 0 {
+			ob.BestBid = &ob.Bids[0]
+		}
+	}
+
+	// Parse asks
+	if asks, ok := data["a"].([]interface{}); ok {
+		ob.Asks = make([]model.OrderBookLevel, 0, len(asks))
+		for _, ask := range asks {
+			if level, ok := ask.([]interface{}); ok && len(level) >= 2 {
+				ob.Asks = append(ob.Asks, model.OrderBookLevel{
+					Price:    fmt.Sprint(level[0]),
+					Quantity: fmt.Sprint(level[1]),
+				})
+			}
+		}
+		if len(ob.Asks) > 0 {
+			ob.BestAsk = &ob.Asks[0]
+		}
+	}
+
+	// Calculate spread
+	if ob.BestBid != nil && ob.BestAsk != nil {
+		bidPrice, _ := strconv.ParseFloat(ob.BestBid.Price, 64)
+		askPrice, _ := strconv.ParseFloat(ob.BestAsk.Price, 64)
+		spread := askPrice - bidPrice
+		ob.Spread = fmt.Sprintf("%.8f", spread)
+	}
+
+	return ob
+}
+
+// parsePartialDepth converts a Binance partial book depth frame, which names
+// neither the symbol nor the side fields like a diff, into an order book
+func (m *TradingStreamManager) parsePartialDepth(symbol string, data map[string]interface{}) *model.OrderBook {
+	return m.parseOrderBookData(map[string]interface{}{
+		"s": symbol,
+		"u": data["lastUpdateId"],
+		"b": data["bids"],
+		"a": data["asks"],
+	})
+}
+
+// maintainedBook is a Binance order book kept in sync server-side for mode "book"
+type maintainedBook struct {
+	book    *binance.OrderBook
+	syncing atomic.Bool // a snapshot fetch is in flight
+}
+
+// ensureBinanceBook creates the maintained book for a symbol and starts its initial sync
+func (m *TradingStreamManager) ensureBinanceBook(ec *ExchangeConnection, symbol string) {
+	symbol = strings.ToUpper(symbol)
+
+	ec.mu.Lock()
+	if ec.books == nil {
+		ec.books = make(map[string]*maintainedBook)
+	}
+	mb, ok := ec.books[symbol]
+	if !ok {
+		mb = &maintainedBook{book: binance.NewOrderBook()}
+		ec.books[symbol] = mb
+	}
+	ec.mu.Unlock()
+
+	if !ok {
+		m.resyncBinanceBook(ec, symbol, mb)
+	}
+}
+
+// resyncBinanceBook fetches a REST snapshot in the background unless one is already in flight
+func (m *TradingStreamManager) resyncBinanceBook(ec *ExchangeConnection, symbol string, mb *maintainedBook) {
+	if !mb.syncing.CompareAndSwap(false, true) {
+		return
 	}
 
-	if v, ok := data["option"].(float64); ok {
-		// BTCC: 0=GTC, 8=IOC, 16=FOK
-		switch int(v) {
-		case 0:
-			order.TimeInForce = "GTC"
-		case 8:
-			order.TimeInForce = "IOC"
-		case 16:
-			order.TimeInForce = "FOK"
+	go func() {
+		defer mb.syncing.Store(false)
+
+		client := binance.NewClient(ec.Config, "", "")
+		for attempt := 1; attempt <= bookResyncMaxAttempts; attempt++ {
+			if atomic.LoadInt32(&ec.closed) == 1 {
+				return
+			}
+			ec.mu.RLock()
+			current := ec.books[symbol]
+			ec.mu.RUnlock()
+			if current != mb {
+				return // unsubscribed while syncing
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			snapshot, err := client.DepthSnapshot(ctx, symbol, bookSnapshotLimit)
+			cancel()
+			if err == nil {
+				err = mb.book.LoadSnapshot(snapshot)
+			}
+			if err == nil {
+				logs.Debugf("binance book %s synced at update %d", symbol, snapshot.LastUpdateID)
+				return
+			}
+
+			logs.Warnf("binance book %s sync attempt %d failed: %v", symbol, attempt, err)
+			time.Sleep(bookResyncDelay)
 		}
-	}
-	if v, ok := data["ctime"].(float64); ok {
-		order.CreateTime = int64(v * 1000) // Convert to milliseconds
-	}
-	if v, ok := data["mtime"].(float64); ok {
-		order.UpdateTime = int64(v * 1000)
-	}
-
-	return order
+		logs.Errorf("binance book %s: giving up after %d sync attempts", symbol, bookResyncMaxAttempts)
+	}()
 }
 
-func (m *TradingStreamManager) parseOrderBookData(data map[string]interface{}) *model.OrderBook {
-	ob := &model.OrderBook{
-		Timestamp: time.Now().UnixMilli(),
+// updateBinanceBook applies a diff depth event to the symbol's maintained book,
+// if any, and broadcasts the consolidated top levels to "book" subscribers
+func (m *TradingStreamManager) updateBinanceBook(ec *ExchangeConnection, response model.TradingWebSocketResponse, data map[string]interface{}) {
+	symbol := strings.ToUpper(response.Symbol)
+	ec.mu.RLock()
+	mb := ec.books[symbol]
+	ec.mu.RUnlock()
+	if mb == nil {
+		return
 	}
 
-	if s, ok := data["s"].(string); ok {
-		ob.Symbol = s
+	diff := binance.DepthDiff{
+		Bids: parseDepthLevels(data["b"]),
+		Asks: parseDepthLevels(data["a"]),
 	}
-	if u, ok := data["u"].(float64); ok {
-		ob.LastUpdateID = int64(u)
+	if v, ok := data["U"].(float64); ok {
+		diff.FirstUpdateID = int64(v)
 	}
-
-	// Parse bids
-	if bids, ok := data["b"].([]interface{}); ok {
-		ob.Bids = make([]model.OrderBookLevel, 0, len(bids))
-		for _, bid := range bids {
-			if level, ok := bid.([]interface{}); ok && len(level) >= 2 {
-				ob.Bids = append(ob.Bids, model.OrderBookLevel{
-					Price:    fmt.Sprint(level[0]),
-					Quantity: fmt.Sprint(level[1]),
-				})
-			}
-		}
-		if len(ob.Bids) > 0 {
-			ob.BestBid = &ob.Bids[0]
-		}
+	if v, ok := data["u"].(float64); ok {
+		diff.FinalUpdateID = int64(v)
 	}
 
-	// Parse asks
-	if asks, ok := data["a"].([]interface{}); ok {
-		ob.Asks = make([]model.OrderBookLevel, 0, len(asks))
-		for _, ask := range asks {
-			if level, ok := ask.([]interface{}); ok && len(level) >= 2 {
-				ob.Asks = append(ob.Asks, model.OrderBookLevel{
-					Price:    fmt.Sprint(level[0]),
-					Quantity: fmt.Sprint(level[1]),
-				})
-			}
-		}
-		if len(ob.Asks) > 0 {
-			ob.BestAsk = &ob.Asks[0]
-		}
+	switch err := mb.book.Apply(diff); {
+	case errors.Is(err, binance.ErrOrderBookGap):
+		logs.Warnf("binance book %s: sequence gap at U=%d, re-syncing", symbol, diff.FirstUpdateID)
+		m.resyncBinanceBook(ec, symbol, mb)
+		return
+	case errors.Is(err, binance.ErrOrderBookNotSynced):
+		m.resyncBinanceBook(ec, symbol, mb)
+		return
+	case err != nil:
+		return
 	}
 
-	// Calculate spread
+	bids, asks, lastUpdateID := mb.book.Top(maintainedBookLevels)
+	ob := &model.OrderBook{
+		Symbol:       symbol,
+		LastUpdateID: lastUpdateID,
+		Bids:         bids,
+		Asks:         asks,
+		Timestamp:    response.Timestamp,
+	}
+	if len(ob.Bids) > 0 {
+		ob.BestBid = &ob.Bids[0]
+	}
+	if len(ob.Asks) > 0 {
+		ob.BestAsk = &ob.Asks[0]
+	}
 	if ob.BestBid != nil && ob.BestAsk != nil {
 		bidPrice, _ := strconv.ParseFloat(ob.BestBid.Price, 64)
 		askPrice, _ := strconv.ParseFloat(ob.BestAsk.Price, 64)
-		spread := askPrice - bidPrice
-		ob.Spread = fmt.Sprintf("%.8f", spread)
+		ob.Spread = fmt.Sprintf("%.8f", askPrice-bidPrice)
 	}
 
-	return ob
+	response.Data = ob
+	response.Mode = depthModePartial
+	m.broadcast(ec, response, true)
+}
+
+// parseDepthLevels converts Binance [[price, qty], ...] arrays into string pairs
+func parseDepthLevels(v interface{}) [][2]string {
+	raw, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	levels := make([][2]string, 0, len(raw))
+	for _, item := range raw {
+		if lv, ok := item.([]interface{}); ok && len(lv) >= 2 {
+			levels = append(levels, [2]string{fmt.Sprint(lv[0]), fmt.Sprint(lv[1])})
+		}
+	}
+	return levels
 }
 
 func (m *TradingStreamManager) connectPrivateStream(ec *ExchangeConnection) {
@@ -1610,48 +2903,31 @@ func (m *TradingStreamManager) connectBinancePrivate(ec *ExchangeConnection) {
 	// Binance requires a listen key for user data stream
 	listenKey, err := m.getBinanceListenKey(ec)
 	if err != nil {
-		log.Printf("failed to get Binance listen key: %v", err)
+		logs.Errorf("failed to get Binance listen key: %v", err)
+		if isBinanceAuthError(err) {
+			m.notifyStreamAuthLost(ec, "listen key request rejected: "+err.Error())
+		}
 		return
 	}
 
 	url := ec.Config.BaseWSURL + "/" + listenKey
 	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		log.Printf("Binance private ws connection error: %v", err)
+		logs.Errorf("Binance private ws connection error: %v", err)
 		return
 	}
 	ec.PrivateWS = ws
 
-	go m.readPrivateMessages(ec)
+	ec.startReader(ws, m.readPrivateMessages)
 	go m.keepAliveListenKey(ec, listenKey)
 }
 
 func (m *TradingStreamManager) getBinanceListenKey(ec *ExchangeConnection) (string, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	var url string
-	if ec.IsTestnet {
-		url = "https://testnet.binance.vision/api/v3/userDataStream"
-	} else {
-		url = "https://api.binance.com/api/v3/userDataStream"
-	}
-
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-MBX-APIKEY", ec.APIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
 	var result struct {
 		ListenKey string `json:"listenKey"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	client := binance.NewClient(ec.Config, ec.APIKey, ec.APISecret)
+	if err := client.DoAPIKey(context.Background(), http.MethodPost, "/v3/userDataStream", nil, &result); err != nil {
 		return "", err
 	}
 
@@ -1673,26 +2949,14 @@ func (m *TradingStreamManager) keepAliveListenKey(ec *ExchangeConnection, listen
 }
 
 func (m *TradingStreamManager) pingBinanceListenKey(ec *ExchangeConnection, listenKey string) {
-	client := &http.Client{Timeout: 10 * time.Second}
-
-	var url string
-	if ec.IsTestnet {
-		url = "https://testnet.binance.vision/api/v3/userDataStream?listenKey=" + listenKey
-	} else {
-		url = "https://api.binance.com/api/v3/userDataStream?listenKey=" + listenKey
-	}
-
-	req, err := http.NewRequest("PUT", url, nil)
-	if err != nil {
-		return
-	}
-	req.Header.Set("X-MBX-APIKEY", ec.APIKey)
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return
+	client := binance.NewClient(ec.Config, ec.APIKey, ec.APISecret)
+	params := url.Values{"listenKey": {listenKey}}
+	if err := client.DoAPIKey(context.Background(), http.MethodPut, "/v3/userDataStream", params, nil); err != nil {
+		logs.Warnf("Binance listen key keepalive error: %v", err)
+		if isBinanceAuthError(err) {
+			m.notifyStreamAuthLost(ec, "listen key keepalive rejected: "+err.Error())
+		}
 	}
-	resp.Body.Close()
 }
 
 func (m *TradingStreamManager) connectBTCCPrivate(ec *ExchangeConnection) {
@@ -1701,19 +2965,19 @@ func (m *TradingStreamManager) connectBTCCPrivate(ec *ExchangeConnection) {
 		EnableCompression: true,
 	}
 
-	log.Printf("BTCC private: connecting to %s", ec.Config.BaseWSURL)
+	logs.Debugf("BTCC private: connecting to %s", ec.Config.BaseWSURL)
 	ws, _, err := dialer.Dial(ec.Config.BaseWSURL, nil)
 	if err != nil {
-		log.Printf("BTCC private ws connection error: %v", err)
+		logs.Errorf("BTCC private ws connection error: %v", err)
 		return
 	}
 	ec.PrivateWS = ws
-	log.Printf("BTCC private: connected successfully")
+	logs.Debugf("BTCC private: connected successfully")
 
 	// BTCC uses server.accessid_auth for OpenAPI authentication
 	// Parameters: [access_id, sha256_of_secret_key]
 	// The secret key should be hashed with SHA256 and rendered as 64-char hex string
-	signature := m.signBTCCAccessKey(ec.APISecret)
+	signature := btcc.SignAccessKey(ec.APISecret)
 
 	// Debug logging for auth troubleshooting
 	secretLen := len(ec.APISecret)
@@ -1721,57 +2985,38 @@ func (m *TradingStreamManager) connectBTCCPrivate(ec *ExchangeConnection) {
 	if len(sigPrefix) > 8 {
 		sigPrefix = sigPrefix[:8] + "..."
 	}
-	log.Printf("BTCC private: auth debug - access_id=%s, secret_len=%d, sig_prefix=%s", ec.APIKey, secretLen, sigPrefix)
+	logs.Debugf("BTCC private: auth debug - access_id=%s, secret_len=%d, sig_prefix=%s", ec.APIKey, secretLen, sigPrefix)
 
-	msgID := atomic.AddInt64(&ec.btccMsgID, 1)
-	authReq := BTCCRequest{
-		ID:     msgID,
-		Method: "server.accessid_auth",
-		Params: []interface{}{ec.APIKey, signature},
-	}
+	authReq := btcc.NewRequest(&ec.btccIDs, btcc.MethodAccessIDAuth, []interface{}{ec.APIKey, signature})
+	msgID := authReq.ID
 
-	log.Printf("BTCC private: sending auth request, id=%d, access_id=%s", msgID, ec.APIKey)
-	if err := ws.WriteJSON(authReq); err != nil {
-		log.Printf("BTCC auth error: %v", err)
+	logs.Debugf("BTCC private: sending auth request, id=%d, access_id=%s", msgID, ec.APIKey)
+	if err := ec.writeBTCC(ws, authReq); err != nil {
+		logs.Errorf("BTCC auth error: %v", err)
 		ws.Close()
 		ec.PrivateWS = nil
 		return
 	}
-	log.Printf("BTCC private: auth request sent")
+	logs.Debugf("BTCC private: auth request sent")
 
 	// Mark as authenticated (will be confirmed by response)
 	ec.btccAuthed = false
 	ec.btccAuthID = msgID
 
 	// Start reading private messages
-	go m.readBTCCPrivateMessages(ec)
+	ec.startReader(ws, m.readBTCCPrivateMessages)
 
 	// Start ping loop for private connection
 	go m.btccPingLoop(ec, true)
 }
 
-// signBTCCAccessKey generates SHA256 hash of the secret key for BTCC authentication
-// Returns a 64-character hex string
-func (m *TradingStreamManager) signBTCCAccessKey(secretKey string) string {
-	hash := sha256.Sum256([]byte(secretKey))
-	return hex.EncodeToString(hash[:])
-}
-
 // readBTCCPrivateMessages reads messages from BTCC private WebSocket
-func (m *TradingStreamManager) readBTCCPrivateMessages(ec *ExchangeConnection) {
-	ec.mu.RLock()
-	ws := ec.PrivateWS
-	ec.mu.RUnlock()
-
-	if ws == nil {
-		return
-	}
-
+func (m *TradingStreamManager) readBTCCPrivateMessages(ec *ExchangeConnection, ws *websocket.Conn) {
 	for {
 		messageType, message, err := ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("BTCC private ws read error: %v", err)
+				logs.Warnf("BTCC private ws read error: %v", err)
 			}
 			return
 		}
@@ -1780,7 +3025,7 @@ func (m *TradingStreamManager) readBTCCPrivateMessages(ec *ExchangeConnection) {
 		if messageType == websocket.BinaryMessage {
 			decompressed, err := m.decompressFlate(message)
 			if err != nil {
-				log.Printf("BTCC decompress error: %v", err)
+				logs.Warnf("BTCC decompress error: %v", err)
 				continue
 			}
 			message = decompressed
@@ -1792,11 +3037,11 @@ func (m *TradingStreamManager) readBTCCPrivateMessages(ec *ExchangeConnection) {
 
 // handleBTCCPrivateMessage handles messages from BTCC private WebSocket
 func (m *TradingStreamManager) handleBTCCPrivateMessage(ec *ExchangeConnection, message []byte) {
-	log.Printf("BTCC private raw message: %s", string(message))
+	logs.Debugf("BTCC private raw message: %s", redactPayload(message))
 
-	var btccResp BTCCResponse
+	var btccResp btcc.Response
 	if err := json.Unmarshal(message, &btccResp); err != nil {
-		log.Printf("BTCC private parse error: %v", err)
+		logs.Warnf("BTCC private parse error: %v", err)
 		return
 	}
 
@@ -1804,6 +3049,13 @@ func (m *TradingStreamManager) handleBTCCPrivateMessage(ec *ExchangeConnection,
 	if btccResp.Error != nil {
 		logs.Errorf("BTCC private error: code=%d, message=%s, id=%v, method=%s", btccResp.Error.Code, btccResp.Error.Message, btccResp.ID, btccResp.Method)
 
+		ec.mu.RLock()
+		authFailed := btccResp.ID != nil && *btccResp.ID == ec.btccAuthID
+		ec.mu.RUnlock()
+		if authFailed {
+			m.notifyStreamAuthLost(ec, "authentication rejected: "+btccResp.Error.Message)
+		}
+
 		// Broadcast error to clients
 		m.broadcastToClients(ec, model.TradingWebSocketResponse{
 			Type:      "error",
@@ -1827,17 +3079,25 @@ func (m *TradingStreamManager) handleBTCCPrivateMessage(ec *ExchangeConnection,
 		}
 		if err := json.Unmarshal(btccResp.Result, &authResult); err == nil {
 			if authResult.Status == "success" {
+				// Snapshot the subscriptions in the same critical section that
+				// marks the socket authenticated; later ones subscribe themselves
 				ec.mu.Lock()
 				ec.btccAuthed = true
+				ws := ec.PrivateWS
+				subs := make([]string, 0, len(ec.PrivateSubs))
+				for sub := range ec.PrivateSubs {
+					subs = append(subs, sub)
+				}
 				ec.mu.Unlock()
-				log.Printf("BTCC authentication successful, user flag: %d", authResult.Flag)
+				logs.Infof("BTCC authentication successful, user flag: %d", authResult.Flag)
 
 				// Subscribe to private channels after authentication
-				for sub := range ec.PrivateSubs {
-					m.sendBTCCSubscription(ec, sub, true)
+				for _, sub := range subs {
+					m.writeBTCCSubscription(ec, ws, sub)
 				}
 				return
 			}
+			m.notifyStreamAuthLost(ec, "authentication status "+authResult.Status)
 		}
 	}
 
@@ -1853,150 +3113,530 @@ func (m *TradingStreamManager) handleBTCCPrivateMessage(ec *ExchangeConnection,
 	}
 }
 
-func (m *TradingStreamManager) readPrivateMessages(ec *ExchangeConnection) {
-	ec.mu.RLock()
-	ws := ec.PrivateWS
-	ec.mu.RUnlock()
+func (m *TradingStreamManager) readPrivateMessages(ec *ExchangeConnection, ws *websocket.Conn) {
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				logs.Warnf("private ws read error: %v", err)
+			}
+			return
+		}
+
+		m.handlePrivateMessage(ec, message)
+	}
+}
+
+func (m *TradingStreamManager) handlePrivateMessage(ec *ExchangeConnection, message []byte) {
+	var response model.TradingWebSocketResponse
+	response.Platform = ec.Platform.String()
+	response.Timestamp = time.Now().UnixMilli()
+
+	switch ec.Platform {
+	case model.PlatformBinance:
+		var data map[string]interface{}
+		if err := json.Unmarshal(message, &data); err != nil {
+			return
+		}
+
+		if eventType, ok := data["e"].(string); ok {
+			switch eventType {
+			case "executionReport":
+				response.Type = "order"
+				order := m.parseBinanceOrder(data)
+				response.Data = order
+				response.Symbol = order.Symbol
+			case "outboundAccountPosition":
+				m.broadcastToClients(ec, model.TradingWebSocketResponse{
+					Type:      "balance",
+					Data:      parseBinanceBalances(data),
+					Platform:  response.Platform,
+					Timestamp: response.Timestamp,
+				})
+				if m.rawBalanceEvents {
+					response.Type = "account"
+					response.Data = data
+				}
+			case "listenKeyExpired":
+				// Binance stops the user data stream; order updates end here
+				logs.Warnf("Binance listen key expired for api key %s", ec.APIKeyID)
+				m.notifyStreamAuthLost(ec, "listen key expired")
+			}
+		}
+
+	case model.PlatformBTCC:
+		// BTCC private messages are handled by readBTCCPrivateMessages
+		return
+	}
+
+	if response.Type != "" {
+		m.broadcastToClients(ec, response)
+	}
+}
+
+func (m *TradingStreamManager) parseBinanceOrder(data map[string]interface{}) *model.Order {
+	order := &model.Order{
+		Platform: model.PlatformBinance,
+	}
+
+	if v, ok := data["i"].(float64); ok {
+		order.OrderID = fmt.Sprintf("%.0f", v)
+	}
+	if v, ok := data["s"].(string); ok {
+		order.Symbol = v
+	}
+	if v, ok := data["S"].(string); ok {
+		order.Side = binanceOrderSide(v)
+	}
+	if v, ok := data["o"].(string); ok {
+		order.Type = binanceOrderType(v)
+	}
+	if v, ok := data["p"].(string); ok {
+		order.Price = v
+	}
+	if v, ok := data["q"].(string); ok {
+		order.Quantity = v
+	}
+	if v, ok := data["z"].(string); ok {
+		order.ExecutedQty = v
+	}
+	if v, ok := data["Z"].(string); ok {
+		order.ExecutedQuote = v
+	}
+	if v, ok := data["X"].(string); ok {
+		order.Status = binanceOrderStatus(v)
+	}
+	if v, ok := data["f"].(string); ok {
+		order.TimeInForce = binanceTimeInForce(v)
+	}
+	if v, ok := data["P"].(string); ok && !isZeroDecimal(v) {
+		// Binance reports "0.00000000" for orders without a trigger price
+		order.StopPrice = v
+	}
+	if v, ok := data["T"].(float64); ok {
+		order.CreateTime = int64(v)
+	}
+
+	return order
+}
+
+func (m *TradingStreamManager) broadcastToClients(ec *ExchangeConnection, response model.TradingWebSocketResponse) {
+	m.record(response)
+	m.recordOrder(ec, response)
+	m.broadcast(ec, response, false)
+}
+
+// broadcast sends the frame to subscribed clients. Orderbook frames go only to
+// clients whose mode matches: maintained-book frames to "book" subscribers,
+// exchange frames to the rest by their partial or diff mode.
+func (m *TradingStreamManager) broadcast(ec *ExchangeConnection, response model.TradingWebSocketResponse, maintainedBook bool) {
+	m.checkAlerts(ec, response)
+
+	ec.mu.RLock()
+	clients := make([]*websocket.Conn, 0, len(ec.Clients))
+	for client := range ec.Clients {
+		clients = append(clients, client)
+	}
+	ec.mu.RUnlock()
+
+	subKey := m.subscriptionKey(response.Type, response.Symbol, "")
+	if response.Type == "kline" {
+		subKey = m.subscriptionKey(response.Type, response.Symbol, response.Interval)
+	}
+
+	// Stale orderbook and kline frames may be dropped from a full send queue
+	var streamKey string
+	if response.Type == "orderbook" || response.Type == "kline" {
+		streamKey = subKey
+	}
+
+	// Marshal once and share the frame between all clients
+	var payload []byte
+
+	for _, client := range clients {
+		m.mu.RLock()
+		state := m.clients[client]
+		isAllowed := state != nil && state.Subscriptions[subKey] && !state.BlockedSubs[subKey]
+		if isAllowed && response.Type == "orderbook" {
+			isAllowed = state.BookSubs[subKey] == maintainedBook &&
+				(maintainedBook || state.DepthModes[subKey] == response.Mode)
+		}
+		var throttle *depthThrottle
+		if isAllowed {
+			if response.Type == "kline" {
+				throttle = state.KlineThrottles[subKey]
+			} else {
+				throttle = state.DepthThrottles[subKey]
+			}
+		}
+		m.mu.RUnlock()
+		if !isAllowed {
+			continue
+		}
+
+		if payload == nil {
+			var err error
+			payload, err = json.Marshal(response)
+			if err != nil {
+				logs.Errorf("marshal broadcast error: %v", err)
+				return
+			}
+		}
+
+		if throttle != nil && response.Type == "kline" {
+			m.sendConflatedKline(client, throttle, payload, response)
+			continue
+		}
+		if throttle != nil {
+			m.sendThrottled(client, throttle, payload, 0)
+			continue
+		}
+		m.enqueue(client, queuedFrame{payload: payload, key: streamKey})
+	}
+}
+
+// record hands kline and trade events to the recorder, which queues without blocking
+func (m *TradingStreamManager) record(response model.TradingWebSocketResponse) {
+	if m.recorder == nil {
+		return
+	}
+	if response.Type != model.RecordTypeKline && response.Type != model.RecordTypeTrades {
+		return
+	}
+	m.recorder.Record(model.MarketRecord{
+		Platform: response.Platform,
+		Type:     response.Type,
+		Symbol:   strings.ToUpper(response.Symbol),
+		Interval: response.Interval,
+		Time:     time.UnixMilli(response.Timestamp),
+		Data:     response.Data,
+	})
+}
+
+// recordOrder hands order updates to the order event store, which queues without blocking
+func (m *TradingStreamManager) recordOrder(ec *ExchangeConnection, response model.TradingWebSocketResponse) {
+	if m.orderEvents == nil || response.Type != "order" {
+		return
+	}
+	order, ok := response.Data.(*model.Order)
+	if !ok || order == nil {
+		return
+	}
+	m.orderEvents.Record(model.OrderEvent{
+		APIKeyID:   ec.APIKeyID,
+		Order:      *order,
+		ReceivedAt: time.UnixMilli(response.Timestamp),
+	})
+}
+
+func (m *TradingStreamManager) sendToClient(conn *websocket.Conn, response model.TradingWebSocketResponse) {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		logs.Errorf("marshal response error: %v", err)
+		return
+	}
+	m.sendRaw(conn, payload)
+}
+
+// sendRaw queues an already encoded JSON frame that must reach the client
+func (m *TradingStreamManager) sendRaw(conn *websocket.Conn, payload []byte) {
+	m.enqueue(conn, queuedFrame{payload: payload})
+}
 
-	if ws == nil {
+// enqueue hands a frame to the client's writer without blocking
+func (m *TradingStreamManager) enqueue(conn *websocket.Conn, frame queuedFrame) {
+	m.mu.RLock()
+	queue := m.queues[conn]
+	var cm *clientMetrics
+	if state, ok := m.clients[conn]; ok {
+		cm = state.Metrics
+	}
+	m.mu.RUnlock()
+	if queue == nil {
 		return
 	}
 
-	for {
-		_, message, err := ws.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("private ws read error: %v", err)
-			}
-			return
+	dropped, overflow := queue.push(frame)
+	if dropped > 0 {
+		m.metrics.framesDropped.Add(int64(dropped))
+		if cm != nil {
+			cm.framesDropped.Add(int64(dropped))
 		}
-
-		m.handlePrivateMessage(ec, message)
+	}
+	if overflow {
+		logs.Warnf("trading client %s fell %d frames behind, closing it", conn.RemoteAddr(), dropped)
+		// closeClient waits for the writer, which must not hold up the sender
+		go m.closeClient(conn, CloseCodeRateLimited, CloseReasonSendQueueFull)
 	}
 }
 
-func (m *TradingStreamManager) handlePrivateMessage(ec *ExchangeConnection, message []byte) {
-	var response model.TradingWebSocketResponse
-	response.Platform = ec.Platform.String()
-	response.Timestamp = time.Now().UnixMilli()
+// writeLoop is the only writer of a client's data frames. It drains the send
+// queue until the client is removed, or until closeClient asks it to finish,
+// in which case it sends the close frame after the queued frames.
+func (m *TradingStreamManager) writeLoop(conn *websocket.Conn, queue *sendQueue, cm *clientMetrics) {
+	defer close(queue.done)
 
-	switch ec.Platform {
-	case model.PlatformBinance:
-		var data map[string]interface{}
-		if err := json.Unmarshal(message, &data); err != nil {
-			return
+	for {
+		frame, ok := queue.next()
+		if !ok {
+			break
 		}
 
-		if eventType, ok := data["e"].(string); ok {
-			switch eventType {
-			case "executionReport":
-				response.Type = "order"
-				order := m.parseBinanceOrder(data)
-				response.Data = order
-				response.Symbol = order.Symbol
-			case "outboundAccountPosition":
-				response.Type = "account"
-				response.Data = data
-			}
+		// Set a reasonable write deadline to avoid hung connections
+		_ = conn.SetWriteDeadline(time.Now().Add(clientWriteWait))
+
+		if err := conn.WriteMessage(websocket.TextMessage, frame.payload); err != nil {
+			m.metrics.writeErrors.Add(1)
+			cm.writeErrors.Add(1)
+			logs.Warnf("send to client %s error: %v", conn.RemoteAddr(), err)
+			// A failed write leaves the connection unusable; the read loop removes the client
+			queue.close()
+			conn.Close()
+			return
 		}
+		m.metrics.framesSent.Add(1)
+		m.metrics.bytesSent.Add(int64(len(frame.payload)))
+		cm.framesSent.Add(1)
+		cm.bytesSent.Add(int64(len(frame.payload)))
+	}
 
-	case model.PlatformBTCC:
-		// BTCC private messages are handled by readBTCCPrivateMessages
-		return
+	if closeMessage := queue.closeMessage(); closeMessage != nil {
+		_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(clientWriteWait))
+		conn.Close()
 	}
+}
 
-	if response.Type != "" {
-		m.broadcastToClients(ec, response)
+// clientMetricsFor returns the counters of a connected client, or nil
+func (m *TradingStreamManager) clientMetricsFor(conn *websocket.Conn) *clientMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if state, ok := m.clients[conn]; ok {
+		return state.Metrics
 	}
+	return nil
 }
 
-func (m *TradingStreamManager) parseBinanceOrder(data map[string]interface{}) *model.Order {
-	order := &model.Order{
-		Platform: model.PlatformBinance,
+// setDepthThrottle configures (or removes, when ms <= 0) orderbook coalescing for a client subscription
+func (m *TradingStreamManager) setDepthThrottle(conn *websocket.Conn, subKey string, ms int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.clients[conn]; ok {
+		resetThrottle(state.DepthThrottles, subKey, ms)
 	}
+}
 
-	if v, ok := data["i"].(float64); ok {
-		order.OrderID = fmt.Sprintf("%.0f", v)
+// setKlineThrottle configures (or removes, when ms <= 0) conflation of the open
+// candle for a client's kline subscription
+func (m *TradingStreamManager) setKlineThrottle(conn *websocket.Conn, subKey string, ms int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.clients[conn]; ok {
+		resetThrottle(state.KlineThrottles, subKey, ms)
 	}
-	if v, ok := data["s"].(string); ok {
-		order.Symbol = v
+}
+
+// resetThrottle replaces the throttle of subKey in throttles; callers hold m.mu
+func resetThrottle(throttles map[string]*depthThrottle, subKey string, ms int) {
+	if t, ok := throttles[subKey]; ok {
+		t.stop()
+		delete(throttles, subKey)
 	}
-	if v, ok := data["S"].(string); ok {
-		order.Side = v
+	if ms > 0 {
+		throttles[subKey] = &depthThrottle{interval: time.Duration(ms) * time.Millisecond, key: subKey}
 	}
-	if v, ok := data["o"].(string); ok {
-		order.Type = v
+}
+
+// sendConflatedKline throttles updates of the open candle. A closed candle is
+// sent at once in place of its pending update, and a pending update of an
+// earlier candle is flushed before the next candle's first frame.
+func (m *TradingStreamManager) sendConflatedKline(conn *websocket.Conn, t *depthThrottle, payload []byte, response model.TradingWebSocketResponse) {
+	openTime, closed := klineCandle(response)
+
+	t.mu.Lock()
+	var flush []byte
+	if t.pending != nil && (closed || t.candle != openTime) {
+		if t.candle == openTime {
+			// The closed candle supersedes its pending update
+			m.countCoalesced(conn, t.pending)
+		} else {
+			flush = t.pending
+		}
+		t.pending = nil
+		if t.timer != nil {
+			t.timer.Stop()
+			t.timer = nil
+		}
 	}
-	if v, ok := data["p"].(string); ok {
-		order.Price = v
+	if closed {
+		t.lastSent = time.Now()
 	}
-	if v, ok := data["q"].(string); ok {
-		order.Quantity = v
+	t.mu.Unlock()
+
+	if flush != nil {
+		m.enqueue(conn, queuedFrame{payload: flush, key: t.key})
 	}
-	if v, ok := data["z"].(string); ok {
-		order.ExecutedQty = v
+	if closed {
+		m.enqueue(conn, queuedFrame{payload: payload})
+		return
 	}
-	if v, ok := data["X"].(string); ok {
-		order.Status = v
+	m.sendThrottled(conn, t, payload, openTime)
+}
+
+// klineCandle returns the open time (ms) of the candle in a kline frame and
+// whether the exchange marked it closed. BTCC does not flag closed candles;
+// its candles end when the next one starts.
+func klineCandle(response model.TradingWebSocketResponse) (int64, bool) {
+	data, ok := response.Data.(map[string]interface{})
+	if !ok {
+		return 0, false
 	}
-	if v, ok := data["f"].(string); ok {
-		order.TimeInForce = v
+	if k, ok := data["k"].(map[string]interface{}); ok {
+		openTime, _ := k["t"].(float64)
+		closed, _ := k["x"].(bool)
+		return int64(openTime), closed
 	}
-	if v, ok := data["T"].(float64); ok {
-		order.CreateTime = int64(v)
+	if openTime, ok := data["time"].(int64); ok {
+		return openTime, false
 	}
+	return 0, false
+}
 
-	return order
+// countCoalesced records a frame replaced by a newer one before being sent
+func (m *TradingStreamManager) countCoalesced(conn *websocket.Conn, payload []byte) {
+	m.metrics.framesCoalesced.Add(1)
+	m.metrics.bytesCoalesced.Add(int64(len(payload)))
+	if cm := m.clientMetricsFor(conn); cm != nil {
+		cm.framesCoalesced.Add(1)
+	}
 }
 
-func (m *TradingStreamManager) broadcastToClients(ec *ExchangeConnection, response model.TradingWebSocketResponse) {
-	ec.mu.RLock()
-	clients := make([]*websocket.Conn, 0, len(ec.Clients))
-	for client := range ec.Clients {
-		clients = append(clients, client)
+// sendThrottled sends the frame immediately if the interval has elapsed, otherwise it
+// replaces any pending frame and schedules a flush at the end of the interval.
+// candle is the open time of a kline frame, 0 for orderbooks.
+func (m *TradingStreamManager) sendThrottled(conn *websocket.Conn, t *depthThrottle, payload []byte, candle int64) {
+	t.mu.Lock()
+	now := time.Now()
+	if t.timer == nil && now.Sub(t.lastSent) >= t.interval {
+		t.lastSent = now
+		t.mu.Unlock()
+		m.enqueue(conn, queuedFrame{payload: payload, key: t.key})
+		return
 	}
-	ec.mu.RUnlock()
 
-	subKey := m.subscriptionKey(response.Type, response.Symbol, "")
-	if response.Type == "kline" {
-		subKey = m.subscriptionKey(response.Type, response.Symbol, response.Interval)
+	if t.pending != nil {
+		m.countCoalesced(conn, t.pending)
+	}
+	t.pending = payload
+	t.candle = candle
+	if t.timer == nil {
+		t.timer = time.AfterFunc(t.interval-now.Sub(t.lastSent), func() {
+			t.mu.Lock()
+			pending := t.pending
+			t.pending = nil
+			t.candle = 0
+			t.timer = nil
+			t.lastSent = time.Now()
+			t.mu.Unlock()
+			if pending != nil {
+				m.enqueue(conn, queuedFrame{payload: pending, key: t.key})
+			}
+		})
 	}
+	t.mu.Unlock()
+}
 
-	for _, client := range clients {
-		m.mu.RLock()
-		state := m.clients[client]
-		isAllowed := state != nil && state.Subscriptions[subKey] && !state.BlockedSubs[subKey]
-		m.mu.RUnlock()
-		if !isAllowed {
-			continue
-		}
-		m.sendToClient(client, response)
+func (t *depthThrottle) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
 	}
+	t.pending = nil
 }
 
-func (m *TradingStreamManager) sendToClient(conn *websocket.Conn, response model.TradingWebSocketResponse) {
+// Stats returns a snapshot of the manager's connection and bandwidth metrics
+func (m *TradingStreamManager) Stats() model.TradingStreamStats {
+	stats := model.TradingStreamStats{
+		FramesSent:      m.metrics.framesSent.Load(),
+		BytesSent:       m.metrics.bytesSent.Load(),
+		WireBytes:       m.metrics.wireBytes.Load(),
+		FramesCoalesced: m.metrics.framesCoalesced.Load(),
+		BytesCoalesced:  m.metrics.bytesCoalesced.Load(),
+		WriteErrors:     m.metrics.writeErrors.Load(),
+		FramesDropped:   m.metrics.framesDropped.Load(),
+		Limits: model.TradingLimits{
+			MaxClients:                    m.limiter.limit(),
+			MaxExchangeConnections:        m.exchangeLimits.Global,
+			MaxExchangeConnectionsPerUser: m.exchangeLimits.PerUser,
+			ExchangeIdleTimeoutMs:         m.exchangeIdleTimeout.Milliseconds(),
+			ClientSendQueue:               m.sendQueueSize,
+		},
+	}
+	stats.BytesSaved = stats.BytesCoalesced
+	if compressed := stats.BytesSent - stats.WireBytes; compressed > 0 {
+		stats.BytesSaved += compressed
+	}
+
+	userKeys := make(map[string]map[string]bool)
 	m.mu.RLock()
-	writeMu := m.writes[conn]
-	m.mu.RUnlock()
-	if writeMu == nil {
-		return
+	stats.Clients = len(m.clients)
+	stats.ClientStats = make([]model.TradingClientStats, 0, len(m.clients))
+	for conn, state := range m.clients {
+		if state.Compressed {
+			stats.CompressedClients++
+		}
+		if state.APIKeyID != "" {
+			if userKeys[state.UserID] == nil {
+				userKeys[state.UserID] = make(map[string]bool)
+			}
+			userKeys[state.UserID][state.APIKeyID] = true
+		}
+		stats.ClientStats = append(stats.ClientStats, model.TradingClientStats{
+			RemoteAddr:      conn.RemoteAddr().String(),
+			UserID:          state.UserID,
+			ConnectedAt:     state.ConnectedAt,
+			FramesSent:      state.Metrics.framesSent.Load(),
+			BytesSent:       state.Metrics.bytesSent.Load(),
+			WireBytes:       state.Metrics.wireBytes.Load(),
+			FramesCoalesced: state.Metrics.framesCoalesced.Load(),
+			WriteErrors:     state.Metrics.writeErrors.Load(),
+			FramesDropped:   state.Metrics.framesDropped.Load(),
+			Queued:          m.queues[conn].len(),
+		})
 	}
+	m.mu.RUnlock()
 
-	writeMu.Lock()
-	defer writeMu.Unlock()
+	stats.ExchangeConnectionsByUser = make(map[string]int, len(userKeys))
+	for userID, keys := range userKeys {
+		stats.ExchangeConnectionsByUser[userID] = len(keys)
+	}
 
-	// Set a reasonable write deadline to avoid hung connections
-	_ = conn.SetWriteDeadline(time.Now().Add(clientWriteWait))
+	m.exchangeMu.RLock()
+	stats.ExchangeConnections = len(m.exchangeConns)
+	m.exchangeMu.RUnlock()
 
-	if err := conn.WriteJSON(response); err != nil {
-		log.Printf("send to client error: %v", err)
+	if m.recorder != nil {
+		recording := m.recorder.Stats()
+		stats.Recording = &recording
 	}
+
+	return stats
 }
 
 func (m *TradingStreamManager) sendError(conn *websocket.Conn, message string) {
+	m.sendCodedError(conn, "", message)
+}
+
+// sendCodedError sends an error frame whose code lets clients tell it apart
+// from free-form errors
+func (m *TradingStreamManager) sendCodedError(conn *websocket.Conn, code, message string) {
 	m.sendToClient(conn, model.TradingWebSocketResponse{
 		Type:      "error",
 		Error:     message,
+		Code:      code,
 		Timestamp: time.Now().UnixMilli(),
 	})
 }
@@ -2049,26 +3689,50 @@ func (m *TradingStreamManager) convertIntervalToSeconds(interval string) int {
 	}
 }
 
-func (m *TradingStreamManager) formatOrderBookStream(platform model.Platform, symbol string) string {
+// formatOrderBookStream names the exchange stream of an orderbook in mode;
+// BTCC has a single stream and ignores it
+func (m *TradingStreamManager) formatOrderBookStream(platform model.Platform, symbol, mode string) string {
 	switch platform {
-	case model.PlatformBinance:
-		return strings.ToLower(symbol) + "@depth@100ms"
 	case model.PlatformBTCC:
 		// BTCC depth format: depth.MARKET.LIMIT.MERGE
 		// Default: 20 levels, 0.01 merge precision
 		return fmt.Sprintf("depth.%s.20", symbol)
 	default:
-		return strings.ToLower(symbol) + "@depth@100ms"
+		if mode == depthModeDiff {
+			return strings.ToLower(symbol) + "@depth@100ms"
+		}
+		return fmt.Sprintf("%s@depth%d@100ms", strings.ToLower(symbol), partialDepthLevels)
 	}
 }
 
+// isPartialDepthStream reports whether a Binance stream name is a partial book
+// depth stream, such as "btcusdt@depth20@100ms", rather than the diff stream
+func isPartialDepthStream(stream string) bool {
+	_, rest, ok := strings.Cut(stream, "@depth")
+	return ok && rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
 func (m *TradingStreamManager) removeClient(conn *websocket.Conn) {
 	m.mu.Lock()
 	state := m.clients[conn]
+	queue := m.queues[conn]
 	delete(m.clients, conn)
-	delete(m.writes, conn)
+	delete(m.queues, conn)
 	m.mu.Unlock()
 
+	if queue != nil {
+		queue.close()
+	}
+
+	if state != nil {
+		for _, t := range state.DepthThrottles {
+			t.stop()
+		}
+		for _, t := range state.KlineThrottles {
+			t.stop()
+		}
+	}
+
 	if state != nil && state.APIKeyID != "" {
 		var shouldCleanup bool
 		var apiKeyID string
@@ -2095,26 +3759,240 @@ func (m *TradingStreamManager) removeClient(conn *websocket.Conn) {
 	}
 }
 
-func (m *TradingStreamManager) cleanupExchangeConn(apiKeyID string) {
+// VerifyCredentials checks that key/secret are accepted by the exchange
+func (m *TradingStreamManager) VerifyCredentials(ctx context.Context, platform model.Platform, isTestnet bool, apiKey, apiSecret string) error {
+	config := m.exchangeConfig(platform, isTestnet)
+
+	switch platform {
+	case model.PlatformBinance:
+		return m.verifyBinanceCredentials(ctx, config, apiKey, apiSecret)
+	case model.PlatformBTCC:
+		return m.verifyBTCCCredentials(ctx, config, apiKey, apiSecret)
+	default:
+		return fmt.Errorf("credential verification not supported for platform %s", platform)
+	}
+}
+
+// notifyStreamAuthLost reports a private stream that stopped delivering
+// because the exchange rejected its credentials
+func (m *TradingStreamManager) notifyStreamAuthLost(ec *ExchangeConnection, reason string) {
+	if m.notifier == nil {
+		return
+	}
+	m.notifier.Notify(model.Notification{
+		Type:    model.NotificationStreamAuthLost,
+		Message: fmt.Sprintf("%s private stream lost authentication: %s", ec.Platform, reason),
+		Fields: map[string]string{
+			"api_key_id": ec.APIKeyID,
+			"platform":   ec.Platform.String(),
+			"testnet":    strconv.FormatBool(ec.IsTestnet),
+		},
+	})
+}
+
+// notifyVerificationFailed reports credentials the exchange refused during verification
+func (m *TradingStreamManager) notifyVerificationFailed(apiKey *model.APIKeyResponse, err error) {
+	if m.notifier == nil {
+		return
+	}
+	m.notifier.Notify(model.Notification{
+		Type:    model.NotificationAPIKeyVerificationFailed,
+		Message: "API key verification failed: " + err.Error(),
+		Fields: map[string]string{
+			"api_key_id": apiKey.ID,
+			"name":       apiKey.Name,
+			"platform":   apiKey.Platform.String(),
+		},
+	})
+}
+
+// isBinanceAuthError reports whether Binance rejected the API key or signature
+func isBinanceAuthError(err error) bool {
+	var apiErr *binance.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.Code {
+	case -1022, -2014, -2015: // invalid signature, bad API key format, invalid key/IP/permissions
+		return true
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized
+}
+
+// verifyBinanceCredentials performs a signed account request
+func (m *TradingStreamManager) verifyBinanceCredentials(ctx context.Context, config model.ExchangeConfig, apiKey, apiSecret string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := binance.NewClient(config, apiKey, apiSecret)
+	if err := client.DoSigned(ctx, http.MethodGet, "/v3/account", nil, nil); err != nil {
+		var apiErr *binance.APIError
+		if errors.As(err, &apiErr) {
+			return fmt.Errorf("binance rejected credentials: %s", apiErr.Msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyBTCCCredentials opens a short-lived WebSocket and performs server.accessid_auth
+func (m *TradingStreamManager) verifyBTCCCredentials(ctx context.Context, config model.ExchangeConfig, apiKey, apiSecret string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	ws, _, err := dialer.DialContext(ctx, config.BaseWSURL, nil)
+	if err != nil {
+		return fmt.Errorf("btcc connection failed: %w", err)
+	}
+	defer ws.Close()
+
+	deadline, _ := ctx.Deadline()
+	_ = ws.SetReadDeadline(deadline)
+
+	const authID int64 = 1
+	if err := ws.WriteJSON(btcc.Request{
+		ID:     authID,
+		Method: btcc.MethodAccessIDAuth,
+		Params: btcc.AuthParams(apiKey, apiSecret),
+	}); err != nil {
+		return fmt.Errorf("btcc auth request failed: %w", err)
+	}
+
+	for {
+		messageType, message, err := ws.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("btcc auth response failed: %w", err)
+		}
+		if messageType == websocket.BinaryMessage {
+			if message, err = m.decompressFlate(message); err != nil {
+				continue
+			}
+		}
+
+		var resp btcc.Response
+		if err := json.Unmarshal(message, &resp); err != nil || resp.ID == nil || *resp.ID != authID {
+			continue
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("btcc rejected credentials: %s", resp.Error.Message)
+		}
+
+		var result struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(resp.Result, &result); err != nil || result.Status != "success" {
+			return fmt.Errorf("btcc rejected credentials")
+		}
+		return nil
+	}
+}
+
+// RebuildExchangeConn replaces the exchange connection of an API key with one
+// using the currently stored credentials. Attached clients are kept and receive
+// a reconnecting/reconnected sequence, and their subscriptions are restored.
+func (m *TradingStreamManager) RebuildExchangeConn(apiKeyID string) {
+	apiKey, err := m.apiKeyRepo.GetByID(context.Background(), apiKeyID)
+	if err != nil || apiKey == nil {
+		logs.Warnf("rebuild exchange conn: API key %s not found: %v", apiKeyID, err)
+		return
+	}
+
 	m.exchangeMu.Lock()
-	ec, ok := m.exchangeConns[apiKeyID]
+	old, ok := m.exchangeConns[apiKeyID]
 	if !ok {
 		m.exchangeMu.Unlock()
 		return
 	}
-	delete(m.exchangeConns, apiKeyID)
+
+	old.mu.RLock()
+	ec := &ExchangeConnection{
+		APIKeyID:    apiKey.ID,
+		Platform:    apiKey.Platform,
+		IsTestnet:   apiKey.IsTestnet,
+		APIKey:      apiKey.APIKey,
+		APISecret:   apiKey.APISecret,
+		Config:      m.exchangeConfig(apiKey.Platform, apiKey.IsTestnet),
+		PublicSubs:  make(map[string]bool, len(old.PublicSubs)),
+		PrivateSubs: make(map[string]bool, len(old.PrivateSubs)),
+		Clients:     make(map[*websocket.Conn]bool, len(old.Clients)),
+		done:        make(chan struct{}),
+	}
+	for k, v := range old.PublicSubs {
+		ec.PublicSubs[k] = v
+	}
+	for k, v := range old.PrivateSubs {
+		ec.PrivateSubs[k] = v
+	}
+	clients := make([]*websocket.Conn, 0, len(old.Clients))
+	for c := range old.Clients {
+		ec.Clients[c] = true
+		clients = append(clients, c)
+	}
+	// Maintained books restart from a fresh snapshot, since the new diff
+	// stream does not continue the old one's sequence
+	books := make([]string, 0, len(old.books))
+	for symbol := range old.books {
+		books = append(books, symbol)
+	}
+	old.mu.RUnlock()
+
+	m.exchangeConns[apiKeyID] = ec
 	m.exchangeMu.Unlock()
 
-	// Close connections outside of lock
-	if atomic.CompareAndSwapInt32(&ec.closed, 0, 1) {
-		close(ec.done)
+	notice := func(typ string) model.TradingWebSocketResponse {
+		return model.TradingWebSocketResponse{
+			Type:      typ,
+			Platform:  ec.Platform.String(),
+			Timestamp: time.Now().UnixMilli(),
+			Data:      map[string]interface{}{"apiKeyId": apiKeyID},
+		}
 	}
-	if ec.PublicWS != nil {
-		ec.PublicWS.Close()
+	for _, c := range clients {
+		m.sendToClient(c, notice("reconnecting"))
 	}
-	if ec.PrivateWS != nil {
-		ec.PrivateWS.Close()
+
+	// Tear down the old upstream connections
+	old.shutdown()
+
+	// ec is already shared with subscribing clients
+	ec.mu.RLock()
+	hasPublic, hasPrivate := len(ec.PublicSubs) > 0, len(ec.PrivateSubs) > 0
+	ec.mu.RUnlock()
+
+	if ec.Platform == model.PlatformBinance {
+		for _, symbol := range books {
+			m.ensureBinanceBook(ec, symbol)
+		}
+	}
+	if hasPublic {
+		m.updatePublicConnection(ec)
+	}
+	if hasPrivate {
+		m.connectPrivateStream(ec)
+	}
+
+	for _, c := range clients {
+		m.sendToClient(c, notice("reconnected"))
+	}
+	logs.Infof("rebuilt exchange connection for apiKeyID=%s", apiKeyID)
+}
+
+// cleanupExchangeConn closes and forgets the exchange connection of an API key,
+// reporting whether there was one
+func (m *TradingStreamManager) cleanupExchangeConn(apiKeyID string) bool {
+	m.exchangeMu.Lock()
+	ec, ok := m.exchangeConns[apiKeyID]
+	if !ok {
+		m.exchangeMu.Unlock()
+		return false
 	}
+	delete(m.exchangeConns, apiKeyID)
+	m.exchangeMu.Unlock()
+
+	// Close connections outside of lock
+	ec.shutdown()
+	return true
 }
 
 func (m *TradingStreamManager) Close() {
@@ -2124,15 +4002,16 @@ func (m *TradingStreamManager) Close() {
 		return
 	}
 	m.closed = true
+	close(m.done)
 	m.mu.Unlock()
 
-	log.Println("TradingStreamManager: closing all connections...")
+	logs.Infof("TradingStreamManager: closing all connections...")
 
 	// Collect all exchange connections to close
 	m.exchangeMu.Lock()
 	exchangeConns := make([]*ExchangeConnection, 0, len(m.exchangeConns))
 	for apiKeyID, ec := range m.exchangeConns {
-		log.Printf("TradingStreamManager: will close exchange connection for apiKeyID=%s", apiKeyID)
+		logs.Debugf("TradingStreamManager: will close exchange connection for apiKeyID=%s", apiKeyID)
 		exchangeConns = append(exchangeConns, ec)
 	}
 	m.exchangeConns = make(map[string]*ExchangeConnection)
@@ -2140,17 +4019,9 @@ func (m *TradingStreamManager) Close() {
 
 	// Close exchange connections outside of lock
 	for _, ec := range exchangeConns {
-		if atomic.CompareAndSwapInt32(&ec.closed, 0, 1) {
-			close(ec.done)
-		}
-		if ec.PublicWS != nil {
-			ec.PublicWS.Close()
-		}
-		if ec.PrivateWS != nil {
-			ec.PrivateWS.Close()
-		}
+		ec.shutdown()
 	}
-	log.Println("TradingStreamManager: exchange connections closed")
+	logs.Infof("TradingStreamManager: exchange connections closed")
 
 	// Collect all client connections to close
 	m.mu.Lock()
@@ -2163,8 +4034,8 @@ func (m *TradingStreamManager) Close() {
 
 	// Close client connections outside of lock
 	for _, conn := range clients {
-		conn.Close()
+		m.closeClient(conn, CloseCodeShutdown, CloseReasonShutdown)
 	}
 
-	log.Println("TradingStreamManager: all connections closed")
+	logs.Infof("TradingStreamManager: all connections closed")
 }
@@ -0,0 +1,680 @@
+package http
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+
+	"control_page/internal/exchangetest"
+	"control_page/internal/model"
+)
+
+const (
+	testBinanceKeyID = "binance-key"
+	testBTCCKeyID    = "btcc-key"
+)
+
+// newBinanceHarness starts a fake Binance and a manager with one key pointing at it
+func newBinanceHarness(t *testing.T) (*tradingHarness, *exchangetest.Binance) {
+	t.Helper()
+	exchange := exchangetest.NewBinance("binance-api-key")
+	t.Cleanup(exchange.Close)
+	h := newTradingHarness(t,
+		map[model.ExchangeNetwork]model.ExchangeURLs{
+			{Platform: model.PlatformBinance}: {WSURL: exchange.WSURL(), RESTURL: exchange.RESTURL()},
+		},
+		testAPIKey(testBinanceKeyID, model.PlatformBinance, "binance-api-key", "binance-secret"),
+	)
+	return h, exchange
+}
+
+// newBTCCHarness starts a fake BTCC and a manager with one key pointing at it
+func newBTCCHarness(t *testing.T) (*tradingHarness, *exchangetest.BTCC) {
+	t.Helper()
+	exchange := exchangetest.NewBTCC("btcc-access-id", "btcc-secret")
+	t.Cleanup(exchange.Close)
+	h := newTradingHarness(t,
+		map[model.ExchangeNetwork]model.ExchangeURLs{
+			{Platform: model.PlatformBTCC}: {WSURL: exchange.WSURL(), RESTURL: exchange.RESTURL()},
+		},
+		testAPIKey(testBTCCKeyID, model.PlatformBTCC, "btcc-access-id", "btcc-secret"),
+	)
+	return h, exchange
+}
+
+func TestTradingBinanceKline(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+
+	waitFor(t, "the kline stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	exchange.PushKline("BTCUSDT", "1m", exchangetest.Kline{
+		OpenTime: 1700000000000, CloseTime: 1700000059999,
+		Open: "100", High: "110", Low: "90", Close: "105", Volume: "12",
+	})
+
+	frame := client.expect("kline")
+	if frame.Platform != "binance" || frame.Symbol != "BTCUSDT" || frame.Interval != "1m" {
+		t.Fatalf("kline frame = %+v, want binance BTCUSDT 1m", frame)
+	}
+	var data struct {
+		K struct {
+			Close string `json:"c"`
+		} `json:"k"`
+	}
+	frame.decode(t, &data)
+	if data.K.Close != "105" {
+		t.Fatalf("kline close = %q, want %q", data.K.Close, "105")
+	}
+}
+
+func TestTradingBinancePartialOrderBook(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT"})
+
+	waitFor(t, "the partial depth stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@depth20@100ms")
+	})
+	exchange.PushPartialDepth("BTCUSDT", 20, 42,
+		[][2]string{{"100.0", "1.5"}, {"99.0", "2"}},
+		[][2]string{{"101.0", "0.5"}},
+	)
+
+	frame := client.expect("orderbook")
+	if frame.Mode != depthModePartial || frame.Symbol != "BTCUSDT" {
+		t.Fatalf("orderbook frame = %+v, want a partial BTCUSDT book", frame)
+	}
+	var book model.OrderBook
+	frame.decode(t, &book)
+	if book.LastUpdateID != 42 || len(book.Bids) != 2 || len(book.Asks) != 1 || book.BestBid.Price != "100.0" {
+		t.Fatalf("orderbook = %+v, want the pushed snapshot", book)
+	}
+}
+
+func TestTradingBinanceDiffOrderBook(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeDiff})
+
+	waitFor(t, "the diff depth stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@depth@100ms")
+	})
+	exchange.PushDepth("BTCUSDT", 10, 12, [][2]string{{"100.0", "0"}}, [][2]string{{"101.0", "3"}})
+
+	frame := client.expect("orderbook")
+	if frame.Mode != depthModeDiff {
+		t.Fatalf("orderbook mode = %q, want %q", frame.Mode, depthModeDiff)
+	}
+	var book model.OrderBook
+	frame.decode(t, &book)
+	if book.LastUpdateID != 12 || len(book.Bids) != 1 || book.Bids[0].Quantity != "0" {
+		t.Fatalf("orderbook = %+v, want the pushed diff", book)
+	}
+}
+
+func TestTradingBinanceMaintainedBook(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	exchange.SetDepthSnapshot("BTCUSDT", exchangetest.DepthSnapshot{
+		LastUpdateID: 100,
+		Bids:         [][2]string{{"100.0", "1"}, {"99.0", "1"}},
+		Asks:         [][2]string{{"101.0", "1"}},
+	})
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeBook})
+
+	waitFor(t, "the diff depth stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@depth@100ms")
+	})
+
+	// The snapshot loads in the background; diffs before it are discarded
+	h.waitBookSynced(t, testBinanceKeyID, "BTCUSDT")
+	exchange.PushDepth("BTCUSDT", 95, 105, [][2]string{{"100.0", "0"}}, [][2]string{{"102.0", "4"}})
+
+	frame := client.expect("orderbook")
+	if frame.Mode != depthModePartial {
+		t.Fatalf("maintained book mode = %q, want %q", frame.Mode, depthModePartial)
+	}
+	var book model.OrderBook
+	frame.decode(t, &book)
+	if book.LastUpdateID != 105 || len(book.Bids) != 1 || book.Bids[0].Price != "99.0" || len(book.Asks) != 2 {
+		t.Fatalf("maintained book = %+v, want the snapshot with the diff applied", book)
+	}
+}
+
+func TestTradingBTCCKlineHistoryAndUpdates(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	exchange.SetKlineHistory("BTCUSDT", [][]any{
+		{1700000060, "101", "102", "103", "100", "5", "500", "BTCUSDT"},
+		{1700000000, "100", "101", "102", "99", "4", "400", "BTCUSDT"},
+	})
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.send(model.TradingWebSocketMessage{Action: "subscribe", Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+
+	// History is sent oldest first before the subscription is acknowledged
+	for _, want := range []string{"100", "101"} {
+		var candle map[string]any
+		client.expect("kline").decode(t, &candle)
+		if candle["open"] != want {
+			t.Fatalf("history candle = %v, want open %s", candle, want)
+		}
+	}
+	client.expect("subscriptions")
+
+	waitFor(t, "the kline subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "kline.BTCUSDT.60")
+	})
+	exchange.PushKline("BTCUSDT", 60, 1700000120, "102", "104", "105", "101", "6", "600")
+
+	frame := client.expect("kline")
+	var candle map[string]any
+	frame.decode(t, &candle)
+	if frame.Platform != "btcc" || frame.Symbol != "BTCUSDT" || candle["close"] != "104" {
+		t.Fatalf("kline frame = %+v %v, want the pushed BTCUSDT candle", frame, candle)
+	}
+}
+
+func TestTradingBTCCOrderBook(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	// BTCC only sends full books, so a diff request is served as partial
+	client.subscribe(model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeDiff})
+
+	waitFor(t, "the depth subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "depth.BTCUSDT")
+	})
+	exchange.PushDepth("BTCUSDT", true, [][2]string{{"100", "1"}}, [][2]string{{"101", "2"}})
+	first := client.expect("orderbook")
+	exchange.PushDepth("BTCUSDT", false, [][2]string{{"99", "3"}}, nil)
+	merged := client.expect("orderbook")
+
+	if first.Mode != depthModePartial || merged.Mode != depthModePartial {
+		t.Fatalf("orderbook modes = %q, %q, want %q", first.Mode, merged.Mode, depthModePartial)
+	}
+	var book model.OrderBook
+	merged.decode(t, &book)
+	if len(book.Bids) != 2 || book.BestBid.Price != "100" || len(book.Asks) != 1 {
+		t.Fatalf("orderbook = %+v, want the update merged into the snapshot", book)
+	}
+}
+
+func TestTradingBTCCTrades(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "trades", Symbol: "BTCUSDT"})
+
+	waitFor(t, "the deals subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "deals.BTCUSDT")
+	})
+	exchange.PushDeals("BTCUSDT", []map[string]any{{"id": 1, "price": "100", "amount": "0.5", "type": "buy"}})
+
+	frame := client.expect("trades")
+	var deals []map[string]any
+	frame.decode(t, &deals)
+	if frame.Symbol != "BTCUSDT" || len(deals) != 1 || deals[0]["price"] != "100" {
+		t.Fatalf("trades frame = %+v %v, want the pushed deal", frame, deals)
+	}
+}
+
+func TestTradingBTCCMarketState(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "state", Symbol: "BTCUSDT"})
+
+	waitFor(t, "the state subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "state")
+	})
+	exchange.PushState(map[string]map[string]any{"BTCUSDT": {"last": "100", "volume": "12"}})
+
+	if frame := client.expect("state"); frame.Symbol != "BTCUSDT" {
+		t.Fatalf("state frame = %+v, want BTCUSDT", frame)
+	}
+}
+
+func TestTradingSubscriptionsStayPerClient(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	klines := h.dial(t)
+	klines.connect(testBinanceKeyID)
+	klines.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+	trades := h.dial(t)
+	trades.connect(testBinanceKeyID)
+	trades.subscribe(model.TradingWebSocketMessage{Type: "trades", Symbol: "BTCUSDT"})
+
+	// Both share one combined stream connection carrying both streams
+	waitFor(t, "the combined stream", func(ctx context.Context) error {
+		if err := exchange.WaitStream(ctx, "btcusdt@trade"); err != nil {
+			return err
+		}
+		return exchange.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	exchange.PushTrade("BTCUSDT", 7, "100.5", "0.1")
+	exchange.PushKline("BTCUSDT", "1m", exchangetest.Kline{OpenTime: 1, CloseTime: 2, Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"})
+
+	if frame := trades.expect("trades"); frame.Symbol != "BTCUSDT" {
+		t.Fatalf("trades frame = %+v, want BTCUSDT", frame)
+	}
+	klines.expect("kline")
+	klines.expectNone("trades", 200*time.Millisecond)
+}
+
+func TestTradingUnsubscribeKeepsSharedKlineStream(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	kline := model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"}
+	leaving, staying := h.dial(t), h.dial(t)
+	for _, client := range []*tradingClient{leaving, staying} {
+		client.connect(testBinanceKeyID)
+		client.subscribe(kline)
+	}
+
+	leaving.unsubscribe(kline)
+	if streams, _ := h.publicStreams(testBinanceKeyID); !streams["btcusdt@kline_1m"] {
+		t.Fatalf("public streams = %v, want the kline stream kept for the other client", streams)
+	}
+	waitFor(t, "the kline stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	exchange.PushKline("BTCUSDT", "1m", exchangetest.Kline{OpenTime: 1, CloseTime: 2, Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"})
+	staying.expect("kline")
+
+	staying.unsubscribe(kline)
+	if streams, _ := h.publicStreams(testBinanceKeyID); len(streams) != 0 {
+		t.Fatalf("public streams = %v, want none after the last unsubscribe", streams)
+	}
+}
+
+func TestTradingUnsubscribeKeepsSharedBook(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	exchange.SetDepthSnapshot("BTCUSDT", exchangetest.DepthSnapshot{
+		LastUpdateID: 100,
+		Bids:         [][2]string{{"100.0", "1"}},
+		Asks:         [][2]string{{"101.0", "1"}},
+	})
+	book := model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeBook}
+	diff := model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeDiff}
+
+	leaving, bookClient, diffClient := h.dial(t), h.dial(t), h.dial(t)
+	for _, client := range []*tradingClient{leaving, bookClient, diffClient} {
+		client.connect(testBinanceKeyID)
+	}
+	leaving.subscribe(book)
+	bookClient.subscribe(book)
+	diffClient.subscribe(diff)
+	h.waitBookSynced(t, testBinanceKeyID, "BTCUSDT")
+
+	// Another book subscriber remains, so the book stays in sync
+	leaving.unsubscribe(book)
+	if _, books := h.publicStreams(testBinanceKeyID); !books["BTCUSDT"] {
+		t.Fatal("maintained book dropped while another client still uses it")
+	}
+	exchange.PushDepth("BTCUSDT", 95, 105, nil, [][2]string{{"102.0", "4"}})
+	if frame := bookClient.expect("orderbook"); frame.Mode != depthModePartial {
+		t.Fatalf("book frame mode = %q, want %q", frame.Mode, depthModePartial)
+	}
+	diffClient.expect("orderbook")
+
+	// The last book subscriber leaves; the diff subscriber keeps the stream
+	bookClient.unsubscribe(book)
+	streams, books := h.publicStreams(testBinanceKeyID)
+	if books["BTCUSDT"] || !streams["btcusdt@depth@100ms"] {
+		t.Fatalf("streams = %v, books = %v, want the diff stream without a book", streams, books)
+	}
+	exchange.PushDepth("BTCUSDT", 106, 106, [][2]string{{"99.0", "2"}}, nil)
+	if frame := diffClient.expect("orderbook"); frame.Mode != depthModeDiff {
+		t.Fatalf("diff frame mode = %q, want %q", frame.Mode, depthModeDiff)
+	}
+}
+
+func TestTradingRebuildReseedsMaintainedBook(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	exchange.SetDepthSnapshot("BTCUSDT", exchangetest.DepthSnapshot{
+		LastUpdateID: 100,
+		Bids:         [][2]string{{"100.0", "1"}},
+		Asks:         [][2]string{{"101.0", "1"}},
+	})
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "orderbook", Symbol: "BTCUSDT", Mode: depthModeBook})
+	h.waitBookSynced(t, testBinanceKeyID, "BTCUSDT")
+
+	// After the rebuild the exchange restarts from a later snapshot
+	exchange.SetDepthSnapshot("BTCUSDT", exchangetest.DepthSnapshot{
+		LastUpdateID: 200,
+		Bids:         [][2]string{{"110.0", "1"}},
+		Asks:         [][2]string{{"111.0", "1"}},
+	})
+	old := h.exchangeConn(testBinanceKeyID)
+	h.manager.RebuildExchangeConn(testBinanceKeyID)
+	client.expect("reconnected")
+	if h.exchangeConn(testBinanceKeyID) == old {
+		t.Fatal("exchange connection was not replaced")
+	}
+	h.waitBookSynced(t, testBinanceKeyID, "BTCUSDT")
+
+	exchange.PushDepth("BTCUSDT", 195, 205, nil, [][2]string{{"112.0", "2"}})
+	var book model.OrderBook
+	client.expect("orderbook").decode(t, &book)
+	if book.LastUpdateID != 205 || book.BestBid == nil || book.BestBid.Price != "110.0" || len(book.Asks) != 2 {
+		t.Fatalf("book after rebuild = %+v, want the new snapshot with the diff applied", book)
+	}
+}
+
+func TestOrderBookMode(t *testing.T) {
+	tests := []struct {
+		mode, bookMode string
+		want           string
+		wantErr        bool
+	}{
+		{want: depthModePartial},
+		{mode: depthModePartial, want: depthModePartial},
+		{mode: depthModeDiff, want: depthModeDiff},
+		{mode: depthModeBook, want: depthModeBook},
+		// bookMode is a deprecated alias of mode
+		{bookMode: depthModeDiff, want: depthModeDiff},
+		{mode: depthModeDiff, bookMode: depthModeDiff, want: depthModeDiff},
+		{mode: depthModePartial, bookMode: depthModeDiff, wantErr: true},
+		{bookMode: depthModeBook, want: depthModeBook},
+		{mode: depthModeBook, bookMode: depthModeBook, want: depthModeBook},
+		{mode: depthModePartial, bookMode: depthModeBook, want: depthModeBook},
+		{mode: depthModeDiff, bookMode: depthModeBook, wantErr: true},
+		{mode: depthModeBook, bookMode: depthModeDiff, wantErr: true},
+		{mode: "full", wantErr: true},
+		{bookMode: "snapshot", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := orderBookMode(tt.mode, tt.bookMode)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("orderBookMode(%q, %q) = %q, %v; want %q, error %v", tt.mode, tt.bookMode, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTradingRejectsConflictingBookMode(t *testing.T) {
+	h, _ := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.send(model.TradingWebSocketMessage{Action: "subscribe", Type: "orderbook", Symbol: "BTCUSDT", BookMode: depthModeBook, Mode: depthModeDiff})
+
+	frame, err := client.read(testFrameTimeout)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if frame.Type != "error" || !strings.Contains(frame.Error, "conflicts") {
+		t.Fatalf("frame = %+v, want a conflict error", frame)
+	}
+	if streams, books := h.publicStreams(testBinanceKeyID); len(streams) != 0 || len(books) != 0 {
+		t.Fatalf("streams = %v, books = %v, want nothing subscribed", streams, books)
+	}
+	client.send(model.TradingWebSocketMessage{Action: "list_subscriptions"})
+	var snapshot struct {
+		Subscriptions []model.TradingSubscription `json:"subscriptions"`
+	}
+	client.expect("subscriptions").decode(t, &snapshot)
+	if len(snapshot.Subscriptions) != 0 {
+		t.Fatalf("subscriptions = %+v, want none", snapshot.Subscriptions)
+	}
+}
+
+func TestTradingPublicReconnectsDoNotLeakReaders(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	running := goleak.IgnoreCurrent()
+
+	// Every change of the public streams replaces the public socket
+	for _, symbol := range []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT", "XRPUSDT"} {
+		kline := model.TradingWebSocketMessage{Type: "kline", Symbol: symbol, Interval: "1m"}
+		client.subscribe(kline)
+		waitFor(t, "the "+symbol+" kline stream", func(ctx context.Context) error {
+			return exchange.WaitStream(ctx, strings.ToLower(symbol)+"@kline_1m")
+		})
+		client.unsubscribe(kline)
+	}
+	client.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+
+	// shutdown waits for the readers, so none of the replaced sockets' may survive Close
+	h.manager.Close()
+	goleak.VerifyNone(t, running)
+}
+
+// The auth response and new private subscriptions touch btccAuthed and
+// PrivateSubs from different goroutines; run with -race to check both are
+// only accessed under ec.mu
+func TestTradingBTCCPrivateSubscribeRacesAuth(t *testing.T) {
+	h, exchange := newBTCCHarness(t)
+	client := h.dial(t)
+	client.connect(testBTCCKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "balance"})
+	waitFor(t, "the asset subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "asset")
+	})
+
+	ec := h.exchangeConn(testBTCCKeyID)
+	ec.mu.RLock()
+	authResponse := fmt.Sprintf(`{"id":%d,"error":null,"result":{"status":"success"}}`, ec.btccAuthID)
+	ec.mu.RUnlock()
+
+	// Replay the auth response on a socket that looks unauthenticated while
+	// another goroutine subscribes, letting either one run first
+	for i := 0; i < 50; i++ {
+		ec.mu.Lock()
+		ec.btccAuthed = false
+		ec.mu.Unlock()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			h.manager.handleBTCCPrivateMessage(ec, []byte(authResponse))
+		}()
+		go func() {
+			defer wg.Done()
+			h.manager.subscribeOrders(nil, ec, fmt.Sprintf("SYM%dUSDT", i))
+		}()
+		wg.Wait()
+	}
+
+	waitFor(t, "the order subscription", func(ctx context.Context) error {
+		return exchange.WaitSubscribed(ctx, "order")
+	})
+}
+
+// flateFrame compresses payload the way BTCC sends binary frames
+func flateFrame(tb testing.TB, payload []byte) []byte {
+	tb.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		tb.Fatalf("flate.NewWriter() error = %v", err)
+	}
+	if _, err := w.Write(payload); err != nil {
+		tb.Fatalf("flate write error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatalf("flate close error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+var btccDepthPayload = bytes.Repeat([]byte(`{"method":"depth.update","params":[true,{"bids":[["100.00","1.5"]],"asks":[["101.00","2.5"]]},"BTCUSDT"]}`), 40)
+
+func TestDecompressFlate(t *testing.T) {
+	m := &TradingStreamManager{}
+	frame := flateFrame(t, btccDepthPayload)
+
+	// Twice, so the second call runs on the pooled reader and buffer
+	for i := 0; i < 2; i++ {
+		got, err := m.decompressFlate(frame)
+		if err != nil {
+			t.Fatalf("decompressFlate() error = %v", err)
+		}
+		if !bytes.Equal(got, btccDepthPayload) {
+			t.Fatalf("decompressFlate() = %d bytes, want the %d byte payload", len(got), len(btccDepthPayload))
+		}
+	}
+	if _, err := m.decompressFlate([]byte("not flate")); err == nil {
+		t.Fatal("decompressFlate() of garbage succeeded")
+	}
+}
+
+// BenchmarkDecompressFlate compares the pooled decompressFlate with a fresh
+// reader and growing buffer per frame; run with -benchmem to see the
+// allocations the pools save
+func BenchmarkDecompressFlate(b *testing.B) {
+	frame := flateFrame(b, btccDepthPayload)
+
+	b.Run("pooled", func(b *testing.B) {
+		m := &TradingStreamManager{}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.decompressFlate(frame); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader := flate.NewReader(bytes.NewReader(frame))
+			if _, err := io.ReadAll(reader); err != nil {
+				b.Fatal(err)
+			}
+			reader.Close()
+		}
+	})
+}
+
+func TestTradingDialsConfiguredNetworkEndpoints(t *testing.T) {
+	exchange := exchangetest.NewBinance("binance-api-key")
+	t.Cleanup(exchange.Close)
+	key := testAPIKey(testBinanceKeyID, model.PlatformBinance, "binance-api-key", "binance-secret")
+	key.IsTestnet = true
+	// Only the testnet endpoints point at the fake; production keeps the defaults
+	h := newTradingHarness(t,
+		map[model.ExchangeNetwork]model.ExchangeURLs{
+			{Platform: model.PlatformBinance, IsTestnet: true}: {WSURL: exchange.WSURL(), RESTURL: exchange.RESTURL()},
+		},
+		key,
+	)
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+	client.subscribe(model.TradingWebSocketMessage{Type: "balance"})
+
+	waitFor(t, "the public stream on the configured websocket url", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	// The user stream needs a listen key from the configured REST url first
+	waitFor(t, "the user data stream", func(ctx context.Context) error {
+		return exchange.WaitUserStream(ctx)
+	})
+}
+
+// Several streams share one combined connection; the envelope and the kline
+// interval decide which clients a frame goes to
+func TestTradingBinanceCombinedStreamRouting(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	minute := h.dial(t)
+	minute.connect(testBinanceKeyID)
+	minute.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"})
+	fiveMinutes := h.dial(t)
+	fiveMinutes.connect(testBinanceKeyID)
+	fiveMinutes.subscribe(model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "5m"})
+
+	waitFor(t, "both kline streams on one connection", func(ctx context.Context) error {
+		if err := exchange.WaitStream(ctx, "btcusdt@kline_1m"); err != nil {
+			return err
+		}
+		return exchange.WaitStream(ctx, "btcusdt@kline_5m")
+	})
+	if n := exchange.PushKline("BTCUSDT", "5m", exchangetest.Kline{
+		OpenTime: 1700000100000, CloseTime: 1700000399999,
+		Open: "100", High: "110", Low: "90", Close: "105", Volume: "12",
+	}); n != 1 {
+		t.Fatalf("kline pushed to %d exchange connections, want one combined connection", n)
+	}
+
+	frame := fiveMinutes.expect("kline")
+	if frame.Symbol != "BTCUSDT" || frame.Interval != "5m" {
+		t.Fatalf("kline frame = %+v, want BTCUSDT 5m", frame)
+	}
+	minute.expectNone("kline", 200*time.Millisecond)
+}
+
+func TestTradingIdleSweep(t *testing.T) {
+	h, exchange := newBinanceHarness(t)
+	h.manager.exchangeIdleTimeout = time.Minute
+	kline := model.TradingWebSocketMessage{Type: "kline", Symbol: "BTCUSDT", Interval: "1m"}
+
+	client := h.dial(t)
+	client.connect(testBinanceKeyID)
+	client.subscribe(kline)
+	waitFor(t, "the kline stream", func(ctx context.Context) error {
+		return exchange.WaitStream(ctx, "btcusdt@kline_1m")
+	})
+	ec := h.exchangeConn(testBinanceKeyID)
+
+	start := time.Now()
+	h.manager.sweepIdleExchangeConns(start.Add(time.Hour))
+	if h.exchangeConn(testBinanceKeyID) != ec {
+		t.Fatal("a connection with subscriptions was swept")
+	}
+
+	client.unsubscribe(kline)
+	h.manager.sweepIdleExchangeConns(start)
+	h.manager.sweepIdleExchangeConns(start.Add(59 * time.Second))
+	if h.exchangeConn(testBinanceKeyID) != ec {
+		t.Fatal("the connection was swept before the idle timeout")
+	}
+
+	// A subscribe landing before the next sweep keeps the connection and
+	// restarts the idle clock
+	client.subscribe(kline)
+	h.manager.sweepIdleExchangeConns(start.Add(2 * time.Minute))
+	if h.exchangeConn(testBinanceKeyID) != ec {
+		t.Fatal("the connection was swept right after a subscribe")
+	}
+	client.unsubscribe(kline)
+	h.manager.sweepIdleExchangeConns(start.Add(3 * time.Minute))
+	if h.exchangeConn(testBinanceKeyID) != ec {
+		t.Fatal("the idle clock was not restarted by the subscribe")
+	}
+
+	h.manager.sweepIdleExchangeConns(start.Add(4 * time.Minute))
+	if h.exchangeConn(testBinanceKeyID) != nil {
+		t.Fatal("the connection outlived the idle timeout")
+	}
+	if atomic.LoadInt32(&ec.closed) != 1 {
+		t.Fatal("the swept connection was not shut down")
+	}
+	frame := client.expect("disconnected")
+	var data map[string]any
+	frame.decode(t, &data)
+	if data["apiKeyId"] != testBinanceKeyID || data["reason"] != "idle" {
+		t.Fatalf("disconnected = %v, want %s gone idle", data, testBinanceKeyID)
+	}
+
+	// The next subscribe brings up a fresh connection
+	client.subscribe(kline)
+	if next := h.exchangeConn(testBinanceKeyID); next == nil || next == ec {
+		t.Fatalf("exchange connection after the sweep = %p, want a new one", next)
+	}
+}
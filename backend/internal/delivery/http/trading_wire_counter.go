@@ -0,0 +1,47 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// wireCountingWriter hands the WebSocket upgrader a connection that counts
+// the bytes written to the socket, which after permessage-deflate is what a
+// client actually receives
+type wireCountingWriter struct {
+	http.ResponseWriter
+	counters []*atomic.Int64
+}
+
+func (w *wireCountingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &wireCountingConn{Conn: conn, counters: w.counters}, brw, nil
+}
+
+func (w *wireCountingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wireCountingConn adds every byte written to each of its counters
+type wireCountingConn struct {
+	net.Conn
+	counters []*atomic.Int64
+}
+
+func (c *wireCountingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	for _, counter := range c.counters {
+		counter.Add(int64(n))
+	}
+	return n, err
+}
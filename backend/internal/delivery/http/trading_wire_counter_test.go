@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveCountedMessage upgrades through a wireCountingWriter, writes payload
+// once and returns the bytes counted on the wire once the client has read it
+func serveCountedMessage(t *testing.T, payload []byte, compress bool) int64 {
+	t.Helper()
+
+	var wire atomic.Int64
+	written := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := tradingUpgrader.Upgrade(&wireCountingWriter{ResponseWriter: w, counters: []*atomic.Int64{&wire}}, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		defer conn.Close()
+		if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+			t.Errorf("WriteMessage() error = %v", err)
+		}
+		close(written)
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{EnableCompression: compress}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer conn.Close()
+
+	_, got, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("received %d bytes, want the %d byte payload", len(got), len(payload))
+	}
+	<-written
+	return wire.Load()
+}
+
+func TestWireCountingWriterMeasuresCompressedBytes(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"price":"100.00","qty":"1.5"},`), 1000)
+
+	plain := serveCountedMessage(t, payload, false)
+	if plain < int64(len(payload)) {
+		t.Fatalf("uncompressed wire bytes = %d, want at least the %d byte payload", plain, len(payload))
+	}
+
+	compressed := serveCountedMessage(t, payload, true)
+	if compressed == 0 || compressed >= int64(len(payload))/2 {
+		t.Fatalf("compressed wire bytes = %d, want well below the %d byte payload", compressed, len(payload))
+	}
+}
@@ -2,13 +2,14 @@ package http
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/yanun0323/logs"
 
 	"control_page/internal/model"
 )
@@ -17,6 +18,11 @@ const (
 	clientPingIntervalKline = 25 * time.Second
 	clientPongWaitKline     = 60 * time.Second
 	clientWriteWaitKline    = 10 * time.Second
+
+	// binanceStreamsPerShard caps the streams carried by one upstream connection.
+	// Binance allows 1024 per connection; staying well below also keeps the
+	// stream-path URL short.
+	binanceStreamsPerShard = 200
 )
 
 var upgrader = websocket.Upgrader{
@@ -31,17 +37,29 @@ var upgrader = websocket.Upgrader{
 type BinanceStreamManager struct {
 	binanceURL string
 	clients    map[*websocket.Conn]map[string]bool // client -> subscriptions
-	binanceWS  *websocket.Conn
+	writes     map[*websocket.Conn]*sync.Mutex     // serializes data frames per client
+	since      map[*websocket.Conn]time.Time       // when each client connected
+	shards     []*binanceShard                     // guarded by subMu
 	mu         sync.RWMutex
 	subMu      sync.Mutex
 	done       chan struct{}
 	closed     bool
+	limiter    *clientLimiter
+}
+
+// binanceShard is one upstream Binance connection carrying a subset of the streams
+type binanceShard struct {
+	streams map[string]bool
+	ws      *websocket.Conn // nil when the last dial failed
 }
 
-func NewBinanceStreamManager(binanceURL string) *BinanceStreamManager {
+func NewBinanceStreamManager(binanceURL string, limiter *clientLimiter) *BinanceStreamManager {
 	return &BinanceStreamManager{
 		binanceURL: binanceURL,
+		limiter:    limiter,
 		clients:    make(map[*websocket.Conn]map[string]bool),
+		writes:     make(map[*websocket.Conn]*sync.Mutex),
+		since:      make(map[*websocket.Conn]time.Time),
 		done:       make(chan struct{}),
 	}
 }
@@ -58,9 +76,16 @@ func (m *BinanceStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if !m.limiter.acquire() {
+		logs.Warnf("websocket client limit (%d) reached, rejecting kline client %s", m.limiter.limit(), r.RemoteAddr)
+		http.Error(w, "too many clients", http.StatusServiceUnavailable)
+		return
+	}
+	defer m.limiter.release()
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("websocket upgrade error: %v", err)
+		logs.Errorf("websocket upgrade error: %v", err)
 		return
 	}
 
@@ -81,6 +106,8 @@ func (m *BinanceStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		return
 	}
 	m.clients[conn] = make(map[string]bool)
+	m.writes[conn] = &sync.Mutex{}
+	m.since[conn] = time.Now()
 	m.mu.Unlock()
 
 	defer func() {
@@ -93,7 +120,7 @@ func (m *BinanceStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("websocket error: %v", err)
+				logs.Warnf("websocket error: %v", err)
 			}
 			break
 		}
@@ -107,7 +134,7 @@ func (m *BinanceStreamManager) HandleWebSocket(w http.ResponseWriter, r *http.Re
 
 		var msg model.WebSocketMessage
 		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("invalid message format: %v", err)
+			logs.Warnf("invalid message format: %v", err)
 			continue
 		}
 
@@ -167,11 +194,16 @@ func (m *BinanceStreamManager) handleUnsubscribe(conn *websocket.Conn, sub model
 func (m *BinanceStreamManager) removeClient(conn *websocket.Conn) {
 	m.mu.Lock()
 	delete(m.clients, conn)
+	delete(m.writes, conn)
+	delete(m.since, conn)
 	m.mu.Unlock()
 
 	m.updateBinanceSubscriptions()
 }
 
+// updateBinanceSubscriptions spreads the clients' streams over upstream shards
+// of at most binanceStreamsPerShard streams. Shards keep their streams across
+// updates so a subscription change only reconnects the shards it touches.
 func (m *BinanceStreamManager) updateBinanceSubscriptions() {
 	m.subMu.Lock()
 	defer m.subMu.Unlock()
@@ -179,57 +211,108 @@ func (m *BinanceStreamManager) updateBinanceSubscriptions() {
 	// Collect all unique subscriptions
 	allSubs := make(map[string]bool)
 	m.mu.RLock()
+	closed := m.closed
 	for _, subs := range m.clients {
 		for sub := range subs {
 			allSubs[sub] = true
 		}
 	}
 	m.mu.RUnlock()
+	if closed {
+		return
+	}
 
-	if len(allSubs) == 0 {
-		// No subscriptions, close Binance connection
-		if m.binanceWS != nil {
-			m.binanceWS.Close()
-			m.binanceWS = nil
+	// Drop streams nobody wants any more
+	changed := make(map[*binanceShard]bool)
+	assigned := make(map[string]bool, len(allSubs))
+	for _, shard := range m.shards {
+		for stream := range shard.streams {
+			if !allSubs[stream] {
+				delete(shard.streams, stream)
+				changed[shard] = true
+				continue
+			}
+			assigned[stream] = true
 		}
-		return
 	}
 
-	// Build combined stream URL
-	streams := make([]string, 0, len(allSubs))
+	// Place new streams into shards with room, then into new shards
+	added := make([]string, 0, len(allSubs))
 	for stream := range allSubs {
-		streams = append(streams, stream)
+		if !assigned[stream] {
+			added = append(added, stream)
+		}
 	}
+	sort.Strings(added)
+	for _, shard := range m.shards {
+		for len(added) > 0 && len(shard.streams) < binanceStreamsPerShard {
+			shard.streams[added[0]] = true
+			added = added[1:]
+			changed[shard] = true
+		}
+	}
+	for len(added) > 0 {
+		n := min(len(added), binanceStreamsPerShard)
+		shard := &binanceShard{streams: make(map[string]bool, n)}
+		for _, stream := range added[:n] {
+			shard.streams[stream] = true
+		}
+		added = added[n:]
+		m.shards = append(m.shards, shard)
+		changed[shard] = true
+	}
+
+	// Reconnect changed shards (and retry failed ones); close empty ones
+	kept := m.shards[:0]
+	for _, shard := range m.shards {
+		if len(shard.streams) == 0 {
+			if shard.ws != nil {
+				shard.ws.Close()
+			}
+			continue
+		}
+		kept = append(kept, shard)
+		if changed[shard] || shard.ws == nil {
+			m.connectShard(shard)
+		}
+	}
+	for i := len(kept); i < len(m.shards); i++ {
+		m.shards[i] = nil
+	}
+	m.shards = kept
+}
 
-	// Close existing connection
-	if m.binanceWS != nil {
-		m.binanceWS.Close()
+// connectShard replaces the shard's upstream connection with one carrying its current streams
+func (m *BinanceStreamManager) connectShard(shard *binanceShard) {
+	if shard.ws != nil {
+		shard.ws.Close()
+		shard.ws = nil
 	}
 
-	// Create new connection with combined streams
+	streams := make([]string, 0, len(shard.streams))
+	for stream := range shard.streams {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+
 	url := m.binanceURL + "/" + strings.Join(streams, "/")
 	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
-		log.Printf("binance connection error: %v", err)
+		logs.Errorf("binance connection error (%d streams): %v", len(streams), err)
 		return
 	}
-	m.binanceWS = ws
+	shard.ws = ws
 
-	// Start reading from Binance
-	go m.readBinanceMessages()
+	// Every shard feeds the common broadcast
+	go m.readBinanceMessages(ws)
 }
 
-func (m *BinanceStreamManager) readBinanceMessages() {
-	ws := m.binanceWS
-	if ws == nil {
-		return
-	}
-
+func (m *BinanceStreamManager) readBinanceMessages(ws *websocket.Conn) {
 	for {
 		_, message, err := ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("binance read error: %v", err)
+				logs.Warnf("binance read error: %v", err)
 			}
 			return
 		}
@@ -239,14 +322,39 @@ func (m *BinanceStreamManager) readBinanceMessages() {
 	}
 }
 
+// broadcast snapshots the clients under the read lock and writes outside it,
+// so a slow client never holds up subscription changes
 func (m *BinanceStreamManager) broadcast(message []byte) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	type target struct {
+		conn    *websocket.Conn
+		writeMu *sync.Mutex
+	}
 
+	m.mu.RLock()
+	targets := make([]target, 0, len(m.clients))
 	for client := range m.clients {
-		if err := client.WriteMessage(websocket.TextMessage, message); err != nil {
-			log.Printf("broadcast error: %v", err)
-		}
+		targets = append(targets, target{conn: client, writeMu: m.writes[client]})
+	}
+	m.mu.RUnlock()
+
+	for _, t := range targets {
+		m.writeToClient(t.conn, t.writeMu, message)
+	}
+}
+
+// writeToClient writes one frame with a deadline so a stalled client costs at
+// most clientWriteWaitKline per broadcast
+func (m *BinanceStreamManager) writeToClient(conn *websocket.Conn, writeMu *sync.Mutex, message []byte) {
+	if writeMu == nil {
+		return
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	_ = conn.SetWriteDeadline(time.Now().Add(clientWriteWaitKline))
+	if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+		logs.Warnf("broadcast to client %s error: %v", conn.RemoteAddr(), err)
 	}
 }
 
@@ -258,30 +366,64 @@ func (m *BinanceStreamManager) Close() {
 	}
 	m.closed = true
 
-	// Collect connections to close
-	binanceWS := m.binanceWS
-	m.binanceWS = nil
-
 	clients := make([]*websocket.Conn, 0, len(m.clients))
 	for client := range m.clients {
 		clients = append(clients, client)
 	}
 	m.clients = make(map[*websocket.Conn]map[string]bool)
+	m.writes = make(map[*websocket.Conn]*sync.Mutex)
+	m.since = make(map[*websocket.Conn]time.Time)
 	m.mu.Unlock()
 
 	close(m.done)
 
-	// Close binance connection outside of lock
-	if binanceWS != nil {
-		binanceWS.Close()
+	// Close the upstream shards outside of the client lock
+	m.subMu.Lock()
+	for _, shard := range m.shards {
+		if shard.ws != nil {
+			shard.ws.Close()
+		}
 	}
+	m.shards = nil
+	m.subMu.Unlock()
 
 	// Close all client connections outside of lock
+	deadline := time.Now().Add(clientWriteWaitKline)
 	for _, client := range clients {
+		_ = client.WriteControl(websocket.CloseMessage, formatCloseMessage(CloseCodeShutdown, CloseReasonShutdown), deadline)
 		client.Close()
 	}
 
-	log.Println("BinanceStreamManager: closed")
+	logs.Infof("BinanceStreamManager: closed")
+}
+
+// Sessions returns a read-only snapshot of the connected kline clients
+func (m *BinanceStreamManager) Sessions() []model.KlineSession {
+	now := time.Now()
+
+	m.mu.RLock()
+	sessions := make([]model.KlineSession, 0, len(m.clients))
+	for conn, subs := range m.clients {
+		streams := make([]string, 0, len(subs))
+		for stream := range subs {
+			streams = append(streams, stream)
+		}
+		sort.Strings(streams)
+
+		connectedAt := m.since[conn]
+		sessions = append(sessions, model.KlineSession{
+			RemoteAddr:  conn.RemoteAddr().String(),
+			ConnectedAt: connectedAt,
+			DurationMs:  now.Sub(connectedAt).Milliseconds(),
+			Streams:     streams,
+		})
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ConnectedAt.Before(sessions[j].ConnectedAt)
+	})
+	return sessions
 }
 
 func formatStreamName(symbol, interval string) string {
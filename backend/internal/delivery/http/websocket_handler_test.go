@@ -0,0 +1,114 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"control_page/internal/model"
+)
+
+// newKlineHarness serves a BinanceStreamManager whose upstream dials fail
+// fast, so only the client side is exercised
+func newKlineHarness(t *testing.T) (*BinanceStreamManager, string) {
+	t.Helper()
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(upstream.Close)
+
+	m := NewBinanceStreamManager("ws"+strings.TrimPrefix(upstream.URL, "http"), nil)
+	srv := httptest.NewServer(http.HandlerFunc(m.HandleWebSocket))
+	t.Cleanup(func() {
+		m.Close()
+		srv.Close()
+	})
+	return m, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// waitSessions polls the manager until ready accepts its sessions
+func waitSessions(t *testing.T, m *BinanceStreamManager, what string, ready func([]model.KlineSession) bool) {
+	t.Helper()
+	for deadline := time.Now().Add(testFrameTimeout); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if ready(m.Sessions()) {
+			return
+		}
+	}
+	t.Fatalf("waiting for %s: sessions = %+v", what, m.Sessions())
+}
+
+// A client that stops reading blocks the broadcast writing to it; clients must
+// still be able to join and change subscriptions meanwhile
+func TestKlineBroadcastStalledClientDoesNotBlockSubscriptions(t *testing.T) {
+	m, url := newKlineHarness(t)
+
+	stalled, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial stalled client: %v", err)
+	}
+	defer stalled.Close()
+	waitSessions(t, m, "the stalled client", func(s []model.KlineSession) bool { return len(s) == 1 })
+
+	// Broadcast until the stalled client's socket buffers are full
+	var sent atomic.Int64
+	done := make(chan struct{})
+	payload := bytes.Repeat([]byte("k"), 1<<20)
+	go func() {
+		defer close(done)
+		for i := 0; i < 64; i++ {
+			m.broadcast(payload)
+			sent.Add(1)
+		}
+	}()
+	for stalledFor := 0; stalledFor < 3; {
+		before := sent.Load()
+		select {
+		case <-done:
+			t.Fatalf("all %d broadcasts went through, want the stalled client to block one", sent.Load())
+		case <-time.After(100 * time.Millisecond):
+		}
+		if sent.Load() == before {
+			stalledFor++
+		} else {
+			stalledFor = 0
+		}
+	}
+
+	// A new client joins and subscribes while the broadcast is stuck
+	client, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial client: %v", err)
+	}
+	defer client.Close()
+	sub := model.WebSocketMessage{Action: "subscribe", Data: model.KlineSubscription{Symbol: "BTCUSDT", Interval: "1m"}}
+	if err := client.WriteJSON(sub); err != nil {
+		t.Fatalf("send subscribe: %v", err)
+	}
+	waitSessions(t, m, "the subscription", func(sessions []model.KlineSession) bool {
+		for _, s := range sessions {
+			if len(s.Streams) == 1 && s.Streams[0] == "btcusdt@kline_1m" {
+				return true
+			}
+		}
+		return false
+	})
+
+	select {
+	case <-done:
+		t.Fatal("the broadcast finished before the subscription, want it still blocked")
+	default:
+	}
+
+	// Dropping the stalled client fails its pending write and frees the broadcast
+	stalled.Close()
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(testFrameTimeout):
+		t.Fatal("the broadcast stayed blocked after the stalled client left")
+	}
+}
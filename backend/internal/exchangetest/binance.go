@@ -0,0 +1,273 @@
+package exchangetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Binance is a fake Binance spot exchange: the combined stream endpoint
+// (/stream?streams=), user data streams (/ws/<listenKey>) and the REST calls
+// the stream manager makes (userDataStream, account, depth).
+type Binance struct {
+	server   *httptest.Server
+	sessions *sessions
+
+	// apiKey is the only key accepted by the signed and USER_STREAM endpoints
+	apiKey string
+
+	mu         sync.Mutex
+	listenKeys map[string]bool
+	depth      map[string]DepthSnapshot
+	lastKey    int64
+}
+
+// Kline is one candle pushed on a <symbol>@kline_<interval> stream
+type Kline struct {
+	OpenTime  int64
+	CloseTime int64
+	Open      string
+	High      string
+	Low       string
+	Close     string
+	Volume    string
+	Closed    bool
+}
+
+// DepthSnapshot is the /api/v3/depth response for one symbol
+type DepthSnapshot struct {
+	LastUpdateID int64       `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+// NewBinance starts a fake Binance exchange accepting apiKey
+func NewBinance(apiKey string) *Binance {
+	b := &Binance{
+		sessions:   newSessions(),
+		apiKey:     apiKey,
+		listenKeys: make(map[string]bool),
+		depth:      make(map[string]DepthSnapshot),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", b.handleStream)
+	mux.HandleFunc("/ws/", b.handleUserStream)
+	mux.HandleFunc("/api/v3/userDataStream", b.handleUserDataStream)
+	mux.HandleFunc("/api/v3/account", b.handleAccount)
+	mux.HandleFunc("/api/v3/depth", b.handleDepth)
+	b.server = httptest.NewServer(mux)
+	return b
+}
+
+// WSURL is the value for ExchangeConfig.BaseWSURL
+func (b *Binance) WSURL() string {
+	return wsURL(b.server.URL) + "/ws"
+}
+
+// RESTURL is the value for ExchangeConfig.BaseRESTURL
+func (b *Binance) RESTURL() string {
+	return b.server.URL + "/api"
+}
+
+// Close drops every session and stops the server
+func (b *Binance) Close() {
+	b.sessions.closeAll()
+	b.server.Close()
+}
+
+// SetDepthSnapshot sets the REST order book returned for symbol
+func (b *Binance) SetDepthSnapshot(symbol string, snapshot DepthSnapshot) {
+	b.mu.Lock()
+	b.depth[strings.ToUpper(symbol)] = snapshot
+	b.mu.Unlock()
+}
+
+// WaitStream blocks until a combined stream connection carries stream, such as "btcusdt@kline_1m"
+func (b *Binance) WaitStream(ctx context.Context, stream string) error {
+	return b.sessions.wait(ctx, func(s *session) bool { return s.streams[stream] })
+}
+
+// WaitUserStream blocks until a user data stream is connected
+func (b *Binance) WaitUserStream(ctx context.Context) error {
+	return b.sessions.wait(ctx, func(s *session) bool { return s.private })
+}
+
+// PushKline sends a kline event to the connections subscribed to symbol@kline_interval
+func (b *Binance) PushKline(symbol, interval string, k Kline) int {
+	stream := strings.ToLower(symbol) + "@kline_" + interval
+	return b.push(stream, map[string]any{
+		"e": "kline",
+		"E": time.Now().UnixMilli(),
+		"s": strings.ToUpper(symbol),
+		"k": map[string]any{
+			"t": k.OpenTime,
+			"T": k.CloseTime,
+			"s": strings.ToUpper(symbol),
+			"i": interval,
+			"o": k.Open,
+			"h": k.High,
+			"l": k.Low,
+			"c": k.Close,
+			"v": k.Volume,
+			"x": k.Closed,
+		},
+	})
+}
+
+// PushDepth sends a depthUpdate diff covering update IDs first..final to the
+// connections subscribed to symbol@depth@100ms
+func (b *Binance) PushDepth(symbol string, first, final int64, bids, asks [][2]string) int {
+	stream := strings.ToLower(symbol) + "@depth@100ms"
+	return b.push(stream, map[string]any{
+		"e": "depthUpdate",
+		"E": time.Now().UnixMilli(),
+		"s": strings.ToUpper(symbol),
+		"U": first,
+		"u": final,
+		"b": bids,
+		"a": asks,
+	})
+}
+
+// PushPartialDepth sends a top-levels snapshot to the connections subscribed to
+// symbol@depth<levels>@100ms
+func (b *Binance) PushPartialDepth(symbol string, levels int, lastUpdateID int64, bids, asks [][2]string) int {
+	stream := fmt.Sprintf("%s@depth%d@100ms", strings.ToLower(symbol), levels)
+	return b.push(stream, map[string]any{
+		"lastUpdateId": lastUpdateID,
+		"bids":         bids,
+		"asks":         asks,
+	})
+}
+
+// PushTrade sends a trade event to the connections subscribed to symbol@trade
+func (b *Binance) PushTrade(symbol string, id int64, price, qty string) int {
+	stream := strings.ToLower(symbol) + "@trade"
+	return b.push(stream, map[string]any{
+		"e": "trade",
+		"E": time.Now().UnixMilli(),
+		"s": strings.ToUpper(symbol),
+		"t": id,
+		"p": price,
+		"q": qty,
+		"T": time.Now().UnixMilli(),
+	})
+}
+
+// PushUserEvent sends a raw user data event, such as an executionReport or
+// outboundAccountPosition, to every user data stream
+func (b *Binance) PushUserEvent(event map[string]any) int {
+	return b.sessions.broadcast(event, func(s *session) bool { return s.private })
+}
+
+func (b *Binance) push(stream string, data map[string]any) int {
+	envelope := map[string]any{"stream": stream, "data": data}
+	return b.sessions.broadcast(envelope, func(s *session) bool { return s.streams[stream] })
+}
+
+func (b *Binance) handleStream(w http.ResponseWriter, r *http.Request) {
+	streams := make(map[string]bool)
+	for _, stream := range strings.Split(r.URL.Query().Get("streams"), "/") {
+		if stream != "" {
+			streams[stream] = true
+		}
+	}
+	if len(streams) == 0 {
+		http.Error(w, "streams is required", http.StatusBadRequest)
+		return
+	}
+	b.serve(w, r, &session{streams: streams})
+}
+
+func (b *Binance) handleUserStream(w http.ResponseWriter, r *http.Request) {
+	listenKey := strings.TrimPrefix(r.URL.Path, "/ws/")
+	b.mu.Lock()
+	ok := b.listenKeys[listenKey]
+	b.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown listen key", http.StatusNotFound)
+		return
+	}
+	b.serve(w, r, &session{streams: map[string]bool{}, private: true})
+}
+
+// serve upgrades the request and discards client frames until it disconnects
+func (b *Binance) serve(w http.ResponseWriter, r *http.Request, sess *session) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sess.ws = ws
+	b.sessions.add(sess)
+	defer func() {
+		b.sessions.remove(sess)
+		ws.Close()
+	}()
+
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (b *Binance) handleUserDataStream(w http.ResponseWriter, r *http.Request) {
+	if !b.authorized(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		key := fmt.Sprintf("listenkey-%d", atomic.AddInt64(&b.lastKey, 1))
+		b.mu.Lock()
+		b.listenKeys[key] = true
+		b.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"listenKey": key})
+	case http.MethodPut, http.MethodDelete:
+		writeJSON(w, http.StatusOK, map[string]any{})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (b *Binance) handleAccount(w http.ResponseWriter, r *http.Request) {
+	if !b.authorized(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"canTrade": true, "balances": []any{}})
+}
+
+func (b *Binance) handleDepth(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+	b.mu.Lock()
+	snapshot, ok := b.depth[symbol]
+	b.mu.Unlock()
+	if !ok {
+		snapshot = DepthSnapshot{Bids: [][2]string{}, Asks: [][2]string{}}
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// authorized rejects requests without the configured X-MBX-APIKEY the way Binance does
+func (b *Binance) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-MBX-APIKEY") == b.apiKey {
+		return true
+	}
+	writeJSON(w, http.StatusUnauthorized, map[string]any{
+		"code": -2015,
+		"msg":  "Invalid API-key, IP, or permissions for action.",
+	})
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,248 @@
+package exchangetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"control_page/pkg/btcc"
+)
+
+// BTCC JSON-RPC error codes returned by the fake
+const (
+	BTCCErrorInvalidParams  = 2
+	BTCCErrorMethodNotFound = 3
+	BTCCErrorAuthRequired   = 6
+	BTCCErrorAuthFailed     = 7
+)
+
+// BTCC is a fake BTCC spot exchange speaking the JSON-RPC WebSocket dialect
+// (server.accessid_auth, server.ping, *.subscribe/*.unsubscribe and *.update
+// pushes) plus the kline history REST endpoint.
+type BTCC struct {
+	server   *httptest.Server
+	sessions *sessions
+
+	accessID  string
+	signature string
+
+	mu      sync.Mutex
+	history map[string][][]any
+}
+
+// NewBTCC starts a fake BTCC exchange accepting accessID with secretKey
+func NewBTCC(accessID, secretKey string) *BTCC {
+	b := &BTCC{
+		sessions:  newSessions(),
+		accessID:  accessID,
+		signature: btcc.SignAccessKey(secretKey),
+		history:   make(map[string][][]any),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", b.handleWebSocket)
+	mux.HandleFunc("/btcc_api_trade/market/kline", b.handleKlineHistory)
+	b.server = httptest.NewServer(mux)
+	return b
+}
+
+// WSURL is the value for ExchangeConfig.BaseWSURL
+func (b *BTCC) WSURL() string {
+	return wsURL(b.server.URL) + "/ws"
+}
+
+// RESTURL is the value for ExchangeConfig.BaseRESTURL
+func (b *BTCC) RESTURL() string {
+	return b.server.URL
+}
+
+// Close drops every session and stops the server
+func (b *BTCC) Close() {
+	b.sessions.closeAll()
+	b.server.Close()
+}
+
+// SetKlineHistory sets the rows returned by the kline history endpoint for
+// market; each row is [timestamp, open, close, high, low, volume, amount, market]
+func (b *BTCC) SetKlineHistory(market string, rows [][]any) {
+	b.mu.Lock()
+	b.history[market] = rows
+	b.mu.Unlock()
+}
+
+// WaitSubscribed blocks until a session holds subscription, named like the
+// stream manager's BTCC streams: "kline.BTCUSDT.60", "depth.BTCUSDT",
+// "deals.BTCUSDT", "state", "order" or "asset"
+func (b *BTCC) WaitSubscribed(ctx context.Context, subscription string) error {
+	return b.sessions.wait(ctx, func(s *session) bool { return s.streams[subscription] })
+}
+
+// WaitAuthenticated blocks until a session has passed server.accessid_auth
+func (b *BTCC) WaitAuthenticated(ctx context.Context) error {
+	return b.sessions.wait(ctx, func(s *session) bool { return s.authed })
+}
+
+// PushKline sends a kline.update row to the sessions subscribed to market at
+// interval seconds
+func (b *BTCC) PushKline(market string, interval int, timestamp int64, open, close, high, low, volume, amount string) int {
+	row := []any{timestamp, open, close, high, low, volume, amount, market}
+	return b.push(fmt.Sprintf("kline.%s.%d", market, interval), "kline.update", []any{row})
+}
+
+// PushDepth sends a depth.update to the sessions subscribed to market; full
+// marks a snapshot rather than an incremental update
+func (b *BTCC) PushDepth(market string, full bool, bids, asks [][2]string) int {
+	depth := map[string]any{
+		"bids": bids,
+		"asks": asks,
+		"time": time.Now().UnixMilli(),
+	}
+	return b.push("depth."+market, "depth.update", []any{full, depth, market})
+}
+
+// PushDeals sends a deals.update to the sessions subscribed to market
+func (b *BTCC) PushDeals(market string, deals []map[string]any) int {
+	return b.push("deals."+market, "deals.update", []any{market, deals})
+}
+
+// PushState sends a state.update carrying the given per market fields
+func (b *BTCC) PushState(states map[string]map[string]any) int {
+	return b.push("state", "state.update", []any{states})
+}
+
+// PushOrder sends an order.update with the BTCC event status (1 put, 2
+// update, 3 finish) to the authenticated sessions subscribed to orders
+func (b *BTCC) PushOrder(status int, order map[string]any) int {
+	return b.push("order", "order.update", []any{status, order})
+}
+
+// PushAsset sends an asset.update, keyed by asset with available/frozen
+// amounts, to the authenticated sessions subscribed to assets
+func (b *BTCC) PushAsset(assets map[string]map[string]string) int {
+	return b.push("asset", "asset.update", []any{assets})
+}
+
+func (b *BTCC) push(subscription, method string, params any) int {
+	notification := map[string]any{"id": nil, "method": method, "params": params}
+	return b.sessions.broadcast(notification, func(s *session) bool { return s.streams[subscription] })
+}
+
+func (b *BTCC) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	sess := &session{ws: ws, streams: make(map[string]bool)}
+	b.sessions.add(sess)
+	defer func() {
+		b.sessions.remove(sess)
+		ws.Close()
+	}()
+
+	for {
+		var req struct {
+			ID     int64             `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := ws.ReadJSON(&req); err != nil {
+			return
+		}
+
+		result, rpcErr := b.handleRequest(sess, req.Method, req.Params)
+		resp := map[string]any{"id": req.ID, "result": result, "error": nil}
+		if rpcErr != nil {
+			resp["result"] = nil
+			resp["error"] = rpcErr
+		}
+		if err := sess.writeJSON(resp); err != nil {
+			return
+		}
+	}
+}
+
+// handleRequest answers one JSON-RPC request, updating the session's state
+func (b *BTCC) handleRequest(sess *session, method string, params []json.RawMessage) (any, *btcc.Error) {
+	success := map[string]string{"status": "success"}
+
+	switch method {
+	case btcc.MethodPing:
+		return "pong", nil
+	case btcc.MethodAccessIDAuth:
+		var accessID, signature string
+		if len(params) != 2 || json.Unmarshal(params[0], &accessID) != nil || json.Unmarshal(params[1], &signature) != nil {
+			return nil, &btcc.Error{Code: BTCCErrorInvalidParams, Message: "invalid argument"}
+		}
+		if accessID != b.accessID || signature != b.signature {
+			return nil, &btcc.Error{Code: BTCCErrorAuthFailed, Message: "auth fail"}
+		}
+		b.sessions.update(func() { sess.authed = true })
+		return map[string]any{"status": "success", "flag": 1}, nil
+	}
+
+	topic, action, ok := strings.Cut(method, ".")
+	if !ok {
+		return nil, &btcc.Error{Code: BTCCErrorMethodNotFound, Message: "method not found"}
+	}
+
+	var subscription string
+	switch topic {
+	case "kline":
+		var market string
+		var interval int
+		if action == "subscribe" && (len(params) < 2 || json.Unmarshal(params[0], &market) != nil || json.Unmarshal(params[1], &interval) != nil) {
+			return nil, &btcc.Error{Code: BTCCErrorInvalidParams, Message: "invalid argument"}
+		}
+		subscription = fmt.Sprintf("kline.%s.%d", market, interval)
+	case "depth", "deals":
+		var market string
+		if action == "subscribe" && (len(params) < 1 || json.Unmarshal(params[0], &market) != nil) {
+			return nil, &btcc.Error{Code: BTCCErrorInvalidParams, Message: "invalid argument"}
+		}
+		subscription = topic + "." + market
+	case "state":
+		subscription = topic
+	case "order", "asset":
+		b.sessions.mu.Lock()
+		authed := sess.authed
+		b.sessions.mu.Unlock()
+		if !authed {
+			return nil, &btcc.Error{Code: BTCCErrorAuthRequired, Message: "require auth"}
+		}
+		subscription = topic
+	default:
+		return nil, &btcc.Error{Code: BTCCErrorMethodNotFound, Message: "method not found"}
+	}
+
+	switch action {
+	case "subscribe":
+		b.sessions.update(func() { sess.streams[subscription] = true })
+	case "unsubscribe":
+		// Like BTCC, unsubscribe drops every subscription of the topic
+		b.sessions.update(func() {
+			for stream := range sess.streams {
+				if stream == topic || strings.HasPrefix(stream, topic+".") {
+					delete(sess.streams, stream)
+				}
+			}
+		})
+	default:
+		return nil, &btcc.Error{Code: BTCCErrorMethodNotFound, Message: "method not found"}
+	}
+	return success, nil
+}
+
+func (b *BTCC) handleKlineHistory(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	rows, ok := b.history[r.URL.Query().Get("market")]
+	b.mu.Unlock()
+	if !ok {
+		rows = [][]any{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"error": nil, "result": rows})
+}
@@ -0,0 +1,135 @@
+// Package exchangetest provides in-process fake exchanges that speak enough of
+// the Binance combined-stream and BTCC JSON-RPC dialects to drive the trading
+// stream manager end to end. Point the manager at them with the URLs returned
+// by WSURL and RESTURL.
+package exchangetest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin:       func(r *http.Request) bool { return true },
+	EnableCompression: true,
+}
+
+// session is one WebSocket accepted by a fake exchange
+type session struct {
+	ws *websocket.Conn
+
+	writeMu sync.Mutex
+
+	// streams are the Binance stream names or BTCC subscriptions of the session
+	streams map[string]bool
+	private bool
+	authed  bool
+}
+
+func (s *session) writeJSON(v any) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.ws.WriteJSON(v)
+}
+
+// sessions tracks the open sessions of a fake exchange and lets callers wait
+// for a subscription to show up
+type sessions struct {
+	mu      sync.Mutex
+	all     map[*session]bool
+	changed chan struct{}
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		all:     make(map[*session]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+func (s *sessions) add(sess *session) {
+	s.mu.Lock()
+	s.all[sess] = true
+	s.notifyLocked()
+	s.mu.Unlock()
+}
+
+func (s *sessions) remove(sess *session) {
+	s.mu.Lock()
+	delete(s.all, sess)
+	s.notifyLocked()
+	s.mu.Unlock()
+}
+
+// update runs fn under the lock and wakes up waiters
+func (s *sessions) update(fn func()) {
+	s.mu.Lock()
+	fn()
+	s.notifyLocked()
+	s.mu.Unlock()
+}
+
+func (s *sessions) notifyLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// matching returns the sessions for which match reports true
+func (s *sessions) matching(match func(*session) bool) []*session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []*session
+	for sess := range s.all {
+		if match(sess) {
+			out = append(out, sess)
+		}
+	}
+	return out
+}
+
+// wait blocks until some session matches or ctx is done
+func (s *sessions) wait(ctx context.Context, match func(*session) bool) error {
+	for {
+		s.mu.Lock()
+		for sess := range s.all {
+			if match(sess) {
+				s.mu.Unlock()
+				return nil
+			}
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// broadcast writes v to every matching session and returns how many received it
+func (s *sessions) broadcast(v any, match func(*session) bool) int {
+	sent := 0
+	for _, sess := range s.matching(match) {
+		if sess.writeJSON(v) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+func (s *sessions) closeAll() {
+	for _, sess := range s.matching(func(*session) bool { return true }) {
+		sess.ws.Close()
+	}
+}
+
+// wsURL turns the http URL of an httptest server into its ws equivalent
+func wsURL(httpURL string) string {
+	return "ws" + httpURL[len("http"):]
+}
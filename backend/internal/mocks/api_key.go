@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var _ adaptor.APIKeyUseCase = (*APIKeyUseCase)(nil)
+
+// APIKeyUseCase is an adaptor.APIKeyUseCase for handler tests; each method calls the
+// matching Func field and fails with ErrUnexpectedCall when it is unset
+type APIKeyUseCase struct {
+	CreateFunc                  func(ctx context.Context, req *model.CreateAPIKeyRequest) (*model.APIKeyResponse, error)
+	GetByIDFunc                 func(ctx context.Context, id string) (*model.APIKeyResponse, error)
+	ListFunc                    func(ctx context.Context, filter model.APIKeyFilter) (*model.APIKeyListResult, error)
+	UpdateFunc                  func(ctx context.Context, id string, req *model.UpdateAPIKeyRequest) (*model.APIKeyResponse, error)
+	DeleteFunc                  func(ctx context.Context, id string) error
+	RotateFunc                  func(ctx context.Context, id string, req *model.RotateAPIKeyRequest) (*model.APIKeyResponse, error)
+	RollbackFunc                func(ctx context.Context, id string) (*model.APIKeyResponse, error)
+	GetPlatformsFunc            func() []model.Platform
+	GetPlatformCapabilitiesFunc func() []model.PlatformCapabilities
+}
+
+func (m *APIKeyUseCase) Create(ctx context.Context, req *model.CreateAPIKeyRequest) (*model.APIKeyResponse, error) {
+	if m.CreateFunc == nil {
+		return nil, unexpected("APIKeyUseCase.Create")
+	}
+	return m.CreateFunc(ctx, req)
+}
+
+func (m *APIKeyUseCase) GetByID(ctx context.Context, id string) (*model.APIKeyResponse, error) {
+	if m.GetByIDFunc == nil {
+		return nil, unexpected("APIKeyUseCase.GetByID")
+	}
+	return m.GetByIDFunc(ctx, id)
+}
+
+func (m *APIKeyUseCase) List(ctx context.Context, filter model.APIKeyFilter) (*model.APIKeyListResult, error) {
+	if m.ListFunc == nil {
+		return nil, unexpected("APIKeyUseCase.List")
+	}
+	return m.ListFunc(ctx, filter)
+}
+
+func (m *APIKeyUseCase) Update(ctx context.Context, id string, req *model.UpdateAPIKeyRequest) (*model.APIKeyResponse, error) {
+	if m.UpdateFunc == nil {
+		return nil, unexpected("APIKeyUseCase.Update")
+	}
+	return m.UpdateFunc(ctx, id, req)
+}
+
+func (m *APIKeyUseCase) Delete(ctx context.Context, id string) error {
+	if m.DeleteFunc == nil {
+		return unexpected("APIKeyUseCase.Delete")
+	}
+	return m.DeleteFunc(ctx, id)
+}
+
+func (m *APIKeyUseCase) Rotate(ctx context.Context, id string, req *model.RotateAPIKeyRequest) (*model.APIKeyResponse, error) {
+	if m.RotateFunc == nil {
+		return nil, unexpected("APIKeyUseCase.Rotate")
+	}
+	return m.RotateFunc(ctx, id, req)
+}
+
+func (m *APIKeyUseCase) Rollback(ctx context.Context, id string) (*model.APIKeyResponse, error) {
+	if m.RollbackFunc == nil {
+		return nil, unexpected("APIKeyUseCase.Rollback")
+	}
+	return m.RollbackFunc(ctx, id)
+}
+
+func (m *APIKeyUseCase) GetPlatforms() []model.Platform {
+	if m.GetPlatformsFunc == nil {
+		return nil
+	}
+	return m.GetPlatformsFunc()
+}
+
+func (m *APIKeyUseCase) GetPlatformCapabilities() []model.PlatformCapabilities {
+	if m.GetPlatformCapabilitiesFunc == nil {
+		return nil
+	}
+	return m.GetPlatformCapabilitiesFunc()
+}
@@ -0,0 +1,128 @@
+package mocks
+
+import (
+	"context"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+var _ adaptor.AuthUseCase = (*AuthUseCase)(nil)
+
+// AuthUseCase is an adaptor.AuthUseCase for handler tests; each method calls the
+// matching Func field and fails with ErrUnexpectedCall when it is unset
+type AuthUseCase struct {
+	RegisterFunc            func(ctx context.Context, username, password string) (*model.RegisterResult, error)
+	ActivateAccountFunc     func(ctx context.Context, userID string, code string) error
+	LoginFunc               func(ctx context.Context, username, password string, client model.ClientInfo) (*model.LoginResult, error)
+	VerifyTOTPFunc          func(ctx context.Context, userID string, code string, rememberDevice bool, client model.ClientInfo) (*model.LoginResult, error)
+	ValidateTokenFunc       func(ctx context.Context, token string) (*model.UserWithRoles, error)
+	HasPermissionFunc       func(ctx context.Context, userID string, permission enum.Permission) (bool, error)
+	ChangePasswordFunc      func(ctx context.Context, userID string, currentPassword, newPassword string) error
+	SetupTOTPRebindFunc     func(ctx context.Context, userID string, password string) (*model.TOTPSetup, error)
+	ConfirmTOTPRebindFunc   func(ctx context.Context, userID string, code string) error
+	CancelTOTPRebindFunc    func(ctx context.Context, userID string) error
+	GetProfileFunc          func(ctx context.Context, userID string) (*model.Profile, error)
+	ListLoginsFunc          func(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error)
+	ListTrustedDevicesFunc  func(ctx context.Context, userID string) ([]model.TrustedDevice, error)
+	RevokeTrustedDeviceFunc func(ctx context.Context, userID, deviceID string) error
+}
+
+func (m *AuthUseCase) Register(ctx context.Context, username, password string) (*model.RegisterResult, error) {
+	if m.RegisterFunc == nil {
+		return nil, unexpected("AuthUseCase.Register")
+	}
+	return m.RegisterFunc(ctx, username, password)
+}
+
+func (m *AuthUseCase) ActivateAccount(ctx context.Context, userID string, code string) error {
+	if m.ActivateAccountFunc == nil {
+		return unexpected("AuthUseCase.ActivateAccount")
+	}
+	return m.ActivateAccountFunc(ctx, userID, code)
+}
+
+func (m *AuthUseCase) Login(ctx context.Context, username, password string, client model.ClientInfo) (*model.LoginResult, error) {
+	if m.LoginFunc == nil {
+		return nil, unexpected("AuthUseCase.Login")
+	}
+	return m.LoginFunc(ctx, username, password, client)
+}
+
+func (m *AuthUseCase) VerifyTOTP(ctx context.Context, userID string, code string, rememberDevice bool, client model.ClientInfo) (*model.LoginResult, error) {
+	if m.VerifyTOTPFunc == nil {
+		return nil, unexpected("AuthUseCase.VerifyTOTP")
+	}
+	return m.VerifyTOTPFunc(ctx, userID, code, rememberDevice, client)
+}
+
+func (m *AuthUseCase) ValidateToken(ctx context.Context, token string) (*model.UserWithRoles, error) {
+	if m.ValidateTokenFunc == nil {
+		return nil, unexpected("AuthUseCase.ValidateToken")
+	}
+	return m.ValidateTokenFunc(ctx, token)
+}
+
+func (m *AuthUseCase) HasPermission(ctx context.Context, userID string, permission enum.Permission) (bool, error) {
+	if m.HasPermissionFunc == nil {
+		return false, unexpected("AuthUseCase.HasPermission")
+	}
+	return m.HasPermissionFunc(ctx, userID, permission)
+}
+
+func (m *AuthUseCase) ChangePassword(ctx context.Context, userID string, currentPassword, newPassword string) error {
+	if m.ChangePasswordFunc == nil {
+		return unexpected("AuthUseCase.ChangePassword")
+	}
+	return m.ChangePasswordFunc(ctx, userID, currentPassword, newPassword)
+}
+
+func (m *AuthUseCase) SetupTOTPRebind(ctx context.Context, userID string, password string) (*model.TOTPSetup, error) {
+	if m.SetupTOTPRebindFunc == nil {
+		return nil, unexpected("AuthUseCase.SetupTOTPRebind")
+	}
+	return m.SetupTOTPRebindFunc(ctx, userID, password)
+}
+
+func (m *AuthUseCase) ConfirmTOTPRebind(ctx context.Context, userID string, code string) error {
+	if m.ConfirmTOTPRebindFunc == nil {
+		return unexpected("AuthUseCase.ConfirmTOTPRebind")
+	}
+	return m.ConfirmTOTPRebindFunc(ctx, userID, code)
+}
+
+func (m *AuthUseCase) CancelTOTPRebind(ctx context.Context, userID string) error {
+	if m.CancelTOTPRebindFunc == nil {
+		return unexpected("AuthUseCase.CancelTOTPRebind")
+	}
+	return m.CancelTOTPRebindFunc(ctx, userID)
+}
+
+func (m *AuthUseCase) GetProfile(ctx context.Context, userID string) (*model.Profile, error) {
+	if m.GetProfileFunc == nil {
+		return nil, unexpected("AuthUseCase.GetProfile")
+	}
+	return m.GetProfileFunc(ctx, userID)
+}
+
+func (m *AuthUseCase) ListLogins(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error) {
+	if m.ListLoginsFunc == nil {
+		return nil, unexpected("AuthUseCase.ListLogins")
+	}
+	return m.ListLoginsFunc(ctx, userID, limit)
+}
+
+func (m *AuthUseCase) ListTrustedDevices(ctx context.Context, userID string) ([]model.TrustedDevice, error) {
+	if m.ListTrustedDevicesFunc == nil {
+		return nil, unexpected("AuthUseCase.ListTrustedDevices")
+	}
+	return m.ListTrustedDevicesFunc(ctx, userID)
+}
+
+func (m *AuthUseCase) RevokeTrustedDevice(ctx context.Context, userID, deviceID string) error {
+	if m.RevokeTrustedDeviceFunc == nil {
+		return unexpected("AuthUseCase.RevokeTrustedDevice")
+	}
+	return m.RevokeTrustedDeviceFunc(ctx, userID, deviceID)
+}
@@ -0,0 +1,18 @@
+// Package mocks provides hand-written implementations of the adaptor use case
+// interfaces so HTTP handlers can be exercised with httptest without Mongo.
+// Set the Func field of each method a test expects to be called; any other
+// call returns an error wrapping ErrUnexpectedCall, which handlers surface as
+// a 500 and makes a missing expectation easy to spot.
+package mocks
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnexpectedCall is returned by a mock method whose Func field is unset
+var ErrUnexpectedCall = errors.New("unexpected call")
+
+func unexpected(method string) error {
+	return fmt.Errorf("%s: %w", method, ErrUnexpectedCall)
+}
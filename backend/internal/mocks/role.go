@@ -0,0 +1,88 @@
+package mocks
+
+import (
+	"context"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+var _ adaptor.RoleUseCase = (*RoleUseCase)(nil)
+
+// RoleUseCase is an adaptor.RoleUseCase for handler tests; each method calls the
+// matching Func field and fails with ErrUnexpectedCall when it is unset
+type RoleUseCase struct {
+	CreateRoleFunc        func(ctx context.Context, name, description string, permissions []enum.Permission, maxTokenTTL int64) (*model.RoleWithPermissions, error)
+	GetRoleFunc           func(ctx context.Context, id string) (*model.RoleWithPermissions, error)
+	ListRolesFunc         func(ctx context.Context) ([]model.RoleWithPermissions, error)
+	UpdateRoleFunc        func(ctx context.Context, id string, name, description string, maxTokenTTL *int64) (*model.RoleWithPermissions, error)
+	GetRoleUsersFunc      func(ctx context.Context, id string) ([]model.User, error)
+	DeleteRoleFunc        func(ctx context.Context, actorID, id string, force bool) ([]model.User, error)
+	SetPermissionsFunc    func(ctx context.Context, roleID string, permissions []enum.Permission) error
+	GetPermissionsFunc    func(ctx context.Context, roleID string) ([]enum.Permission, error)
+	GetAllPermissionsFunc func() []enum.Permission
+}
+
+func (m *RoleUseCase) CreateRole(ctx context.Context, name, description string, permissions []enum.Permission, maxTokenTTL int64) (*model.RoleWithPermissions, error) {
+	if m.CreateRoleFunc == nil {
+		return nil, unexpected("RoleUseCase.CreateRole")
+	}
+	return m.CreateRoleFunc(ctx, name, description, permissions, maxTokenTTL)
+}
+
+func (m *RoleUseCase) GetRole(ctx context.Context, id string) (*model.RoleWithPermissions, error) {
+	if m.GetRoleFunc == nil {
+		return nil, unexpected("RoleUseCase.GetRole")
+	}
+	return m.GetRoleFunc(ctx, id)
+}
+
+func (m *RoleUseCase) ListRoles(ctx context.Context) ([]model.RoleWithPermissions, error) {
+	if m.ListRolesFunc == nil {
+		return nil, unexpected("RoleUseCase.ListRoles")
+	}
+	return m.ListRolesFunc(ctx)
+}
+
+func (m *RoleUseCase) UpdateRole(ctx context.Context, id string, name, description string, maxTokenTTL *int64) (*model.RoleWithPermissions, error) {
+	if m.UpdateRoleFunc == nil {
+		return nil, unexpected("RoleUseCase.UpdateRole")
+	}
+	return m.UpdateRoleFunc(ctx, id, name, description, maxTokenTTL)
+}
+
+func (m *RoleUseCase) GetRoleUsers(ctx context.Context, id string) ([]model.User, error) {
+	if m.GetRoleUsersFunc == nil {
+		return nil, unexpected("RoleUseCase.GetRoleUsers")
+	}
+	return m.GetRoleUsersFunc(ctx, id)
+}
+
+func (m *RoleUseCase) DeleteRole(ctx context.Context, actorID, id string, force bool) ([]model.User, error) {
+	if m.DeleteRoleFunc == nil {
+		return nil, unexpected("RoleUseCase.DeleteRole")
+	}
+	return m.DeleteRoleFunc(ctx, actorID, id, force)
+}
+
+func (m *RoleUseCase) SetPermissions(ctx context.Context, roleID string, permissions []enum.Permission) error {
+	if m.SetPermissionsFunc == nil {
+		return unexpected("RoleUseCase.SetPermissions")
+	}
+	return m.SetPermissionsFunc(ctx, roleID, permissions)
+}
+
+func (m *RoleUseCase) GetPermissions(ctx context.Context, roleID string) ([]enum.Permission, error) {
+	if m.GetPermissionsFunc == nil {
+		return nil, unexpected("RoleUseCase.GetPermissions")
+	}
+	return m.GetPermissionsFunc(ctx, roleID)
+}
+
+func (m *RoleUseCase) GetAllPermissions() []enum.Permission {
+	if m.GetAllPermissionsFunc == nil {
+		return nil
+	}
+	return m.GetAllPermissionsFunc()
+}
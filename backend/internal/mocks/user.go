@@ -0,0 +1,127 @@
+package mocks
+
+import (
+	"context"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var _ adaptor.UserUseCase = (*UserUseCase)(nil)
+
+// UserUseCase is an adaptor.UserUseCase for handler tests; each method calls the
+// matching Func field and fails with ErrUnexpectedCall when it is unset
+type UserUseCase struct {
+	GetUserFunc          func(ctx context.Context, id string) (*model.UserWithRoles, error)
+	ListUsersFunc        func(ctx context.Context) ([]model.UserWithRoles, error)
+	CreateUserFunc       func(ctx context.Context, user *model.User, roleIDs []string) (*model.UserWithRoles, error)
+	UpdateUserFunc       func(ctx context.Context, user *model.User) error
+	DeleteUserFunc       func(ctx context.Context, id string) error
+	AssignRoleFunc       func(ctx context.Context, userID, roleID string) error
+	RemoveRoleFunc       func(ctx context.Context, userID, roleID string) error
+	ValidateRolesFunc    func(ctx context.Context, roleIDs []string) error
+	SetRolesFunc         func(ctx context.Context, userID string, roleIDs []string) error
+	ResetUserTOTPFunc    func(ctx context.Context, userID string) (*model.TOTPSetup, error)
+	ValidatePasswordFunc func(password string) error
+	UnlockUserFunc       func(ctx context.Context, actorID, userID string) (*model.UserWithRoles, error)
+	ResetPasswordFunc    func(ctx context.Context, actorID, userID string) (string, error)
+	SetIPAllowlistFunc   func(ctx context.Context, actorID, userID string, cidrs []string) (*model.UserWithRoles, error)
+}
+
+func (m *UserUseCase) GetUser(ctx context.Context, id string) (*model.UserWithRoles, error) {
+	if m.GetUserFunc == nil {
+		return nil, unexpected("UserUseCase.GetUser")
+	}
+	return m.GetUserFunc(ctx, id)
+}
+
+func (m *UserUseCase) ListUsers(ctx context.Context) ([]model.UserWithRoles, error) {
+	if m.ListUsersFunc == nil {
+		return nil, unexpected("UserUseCase.ListUsers")
+	}
+	return m.ListUsersFunc(ctx)
+}
+
+func (m *UserUseCase) CreateUser(ctx context.Context, user *model.User, roleIDs []string) (*model.UserWithRoles, error) {
+	if m.CreateUserFunc == nil {
+		return nil, unexpected("UserUseCase.CreateUser")
+	}
+	return m.CreateUserFunc(ctx, user, roleIDs)
+}
+
+func (m *UserUseCase) UpdateUser(ctx context.Context, user *model.User) error {
+	if m.UpdateUserFunc == nil {
+		return unexpected("UserUseCase.UpdateUser")
+	}
+	return m.UpdateUserFunc(ctx, user)
+}
+
+func (m *UserUseCase) DeleteUser(ctx context.Context, id string) error {
+	if m.DeleteUserFunc == nil {
+		return unexpected("UserUseCase.DeleteUser")
+	}
+	return m.DeleteUserFunc(ctx, id)
+}
+
+func (m *UserUseCase) AssignRole(ctx context.Context, userID, roleID string) error {
+	if m.AssignRoleFunc == nil {
+		return unexpected("UserUseCase.AssignRole")
+	}
+	return m.AssignRoleFunc(ctx, userID, roleID)
+}
+
+func (m *UserUseCase) RemoveRole(ctx context.Context, userID, roleID string) error {
+	if m.RemoveRoleFunc == nil {
+		return unexpected("UserUseCase.RemoveRole")
+	}
+	return m.RemoveRoleFunc(ctx, userID, roleID)
+}
+
+func (m *UserUseCase) ValidateRoles(ctx context.Context, roleIDs []string) error {
+	if m.ValidateRolesFunc == nil {
+		return unexpected("UserUseCase.ValidateRoles")
+	}
+	return m.ValidateRolesFunc(ctx, roleIDs)
+}
+
+func (m *UserUseCase) SetRoles(ctx context.Context, userID string, roleIDs []string) error {
+	if m.SetRolesFunc == nil {
+		return unexpected("UserUseCase.SetRoles")
+	}
+	return m.SetRolesFunc(ctx, userID, roleIDs)
+}
+
+func (m *UserUseCase) ResetUserTOTP(ctx context.Context, userID string) (*model.TOTPSetup, error) {
+	if m.ResetUserTOTPFunc == nil {
+		return nil, unexpected("UserUseCase.ResetUserTOTP")
+	}
+	return m.ResetUserTOTPFunc(ctx, userID)
+}
+
+func (m *UserUseCase) ValidatePassword(password string) error {
+	if m.ValidatePasswordFunc == nil {
+		return unexpected("UserUseCase.ValidatePassword")
+	}
+	return m.ValidatePasswordFunc(password)
+}
+
+func (m *UserUseCase) UnlockUser(ctx context.Context, actorID, userID string) (*model.UserWithRoles, error) {
+	if m.UnlockUserFunc == nil {
+		return nil, unexpected("UserUseCase.UnlockUser")
+	}
+	return m.UnlockUserFunc(ctx, actorID, userID)
+}
+
+func (m *UserUseCase) ResetPassword(ctx context.Context, actorID, userID string) (string, error) {
+	if m.ResetPasswordFunc == nil {
+		return "", unexpected("UserUseCase.ResetPassword")
+	}
+	return m.ResetPasswordFunc(ctx, actorID, userID)
+}
+
+func (m *UserUseCase) SetIPAllowlist(ctx context.Context, actorID, userID string, cidrs []string) (*model.UserWithRoles, error) {
+	if m.SetIPAllowlistFunc == nil {
+		return nil, unexpected("UserUseCase.SetIPAllowlist")
+	}
+	return m.SetIPAllowlistFunc(ctx, actorID, userID, cidrs)
+}
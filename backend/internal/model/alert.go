@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// AlertCondition is what an alert rule watches
+type AlertCondition string
+
+const (
+	// AlertConditionSpreadAbove fires while the best ask minus best bid exceeds Threshold
+	AlertConditionSpreadAbove AlertCondition = "spread_above"
+	// AlertConditionPriceOutside fires while the last trade price is below Lower or above Upper
+	AlertConditionPriceOutside AlertCondition = "price_outside"
+)
+
+// Alert states delivered when a rule changes state
+const (
+	AlertStateTriggered = "triggered"
+	AlertStateRecovered = "recovered"
+)
+
+// AlertRule watches live trading stream data of one API key and symbol
+type AlertRule struct {
+	ID        string         `json:"id"`
+	APIKeyID  string         `json:"api_key_id"`
+	Symbol    string         `json:"symbol"`
+	Condition AlertCondition `json:"condition"`
+	Threshold float64        `json:"threshold,omitempty"` // spread_above
+	Lower     float64        `json:"lower,omitempty"`     // price_outside
+	Upper     float64        `json:"upper,omitempty"`     // price_outside
+	// ForSeconds is how long the condition must hold before the rule triggers,
+	// and how long it must be clear before the rule recovers
+	ForSeconds int        `json:"for_seconds"`
+	Enabled    bool       `json:"enabled"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	UpdatedBy  string     `json:"updated_by,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// Breached reports whether an observed value violates the rule's condition
+func (r *AlertRule) Breached(value float64) bool {
+	switch r.Condition {
+	case AlertConditionSpreadAbove:
+		return value > r.Threshold
+	case AlertConditionPriceOutside:
+		return value < r.Lower || value > r.Upper
+	}
+	return false
+}
+
+// AlertRuleRequest creates or replaces an alert rule
+type AlertRuleRequest struct {
+	APIKeyID   string         `json:"api_key_id"`
+	Symbol     string         `json:"symbol"`
+	Condition  AlertCondition `json:"condition"`
+	Threshold  float64        `json:"threshold"`
+	Lower      float64        `json:"lower"`
+	Upper      float64        `json:"upper"`
+	ForSeconds int            `json:"for_seconds"`
+	Enabled    *bool          `json:"enabled"` // defaults to true
+}
+
+// Alert is sent on /ws/trading (type "alert") and to the webhook when a rule
+// triggers or recovers
+type Alert struct {
+	RuleID    string         `json:"ruleId"`
+	APIKeyID  string         `json:"apiKeyId"`
+	Symbol    string         `json:"symbol"`
+	Condition AlertCondition `json:"condition"`
+	State     string         `json:"state"` // triggered or recovered
+	Value     float64        `json:"value"` // the observed spread or price
+	Threshold float64        `json:"threshold,omitempty"`
+	Lower     float64        `json:"lower,omitempty"`
+	Upper     float64        `json:"upper,omitempty"`
+	Time      time.Time      `json:"time"`
+}
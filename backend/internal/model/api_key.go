@@ -33,34 +33,84 @@ func AllPlatforms() []Platform {
 	}
 }
 
+// APIKeyScope limits what an API key may be used for
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead  APIKeyScope = "read"
+	APIKeyScopeTrade APIKeyScope = "trade"
+)
+
+func (s APIKeyScope) IsValid() bool {
+	switch s {
+	case APIKeyScopeRead, APIKeyScopeTrade:
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultAPIKeyScopes is applied to keys without explicit scopes, so they stay read-only
+func DefaultAPIKeyScopes() []APIKeyScope {
+	return []APIKeyScope{APIKeyScopeRead}
+}
+
 type APIKey struct {
-	ID        string    `json:"id"` // MongoDB ObjectID as string
-	Name      string    `json:"name"`
-	Platform  Platform  `json:"platform"`
-	APIKey    string    `json:"api_key"`
-	APISecret string    `json:"-"` // Never expose in JSON responses
-	IsTestnet bool      `json:"is_testnet"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string        `json:"id"` // MongoDB ObjectID as string
+	Name      string        `json:"name"`
+	Platform  Platform      `json:"platform"`
+	APIKey    string        `json:"api_key"`
+	APISecret string        `json:"-"` // Never expose in JSON responses
+	IsTestnet bool          `json:"is_testnet"`
+	IsActive  bool          `json:"is_active"`
+	Scopes    []APIKeyScope `json:"scopes"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+
+	// DefaultSubscriptions are applied to every trading client right after it connects to the key
+	DefaultSubscriptions []TradingSubscription `json:"default_subscriptions,omitempty"`
+
+	Previous *RotatedCredentials `json:"-"` // credentials replaced by the last rotation
+}
+
+// RotatedCredentials keeps encrypted credentials replaced by a rotation so the
+// rotation can be rolled back until ExpiresAt
+type RotatedCredentials struct {
+	APIKey    string // encrypted
+	APISecret string // encrypted
+	ExpiresAt time.Time
+}
+
+// HasScope reports whether the key has been granted the given scope
+func (a *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // APIKeyResponse is the response structure that masks sensitive data
 type APIKeyResponse struct {
-	ID              string    `json:"id"`
-	Name            string    `json:"name"`
-	Platform        Platform  `json:"platform"`
-	APIKeyMasked    string    `json:"api_key_masked"`
-	APISecretMasked string    `json:"api_secret_masked"`
-	IsTestnet       bool      `json:"is_testnet"`
-	IsActive        bool      `json:"is_active"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              string        `json:"id"`
+	Name            string        `json:"name"`
+	Platform        Platform      `json:"platform"`
+	APIKeyMasked    string        `json:"api_key_masked"`
+	APISecretMasked string        `json:"api_secret_masked"`
+	IsTestnet       bool          `json:"is_testnet"`
+	IsActive        bool          `json:"is_active"`
+	Scopes          []APIKeyScope `json:"scopes"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+
+	DefaultSubscriptions   []TradingSubscription `json:"default_subscriptions,omitempty"`
+	RollbackAvailableUntil *time.Time            `json:"rollback_available_until,omitempty"`
 }
 
 // ToResponse converts APIKey to APIKeyResponse with masked sensitive data
 func (a *APIKey) ToResponse() APIKeyResponse {
-	return APIKeyResponse{
+	resp := APIKeyResponse{
 		ID:              a.ID,
 		Name:            a.Name,
 		Platform:        a.Platform,
@@ -68,9 +118,17 @@ func (a *APIKey) ToResponse() APIKeyResponse {
 		APISecretMasked: maskAPIKey(a.APISecret),
 		IsTestnet:       a.IsTestnet,
 		IsActive:        a.IsActive,
+		Scopes:          a.Scopes,
 		CreatedAt:       a.CreatedAt,
 		UpdatedAt:       a.UpdatedAt,
+
+		DefaultSubscriptions: a.DefaultSubscriptions,
 	}
+	if a.Previous != nil && time.Now().Before(a.Previous.ExpiresAt) {
+		expiresAt := a.Previous.ExpiresAt
+		resp.RollbackAvailableUntil = &expiresAt
+	}
+	return resp
 }
 
 // maskAPIKey masks the API key, showing only first 4 and last 4 characters
@@ -83,18 +141,48 @@ func maskAPIKey(key string) string {
 
 // CreateAPIKeyRequest is the request structure for creating an API key
 type CreateAPIKeyRequest struct {
-	Name      string   `json:"name"`
-	Platform  Platform `json:"platform"`
-	APIKey    string   `json:"api_key"`
-	APISecret string   `json:"api_secret"`
-	IsTestnet bool     `json:"is_testnet"`
+	Name      string        `json:"name"`
+	Platform  Platform      `json:"platform"`
+	APIKey    string        `json:"api_key"`
+	APISecret string        `json:"api_secret"`
+	IsTestnet bool          `json:"is_testnet"`
+	Scopes    []APIKeyScope `json:"scopes,omitempty"` // defaults to read-only
+	Force     bool          `json:"force,omitempty"`  // allow registering a key that already exists
+
+	DefaultSubscriptions []TradingSubscription `json:"default_subscriptions,omitempty"`
 }
 
 // UpdateAPIKeyRequest is the request structure for updating an API key
 type UpdateAPIKeyRequest struct {
-	Name      *string `json:"name,omitempty"`
-	APIKey    *string `json:"api_key,omitempty"`
-	APISecret *string `json:"api_secret,omitempty"`
-	IsTestnet *bool   `json:"is_testnet,omitempty"`
-	IsActive  *bool   `json:"is_active,omitempty"`
+	Name      *string       `json:"name,omitempty"`
+	APIKey    *string       `json:"api_key,omitempty"`
+	APISecret *string       `json:"api_secret,omitempty"`
+	IsTestnet *bool         `json:"is_testnet,omitempty"`
+	IsActive  *bool         `json:"is_active,omitempty"`
+	Scopes    []APIKeyScope `json:"scopes,omitempty"`
+
+	// DefaultSubscriptions replaces the bundle when present; an empty list clears it
+	DefaultSubscriptions *[]TradingSubscription `json:"default_subscriptions,omitempty"`
+}
+
+// RotateAPIKeyRequest is the request structure for rotating API key credentials
+type RotateAPIKeyRequest struct {
+	APIKey    string `json:"api_key"`
+	APISecret string `json:"api_secret"`
+}
+
+// APIKeyFilter narrows the API key list; nil fields are not filtered on
+type APIKeyFilter struct {
+	Platform  *Platform `json:"platform,omitempty"`
+	IsTestnet *bool     `json:"is_testnet,omitempty"`
+	IsActive  *bool     `json:"is_active,omitempty"`
+	Query     string    `json:"q,omitempty"` // case-insensitive name substring
+	Limit     int64     `json:"limit,omitempty"`
+	Offset    int64     `json:"offset,omitempty"`
+}
+
+// APIKeyListResult is a page of API keys together with the total match count
+type APIKeyListResult struct {
+	Items []APIKeyResponse `json:"items"`
+	Total int64            `json:"total"`
 }
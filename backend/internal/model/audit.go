@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// AuditEntry records an administrative action
+type AuditEntry struct {
+	ID         string         `json:"id"` // MongoDB ObjectID as string
+	ActorID    string         `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type"`
+	TargetID   string         `json:"target_id"`
+	Details    map[string]any `json:"details,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
@@ -7,25 +7,41 @@ const (
 	PermissionViewKline      Permission = "view:kline"
 	PermissionViewAPIKeys    Permission = "view:api_keys"
 	PermissionViewSettings   Permission = "view:settings"
+	PermissionViewTrading    Permission = "view:trading"
+	PermissionViewSessions   Permission = "view:sessions"
 	PermissionManageUsers    Permission = "manage:users"
 	PermissionManageRoles    Permission = "manage:roles"
 	PermissionManageAPIKeys  Permission = "manage:api_keys"
 	PermissionManageSettings Permission = "manage:settings"
+	PermissionManageSessions Permission = "manage:sessions"
 )
 
 func (p Permission) String() string {
 	return string(p)
 }
 
+// IsValid reports whether p is one of AllPermissions
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions() {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
 func AllPermissions() []Permission {
 	return []Permission{
 		PermissionViewDashboard,
 		PermissionViewKline,
 		PermissionViewAPIKeys,
 		PermissionViewSettings,
+		PermissionViewTrading,
+		PermissionViewSessions,
 		PermissionManageUsers,
 		PermissionManageRoles,
 		PermissionManageAPIKeys,
 		PermissionManageSettings,
+		PermissionManageSessions,
 	}
 }
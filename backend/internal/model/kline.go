@@ -37,3 +37,11 @@ type WebSocketMessage struct {
 	Action string            `json:"action"` // subscribe, unsubscribe
 	Data   KlineSubscription `json:"data"`
 }
+
+// SymbolInfo is a tradable symbol listed on an exchange
+type SymbolInfo struct {
+	Symbol   string   `json:"symbol"`
+	Base     string   `json:"base"`
+	Quote    string   `json:"quote"`
+	Platform Platform `json:"platform"`
+}
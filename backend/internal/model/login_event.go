@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ClientInfo identifies where a request came from
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	// DeviceToken is the remember-device token sent with a login, if any
+	DeviceToken string
+}
+
+// LoginEvent records one completed or failed login attempt
+type LoginEvent struct {
+	ID        string    `json:"id"`                // MongoDB ObjectID as string
+	UserID    string    `json:"user_id,omitempty"` // empty when the username did not match a user
+	Username  string    `json:"username"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"` // failure reason, e.g. invalid_password
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
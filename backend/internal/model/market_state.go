@@ -0,0 +1,40 @@
+package model
+
+// MarketStatus tells whether a market currently accepts orders
+type MarketStatus string
+
+const (
+	MarketStatusOpen   MarketStatus = "open"
+	MarketStatusHalted MarketStatus = "halted"
+)
+
+func (s MarketStatus) String() string {
+	return string(s)
+}
+
+func (s MarketStatus) IsValid() bool {
+	switch s {
+	case MarketStatusOpen, MarketStatusHalted:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllMarketStatuses() []MarketStatus {
+	return []MarketStatus{MarketStatusOpen, MarketStatusHalted}
+}
+
+// MarketState is the normalized trading status and rolling statistics of one
+// market, sent as "state" frames and returned by the market state endpoint
+type MarketState struct {
+	Symbol     string       `json:"symbol"`
+	Status     MarketStatus `json:"status"`
+	Last       string       `json:"last,omitempty"`
+	High       string       `json:"high,omitempty"`
+	Low        string       `json:"low,omitempty"`
+	Volume     string       `json:"volume,omitempty"`     // base asset volume over Period
+	Period     int64        `json:"period,omitempty"`     // statistics window in seconds
+	ReopenTime int64        `json:"reopenTime,omitempty"` // Unix ms a halted market reopens, when known
+	Platform   Platform     `json:"platform"`
+}
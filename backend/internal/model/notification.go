@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// NotificationType identifies a backend event that is pushed to the
+// notification webhook
+type NotificationType string
+
+const (
+	NotificationStreamAuthLost           NotificationType = "stream_auth_lost"
+	NotificationUserLocked               NotificationType = "user_locked"
+	NotificationAPIKeyVerificationFailed NotificationType = "api_key_verification_failed"
+	NotificationTest                     NotificationType = "test"
+)
+
+func (t NotificationType) String() string {
+	return string(t)
+}
+
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case NotificationStreamAuthLost, NotificationUserLocked, NotificationAPIKeyVerificationFailed, NotificationTest:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllNotificationTypes() []NotificationType {
+	return []NotificationType{
+		NotificationStreamAuthLost,
+		NotificationUserLocked,
+		NotificationAPIKeyVerificationFailed,
+		NotificationTest,
+	}
+}
+
+// Notification is one critical backend event
+type Notification struct {
+	Type    NotificationType  `json:"type"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"` // e.g. api_key_id, username
+	Time    time.Time         `json:"time"`
+}
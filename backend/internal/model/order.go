@@ -0,0 +1,190 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OrderSide is the canonical side of an order on every platform
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "BUY"
+	OrderSideSell OrderSide = "SELL"
+)
+
+func (s OrderSide) String() string {
+	return string(s)
+}
+
+func (s OrderSide) IsValid() bool {
+	switch s {
+	case OrderSideBuy, OrderSideSell:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllOrderSides() []OrderSide {
+	return []OrderSide{OrderSideBuy, OrderSideSell}
+}
+
+func (s OrderSide) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (s *OrderSide) UnmarshalText(b []byte) error {
+	return parseOrderEnum(b, s, OrderSide.IsValid, "order side")
+}
+
+// OrderStatus is the canonical order status, following Binance's vocabulary
+type OrderStatus string
+
+const (
+	OrderStatusNew             OrderStatus = "NEW"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusCanceled        OrderStatus = "CANCELED"
+	OrderStatusPendingCancel   OrderStatus = "PENDING_CANCEL"
+	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
+	OrderStatusUnknown         OrderStatus = "UNKNOWN" // a value the parser could not map
+)
+
+func (s OrderStatus) String() string {
+	return string(s)
+}
+
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusNew, OrderStatusPartiallyFilled, OrderStatusFilled, OrderStatusCanceled,
+		OrderStatusPendingCancel, OrderStatusRejected, OrderStatusExpired, OrderStatusUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFinal reports whether the order can no longer change
+func (s OrderStatus) IsFinal() bool {
+	switch s {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusRejected, OrderStatusExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllOrderStatuses() []OrderStatus {
+	return []OrderStatus{
+		OrderStatusNew,
+		OrderStatusPartiallyFilled,
+		OrderStatusFilled,
+		OrderStatusCanceled,
+		OrderStatusPendingCancel,
+		OrderStatusRejected,
+		OrderStatusExpired,
+		OrderStatusUnknown,
+	}
+}
+
+func (s OrderStatus) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (s *OrderStatus) UnmarshalText(b []byte) error {
+	return parseOrderEnum(b, s, OrderStatus.IsValid, "order status")
+}
+
+// OrderType is the canonical order type, following Binance's vocabulary
+type OrderType string
+
+const (
+	OrderTypeLimit           OrderType = "LIMIT"
+	OrderTypeMarket          OrderType = "MARKET"
+	OrderTypeStopLoss        OrderType = "STOP_LOSS"
+	OrderTypeStopLossLimit   OrderType = "STOP_LOSS_LIMIT"
+	OrderTypeTakeProfit      OrderType = "TAKE_PROFIT"
+	OrderTypeTakeProfitLimit OrderType = "TAKE_PROFIT_LIMIT"
+	OrderTypeLimitMaker      OrderType = "LIMIT_MAKER"
+)
+
+func (t OrderType) String() string {
+	return string(t)
+}
+
+func (t OrderType) IsValid() bool {
+	switch t {
+	case OrderTypeLimit, OrderTypeMarket, OrderTypeStopLoss, OrderTypeStopLossLimit,
+		OrderTypeTakeProfit, OrderTypeTakeProfitLimit, OrderTypeLimitMaker:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllOrderTypes() []OrderType {
+	return []OrderType{
+		OrderTypeLimit,
+		OrderTypeMarket,
+		OrderTypeStopLoss,
+		OrderTypeStopLossLimit,
+		OrderTypeTakeProfit,
+		OrderTypeTakeProfitLimit,
+		OrderTypeLimitMaker,
+	}
+}
+
+func (t OrderType) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func (t *OrderType) UnmarshalText(b []byte) error {
+	return parseOrderEnum(b, t, OrderType.IsValid, "order type")
+}
+
+// TimeInForce is the canonical time in force of an order
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC"
+	TimeInForceIOC TimeInForce = "IOC"
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+func (t TimeInForce) String() string {
+	return string(t)
+}
+
+func (t TimeInForce) IsValid() bool {
+	switch t {
+	case TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+		return true
+	default:
+		return false
+	}
+}
+
+func AllTimeInForces() []TimeInForce {
+	return []TimeInForce{TimeInForceGTC, TimeInForceIOC, TimeInForceFOK}
+}
+
+func (t TimeInForce) MarshalText() ([]byte, error) {
+	return []byte(t), nil
+}
+
+func (t *TimeInForce) UnmarshalText(b []byte) error {
+	return parseOrderEnum(b, t, TimeInForce.IsValid, "time in force")
+}
+
+// parseOrderEnum decodes a case-insensitive enum value; an empty value is
+// kept so optional fields (e.g. a missing time in force) round-trip
+func parseOrderEnum[T ~string](b []byte, dst *T, valid func(T) bool, name string) error {
+	v := T(strings.ToUpper(strings.TrimSpace(string(b))))
+	if v != "" && !valid(v) {
+		return fmt.Errorf("invalid %s %q", name, string(b))
+	}
+	*dst = v
+	return nil
+}
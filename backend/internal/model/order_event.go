@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// OrderEvent is one order update received on an API key's private stream
+type OrderEvent struct {
+	ID         string    `json:"id"`
+	APIKeyID   string    `json:"apiKeyId"`
+	Order      Order     `json:"order"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+// OrderEventQuery filters stored order events; zero From/To leave that bound open
+type OrderEventQuery struct {
+	APIKeyID string
+	Symbol   string
+	From     time.Time
+	To       time.Time
+	Limit    int64
+	Offset   int64
+}
+
+// OrderEventPage is a page of order events, newest first, with the total match count
+type OrderEventPage struct {
+	Items []OrderEvent `json:"items"`
+	Total int64        `json:"total"`
+}
+
+// TradingReport summarizes one UTC day of fills of an API key
+type TradingReport struct {
+	APIKeyID    string         `json:"apiKeyId"`
+	Date        string         `json:"date"` // YYYY-MM-DD, UTC
+	Fills       int            `json:"fills"`
+	Volume      float64        `json:"volume"` // notional of all fills, in quote currency
+	RealizedPnL float64        `json:"realizedPnl"`
+	Symbols     []SymbolReport `json:"symbols"`
+}
+
+// SymbolReport is the per-symbol part of a TradingReport. Realized PnL uses
+// average-cost accounting over every stored fill up to the end of the day, so
+// Position and AvgCost carry over from earlier days.
+type SymbolReport struct {
+	Symbol       string  `json:"symbol"`
+	Fills        int     `json:"fills"`
+	BoughtQty    float64 `json:"boughtQty"`
+	SoldQty      float64 `json:"soldQty"`
+	BuyNotional  float64 `json:"buyNotional"`
+	SellNotional float64 `json:"sellNotional"`
+	Volume       float64 `json:"volume"`
+	RealizedPnL  float64 `json:"realizedPnl"`
+	Position     float64 `json:"position"` // signed net quantity at the end of the day
+	AvgCost      float64 `json:"avgCost"`  // average entry price of Position
+}
@@ -0,0 +1,16 @@
+package model
+
+// PasswordPolicy describes the rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	RejectCommon  bool
+}
+
+// DefaultPasswordPolicy only enforces a 6-character minimum
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 6}
+}
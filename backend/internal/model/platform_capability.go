@@ -0,0 +1,80 @@
+package model
+
+import "slices"
+
+// PlatformCapabilities describes what the trading stream supports for a platform
+type PlatformCapabilities struct {
+	Platform          Platform `json:"platform"`
+	SubscriptionTypes []string `json:"subscription_types"`
+	Intervals         []string `json:"intervals"`
+	HasTestnet        bool     `json:"has_testnet"`
+	PrivateStreams    bool     `json:"private_streams"`
+	OrderTypes        []string `json:"order_types"`
+}
+
+// platformCapabilities is the single source of truth for per-platform features,
+// used by the capabilities endpoint and by the trading stream when validating subscriptions
+var platformCapabilities = map[Platform]PlatformCapabilities{
+	PlatformBinance: {
+		Platform:          PlatformBinance,
+		SubscriptionTypes: []string{"kline", "orderbook", "depth", "order", "trades", "deals", "balance"},
+		Intervals:         []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"},
+		HasTestnet:        true,
+		PrivateStreams:    true,
+		OrderTypes:        []string{"LIMIT", "MARKET", "LIMIT_MAKER", "STOP_LOSS_LIMIT", "TAKE_PROFIT_LIMIT"},
+	},
+	PlatformBTCC: {
+		Platform:          PlatformBTCC,
+		SubscriptionTypes: []string{"kline", "orderbook", "depth", "order", "trades", "deals", "asset", "balance", "state"},
+		Intervals:         []string{"1m", "5m", "15m", "30m", "1h", "4h", "1d", "1w"},
+		HasTestnet:        true,
+		PrivateStreams:    true,
+		OrderTypes:        []string{"LIMIT", "MARKET"},
+	},
+	// OKX and Bybit keys can be stored but are not streamed yet
+	PlatformOKX: {
+		Platform:          PlatformOKX,
+		SubscriptionTypes: []string{},
+		Intervals:         []string{},
+		HasTestnet:        true,
+		OrderTypes:        []string{},
+	},
+	PlatformBybit: {
+		Platform:          PlatformBybit,
+		SubscriptionTypes: []string{},
+		Intervals:         []string{},
+		HasTestnet:        true,
+		OrderTypes:        []string{},
+	},
+}
+
+// GetPlatformCapabilities returns the capabilities of a platform
+func GetPlatformCapabilities(p Platform) (PlatformCapabilities, bool) {
+	c, ok := platformCapabilities[p]
+	return c, ok
+}
+
+// AllPlatformCapabilities returns capabilities for every platform in AllPlatforms order
+func AllPlatformCapabilities() []PlatformCapabilities {
+	result := make([]PlatformCapabilities, 0, len(platformCapabilities))
+	for _, p := range AllPlatforms() {
+		if c, ok := platformCapabilities[p]; ok {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// SupportsStreaming reports whether the trading stream can connect this platform
+func (c PlatformCapabilities) SupportsStreaming() bool {
+	return len(c.SubscriptionTypes) > 0
+}
+
+func (c PlatformCapabilities) SupportsSubscription(subType string) bool {
+	return slices.Contains(c.SubscriptionTypes, subType)
+}
+
+// SupportsInterval reports whether a kline interval is supported; an empty interval is allowed
+func (c PlatformCapabilities) SupportsInterval(interval string) bool {
+	return interval == "" || slices.Contains(c.Intervals, interval)
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// UserPreferences is a user's UI state, such as the selected symbol, chart
+// interval and panel layout. Data is an opaque JSON object owned by the frontend.
+type UserPreferences struct {
+	UserID    string          `json:"user_id"`
+	Data      json.RawMessage `json:"data"`
+	Version   int64           `json:"version"` // 0 until the first save
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
+
+// ETag identifies this revision for If-Match checks
+func (p *UserPreferences) ETag() string {
+	return strconv.Quote(strconv.FormatInt(p.Version, 10))
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Recorded market event types
+const (
+	RecordTypeKline  = "kline"
+	RecordTypeTrades = "trades"
+)
+
+// MarketRecord is one kline or trade event captured from an exchange stream
+type MarketRecord struct {
+	Platform string      `json:"platform"`
+	Type     string      `json:"type"` // kline or trades
+	Symbol   string      `json:"symbol"`
+	Interval string      `json:"interval,omitempty"`
+	Time     time.Time   `json:"time"`
+	Data     interface{} `json:"data"`
+}
+
+// MarketRecordQuery filters recorded events; zero From/To leave that bound open
+type MarketRecordQuery struct {
+	Symbol string
+	Type   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// RecordingStats reports the recorder's queue and write counters
+type RecordingStats struct {
+	Queued   int   `json:"queued"`
+	Written  int64 `json:"written"`
+	Dropped  int64 `json:"dropped"` // oldest events discarded because the queue was full
+	Failures int64 `json:"failures"`
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Setting represents a strategy setting configuration
 type Setting struct {
 	MongoID    string                 `json:"id"`
@@ -7,6 +9,10 @@ type Setting struct {
 	Quote      string                 `json:"quote"`
 	Strategy   string                 `json:"strategy"`
 	Parameters map[string]interface{} `json:"parameters"`
+	CreatedBy  string                 `json:"created_by"`
+	UpdatedBy  string                 `json:"updated_by"`
+	CreatedAt  *time.Time             `json:"created_at"`
+	UpdatedAt  *time.Time             `json:"updated_at"`
 }
 
 // SettingResponse is the API response structure
@@ -16,6 +22,10 @@ type SettingResponse struct {
 	Quote      string                 `json:"quote"`
 	Strategy   string                 `json:"strategy"`
 	Parameters map[string]interface{} `json:"parameters"`
+	CreatedBy  string                 `json:"created_by,omitempty"`
+	UpdatedBy  string                 `json:"updated_by,omitempty"`
+	CreatedAt  *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time             `json:"updated_at,omitempty"`
 }
 
 // CreateSettingRequest is the request structure for creating a setting
@@ -42,5 +52,9 @@ func (s *Setting) ToResponse() SettingResponse {
 		Quote:      s.Quote,
 		Strategy:   s.Strategy,
 		Parameters: s.Parameters,
+		CreatedBy:  s.CreatedBy,
+		UpdatedBy:  s.UpdatedBy,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
 	}
 }
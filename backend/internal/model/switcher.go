@@ -1,10 +1,32 @@
 package model
 
+import "time"
+
 // Switcher represents a trading pair switcher configuration
 // The document structure is dynamic with trading pair keys (e.g., "SOL_USDT")
 type Switcher struct {
-	MongoID string                  `json:"id"`
-	Pairs   map[string]SwitcherPair `json:"pairs"`
+	MongoID   string                  `json:"id"`
+	Pairs     map[string]SwitcherPair `json:"pairs"`
+	CreatedBy string                  `json:"created_by"`
+	UpdatedBy string                  `json:"updated_by"`
+	CreatedAt *time.Time              `json:"created_at"`
+	UpdatedAt *time.Time              `json:"updated_at"`
+
+	// Extra keeps document fields that are neither audit fields nor pairs,
+	// such as those of legacy documents, so they are written back unchanged
+	Extra map[string]interface{} `json:"-"`
+}
+
+// NewSwitcher creates a Switcher with a guaranteed non-nil Pairs map
+func NewSwitcher(id string, pairs map[string]SwitcherPair) *Switcher {
+	s := &Switcher{
+		MongoID: id,
+		Pairs:   make(map[string]SwitcherPair, len(pairs)),
+	}
+	for pair, config := range pairs {
+		s.Pairs[pair] = config
+	}
+	return s
 }
 
 // SwitcherPair represents the enable status for a trading pair
@@ -14,8 +36,12 @@ type SwitcherPair struct {
 
 // SwitcherResponse is the API response structure
 type SwitcherResponse struct {
-	ID    string                  `json:"id"`
-	Pairs map[string]SwitcherPair `json:"pairs"`
+	ID        string                  `json:"id"`
+	Pairs     map[string]SwitcherPair `json:"pairs"`
+	CreatedBy string                  `json:"created_by,omitempty"`
+	UpdatedBy string                  `json:"updated_by,omitempty"`
+	CreatedAt *time.Time              `json:"created_at,omitempty"`
+	UpdatedAt *time.Time              `json:"updated_at,omitempty"`
 }
 
 // UpdateSwitcherRequest is the request structure for updating a switcher
@@ -26,7 +52,11 @@ type UpdateSwitcherRequest struct {
 // ToResponse converts Switcher to SwitcherResponse
 func (s *Switcher) ToResponse() SwitcherResponse {
 	return SwitcherResponse{
-		ID:    s.MongoID,
-		Pairs: s.Pairs,
+		ID:        s.MongoID,
+		Pairs:     s.Pairs,
+		CreatedBy: s.CreatedBy,
+		UpdatedBy: s.UpdatedBy,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
 	}
 }
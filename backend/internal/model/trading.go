@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // OrderBookLevel represents a single price level in the order book
 type OrderBookLevel struct {
 	Price    string `json:"price"`
@@ -20,19 +22,28 @@ type OrderBook struct {
 
 // Order represents a user's order
 type Order struct {
-	OrderID     string   `json:"orderId"`
-	Symbol      string   `json:"symbol"`
-	Side        string   `json:"side"` // BUY or SELL
-	Type        string   `json:"type"` // LIMIT, MARKET, etc.
-	Price       string   `json:"price"`
-	Quantity    string   `json:"quantity"`
-	ExecutedQty string   `json:"executedQty"`
-	Status      string   `json:"status"` // NEW, FILLED, CANCELED, etc.
-	TimeInForce string   `json:"timeInForce"`
-	CreateTime  int64    `json:"createTime"`
-	UpdateTime  int64    `json:"updateTime"`
-	StopPrice   string   `json:"stopPrice,omitempty"`
-	Platform    Platform `json:"platform"`
+	OrderID     string    `json:"orderId"`
+	Symbol      string    `json:"symbol"`
+	Side        OrderSide `json:"side"`
+	Type        OrderType `json:"type"`
+	Price       string    `json:"price"`
+	Quantity    string    `json:"quantity"`
+	ExecutedQty string    `json:"executedQty"`
+	// ExecutedQuote is the cumulative quote amount filled so far (optional)
+	ExecutedQuote string      `json:"executedQuote,omitempty"`
+	Status        OrderStatus `json:"status"`
+	TimeInForce   TimeInForce `json:"timeInForce"`
+	CreateTime    int64       `json:"createTime"`
+	UpdateTime    int64       `json:"updateTime"`
+	StopPrice     string      `json:"stopPrice,omitempty"`
+	Platform      Platform    `json:"platform"`
+}
+
+// Balance is one asset of a "balance" frame, normalized across platforms
+type Balance struct {
+	Asset  string `json:"asset"`
+	Free   string `json:"free"`
+	Locked string `json:"locked"`
 }
 
 // SpreadRecord represents a single spread measurement
@@ -45,22 +56,132 @@ type SpreadRecord struct {
 
 // TradingWebSocketMessage represents messages for the trading WebSocket
 type TradingWebSocketMessage struct {
-	Action   string `json:"action"`   // subscribe, unsubscribe, connect
-	Type     string `json:"type"`     // kline, orderbook, orders
-	APIKeyID string `json:"apiKeyId"` // API Key ID for private streams
-	Symbol   string `json:"symbol"`   // Trading pair
-	Interval string `json:"interval"` // Kline interval (1m, 5m, etc.)
+	Action   string `json:"action"`            // hello, connect, subscribe, unsubscribe, ...
+	Type     string `json:"type"`              // kline, orderbook, orders
+	APIKeyID string `json:"apiKeyId"`          // API Key ID for private streams
+	Symbol   string `json:"symbol"`            // Trading pair
+	Interval string `json:"interval"`          // Kline interval (1m, 5m, etc.)
+	Token    string `json:"token,omitempty"`   // JWT for the reauth action
+	Version  int    `json:"version,omitempty"` // protocol version requested by the hello action
+
+	// DepthThrottleMs coalesces orderbook updates to at most one frame per interval (0 = no throttling)
+	DepthThrottleMs int `json:"depthThrottleMs,omitempty"`
+	// ConflateMs sends at most one frame per interval for an orderbook or kline
+	// subscription, always the latest; closed klines and trades pass through (0 = off)
+	ConflateMs int `json:"conflateMs,omitempty"`
+	// Mode selects orderbook frames: "partial" (default) sends snapshots of the
+	// top levels, "diff" forwards the exchange's incremental updates and "book"
+	// sends the top levels of a server-maintained book built from them (Binance)
+	Mode string `json:"mode,omitempty"`
+	// BookMode is the deprecated spelling of Mode "diff" and "book"
+	BookMode string `json:"bookMode,omitempty"`
 }
 
 // TradingWebSocketResponse represents response messages from the trading WebSocket
 type TradingWebSocketResponse struct {
-	Type      string      `json:"type"`      // kline, orderbook, orders, spread, error, connected
+	Type      string      `json:"type"`      // hello, ready, connected, kline, orderbook, orders, spread, error
 	Data      interface{} `json:"data"`      // The actual data
 	Platform  string      `json:"platform"`  // Exchange platform
 	Symbol    string      `json:"symbol"`    // Trading pair
 	Interval  string      `json:"interval"`  // Kline interval (optional)
 	Timestamp int64       `json:"timestamp"` // Event timestamp
 	Error     string      `json:"error,omitempty"`
+	Code      string      `json:"code,omitempty"` // machine-readable error code (optional)
+	// Mode tags orderbook frames: "partial" replaces the client's book, "diff" is merged into it
+	Mode string `json:"mode,omitempty"`
+}
+
+// TradingSubscription describes a single active stream of a trading WebSocket client
+type TradingSubscription struct {
+	Type     string `json:"type"`
+	Symbol   string `json:"symbol,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// TradingSession describes one live /ws/trading connection of a user
+type TradingSession struct {
+	ID             string                `json:"id"`
+	RemoteAddr     string                `json:"remoteAddr"`
+	UserID         string                `json:"userId,omitempty"`
+	APIKeyID       string                `json:"apiKeyId,omitempty"`
+	Platform       Platform              `json:"platform,omitempty"` // of the exchange connection, once attached
+	ConnectedAt    time.Time             `json:"connectedAt"`
+	DurationMs     int64                 `json:"durationMs"`
+	TokenExpiresAt *time.Time            `json:"tokenExpiresAt,omitempty"`
+	Subscriptions  []TradingSubscription `json:"subscriptions"`
+}
+
+// KlineSession describes one live /ws/kline connection; the kline stream is
+// anonymous, so clients are identified by address only
+type KlineSession struct {
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	DurationMs  int64     `json:"durationMs"`
+	Streams     []string  `json:"streams"` // Binance stream names, e.g. btcusdt@kline_1m
+}
+
+// WebSocketSessions lists every connected WebSocket client
+type WebSocketSessions struct {
+	Trading []TradingSession `json:"trading"`
+	Kline   []KlineSession   `json:"kline"`
+}
+
+// TradingDisconnectRequest selects the trading connections an admin closes;
+// exactly one of SessionID and APIKeyID is set
+type TradingDisconnectRequest struct {
+	SessionID string `json:"sessionId,omitempty"`
+	APIKeyID  string `json:"apiKeyId,omitempty"`
+}
+
+// TradingDisconnectResult reports what a forced disconnect closed
+type TradingDisconnectResult struct {
+	ClosedClients  int  `json:"closedClients"`
+	ExchangeClosed bool `json:"exchangeClosed"` // the API key's exchange connection was torn down
+}
+
+// TradingStreamStats reports runtime metrics of the trading WebSocket manager
+type TradingStreamStats struct {
+	Clients             int   `json:"clients"`
+	CompressedClients   int   `json:"compressedClients"`
+	ExchangeConnections int   `json:"exchangeConnections"`
+	FramesSent          int64 `json:"framesSent"`
+	BytesSent           int64 `json:"bytesSent"` // message payloads before compression
+	WireBytes           int64 `json:"wireBytes"` // bytes written to client sockets after compression, with framing
+	FramesCoalesced     int64 `json:"framesCoalesced"`
+	BytesCoalesced      int64 `json:"bytesCoalesced"`
+	BytesSaved          int64 `json:"bytesSaved"`    // BytesCoalesced plus what compression removed from BytesSent
+	WriteErrors         int64 `json:"writeErrors"`   // frames lost to failed client writes
+	FramesDropped       int64 `json:"framesDropped"` // stale orderbook/kline frames dropped from full send queues
+
+	// ExchangeConnectionsByUser counts the exchange connections each user's clients are attached to
+	ExchangeConnectionsByUser map[string]int `json:"exchangeConnectionsByUser"`
+	Limits                    TradingLimits  `json:"limits"`
+
+	ClientStats []TradingClientStats `json:"clientStats"`
+	Recording   *RecordingStats      `json:"recording,omitempty"` // nil when recording is disabled
+}
+
+// TradingLimits reports the configured connection limits; zero means unlimited or disabled
+type TradingLimits struct {
+	MaxClients                    int64 `json:"maxClients"` // kline and trading WebSocket clients combined
+	MaxExchangeConnections        int   `json:"maxExchangeConnections"`
+	MaxExchangeConnectionsPerUser int   `json:"maxExchangeConnectionsPerUser"`
+	ExchangeIdleTimeoutMs         int64 `json:"exchangeIdleTimeoutMs"`
+	ClientSendQueue               int   `json:"clientSendQueue"` // frames buffered per client before stale ones are dropped
+}
+
+// TradingClientStats reports the delivery counters of one trading WebSocket client
+type TradingClientStats struct {
+	RemoteAddr      string    `json:"remoteAddr"`
+	UserID          string    `json:"userId"`
+	ConnectedAt     time.Time `json:"connectedAt"`
+	FramesSent      int64     `json:"framesSent"`
+	BytesSent       int64     `json:"bytesSent"`
+	WireBytes       int64     `json:"wireBytes"`
+	FramesCoalesced int64     `json:"framesCoalesced"`
+	WriteErrors     int64     `json:"writeErrors"`
+	FramesDropped   int64     `json:"framesDropped"`
+	Queued          int       `json:"queued"` // frames waiting in the send queue
 }
 
 // ExchangeConfig holds exchange-specific configuration
@@ -71,52 +192,83 @@ type ExchangeConfig struct {
 	BaseRESTURL string   `json:"baseRestUrl"`
 }
 
-// GetBinanceConfig returns Binance WebSocket configuration
-func GetBinanceConfig(isTestnet bool) ExchangeConfig {
-	if isTestnet {
-		return ExchangeConfig{
-			Platform:    PlatformBinance,
-			IsTestnet:   true,
-			BaseWSURL:   "wss://testnet.binance.vision/ws",
-			BaseRESTURL: "https://testnet.binance.vision/api",
-		}
-	}
-	return ExchangeConfig{
-		Platform:    PlatformBinance,
-		IsTestnet:   false,
-		BaseWSURL:   "wss://stream.binance.com:9443/ws",
-		BaseRESTURL: "https://api.binance.com/api",
-	}
+// ExchangeNetwork identifies the production or testnet environment of a platform
+type ExchangeNetwork struct {
+	Platform  Platform
+	IsTestnet bool
 }
 
-// GetBTCCConfig returns BTCC WebSocket configuration
-func GetBTCCConfig(isTestnet bool) ExchangeConfig {
-	if isTestnet {
-		// BTCC testnet/UAT environment
-		return ExchangeConfig{
-			Platform:    PlatformBTCC,
-			IsTestnet:   true,
-			BaseWSURL:   "wss://spot.cryptouat.com:8700/ws",
-			BaseRESTURL: "https://spot.cryptouat.com:8700",
-		}
+// ExchangeURLs are the base endpoints of one exchange network
+type ExchangeURLs struct {
+	WSURL   string
+	RESTURL string
+}
+
+// DefaultExchangeURLs are the public endpoints used unless configured otherwise
+var DefaultExchangeURLs = map[ExchangeNetwork]ExchangeURLs{
+	{Platform: PlatformBinance}: {
+		WSURL:   "wss://stream.binance.com:9443/ws",
+		RESTURL: "https://api.binance.com/api",
+	},
+	{Platform: PlatformBinance, IsTestnet: true}: {
+		WSURL:   "wss://testnet.binance.vision/ws",
+		RESTURL: "https://testnet.binance.vision/api",
+	},
+	{Platform: PlatformBTCC}: {
+		WSURL:   "wss://spotprice2.btcccdn.com/ws",
+		RESTURL: "https://spotapi2.btcccdn.com",
+	},
+	// BTCC testnet/UAT environment
+	{Platform: PlatformBTCC, IsTestnet: true}: {
+		WSURL:   "wss://spot.cryptouat.com:8700/ws",
+		RESTURL: "https://spot.cryptouat.com:8700",
+	},
+}
+
+// ExchangeEndpoints resolves the base URLs of every platform and network. A
+// nil *ExchangeEndpoints serves DefaultExchangeURLs.
+type ExchangeEndpoints struct {
+	urls map[ExchangeNetwork]ExchangeURLs
+}
+
+// NewExchangeEndpoints starts from DefaultExchangeURLs and applies overrides,
+// where an empty field keeps the default
+func NewExchangeEndpoints(overrides map[ExchangeNetwork]ExchangeURLs) *ExchangeEndpoints {
+	urls := make(map[ExchangeNetwork]ExchangeURLs, len(DefaultExchangeURLs))
+	for network, defaults := range DefaultExchangeURLs {
+		urls[network] = defaults
 	}
-	// BTCC production environment
-	return ExchangeConfig{
-		Platform:    PlatformBTCC,
-		IsTestnet:   false,
-		BaseWSURL:   "wss://spotprice2.btcccdn.com/ws",
-		BaseRESTURL: "https://spotapi2.btcccdn.com",
+	for network, override := range overrides {
+		resolved := urls[network]
+		if override.WSURL != "" {
+			resolved.WSURL = override.WSURL
+		}
+		if override.RESTURL != "" {
+			resolved.RESTURL = override.RESTURL
+		}
+		urls[network] = resolved
 	}
+	return &ExchangeEndpoints{urls: urls}
 }
 
-// GetExchangeConfig returns exchange configuration based on platform
-func GetExchangeConfig(platform Platform, isTestnet bool) ExchangeConfig {
-	switch platform {
-	case PlatformBinance:
-		return GetBinanceConfig(isTestnet)
-	case PlatformBTCC:
-		return GetBTCCConfig(isTestnet)
-	default:
-		return GetBinanceConfig(isTestnet)
+// Config returns the exchange configuration of platform's network; platforms
+// without endpoints fall back to Binance
+func (e *ExchangeEndpoints) Config(platform Platform, isTestnet bool) ExchangeConfig {
+	urls := DefaultExchangeURLs
+	if e != nil {
+		urls = e.urls
+	}
+
+	network := ExchangeNetwork{Platform: platform, IsTestnet: isTestnet}
+	endpoint, ok := urls[network]
+	if !ok {
+		network.Platform = PlatformBinance
+		endpoint = urls[network]
+	}
+	return ExchangeConfig{
+		Platform:    network.Platform,
+		IsTestnet:   isTestnet,
+		BaseWSURL:   endpoint.WSURL,
+		BaseRESTURL: endpoint.RESTURL,
 	}
 }
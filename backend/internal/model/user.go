@@ -1,27 +1,93 @@
 package model
 
 import (
+	"net/netip"
 	"time"
 
 	"control_page/internal/model/enum"
 )
 
 type User struct {
-	ID                string    `json:"id"` // MongoDB ObjectID as string
-	Username          string    `json:"username"`
-	Password          string    `json:"-"`
-	IsActive          bool      `json:"is_active"`
-	TOTPSecret        *string   `json:"-"`
-	TOTPEnabled       bool      `json:"totp_enabled"`
-	PendingTOTPSecret *string   `json:"-"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                string     `json:"id"` // MongoDB ObjectID as string
+	Username          string     `json:"username"`
+	Email             string     `json:"email"`
+	Password          string     `json:"-"`
+	IsActive          bool       `json:"is_active"`
+	TOTPSecret        *string    `json:"-"`
+	TOTPEnabled       bool       `json:"totp_enabled"`
+	PendingTOTPSecret *string    `json:"-"`
+	FailedAttempts    int        `json:"failed_attempts"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	// MustChangePassword is set by an admin password reset; until the user
+	// changes their password only their own account endpoints are reachable
+	MustChangePassword bool `json:"must_change_password"`
+	// IPAllowlist restricts the account to these CIDR ranges; empty allows any address
+	IPAllowlist []string `json:"ip_allowlist,omitempty"`
+	// SessionsRevokedAt invalidates every token issued before it
+	SessionsRevokedAt *time.Time `json:"-"`
+	// TrustedDevices may skip the TOTP step of a password login
+	TrustedDevices []TrustedDevice `json:"-"`
+	LastLoginAt    *time.Time      `json:"last_login_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// PendingActivation reports whether the user registered but never finished
+// activation, as opposed to an account an admin deactivated after use
+func (u *User) PendingActivation() bool {
+	return !u.IsActive && !u.TOTPEnabled && u.LastLoginAt == nil
+}
+
+// IsLocked reports whether the account is locked out at the given time
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// AllowsIP reports whether the account may be used from ip; an empty
+// allowlist allows any address and an unparsable ip none
+func (u *User) AllowsIP(ip string) bool {
+	if len(u.IPAllowlist) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.WithZone("").Unmap()
+	for _, cidr := range u.IPAllowlist {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedDevice is a device remembered after a TOTP login. The device holds a
+// signed token naming ID, so removing the entry revokes it before ExpiresAt.
+type TrustedDevice struct {
+	ID         string     `json:"id"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// LockoutPolicy locks an account for Duration after MaxAttempts consecutive
+// failed logins; a MaxAttempts of zero disables lockout
+type LockoutPolicy struct {
+	MaxAttempts int
+	Duration    time.Duration
 }
 
 type Role struct {
-	ID          string    `json:"id"` // MongoDB ObjectID as string
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
+	ID          string `json:"id"` // MongoDB ObjectID as string
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// MaxTokenTTL caps the lifetime in seconds of tokens issued to holders of
+	// this role; 0 leaves the global jwt.expiration in effect
+	MaxTokenTTL int64     `json:"max_token_ttl,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -44,6 +110,41 @@ type UserWithRoles struct {
 	Permissions []enum.Permission `json:"permissions"`
 }
 
+// HasPermission reports whether any of the user's roles grants the permission
+func (u *UserWithRoles) HasPermission(permission enum.Permission) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// UserDetail is a user with the resources they currently control
+type UserDetail struct {
+	UserWithRoles
+	APIKeys  []APIKeyResponse `json:"api_keys,omitempty"` // omitted when the caller lacks view:api_keys
+	Sessions []TradingSession `json:"sessions"`
+}
+
+// Profile is the signed-in user's view of their own account. It carries only
+// the presence of a pending TOTP rebind, never any secret.
+type Profile struct {
+	ID                 string            `json:"id"`
+	Username           string            `json:"username"`
+	Email              string            `json:"email"`
+	IsActive           bool              `json:"is_active"`
+	TOTPEnabled        bool              `json:"totp_enabled"`
+	PendingRebind      bool              `json:"pending_rebind"`
+	PasswordChangedAt  *time.Time        `json:"password_changed_at,omitempty"`
+	MustChangePassword bool              `json:"must_change_password"`
+	LastLoginAt        *time.Time        `json:"last_login_at,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+	Roles              []Role            `json:"roles"`
+	Permissions        []enum.Permission `json:"permissions"`
+}
+
 type RoleWithPermissions struct {
 	Role
 	Permissions []enum.Permission `json:"permissions"`
@@ -54,15 +155,21 @@ type LoginResult struct {
 	RequiresTOTP      bool           `json:"requires_totp"`
 	RequiresTOTPSetup bool           `json:"requires_totp_setup"`
 	Token             string         `json:"token,omitempty"`
+	ExpiresIn         int64          `json:"expires_in,omitempty"` // token lifetime in seconds
 	User              *UserWithRoles `json:"user,omitempty"`
 	TempUserID        string         `json:"temp_user_id,omitempty"`
 	TOTPSetup         *TOTPSetup     `json:"totp_setup,omitempty"`
+	// DeviceToken lets this device skip TOTP on later logins; only set when
+	// VerifyTOTP was asked to remember the device
+	DeviceToken string `json:"device_token,omitempty"`
 }
 
 // TOTPSetup contains information needed to set up TOTP
 type TOTPSetup struct {
 	Secret string `json:"secret"`
 	QRCode string `json:"qr_code"`
+	// OTPAuthURL is the otpauth:// URI encoded in QRCode, for manual entry or deep links
+	OTPAuthURL string `json:"otpauth_url"`
 }
 
 // RegisterResult represents the result of a registration
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const collectionAlertRule = "alert_rule"
+
+var _ adaptor.AlertRuleRepository = (*AlertRuleMongoRepository)(nil)
+
+// AlertRuleMongoDocument represents the MongoDB document structure for alert rules
+type AlertRuleMongoDocument struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	APIKeyID   string             `bson:"api_key_id"`
+	Symbol     string             `bson:"symbol"`
+	Condition  string             `bson:"condition"`
+	Threshold  float64            `bson:"threshold,omitempty"`
+	Lower      float64            `bson:"lower,omitempty"`
+	Upper      float64            `bson:"upper,omitempty"`
+	ForSeconds int                `bson:"for_seconds"`
+	Enabled    bool               `bson:"enabled"`
+	CreatedBy  string             `bson:"created_by,omitempty"`
+	UpdatedBy  string             `bson:"updated_by,omitempty"`
+	CreatedAt  *time.Time         `bson:"created_at,omitempty"`
+	UpdatedAt  *time.Time         `bson:"updated_at,omitempty"`
+}
+
+type AlertRuleMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAlertRuleMongoRepository(db *mongo.Database) *AlertRuleMongoRepository {
+	return &AlertRuleMongoRepository{
+		collection: db.Collection(collectionAlertRule),
+	}
+}
+
+func (r *AlertRuleMongoRepository) GetAll(ctx context.Context) ([]model.AlertRule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "api_key_id", Value: 1}, {Key: "symbol", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []AlertRuleMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	rules := make([]model.AlertRule, 0, len(docs))
+	for _, doc := range docs {
+		rules = append(rules, *documentToAlertRule(&doc))
+	}
+	return rules, nil
+}
+
+func (r *AlertRuleMongoRepository) GetByID(ctx context.Context, id string) (*model.AlertRule, error) {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc AlertRuleMongoDocument
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return documentToAlertRule(&doc), nil
+}
+
+func (r *AlertRuleMongoRepository) Create(ctx context.Context, rule *model.AlertRule) error {
+	doc := alertRuleToDocument(rule)
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	rule.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	return nil
+}
+
+func (r *AlertRuleMongoRepository) Update(ctx context.Context, rule *model.AlertRule) error {
+	objectID, err := parseObjectID(rule.ID)
+	if err != nil {
+		return err
+	}
+
+	doc := alertRuleToDocument(rule)
+	doc.ID = objectID
+	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, doc)
+	return err
+}
+
+func (r *AlertRuleMongoRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+func alertRuleToDocument(rule *model.AlertRule) AlertRuleMongoDocument {
+	return AlertRuleMongoDocument{
+		APIKeyID:   rule.APIKeyID,
+		Symbol:     rule.Symbol,
+		Condition:  string(rule.Condition),
+		Threshold:  rule.Threshold,
+		Lower:      rule.Lower,
+		Upper:      rule.Upper,
+		ForSeconds: rule.ForSeconds,
+		Enabled:    rule.Enabled,
+		CreatedBy:  rule.CreatedBy,
+		UpdatedBy:  rule.UpdatedBy,
+		CreatedAt:  rule.CreatedAt,
+		UpdatedAt:  rule.UpdatedAt,
+	}
+}
+
+func documentToAlertRule(doc *AlertRuleMongoDocument) *model.AlertRule {
+	return &model.AlertRule{
+		ID:         doc.ID.Hex(),
+		APIKeyID:   doc.APIKeyID,
+		Symbol:     doc.Symbol,
+		Condition:  model.AlertCondition(doc.Condition),
+		Threshold:  doc.Threshold,
+		Lower:      doc.Lower,
+		Upper:      doc.Upper,
+		ForSeconds: doc.ForSeconds,
+		Enabled:    doc.Enabled,
+		CreatedBy:  doc.CreatedBy,
+		UpdatedBy:  doc.UpdatedBy,
+		CreatedAt:  doc.CreatedAt,
+		UpdatedAt:  doc.UpdatedAt,
+	}
+}
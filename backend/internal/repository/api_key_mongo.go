@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"errors"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
@@ -19,13 +21,33 @@ var _ adaptor.APIKeyRepository = (*APIKeyMongoRepository)(nil)
 
 // APIKeyMongoDocument represents the MongoDB document structure
 type APIKeyMongoDocument struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Name      string             `bson:"name"`
-	Platform  string             `bson:"platform"`
-	Enable    bool               `bson:"enable"`
-	Testnet   bool               `bson:"testnet"`
-	APIKey    string             `bson:"api_key"`
-	APISecret string             `bson:"api_secret"`
+	ID        primitive.ObjectID               `bson:"_id,omitempty"`
+	Name      string                           `bson:"name"`
+	Platform  string                           `bson:"platform"`
+	Enable    bool                             `bson:"enable"`
+	Testnet   bool                             `bson:"testnet"`
+	APIKey    string                           `bson:"api_key"`
+	APISecret string                           `bson:"api_secret"`
+	Scopes    []string                         `bson:"scopes,omitempty"`
+	Previous  *RotatedCredentialsMongoDocument `bson:"previous,omitempty"`
+	CreatedAt time.Time                        `bson:"created_at"`
+	UpdatedAt time.Time                        `bson:"updated_at"`
+
+	DefaultSubscriptions []SubscriptionMongoDocument `bson:"default_subscriptions,omitempty"`
+}
+
+// SubscriptionMongoDocument is one stream of an API key's default subscription bundle
+type SubscriptionMongoDocument struct {
+	Type     string `bson:"type"`
+	Symbol   string `bson:"symbol,omitempty"`
+	Interval string `bson:"interval,omitempty"`
+}
+
+// RotatedCredentialsMongoDocument holds encrypted credentials replaced by a rotation
+type RotatedCredentialsMongoDocument struct {
+	APIKey    string    `bson:"api_key"`
+	APISecret string    `bson:"api_secret"`
+	ExpiresAt time.Time `bson:"expires_at"`
 }
 
 type APIKeyMongoRepository struct {
@@ -39,6 +61,7 @@ func NewAPIKeyMongoRepository(db *mongo.Database) *APIKeyMongoRepository {
 }
 
 func (r *APIKeyMongoRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
+	now := time.Now()
 	doc := APIKeyMongoDocument{
 		Name:      apiKey.Name,
 		Platform:  string(apiKey.Platform),
@@ -46,6 +69,11 @@ func (r *APIKeyMongoRepository) Create(ctx context.Context, apiKey *model.APIKey
 		Testnet:   apiKey.IsTestnet,
 		APIKey:    apiKey.APIKey,
 		APISecret: apiKey.APISecret,
+		Scopes:    scopesToStrings(apiKey.Scopes),
+		CreatedAt: now,
+		UpdatedAt: now,
+
+		DefaultSubscriptions: subscriptionsToDocuments(apiKey.DefaultSubscriptions),
 	}
 
 	result, err := r.collection.InsertOne(ctx, doc)
@@ -56,16 +84,16 @@ func (r *APIKeyMongoRepository) Create(ctx context.Context, apiKey *model.APIKey
 	// Set the generated ID back to the model
 	objectID := result.InsertedID.(primitive.ObjectID)
 	apiKey.ID = objectID.Hex()
-	apiKey.CreatedAt = time.Now()
-	apiKey.UpdatedAt = time.Now()
+	apiKey.CreatedAt = now
+	apiKey.UpdatedAt = now
 
 	return nil
 }
 
 func (r *APIKeyMongoRepository) GetByID(ctx context.Context, id string) (*model.APIKey, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return nil, nil // Invalid ID format
+		return nil, err
 	}
 
 	var doc APIKeyMongoDocument
@@ -81,7 +109,8 @@ func (r *APIKeyMongoRepository) GetByID(ctx context.Context, id string) (*model.
 }
 
 func (r *APIKeyMongoRepository) List(ctx context.Context) ([]model.APIKey, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +129,50 @@ func (r *APIKeyMongoRepository) List(ctx context.Context) ([]model.APIKey, error
 	return apiKeys, nil
 }
 
+func (r *APIKeyMongoRepository) Search(ctx context.Context, filter model.APIKeyFilter) ([]model.APIKey, int64, error) {
+	query := bson.M{}
+	if filter.Platform != nil {
+		query["platform"] = string(*filter.Platform)
+	}
+	if filter.IsTestnet != nil {
+		query["testnet"] = *filter.IsTestnet
+	}
+	if filter.IsActive != nil {
+		query["enable"] = *filter.IsActive
+	}
+	if filter.Query != "" {
+		query["name"] = bson.M{"$regex": regexp.QuoteMeta(filter.Query), "$options": "i"}
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(filter.Offset)
+	if filter.Limit > 0 {
+		opts.SetLimit(filter.Limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []APIKeyMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+
+	apiKeys := make([]model.APIKey, 0, len(docs))
+	for _, doc := range docs {
+		apiKeys = append(apiKeys, *documentToAPIKey(&doc))
+	}
+
+	return apiKeys, total, nil
+}
+
 func (r *APIKeyMongoRepository) GetByPlatform(ctx context.Context, platform model.Platform) ([]model.APIKey, error) {
 	cursor, err := r.collection.Find(ctx, bson.M{"platform": string(platform)})
 	if err != nil {
@@ -121,11 +194,12 @@ func (r *APIKeyMongoRepository) GetByPlatform(ctx context.Context, platform mode
 }
 
 func (r *APIKeyMongoRepository) Update(ctx context.Context, apiKey *model.APIKey) error {
-	objectID, err := primitive.ObjectIDFromHex(apiKey.ID)
+	objectID, err := parseObjectID(apiKey.ID)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
 			"name":       apiKey.Name,
@@ -133,6 +207,10 @@ func (r *APIKeyMongoRepository) Update(ctx context.Context, apiKey *model.APIKey
 			"api_secret": apiKey.APISecret,
 			"testnet":    apiKey.IsTestnet,
 			"enable":     apiKey.IsActive,
+			"scopes":     scopesToStrings(apiKey.Scopes),
+			"updated_at": now,
+
+			"default_subscriptions": subscriptionsToDocuments(apiKey.DefaultSubscriptions),
 		},
 	}
 
@@ -141,14 +219,90 @@ func (r *APIKeyMongoRepository) Update(ctx context.Context, apiKey *model.APIKey
 		return err
 	}
 
-	apiKey.UpdatedAt = time.Now()
+	apiKey.UpdatedAt = now
 	return nil
 }
 
+// Rotate swaps the credentials only if the stored api_key still equals
+// expectedAPIKey, so concurrent rotations cannot overwrite each other.
+// It reports whether the document was updated.
+func (r *APIKeyMongoRepository) Rotate(ctx context.Context, apiKey *model.APIKey, expectedAPIKey string) (bool, error) {
+	objectID, err := parseObjectID(apiKey.ID)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"api_key":    apiKey.APIKey,
+		"api_secret": apiKey.APISecret,
+		"updated_at": now,
+	}
+	update := bson.M{"$set": set}
+	if apiKey.Previous != nil {
+		set["previous"] = RotatedCredentialsMongoDocument{
+			APIKey:    apiKey.Previous.APIKey,
+			APISecret: apiKey.Previous.APISecret,
+			ExpiresAt: apiKey.Previous.ExpiresAt,
+		}
+	} else {
+		update["$unset"] = bson.M{"previous": ""}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": objectID, "api_key": expectedAPIKey}, update)
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount == 0 {
+		return false, nil
+	}
+
+	apiKey.UpdatedAt = now
+	return true, nil
+}
+
+// BackfillTimestamps sets created_at/updated_at from the ObjectID timestamp on
+// documents written before those fields existed, returning how many were updated
+func (r *APIKeyMongoRepository) BackfillTimestamps(ctx context.Context) (int64, error) {
+	fromID := bson.M{"$toDate": "$_id"}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"created_at": bson.M{"$ifNull": bson.A{"$created_at", fromID}},
+			"updated_at": bson.M{"$ifNull": bson.A{"$updated_at", fromID}},
+		}}},
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{"$exists": false}},
+		bson.M{"updated_at": bson.M{"$exists": false}},
+	}}
+
+	result, err := r.collection.UpdateMany(ctx, filter, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// BackfillScopes stores the default read-only scopes on documents written
+// before scopes existed, returning how many were updated
+func (r *APIKeyMongoRepository) BackfillScopes(ctx context.Context) (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"scopes": bson.M{"$exists": false}},
+		bson.M{"scopes": bson.A{}},
+	}}
+	update := bson.M{"$set": bson.M{"scopes": scopesToStrings(model.DefaultAPIKeyScopes())}}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
 func (r *APIKeyMongoRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
@@ -180,6 +334,33 @@ func (r *APIKeyMongoRepository) GetActiveByPlatform(ctx context.Context, platfor
 }
 
 func documentToAPIKey(doc *APIKeyMongoDocument) *model.APIKey {
+	// Documents created before scopes existed are read-only until BackfillScopes has run
+	scopes := model.DefaultAPIKeyScopes()
+	if len(doc.Scopes) > 0 {
+		scopes = make([]model.APIKeyScope, 0, len(doc.Scopes))
+		for _, s := range doc.Scopes {
+			scopes = append(scopes, model.APIKeyScope(s))
+		}
+	}
+
+	var previous *model.RotatedCredentials
+	if doc.Previous != nil {
+		previous = &model.RotatedCredentials{
+			APIKey:    doc.Previous.APIKey,
+			APISecret: doc.Previous.APISecret,
+			ExpiresAt: doc.Previous.ExpiresAt,
+		}
+	}
+
+	// Fall back to the ObjectID timestamp until BackfillTimestamps has run
+	createdAt, updatedAt := doc.CreatedAt, doc.UpdatedAt
+	if createdAt.IsZero() {
+		createdAt = doc.ID.Timestamp()
+	}
+	if updatedAt.IsZero() {
+		updatedAt = createdAt
+	}
+
 	return &model.APIKey{
 		ID:        doc.ID.Hex(),
 		Name:      doc.Name,
@@ -188,7 +369,38 @@ func documentToAPIKey(doc *APIKeyMongoDocument) *model.APIKey {
 		APISecret: doc.APISecret,
 		IsTestnet: doc.Testnet,
 		IsActive:  doc.Enable,
-		CreatedAt: doc.ID.Timestamp(),
-		UpdatedAt: doc.ID.Timestamp(),
+		Scopes:    scopes,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+		Previous:  previous,
+
+		DefaultSubscriptions: documentsToSubscriptions(doc.DefaultSubscriptions),
+	}
+}
+
+func subscriptionsToDocuments(subs []model.TradingSubscription) []SubscriptionMongoDocument {
+	docs := make([]SubscriptionMongoDocument, 0, len(subs))
+	for _, sub := range subs {
+		docs = append(docs, SubscriptionMongoDocument{Type: sub.Type, Symbol: sub.Symbol, Interval: sub.Interval})
+	}
+	return docs
+}
+
+func documentsToSubscriptions(docs []SubscriptionMongoDocument) []model.TradingSubscription {
+	if len(docs) == 0 {
+		return nil
+	}
+	subs := make([]model.TradingSubscription, 0, len(docs))
+	for _, doc := range docs {
+		subs = append(subs, model.TradingSubscription{Type: doc.Type, Symbol: doc.Symbol, Interval: doc.Interval})
+	}
+	return subs
+}
+
+func scopesToStrings(scopes []model.APIKeyScope) []string {
+	result := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		result = append(result, string(s))
 	}
+	return result
 }
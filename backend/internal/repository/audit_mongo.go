@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const collectionAuditLog = "audit_log"
+
+var _ adaptor.AuditRepository = (*AuditMongoRepository)(nil)
+
+// AuditMongoDocument represents the MongoDB document structure for audit entries
+type AuditMongoDocument struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	ActorID    string             `bson:"actor_id"`
+	Action     string             `bson:"action"`
+	TargetType string             `bson:"target_type"`
+	TargetID   string             `bson:"target_id"`
+	Details    map[string]any     `bson:"details,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+type AuditMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditMongoRepository(db *mongo.Database) *AuditMongoRepository {
+	return &AuditMongoRepository{
+		collection: db.Collection(collectionAuditLog),
+	}
+}
+
+func (r *AuditMongoRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	now := time.Now()
+	doc := AuditMongoDocument{
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Details:    entry.Details,
+		CreatedAt:  now,
+	}
+
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	entry.CreatedAt = now
+	return nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const (
+	collectionLoginEvent = "login_event"
+
+	// The collection is capped so history never grows without bound
+	loginEventCapBytes = 64 << 20
+	loginEventCapDocs  = 200000
+)
+
+var _ adaptor.LoginEventRepository = (*LoginEventMongoRepository)(nil)
+
+// LoginEventMongoDocument represents the MongoDB document structure for login events
+type LoginEventMongoDocument struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    string             `bson:"user_id,omitempty"`
+	Username  string             `bson:"username"`
+	Success   bool               `bson:"success"`
+	Reason    string             `bson:"reason,omitempty"`
+	IP        string             `bson:"ip"`
+	UserAgent string             `bson:"user_agent"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+type LoginEventMongoRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewLoginEventMongoRepository(db *mongo.Database) *LoginEventMongoRepository {
+	return &LoginEventMongoRepository{
+		db:         db,
+		collection: db.Collection(collectionLoginEvent),
+	}
+}
+
+// EnsureCollection creates the capped collection and its per-user index if missing
+func (r *LoginEventMongoRepository) EnsureCollection(ctx context.Context) error {
+	names, err := r.db.ListCollectionNames(ctx, bson.M{"name": collectionLoginEvent})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		opts := options.CreateCollection().
+			SetCapped(true).
+			SetSizeInBytes(loginEventCapBytes).
+			SetMaxDocuments(loginEventCapDocs)
+		if err := r.db.CreateCollection(ctx, collectionLoginEvent, opts); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+	})
+	return err
+}
+
+func (r *LoginEventMongoRepository) Create(ctx context.Context, event *model.LoginEvent) error {
+	now := time.Now()
+	doc := LoginEventMongoDocument{
+		UserID:    event.UserID,
+		Username:  event.Username,
+		Success:   event.Success,
+		Reason:    event.Reason,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		CreatedAt: now,
+	}
+
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	event.ID = result.InsertedID.(primitive.ObjectID).Hex()
+	event.CreatedAt = now
+	return nil
+}
+
+func (r *LoginEventMongoRepository) ListByUser(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []LoginEventMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	events := make([]model.LoginEvent, 0, len(docs))
+	for _, doc := range docs {
+		events = append(events, model.LoginEvent{
+			ID:        doc.ID.Hex(),
+			UserID:    doc.UserID,
+			Username:  doc.Username,
+			Success:   doc.Success,
+			Reason:    doc.Reason,
+			IP:        doc.IP,
+			UserAgent: doc.UserAgent,
+			CreatedAt: doc.CreatedAt,
+		})
+	}
+
+	return events, nil
+}
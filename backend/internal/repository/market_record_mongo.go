@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const collectionMarketRecord = "market_records"
+
+var _ adaptor.MarketRecordRepository = (*MarketRecordMongoRepository)(nil)
+
+// MarketRecordMongoDocument is one measurement in the market_records time-series collection
+type MarketRecordMongoDocument struct {
+	Time time.Time             `bson:"time"`
+	Meta MarketRecordMongoMeta `bson:"meta"`
+	Data interface{}           `bson:"data"`
+}
+
+// MarketRecordMongoMeta is the time-series metaField grouping records into series
+type MarketRecordMongoMeta struct {
+	Platform string `bson:"platform"`
+	Type     string `bson:"type"`
+	Symbol   string `bson:"symbol"`
+	Interval string `bson:"interval,omitempty"`
+}
+
+type MarketRecordMongoRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewMarketRecordMongoRepository(db *mongo.Database) *MarketRecordMongoRepository {
+	// Decode payloads as bson.M so they serialize back to plain JSON objects
+	collOpts := options.Collection().SetBSONOptions(&options.BSONOptions{DefaultDocumentM: true})
+	return &MarketRecordMongoRepository{
+		db:         db,
+		collection: db.Collection(collectionMarketRecord, collOpts),
+	}
+}
+
+// EnsureCollection creates the time-series collection with the given TTL, or
+// updates the TTL of an existing one so retention changes apply on restart
+func (r *MarketRecordMongoRepository) EnsureCollection(ctx context.Context, retention time.Duration) error {
+	expireAfter := int64(retention / time.Second)
+
+	names, err := r.db.ListCollectionNames(ctx, bson.M{"name": collectionMarketRecord})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return r.db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collectionMarketRecord},
+			{Key: "expireAfterSeconds", Value: expireAfter},
+		}).Err()
+	}
+
+	metaField := "meta"
+	granularity := "seconds"
+	opts := options.CreateCollection().
+		SetTimeSeriesOptions(&options.TimeSeriesOptions{
+			TimeField:   "time",
+			MetaField:   &metaField,
+			Granularity: &granularity,
+		}).
+		SetExpireAfterSeconds(expireAfter)
+	return r.db.CreateCollection(ctx, collectionMarketRecord, opts)
+}
+
+func (r *MarketRecordMongoRepository) InsertMany(ctx context.Context, records []model.MarketRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(records))
+	for i, rec := range records {
+		docs[i] = MarketRecordMongoDocument{
+			Time: rec.Time,
+			Meta: MarketRecordMongoMeta{
+				Platform: rec.Platform,
+				Type:     rec.Type,
+				Symbol:   rec.Symbol,
+				Interval: rec.Interval,
+			},
+			Data: rec.Data,
+		}
+	}
+
+	// Unordered so one bad document does not stop the rest of the batch
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+func (r *MarketRecordMongoRepository) Query(ctx context.Context, query model.MarketRecordQuery) ([]model.MarketRecord, error) {
+	filter := bson.M{"meta.symbol": query.Symbol}
+	if query.Type != "" {
+		filter["meta.type"] = query.Type
+	}
+	timeRange := bson.M{}
+	if !query.From.IsZero() {
+		timeRange["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timeRange["$lte"] = query.To
+	}
+	if len(timeRange) > 0 {
+		filter["time"] = timeRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}})
+	if query.Limit > 0 {
+		opts.SetLimit(int64(query.Limit))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []MarketRecordMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	records := make([]model.MarketRecord, 0, len(docs))
+	for _, doc := range docs {
+		records = append(records, model.MarketRecord{
+			Platform: doc.Meta.Platform,
+			Type:     doc.Meta.Type,
+			Symbol:   doc.Meta.Symbol,
+			Interval: doc.Meta.Interval,
+			Time:     doc.Time,
+			Data:     doc.Data,
+		})
+	}
+
+	return records, nil
+}
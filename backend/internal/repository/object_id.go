@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"control_page/internal/adaptor"
+)
+
+// parseObjectID converts a hex ID, reporting malformed input as
+// adaptor.ErrInvalidID so callers can tell it apart from a missing document
+func parseObjectID(id string) (primitive.ObjectID, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %q", adaptor.ErrInvalidID, id)
+	}
+	return objectID, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+
+	"control_page/internal/adaptor"
+)
+
+// getByID adapts a repository's GetByID, reporting whether it returned a document
+type getByID func(ctx context.Context, db *mongo.Database, id string) (bool, error)
+
+func TestGetByIDContract(t *testing.T) {
+	repos := map[string]getByID{
+		"user": func(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+			v, err := NewUserMongoRepository(db).GetByID(ctx, id)
+			return v != nil, err
+		},
+		"role": func(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+			v, err := NewRoleMongoRepository(db).GetByID(ctx, id)
+			return v != nil, err
+		},
+		"api key": func(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+			v, err := NewAPIKeyMongoRepository(db).GetByID(ctx, id)
+			return v != nil, err
+		},
+		"setting": func(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+			v, err := NewSettingMongoRepository(db).GetByID(ctx, id)
+			return v != nil, err
+		},
+		"switcher": func(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+			v, err := NewSwitcherMongoRepository(db).GetByID(ctx, id)
+			return v != nil, err
+		},
+	}
+
+	id := primitive.NewObjectID()
+	const ns = "test.collection"
+	tests := []struct {
+		name      string
+		id        string
+		responses []bson.D
+		found     bool
+		check     func(err error) bool
+	}{
+		{
+			name:  "malformed id",
+			id:    "not-an-object-id",
+			check: func(err error) bool { return errors.Is(err, adaptor.ErrInvalidID) },
+		},
+		{
+			name:      "missing document",
+			id:        id.Hex(),
+			responses: []bson.D{mtest.CreateCursorResponse(0, ns, mtest.FirstBatch)},
+			check:     func(err error) bool { return err == nil },
+		},
+		{
+			name:      "found document",
+			id:        id.Hex(),
+			responses: []bson.D{mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: id}})},
+			found:     true,
+			check:     func(err error) bool { return err == nil },
+		},
+		{
+			name:      "database error",
+			id:        id.Hex(),
+			responses: []bson.D{mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11600, Name: "InterruptedAtShutdown", Message: "shutting down"})},
+			check:     func(err error) bool { return err != nil && !errors.Is(err, adaptor.ErrInvalidID) },
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	for name, get := range repos {
+		for _, tt := range tests {
+			mt.Run(name+"/"+tt.name, func(mt *mtest.T) {
+				mt.AddMockResponses(tt.responses...)
+				found, err := get(context.Background(), mt.DB, tt.id)
+				if found != tt.found || !tt.check(err) {
+					mt.Fatalf("GetByID(%q) = found %v, error %v", tt.id, found, err)
+				}
+			})
+		}
+	}
+}
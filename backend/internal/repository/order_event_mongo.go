@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const (
+	collectionOrderEvent = "order_event"
+	orderEventTTLIndex   = "received_at_ttl"
+
+	// Mongo error code for an existing index with different options
+	codeIndexOptionsConflict = 85
+)
+
+var _ adaptor.OrderEventRepository = (*OrderEventMongoRepository)(nil)
+
+// OrderEventMongoDocument represents the MongoDB document structure for order events
+type OrderEventMongoDocument struct {
+	ID         primitive.ObjectID      `bson:"_id,omitempty"`
+	APIKeyID   string                  `bson:"api_key_id"`
+	Symbol     string                  `bson:"symbol"` // upper-cased Order.Symbol, for filtering
+	Order      OrderEventMongoOrderDoc `bson:"order"`
+	ReceivedAt time.Time               `bson:"received_at"`
+}
+
+// OrderEventMongoOrderDoc is the normalized order as received from the exchange
+type OrderEventMongoOrderDoc struct {
+	OrderID       string `bson:"order_id"`
+	Symbol        string `bson:"symbol"`
+	Side          string `bson:"side"`
+	Type          string `bson:"type"`
+	Price         string `bson:"price"`
+	Quantity      string `bson:"quantity"`
+	ExecutedQty   string `bson:"executed_qty"`
+	ExecutedQuote string `bson:"executed_quote,omitempty"`
+	Status        string `bson:"status"`
+	TimeInForce   string `bson:"time_in_force,omitempty"`
+	CreateTime    int64  `bson:"create_time,omitempty"`
+	UpdateTime    int64  `bson:"update_time,omitempty"`
+	StopPrice     string `bson:"stop_price,omitempty"`
+	Platform      string `bson:"platform"`
+}
+
+type OrderEventMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOrderEventMongoRepository(db *mongo.Database) *OrderEventMongoRepository {
+	return &OrderEventMongoRepository{
+		collection: db.Collection(collectionOrderEvent),
+	}
+}
+
+// EnsureCollection creates the query index and the TTL index expiring events
+// after retention, updating the TTL of an existing index so retention changes
+// apply on restart
+func (r *OrderEventMongoRepository) EnsureCollection(ctx context.Context, retention time.Duration) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "api_key_id", Value: 1}, {Key: "symbol", Value: 1}, {Key: "received_at", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	expireAfter := int32(retention / time.Second)
+	_, err = r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "received_at", Value: 1}},
+		Options: options.Index().SetName(orderEventTTLIndex).SetExpireAfterSeconds(expireAfter),
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == codeIndexOptionsConflict {
+		return r.collection.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: collectionOrderEvent},
+			{Key: "index", Value: bson.D{
+				{Key: "name", Value: orderEventTTLIndex},
+				{Key: "expireAfterSeconds", Value: expireAfter},
+			}},
+		}).Err()
+	}
+	return err
+}
+
+func (r *OrderEventMongoRepository) InsertMany(ctx context.Context, events []model.OrderEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i, event := range events {
+		o := event.Order
+		docs[i] = OrderEventMongoDocument{
+			APIKeyID: event.APIKeyID,
+			Symbol:   strings.ToUpper(o.Symbol),
+			Order: OrderEventMongoOrderDoc{
+				OrderID:       o.OrderID,
+				Symbol:        o.Symbol,
+				Side:          o.Side.String(),
+				Type:          o.Type.String(),
+				Price:         o.Price,
+				Quantity:      o.Quantity,
+				ExecutedQty:   o.ExecutedQty,
+				ExecutedQuote: o.ExecutedQuote,
+				Status:        o.Status.String(),
+				TimeInForce:   o.TimeInForce.String(),
+				CreateTime:    o.CreateTime,
+				UpdateTime:    o.UpdateTime,
+				StopPrice:     o.StopPrice,
+				Platform:      o.Platform.String(),
+			},
+			ReceivedAt: event.ReceivedAt,
+		}
+	}
+
+	// Unordered so one bad document does not stop the rest of the batch
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	return err
+}
+
+func (r *OrderEventMongoRepository) Query(ctx context.Context, query model.OrderEventQuery) (*model.OrderEventPage, error) {
+	filter := bson.M{"api_key_id": query.APIKeyID}
+	if query.Symbol != "" {
+		filter["symbol"] = query.Symbol
+	}
+	timeRange := bson.M{}
+	if !query.From.IsZero() {
+		timeRange["$gte"] = query.From
+	}
+	if !query.To.IsZero() {
+		timeRange["$lte"] = query.To
+	}
+	if len(timeRange) > 0 {
+		filter["received_at"] = timeRange
+	}
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "received_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(query.Offset)
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []OrderEventMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	items := make([]model.OrderEvent, 0, len(docs))
+	for _, doc := range docs {
+		items = append(items, documentToOrderEvent(doc))
+	}
+
+	return &model.OrderEventPage{Items: items, Total: total}, nil
+}
+
+// Timeline streams the events through an aggregation that keeps only orders
+// with a fill, since unfilled updates cannot affect a report
+func (r *OrderEventMongoRepository) Timeline(ctx context.Context, apiKeyID string, until time.Time) ([]model.OrderEvent, error) {
+	executed := bson.M{"$convert": bson.M{
+		"input": "$order.executed_qty", "to": "double", "onError": 0, "onNull": 0,
+	}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"api_key_id":  apiKeyID,
+			"received_at": bson.M{"$lt": until},
+			"$expr":       bson.M{"$gt": bson.A{executed, 0}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "received_at", Value: 1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var events []model.OrderEvent
+	for cursor.Next(ctx) {
+		var doc OrderEventMongoDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		events = append(events, documentToOrderEvent(doc))
+	}
+	return events, cursor.Err()
+}
+
+func documentToOrderEvent(doc OrderEventMongoDocument) model.OrderEvent {
+	o := doc.Order
+	return model.OrderEvent{
+		ID:       doc.ID.Hex(),
+		APIKeyID: doc.APIKeyID,
+		Order: model.Order{
+			OrderID:       o.OrderID,
+			Symbol:        o.Symbol,
+			Side:          model.OrderSide(o.Side),
+			Type:          model.OrderType(o.Type),
+			Price:         o.Price,
+			Quantity:      o.Quantity,
+			ExecutedQty:   o.ExecutedQty,
+			ExecutedQuote: o.ExecutedQuote,
+			Status:        orderStatusFromDoc(o.Status),
+			TimeInForce:   model.TimeInForce(o.TimeInForce),
+			CreateTime:    o.CreateTime,
+			UpdateTime:    o.UpdateTime,
+			StopPrice:     o.StopPrice,
+			Platform:      model.Platform(o.Platform),
+		},
+		ReceivedAt: doc.ReceivedAt,
+	}
+}
+
+// orderStatusFromDoc maps BTCC's "PLACED", stored before statuses were
+// canonical, to NEW
+func orderStatusFromDoc(status string) model.OrderStatus {
+	if status == "PLACED" {
+		return model.OrderStatusNew
+	}
+	return model.OrderStatus(status)
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"testing"
+
+	"control_page/internal/model"
+)
+
+func TestDocumentToOrderEventCanonicalStatus(t *testing.T) {
+	tests := []struct {
+		stored string
+		want   model.OrderStatus
+	}{
+		{stored: "PLACED", want: model.OrderStatusNew}, // BTCC, before statuses were canonical
+		{stored: "NEW", want: model.OrderStatusNew},
+		{stored: "PARTIALLY_FILLED", want: model.OrderStatusPartiallyFilled},
+		{stored: "FILLED", want: model.OrderStatusFilled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stored, func(t *testing.T) {
+			doc := OrderEventMongoDocument{Order: OrderEventMongoOrderDoc{Side: "BUY", Type: "LIMIT", Status: tt.stored, TimeInForce: "GTC"}}
+			order := documentToOrderEvent(doc).Order
+			if order.Status != tt.want || order.Side != model.OrderSideBuy || order.Type != model.OrderTypeLimit || order.TimeInForce != model.TimeInForceGTC {
+				t.Fatalf("order = %+v, want status %s", order, tt.want)
+			}
+		})
+	}
+}
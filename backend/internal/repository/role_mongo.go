@@ -24,6 +24,7 @@ type RoleMongoDocument struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"`
 	Name        string             `bson:"name"`
 	Description string             `bson:"description"`
+	MaxTokenTTL int64              `bson:"max_token_ttl,omitempty"` // seconds
 	CreatedAt   time.Time          `bson:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at"`
 }
@@ -36,14 +37,18 @@ type RolePermissionMongoDocument struct {
 }
 
 type RoleMongoRepository struct {
+	client               *mongo.Client
 	roleCollection       *mongo.Collection
 	permissionCollection *mongo.Collection
+	userRoleCollection   *mongo.Collection
 }
 
 func NewRoleMongoRepository(db *mongo.Database) *RoleMongoRepository {
 	return &RoleMongoRepository{
+		client:               db.Client(),
 		roleCollection:       db.Collection(collectionRole),
 		permissionCollection: db.Collection(collectionRolePermission),
+		userRoleCollection:   db.Collection(collectionUserRole),
 	}
 }
 
@@ -52,6 +57,7 @@ func (r *RoleMongoRepository) Create(ctx context.Context, role *model.Role) erro
 	doc := RoleMongoDocument{
 		Name:        role.Name,
 		Description: role.Description,
+		MaxTokenTTL: role.MaxTokenTTL,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -70,9 +76,9 @@ func (r *RoleMongoRepository) Create(ctx context.Context, role *model.Role) erro
 }
 
 func (r *RoleMongoRepository) GetByID(ctx context.Context, id string) (*model.Role, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return nil, nil // Invalid ID format
+		return nil, err
 	}
 
 	var doc RoleMongoDocument
@@ -101,17 +107,18 @@ func (r *RoleMongoRepository) GetByName(ctx context.Context, name string) (*mode
 }
 
 func (r *RoleMongoRepository) Update(ctx context.Context, role *model.Role) error {
-	objectID, err := primitive.ObjectIDFromHex(role.ID)
+	objectID, err := parseObjectID(role.ID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	role.UpdatedAt = time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"name":        role.Name,
-			"description": role.Description,
-			"updated_at":  role.UpdatedAt,
+			"name":          role.Name,
+			"description":   role.Description,
+			"max_token_ttl": role.MaxTokenTTL,
+			"updated_at":    role.UpdatedAt,
 		},
 	}
 
@@ -120,22 +127,49 @@ func (r *RoleMongoRepository) Update(ctx context.Context, role *model.Role) erro
 }
 
 func (r *RoleMongoRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
-	// Delete role permissions first
-	_, err = r.permissionCollection.DeleteMany(ctx, bson.M{"role_id": objectID})
+	session, err := r.client.StartSession()
 	if err != nil {
 		return err
 	}
+	defer session.EndSession(ctx)
 
-	// Delete the role
-	_, err = r.roleCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	// Remove user assignments, permissions and the role together so a failure
+	// never leaves users pointing at a half-deleted role
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, r.deleteRole(sc, objectID)
+	})
+	if transactionsUnsupported(err) {
+		// A standalone mongod has no transactions. The role document goes
+		// last, so an interrupted delete leaves a role that can be deleted again.
+		return r.deleteRole(ctx, objectID)
+	}
 	return err
 }
 
+// deleteRole removes the role's user assignments, then its permissions, then the role itself
+func (r *RoleMongoRepository) deleteRole(ctx context.Context, objectID primitive.ObjectID) error {
+	if _, err := r.userRoleCollection.DeleteMany(ctx, bson.M{"role_id": objectID}); err != nil {
+		return err
+	}
+	if _, err := r.permissionCollection.DeleteMany(ctx, bson.M{"role_id": objectID}); err != nil {
+		return err
+	}
+	_, err := r.roleCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
+
+// transactionsUnsupported reports whether err comes from a deployment without
+// transactions, such as a standalone mongod (IllegalOperation, code 20)
+func transactionsUnsupported(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && serverErr.HasErrorCode(20)
+}
+
 func (r *RoleMongoRepository) List(ctx context.Context) ([]model.Role, error) {
 	cursor, err := r.roleCollection.Find(ctx, bson.M{})
 	if err != nil {
@@ -157,9 +191,9 @@ func (r *RoleMongoRepository) List(ctx context.Context) ([]model.Role, error) {
 }
 
 func (r *RoleMongoRepository) GetRolesByUserID(ctx context.Context, userID string) ([]model.Role, error) {
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	userObjectID, err := parseObjectID(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID")
+		return nil, err
 	}
 
 	// First, get user's role IDs from user_role collection
@@ -207,9 +241,9 @@ func (r *RoleMongoRepository) GetRolesByUserID(ctx context.Context, userID strin
 }
 
 func (r *RoleMongoRepository) AddPermission(ctx context.Context, roleID string, permission enum.Permission) error {
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	// Check if permission already exists
@@ -234,9 +268,9 @@ func (r *RoleMongoRepository) AddPermission(ctx context.Context, roleID string,
 }
 
 func (r *RoleMongoRepository) RemovePermission(ctx context.Context, roleID string, permission enum.Permission) error {
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	_, err = r.permissionCollection.DeleteOne(ctx, bson.M{
@@ -247,9 +281,9 @@ func (r *RoleMongoRepository) RemovePermission(ctx context.Context, roleID strin
 }
 
 func (r *RoleMongoRepository) GetPermissions(ctx context.Context, roleID string) ([]enum.Permission, error) {
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return nil, errors.New("invalid role ID")
+		return nil, err
 	}
 
 	cursor, err := r.permissionCollection.Find(ctx, bson.M{"role_id": roleObjectID})
@@ -271,10 +305,46 @@ func (r *RoleMongoRepository) GetPermissions(ctx context.Context, roleID string)
 	return permissions, nil
 }
 
+// GetPermissionsForRoles loads the permissions of several roles in one query.
+// Every requested role has an entry, empty when it has no permissions.
+func (r *RoleMongoRepository) GetPermissionsForRoles(ctx context.Context, roleIDs []string) (map[string][]enum.Permission, error) {
+	result := make(map[string][]enum.Permission, len(roleIDs))
+	objectIDs := make([]primitive.ObjectID, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		objectID, err := parseObjectID(roleID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+		result[roleID] = []enum.Permission{}
+	}
+	if len(objectIDs) == 0 {
+		return result, nil
+	}
+
+	cursor, err := r.permissionCollection.Find(ctx, bson.M{"role_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []RolePermissionMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		roleID := doc.RoleID.Hex()
+		result[roleID] = append(result[roleID], enum.Permission(doc.Permission))
+	}
+
+	return result, nil
+}
+
 func (r *RoleMongoRepository) SetPermissions(ctx context.Context, roleID string, permissions []enum.Permission) error {
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	// Delete existing permissions
@@ -305,6 +375,7 @@ func documentToRole(doc *RoleMongoDocument) *model.Role {
 		ID:          doc.ID.Hex(),
 		Name:        doc.Name,
 		Description: doc.Description,
+		MaxTokenTTL: doc.MaxTokenTTL,
 		CreatedAt:   doc.CreatedAt,
 		UpdatedAt:   doc.UpdatedAt,
 	}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestTransactionsUnsupported(t *testing.T) {
+	standalone := mongo.CommandError{
+		Code:    20,
+		Name:    "IllegalOperation",
+		Message: "Transaction numbers are only allowed on a replica set member or mongos",
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "standalone mongod", err: standalone, want: true},
+		{name: "wrapped standalone mongod", err: fmt.Errorf("delete role: %w", standalone), want: true},
+		{name: "other command error", err: mongo.CommandError{Code: 11600, Name: "InterruptedAtShutdown"}, want: false},
+		{name: "plain error", err: errors.New("connection refused"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transactionsUnsupported(tt.err); got != tt.want {
+				t.Fatalf("transactionsUnsupported(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
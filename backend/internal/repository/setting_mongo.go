@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -23,6 +24,10 @@ type SettingMongoDocument struct {
 	Quote      string                 `bson:"QUOTE"`
 	Strategy   string                 `bson:"STRATEGY"`
 	Parameters bson.M                 `bson:"PARAMETERS"`
+	CreatedBy  string                 `bson:"CREATED_BY,omitempty"`
+	UpdatedBy  string                 `bson:"UPDATED_BY,omitempty"`
+	CreatedAt  *time.Time             `bson:"CREATED_AT,omitempty"`
+	UpdatedAt  *time.Time             `bson:"UPDATED_AT,omitempty"`
 }
 
 type SettingMongoRepository struct {
@@ -56,9 +61,9 @@ func (r *SettingMongoRepository) GetAll(ctx context.Context) ([]model.Setting, e
 }
 
 func (r *SettingMongoRepository) GetByID(ctx context.Context, id string) (*model.Setting, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return nil, nil // Invalid ID format, return nil
+		return nil, err
 	}
 
 	var doc SettingMongoDocument
@@ -92,6 +97,10 @@ func (r *SettingMongoRepository) Create(ctx context.Context, setting *model.Sett
 		Quote:      setting.Quote,
 		Strategy:   setting.Strategy,
 		Parameters: convertParametersToBSON(setting.Parameters),
+		CreatedBy:  setting.CreatedBy,
+		UpdatedBy:  setting.UpdatedBy,
+		CreatedAt:  setting.CreatedAt,
+		UpdatedAt:  setting.UpdatedAt,
 	}
 
 	result, err := r.collection.InsertOne(ctx, doc)
@@ -106,9 +115,9 @@ func (r *SettingMongoRepository) Create(ctx context.Context, setting *model.Sett
 }
 
 func (r *SettingMongoRepository) Update(ctx context.Context, setting *model.Setting) error {
-	objectID, err := primitive.ObjectIDFromHex(setting.MongoID)
+	objectID, err := parseObjectID(setting.MongoID)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	update := bson.M{
@@ -117,6 +126,8 @@ func (r *SettingMongoRepository) Update(ctx context.Context, setting *model.Sett
 			"QUOTE":      setting.Quote,
 			"STRATEGY":   setting.Strategy,
 			"PARAMETERS": convertParametersToBSON(setting.Parameters),
+			"UPDATED_BY": setting.UpdatedBy,
+			"UPDATED_AT": setting.UpdatedAt,
 		},
 	}
 
@@ -124,16 +135,18 @@ func (r *SettingMongoRepository) Update(ctx context.Context, setting *model.Sett
 	return err
 }
 
-func (r *SettingMongoRepository) UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+func (r *SettingMongoRepository) UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}, updatedBy string, updatedAt time.Time) error {
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	// Update only the specific strategy parameters
 	update := bson.M{
 		"$set": bson.M{
 			"PARAMETERS." + strategy: parameters,
+			"UPDATED_BY":             updatedBy,
+			"UPDATED_AT":             updatedAt,
 		},
 	}
 
@@ -142,9 +155,9 @@ func (r *SettingMongoRepository) UpdateParameters(ctx context.Context, id string
 }
 
 func (r *SettingMongoRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
@@ -163,6 +176,10 @@ func documentToSetting(doc *SettingMongoDocument) *model.Setting {
 		Quote:      doc.Quote,
 		Strategy:   doc.Strategy,
 		Parameters: parameters,
+		CreatedBy:  doc.CreatedBy,
+		UpdatedBy:  doc.UpdatedBy,
+		CreatedAt:  doc.CreatedAt,
+		UpdatedAt:  doc.UpdatedAt,
 	}
 }
 
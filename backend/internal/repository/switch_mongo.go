@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,6 +15,15 @@ import (
 
 const collectionSwitcher = "switch"
 
+// Audit fields are stored next to the pair keys; pair keys are always
+// upper-case symbols so these names cannot collide with a pair
+const (
+	switcherFieldCreatedBy = "created_by"
+	switcherFieldUpdatedBy = "updated_by"
+	switcherFieldCreatedAt = "created_at"
+	switcherFieldUpdatedAt = "updated_at"
+)
+
 var _ adaptor.SwitcherRepository = (*SwitcherMongoRepository)(nil)
 
 type SwitcherMongoRepository struct {
@@ -52,9 +62,9 @@ func (r *SwitcherMongoRepository) GetAll(ctx context.Context) ([]model.Switcher,
 }
 
 func (r *SwitcherMongoRepository) GetByID(ctx context.Context, id string) (*model.Switcher, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return nil, nil // Invalid ID format, return nil
+		return nil, err
 	}
 
 	var raw bson.M
@@ -70,7 +80,19 @@ func (r *SwitcherMongoRepository) GetByID(ctx context.Context, id string) (*mode
 }
 
 func (r *SwitcherMongoRepository) Create(ctx context.Context, switcher *model.Switcher) error {
-	doc := bson.M{}
+	doc := bson.M{
+		switcherFieldCreatedBy: switcher.CreatedBy,
+		switcherFieldUpdatedBy: switcher.UpdatedBy,
+	}
+	if switcher.CreatedAt != nil {
+		doc[switcherFieldCreatedAt] = *switcher.CreatedAt
+	}
+	if switcher.UpdatedAt != nil {
+		doc[switcherFieldUpdatedAt] = *switcher.UpdatedAt
+	}
+	for key, value := range switcher.Extra {
+		doc[key] = value
+	}
 	for pair, config := range switcher.Pairs {
 		doc[pair] = bson.M{"enable": config.Enable}
 	}
@@ -87,13 +109,16 @@ func (r *SwitcherMongoRepository) Create(ctx context.Context, switcher *model.Sw
 }
 
 func (r *SwitcherMongoRepository) Update(ctx context.Context, switcher *model.Switcher) error {
-	objectID, err := primitive.ObjectIDFromHex(switcher.MongoID)
+	objectID, err := parseObjectID(switcher.MongoID)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
-	// Build update document
-	update := bson.M{}
+	// Build update document; $set leaves the Extra fields untouched
+	update := bson.M{switcherFieldUpdatedBy: switcher.UpdatedBy}
+	if switcher.UpdatedAt != nil {
+		update[switcherFieldUpdatedAt] = *switcher.UpdatedAt
+	}
 	for pair, config := range switcher.Pairs {
 		update[pair] = bson.M{"enable": config.Enable}
 	}
@@ -106,24 +131,28 @@ func (r *SwitcherMongoRepository) Update(ctx context.Context, switcher *model.Sw
 	return err
 }
 
-func (r *SwitcherMongoRepository) UpdatePair(ctx context.Context, id string, pair string, enable bool) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+func (r *SwitcherMongoRepository) UpdatePair(ctx context.Context, id string, pair string, enable bool, updatedBy string, updatedAt time.Time) error {
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	_, err = r.collection.UpdateOne(
 		ctx,
 		bson.M{"_id": objectID},
-		bson.M{"$set": bson.M{pair: bson.M{"enable": enable}}},
+		bson.M{"$set": bson.M{
+			pair:                   bson.M{"enable": enable},
+			switcherFieldUpdatedBy: updatedBy,
+			switcherFieldUpdatedAt: updatedAt,
+		}},
 	)
 	return err
 }
 
 func (r *SwitcherMongoRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid MongoDB ObjectID")
+		return err
 	}
 
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
@@ -131,27 +160,52 @@ func (r *SwitcherMongoRepository) Delete(ctx context.Context, id string) error {
 }
 
 func documentToSwitcher(raw bson.M) *model.Switcher {
-	switcher := &model.Switcher{
-		Pairs: make(map[string]model.SwitcherPair),
-	}
+	switcher := model.NewSwitcher("", nil)
 
 	for key, value := range raw {
-		if key == "_id" {
+		switch key {
+		case "_id":
 			if oid, ok := value.(primitive.ObjectID); ok {
 				switcher.MongoID = oid.Hex()
 			}
 			continue
+		case switcherFieldCreatedBy:
+			switcher.CreatedBy, _ = value.(string)
+			continue
+		case switcherFieldUpdatedBy:
+			switcher.UpdatedBy, _ = value.(string)
+			continue
+		case switcherFieldCreatedAt:
+			switcher.CreatedAt = bsonTime(value)
+			continue
+		case switcherFieldUpdatedAt:
+			switcher.UpdatedAt = bsonTime(value)
+			continue
 		}
 
-		// Parse trading pair configuration
+		// A pair is an object with a boolean enable; anything else is kept
+		// aside instead of being read as a disabled pair and overwritten
 		if pairConfig, ok := value.(bson.M); ok {
-			pair := model.SwitcherPair{}
 			if enable, ok := pairConfig["enable"].(bool); ok {
-				pair.Enable = enable
+				switcher.Pairs[key] = model.SwitcherPair{Enable: enable}
+				continue
 			}
-			switcher.Pairs[key] = pair
 		}
+		if switcher.Extra == nil {
+			switcher.Extra = make(map[string]interface{})
+		}
+		switcher.Extra[key] = value
 	}
 
 	return switcher
 }
+
+// bsonTime converts a decoded BSON date to a time, returning nil for missing or malformed values
+func bsonTime(value interface{}) *time.Time {
+	dt, ok := value.(primitive.DateTime)
+	if !ok {
+		return nil
+	}
+	t := dt.Time()
+	return &t
+}
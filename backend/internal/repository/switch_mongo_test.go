@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"control_page/internal/model"
+)
+
+func TestDocumentToSwitcherWithoutPairs(t *testing.T) {
+	oid := primitive.NewObjectID()
+	switcher := documentToSwitcher(bson.M{
+		"_id":                  oid,
+		switcherFieldCreatedBy: "admin",
+		"legacy":               "kept aside",
+	})
+
+	if switcher.MongoID != oid.Hex() {
+		t.Fatalf("MongoID = %q, want %q", switcher.MongoID, oid.Hex())
+	}
+	if switcher.Pairs == nil {
+		t.Fatal("Pairs is nil for a document without pairs")
+	}
+	if len(switcher.Pairs) != 0 {
+		t.Fatalf("Pairs = %v, want none", switcher.Pairs)
+	}
+	if switcher.Extra["legacy"] != "kept aside" {
+		t.Fatalf("Extra = %v, want the legacy field", switcher.Extra)
+	}
+
+	// The use cases write into the decoded map directly
+	switcher.Pairs["BTC_USDT"] = model.SwitcherPair{Enable: true}
+}
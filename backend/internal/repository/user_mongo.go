@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
@@ -19,17 +20,40 @@ var _ adaptor.UserRepository = (*UserMongoRepository)(nil)
 
 // UserMongoDocument represents the MongoDB document structure for users
 type UserMongoDocument struct {
-	ID                primitive.ObjectID `bson:"_id,omitempty"`
-	Username          string             `bson:"username"`
-	Password          string             `bson:"password"`
-	IsActive          bool               `bson:"is_active"`
-	TOTPSecret        *string            `bson:"totp_secret,omitempty"`
-	TOTPEnabled       bool               `bson:"totp_enabled"`
-	PendingTOTPSecret *string            `bson:"pending_totp_secret,omitempty"`
-	CreatedAt         time.Time          `bson:"created_at"`
-	UpdatedAt         time.Time          `bson:"updated_at"`
+	ID                 primitive.ObjectID      `bson:"_id,omitempty"`
+	Username           string                  `bson:"username"`
+	Email              string                  `bson:"email,omitempty"`
+	Password           string                  `bson:"password"`
+	IsActive           bool                    `bson:"is_active"`
+	TOTPSecret         *string                 `bson:"totp_secret,omitempty"`
+	TOTPEnabled        bool                    `bson:"totp_enabled"`
+	PendingTOTPSecret  *string                 `bson:"pending_totp_secret,omitempty"`
+	FailedAttempts     int                     `bson:"failed_attempts"`
+	LockedUntil        *time.Time              `bson:"locked_until,omitempty"`
+	PasswordChangedAt  *time.Time              `bson:"password_changed_at,omitempty"`
+	MustChangePassword bool                    `bson:"must_change_password,omitempty"`
+	IPAllowlist        []string                `bson:"ip_allowlist,omitempty"`
+	SessionsRevokedAt  *time.Time              `bson:"sessions_revoked_at,omitempty"`
+	TrustedDevices     []TrustedDeviceDocument `bson:"trusted_devices,omitempty"`
+	LastLoginAt        *time.Time              `bson:"last_login_at,omitempty"`
+	CreatedAt          time.Time               `bson:"created_at"`
+	UpdatedAt          time.Time               `bson:"updated_at"`
 }
 
+// TrustedDeviceDocument is one remembered device embedded in a user document
+type TrustedDeviceDocument struct {
+	ID         string     `bson:"id"`
+	IP         string     `bson:"ip"`
+	UserAgent  string     `bson:"user_agent"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	ExpiresAt  time.Time  `bson:"expires_at"`
+	LastUsedAt *time.Time `bson:"last_used_at,omitempty"`
+}
+
+// maxTrustedDevices caps the remembered devices kept per user; adding one
+// beyond it drops the oldest
+const maxTrustedDevices = 20
+
 type UserMongoRepository struct {
 	collection *mongo.Collection
 }
@@ -40,10 +64,32 @@ func NewUserMongoRepository(db *mongo.Database) *UserMongoRepository {
 	}
 }
 
+// EnsureIndexes creates the unique email index. Users without an email store
+// none or an empty one, so only non-empty emails take part.
+func (r *UserMongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+	})
+	return err
+}
+
+// duplicateEmail maps a unique index violation to adaptor.ErrDuplicateEmail;
+// email is the only unique field besides _id
+func duplicateEmail(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return adaptor.ErrDuplicateEmail
+	}
+	return err
+}
+
 func (r *UserMongoRepository) Create(ctx context.Context, user *model.User) error {
 	now := time.Now()
 	doc := UserMongoDocument{
 		Username:          user.Username,
+		Email:             user.Email,
 		Password:          user.Password,
 		IsActive:          user.IsActive,
 		TOTPSecret:        user.TOTPSecret,
@@ -55,7 +101,7 @@ func (r *UserMongoRepository) Create(ctx context.Context, user *model.User) erro
 
 	result, err := r.collection.InsertOne(ctx, doc)
 	if err != nil {
-		return err
+		return duplicateEmail(err)
 	}
 
 	objectID := result.InsertedID.(primitive.ObjectID)
@@ -67,9 +113,9 @@ func (r *UserMongoRepository) Create(ctx context.Context, user *model.User) erro
 }
 
 func (r *UserMongoRepository) GetByID(ctx context.Context, id string) (*model.User, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return nil, nil // Invalid ID format
+		return nil, err
 	}
 
 	var doc UserMongoDocument
@@ -97,29 +143,43 @@ func (r *UserMongoRepository) GetByUsername(ctx context.Context, username string
 	return documentToUser(&doc), nil
 }
 
+func (r *UserMongoRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	var doc UserMongoDocument
+	err := r.collection.FindOne(ctx, bson.M{"email": email}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return documentToUser(&doc), nil
+}
+
 func (r *UserMongoRepository) Update(ctx context.Context, user *model.User) error {
-	objectID, err := primitive.ObjectIDFromHex(user.ID)
+	objectID, err := parseObjectID(user.ID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	user.UpdatedAt = time.Now()
 	update := bson.M{
 		"$set": bson.M{
 			"username":   user.Username,
+			"email":      user.Email,
 			"is_active":  user.IsActive,
 			"updated_at": user.UpdatedAt,
 		},
 	}
 
 	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
-	return err
+	return duplicateEmail(err)
 }
 
 func (r *UserMongoRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
@@ -147,16 +207,44 @@ func (r *UserMongoRepository) List(ctx context.Context) ([]model.User, error) {
 }
 
 func (r *UserMongoRepository) UpdatePassword(ctx context.Context, id string, hashedPassword string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
+	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"password":   hashedPassword,
-			"updated_at": time.Now(),
+			"password":            hashedPassword,
+			"password_changed_at": now,
+			"updated_at":          now,
 		},
+		"$unset": bson.M{"must_change_password": ""},
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// SetTemporaryPassword replaces the password with an admin-issued one that
+// must be changed at the next login, clears any lockout and revokes the
+// sessions issued so far
+func (r *UserMongoRepository) SetTemporaryPassword(ctx context.Context, id string, hashedPassword string) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"password":             hashedPassword,
+			"must_change_password": true,
+			"failed_attempts":      0,
+			"sessions_revoked_at":  now,
+			"updated_at":           now,
+		},
+		"$unset": bson.M{"locked_until": ""},
 	}
 
 	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
@@ -164,9 +252,9 @@ func (r *UserMongoRepository) UpdatePassword(ctx context.Context, id string, has
 }
 
 func (r *UserMongoRepository) UpdateUsername(ctx context.Context, id string, username string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -181,9 +269,9 @@ func (r *UserMongoRepository) UpdateUsername(ctx context.Context, id string, use
 }
 
 func (r *UserMongoRepository) UpdateRegistration(ctx context.Context, id string, hashedPassword, totpSecret string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -199,9 +287,9 @@ func (r *UserMongoRepository) UpdateRegistration(ctx context.Context, id string,
 }
 
 func (r *UserMongoRepository) SetTOTPSecret(ctx context.Context, id string, secret string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -216,9 +304,9 @@ func (r *UserMongoRepository) SetTOTPSecret(ctx context.Context, id string, secr
 }
 
 func (r *UserMongoRepository) EnableTOTP(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -233,9 +321,9 @@ func (r *UserMongoRepository) EnableTOTP(ctx context.Context, id string) error {
 }
 
 func (r *UserMongoRepository) Activate(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -250,9 +338,9 @@ func (r *UserMongoRepository) Activate(ctx context.Context, id string) error {
 }
 
 func (r *UserMongoRepository) SetPendingTOTPSecret(ctx context.Context, id string, secret string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -267,9 +355,9 @@ func (r *UserMongoRepository) SetPendingTOTPSecret(ctx context.Context, id strin
 }
 
 func (r *UserMongoRepository) ConfirmTOTPRebind(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	// First, get the pending TOTP secret
@@ -294,9 +382,9 @@ func (r *UserMongoRepository) ConfirmTOTPRebind(ctx context.Context, id string)
 }
 
 func (r *UserMongoRepository) ClearPendingTOTPSecret(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	objectID, err := parseObjectID(id)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
 	update := bson.M{
@@ -312,16 +400,183 @@ func (r *UserMongoRepository) ClearPendingTOTPSecret(ctx context.Context, id str
 	return err
 }
 
+// IncrementFailedAttempts atomically bumps the failed login counter and returns the new value
+func (r *UserMongoRepository) IncrementFailedAttempts(ctx context.Context, id string) (int, error) {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return 0, err
+	}
+
+	var doc UserMongoDocument
+	err = r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": objectID},
+		bson.M{"$inc": bson.M{"failed_attempts": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc)
+	if err != nil {
+		return 0, err
+	}
+
+	return doc.FailedAttempts, nil
+}
+
+// LockUntil locks the account until the given time and restarts the failed
+// login counter, so once the lock expires a full new series of failures is
+// needed to lock it again
+func (r *UserMongoRepository) LockUntil(ctx context.Context, id string, until time.Time) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"locked_until":    until,
+			"failed_attempts": 0,
+			"updated_at":      time.Now(),
+		},
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// ResetLockout clears the failed login counter and any active lock
+func (r *UserMongoRepository) ResetLockout(ctx context.Context, id string) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"failed_attempts": 0},
+		"$unset": bson.M{"locked_until": ""},
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// SetIPAllowlist replaces the CIDR ranges the user may connect from; an empty
+// list removes the restriction
+func (r *UserMongoRepository) SetIPAllowlist(ctx context.Context, id string, cidrs []string) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{"ip_allowlist": cidrs, "updated_at": time.Now()}}
+	if len(cidrs) == 0 {
+		update = bson.M{"$set": bson.M{"updated_at": time.Now()}, "$unset": bson.M{"ip_allowlist": ""}}
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// AddTrustedDevice remembers a device for the user, keeping at most
+// maxTrustedDevices of the most recent ones
+func (r *UserMongoRepository) AddTrustedDevice(ctx context.Context, id string, device model.TrustedDevice) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	doc := TrustedDeviceDocument{
+		ID:         device.ID,
+		IP:         device.IP,
+		UserAgent:  device.UserAgent,
+		CreatedAt:  device.CreatedAt,
+		ExpiresAt:  device.ExpiresAt,
+		LastUsedAt: device.LastUsedAt,
+	}
+	update := bson.M{"$push": bson.M{"trusted_devices": bson.M{"$each": bson.A{doc}, "$slice": -maxTrustedDevices}}}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// TouchTrustedDevice records when a remembered device was last used to log in
+func (r *UserMongoRepository) TouchTrustedDevice(ctx context.Context, id string, deviceID string, at time.Time) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": objectID, "trusted_devices.id": deviceID}
+	_, err = r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"trusted_devices.$.last_used_at": at}})
+	return err
+}
+
+// RemoveTrustedDevice forgets a remembered device, reporting whether the user had it
+func (r *UserMongoRepository) RemoveTrustedDevice(ctx context.Context, id string, deviceID string) (bool, error) {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return false, err
+	}
+
+	update := bson.M{"$pull": bson.M{"trusted_devices": bson.M{"id": deviceID}}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ClearTrustedDevices forgets every remembered device of the user
+func (r *UserMongoRepository) ClearTrustedDevices(ctx context.Context, id string) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$unset": bson.M{"trusted_devices": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	return err
+}
+
+// RecordLogin stores the time of the user's last completed login
+func (r *UserMongoRepository) RecordLogin(ctx context.Context, id string, at time.Time) error {
+	objectID, err := parseObjectID(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"last_login_at": at}})
+	return err
+}
+
 func documentToUser(doc *UserMongoDocument) *model.User {
+	var devices []model.TrustedDevice
+	for _, d := range doc.TrustedDevices {
+		devices = append(devices, model.TrustedDevice{
+			ID:         d.ID,
+			IP:         d.IP,
+			UserAgent:  d.UserAgent,
+			CreatedAt:  d.CreatedAt,
+			ExpiresAt:  d.ExpiresAt,
+			LastUsedAt: d.LastUsedAt,
+		})
+	}
+
 	return &model.User{
-		ID:                doc.ID.Hex(),
-		Username:          doc.Username,
-		Password:          doc.Password,
-		IsActive:          doc.IsActive,
-		TOTPSecret:        doc.TOTPSecret,
-		TOTPEnabled:       doc.TOTPEnabled,
-		PendingTOTPSecret: doc.PendingTOTPSecret,
-		CreatedAt:         doc.CreatedAt,
-		UpdatedAt:         doc.UpdatedAt,
+		ID:                 doc.ID.Hex(),
+		Username:           doc.Username,
+		Email:              doc.Email,
+		Password:           doc.Password,
+		IsActive:           doc.IsActive,
+		TOTPSecret:         doc.TOTPSecret,
+		TOTPEnabled:        doc.TOTPEnabled,
+		PendingTOTPSecret:  doc.PendingTOTPSecret,
+		FailedAttempts:     doc.FailedAttempts,
+		LockedUntil:        doc.LockedUntil,
+		PasswordChangedAt:  doc.PasswordChangedAt,
+		MustChangePassword: doc.MustChangePassword,
+		IPAllowlist:        doc.IPAllowlist,
+		SessionsRevokedAt:  doc.SessionsRevokedAt,
+		TrustedDevices:     devices,
+		LastLoginAt:        doc.LastLoginAt,
+		CreatedAt:          doc.CreatedAt,
+		UpdatedAt:          doc.UpdatedAt,
 	}
 }
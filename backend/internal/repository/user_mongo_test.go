@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"control_page/internal/adaptor"
+)
+
+func TestDuplicateEmail(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: `E11000 duplicate key error collection: users index: email_1 dup key: { email: "alice@example.com" }`,
+	}}}
+	other := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 121, Message: "Document failed validation"}}}
+	plain := errors.New("connection refused")
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unique index violation", err: duplicate, want: true},
+		{name: "other write error", err: other, want: false},
+		{name: "plain error", err: plain, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := duplicateEmail(tt.err)
+			if errors.Is(got, adaptor.ErrDuplicateEmail) != tt.want {
+				t.Fatalf("duplicateEmail(%v) = %v, want ErrDuplicateEmail %v", tt.err, got, tt.want)
+			}
+			if !tt.want && got.Error() != tt.err.Error() {
+				t.Fatalf("duplicateEmail(%v) = %v, want the error unchanged", tt.err, got)
+			}
+		})
+	}
+	if err := duplicateEmail(nil); err != nil {
+		t.Fatalf("duplicateEmail(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const collectionUserPreferences = "user_preferences"
+
+var _ adaptor.UserPreferencesRepository = (*UserPreferencesMongoRepository)(nil)
+
+// UserPreferencesMongoDocument represents the MongoDB document structure for
+// user preferences. The blob is stored as a JSON string so it round-trips
+// byte for byte.
+type UserPreferencesMongoDocument struct {
+	UserID    string    `bson:"_id"`
+	Data      string    `bson:"data"`
+	Version   int64     `bson:"version"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+type UserPreferencesMongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUserPreferencesMongoRepository(db *mongo.Database) *UserPreferencesMongoRepository {
+	return &UserPreferencesMongoRepository{
+		collection: db.Collection(collectionUserPreferences),
+	}
+}
+
+// Get returns the user's preferences, or nil if they never saved any
+func (r *UserPreferencesMongoRepository) Get(ctx context.Context, userID string) (*model.UserPreferences, error) {
+	var doc UserPreferencesMongoDocument
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &model.UserPreferences{
+		UserID:    doc.UserID,
+		Data:      json.RawMessage(doc.Data),
+		Version:   doc.Version,
+		UpdatedAt: &doc.UpdatedAt,
+	}, nil
+}
+
+// Save writes prefs if the stored version still equals prefs.Version (0 for
+// no document yet) and bumps the version. It returns false without writing
+// when another save got there first.
+func (r *UserPreferencesMongoRepository) Save(ctx context.Context, prefs *model.UserPreferences) (bool, error) {
+	now := time.Now()
+	next := prefs.Version + 1
+
+	if prefs.Version == 0 {
+		_, err := r.collection.InsertOne(ctx, UserPreferencesMongoDocument{
+			UserID:    prefs.UserID,
+			Data:      string(prefs.Data),
+			Version:   next,
+			UpdatedAt: now,
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	} else {
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": prefs.UserID, "version": prefs.Version},
+			bson.M{"$set": bson.M{
+				"data":       string(prefs.Data),
+				"version":    next,
+				"updated_at": now,
+			}},
+		)
+		if err != nil {
+			return false, err
+		}
+		if result.MatchedCount == 0 {
+			return false, nil
+		}
+	}
+
+	prefs.Version = next
+	prefs.UpdatedAt = &now
+	return true, nil
+}
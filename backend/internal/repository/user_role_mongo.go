@@ -2,13 +2,13 @@ package repository
 
 import (
 	"context"
-	"errors"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"control_page/internal/adaptor"
+	"control_page/internal/model"
 	"control_page/internal/model/enum"
 )
 
@@ -36,14 +36,14 @@ func NewUserRoleMongoRepository(db *mongo.Database) *UserRoleMongoRepository {
 }
 
 func (r *UserRoleMongoRepository) AssignRole(ctx context.Context, userID, roleID string) error {
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	userObjectID, err := parseObjectID(userID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	// Check if assignment already exists
@@ -68,14 +68,14 @@ func (r *UserRoleMongoRepository) AssignRole(ctx context.Context, userID, roleID
 }
 
 func (r *UserRoleMongoRepository) RemoveRole(ctx context.Context, userID, roleID string) error {
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	userObjectID, err := parseObjectID(userID)
 	if err != nil {
-		return errors.New("invalid user ID")
+		return err
 	}
 
-	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	roleObjectID, err := parseObjectID(roleID)
 	if err != nil {
-		return errors.New("invalid role ID")
+		return err
 	}
 
 	_, err = r.userRoleCollection.DeleteOne(ctx, bson.M{
@@ -86,9 +86,9 @@ func (r *UserRoleMongoRepository) RemoveRole(ctx context.Context, userID, roleID
 }
 
 func (r *UserRoleMongoRepository) GetUserPermissions(ctx context.Context, userID string) ([]enum.Permission, error) {
-	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	userObjectID, err := parseObjectID(userID)
 	if err != nil {
-		return nil, errors.New("invalid user ID")
+		return nil, err
 	}
 
 	// Use aggregation pipeline to get distinct permissions for user's roles
@@ -130,3 +130,78 @@ func (r *UserRoleMongoRepository) GetUserPermissions(ctx context.Context, userID
 
 	return permissions, nil
 }
+
+// GetRoleIDsForUsers returns the assigned role IDs of several users in one query
+func (r *UserRoleMongoRepository) GetRoleIDsForUsers(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(userIDs))
+	objectIDs := make([]primitive.ObjectID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		objectID, err := parseObjectID(userID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	if len(objectIDs) == 0 {
+		return result, nil
+	}
+
+	cursor, err := r.userRoleCollection.Find(ctx, bson.M{"user_id": bson.M{"$in": objectIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []UserRoleMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		userID := doc.UserID.Hex()
+		result[userID] = append(result[userID], doc.RoleID.Hex())
+	}
+
+	return result, nil
+}
+
+func (r *UserRoleMongoRepository) GetUsersByRoleID(ctx context.Context, roleID string) ([]model.User, error) {
+	roleObjectID, err := parseObjectID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		// Match assignments of the role
+		{{Key: "$match", Value: bson.M{"role_id": roleObjectID}}},
+		// Lookup the assigned user
+		{{Key: "$lookup", Value: bson.M{
+			"from":         collectionUser,
+			"localField":   "user_id",
+			"foreignField": "_id",
+			"as":           "user",
+		}}},
+		// Drop assignments whose user no longer exists
+		{{Key: "$unwind", Value: bson.M{"path": "$user"}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$user"}}},
+		{{Key: "$sort", Value: bson.M{"username": 1}}},
+	}
+
+	cursor, err := r.userRoleCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []UserMongoDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	users := make([]model.User, 0, len(docs))
+	for _, doc := range docs {
+		users = append(users, *documentToUser(&doc))
+	}
+
+	return users, nil
+}
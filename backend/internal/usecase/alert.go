@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var (
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	ErrInvalidAlertRule  = errors.New("invalid alert rule")
+)
+
+const (
+	alertWebhookTimeout   = 5 * time.Second
+	maxAlertRuleForPeriod = 24 * 60 * 60 // seconds
+)
+
+var _ adaptor.AlertUseCase = (*AlertUseCase)(nil)
+
+// alertState tracks one rule between observations
+type alertState struct {
+	firing bool
+	// pendingSince is when the observed condition started to differ from
+	// firing; the rule flips once that has lasted ForSeconds
+	pendingSince time.Time
+}
+
+// AlertUseCase manages alert rules and evaluates them against observations
+// from the trading stream. Rules are kept in memory so Evaluate can run on the
+// stream's read path; webhooks are posted from their own goroutines.
+type AlertUseCase struct {
+	alertRepo  adaptor.AlertRuleRepository
+	apiKeyRepo adaptor.APIKeyRepository
+	webhookURL string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	rules  map[string]model.AlertRule // rule ID -> rule
+	states map[string]*alertState     // rule ID -> state
+}
+
+func NewAlertUseCase(alertRepo adaptor.AlertRuleRepository, apiKeyRepo adaptor.APIKeyRepository, webhookURL string) *AlertUseCase {
+	return &AlertUseCase{
+		alertRepo:  alertRepo,
+		apiKeyRepo: apiKeyRepo,
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: alertWebhookTimeout},
+		rules:      make(map[string]model.AlertRule),
+		states:     make(map[string]*alertState),
+	}
+}
+
+// Load reads the stored rules into memory; call once at startup
+func (uc *AlertUseCase) Load(ctx context.Context) error {
+	rules, err := uc.alertRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	for _, rule := range rules {
+		uc.rules[rule.ID] = rule
+	}
+	return nil
+}
+
+func (uc *AlertUseCase) List(ctx context.Context) ([]model.AlertRule, error) {
+	return uc.alertRepo.GetAll(ctx)
+}
+
+func (uc *AlertUseCase) GetByID(ctx context.Context, id string) (*model.AlertRule, error) {
+	rule, err := uc.alertRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+func (uc *AlertUseCase) Create(ctx context.Context, actorID string, req *model.AlertRuleRequest) (*model.AlertRule, error) {
+	rule, err := uc.ruleFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.CreatedBy = actorID
+	rule.UpdatedBy = actorID
+	rule.CreatedAt = &now
+	rule.UpdatedAt = &now
+
+	if err := uc.alertRepo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	uc.setRule(*rule)
+	return rule, nil
+}
+
+func (uc *AlertUseCase) Update(ctx context.Context, actorID, id string, req *model.AlertRuleRequest) (*model.AlertRule, error) {
+	existing, err := uc.alertRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrAlertRuleNotFound
+	}
+
+	rule, err := uc.ruleFromRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.ID = existing.ID
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedBy = actorID
+	rule.UpdatedAt = &now
+
+	if err := uc.alertRepo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	uc.setRule(*rule)
+	return rule, nil
+}
+
+func (uc *AlertUseCase) Delete(ctx context.Context, id string) error {
+	existing, err := uc.alertRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrAlertRuleNotFound
+	}
+
+	if err := uc.alertRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	delete(uc.rules, id)
+	delete(uc.states, id)
+	uc.mu.Unlock()
+	return nil
+}
+
+// setRule installs a created or edited rule and restarts its evaluation
+func (uc *AlertUseCase) setRule(rule model.AlertRule) {
+	uc.mu.Lock()
+	uc.rules[rule.ID] = rule
+	delete(uc.states, rule.ID)
+	uc.mu.Unlock()
+}
+
+func (uc *AlertUseCase) ruleFromRequest(ctx context.Context, req *model.AlertRuleRequest) (*model.AlertRule, error) {
+	rule := &model.AlertRule{
+		APIKeyID:   strings.TrimSpace(req.APIKeyID),
+		Symbol:     strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Condition:  req.Condition,
+		ForSeconds: req.ForSeconds,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+
+	if rule.Symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidAlertRule)
+	}
+	if rule.ForSeconds < 0 || rule.ForSeconds > maxAlertRuleForPeriod {
+		return nil, fmt.Errorf("%w: for_seconds must be between 0 and %d", ErrInvalidAlertRule, maxAlertRuleForPeriod)
+	}
+	switch rule.Condition {
+	case model.AlertConditionSpreadAbove:
+		if req.Threshold <= 0 {
+			return nil, fmt.Errorf("%w: threshold must be positive", ErrInvalidAlertRule)
+		}
+		rule.Threshold = req.Threshold
+	case model.AlertConditionPriceOutside:
+		if req.Lower <= 0 || req.Upper <= req.Lower {
+			return nil, fmt.Errorf("%w: lower must be positive and below upper", ErrInvalidAlertRule)
+		}
+		rule.Lower = req.Lower
+		rule.Upper = req.Upper
+	default:
+		return nil, fmt.Errorf("%w: condition must be %s or %s", ErrInvalidAlertRule, model.AlertConditionSpreadAbove, model.AlertConditionPriceOutside)
+	}
+
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, rule.APIKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return rule, nil
+}
+
+func (uc *AlertUseCase) Evaluate(apiKeyID, symbol string, condition model.AlertCondition, value float64, at time.Time) []model.Alert {
+	symbol = strings.ToUpper(symbol)
+
+	uc.mu.Lock()
+	var alerts []model.Alert
+	for id, rule := range uc.rules {
+		if !rule.Enabled || rule.APIKeyID != apiKeyID || rule.Symbol != symbol || rule.Condition != condition {
+			continue
+		}
+
+		state := uc.states[id]
+		if state == nil {
+			state = &alertState{}
+			uc.states[id] = state
+		}
+		if !state.observe(rule.Breached(value), time.Duration(rule.ForSeconds)*time.Second, at) {
+			continue
+		}
+
+		alert := model.Alert{
+			RuleID:    rule.ID,
+			APIKeyID:  rule.APIKeyID,
+			Symbol:    rule.Symbol,
+			Condition: rule.Condition,
+			State:     model.AlertStateRecovered,
+			Value:     value,
+			Threshold: rule.Threshold,
+			Lower:     rule.Lower,
+			Upper:     rule.Upper,
+			Time:      at,
+		}
+		if state.firing {
+			alert.State = model.AlertStateTriggered
+		}
+		alerts = append(alerts, alert)
+	}
+	uc.mu.Unlock()
+
+	for _, alert := range alerts {
+		log.Printf("Alert %s: rule %s %s %s value=%g", alert.State, alert.RuleID, alert.Symbol, alert.Condition, alert.Value)
+		if uc.webhookURL != "" {
+			go uc.postWebhook(alert)
+		}
+	}
+	return alerts
+}
+
+// observe applies one observation and reports whether the rule flipped. The
+// condition has to hold (or stay clear) for the whole period before the rule
+// triggers (or recovers), so a value hovering at the threshold does not flap.
+func (s *alertState) observe(breached bool, period time.Duration, at time.Time) bool {
+	if breached == s.firing {
+		s.pendingSince = time.Time{}
+		return false
+	}
+	if s.pendingSince.IsZero() {
+		s.pendingSince = at
+	}
+	if at.Sub(s.pendingSince) < period {
+		return false
+	}
+
+	s.firing = breached
+	s.pendingSince = time.Time{}
+	return true
+}
+
+func (uc *AlertUseCase) postWebhook(alert model.Alert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+
+	resp, err := uc.httpClient.Post(uc.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Warning: alert webhook failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		log.Printf("Warning: alert webhook returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"control_page/internal/model"
+)
+
+// newTestAlertUseCase evaluates rules without a repository behind them
+func newTestAlertUseCase(webhookURL string, rules ...model.AlertRule) *AlertUseCase {
+	uc := NewAlertUseCase(nil, nil, webhookURL)
+	for _, rule := range rules {
+		uc.setRule(rule)
+	}
+	return uc
+}
+
+func spreadRule(forSeconds int) model.AlertRule {
+	return model.AlertRule{
+		ID:         "rule-1",
+		APIKeyID:   "key-1",
+		Symbol:     "BTCUSDT",
+		Condition:  model.AlertConditionSpreadAbove,
+		Threshold:  10,
+		ForSeconds: forSeconds,
+		Enabled:    true,
+	}
+}
+
+func TestAlertHysteresis(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	type observation struct {
+		after time.Duration
+		value float64
+		want  string // alert state expected from this observation, "" for none
+	}
+
+	tests := []struct {
+		name         string
+		forSeconds   int
+		observations []observation
+	}{
+		{
+			name:       "immediate rule flips on each change",
+			forSeconds: 0,
+			observations: []observation{
+				{0, 5, ""},
+				{time.Second, 11, model.AlertStateTriggered},
+				{2 * time.Second, 12, ""},
+				{3 * time.Second, 5, model.AlertStateRecovered},
+				{4 * time.Second, 5, ""},
+			},
+		},
+		{
+			name:       "triggers once the breach held for the period",
+			forSeconds: 5,
+			observations: []observation{
+				{0, 11, ""},
+				{4 * time.Second, 12, ""},
+				{5 * time.Second, 11, model.AlertStateTriggered},
+				{6 * time.Second, 15, ""},
+			},
+		},
+		{
+			name:       "a short breach does not trigger",
+			forSeconds: 5,
+			observations: []observation{
+				{0, 11, ""},
+				{3 * time.Second, 5, ""},
+				{6 * time.Second, 11, ""},
+				{10 * time.Second, 11, ""},
+				{11 * time.Second, 11, model.AlertStateTriggered},
+			},
+		},
+		{
+			name:       "the threshold itself is not a breach",
+			forSeconds: 0,
+			observations: []observation{
+				{0, 10, ""},
+				{time.Second, 10, ""},
+			},
+		},
+		{
+			name:       "recovers once clear for the period",
+			forSeconds: 5,
+			observations: []observation{
+				{0, 11, ""},
+				{5 * time.Second, 11, model.AlertStateTriggered},
+				{6 * time.Second, 5, ""},
+				{8 * time.Second, 11, ""},
+				{9 * time.Second, 5, ""},
+				{13 * time.Second, 5, ""},
+				{14 * time.Second, 5, model.AlertStateRecovered},
+				{30 * time.Second, 5, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestAlertUseCase("", spreadRule(tt.forSeconds))
+			for _, obs := range tt.observations {
+				alerts := uc.Evaluate("key-1", "btcusdt", model.AlertConditionSpreadAbove, obs.value, start.Add(obs.after))
+				if obs.want == "" {
+					if len(alerts) != 0 {
+						t.Fatalf("at +%s value %g: got %+v, want no alert", obs.after, obs.value, alerts)
+					}
+					continue
+				}
+				if len(alerts) != 1 || alerts[0].State != obs.want {
+					t.Fatalf("at +%s value %g: got %+v, want one %s alert", obs.after, obs.value, alerts, obs.want)
+				}
+				if alerts[0].Value != obs.value || !alerts[0].Time.Equal(start.Add(obs.after)) {
+					t.Fatalf("alert = %+v, want value %g at +%s", alerts[0], obs.value, obs.after)
+				}
+			}
+		})
+	}
+}
+
+func TestAlertPriceBand(t *testing.T) {
+	rule := model.AlertRule{
+		ID:        "band",
+		APIKeyID:  "key-1",
+		Symbol:    "BTCUSDT",
+		Condition: model.AlertConditionPriceOutside,
+		Lower:     100,
+		Upper:     200,
+		Enabled:   true,
+	}
+	uc := newTestAlertUseCase("", rule)
+	at := time.Now()
+
+	for _, step := range []struct {
+		price float64
+		want  string
+	}{
+		{150, ""},
+		{100, ""},
+		{99, model.AlertStateTriggered},
+		{201, ""},
+		{200, model.AlertStateRecovered},
+		{250, model.AlertStateTriggered},
+	} {
+		at = at.Add(time.Second)
+		alerts := uc.Evaluate("key-1", "BTCUSDT", model.AlertConditionPriceOutside, step.price, at)
+		got := ""
+		if len(alerts) > 0 {
+			got = alerts[0].State
+		}
+		if len(alerts) > 1 || got != step.want {
+			t.Fatalf("price %g: got %+v, want %q", step.price, alerts, step.want)
+		}
+	}
+}
+
+func TestAlertEvaluateMatchesOnlyItsRules(t *testing.T) {
+	disabled := spreadRule(0)
+	disabled.ID = "disabled"
+	disabled.Enabled = false
+	uc := newTestAlertUseCase("", spreadRule(0), disabled)
+	at := time.Now()
+
+	for _, other := range []struct {
+		apiKeyID, symbol string
+		condition        model.AlertCondition
+	}{
+		{"key-2", "BTCUSDT", model.AlertConditionSpreadAbove},
+		{"key-1", "ETHUSDT", model.AlertConditionSpreadAbove},
+		{"key-1", "BTCUSDT", model.AlertConditionPriceOutside},
+	} {
+		if alerts := uc.Evaluate(other.apiKeyID, other.symbol, other.condition, 50, at); len(alerts) != 0 {
+			t.Fatalf("Evaluate(%s, %s, %s) = %+v, want no alerts", other.apiKeyID, other.symbol, other.condition, alerts)
+		}
+	}
+
+	alerts := uc.Evaluate("key-1", "BTCUSDT", model.AlertConditionSpreadAbove, 50, at)
+	if len(alerts) != 1 || alerts[0].RuleID != "rule-1" {
+		t.Fatalf("alerts = %+v, want only rule-1 to trigger", alerts)
+	}
+
+	// Editing a rule restarts its evaluation, so the firing state is dropped
+	uc.setRule(spreadRule(0))
+	alerts = uc.Evaluate("key-1", "BTCUSDT", model.AlertConditionSpreadAbove, 50, at.Add(time.Second))
+	if len(alerts) != 1 || alerts[0].State != model.AlertStateTriggered {
+		t.Fatalf("alerts after the edit = %+v, want rule-1 to trigger again", alerts)
+	}
+}
+
+func TestAlertWebhook(t *testing.T) {
+	received := make(chan model.Alert, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert model.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		received <- alert
+	}))
+	defer srv.Close()
+
+	uc := newTestAlertUseCase(srv.URL, spreadRule(0))
+	at := time.Now()
+	uc.Evaluate("key-1", "BTCUSDT", model.AlertConditionSpreadAbove, 50, at)
+	uc.Evaluate("key-1", "BTCUSDT", model.AlertConditionSpreadAbove, 1, at.Add(time.Second))
+
+	states := make(map[string]bool)
+	for range 2 {
+		select {
+		case alert := <-received:
+			if alert.RuleID != "rule-1" || alert.Symbol != "BTCUSDT" {
+				t.Fatalf("webhook alert = %+v, want rule-1 on BTCUSDT", alert)
+			}
+			states[alert.State] = true
+		case <-time.After(alertWebhookTimeout):
+			t.Fatalf("webhook received %v, want a triggered and a recovered alert", states)
+		}
+	}
+	if !states[model.AlertStateTriggered] || !states[model.AlertStateRecovered] {
+		t.Fatalf("webhook states = %v, want triggered and recovered", states)
+	}
+}
@@ -3,28 +3,55 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
+	"control_page/pkg/secretbox"
 )
 
 var (
-	ErrAPIKeyNotFound    = errors.New("api key not found")
-	ErrInvalidPlatform   = errors.New("invalid platform")
-	ErrAPIKeyNameEmpty   = errors.New("api key name is required")
-	ErrAPIKeyEmpty       = errors.New("api key is required")
-	ErrAPISecretEmpty    = errors.New("api secret is required")
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrInvalidPlatform    = errors.New("invalid platform")
+	ErrAPIKeyNameEmpty    = errors.New("api key name is required")
+	ErrAPIKeyEmpty        = errors.New("api key is required")
+	ErrAPISecretEmpty     = errors.New("api secret is required")
+	ErrInvalidAPIKeyScope = errors.New("invalid api key scope")
+	ErrAPIKeyReadOnly     = errors.New("api key is read-only: trade scope is required")
+	ErrAPIKeyDuplicate    = errors.New("api key already registered")
+	ErrInvalidPagination  = errors.New("limit and offset must not be negative")
+	ErrAPIKeyUnchanged    = errors.New("new credentials must differ from the current ones")
+	ErrAPIKeyConflict     = errors.New("api key was modified concurrently")
+	ErrNoRollback         = errors.New("no previous credentials available for rollback")
+
+	ErrInvalidDefaultSubscription = errors.New("invalid default subscription")
+)
+
+const (
+	// maxAPIKeyPageSize caps limit; a zero limit returns all matching keys
+	maxAPIKeyPageSize = 200
+	// maxDefaultSubscriptions caps the bundle applied on every trading connect
+	maxDefaultSubscriptions = 20
 )
 
 var _ adaptor.APIKeyUseCase = (*APIKeyUseCase)(nil)
 
 type APIKeyUseCase struct {
 	apiKeyRepo adaptor.APIKeyRepository
+
+	box                 *secretbox.Box
+	rotationGracePeriod time.Duration
 }
 
-func NewAPIKeyUseCase(apiKeyRepo adaptor.APIKeyRepository) *APIKeyUseCase {
+// NewAPIKeyUseCase creates an APIKeyUseCase; box encrypts credentials retained
+// for rollback for rotationGracePeriod after a rotation
+func NewAPIKeyUseCase(apiKeyRepo adaptor.APIKeyRepository, box *secretbox.Box, rotationGracePeriod time.Duration) *APIKeyUseCase {
 	return &APIKeyUseCase{
-		apiKeyRepo: apiKeyRepo,
+		apiKeyRepo:          apiKeyRepo,
+		box:                 box,
+		rotationGracePeriod: rotationGracePeriod,
 	}
 }
 
@@ -41,6 +68,26 @@ func (uc *APIKeyUseCase) Create(ctx context.Context, req *model.CreateAPIKeyRequ
 	if !req.Platform.IsValid() {
 		return nil, ErrInvalidPlatform
 	}
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	defaultSubs, err := normalizeDefaultSubscriptions(req.Platform, req.DefaultSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.Force {
+		existing, err := uc.apiKeyRepo.GetByPlatform(ctx, req.Platform)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range existing {
+			if key.APIKey == req.APIKey {
+				return nil, fmt.Errorf("%w as %q", ErrAPIKeyDuplicate, key.Name)
+			}
+		}
+	}
 
 	apiKey := &model.APIKey{
 		Name:      req.Name,
@@ -49,6 +96,9 @@ func (uc *APIKeyUseCase) Create(ctx context.Context, req *model.CreateAPIKeyRequ
 		APISecret: req.APISecret,
 		IsTestnet: req.IsTestnet,
 		IsActive:  true,
+		Scopes:    scopes,
+
+		DefaultSubscriptions: defaultSubs,
 	}
 
 	if err := uc.apiKeyRepo.Create(ctx, apiKey); err != nil {
@@ -72,8 +122,18 @@ func (uc *APIKeyUseCase) GetByID(ctx context.Context, id string) (*model.APIKeyR
 	return &response, nil
 }
 
-func (uc *APIKeyUseCase) List(ctx context.Context) ([]model.APIKeyResponse, error) {
-	apiKeys, err := uc.apiKeyRepo.List(ctx)
+func (uc *APIKeyUseCase) List(ctx context.Context, filter model.APIKeyFilter) (*model.APIKeyListResult, error) {
+	if filter.Platform != nil && !filter.Platform.IsValid() {
+		return nil, ErrInvalidPlatform
+	}
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, ErrInvalidPagination
+	}
+	if filter.Limit > maxAPIKeyPageSize {
+		filter.Limit = maxAPIKeyPageSize
+	}
+
+	apiKeys, total, err := uc.apiKeyRepo.Search(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +143,7 @@ func (uc *APIKeyUseCase) List(ctx context.Context) ([]model.APIKeyResponse, erro
 		responses[i] = key.ToResponse()
 	}
 
-	return responses, nil
+	return &model.APIKeyListResult{Items: responses, Total: total}, nil
 }
 
 func (uc *APIKeyUseCase) Update(ctx context.Context, id string, req *model.UpdateAPIKeyRequest) (*model.APIKeyResponse, error) {
@@ -119,6 +179,20 @@ func (uc *APIKeyUseCase) Update(ctx context.Context, id string, req *model.Updat
 	if req.IsActive != nil {
 		apiKey.IsActive = *req.IsActive
 	}
+	if req.Scopes != nil {
+		scopes, err := normalizeAPIKeyScopes(req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.Scopes = scopes
+	}
+	if req.DefaultSubscriptions != nil {
+		defaultSubs, err := normalizeDefaultSubscriptions(apiKey.Platform, *req.DefaultSubscriptions)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.DefaultSubscriptions = defaultSubs
+	}
 
 	if err := uc.apiKeyRepo.Update(ctx, apiKey); err != nil {
 		return nil, err
@@ -140,6 +214,172 @@ func (uc *APIKeyUseCase) Delete(ctx context.Context, id string) error {
 	return uc.apiKeyRepo.Delete(ctx, id)
 }
 
+// Rotate replaces the credentials of an API key, keeping the old ones encrypted
+// for the grace period. Callers are expected to have verified the new
+// credentials against the exchange beforehand.
+func (uc *APIKeyUseCase) Rotate(ctx context.Context, id string, req *model.RotateAPIKeyRequest) (*model.APIKeyResponse, error) {
+	if req.APIKey == "" {
+		return nil, ErrAPIKeyEmpty
+	}
+	if req.APISecret == "" {
+		return nil, ErrAPISecretEmpty
+	}
+
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	if apiKey.APIKey == req.APIKey && apiKey.APISecret == req.APISecret {
+		return nil, ErrAPIKeyUnchanged
+	}
+
+	sealedKey, err := uc.box.Seal(apiKey.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	sealedSecret, err := uc.box.Seal(apiKey.APISecret)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedAPIKey := apiKey.APIKey
+	apiKey.APIKey = req.APIKey
+	apiKey.APISecret = req.APISecret
+	apiKey.Previous = &model.RotatedCredentials{
+		APIKey:    sealedKey,
+		APISecret: sealedSecret,
+		ExpiresAt: time.Now().Add(uc.rotationGracePeriod),
+	}
+
+	ok, err := uc.apiKeyRepo.Rotate(ctx, apiKey, expectedAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAPIKeyConflict
+	}
+
+	response := apiKey.ToResponse()
+	return &response, nil
+}
+
+// Rollback restores the credentials replaced by the last rotation while the
+// grace period has not expired
+func (uc *APIKeyUseCase) Rollback(ctx context.Context, id string) (*model.APIKeyResponse, error) {
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	if apiKey.Previous == nil || time.Now().After(apiKey.Previous.ExpiresAt) {
+		return nil, ErrNoRollback
+	}
+
+	previousKey, err := uc.box.Open(apiKey.Previous.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	previousSecret, err := uc.box.Open(apiKey.Previous.APISecret)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedAPIKey := apiKey.APIKey
+	apiKey.APIKey = previousKey
+	apiKey.APISecret = previousSecret
+	apiKey.Previous = nil
+
+	ok, err := uc.apiKeyRepo.Rotate(ctx, apiKey, expectedAPIKey)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrAPIKeyConflict
+	}
+
+	response := apiKey.ToResponse()
+	return &response, nil
+}
+
 func (uc *APIKeyUseCase) GetPlatforms() []model.Platform {
 	return model.AllPlatforms()
 }
+
+// normalizeAPIKeyScopes validates and de-duplicates scopes, defaulting to read-only when none are given
+func normalizeAPIKeyScopes(scopes []model.APIKeyScope) ([]model.APIKeyScope, error) {
+	if len(scopes) == 0 {
+		return model.DefaultAPIKeyScopes(), nil
+	}
+
+	seen := make(map[model.APIKeyScope]bool, len(scopes))
+	result := make([]model.APIKeyScope, 0, len(scopes))
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyScope, scope)
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+	return result, nil
+}
+
+// normalizeDefaultSubscriptions validates a subscription bundle against the
+// platform capabilities table, upper-cases symbols and drops duplicates
+func normalizeDefaultSubscriptions(platform model.Platform, subs []model.TradingSubscription) ([]model.TradingSubscription, error) {
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	if len(subs) > maxDefaultSubscriptions {
+		return nil, fmt.Errorf("%w: at most %d subscriptions", ErrInvalidDefaultSubscription, maxDefaultSubscriptions)
+	}
+
+	caps, ok := model.GetPlatformCapabilities(platform)
+	if !ok || !caps.SupportsStreaming() {
+		return nil, fmt.Errorf("%w: platform %s does not support streaming", ErrInvalidDefaultSubscription, platform)
+	}
+
+	seen := make(map[model.TradingSubscription]bool, len(subs))
+	result := make([]model.TradingSubscription, 0, len(subs))
+	for _, sub := range subs {
+		sub.Type = strings.ToLower(strings.TrimSpace(sub.Type))
+		sub.Symbol = strings.ToUpper(strings.TrimSpace(sub.Symbol))
+		sub.Interval = strings.TrimSpace(sub.Interval)
+
+		if !caps.SupportsSubscription(sub.Type) {
+			return nil, fmt.Errorf("%w: %q is not supported on %s", ErrInvalidDefaultSubscription, sub.Type, platform)
+		}
+		switch sub.Type {
+		case "kline":
+			if sub.Interval == "" || !caps.SupportsInterval(sub.Interval) {
+				return nil, fmt.Errorf("%w: unsupported kline interval %q", ErrInvalidDefaultSubscription, sub.Interval)
+			}
+			fallthrough
+		case "orderbook", "depth", "trades", "deals":
+			if sub.Symbol == "" {
+				return nil, fmt.Errorf("%w: %s requires a symbol", ErrInvalidDefaultSubscription, sub.Type)
+			}
+		}
+		if sub.Type != "kline" {
+			sub.Interval = ""
+		}
+
+		if seen[sub] {
+			continue
+		}
+		seen[sub] = true
+		result = append(result, sub)
+	}
+	return result, nil
+}
+
+func (uc *APIKeyUseCase) GetPlatformCapabilities() []model.PlatformCapabilities {
+	return model.AllPlatformCapabilities()
+}
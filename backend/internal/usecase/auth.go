@@ -1,11 +1,10 @@
 package usecase
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"errors"
-	"image/png"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -30,35 +29,74 @@ var (
 	ErrPasswordSameAsOld  = errors.New("new password cannot be the same as current password")
 	ErrInvalidTOTPCode    = errors.New("invalid TOTP code")
 	ErrTOTPNotSetup       = errors.New("TOTP is not set up")
+	ErrAccountLocked      = errors.New("account is temporarily locked")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrEmailAlreadyExists = adaptor.ErrDuplicateEmail
+
+	// ErrInvalidID reports a malformed resource ID (400), as opposed to the
+	// per-resource not-found errors for well-formed IDs (404)
+	ErrInvalidID = adaptor.ErrInvalidID
 )
 
+// maxLoginHistory caps how many login events ListLogins returns
+const maxLoginHistory = 100
+
 type AuthUseCase struct {
 	userRepo     adaptor.UserRepository
 	roleRepo     adaptor.RoleRepository
 	userRoleRepo adaptor.UserRoleRepository
+	loginRepo    adaptor.LoginEventRepository
 	jwtSecret    []byte
 	jwtExpiry    time.Duration
 	appName      string
+
+	passwordPolicy model.PasswordPolicy
+	lockoutPolicy  model.LockoutPolicy
+	totpQRSize     int
+
+	// rememberDeviceTTL is how long a remembered device skips TOTP; 0 disables it
+	rememberDeviceTTL time.Duration
+
+	notifier adaptor.NotificationUseCase // nil when notifications are disabled
 }
 
 func NewAuthUseCase(
 	userRepo adaptor.UserRepository,
 	roleRepo adaptor.RoleRepository,
 	userRoleRepo adaptor.UserRoleRepository,
+	loginRepo adaptor.LoginEventRepository,
 	jwtSecret string,
 	jwtExpiry time.Duration,
+	passwordPolicy model.PasswordPolicy,
+	lockoutPolicy model.LockoutPolicy,
+	totpQRSize int,
+	rememberDeviceTTL time.Duration,
+	notifier adaptor.NotificationUseCase,
 ) *AuthUseCase {
 	return &AuthUseCase{
 		userRepo:     userRepo,
 		roleRepo:     roleRepo,
 		userRoleRepo: userRoleRepo,
+		loginRepo:    loginRepo,
 		jwtSecret:    []byte(jwtSecret),
 		jwtExpiry:    jwtExpiry,
 		appName:      "Nova",
+
+		passwordPolicy: passwordPolicy,
+		lockoutPolicy:  lockoutPolicy,
+		totpQRSize:     totpQRSize,
+
+		rememberDeviceTTL: rememberDeviceTTL,
+
+		notifier: notifier,
 	}
 }
 
 func (uc *AuthUseCase) Register(ctx context.Context, username, password string) (*model.RegisterResult, error) {
+	if err := validatePassword(uc.passwordPolicy, password); err != nil {
+		return nil, err
+	}
+
 	// Check if user exists by username
 	existingUser, err := uc.userRepo.GetByUsername(ctx, username)
 	if err != nil {
@@ -120,24 +158,17 @@ func (uc *AuthUseCase) Register(ctx context.Context, username, password string)
 		}
 	}
 
-	// Generate QR code
-	img, err := key.Image(200, 200)
+	qrCode, err := totpQRCode(key, uc.totpQRSize)
 	if err != nil {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, err
-	}
-
-	qrCode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
-
 	return &model.RegisterResult{
 		UserID: userID,
 		TOTPSetup: model.TOTPSetup{
-			Secret: key.Secret(),
-			QRCode: qrCode,
+			Secret:     key.Secret(),
+			QRCode:     qrCode,
+			OTPAuthURL: key.URL(),
 		},
 	}, nil
 }
@@ -172,17 +203,35 @@ func (uc *AuthUseCase) ActivateAccount(ctx context.Context, userID string, code
 	return uc.userRepo.Activate(ctx, userID)
 }
 
-func (uc *AuthUseCase) Login(ctx context.Context, username, password string) (*model.LoginResult, error) {
+func (uc *AuthUseCase) Login(ctx context.Context, username, password string, client model.ClientInfo) (*model.LoginResult, error) {
 	user, err := uc.userRepo.GetByUsername(ctx, username)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
+		uc.recordLoginEvent(ctx, "", username, client, ErrUserNotFound)
 		return nil, ErrUserNotFound
 	}
+	if user.IsLocked(time.Now()) {
+		uc.recordLoginEvent(ctx, user.ID, user.Username, client, ErrAccountLocked)
+		return nil, ErrAccountLocked
+	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, ErrInvalidCredentials
+		err = uc.recordFailedAttempt(ctx, user, ErrInvalidCredentials)
+		uc.recordLoginEvent(ctx, user.ID, user.Username, client, err)
+		return nil, err
+	}
+
+	// Reject inactive accounts before either 2FA branch; a registration that
+	// was never activated is told apart from a deactivated account
+	if !user.IsActive {
+		err := ErrUserInactive
+		if user.PendingActivation() {
+			err = ErrUserNotActivated
+		}
+		uc.recordLoginEvent(ctx, user.ID, user.Username, client, err)
+		return nil, err
 	}
 
 	// Check if 2FA is enabled
@@ -201,8 +250,16 @@ func (uc *AuthUseCase) Login(ctx context.Context, username, password string) (*m
 		}, nil
 	}
 
-	if !user.IsActive {
-		return nil, ErrUserInactive
+	// A device remembered after an earlier TOTP login skips the code
+	if device := uc.trustedDevice(user, client.DeviceToken); device != nil {
+		result, err := uc.finishLogin(ctx, user, client)
+		if err != nil {
+			return nil, err
+		}
+		if err := uc.userRepo.TouchTrustedDevice(ctx, user.ID, device.ID, time.Now()); err != nil {
+			log.Printf("Warning: failed to record use of trusted device for user %s: %v", user.ID, err)
+		}
+		return result, nil
 	}
 
 	// 2FA is mandatory, always require TOTP verification
@@ -212,30 +269,156 @@ func (uc *AuthUseCase) Login(ctx context.Context, username, password string) (*m
 	}, nil
 }
 
-func (uc *AuthUseCase) VerifyTOTP(ctx context.Context, userID string, code string) (string, *model.UserWithRoles, error) {
+// VerifyTOTP completes a login with a TOTP code. With rememberDevice, and
+// auth.remember_device configured, the result carries a device token that
+// lets this device skip TOTP on later logins.
+func (uc *AuthUseCase) VerifyTOTP(ctx context.Context, userID string, code string, rememberDevice bool, client model.ClientInfo) (*model.LoginResult, error) {
 	user, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 	if user == nil {
-		return "", nil, ErrUserNotFound
+		return nil, ErrUserNotFound
+	}
+	if user.IsLocked(time.Now()) {
+		uc.recordLoginEvent(ctx, user.ID, user.Username, client, ErrAccountLocked)
+		return nil, ErrAccountLocked
 	}
 
 	if !user.TOTPEnabled || user.TOTPSecret == nil {
-		return "", nil, ErrTOTPNotSetup
+		return nil, ErrTOTPNotSetup
 	}
 
 	// Validate TOTP code
 	if !totp.Validate(code, *user.TOTPSecret) {
-		return "", nil, ErrInvalidTOTPCode
+		err := uc.recordFailedAttempt(ctx, user, ErrInvalidTOTPCode)
+		uc.recordLoginEvent(ctx, user.ID, user.Username, client, err)
+		return nil, err
+	}
+
+	result, err := uc.finishLogin(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Best effort: the login stands even if the device cannot be remembered
+	if rememberDevice && uc.rememberDeviceTTL > 0 {
+		token, err := uc.rememberDevice(ctx, user, client)
+		if err != nil {
+			log.Printf("Warning: failed to remember device for user %s: %v", user.ID, err)
+		} else {
+			result.DeviceToken = token
+		}
+	}
+
+	return result, nil
+}
+
+// finishLogin issues the token of a login that passed its second factor and
+// records it in login history
+func (uc *AuthUseCase) finishLogin(ctx context.Context, user *model.User, client model.ClientInfo) (*model.LoginResult, error) {
+	// Only a fully completed login resets the counter, so a known password
+	// cannot be used to clear failures while guessing TOTP codes
+	if user.FailedAttempts > 0 || user.LockedUntil != nil {
+		if err := uc.userRepo.ResetLockout(ctx, user.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	result, err := uc.completeLogin(ctx, user)
 	if err != nil {
-		return "", nil, err
+		return nil, err
+	}
+	uc.recordLoginEvent(ctx, user.ID, user.Username, client, nil)
+
+	return result, nil
+}
+
+// loginFailureReasons maps login errors to the reason stored in login history
+var loginFailureReasons = map[error]string{
+	ErrUserNotFound:       "unknown_user",
+	ErrInvalidCredentials: "invalid_password",
+	ErrInvalidTOTPCode:    "invalid_totp",
+	ErrUserInactive:       "inactive",
+	ErrUserNotActivated:   "not_activated",
+	ErrAccountLocked:      "locked",
+}
+
+// recordLoginEvent appends a login history entry; cause is nil for a
+// successful login. Failures to write are logged and never block the login.
+func (uc *AuthUseCase) recordLoginEvent(ctx context.Context, userID, username string, client model.ClientInfo, cause error) {
+	event := &model.LoginEvent{
+		UserID:    userID,
+		Username:  username,
+		Success:   cause == nil,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if cause != nil {
+		event.Reason = "error"
+		for err, reason := range loginFailureReasons {
+			if errors.Is(cause, err) {
+				event.Reason = reason
+				break
+			}
+		}
+	}
+
+	if err := uc.loginRepo.Create(ctx, event); err != nil {
+		log.Printf("Warning: failed to record login event for %s: %v", username, err)
+	}
+}
+
+// ListLogins returns the most recent login history entries of a user, newest first
+func (uc *AuthUseCase) ListLogins(ctx context.Context, userID string, limit int64) ([]model.LoginEvent, error) {
+	if limit <= 0 || limit > maxLoginHistory {
+		limit = maxLoginHistory
+	}
+
+	// Login events store the user ID as a plain string, so check the user
+	// to report malformed and unknown IDs like every other user endpoint
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return uc.loginRepo.ListByUser(ctx, userID, limit)
+}
+
+// recordFailedAttempt counts a failed login and locks the account once the
+// policy threshold is reached; it returns cause, or ErrAccountLocked if this
+// attempt triggered the lock
+func (uc *AuthUseCase) recordFailedAttempt(ctx context.Context, user *model.User, cause error) error {
+	if uc.lockoutPolicy.MaxAttempts <= 0 {
+		return cause
+	}
+
+	attempts, err := uc.userRepo.IncrementFailedAttempts(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if attempts < uc.lockoutPolicy.MaxAttempts {
+		return cause
 	}
 
-	return result.Token, result.User, nil
+	if err := uc.userRepo.LockUntil(ctx, user.ID, time.Now().Add(uc.lockoutPolicy.Duration)); err != nil {
+		return err
+	}
+	log.Printf("account %s locked after %d failed login attempts", user.Username, attempts)
+	if uc.notifier != nil {
+		uc.notifier.Notify(model.Notification{
+			Type:    model.NotificationUserLocked,
+			Message: fmt.Sprintf("account locked after %d failed login attempts", attempts),
+			Fields: map[string]string{
+				"username": user.Username,
+				"user_id":  user.ID,
+				"until":    time.Now().Add(uc.lockoutPolicy.Duration).UTC().Format(time.RFC3339),
+			},
+		})
+	}
+	return ErrAccountLocked
 }
 
 func (uc *AuthUseCase) completeLogin(ctx context.Context, user *model.User) (*model.LoginResult, error) {
@@ -251,11 +434,20 @@ func (uc *AuthUseCase) completeLogin(ctx context.Context, user *model.User) (*mo
 	}
 
 	// Generate JWT token
-	token, err := uc.generateToken(user.ID, user.Username)
+	expiry := uc.tokenExpiry(roles)
+	token, err := uc.generateToken(user.ID, user.Username, expiry)
 	if err != nil {
 		return nil, err
 	}
 
+	// Best effort: a failed timestamp write must not block the login
+	now := time.Now()
+	if err := uc.userRepo.RecordLogin(ctx, user.ID, now); err != nil {
+		log.Printf("Warning: failed to record login for user %s: %v", user.ID, err)
+	} else {
+		user.LastLoginAt = &now
+	}
+
 	userWithRoles := &model.UserWithRoles{
 		User:        *user,
 		Roles:       roles,
@@ -265,6 +457,7 @@ func (uc *AuthUseCase) completeLogin(ctx context.Context, user *model.User) (*mo
 	return &model.LoginResult{
 		RequiresTOTP: false,
 		Token:        token,
+		ExpiresIn:    int64(expiry / time.Second),
 		User:         userWithRoles,
 	}, nil
 }
@@ -302,6 +495,14 @@ func (uc *AuthUseCase) ValidateToken(ctx context.Context, tokenString string) (*
 		return nil, ErrUserInactive
 	}
 
+	// Tokens issued before an admin password reset are no longer valid
+	if user.SessionsRevokedAt != nil {
+		issuedAt, err := claims.GetIssuedAt()
+		if err != nil || issuedAt == nil || issuedAt.Unix() < user.SessionsRevokedAt.Unix() {
+			return nil, ErrInvalidToken
+		}
+	}
+
 	roles, err := uc.roleRepo.GetRolesByUserID(ctx, user.ID)
 	if err != nil {
 		return nil, err
@@ -352,6 +553,10 @@ func (uc *AuthUseCase) ChangePassword(ctx context.Context, userID string, curren
 		return ErrPasswordSameAsOld
 	}
 
+	if err := validatePassword(uc.passwordPolicy, newPassword); err != nil {
+		return err
+	}
+
 	// Hash new password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
 	if err != nil {
@@ -361,11 +566,62 @@ func (uc *AuthUseCase) ChangePassword(ctx context.Context, userID string, curren
 	return uc.userRepo.UpdatePassword(ctx, userID, string(hashedPassword))
 }
 
-func (uc *AuthUseCase) generateToken(userID string, username string) (string, error) {
+// GetProfile assembles the signed-in user's own account summary
+func (uc *AuthUseCase) GetProfile(ctx context.Context, userID string) (*model.Profile, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	roles, err := uc.roleRepo.GetRolesByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := uc.userRoleRepo.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Profile{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Email:              user.Email,
+		IsActive:           user.IsActive,
+		TOTPEnabled:        user.TOTPEnabled,
+		PendingRebind:      user.PendingTOTPSecret != nil && *user.PendingTOTPSecret != "",
+		PasswordChangedAt:  user.PasswordChangedAt,
+		MustChangePassword: user.MustChangePassword,
+		LastLoginAt:        user.LastLoginAt,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+		Roles:              roles,
+		Permissions:        permissions,
+	}, nil
+}
+
+// tokenExpiry returns the lifetime of a token for a user holding roles. The
+// global jwt.expiration is the upper bound; any role with a max_token_ttl can
+// only shorten it, and with several such roles the shortest wins, so the most
+// sensitive role a user holds decides how often they re-authenticate.
+func (uc *AuthUseCase) tokenExpiry(roles []model.Role) time.Duration {
+	expiry := uc.jwtExpiry
+	for _, role := range roles {
+		if ttl := time.Duration(role.MaxTokenTTL) * time.Second; ttl > 0 && ttl < expiry {
+			expiry = ttl
+		}
+	}
+	return expiry
+}
+
+func (uc *AuthUseCase) generateToken(userID string, username string, expiry time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
-		"exp":      time.Now().Add(uc.jwtExpiry).Unix(),
+		"exp":      time.Now().Add(expiry).Unix(),
 		"iat":      time.Now().Unix(),
 	}
 
@@ -390,22 +646,15 @@ func (uc *AuthUseCase) generateTOTPSetup(ctx context.Context, user *model.User)
 		return nil, err
 	}
 
-	// Generate QR code
-	img, err := key.Image(200, 200)
+	qrCode, err := totpQRCode(key, uc.totpQRSize)
 	if err != nil {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, err
-	}
-
-	qrCode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
-
 	return &model.TOTPSetup{
-		Secret: secret,
-		QRCode: qrCode,
+		Secret:     secret,
+		QRCode:     qrCode,
+		OTPAuthURL: key.URL(),
 	}, nil
 }
 
@@ -438,22 +687,15 @@ func (uc *AuthUseCase) SetupTOTPRebind(ctx context.Context, userID string, passw
 		return nil, err
 	}
 
-	// Generate QR code
-	img, err := key.Image(200, 200)
+	qrCode, err := totpQRCode(key, uc.totpQRSize)
 	if err != nil {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, err
-	}
-
-	qrCode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
-
 	return &model.TOTPSetup{
-		Secret: key.Secret(),
-		QRCode: qrCode,
+		Secret:     key.Secret(),
+		QRCode:     qrCode,
+		OTPAuthURL: key.URL(),
 	}, nil
 }
 
@@ -476,7 +718,12 @@ func (uc *AuthUseCase) ConfirmTOTPRebind(ctx context.Context, userID string, cod
 	}
 
 	// Confirm the rebind: move pending secret to active secret
-	return uc.userRepo.ConfirmTOTPRebind(ctx, userID)
+	if err := uc.userRepo.ConfirmTOTPRebind(ctx, userID); err != nil {
+		return err
+	}
+
+	// Devices remembered under the old secret must prove the new one
+	return uc.userRepo.ClearTrustedDevices(ctx, userID)
 }
 
 func (uc *AuthUseCase) CancelTOTPRebind(ctx context.Context, userID string) error {
@@ -0,0 +1,477 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+const testPassword = "Secret123!"
+
+func newTestAuthUseCase(repos *fakeRepos, lockout model.LockoutPolicy) *AuthUseCase {
+	return NewAuthUseCase(
+		repos.users, repos.roles, repos.userRoles, repos.logins,
+		"test-secret", time.Hour,
+		model.PasswordPolicy{MinLength: 6}, lockout, 128, 0, nil,
+	)
+}
+
+// createTestUser stores an active user with TOTP enabled and testPassword;
+// edit adjusts the user before it is stored
+func createTestUser(t *testing.T, repos *fakeRepos, username string, edit func(u *model.User)) *model.User {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "test", AccountName: username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := key.Secret()
+	user := &model.User{
+		Username:    username,
+		Password:    string(hashed),
+		IsActive:    true,
+		TOTPSecret:  &secret,
+		TOTPEnabled: true,
+	}
+	if edit != nil {
+		edit(user)
+	}
+	if err := repos.users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestLoginRejectsInactiveUsers(t *testing.T) {
+	lastLogin := time.Now().Add(-24 * time.Hour)
+	tests := []struct {
+		name   string
+		edit   func(u *model.User)
+		want   error
+		reason string
+	}{
+		{
+			name: "deactivated with TOTP enabled",
+			edit: func(u *model.User) {
+				u.IsActive = false
+			},
+			want:   ErrUserInactive,
+			reason: "inactive",
+		},
+		{
+			name: "deactivated before finishing TOTP setup",
+			edit: func(u *model.User) {
+				u.IsActive = false
+				u.TOTPEnabled = false
+				u.LastLoginAt = &lastLogin
+			},
+			want:   ErrUserInactive,
+			reason: "inactive",
+		},
+		{
+			name: "registered but never activated",
+			edit: func(u *model.User) {
+				u.IsActive = false
+				u.TOTPEnabled = false
+			},
+			want:   ErrUserNotActivated,
+			reason: "not_activated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newFakeRepos()
+			uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+			user := createTestUser(t, repos, "alice", tt.edit)
+
+			result, err := uc.Login(context.Background(), "alice", testPassword, model.ClientInfo{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login() error = %v, want %v", err, tt.want)
+			}
+			if result != nil {
+				t.Fatalf("Login() result = %+v, want nil", result)
+			}
+			if got := repos.logins.last(); got.UserID != user.ID || got.Success || got.Reason != tt.reason {
+				t.Fatalf("login event = %+v, want failure %q for %s", got, tt.reason, user.ID)
+			}
+
+			stored, _ := repos.users.GetByID(context.Background(), user.ID)
+			if stored.TOTPSecret == nil || *stored.TOTPSecret != *user.TOTPSecret {
+				t.Fatal("rejected login replaced the TOTP secret")
+			}
+		})
+	}
+}
+
+func TestLoginInactiveUserWithWrongPasswordIsInvalidCredentials(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	createTestUser(t, repos, "alice", func(u *model.User) { u.IsActive = false })
+
+	if _, err := uc.Login(context.Background(), "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestLoginActiveUserBranches(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      func(u *model.User)
+		wantSetup bool
+		wantTOTP  bool
+	}{
+		{name: "TOTP required", wantTOTP: true},
+		{name: "TOTP setup required", edit: func(u *model.User) { u.TOTPEnabled = false }, wantSetup: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newFakeRepos()
+			uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+			user := createTestUser(t, repos, "alice", tt.edit)
+
+			result, err := uc.Login(context.Background(), "alice", testPassword, model.ClientInfo{})
+			if err != nil {
+				t.Fatalf("Login() error = %v", err)
+			}
+			if result.RequiresTOTP != tt.wantTOTP || result.RequiresTOTPSetup != tt.wantSetup {
+				t.Fatalf("Login() = %+v, want RequiresTOTP=%v RequiresTOTPSetup=%v", result, tt.wantTOTP, tt.wantSetup)
+			}
+			if result.TempUserID != user.ID || result.Token != "" {
+				t.Fatalf("Login() = %+v, want temp user %s and no token", result, user.ID)
+			}
+			if tt.wantSetup && result.TOTPSetup == nil {
+				t.Fatal("Login() returned no TOTP setup")
+			}
+		})
+	}
+}
+
+func TestLoginLocksAccountAfterMaxAttempts(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{MaxAttempts: 3, Duration: time.Hour})
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: Login() error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("attempt 3: Login() error = %v, want %v", err, ErrAccountLocked)
+	}
+	if _, err := uc.Login(ctx, "alice", testPassword, model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("correct password while locked: Login() error = %v, want %v", err, ErrAccountLocked)
+	}
+
+	stored, _ := repos.users.GetByID(ctx, user.ID)
+	if !stored.IsLocked(time.Now()) {
+		t.Fatal("account is not locked")
+	}
+	if stored.FailedAttempts != 0 {
+		t.Fatalf("FailedAttempts = %d after locking, want 0", stored.FailedAttempts)
+	}
+}
+
+func TestExpiredLockNeedsFullSeriesOfFailures(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{MaxAttempts: 3, Duration: time.Hour})
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, _ = uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{})
+	}
+
+	// Let the lock run out
+	expired := time.Now().Add(-time.Minute)
+	if err := repos.users.update(user.ID, func(u *model.User) { u.LockedUntil = &expired }); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d after expiry: Login() error = %v, want %v", i+1, err, ErrInvalidCredentials)
+		}
+	}
+	if _, err := uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("attempt 3 after expiry: Login() error = %v, want %v", err, ErrAccountLocked)
+	}
+}
+
+func TestVerifyTOTPFailuresCountTowardsLockout(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{MaxAttempts: 2, Duration: time.Hour})
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	if _, err := uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := uc.VerifyTOTP(ctx, user.ID, "000000", false, model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("VerifyTOTP() error = %v, want %v", err, ErrAccountLocked)
+	}
+	if _, err := uc.VerifyTOTP(ctx, user.ID, totpCode(t, *user.TOTPSecret), false, model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("VerifyTOTP() with a valid code while locked error = %v, want %v", err, ErrAccountLocked)
+	}
+}
+
+func TestCompletedLoginResetsFailedAttempts(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{MaxAttempts: 3, Duration: time.Hour})
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _ = uc.Login(ctx, "alice", "wrong-password", model.ClientInfo{})
+	}
+
+	// A correct password alone does not clear the counter
+	if _, err := uc.Login(ctx, "alice", testPassword, model.ClientInfo{}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if stored, _ := repos.users.GetByID(ctx, user.ID); stored.FailedAttempts != 2 {
+		t.Fatalf("FailedAttempts = %d after password step, want 2", stored.FailedAttempts)
+	}
+
+	result, err := uc.VerifyTOTP(ctx, user.ID, totpCode(t, *user.TOTPSecret), false, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("VerifyTOTP() error = %v", err)
+	}
+	if result.Token == "" {
+		t.Fatal("VerifyTOTP() returned no token")
+	}
+	if stored, _ := repos.users.GetByID(ctx, user.ID); stored.FailedAttempts != 0 || stored.LockedUntil != nil {
+		t.Fatalf("lockout state = %d/%v after login, want cleared", stored.FailedAttempts, stored.LockedUntil)
+	}
+}
+
+func TestUnlockUserClearsLockout(t *testing.T) {
+	repos := newFakeRepos()
+	auth := newTestAuthUseCase(repos, model.LockoutPolicy{MaxAttempts: 2, Duration: time.Hour})
+	users := newTestUserUseCase(repos)
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _ = auth.Login(ctx, "alice", "wrong-password", model.ClientInfo{})
+	}
+	if _, err := auth.Login(ctx, "alice", testPassword, model.ClientInfo{}); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrAccountLocked)
+	}
+
+	unlocked, err := users.UnlockUser(ctx, "admin-id", user.ID)
+	if err != nil {
+		t.Fatalf("UnlockUser() error = %v", err)
+	}
+	if unlocked.LockedUntil != nil || unlocked.FailedAttempts != 0 {
+		t.Fatalf("UnlockUser() = %d/%v, want cleared lockout", unlocked.FailedAttempts, unlocked.LockedUntil)
+	}
+	if n := len(repos.audit.entries); n != 1 || repos.audit.entries[0].Action != "user.unlock" || repos.audit.entries[0].ActorID != "admin-id" {
+		t.Fatalf("audit entries = %+v, want one user.unlock by admin-id", repos.audit.entries)
+	}
+
+	result, err := auth.Login(ctx, "alice", testPassword, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("Login() after unlock error = %v", err)
+	}
+	if !result.RequiresTOTP {
+		t.Fatalf("Login() after unlock = %+v, want RequiresTOTP", result)
+	}
+
+	// One typo after the unlock does not lock the account again
+	if _, err := auth.Login(ctx, "alice", "wrong-password", model.ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestUnlockUserUnknownUser(t *testing.T) {
+	users := newTestUserUseCase(newFakeRepos())
+
+	if _, err := users.UnlockUser(context.Background(), "admin-id", "64b7f0000000000000000000"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("UnlockUser() error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestTokenExpiryUsesShortestRoleTTL(t *testing.T) {
+	uc := newTestAuthUseCase(newFakeRepos(), model.LockoutPolicy{})
+	admin := model.Role{Name: "admin", MaxTokenTTL: 3600}
+	viewer := model.Role{Name: "viewer", MaxTokenTTL: 12 * 3600}
+	uncapped := model.Role{Name: "user"}
+	tooLong := model.Role{Name: "service", MaxTokenTTL: 48 * 3600}
+
+	tests := []struct {
+		name  string
+		roles []model.Role
+		want  time.Duration
+	}{
+		{name: "no roles", want: time.Hour},
+		{name: "uncapped role", roles: []model.Role{uncapped}, want: time.Hour},
+		{name: "TTL above the global expiry", roles: []model.Role{tooLong}, want: time.Hour},
+		{name: "admin and viewer", roles: []model.Role{viewer, admin}, want: time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uc.tokenExpiry(tt.roles); got != tt.want {
+				t.Fatalf("tokenExpiry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	uc.jwtExpiry = 24 * time.Hour
+	if got := uc.tokenExpiry([]model.Role{viewer}); got != 12*time.Hour {
+		t.Fatalf("tokenExpiry(viewer) = %v, want 12h", got)
+	}
+	if got := uc.tokenExpiry([]model.Role{viewer, admin}); got != time.Hour {
+		t.Fatalf("tokenExpiry(viewer, admin) = %v, want 1h", got)
+	}
+}
+
+func TestLoginReportsTokenLifetime(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	role := &model.Role{Name: "admin", MaxTokenTTL: 900}
+	if err := repos.roles.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.userRoles.AssignRole(ctx, user.ID, role.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := uc.VerifyTOTP(ctx, user.ID, totpCode(t, *user.TOTPSecret), false, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("VerifyTOTP() error = %v", err)
+	}
+	if result.ExpiresIn != 900 {
+		t.Fatalf("ExpiresIn = %d, want 900", result.ExpiresIn)
+	}
+}
+
+func TestListLoginsReportsMalformedAndUnknownUsers(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	user := createTestUser(t, repos, "alice", nil)
+	if err := repos.logins.Create(context.Background(), &model.LoginEvent{UserID: user.ID, Username: "alice", Success: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		wantErr error
+		events  int
+	}{
+		{name: "malformed", id: "not-an-object-id", wantErr: ErrInvalidID},
+		{name: "well-formed but missing", id: primitive.NewObjectID().Hex(), wantErr: ErrUserNotFound},
+		{name: "valid", id: user.ID, events: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := uc.ListLogins(context.Background(), tt.id, 0)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("ListLogins(%q) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if len(events) != tt.events {
+				t.Fatalf("ListLogins(%q) = %d events, want %d", tt.id, len(events), tt.events)
+			}
+		})
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	ctx := context.Background()
+	pending := "PENDINGREBINDSECRET"
+	user := createTestUser(t, repos, "alice", func(u *model.User) {
+		u.Email = "alice@example.com"
+		u.PendingTOTPSecret = &pending
+	})
+	role := &model.Role{Name: "trader"}
+	if err := repos.roles.Create(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.roles.SetPermissions(ctx, role.ID, []enum.Permission{enum.PermissionViewKline}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.userRoles.AssignRole(ctx, user.ID, role.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := uc.GetProfile(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+	if !profile.TOTPEnabled || !profile.PendingRebind || profile.PasswordChangedAt != nil || profile.LastLoginAt != nil {
+		t.Fatalf("profile = %+v, want TOTP enabled, a pending rebind and no password change or login yet", profile)
+	}
+	if len(profile.Roles) != 1 || profile.Roles[0].Name != "trader" ||
+		len(profile.Permissions) != 1 || profile.Permissions[0] != enum.PermissionViewKline {
+		t.Fatalf("profile roles %+v permissions %v, want trader with view kline", profile.Roles, profile.Permissions)
+	}
+
+	// Changing the password and completing a login show up on the next read
+	if err := uc.ChangePassword(ctx, user.ID, testPassword, "Another456!"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := uc.Login(ctx, "alice", "Another456!", model.ClientInfo{}); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if _, err := uc.VerifyTOTP(ctx, user.ID, totpCode(t, *user.TOTPSecret), false, model.ClientInfo{}); err != nil {
+		t.Fatalf("VerifyTOTP() error = %v", err)
+	}
+	if err := repos.users.ClearPendingTOTPSecret(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err = uc.GetProfile(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetProfile() error = %v", err)
+	}
+	if profile.PendingRebind || profile.PasswordChangedAt == nil || profile.LastLoginAt == nil {
+		t.Fatalf("profile = %+v, want no pending rebind and both timestamps set", profile)
+	}
+
+	// Neither secret nor the password hash can leak through the JSON
+	data, err := json.Marshal(profile)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	stored, _ := repos.users.GetByID(ctx, user.ID)
+	for _, secret := range []string{*user.TOTPSecret, pending, stored.Password, "totp_secret", `"password"`} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("profile JSON %s contains %q", data, secret)
+		}
+	}
+
+	if _, err := uc.GetProfile(ctx, primitive.NewObjectID().Hex()); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetProfile(unknown) error = %v, want %v", err, ErrUserNotFound)
+	}
+}
@@ -0,0 +1,602 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+// fakeUserRepo is an in-memory adaptor.UserRepository with the same update
+// semantics as the Mongo repository
+type fakeUserRepo struct {
+	mu    sync.Mutex
+	users map[string]*model.User
+}
+
+var _ adaptor.UserRepository = (*fakeUserRepo)(nil)
+
+func newFakeUserRepo() *fakeUserRepo {
+	return &fakeUserRepo{users: make(map[string]*model.User)}
+}
+
+func (r *fakeUserRepo) update(id string, fn func(u *model.User)) error {
+	if !primitive.IsValidObjectID(id) {
+		return adaptor.ErrInvalidID
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[id]; ok {
+		fn(u)
+		u.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// find returns a copy of the first user matching fn, so callers cannot
+// change the stored user without going through the repository
+func (r *fakeUserRepo) find(fn func(u *model.User) bool) *model.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if fn(u) {
+			c := *u
+			c.TrustedDevices = append([]model.TrustedDevice(nil), u.TrustedDevices...)
+			return &c
+		}
+	}
+	return nil
+}
+
+func (r *fakeUserRepo) Create(_ context.Context, user *model.User) error {
+	if user.Email != "" && r.find(func(u *model.User) bool { return u.Email == user.Email }) != nil {
+		return adaptor.ErrDuplicateEmail
+	}
+	now := time.Now()
+	user.ID = primitive.NewObjectID().Hex()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	c := *user
+	r.mu.Lock()
+	r.users[user.ID] = &c
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *fakeUserRepo) GetByID(_ context.Context, id string) (*model.User, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, adaptor.ErrInvalidID
+	}
+	return r.find(func(u *model.User) bool { return u.ID == id }), nil
+}
+
+func (r *fakeUserRepo) GetByUsername(_ context.Context, username string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.Username == username }), nil
+}
+
+func (r *fakeUserRepo) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	return r.find(func(u *model.User) bool { return u.Email == email }), nil
+}
+
+func (r *fakeUserRepo) Update(_ context.Context, user *model.User) error {
+	if user.Email != "" && r.find(func(u *model.User) bool { return u.Email == user.Email && u.ID != user.ID }) != nil {
+		return adaptor.ErrDuplicateEmail
+	}
+	return r.update(user.ID, func(u *model.User) {
+		u.Username = user.Username
+		u.Email = user.Email
+		u.IsActive = user.IsActive
+	})
+}
+
+func (r *fakeUserRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, id)
+	return nil
+}
+
+func (r *fakeUserRepo) List(_ context.Context) ([]model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := make([]model.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, *u)
+	}
+	return users, nil
+}
+
+func (r *fakeUserRepo) UpdatePassword(_ context.Context, id string, hashedPassword string) error {
+	return r.update(id, func(u *model.User) {
+		now := time.Now()
+		u.Password = hashedPassword
+		u.PasswordChangedAt = &now
+		u.MustChangePassword = false
+	})
+}
+
+func (r *fakeUserRepo) SetTemporaryPassword(_ context.Context, id string, hashedPassword string) error {
+	return r.update(id, func(u *model.User) {
+		now := time.Now()
+		u.Password = hashedPassword
+		u.MustChangePassword = true
+		u.FailedAttempts = 0
+		u.LockedUntil = nil
+		u.SessionsRevokedAt = &now
+	})
+}
+
+func (r *fakeUserRepo) UpdateUsername(_ context.Context, id string, username string) error {
+	return r.update(id, func(u *model.User) { u.Username = username })
+}
+
+func (r *fakeUserRepo) UpdateRegistration(_ context.Context, id string, hashedPassword, totpSecret string) error {
+	return r.update(id, func(u *model.User) {
+		u.Password = hashedPassword
+		u.TOTPSecret = &totpSecret
+	})
+}
+
+func (r *fakeUserRepo) SetTOTPSecret(_ context.Context, id string, secret string) error {
+	return r.update(id, func(u *model.User) { u.TOTPSecret = &secret })
+}
+
+func (r *fakeUserRepo) EnableTOTP(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) { u.TOTPEnabled = true })
+}
+
+func (r *fakeUserRepo) Activate(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) { u.IsActive = true })
+}
+
+func (r *fakeUserRepo) SetPendingTOTPSecret(_ context.Context, id string, secret string) error {
+	return r.update(id, func(u *model.User) { u.PendingTOTPSecret = &secret })
+}
+
+func (r *fakeUserRepo) ConfirmTOTPRebind(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) {
+		u.TOTPSecret = u.PendingTOTPSecret
+		u.PendingTOTPSecret = nil
+	})
+}
+
+func (r *fakeUserRepo) ClearPendingTOTPSecret(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) { u.PendingTOTPSecret = nil })
+}
+
+func (r *fakeUserRepo) IncrementFailedAttempts(_ context.Context, id string) (int, error) {
+	var attempts int
+	err := r.update(id, func(u *model.User) {
+		u.FailedAttempts++
+		attempts = u.FailedAttempts
+	})
+	return attempts, err
+}
+
+func (r *fakeUserRepo) LockUntil(_ context.Context, id string, until time.Time) error {
+	return r.update(id, func(u *model.User) {
+		u.LockedUntil = &until
+		u.FailedAttempts = 0
+	})
+}
+
+func (r *fakeUserRepo) ResetLockout(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) {
+		u.FailedAttempts = 0
+		u.LockedUntil = nil
+	})
+}
+
+func (r *fakeUserRepo) RecordLogin(_ context.Context, id string, at time.Time) error {
+	return r.update(id, func(u *model.User) { u.LastLoginAt = &at })
+}
+
+func (r *fakeUserRepo) SetIPAllowlist(_ context.Context, id string, cidrs []string) error {
+	return r.update(id, func(u *model.User) { u.IPAllowlist = cidrs })
+}
+
+func (r *fakeUserRepo) AddTrustedDevice(_ context.Context, id string, device model.TrustedDevice) error {
+	return r.update(id, func(u *model.User) { u.TrustedDevices = append(u.TrustedDevices, device) })
+}
+
+func (r *fakeUserRepo) TouchTrustedDevice(_ context.Context, id string, deviceID string, at time.Time) error {
+	return r.update(id, func(u *model.User) {
+		for i := range u.TrustedDevices {
+			if u.TrustedDevices[i].ID == deviceID {
+				u.TrustedDevices[i].LastUsedAt = &at
+			}
+		}
+	})
+}
+
+func (r *fakeUserRepo) RemoveTrustedDevice(_ context.Context, id string, deviceID string) (bool, error) {
+	removed := false
+	err := r.update(id, func(u *model.User) {
+		kept := u.TrustedDevices[:0]
+		for _, d := range u.TrustedDevices {
+			if d.ID == deviceID {
+				removed = true
+				continue
+			}
+			kept = append(kept, d)
+		}
+		u.TrustedDevices = kept
+	})
+	return removed, err
+}
+
+func (r *fakeUserRepo) ClearTrustedDevices(_ context.Context, id string) error {
+	return r.update(id, func(u *model.User) { u.TrustedDevices = nil })
+}
+
+// fakeRoleRepo is an in-memory adaptor.RoleRepository
+type fakeRoleRepo struct {
+	mu          sync.Mutex
+	roles       map[string]*model.Role
+	permissions map[string][]enum.Permission
+	userRoles   *fakeUserRoleRepo
+}
+
+var _ adaptor.RoleRepository = (*fakeRoleRepo)(nil)
+
+func newFakeRoleRepo(userRoles *fakeUserRoleRepo) *fakeRoleRepo {
+	r := &fakeRoleRepo{
+		roles:       make(map[string]*model.Role),
+		permissions: make(map[string][]enum.Permission),
+		userRoles:   userRoles,
+	}
+	userRoles.roles = r
+	return r
+}
+
+func (r *fakeRoleRepo) Create(_ context.Context, role *model.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	role.ID = primitive.NewObjectID().Hex()
+	c := *role
+	r.roles[role.ID] = &c
+	return nil
+}
+
+func (r *fakeRoleRepo) GetByID(_ context.Context, id string) (*model.Role, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, adaptor.ErrInvalidID
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if role, ok := r.roles[id]; ok {
+		c := *role
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) GetByName(_ context.Context, name string) (*model.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, role := range r.roles {
+		if role.Name == name {
+			c := *role
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepo) Update(_ context.Context, role *model.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *role
+	r.roles[role.ID] = &c
+	return nil
+}
+
+func (r *fakeRoleRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.roles, id)
+	delete(r.permissions, id)
+	return nil
+}
+
+func (r *fakeRoleRepo) List(_ context.Context) ([]model.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roles := make([]model.Role, 0, len(r.roles))
+	for _, role := range r.roles {
+		roles = append(roles, *role)
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepo) GetRolesByUserID(_ context.Context, userID string) ([]model.Role, error) {
+	roleIDs := r.userRoles.roleIDs(userID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	roles := make([]model.Role, 0, len(roleIDs))
+	for _, id := range roleIDs {
+		if role, ok := r.roles[id]; ok {
+			roles = append(roles, *role)
+		}
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepo) AddPermission(_ context.Context, roleID string, permission enum.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.permissions[roleID] = append(r.permissions[roleID], permission)
+	return nil
+}
+
+func (r *fakeRoleRepo) RemovePermission(_ context.Context, roleID string, permission enum.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.permissions[roleID][:0]
+	for _, p := range r.permissions[roleID] {
+		if p != permission {
+			kept = append(kept, p)
+		}
+	}
+	r.permissions[roleID] = kept
+	return nil
+}
+
+func (r *fakeRoleRepo) GetPermissions(_ context.Context, roleID string) ([]enum.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]enum.Permission(nil), r.permissions[roleID]...), nil
+}
+
+func (r *fakeRoleRepo) GetPermissionsForRoles(_ context.Context, roleIDs []string) (map[string][]enum.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string][]enum.Permission, len(roleIDs))
+	for _, id := range roleIDs {
+		result[id] = append([]enum.Permission(nil), r.permissions[id]...)
+	}
+	return result, nil
+}
+
+func (r *fakeRoleRepo) SetPermissions(_ context.Context, roleID string, permissions []enum.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.permissions[roleID] = append([]enum.Permission(nil), permissions...)
+	return nil
+}
+
+// fakeUserRoleRepo is an in-memory adaptor.UserRoleRepository backed by the
+// roles of a fakeRoleRepo
+type fakeUserRoleRepo struct {
+	mu        sync.Mutex
+	userRoles map[string][]string
+	roles     *fakeRoleRepo
+	users     *fakeUserRepo
+}
+
+var _ adaptor.UserRoleRepository = (*fakeUserRoleRepo)(nil)
+
+func (r *fakeUserRoleRepo) roleIDs(userID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.userRoles[userID]...)
+}
+
+func (r *fakeUserRoleRepo) AssignRole(_ context.Context, userID, roleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range r.userRoles[userID] {
+		if id == roleID {
+			return nil
+		}
+	}
+	r.userRoles[userID] = append(r.userRoles[userID], roleID)
+	return nil
+}
+
+func (r *fakeUserRoleRepo) RemoveRole(_ context.Context, userID, roleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.userRoles[userID][:0]
+	for _, id := range r.userRoles[userID] {
+		if id != roleID {
+			kept = append(kept, id)
+		}
+	}
+	r.userRoles[userID] = kept
+	return nil
+}
+
+func (r *fakeUserRoleRepo) GetUserPermissions(ctx context.Context, userID string) ([]enum.Permission, error) {
+	var permissions []enum.Permission
+	for _, roleID := range r.roleIDs(userID) {
+		p, err := r.roles.GetPermissions(ctx, roleID)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p...)
+	}
+	return permissions, nil
+}
+
+func (r *fakeUserRoleRepo) GetRoleIDsForUsers(_ context.Context, userIDs []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(userIDs))
+	for _, id := range userIDs {
+		result[id] = r.roleIDs(id)
+	}
+	return result, nil
+}
+
+func (r *fakeUserRoleRepo) GetUsersByRoleID(ctx context.Context, roleID string) ([]model.User, error) {
+	r.mu.Lock()
+	var userIDs []string
+	for userID, roleIDs := range r.userRoles {
+		for _, id := range roleIDs {
+			if id == roleID {
+				userIDs = append(userIDs, userID)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	users := make([]model.User, 0, len(userIDs))
+	for _, id := range userIDs {
+		if u, _ := r.users.GetByID(ctx, id); u != nil {
+			users = append(users, *u)
+		}
+	}
+	return users, nil
+}
+
+// fakeAuditRepo records audit entries in memory
+type fakeAuditRepo struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+var _ adaptor.AuditRepository = (*fakeAuditRepo)(nil)
+
+func (r *fakeAuditRepo) Create(_ context.Context, entry *model.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+// fakeLoginEventRepo records login events in memory
+type fakeLoginEventRepo struct {
+	mu     sync.Mutex
+	events []model.LoginEvent
+}
+
+var _ adaptor.LoginEventRepository = (*fakeLoginEventRepo)(nil)
+
+func (r *fakeLoginEventRepo) EnsureCollection(context.Context) error { return nil }
+
+func (r *fakeLoginEventRepo) Create(_ context.Context, event *model.LoginEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.CreatedAt = time.Now()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *fakeLoginEventRepo) ListByUser(_ context.Context, userID string, limit int64) ([]model.LoginEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []model.LoginEvent
+	for i := len(r.events) - 1; i >= 0 && int64(len(events)) < limit; i-- {
+		if r.events[i].UserID == userID {
+			events = append(events, r.events[i])
+		}
+	}
+	return events, nil
+}
+
+// last returns the most recent login event
+func (r *fakeLoginEventRepo) last() model.LoginEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return model.LoginEvent{}
+	}
+	return r.events[len(r.events)-1]
+}
+
+// fakeRepos bundles the in-memory repositories an AuthUseCase needs
+type fakeRepos struct {
+	users     *fakeUserRepo
+	roles     *fakeRoleRepo
+	userRoles *fakeUserRoleRepo
+	logins    *fakeLoginEventRepo
+	audit     *fakeAuditRepo
+}
+
+func newFakeRepos() *fakeRepos {
+	users := newFakeUserRepo()
+	userRoles := &fakeUserRoleRepo{userRoles: make(map[string][]string), users: users}
+	return &fakeRepos{
+		users:     users,
+		roles:     newFakeRoleRepo(userRoles),
+		userRoles: userRoles,
+		logins:    &fakeLoginEventRepo{},
+		audit:     &fakeAuditRepo{},
+	}
+}
+
+// fakeSwitcherRepo is an in-memory adaptor.SwitcherRepository that, like the
+// Mongo repository, hands out decoded copies of the stored pairs
+type fakeSwitcherRepo struct {
+	mu        sync.Mutex
+	switchers map[string]map[string]model.SwitcherPair
+}
+
+var _ adaptor.SwitcherRepository = (*fakeSwitcherRepo)(nil)
+
+func newFakeSwitcherRepo() *fakeSwitcherRepo {
+	return &fakeSwitcherRepo{switchers: make(map[string]map[string]model.SwitcherPair)}
+}
+
+// add stores switcher under a new ID and returns it
+func (r *fakeSwitcherRepo) add(switcher *model.Switcher) string {
+	_ = r.Create(context.Background(), switcher)
+	return switcher.MongoID
+}
+
+func (r *fakeSwitcherRepo) GetAll(_ context.Context) ([]model.Switcher, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switchers := make([]model.Switcher, 0, len(r.switchers))
+	for id, pairs := range r.switchers {
+		switchers = append(switchers, *model.NewSwitcher(id, pairs))
+	}
+	return switchers, nil
+}
+
+func (r *fakeSwitcherRepo) GetByID(_ context.Context, id string) (*model.Switcher, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pairs, ok := r.switchers[id]
+	if !ok {
+		return nil, nil
+	}
+	return model.NewSwitcher(id, pairs), nil
+}
+
+func (r *fakeSwitcherRepo) Create(_ context.Context, switcher *model.Switcher) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switcher.MongoID = primitive.NewObjectID().Hex()
+	r.switchers[switcher.MongoID] = model.NewSwitcher("", switcher.Pairs).Pairs
+	return nil
+}
+
+func (r *fakeSwitcherRepo) Update(_ context.Context, switcher *model.Switcher) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.switchers[switcher.MongoID]; ok {
+		r.switchers[switcher.MongoID] = model.NewSwitcher("", switcher.Pairs).Pairs
+	}
+	return nil
+}
+
+func (r *fakeSwitcherRepo) UpdatePair(_ context.Context, id string, pair string, enable bool, _ string, _ time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pairs, ok := r.switchers[id]; ok {
+		pairs[pair] = model.SwitcherPair{Enable: enable}
+	}
+	return nil
+}
+
+func (r *fakeSwitcherRepo) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.switchers, id)
+	return nil
+}
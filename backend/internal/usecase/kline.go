@@ -2,12 +2,31 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/pkg/binance"
+	"control_page/pkg/btcc"
 )
 
 var _ adaptor.KlineUseCase = (*KlineUseCase)(nil)
 
+var (
+	ErrInvalidKlineInterval = errors.New("invalid kline interval")
+	ErrSymbolSearchPlatform = errors.New("symbol search is not supported for this platform")
+)
+
+const (
+	symbolCacheTTL       = 10 * time.Minute
+	maxSymbolSearchItems = 50
+)
+
 // Popular trading pairs on Binance
 var defaultSymbols = []string{
 	"BTCUSDT",
@@ -41,20 +60,136 @@ var defaultIntervals = []string{
 	"1M",
 }
 
-type KlineUseCase struct{}
+type KlineUseCase struct {
+	symbols   []string
+	intervals []string
+
+	httpClient  *http.Client
+	endpoints   *model.ExchangeEndpoints
+	symbolMu    sync.Mutex
+	symbolCache map[model.Platform]symbolCacheEntry
+}
+
+type symbolCacheEntry struct {
+	symbols   []model.SymbolInfo
+	fetchedAt time.Time
+}
+
+// NewKlineUseCase uses the built-in defaults for empty lists. Intervals must
+// be ones Binance supports.
+func NewKlineUseCase(symbols, intervals []string, endpoints *model.ExchangeEndpoints) (*KlineUseCase, error) {
+	uc := &KlineUseCase{
+		endpoints:   endpoints,
+		symbols:     defaultSymbols,
+		intervals:   defaultIntervals,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		symbolCache: make(map[model.Platform]symbolCacheEntry),
+	}
+
+	if len(symbols) > 0 {
+		uc.symbols = make([]string, 0, len(symbols))
+		for _, s := range symbols {
+			if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+				uc.symbols = append(uc.symbols, s)
+			}
+		}
+	}
 
-func NewKlineUseCase() *KlineUseCase {
-	return &KlineUseCase{}
+	if len(intervals) > 0 {
+		binance, _ := model.GetPlatformCapabilities(model.PlatformBinance)
+		uc.intervals = make([]string, 0, len(intervals))
+		for _, i := range intervals {
+			i = strings.TrimSpace(i)
+			if !binance.SupportsInterval(i) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidKlineInterval, i)
+			}
+			uc.intervals = append(uc.intervals, i)
+		}
+	}
+
+	return uc, nil
 }
 
 func (uc *KlineUseCase) GetAvailableSymbols(ctx context.Context) ([]string, error) {
-	symbols := make([]string, len(defaultSymbols))
-	copy(symbols, defaultSymbols)
+	symbols := make([]string, len(uc.symbols))
+	copy(symbols, uc.symbols)
 	return symbols, nil
 }
 
 func (uc *KlineUseCase) GetAvailableIntervals(ctx context.Context) ([]string, error) {
-	intervals := make([]string, len(defaultIntervals))
-	copy(intervals, defaultIntervals)
+	intervals := make([]string, len(uc.intervals))
+	copy(intervals, uc.intervals)
 	return intervals, nil
 }
+
+// SearchSymbols returns tradable symbols on the platform whose name contains
+// query (case-insensitive). The exchange's symbol list is cached for symbolCacheTTL.
+func (uc *KlineUseCase) SearchSymbols(ctx context.Context, platform model.Platform, query string) ([]model.SymbolInfo, error) {
+	all, err := uc.listExchangeSymbols(ctx, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToUpper(strings.TrimSpace(query))
+	result := make([]model.SymbolInfo, 0)
+	for _, s := range all {
+		if strings.Contains(s.Symbol, query) {
+			result = append(result, s)
+			if len(result) == maxSymbolSearchItems {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (uc *KlineUseCase) listExchangeSymbols(ctx context.Context, platform model.Platform) ([]model.SymbolInfo, error) {
+	uc.symbolMu.Lock()
+	defer uc.symbolMu.Unlock()
+
+	if entry, ok := uc.symbolCache[platform]; ok && time.Since(entry.fetchedAt) < symbolCacheTTL {
+		return entry.symbols, nil
+	}
+
+	var symbols []model.SymbolInfo
+	switch platform {
+	case model.PlatformBinance:
+		client := binance.NewClient(uc.endpoints.Config(model.PlatformBinance, false), "", "")
+		infos, err := client.ExchangeInfo(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch binance exchange info: %w", err)
+		}
+		for _, info := range infos {
+			if info.Status != "TRADING" {
+				continue
+			}
+			symbols = append(symbols, model.SymbolInfo{
+				Symbol:   info.Symbol,
+				Base:     info.BaseAsset,
+				Quote:    info.QuoteAsset,
+				Platform: platform,
+			})
+		}
+	case model.PlatformBTCC:
+		markets, err := btcc.FetchMarkets(ctx, uc.httpClient, false)
+		if err != nil {
+			return nil, fmt.Errorf("fetch btcc market list: %w", err)
+		}
+		for _, market := range markets {
+			if !market.Switch {
+				continue
+			}
+			symbols = append(symbols, model.SymbolInfo{
+				Symbol:   strings.ToUpper(market.Name),
+				Base:     market.Stock,
+				Quote:    market.Money,
+				Platform: platform,
+			})
+		}
+	default:
+		return nil, ErrSymbolSearchPlatform
+	}
+
+	uc.symbolCache[platform] = symbolCacheEntry{symbols: symbols, fetchedAt: time.Now()}
+	return symbols, nil
+}
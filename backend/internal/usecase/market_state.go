@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/pkg/binance"
+	"control_page/pkg/btcc"
+)
+
+var _ adaptor.MarketStateUseCase = (*MarketStateUseCase)(nil)
+
+var (
+	ErrMarketSymbolRequired = errors.New("symbol is required")
+	ErrMarketNotFound       = errors.New("market not found")
+	ErrMarketStatePlatform  = errors.New("market state is not supported for this platform")
+)
+
+// binanceStatePeriod is the window of Binance's rolling 24hr ticker, in seconds
+const binanceStatePeriod = 86400
+
+// MarketStateUseCase queries the trading status of a market on the exchange
+// of an API key, normalized to the shape of the trading stream's "state" frames
+type MarketStateUseCase struct {
+	apiKeyRepo adaptor.APIKeyRepository
+	httpClient *http.Client
+	endpoints  *model.ExchangeEndpoints
+}
+
+func NewMarketStateUseCase(apiKeyRepo adaptor.APIKeyRepository, endpoints *model.ExchangeEndpoints) *MarketStateUseCase {
+	return &MarketStateUseCase{
+		apiKeyRepo: apiKeyRepo,
+		endpoints:  endpoints,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (uc *MarketStateUseCase) State(ctx context.Context, apiKeyID, symbol string) (*model.MarketState, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, ErrMarketSymbolRequired
+	}
+
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	switch apiKey.Platform {
+	case model.PlatformBinance:
+		return uc.binanceState(ctx, apiKey.IsTestnet, symbol)
+	case model.PlatformBTCC:
+		return uc.btccState(ctx, apiKey.IsTestnet, symbol)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrMarketStatePlatform, apiKey.Platform)
+	}
+}
+
+// binanceState synthesizes a state from the exchangeInfo trading status and
+// the 24hr ticker; any status other than TRADING means orders are rejected
+func (uc *MarketStateUseCase) binanceState(ctx context.Context, isTestnet bool, symbol string) (*model.MarketState, error) {
+	client := binance.NewClient(uc.endpoints.Config(model.PlatformBinance, isTestnet), "", "")
+
+	info, err := client.SymbolExchangeInfo(ctx, symbol)
+	if err != nil {
+		if binance.IsUnknownSymbol(err) {
+			return nil, fmt.Errorf("%w: %s", ErrMarketNotFound, symbol)
+		}
+		return nil, fmt.Errorf("fetch binance exchange info: %w", err)
+	}
+	ticker, err := client.Ticker24h(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("fetch binance ticker: %w", err)
+	}
+
+	state := &model.MarketState{
+		Symbol:   info.Symbol,
+		Status:   model.MarketStatusOpen,
+		Last:     ticker.LastPrice,
+		High:     ticker.HighPrice,
+		Low:      ticker.LowPrice,
+		Volume:   ticker.Volume,
+		Period:   binanceStatePeriod,
+		Platform: model.PlatformBinance,
+	}
+	if info.Status != "TRADING" {
+		state.Status = model.MarketStatusHalted
+	}
+	return state, nil
+}
+
+// btccState combines state.query statistics with the market's switch flag,
+// which state.query does not report
+func (uc *MarketStateUseCase) btccState(ctx context.Context, isTestnet bool, symbol string) (*model.MarketState, error) {
+	market, err := btcc.FetchMarketDetail(ctx, uc.httpClient, isTestnet, symbol)
+	if err != nil {
+		if errors.Is(err, btcc.ErrUnknownMarket) {
+			return nil, fmt.Errorf("%w: %s", ErrMarketNotFound, symbol)
+		}
+		return nil, fmt.Errorf("fetch btcc market detail: %w", err)
+	}
+
+	state, err := btcc.QueryState(ctx, uc.endpoints.Config(model.PlatformBTCC, isTestnet).BaseWSURL, market.Name, btcc.DefaultStatePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("query btcc market state: %w", err)
+	}
+
+	state.Symbol = strings.ToUpper(market.Name)
+	state.Status = model.MarketStatusOpen
+	state.ReopenTime = 0
+	if !market.Switch {
+		state.Status = model.MarketStatusHalted
+		if market.OpenTime > 0 {
+			state.ReopenTime = market.OpenTime * 1000
+		}
+	}
+	return state, nil
+}
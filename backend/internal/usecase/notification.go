@@ -0,0 +1,212 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+const (
+	notificationTimeout    = 5 * time.Second
+	notificationRetryDelay = time.Second
+
+	// NotificationSignatureHeader carries "sha256=<hex HMAC of the body>"
+	// when a signing secret is configured
+	NotificationSignatureHeader = "X-Signature-256"
+)
+
+var _ adaptor.NotificationUseCase = (*NotificationUseCase)(nil)
+
+// NotificationOptions configures the webhook sink
+type NotificationOptions struct {
+	WebhookURL string
+	Secret     string                   // HMAC-SHA256 signing secret, optional
+	Events     []model.NotificationType // empty sends every type
+	QueueSize  int
+	MaxRetries int
+}
+
+// NotificationUseCase posts critical backend events to a webhook from a
+// background goroutine. Notify never blocks the caller: when the queue is
+// full the notification is dropped and logged. Failed deliveries (network
+// errors, 429 and 5xx) are retried with exponential backoff.
+type NotificationUseCase struct {
+	opts       NotificationOptions
+	events     map[model.NotificationType]bool
+	httpClient *http.Client
+	retryDelay time.Duration // first backoff, doubled on each retry
+
+	queue chan model.Notification
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	dropped atomic.Int64
+}
+
+func NewNotificationUseCase(opts NotificationOptions) *NotificationUseCase {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 100
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+
+	uc := &NotificationUseCase{
+		opts:       opts,
+		events:     make(map[model.NotificationType]bool, len(opts.Events)),
+		httpClient: &http.Client{Timeout: notificationTimeout},
+		retryDelay: notificationRetryDelay,
+		queue:      make(chan model.Notification, opts.QueueSize),
+		done:       make(chan struct{}),
+	}
+	for _, t := range opts.Events {
+		uc.events[t] = true
+	}
+	uc.wg.Add(1)
+	go uc.sendLoop()
+	return uc
+}
+
+// Notify queues n unless its type is filtered out; test notifications are
+// always sent
+func (uc *NotificationUseCase) Notify(n model.Notification) {
+	if len(uc.events) > 0 && !uc.events[n.Type] && n.Type != model.NotificationTest {
+		return
+	}
+	if n.Time.IsZero() {
+		n.Time = time.Now().UTC()
+	}
+
+	select {
+	case uc.queue <- n:
+	default:
+		if d := uc.dropped.Add(1); d%100 == 1 {
+			log.Printf("Warning: notification queue full, %d notifications dropped so far", d)
+		}
+	}
+}
+
+// Close stops the sender after delivering the notifications already queued
+func (uc *NotificationUseCase) Close() {
+	uc.once.Do(func() {
+		close(uc.done)
+		uc.wg.Wait()
+	})
+}
+
+func (uc *NotificationUseCase) sendLoop() {
+	defer uc.wg.Done()
+
+	for {
+		select {
+		case n := <-uc.queue:
+			uc.deliver(n)
+		case <-uc.done:
+			for {
+				select {
+				case n := <-uc.queue:
+					uc.deliver(n)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver posts n, retrying failed attempts; retries are skipped once Close
+// was called so shutdown is not held up by an unreachable webhook
+func (uc *NotificationUseCase) deliver(n model.Notification) {
+	body, err := json.Marshal(newWebhookPayload(n))
+	if err != nil {
+		log.Printf("Warning: marshal notification: %v", err)
+		return
+	}
+
+	delay := uc.retryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := uc.post(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= uc.opts.MaxRetries {
+			log.Printf("Warning: notification %s not delivered: %v", n.Type, err)
+			return
+		}
+
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-uc.done:
+			log.Printf("Warning: notification %s not delivered before shutdown: %v", n.Type, err)
+			return
+		}
+	}
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (uc *NotificationUseCase) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, uc.opts.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if uc.opts.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(uc.opts.Secret))
+		mac.Write(body)
+		req.Header.Set(NotificationSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := uc.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	case resp.StatusCode >= http.StatusBadRequest:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+// webhookPayload is the notification plus a one-line summary under "text"
+// (Slack) and "content" (Discord), so chat webhooks render it unmodified
+type webhookPayload struct {
+	model.Notification
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+func newWebhookPayload(n model.Notification) webhookPayload {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", n.Type, n.Message)
+
+	keys := make([]string, 0, len(n.Fields))
+	for k := range n.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, n.Fields[k])
+	}
+
+	summary := b.String()
+	return webhookPayload{Notification: n, Text: summary, Content: summary}
+}
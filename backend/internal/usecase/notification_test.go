@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"control_page/internal/model"
+)
+
+// webhookRecorder is a webhook endpoint that records each delivery attempt
+type webhookRecorder struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	messages []string
+}
+
+func (r *webhookRecorder) record(req *http.Request) model.Notification {
+	body, _ := io.ReadAll(req.Body)
+	var n model.Notification
+	json.Unmarshal(body, &n)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, body)
+	r.headers = append(r.headers, req.Header.Clone())
+	r.messages = append(r.messages, n.Message)
+	return n
+}
+
+// attempts lists the message of every delivery attempt, oldest first
+func (r *webhookRecorder) attempts() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.messages...)
+}
+
+// newTestNotificationUseCase sends to a webhook answering each attempt with
+// the status chosen for its notification, retrying after a millisecond
+// instead of a second
+func newTestNotificationUseCase(t *testing.T, opts NotificationOptions, status func(model.Notification) int) (*NotificationUseCase, *webhookRecorder) {
+	t.Helper()
+	rec := &webhookRecorder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(status(rec.record(req)))
+	}))
+	t.Cleanup(server.Close)
+
+	opts.WebhookURL = server.URL
+	uc := NewNotificationUseCase(opts)
+	uc.retryDelay = time.Millisecond
+	t.Cleanup(uc.Close)
+	return uc, rec
+}
+
+func TestNotificationSignature(t *testing.T) {
+	ok := func(model.Notification) int { return http.StatusOK }
+
+	signed, rec := newTestNotificationUseCase(t, NotificationOptions{Secret: "webhook-secret"}, ok)
+	signed.Notify(model.Notification{Type: model.NotificationUserLocked, Message: "locked", Fields: map[string]string{"username": "trader"}})
+	signed.Close()
+
+	if len(rec.bodies) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(rec.bodies))
+	}
+	mac := hmac.New(sha256.New, []byte("webhook-secret"))
+	mac.Write(rec.bodies[0])
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := rec.headers[0].Get(NotificationSignatureHeader); got != want {
+		t.Fatalf("%s = %q, want %q", NotificationSignatureHeader, got, want)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(rec.bodies[0], &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Text != "[user_locked] locked username=trader" || payload.Content != payload.Text {
+		t.Fatalf("payload = %+v, want the summary under text and content", payload)
+	}
+
+	unsigned, rec := newTestNotificationUseCase(t, NotificationOptions{}, ok)
+	unsigned.Notify(model.Notification{Type: model.NotificationUserLocked, Message: "locked"})
+	unsigned.Close()
+	if len(rec.headers) != 1 || rec.headers[0].Get(NotificationSignatureHeader) != "" {
+		t.Fatalf("headers = %v, want one unsigned delivery", rec.headers)
+	}
+}
+
+func TestNotificationEventFilter(t *testing.T) {
+	uc, rec := newTestNotificationUseCase(t, NotificationOptions{
+		Events: []model.NotificationType{model.NotificationUserLocked},
+	}, func(model.Notification) int { return http.StatusOK })
+
+	uc.Notify(model.Notification{Type: model.NotificationStreamAuthLost, Message: "filtered"})
+	uc.Notify(model.Notification{Type: model.NotificationTest, Message: "test"})
+	uc.Notify(model.Notification{Type: model.NotificationUserLocked, Message: "locked"})
+	uc.Close()
+
+	// Test notifications pass whatever the filter says
+	if got, want := rec.attempts(), []string{"test", "locked"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("delivered = %v, want %v", got, want)
+	}
+}
+
+func TestNotificationRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{name: "server error", status: http.StatusBadGateway, attempts: 3},
+		{name: "rate limited", status: http.StatusTooManyRequests, attempts: 3},
+		{name: "client error", status: http.StatusBadRequest, attempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivered := make(chan struct{})
+			uc, rec := newTestNotificationUseCase(t, NotificationOptions{MaxRetries: 2}, func(n model.Notification) int {
+				if n.Message == "next" {
+					close(delivered)
+					return http.StatusOK
+				}
+				return tt.status
+			})
+
+			// Deliveries are sequential, so once "next" arrives the first
+			// notification is done retrying
+			uc.Notify(model.Notification{Type: model.NotificationTest, Message: "failing"})
+			uc.Notify(model.Notification{Type: model.NotificationTest, Message: "next"})
+			select {
+			case <-delivered:
+			case <-time.After(5 * time.Second):
+				t.Fatal("second notification not delivered")
+			}
+
+			var want []string
+			for i := 0; i < tt.attempts; i++ {
+				want = append(want, "failing")
+			}
+			want = append(want, "next")
+			if got := rec.attempts(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("attempts = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNotificationFullQueueDrops(t *testing.T) {
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	uc, rec := newTestNotificationUseCase(t, NotificationOptions{QueueSize: 1}, func(model.Notification) int {
+		received <- struct{}{}
+		<-release
+		return http.StatusOK
+	})
+
+	// The first notification holds the sender, the second fills the queue
+	uc.Notify(model.Notification{Type: model.NotificationTest, Message: "in flight"})
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first notification not sent")
+	}
+	uc.Notify(model.Notification{Type: model.NotificationTest, Message: "queued"})
+
+	returned := make(chan struct{})
+	go func() {
+		uc.Notify(model.Notification{Type: model.NotificationTest, Message: "dropped"})
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+	if dropped := uc.dropped.Load(); dropped != 1 {
+		t.Fatalf("dropped = %d, want 1", dropped)
+	}
+
+	close(release)
+	uc.Close()
+	if got, want := rec.attempts(), []string{"in flight", "queued"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("delivered = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var (
+	ErrOrderEventInvalidRange = errors.New("from must not be after to")
+)
+
+const (
+	defaultOrderEventLimit = 100
+	maxOrderEventLimit     = 1000
+)
+
+var _ adaptor.OrderEventUseCase = (*OrderEventUseCase)(nil)
+
+// OrderEventUseCase persists order updates from the trading stream in batches
+// from a background goroutine. Like RecordingUseCase, Record never blocks the
+// broadcast path and drops the oldest queued event when the queue is full.
+type OrderEventUseCase struct {
+	eventRepo  adaptor.OrderEventRepository
+	apiKeyRepo adaptor.APIKeyRepository
+	opts       RecordingOptions
+
+	queue chan model.OrderEvent
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	dropped atomic.Int64
+}
+
+func NewOrderEventUseCase(eventRepo adaptor.OrderEventRepository, apiKeyRepo adaptor.APIKeyRepository, opts RecordingOptions) *OrderEventUseCase {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	uc := &OrderEventUseCase{
+		eventRepo:  eventRepo,
+		apiKeyRepo: apiKeyRepo,
+		opts:       opts,
+		queue:      make(chan model.OrderEvent, opts.BufferSize),
+		done:       make(chan struct{}),
+	}
+	uc.wg.Add(1)
+	go uc.writeLoop()
+	return uc
+}
+
+func (uc *OrderEventUseCase) Record(event model.OrderEvent) {
+	for {
+		select {
+		case uc.queue <- event:
+			return
+		default:
+		}
+
+		// Queue is full: drop the oldest event to make room
+		select {
+		case <-uc.queue:
+			if n := uc.dropped.Add(1); n%1000 == 1 {
+				log.Printf("Warning: order event queue full, %d events dropped so far", n)
+			}
+		default:
+		}
+	}
+}
+
+func (uc *OrderEventUseCase) Query(ctx context.Context, query model.OrderEventQuery) (*model.OrderEventPage, error) {
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, query.APIKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && query.From.After(query.To) {
+		return nil, ErrOrderEventInvalidRange
+	}
+	query.Symbol = strings.ToUpper(strings.TrimSpace(query.Symbol))
+	if query.Limit <= 0 {
+		query.Limit = defaultOrderEventLimit
+	}
+	if query.Limit > maxOrderEventLimit {
+		query.Limit = maxOrderEventLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	return uc.eventRepo.Query(ctx, query)
+}
+
+// Close stops the writer after flushing the events already queued
+func (uc *OrderEventUseCase) Close() {
+	uc.once.Do(func() {
+		close(uc.done)
+		uc.wg.Wait()
+	})
+}
+
+func (uc *OrderEventUseCase) writeLoop() {
+	defer uc.wg.Done()
+
+	ticker := time.NewTicker(uc.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.OrderEvent, 0, uc.opts.BatchSize)
+	for {
+		select {
+		case event := <-uc.queue:
+			batch = append(batch, event)
+			if len(batch) >= uc.opts.BatchSize {
+				batch = uc.flush(batch)
+			}
+		case <-ticker.C:
+			batch = uc.flush(batch)
+		case <-uc.done:
+			for {
+				select {
+				case event := <-uc.queue:
+					batch = append(batch, event)
+					if len(batch) >= uc.opts.BatchSize {
+						batch = uc.flush(batch)
+					}
+				default:
+					uc.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes the batch and returns it emptied for reuse
+func (uc *OrderEventUseCase) flush(batch []model.OrderEvent) []model.OrderEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordWriteTimeout)
+	defer cancel()
+
+	if err := uc.eventRepo.InsertMany(ctx, batch); err != nil {
+		log.Printf("Warning: failed to write %d order events: %v", len(batch), err)
+	}
+	return batch[:0]
+}
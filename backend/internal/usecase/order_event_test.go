@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+// fakeOrderEventRepo keeps each InsertMany batch. When hold is set, the first
+// write reports on entered and waits for hold to be closed.
+type fakeOrderEventRepo struct {
+	adaptor.OrderEventRepository
+	hold    chan struct{}
+	entered chan struct{}
+
+	mu      sync.Mutex
+	batches [][]model.OrderEvent
+	held    bool
+}
+
+func (r *fakeOrderEventRepo) InsertMany(_ context.Context, events []model.OrderEvent) error {
+	r.mu.Lock()
+	wait := r.hold != nil && !r.held
+	r.held = true
+	r.mu.Unlock()
+	if wait {
+		r.entered <- struct{}{}
+		<-r.hold
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The writer reuses its batch slice after the call
+	r.batches = append(r.batches, append([]model.OrderEvent(nil), events...))
+	return nil
+}
+
+// ids lists the ID of each written event by batch
+func (r *fakeOrderEventRepo) ids() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batches := make([][]string, len(r.batches))
+	for i, batch := range r.batches {
+		for _, event := range batch {
+			batches[i] = append(batches[i], event.ID)
+		}
+	}
+	return batches
+}
+
+func TestOrderEventRecordNeverBlocks(t *testing.T) {
+	repo := &fakeOrderEventRepo{hold: make(chan struct{}), entered: make(chan struct{})}
+	uc := NewOrderEventUseCase(repo, nil, RecordingOptions{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour})
+	defer uc.Close()
+
+	// The writer is stuck on the first event, so the queue stays full
+	uc.Record(model.OrderEvent{ID: "E0"})
+	select {
+	case <-repo.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first event not written")
+	}
+
+	returned := make(chan struct{})
+	go func() {
+		for _, id := range []string{"E1", "E2", "E3", "E4"} {
+			uc.Record(model.OrderEvent{ID: id})
+		}
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		close(repo.hold)
+		t.Fatal("Record blocked on a full queue")
+	}
+	if dropped := uc.dropped.Load(); dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+
+	close(repo.hold)
+	uc.Close()
+	want := [][]string{{"E0"}, {"E3"}, {"E4"}}
+	if got := repo.ids(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("written = %v, want the newest events kept %v", got, want)
+	}
+}
+
+func TestOrderEventFlushesOnClose(t *testing.T) {
+	repo := &fakeOrderEventRepo{}
+	uc := NewOrderEventUseCase(repo, nil, RecordingOptions{BatchSize: 100, FlushInterval: time.Hour})
+	for _, id := range []string{"E1", "E2", "E3"} {
+		uc.Record(model.OrderEvent{ID: id})
+	}
+
+	// Neither the batch size nor the interval is reached before Close
+	uc.Close()
+	if got, want := repo.ids(), [][]string{{"E1", "E2", "E3"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("written = %v, want %v", got, want)
+	}
+
+	// Recording after Close neither blocks nor writes
+	uc.Record(model.OrderEvent{ID: "E4"})
+	uc.Close()
+	if got := len(repo.ids()); got != 1 {
+		t.Fatalf("batches = %d after a second Close, want 1", got)
+	}
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"control_page/internal/model"
+)
+
+var ErrWeakPassword = errors.New("password does not meet policy")
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			m[line] = true
+		}
+	}
+	return m
+}()
+
+// validatePassword checks password against the policy, reporting the first rule that fails
+func validatePassword(policy model.PasswordPolicy, password string) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, policy.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireUpper && !hasUpper {
+		return fmt.Errorf("%w: must contain an uppercase letter", ErrWeakPassword)
+	}
+	if policy.RequireLower && !hasLower {
+		return fmt.Errorf("%w: must contain a lowercase letter", ErrWeakPassword)
+	}
+	if policy.RequireDigit && !hasDigit {
+		return fmt.Errorf("%w: must contain a digit", ErrWeakPassword)
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		return fmt.Errorf("%w: must contain a symbol", ErrWeakPassword)
+	}
+	if policy.RejectCommon && commonPasswords[strings.ToLower(password)] {
+		return fmt.Errorf("%w: password is too common", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// temporaryPasswordLength is the minimum length of admin-issued passwords
+const temporaryPasswordLength = 16
+
+// temporaryPasswordClasses leave out look-alike characters so a temporary
+// password can be read out or copied by hand
+var temporaryPasswordClasses = []string{
+	"ABCDEFGHJKLMNPQRSTUVWXYZ",
+	"abcdefghijkmnpqrstuvwxyz",
+	"23456789",
+	"!@#$%^&*-_=+?",
+}
+
+// generateTemporaryPassword returns a random password containing every
+// character class, so it satisfies any policy no longer than it
+func generateTemporaryPassword(policy model.PasswordPolicy) (string, error) {
+	length := max(temporaryPasswordLength, policy.MinLength)
+	all := strings.Join(temporaryPasswordClasses, "")
+
+	password := make([]byte, 0, length)
+	for _, class := range temporaryPasswordClasses {
+		c, err := randomChar(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+	for len(password) < length {
+		c, err := randomChar(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle so the guaranteed characters are not always first
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
+
+func randomChar(chars string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n.Int64()], nil
+}
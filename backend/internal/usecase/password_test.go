@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+// strictPasswordPolicy turns every rule on
+var strictPasswordPolicy = model.PasswordPolicy{
+	MinLength:     10,
+	RequireUpper:  true,
+	RequireLower:  true,
+	RequireDigit:  true,
+	RequireSymbol: true,
+	RejectCommon:  true,
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   model.PasswordPolicy
+		password string
+		want     string // the error message, "" for a valid password
+	}{
+		{name: "default policy accepts six characters", policy: model.DefaultPasswordPolicy(), password: "abcdef"},
+		{name: "default policy rejects five", policy: model.DefaultPasswordPolicy(), password: "abcde", want: "password does not meet policy: must be at least 6 characters"},
+		{name: "default policy allows common passwords", policy: model.DefaultPasswordPolicy(), password: "123456"},
+		{name: "length counts characters not bytes", policy: model.PasswordPolicy{MinLength: 6}, password: "密碼密碼密碼"},
+		{name: "too short", policy: strictPasswordPolicy, password: "Ab1!", want: "password does not meet policy: must be at least 10 characters"},
+		{name: "no uppercase", policy: strictPasswordPolicy, password: "abcdefgh1!", want: "password does not meet policy: must contain an uppercase letter"},
+		{name: "no lowercase", policy: strictPasswordPolicy, password: "ABCDEFGH1!", want: "password does not meet policy: must contain a lowercase letter"},
+		{name: "no digit", policy: strictPasswordPolicy, password: "Abcdefghi!", want: "password does not meet policy: must contain a digit"},
+		{name: "no symbol", policy: strictPasswordPolicy, password: "Abcdefghi1", want: "password does not meet policy: must contain a symbol"},
+		{name: "common in any case", policy: model.PasswordPolicy{MinLength: 6, RejectCommon: true}, password: "QWERTY123", want: "password does not meet policy: password is too common"},
+		{name: "meets every rule", policy: strictPasswordPolicy, password: "Correct-Horse-9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePassword(tt.policy, tt.password)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("validatePassword(%q) error = %v, want nil", tt.password, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrWeakPassword) || err.Error() != tt.want {
+				t.Fatalf("validatePassword(%q) error = %v, want %q", tt.password, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTemporaryPasswordMeetsPolicy(t *testing.T) {
+	for _, policy := range []model.PasswordPolicy{strictPasswordPolicy, {MinLength: 24, RequireSymbol: true}} {
+		for i := 0; i < 20; i++ {
+			password, err := generateTemporaryPassword(policy)
+			if err != nil {
+				t.Fatalf("generateTemporaryPassword() error = %v", err)
+			}
+			if len(password) < max(temporaryPasswordLength, policy.MinLength) {
+				t.Fatalf("temporary password %q is shorter than %d", password, policy.MinLength)
+			}
+			if err := validatePassword(policy, password); err != nil {
+				t.Fatalf("temporary password %q: %v", password, err)
+			}
+		}
+	}
+}
+
+func TestPasswordPolicyEnforcedOnEveryPath(t *testing.T) {
+	ctx := context.Background()
+	repos := newFakeRepos()
+	auth := NewAuthUseCase(
+		repos.users, repos.roles, repos.userRoles, repos.logins,
+		"test-secret", 0, strictPasswordPolicy, model.LockoutPolicy{}, 128, 0, nil,
+	)
+	users := NewUserUseCase(repos.users, repos.roles, repos.userRoles, repos.audit, strictPasswordPolicy, 128)
+	user := createTestUser(t, repos, "alice", nil)
+
+	const weak = "abcdefgh1!"
+	if _, err := auth.Register(ctx, "bob", weak); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("Register() error = %v, want %v", err, ErrWeakPassword)
+	}
+	if err := auth.ChangePassword(ctx, user.ID, testPassword, weak); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, ErrWeakPassword)
+	}
+	if err := users.ValidatePassword(weak); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("ValidatePassword() error = %v, want %v", err, ErrWeakPassword)
+	}
+	if stored := repos.users.find(func(u *model.User) bool { return u.Username == "bob" }); stored != nil {
+		t.Fatalf("Register() stored %+v despite the weak password", stored)
+	}
+
+	if err := auth.ChangePassword(ctx, user.ID, testPassword, "Correct-Horse-9"); err != nil {
+		t.Fatalf("ChangePassword() with a strong password error = %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var _ adaptor.PreferencesUseCase = (*PreferencesUseCase)(nil)
+
+// MaxPreferencesBytes caps the size of a stored preferences blob
+const MaxPreferencesBytes = 64 << 10
+
+var (
+	ErrInvalidPreferences  = errors.New("preferences must be a JSON object")
+	ErrPreferencesTooLarge = errors.New("preferences exceed the size limit")
+	ErrPreferencesConflict = errors.New("preferences were changed by another session")
+)
+
+type PreferencesUseCase struct {
+	prefsRepo adaptor.UserPreferencesRepository
+}
+
+func NewPreferencesUseCase(prefsRepo adaptor.UserPreferencesRepository) *PreferencesUseCase {
+	return &PreferencesUseCase{prefsRepo: prefsRepo}
+}
+
+// GetPreferences returns the user's preferences; a user who never saved any
+// gets an empty object at version 0
+func (uc *PreferencesUseCase) GetPreferences(ctx context.Context, userID string) (*model.UserPreferences, error) {
+	prefs, err := uc.prefsRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = &model.UserPreferences{UserID: userID, Data: json.RawMessage("{}")}
+	}
+	return prefs, nil
+}
+
+// SavePreferences replaces the user's preferences if they are still at
+// version, returning ErrPreferencesConflict otherwise
+func (uc *PreferencesUseCase) SavePreferences(ctx context.Context, userID string, data []byte, version int64) (*model.UserPreferences, error) {
+	if len(data) > MaxPreferencesBytes {
+		return nil, fmt.Errorf("%w of %d bytes", ErrPreferencesTooLarge, MaxPreferencesBytes)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, ErrInvalidPreferences
+	}
+	if compact.Len() == 0 || compact.Bytes()[0] != '{' {
+		return nil, ErrInvalidPreferences
+	}
+
+	prefs := &model.UserPreferences{
+		UserID:  userID,
+		Data:    json.RawMessage(compact.Bytes()),
+		Version: version,
+	}
+	saved, err := uc.prefsRepo.Save(ctx, prefs)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrPreferencesConflict
+	}
+	return prefs, nil
+}
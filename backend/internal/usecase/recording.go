@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var (
+	ErrRecordSymbolRequired = errors.New("symbol is required")
+	ErrRecordInvalidRange   = errors.New("from must not be after to")
+)
+
+const (
+	defaultRecordQueryLimit = 1000
+	maxRecordQueryLimit     = 10000
+	recordWriteTimeout      = 10 * time.Second
+)
+
+var _ adaptor.RecordingUseCase = (*RecordingUseCase)(nil)
+
+// RecordingOptions tunes the recorder's queue and batching
+type RecordingOptions struct {
+	BufferSize    int           // queued events before the oldest are dropped
+	BatchSize     int           // events per InsertMany
+	FlushInterval time.Duration // maximum time an event waits in a partial batch
+}
+
+// RecordingUseCase writes market events to Mongo in batches from a background
+// goroutine. Record never blocks the broadcast path: when the queue is full the
+// oldest queued event is discarded and counted.
+type RecordingUseCase struct {
+	recordRepo adaptor.MarketRecordRepository
+	opts       RecordingOptions
+
+	queue chan model.MarketRecord
+	done  chan struct{}
+	wg    sync.WaitGroup
+	once  sync.Once
+
+	written  atomic.Int64
+	dropped  atomic.Int64
+	failures atomic.Int64
+}
+
+func NewRecordingUseCase(recordRepo adaptor.MarketRecordRepository, opts RecordingOptions) *RecordingUseCase {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+
+	uc := &RecordingUseCase{
+		recordRepo: recordRepo,
+		opts:       opts,
+		queue:      make(chan model.MarketRecord, opts.BufferSize),
+		done:       make(chan struct{}),
+	}
+	uc.wg.Add(1)
+	go uc.writeLoop()
+	return uc
+}
+
+func (uc *RecordingUseCase) Record(record model.MarketRecord) {
+	for {
+		select {
+		case uc.queue <- record:
+			return
+		default:
+		}
+
+		// Queue is full: drop the oldest event to make room
+		select {
+		case <-uc.queue:
+			uc.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+func (uc *RecordingUseCase) Query(ctx context.Context, query model.MarketRecordQuery) ([]model.MarketRecord, error) {
+	if query.Symbol == "" {
+		return nil, ErrRecordSymbolRequired
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && query.From.After(query.To) {
+		return nil, ErrRecordInvalidRange
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultRecordQueryLimit
+	}
+	if query.Limit > maxRecordQueryLimit {
+		query.Limit = maxRecordQueryLimit
+	}
+
+	return uc.recordRepo.Query(ctx, query)
+}
+
+func (uc *RecordingUseCase) Stats() model.RecordingStats {
+	return model.RecordingStats{
+		Queued:   len(uc.queue),
+		Written:  uc.written.Load(),
+		Dropped:  uc.dropped.Load(),
+		Failures: uc.failures.Load(),
+	}
+}
+
+// Close stops the writer after flushing the events already queued
+func (uc *RecordingUseCase) Close() {
+	uc.once.Do(func() {
+		close(uc.done)
+		uc.wg.Wait()
+	})
+}
+
+func (uc *RecordingUseCase) writeLoop() {
+	defer uc.wg.Done()
+
+	ticker := time.NewTicker(uc.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.MarketRecord, 0, uc.opts.BatchSize)
+	for {
+		select {
+		case record := <-uc.queue:
+			batch = append(batch, normalizeRecord(record))
+			if len(batch) >= uc.opts.BatchSize {
+				batch = uc.flush(batch)
+			}
+		case <-ticker.C:
+			batch = uc.flush(batch)
+		case <-uc.done:
+			for {
+				select {
+				case record := <-uc.queue:
+					batch = append(batch, normalizeRecord(record))
+					if len(batch) >= uc.opts.BatchSize {
+						batch = uc.flush(batch)
+					}
+				default:
+					uc.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes the batch and returns it emptied for reuse
+func (uc *RecordingUseCase) flush(batch []model.MarketRecord) []model.MarketRecord {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordWriteTimeout)
+	defer cancel()
+
+	if err := uc.recordRepo.InsertMany(ctx, batch); err != nil {
+		uc.failures.Add(int64(len(batch)))
+		log.Printf("Warning: failed to write %d market records: %v", len(batch), err)
+	} else {
+		uc.written.Add(int64(len(batch)))
+	}
+	return batch[:0]
+}
+
+// normalizeRecord round-trips the payload through JSON so raw exchange
+// messages are stored as documents rather than opaque bytes
+func normalizeRecord(record model.MarketRecord) model.MarketRecord {
+	raw, err := json.Marshal(record.Data)
+	if err != nil {
+		return record
+	}
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err == nil {
+		record.Data = data
+	}
+	return record
+}
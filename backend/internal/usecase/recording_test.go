@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+// fakeRecordRepo keeps each InsertMany batch. When hold is set, the first
+// write reports on entered and waits for hold to be closed.
+type fakeRecordRepo struct {
+	adaptor.MarketRecordRepository
+	err     error
+	hold    chan struct{}
+	entered chan struct{}
+
+	mu      sync.Mutex
+	batches [][]model.MarketRecord
+	held    bool
+}
+
+func (r *fakeRecordRepo) InsertMany(_ context.Context, records []model.MarketRecord) error {
+	r.mu.Lock()
+	wait := r.hold != nil && !r.held
+	r.held = true
+	r.mu.Unlock()
+	if wait {
+		r.entered <- struct{}{}
+		<-r.hold
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The writer reuses its batch slice after the call
+	r.batches = append(r.batches, append([]model.MarketRecord(nil), records...))
+	return r.err
+}
+
+// symbols lists the symbol of each written record by batch
+func (r *fakeRecordRepo) symbols() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	batches := make([][]string, len(r.batches))
+	for i, batch := range r.batches {
+		for _, record := range batch {
+			batches[i] = append(batches[i], record.Symbol)
+		}
+	}
+	return batches
+}
+
+func TestRecordingDropsOldest(t *testing.T) {
+	repo := &fakeRecordRepo{hold: make(chan struct{}), entered: make(chan struct{})}
+	uc := NewRecordingUseCase(repo, RecordingOptions{BufferSize: 3, BatchSize: 1, FlushInterval: time.Hour})
+	defer uc.Close()
+
+	// The writer holds the first record while the queue fills up behind it
+	uc.Record(model.MarketRecord{Symbol: "R0"})
+	select {
+	case <-repo.entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first record not written")
+	}
+	for _, symbol := range []string{"R1", "R2", "R3", "R4", "R5"} {
+		uc.Record(model.MarketRecord{Symbol: symbol})
+	}
+
+	if stats := uc.Stats(); stats.Dropped != 2 || stats.Queued != 3 {
+		t.Errorf("stats = %+v, want 2 dropped and 3 queued", stats)
+	}
+
+	close(repo.hold)
+	uc.Close()
+	want := [][]string{{"R0"}, {"R3"}, {"R4"}, {"R5"}}
+	if got := repo.symbols(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("written = %v, want the newest records kept %v", got, want)
+	}
+	if stats := uc.Stats(); stats.Written != 4 || stats.Queued != 0 {
+		t.Fatalf("stats = %+v, want 4 written and none queued", stats)
+	}
+}
+
+func TestRecordingBatches(t *testing.T) {
+	repo := &fakeRecordRepo{}
+	uc := NewRecordingUseCase(repo, RecordingOptions{BatchSize: 2, FlushInterval: time.Hour})
+	for _, symbol := range []string{"R1", "R2", "R3", "R4", "R5"} {
+		uc.Record(model.MarketRecord{Symbol: symbol, Data: map[string]int{"price": 100}})
+	}
+	uc.Close()
+
+	// Full batches go out as they fill, the remainder on Close
+	want := [][]string{{"R1", "R2"}, {"R3", "R4"}, {"R5"}}
+	if got := repo.symbols(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+	if data := repo.batches[0][0].Data; !reflect.DeepEqual(data, map[string]interface{}{"price": 100.0}) {
+		t.Fatalf("record data = %#v, want it decoded from JSON", data)
+	}
+	if stats := uc.Stats(); stats.Written != 5 || stats.Dropped != 0 {
+		t.Fatalf("stats = %+v, want 5 written", stats)
+	}
+}
+
+func TestRecordingFlushesPartialBatches(t *testing.T) {
+	repo := &fakeRecordRepo{}
+	uc := NewRecordingUseCase(repo, RecordingOptions{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer uc.Close()
+
+	uc.Record(model.MarketRecord{Symbol: "R1"})
+	deadline := time.Now().Add(5 * time.Second)
+	for uc.Stats().Written != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch not flushed on the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRecordingCountsFailures(t *testing.T) {
+	repo := &fakeRecordRepo{err: errors.New("write failed")}
+	uc := NewRecordingUseCase(repo, RecordingOptions{BatchSize: 2, FlushInterval: time.Hour})
+	for _, symbol := range []string{"R1", "R2", "R3"} {
+		uc.Record(model.MarketRecord{Symbol: symbol})
+	}
+	uc.Close()
+
+	if stats := uc.Stats(); stats.Failures != 3 || stats.Written != 0 {
+		t.Fatalf("stats = %+v, want 3 failures and none written", stats)
+	}
+}
@@ -3,6 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
@@ -14,17 +17,34 @@ var _ adaptor.RoleUseCase = (*RoleUseCase)(nil)
 var (
 	ErrRoleNotFound     = errors.New("role not found")
 	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrInvalidPermission = errors.New("invalid permissions")
+	ErrInvalidTokenTTL   = errors.New("max_token_ttl must not be negative")
 )
 
 type RoleUseCase struct {
-	roleRepo adaptor.RoleRepository
+	roleRepo     adaptor.RoleRepository
+	userRoleRepo adaptor.UserRoleRepository
+	auditRepo    adaptor.AuditRepository
 }
 
-func NewRoleUseCase(roleRepo adaptor.RoleRepository) *RoleUseCase {
-	return &RoleUseCase{roleRepo: roleRepo}
+func NewRoleUseCase(roleRepo adaptor.RoleRepository, userRoleRepo adaptor.UserRoleRepository, auditRepo adaptor.AuditRepository) *RoleUseCase {
+	return &RoleUseCase{
+		roleRepo:     roleRepo,
+		userRoleRepo: userRoleRepo,
+		auditRepo:    auditRepo,
+	}
 }
 
-func (uc *RoleUseCase) CreateRole(ctx context.Context, name, description string, permissions []enum.Permission) (*model.RoleWithPermissions, error) {
+func (uc *RoleUseCase) CreateRole(ctx context.Context, name, description string, permissions []enum.Permission, maxTokenTTL int64) (*model.RoleWithPermissions, error) {
+	if maxTokenTTL < 0 {
+		return nil, ErrInvalidTokenTTL
+	}
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if role already exists
 	existing, err := uc.roleRepo.GetByName(ctx, name)
 	if err != nil {
@@ -37,6 +57,7 @@ func (uc *RoleUseCase) CreateRole(ctx context.Context, name, description string,
 	role := &model.Role{
 		Name:        name,
 		Description: description,
+		MaxTokenTTL: maxTokenTTL,
 	}
 
 	if err := uc.roleRepo.Create(ctx, role); err != nil {
@@ -82,22 +103,32 @@ func (uc *RoleUseCase) ListRoles(ctx context.Context) ([]model.RoleWithPermissio
 		return nil, err
 	}
 
+	roleIDs := make([]string, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	permissions, err := uc.roleRepo.GetPermissionsForRoles(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	result := make([]model.RoleWithPermissions, len(roles))
 	for i, role := range roles {
-		permissions, err := uc.roleRepo.GetPermissions(ctx, role.ID)
-		if err != nil {
-			return nil, err
-		}
 		result[i] = model.RoleWithPermissions{
 			Role:        role,
-			Permissions: permissions,
+			Permissions: permissions[role.ID],
 		}
 	}
 
 	return result, nil
 }
 
-func (uc *RoleUseCase) UpdateRole(ctx context.Context, id string, name, description string) (*model.RoleWithPermissions, error) {
+// UpdateRole renames the role; a nil maxTokenTTL keeps the current token TTL
+func (uc *RoleUseCase) UpdateRole(ctx context.Context, id string, name, description string, maxTokenTTL *int64) (*model.RoleWithPermissions, error) {
+	if maxTokenTTL != nil && *maxTokenTTL < 0 {
+		return nil, ErrInvalidTokenTTL
+	}
+
 	role, err := uc.roleRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -119,6 +150,9 @@ func (uc *RoleUseCase) UpdateRole(ctx context.Context, id string, name, descript
 
 	role.Name = name
 	role.Description = description
+	if maxTokenTTL != nil {
+		role.MaxTokenTTL = *maxTokenTTL
+	}
 
 	if err := uc.roleRepo.Update(ctx, role); err != nil {
 		return nil, err
@@ -135,19 +169,70 @@ func (uc *RoleUseCase) UpdateRole(ctx context.Context, id string, name, descript
 	}, nil
 }
 
-func (uc *RoleUseCase) DeleteRole(ctx context.Context, id string) error {
+// GetRoleUsers returns the users currently assigned to the role
+func (uc *RoleUseCase) GetRoleUsers(ctx context.Context, id string) ([]model.User, error) {
 	role, err := uc.roleRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if role == nil {
-		return ErrRoleNotFound
+		return nil, ErrRoleNotFound
+	}
+
+	return uc.userRoleRepo.GetUsersByRoleID(ctx, id)
+}
+
+// DeleteRole deletes the role and returns the users who lost it. A role that
+// is still assigned is only deleted when force is set; otherwise ErrRoleInUse
+// is returned together with the assigned users.
+func (uc *RoleUseCase) DeleteRole(ctx context.Context, actorID, id string, force bool) ([]model.User, error) {
+	role, err := uc.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+
+	users, err := uc.userRoleRepo.GetUsersByRoleID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 && !force {
+		return users, ErrRoleInUse
+	}
+
+	if err := uc.roleRepo.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+
+	affected := make([]map[string]any, len(users))
+	for i, user := range users {
+		affected[i] = map[string]any{"id": user.ID, "username": user.Username}
+	}
+	if err := uc.auditRepo.Create(ctx, &model.AuditEntry{
+		ActorID:    actorID,
+		Action:     "role.delete",
+		TargetType: "role",
+		TargetID:   id,
+		Details: map[string]any{
+			"name":           role.Name,
+			"forced":         force,
+			"affected_users": affected,
+		},
+	}); err != nil {
+		log.Printf("failed to write audit entry role.delete for %s: %v", id, err)
 	}
 
-	return uc.roleRepo.Delete(ctx, id)
+	return users, nil
 }
 
 func (uc *RoleUseCase) SetPermissions(ctx context.Context, roleID string, permissions []enum.Permission) error {
+	permissions, err := normalizePermissions(permissions)
+	if err != nil {
+		return err
+	}
+
 	role, err := uc.roleRepo.GetByID(ctx, roleID)
 	if err != nil {
 		return err
@@ -166,3 +251,30 @@ func (uc *RoleUseCase) GetPermissions(ctx context.Context, roleID string) ([]enu
 func (uc *RoleUseCase) GetAllPermissions() []enum.Permission {
 	return enum.AllPermissions()
 }
+
+// normalizePermissions rejects unknown permissions, listing every invalid
+// entry and the allowed set, and drops duplicates keeping the first occurrence
+func normalizePermissions(permissions []enum.Permission) ([]enum.Permission, error) {
+	result := make([]enum.Permission, 0, len(permissions))
+	seen := make(map[enum.Permission]bool, len(permissions))
+	var invalid []string
+	for _, p := range permissions {
+		if !p.IsValid() {
+			invalid = append(invalid, fmt.Sprintf("%q", p))
+			continue
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+
+	if len(invalid) > 0 {
+		allowed := make([]string, 0, len(enum.AllPermissions()))
+		for _, p := range enum.AllPermissions() {
+			allowed = append(allowed, p.String())
+		}
+		return nil, fmt.Errorf("%w %s, allowed: %s", ErrInvalidPermission, strings.Join(invalid, ", "), strings.Join(allowed, ", "))
+	}
+	return result, nil
+}
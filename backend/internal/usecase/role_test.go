@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+	"control_page/internal/model/enum"
+)
+
+// countingRoleRepo counts the role and permission reads that reach the
+// repository, each of which is one round trip to Mongo
+type countingRoleRepo struct {
+	adaptor.RoleRepository
+	queries *atomic.Int64
+}
+
+func (r countingRoleRepo) List(ctx context.Context) ([]model.Role, error) {
+	r.queries.Add(1)
+	return r.RoleRepository.List(ctx)
+}
+
+func (r countingRoleRepo) GetRolesByUserID(ctx context.Context, userID string) ([]model.Role, error) {
+	r.queries.Add(1)
+	return r.RoleRepository.GetRolesByUserID(ctx, userID)
+}
+
+func (r countingRoleRepo) GetPermissions(ctx context.Context, roleID string) ([]enum.Permission, error) {
+	r.queries.Add(1)
+	return r.RoleRepository.GetPermissions(ctx, roleID)
+}
+
+func (r countingRoleRepo) GetPermissionsForRoles(ctx context.Context, roleIDs []string) (map[string][]enum.Permission, error) {
+	r.queries.Add(1)
+	return r.RoleRepository.GetPermissionsForRoles(ctx, roleIDs)
+}
+
+// countingUserRoleRepo counts the user role reads that reach the repository
+type countingUserRoleRepo struct {
+	adaptor.UserRoleRepository
+	queries *atomic.Int64
+}
+
+func (r countingUserRoleRepo) GetUserPermissions(ctx context.Context, userID string) ([]enum.Permission, error) {
+	r.queries.Add(1)
+	return r.UserRoleRepository.GetUserPermissions(ctx, userID)
+}
+
+func (r countingUserRoleRepo) GetRoleIDsForUsers(ctx context.Context, userIDs []string) (map[string][]string, error) {
+	r.queries.Add(1)
+	return r.UserRoleRepository.GetRoleIDsForUsers(ctx, userIDs)
+}
+
+// addTestRoles creates n roles with two permissions each and returns their IDs
+func addTestRoles(tb testing.TB, repos *fakeRepos, n int) []string {
+	tb.Helper()
+	ctx := context.Background()
+	ids := make([]string, n)
+	for i := range ids {
+		role := &model.Role{Name: fmt.Sprintf("role-%d", i)}
+		if err := repos.roles.Create(ctx, role); err != nil {
+			tb.Fatalf("create role: %v", err)
+		}
+		if err := repos.roles.SetPermissions(ctx, role.ID, []enum.Permission{enum.PermissionViewDashboard, enum.PermissionViewKline}); err != nil {
+			tb.Fatalf("set permissions: %v", err)
+		}
+		ids[i] = role.ID
+	}
+	return ids
+}
+
+func newCountingRoleUseCase(repos *fakeRepos, queries *atomic.Int64) *RoleUseCase {
+	return NewRoleUseCase(countingRoleRepo{repos.roles, queries}, countingUserRoleRepo{repos.userRoles, queries}, repos.audit)
+}
+
+func TestListRolesQueriesDoNotGrowWithRoles(t *testing.T) {
+	for _, n := range []int{1, 10, 100} {
+		repos := newFakeRepos()
+		addTestRoles(t, repos, n)
+		var queries atomic.Int64
+
+		roles, err := newCountingRoleUseCase(repos, &queries).ListRoles(context.Background())
+		if err != nil {
+			t.Fatalf("ListRoles() error = %v", err)
+		}
+		if len(roles) != n {
+			t.Fatalf("ListRoles() = %d roles, want %d", len(roles), n)
+		}
+		for _, role := range roles {
+			if len(role.Permissions) != 2 {
+				t.Fatalf("role %s permissions = %v, want 2", role.Name, role.Permissions)
+			}
+		}
+		// One query for the roles and one for all of their permissions
+		if got := queries.Load(); got != 2 {
+			t.Fatalf("ListRoles() with %d roles made %d queries, want 2", n, got)
+		}
+	}
+}
+
+// BenchmarkListRoles reports the repository round trips per listing, which
+// stay the same however many roles there are
+func BenchmarkListRoles(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("roles=%d", n), func(b *testing.B) {
+			repos := newFakeRepos()
+			addTestRoles(b, repos, n)
+			var queries atomic.Int64
+			uc := newCountingRoleUseCase(repos, &queries)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := uc.ListRoles(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -2,19 +2,119 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
 )
 
 var (
-	ErrSettingNotFound     = errors.New("setting not found")
-	ErrSettingBaseEmpty    = errors.New("base is required")
-	ErrSettingQuoteEmpty   = errors.New("quote is required")
+	ErrSettingNotFound      = errors.New("setting not found")
+	ErrSettingBaseEmpty     = errors.New("base is required")
+	ErrSettingQuoteEmpty    = errors.New("quote is required")
 	ErrSettingStrategyEmpty = errors.New("strategy is required")
+	ErrSettingParameters    = errors.New("invalid parameters")
 )
 
+// maxParameterDepth bounds how deeply setting parameters may nest
+const maxParameterDepth = 8
+
+// ParameterError is one invalid entry of a setting's parameters
+type ParameterError struct {
+	Field   string `json:"field"` // path such as parameters.grid.levels[2]
+	Message string `json:"message"`
+}
+
+// ParametersError lists every invalid entry of a request's parameters
+type ParametersError struct {
+	Fields []ParameterError
+}
+
+func (e *ParametersError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return fmt.Sprintf("%v: %s", ErrSettingParameters, strings.Join(msgs, "; "))
+}
+
+func (e *ParametersError) Unwrap() error {
+	return ErrSettingParameters
+}
+
+// validateParameters checks that params only hold values Mongo stores as-is:
+// finite numbers, strings, booleans, null, arrays and objects with keys that
+// are usable in a dotted update path, nested at most maxParameterDepth levels
+func validateParameters(root string, params map[string]interface{}) error {
+	var fields []ParameterError
+	validateParameterObject(root, params, 1, &fields)
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return &ParametersError{Fields: fields}
+}
+
+func validateParameterObject(path string, obj map[string]interface{}, depth int, fields *[]ParameterError) {
+	for key, value := range obj {
+		field := path + "." + key
+		if msg := parameterKeyProblem(key); msg != "" {
+			*fields = append(*fields, ParameterError{Field: field, Message: msg})
+			continue
+		}
+		validateParameterValue(field, value, depth, fields)
+	}
+}
+
+func validateParameterValue(field string, value interface{}, depth int, fields *[]ParameterError) {
+	switch v := value.(type) {
+	case nil, bool, string:
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			*fields = append(*fields, ParameterError{Field: field, Message: "must be a finite number"})
+		}
+	case json.Number:
+		if f, err := v.Float64(); err != nil || math.IsInf(f, 0) {
+			*fields = append(*fields, ParameterError{Field: field, Message: "must be a finite number"})
+		}
+	case map[string]interface{}:
+		if depth >= maxParameterDepth {
+			*fields = append(*fields, ParameterError{Field: field, Message: fmt.Sprintf("nested deeper than %d levels", maxParameterDepth)})
+			return
+		}
+		validateParameterObject(field, v, depth+1, fields)
+	case []interface{}:
+		if depth >= maxParameterDepth {
+			*fields = append(*fields, ParameterError{Field: field, Message: fmt.Sprintf("nested deeper than %d levels", maxParameterDepth)})
+			return
+		}
+		for i, item := range v {
+			validateParameterValue(fmt.Sprintf("%s[%d]", field, i), item, depth+1, fields)
+		}
+	default:
+		*fields = append(*fields, ParameterError{Field: field, Message: fmt.Sprintf("unsupported type %T", value)})
+	}
+}
+
+// parameterKeyProblem describes why key cannot be stored, or returns ""
+func parameterKeyProblem(key string) string {
+	switch {
+	case key == "":
+		return "key must not be empty"
+	case strings.HasPrefix(key, "$"):
+		return "key must not start with '$'"
+	case strings.Contains(key, "."):
+		return "key must not contain '.'"
+	}
+	return ""
+}
+
 var _ adaptor.SettingUseCase = (*SettingUseCase)(nil)
 
 type SettingUseCase struct {
@@ -67,7 +167,7 @@ func (uc *SettingUseCase) GetByBaseQuote(ctx context.Context, base, quote string
 	return &response, nil
 }
 
-func (uc *SettingUseCase) Create(ctx context.Context, req *model.CreateSettingRequest) (*model.SettingResponse, error) {
+func (uc *SettingUseCase) Create(ctx context.Context, actorID string, req *model.CreateSettingRequest) (*model.SettingResponse, error) {
 	if req.Base == "" {
 		return nil, ErrSettingBaseEmpty
 	}
@@ -77,12 +177,20 @@ func (uc *SettingUseCase) Create(ctx context.Context, req *model.CreateSettingRe
 	if req.Strategy == "" {
 		return nil, ErrSettingStrategyEmpty
 	}
+	if err := validateParameters("parameters", req.Parameters); err != nil {
+		return nil, err
+	}
 
+	now := time.Now()
 	setting := &model.Setting{
 		Base:       req.Base,
 		Quote:      req.Quote,
 		Strategy:   req.Strategy,
 		Parameters: req.Parameters,
+		CreatedBy:  actorID,
+		UpdatedBy:  actorID,
+		CreatedAt:  &now,
+		UpdatedAt:  &now,
 	}
 
 	if err := uc.settingRepo.Create(ctx, setting); err != nil {
@@ -93,7 +201,7 @@ func (uc *SettingUseCase) Create(ctx context.Context, req *model.CreateSettingRe
 	return &response, nil
 }
 
-func (uc *SettingUseCase) Update(ctx context.Context, id string, req *model.UpdateSettingRequest) (*model.SettingResponse, error) {
+func (uc *SettingUseCase) Update(ctx context.Context, actorID, id string, req *model.UpdateSettingRequest) (*model.SettingResponse, error) {
 	setting, err := uc.settingRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -121,9 +229,16 @@ func (uc *SettingUseCase) Update(ctx context.Context, id string, req *model.Upda
 		setting.Strategy = *req.Strategy
 	}
 	if req.Parameters != nil {
+		if err := validateParameters("parameters", req.Parameters); err != nil {
+			return nil, err
+		}
 		setting.Parameters = req.Parameters
 	}
 
+	now := time.Now()
+	setting.UpdatedBy = actorID
+	setting.UpdatedAt = &now
+
 	if err := uc.settingRepo.Update(ctx, setting); err != nil {
 		return nil, err
 	}
@@ -132,7 +247,15 @@ func (uc *SettingUseCase) Update(ctx context.Context, id string, req *model.Upda
 	return &response, nil
 }
 
-func (uc *SettingUseCase) UpdateParameters(ctx context.Context, id string, strategy string, parameters map[string]interface{}) (*model.SettingResponse, error) {
+func (uc *SettingUseCase) UpdateParameters(ctx context.Context, actorID, id string, strategy string, parameters map[string]interface{}) (*model.SettingResponse, error) {
+	// The strategy becomes a key of the parameters document
+	if msg := parameterKeyProblem(strategy); msg != "" {
+		return nil, &ParametersError{Fields: []ParameterError{{Field: "strategy", Message: msg}}}
+	}
+	if err := validateParameters("parameters", parameters); err != nil {
+		return nil, err
+	}
+
 	setting, err := uc.settingRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -141,7 +264,8 @@ func (uc *SettingUseCase) UpdateParameters(ctx context.Context, id string, strat
 		return nil, ErrSettingNotFound
 	}
 
-	if err := uc.settingRepo.UpdateParameters(ctx, id, strategy, parameters); err != nil {
+	now := time.Now()
+	if err := uc.settingRepo.UpdateParameters(ctx, id, strategy, parameters, actorID, now); err != nil {
 		return nil, err
 	}
 
@@ -150,6 +274,8 @@ func (uc *SettingUseCase) UpdateParameters(ctx context.Context, id string, strat
 		setting.Parameters = make(map[string]interface{})
 	}
 	setting.Parameters[strategy] = parameters
+	setting.UpdatedBy = actorID
+	setting.UpdatedAt = &now
 
 	response := setting.ToResponse()
 	return &response, nil
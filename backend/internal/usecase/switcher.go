@@ -3,15 +3,39 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"time"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
 )
 
 var (
-	ErrSwitcherNotFound = errors.New("switcher not found")
+	ErrSwitcherNotFound    = errors.New("switcher not found")
+	ErrSwitcherPairsEmpty  = errors.New("at least one pair is required")
+	ErrSwitcherInvalidPair = errors.New("invalid pair name")
 )
 
+// switcherPairPattern matches trading pair keys such as "SOL_USDT". Pair names
+// become top-level Mongo field names, so anything outside [A-Z0-9_] (notably
+// "$" and ".") must never get through.
+var switcherPairPattern = regexp.MustCompile(`^([A-Z0-9]{1,20})_([A-Z0-9]{2,10})$`)
+
+const switcherPairExamples = "BTC_USDT, ETH_USDC, SOL_BTC"
+
+// switcherQuoteCurrencies lists the quote assets pairs may be traded against
+var switcherQuoteCurrencies = map[string]bool{
+	"USDT":  true,
+	"USDC":  true,
+	"FDUSD": true,
+	"TUSD":  true,
+	"DAI":   true,
+	"BTC":   true,
+	"ETH":   true,
+	"BNB":   true,
+}
+
 var _ adaptor.SwitcherUseCase = (*SwitcherUseCase)(nil)
 
 type SwitcherUseCase struct {
@@ -51,11 +75,18 @@ func (uc *SwitcherUseCase) GetByID(ctx context.Context, id string) (*model.Switc
 	return &response, nil
 }
 
-func (uc *SwitcherUseCase) Create(ctx context.Context, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error) {
-	switcher := &model.Switcher{
-		Pairs: req.Pairs,
+func (uc *SwitcherUseCase) Create(ctx context.Context, actorID string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error) {
+	if err := validateSwitcherPairs(req.Pairs); err != nil {
+		return nil, err
 	}
 
+	now := time.Now()
+	switcher := model.NewSwitcher("", req.Pairs)
+	switcher.CreatedBy = actorID
+	switcher.UpdatedBy = actorID
+	switcher.CreatedAt = &now
+	switcher.UpdatedAt = &now
+
 	if err := uc.switcherRepo.Create(ctx, switcher); err != nil {
 		return nil, err
 	}
@@ -64,7 +95,11 @@ func (uc *SwitcherUseCase) Create(ctx context.Context, req *model.UpdateSwitcher
 	return &response, nil
 }
 
-func (uc *SwitcherUseCase) Update(ctx context.Context, id string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error) {
+func (uc *SwitcherUseCase) Update(ctx context.Context, actorID, id string, req *model.UpdateSwitcherRequest) (*model.SwitcherResponse, error) {
+	if err := validateSwitcherPairs(req.Pairs); err != nil {
+		return nil, err
+	}
+
 	switcher, err := uc.switcherRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -78,6 +113,10 @@ func (uc *SwitcherUseCase) Update(ctx context.Context, id string, req *model.Upd
 		switcher.Pairs[pair] = config
 	}
 
+	now := time.Now()
+	switcher.UpdatedBy = actorID
+	switcher.UpdatedAt = &now
+
 	if err := uc.switcherRepo.Update(ctx, switcher); err != nil {
 		return nil, err
 	}
@@ -86,7 +125,11 @@ func (uc *SwitcherUseCase) Update(ctx context.Context, id string, req *model.Upd
 	return &response, nil
 }
 
-func (uc *SwitcherUseCase) UpdatePair(ctx context.Context, id string, pair string, enable bool) (*model.SwitcherResponse, error) {
+func (uc *SwitcherUseCase) UpdatePair(ctx context.Context, actorID, id string, pair string, enable bool) (*model.SwitcherResponse, error) {
+	if err := validateSwitcherPair(pair); err != nil {
+		return nil, err
+	}
+
 	switcher, err := uc.switcherRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -95,12 +138,15 @@ func (uc *SwitcherUseCase) UpdatePair(ctx context.Context, id string, pair strin
 		return nil, ErrSwitcherNotFound
 	}
 
-	if err := uc.switcherRepo.UpdatePair(ctx, id, pair, enable); err != nil {
+	now := time.Now()
+	if err := uc.switcherRepo.UpdatePair(ctx, id, pair, enable, actorID, now); err != nil {
 		return nil, err
 	}
 
 	// Update local copy for response
 	switcher.Pairs[pair] = model.SwitcherPair{Enable: enable}
+	switcher.UpdatedBy = actorID
+	switcher.UpdatedAt = &now
 
 	response := switcher.ToResponse()
 	return &response, nil
@@ -117,3 +163,28 @@ func (uc *SwitcherUseCase) Delete(ctx context.Context, id string) error {
 
 	return uc.switcherRepo.Delete(ctx, id)
 }
+
+// validateSwitcherPairs ensures at least one pair is present and every key is a well-formed symbol
+func validateSwitcherPairs(pairs map[string]model.SwitcherPair) error {
+	if len(pairs) == 0 {
+		return ErrSwitcherPairsEmpty
+	}
+	for pair := range pairs {
+		if err := validateSwitcherPair(pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSwitcherPair checks a single pair key for BASE_QUOTE form and a known quote currency
+func validateSwitcherPair(pair string) error {
+	match := switcherPairPattern.FindStringSubmatch(pair)
+	if match == nil {
+		return fmt.Errorf("%w %q: expected BASE_QUOTE in upper case, e.g. %s", ErrSwitcherInvalidPair, pair, switcherPairExamples)
+	}
+	if !switcherQuoteCurrencies[match[2]] {
+		return fmt.Errorf("%w %q: unsupported quote currency %s, e.g. %s", ErrSwitcherInvalidPair, pair, match[2], switcherPairExamples)
+	}
+	return nil
+}
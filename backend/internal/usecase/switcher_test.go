@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+func TestSwitcherUpdatesWithEmptyPairs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		update func(uc *SwitcherUseCase, id string) (*model.SwitcherResponse, error)
+	}{
+		{
+			name: "update pair",
+			update: func(uc *SwitcherUseCase, id string) (*model.SwitcherResponse, error) {
+				return uc.UpdatePair(ctx, "admin-id", id, "BTC_USDT", true)
+			},
+		},
+		{
+			name: "update",
+			update: func(uc *SwitcherUseCase, id string) (*model.SwitcherResponse, error) {
+				return uc.Update(ctx, "admin-id", id, &model.UpdateSwitcherRequest{
+					Pairs: map[string]model.SwitcherPair{"BTC_USDT": {Enable: true}},
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeSwitcherRepo()
+			// A document holding no pairs yet, as left behind when every pair was removed
+			id := repo.add(model.NewSwitcher("", map[string]model.SwitcherPair{}))
+			uc := NewSwitcherUseCase(repo)
+
+			resp, err := tt.update(uc, id)
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if pair, ok := resp.Pairs["BTC_USDT"]; !ok || !pair.Enable {
+				t.Fatalf("response pairs = %v, want BTC_USDT enabled", resp.Pairs)
+			}
+
+			stored, err := uc.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("GetByID() error = %v", err)
+			}
+			if pair, ok := stored.Pairs["BTC_USDT"]; !ok || !pair.Enable {
+				t.Fatalf("stored pairs = %v, want BTC_USDT enabled", stored.Pairs)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+
+	"github.com/pquerna/otp"
+)
+
+// totpQRCode renders key as a size x size PNG data URI for authenticator apps
+func totpQRCode(key *otp.Key, size int) (string, error) {
+	img, err := key.Image(size, size)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var _ adaptor.TradingReportUseCase = (*TradingReportUseCase)(nil)
+
+// TradingReportUseCase builds daily fill summaries from persisted order events
+type TradingReportUseCase struct {
+	eventRepo  adaptor.OrderEventRepository
+	apiKeyRepo adaptor.APIKeyRepository
+}
+
+func NewTradingReportUseCase(eventRepo adaptor.OrderEventRepository, apiKeyRepo adaptor.APIKeyRepository) *TradingReportUseCase {
+	return &TradingReportUseCase{
+		eventRepo:  eventRepo,
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+func (uc *TradingReportUseCase) DailyReport(ctx context.Context, apiKeyID string, date time.Time) (*model.TradingReport, error) {
+	apiKey, err := uc.apiKeyRepo.GetByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	y, m, d := date.UTC().Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	// Earlier days are replayed too so the average cost carries over
+	events, err := uc.eventRepo.Timeline(ctx, apiKeyID, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := summarizeOrderEvents(events, start, end)
+	report.APIKeyID = apiKeyID
+	report.Date = start.Format(time.DateOnly)
+	return report, nil
+}
+
+// symbolBook is the running average-cost position of one symbol
+type symbolBook struct {
+	report   model.SymbolReport
+	position float64 // signed: positive long, negative short
+	avgCost  float64
+	touched  bool // had a fill within the reported day
+}
+
+// orderFill is how much of an order has been seen filled so far
+type orderFill struct {
+	qty   float64
+	quote float64
+}
+
+// summarizeOrderEvents replays events (oldest first) and reports the fills
+// received in [start, end). Events carry cumulative executed quantities, so a
+// fill is the increase over the previous update of the same order: repeated
+// or out-of-order updates add nothing, and a canceled remainder never counts.
+func summarizeOrderEvents(events []model.OrderEvent, start, end time.Time) *model.TradingReport {
+	books := make(map[string]*symbolBook)
+	seen := make(map[string]orderFill)
+
+	for _, event := range events {
+		if !event.ReceivedAt.Before(end) {
+			break
+		}
+		o := event.Order
+		symbol := strings.ToUpper(o.Symbol)
+		key := symbol + "|" + o.OrderID
+
+		qty := parseDecimal(o.ExecutedQty)
+		quote := parseDecimal(o.ExecutedQuote)
+		prev := seen[key]
+		if qty <= prev.qty {
+			continue
+		}
+		seen[key] = orderFill{qty: qty, quote: quote}
+
+		fillQty := qty - prev.qty
+		notional := quote - prev.quote
+		if o.ExecutedQuote == "" || notional <= 0 {
+			notional = fillQty * parseDecimal(o.Price)
+		}
+		price := notional / fillQty
+
+		book, ok := books[symbol]
+		if !ok {
+			book = &symbolBook{report: model.SymbolReport{Symbol: symbol}}
+			books[symbol] = book
+		}
+
+		signed := fillQty
+		if o.Side == model.OrderSideSell {
+			signed = -fillQty
+		}
+		realized := book.apply(signed, price)
+
+		if event.ReceivedAt.Before(start) {
+			continue
+		}
+		book.touched = true
+		r := &book.report
+		r.Fills++
+		r.Volume += notional
+		r.RealizedPnL += realized
+		if signed > 0 {
+			r.BoughtQty += fillQty
+			r.BuyNotional += notional
+		} else {
+			r.SoldQty += fillQty
+			r.SellNotional += notional
+		}
+	}
+
+	report := &model.TradingReport{Symbols: make([]model.SymbolReport, 0, len(books))}
+	for _, book := range books {
+		if !book.touched && book.position == 0 {
+			continue
+		}
+		book.report.Position = book.position
+		book.report.AvgCost = book.avgCost
+		report.Symbols = append(report.Symbols, book.report)
+		report.Fills += book.report.Fills
+		report.Volume += book.report.Volume
+		report.RealizedPnL += book.report.RealizedPnL
+	}
+	sort.Slice(report.Symbols, func(i, j int) bool { return report.Symbols[i].Symbol < report.Symbols[j].Symbol })
+	return report
+}
+
+// apply adds a signed fill at price and returns the PnL it realizes. Fills in
+// the direction of the position re-average the cost; opposite fills close at
+// the average cost, and any excess opens a new position at price.
+func (b *symbolBook) apply(signed, price float64) float64 {
+	if b.position == 0 || (b.position > 0) == (signed > 0) {
+		total := math.Abs(b.position) + math.Abs(signed)
+		b.avgCost = (math.Abs(b.position)*b.avgCost + math.Abs(signed)*price) / total
+		b.position += signed
+		return 0
+	}
+
+	closing := math.Min(math.Abs(signed), math.Abs(b.position))
+	realized := closing * (price - b.avgCost)
+	if b.position < 0 {
+		realized = -realized
+	}
+
+	b.position += signed
+	switch {
+	case math.Abs(b.position) < 1e-12:
+		b.position, b.avgCost = 0, 0
+	case (b.position > 0) == (signed > 0):
+		// Flipped sides: the excess opened at the fill price
+		b.avgCost = price
+	}
+	return realized
+}
+
+// parseDecimal reads an exchange decimal string, treating bad input as zero
+func parseDecimal(v string) float64 {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
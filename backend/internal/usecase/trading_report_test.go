@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"control_page/internal/adaptor"
+	"control_page/internal/model"
+)
+
+var reportDay = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// fillEvent is an order update with cumulative executed quantity and quote amount
+func fillEvent(at time.Time, symbol, orderID string, side model.OrderSide, price, executedQty, executedQuote string) model.OrderEvent {
+	return model.OrderEvent{
+		APIKeyID: "key-1",
+		Order: model.Order{
+			Symbol:        symbol,
+			OrderID:       orderID,
+			Side:          side,
+			Price:         price,
+			ExecutedQty:   executedQty,
+			ExecutedQuote: executedQuote,
+		},
+		ReceivedAt: at,
+	}
+}
+
+// reportHour is a time within the reported day
+func reportHour(hour int) time.Time {
+	return reportDay.Add(time.Duration(hour) * time.Hour)
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func assertSymbolReport(t *testing.T, got, want model.SymbolReport) {
+	t.Helper()
+	if got.Symbol != want.Symbol || got.Fills != want.Fills ||
+		!approxEqual(got.BoughtQty, want.BoughtQty) || !approxEqual(got.SoldQty, want.SoldQty) ||
+		!approxEqual(got.BuyNotional, want.BuyNotional) || !approxEqual(got.SellNotional, want.SellNotional) ||
+		!approxEqual(got.Volume, want.Volume) || !approxEqual(got.RealizedPnL, want.RealizedPnL) ||
+		!approxEqual(got.Position, want.Position) || !approxEqual(got.AvgCost, want.AvgCost) {
+		t.Fatalf("symbol report = %+v, want %+v", got, want)
+	}
+}
+
+func TestSummarizeOrderEvents(t *testing.T) {
+	buy, sell := model.OrderSideBuy, model.OrderSideSell
+
+	tests := []struct {
+		name   string
+		events []model.OrderEvent
+		want   []model.SymbolReport
+	}{
+		{
+			name: "partial fills count once each",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "BTCUSDT", "1", buy, "100", "0", ""),
+				fillEvent(reportHour(2), "BTCUSDT", "1", buy, "100", "0.4", "40"),
+				fillEvent(reportHour(2), "BTCUSDT", "1", buy, "100", "0.4", "40"), // repeated update
+				fillEvent(reportHour(3), "BTCUSDT", "1", buy, "100", "1.0", "100"),
+				fillEvent(reportHour(4), "BTCUSDT", "1", buy, "100", "0.4", "40"), // out of order
+			},
+			want: []model.SymbolReport{{
+				Symbol: "BTCUSDT", Fills: 2, BoughtQty: 1, BuyNotional: 100, Volume: 100,
+				Position: 1, AvgCost: 100,
+			}},
+		},
+		{
+			name: "canceled remainder is not a fill",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "BTCUSDT", "1", sell, "100", "0.5", "50"),
+				fillEvent(reportHour(2), "BTCUSDT", "1", sell, "100", "0.5", "50"), // canceled with 1.5 left
+			},
+			want: []model.SymbolReport{{
+				Symbol: "BTCUSDT", Fills: 1, SoldQty: 0.5, SellNotional: 50, Volume: 50,
+				Position: -0.5, AvgCost: 100,
+			}},
+		},
+		{
+			name: "average cost realizes on the closing side",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "BTCUSDT", "1", buy, "100", "1", "100"),
+				fillEvent(reportHour(2), "BTCUSDT", "2", buy, "200", "1", "200"),
+				fillEvent(reportHour(3), "BTCUSDT", "3", sell, "180", "1", "180"),
+			},
+			want: []model.SymbolReport{{
+				Symbol: "BTCUSDT", Fills: 3, BoughtQty: 2, SoldQty: 1, BuyNotional: 300, SellNotional: 180, Volume: 480,
+				RealizedPnL: 30, Position: 1, AvgCost: 150,
+			}},
+		},
+		{
+			name: "covering a short",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "ETHUSDT", "1", sell, "100", "2", "200"),
+				fillEvent(reportHour(2), "ETHUSDT", "2", buy, "90", "2", "180"),
+			},
+			want: []model.SymbolReport{{
+				Symbol: "ETHUSDT", Fills: 2, BoughtQty: 2, SoldQty: 2, BuyNotional: 180, SellNotional: 200, Volume: 380,
+				RealizedPnL: 20,
+			}},
+		},
+		{
+			name: "flipping sides opens the excess at the fill price",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "BTCUSDT", "1", buy, "100", "1", "100"),
+				fillEvent(reportHour(2), "BTCUSDT", "2", sell, "120", "3", "360"),
+			},
+			want: []model.SymbolReport{{
+				Symbol: "BTCUSDT", Fills: 2, BoughtQty: 1, SoldQty: 3, BuyNotional: 100, SellNotional: 360, Volume: 460,
+				RealizedPnL: 20, Position: -2, AvgCost: 120,
+			}},
+		},
+		{
+			name: "market orders use the executed quote amount",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "BTCUSDT", "1", buy, "0", "0.5", "51"),
+				fillEvent(reportHour(2), "BTCUSDT", "1", buy, "0", "1", "101"),
+			},
+			want: []model.SymbolReport{{
+				Symbol: "BTCUSDT", Fills: 2, BoughtQty: 1, BuyNotional: 101, Volume: 101,
+				Position: 1, AvgCost: 101,
+			}},
+		},
+		{
+			name: "earlier days carry the position but not the counters",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(-20), "BTCUSDT", "1", buy, "100", "1", "100"),
+				fillEvent(reportHour(-10), "ETHUSDT", "2", buy, "10", "1", "10"),
+				fillEvent(reportHour(-5), "XRPUSDT", "3", buy, "1", "1", "1"),
+				fillEvent(reportHour(-4), "XRPUSDT", "4", sell, "2", "1", "2"),
+				fillEvent(reportHour(5), "BTCUSDT", "5", sell, "110", "1", "110"),
+				fillEvent(reportHour(30), "BTCUSDT", "6", buy, "100", "5", "500"), // the next day
+			},
+			want: []model.SymbolReport{
+				{Symbol: "BTCUSDT", Fills: 1, SoldQty: 1, SellNotional: 110, Volume: 110, RealizedPnL: 10},
+				{Symbol: "ETHUSDT", Position: 1, AvgCost: 10},
+			},
+		},
+		{
+			name: "orders are told apart per symbol",
+			events: []model.OrderEvent{
+				fillEvent(reportHour(1), "btcusdt", "1", buy, "100", "1", "100"),
+				fillEvent(reportHour(2), "ETHUSDT", "1", buy, "10", "1", "10"),
+			},
+			want: []model.SymbolReport{
+				{Symbol: "BTCUSDT", Fills: 1, BoughtQty: 1, BuyNotional: 100, Volume: 100, Position: 1, AvgCost: 100},
+				{Symbol: "ETHUSDT", Fills: 1, BoughtQty: 1, BuyNotional: 10, Volume: 10, Position: 1, AvgCost: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := summarizeOrderEvents(tt.events, reportDay, reportDay.AddDate(0, 0, 1))
+			if len(report.Symbols) != len(tt.want) {
+				t.Fatalf("symbols = %+v, want %d", report.Symbols, len(tt.want))
+			}
+			var fills int
+			var volume, pnl float64
+			for i, want := range tt.want {
+				assertSymbolReport(t, report.Symbols[i], want)
+				fills += want.Fills
+				volume += want.Volume
+				pnl += want.RealizedPnL
+			}
+			if report.Fills != fills || !approxEqual(report.Volume, volume) || !approxEqual(report.RealizedPnL, pnl) {
+				t.Fatalf("totals = %d fills, %g volume, %g pnl; want %d, %g, %g",
+					report.Fills, report.Volume, report.RealizedPnL, fills, volume, pnl)
+			}
+		})
+	}
+}
+
+// fakeTimelineRepo serves a fixed event timeline and records the bound it was asked for
+type fakeTimelineRepo struct {
+	adaptor.OrderEventRepository
+	events []model.OrderEvent
+	until  time.Time
+}
+
+func (r *fakeTimelineRepo) Timeline(_ context.Context, _ string, until time.Time) ([]model.OrderEvent, error) {
+	r.until = until
+	return r.events, nil
+}
+
+// fakeKeyRepo knows a single API key
+type fakeKeyRepo struct {
+	adaptor.APIKeyRepository
+	id string
+}
+
+func (r *fakeKeyRepo) GetByID(_ context.Context, id string) (*model.APIKey, error) {
+	if id != r.id {
+		return nil, nil
+	}
+	return &model.APIKey{ID: id}, nil
+}
+
+func TestDailyReport(t *testing.T) {
+	ctx := context.Background()
+	events := &fakeTimelineRepo{events: []model.OrderEvent{
+		fillEvent(reportHour(1), "BTCUSDT", "1", model.OrderSideBuy, "100", "1", "100"),
+	}}
+	uc := NewTradingReportUseCase(events, &fakeKeyRepo{id: "key-1"})
+
+	if _, err := uc.DailyReport(ctx, "key-2", reportDay); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("DailyReport(unknown key) error = %v, want %v", err, ErrAPIKeyNotFound)
+	}
+
+	// Any time of the day, in any zone, reports the whole UTC day
+	local := reportHour(15).In(time.FixedZone("UTC+10", 10*60*60))
+	report, err := uc.DailyReport(ctx, "key-1", local)
+	if err != nil {
+		t.Fatalf("DailyReport() error = %v", err)
+	}
+	if report.APIKeyID != "key-1" || report.Date != "2026-03-10" || report.Fills != 1 {
+		t.Fatalf("report = %+v, want key-1 on 2026-03-10 with one fill", report)
+	}
+	if want := reportDay.AddDate(0, 0, 1); !events.until.Equal(want) {
+		t.Fatalf("timeline read until %s, want %s", events.until, want)
+	}
+}
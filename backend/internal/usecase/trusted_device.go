@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"control_page/internal/model"
+)
+
+var ErrTrustedDeviceNotFound = errors.New("trusted device not found")
+
+// rememberDevice stores a trusted device for user and returns the token that
+// lets it skip TOTP until uc.rememberDeviceTTL has passed
+func (uc *AuthUseCase) rememberDevice(ctx context.Context, user *model.User, client model.ClientInfo) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	device := model.TrustedDevice{
+		ID:        hex.EncodeToString(b),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		CreatedAt: now,
+		ExpiresAt: now.Add(uc.rememberDeviceTTL),
+	}
+	if err := uc.userRepo.AddTrustedDevice(ctx, user.ID, device); err != nil {
+		return "", err
+	}
+
+	// The token carries no user_id claim, so ValidateToken never accepts it
+	// as a session token
+	claims := jwt.MapClaims{
+		"sub":       user.ID,
+		"device_id": device.ID,
+		"exp":       device.ExpiresAt.Unix(),
+		"iat":       now.Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(uc.jwtSecret)
+}
+
+// trustedDevice returns the remembered device of user named by token, or nil
+// when remembering devices is disabled or the token is invalid, expired,
+// revoked or issued before the user's sessions were revoked
+func (uc *AuthUseCase) trustedDevice(user *model.User, token string) *model.TrustedDevice {
+	if uc.rememberDeviceTTL <= 0 || token == "" {
+		return nil
+	}
+
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		return uc.jwtSecret, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil
+	}
+	if subject, err := claims.GetSubject(); err != nil || subject != user.ID {
+		return nil
+	}
+	deviceID, ok := claims["device_id"].(string)
+	if !ok {
+		return nil
+	}
+	if user.SessionsRevokedAt != nil {
+		issuedAt, err := claims.GetIssuedAt()
+		if err != nil || issuedAt == nil || issuedAt.Unix() < user.SessionsRevokedAt.Unix() {
+			return nil
+		}
+	}
+
+	now := time.Now()
+	for i := range user.TrustedDevices {
+		if device := &user.TrustedDevices[i]; device.ID == deviceID && now.Before(device.ExpiresAt) {
+			return device
+		}
+	}
+	return nil
+}
+
+// ListTrustedDevices returns the unexpired remembered devices of a user
+func (uc *AuthUseCase) ListTrustedDevices(ctx context.Context, userID string) ([]model.TrustedDevice, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	now := time.Now()
+	devices := make([]model.TrustedDevice, 0, len(user.TrustedDevices))
+	for _, device := range user.TrustedDevices {
+		if now.Before(device.ExpiresAt) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// RevokeTrustedDevice forgets a remembered device, so its next login needs TOTP again
+func (uc *AuthUseCase) RevokeTrustedDevice(ctx context.Context, userID, deviceID string) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	removed, err := uc.userRepo.RemoveTrustedDevice(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrTrustedDeviceNotFound
+	}
+	return nil
+}
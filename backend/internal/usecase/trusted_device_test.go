@@ -0,0 +1,221 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"control_page/internal/model"
+)
+
+const testRememberDeviceTTL = 30 * 24 * time.Hour
+
+func newTestAuthUseCaseWithDevices(repos *fakeRepos) *AuthUseCase {
+	return NewAuthUseCase(
+		repos.users, repos.roles, repos.userRoles, repos.logins,
+		"test-secret", time.Hour,
+		model.PasswordPolicy{MinLength: 6}, model.LockoutPolicy{}, 128, testRememberDeviceTTL, nil,
+	)
+}
+
+// rememberTestDevice completes a TOTP login asking to remember the device and
+// returns the device token
+func rememberTestDevice(t *testing.T, uc *AuthUseCase, user *model.User) string {
+	t.Helper()
+	result, err := uc.VerifyTOTP(context.Background(), user.ID, totpCode(t, *user.TOTPSecret), true, model.ClientInfo{IP: "10.0.0.1", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("VerifyTOTP() error = %v", err)
+	}
+	if result.DeviceToken == "" {
+		t.Fatal("VerifyTOTP() returned no device token")
+	}
+	return result.DeviceToken
+}
+
+func loginWithDevice(t *testing.T, uc *AuthUseCase, username, deviceToken string) *model.LoginResult {
+	t.Helper()
+	result, err := uc.Login(context.Background(), username, testPassword, model.ClientInfo{DeviceToken: deviceToken})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	return result
+}
+
+func TestRememberedDeviceSkipsTOTP(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCaseWithDevices(repos)
+	user := createTestUser(t, repos, "alice", nil)
+
+	token := rememberTestDevice(t, uc, user)
+
+	result := loginWithDevice(t, uc, "alice", token)
+	if result.RequiresTOTP || result.Token == "" {
+		t.Fatalf("Login() with device token = %+v, want a completed login", result)
+	}
+
+	devices, err := uc.ListTrustedDevices(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("ListTrustedDevices() error = %v", err)
+	}
+	if len(devices) != 1 || devices[0].IP != "10.0.0.1" || devices[0].LastUsedAt == nil {
+		t.Fatalf("ListTrustedDevices() = %+v, want one used device from 10.0.0.1", devices)
+	}
+
+	// The device token still needs the right password
+	if _, err := uc.Login(context.Background(), "alice", "wrong-password", model.ClientInfo{DeviceToken: token}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Login() with wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+}
+
+func TestDeviceTokenIsNotASessionToken(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCaseWithDevices(repos)
+	user := createTestUser(t, repos, "alice", nil)
+
+	token := rememberTestDevice(t, uc, user)
+	if _, err := uc.ValidateToken(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("ValidateToken(device token) error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestDeviceTokenRequiresTOTPWhen(t *testing.T) {
+	tests := []struct {
+		name string
+		// invalidate runs after the device was remembered and returns the
+		// token to log in with
+		invalidate func(t *testing.T, repos *fakeRepos, user *model.User, token string) string
+		useCase    func(repos *fakeRepos) *AuthUseCase
+	}{
+		{
+			name: "feature disabled",
+			useCase: func(repos *fakeRepos) *AuthUseCase {
+				return newTestAuthUseCase(repos, model.LockoutPolicy{})
+			},
+		},
+		{
+			name: "token of another user",
+			invalidate: func(t *testing.T, repos *fakeRepos, _ *model.User, _ string) string {
+				uc := newTestAuthUseCaseWithDevices(repos)
+				return rememberTestDevice(t, uc, createTestUser(t, repos, "bob", nil))
+			},
+		},
+		{
+			name: "tampered token",
+			invalidate: func(_ *testing.T, _ *fakeRepos, _ *model.User, token string) string {
+				return token + "x"
+			},
+		},
+		{
+			name: "device expired",
+			invalidate: func(t *testing.T, repos *fakeRepos, user *model.User, token string) string {
+				expired := time.Now().Add(-time.Minute)
+				_ = repos.users.update(user.ID, func(u *model.User) { u.TrustedDevices[0].ExpiresAt = expired })
+				return token
+			},
+		},
+		{
+			name: "device revoked",
+			invalidate: func(t *testing.T, repos *fakeRepos, user *model.User, token string) string {
+				stored, _ := repos.users.GetByID(context.Background(), user.ID)
+				uc := newTestAuthUseCaseWithDevices(repos)
+				if err := uc.RevokeTrustedDevice(context.Background(), user.ID, stored.TrustedDevices[0].ID); err != nil {
+					t.Fatalf("RevokeTrustedDevice() error = %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "admin password reset",
+			invalidate: func(t *testing.T, repos *fakeRepos, user *model.User, token string) string {
+				if _, err := newTestUserUseCase(repos).ResetPassword(context.Background(), "admin-id", user.ID); err != nil {
+					t.Fatalf("ResetPassword() error = %v", err)
+				}
+				// Log in with the original password to isolate the device check
+				restorePassword(t, repos, user)
+				return token
+			},
+		},
+		{
+			name: "admin TOTP reset",
+			invalidate: func(t *testing.T, repos *fakeRepos, user *model.User, token string) string {
+				if _, err := newTestUserUseCase(repos).ResetUserTOTP(context.Background(), user.ID); err != nil {
+					t.Fatalf("ResetUserTOTP() error = %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "confirmed TOTP rebind",
+			invalidate: func(t *testing.T, repos *fakeRepos, user *model.User, token string) string {
+				uc := newTestAuthUseCaseWithDevices(repos)
+				setup, err := uc.SetupTOTPRebind(context.Background(), user.ID, testPassword)
+				if err != nil {
+					t.Fatalf("SetupTOTPRebind() error = %v", err)
+				}
+				if err := uc.ConfirmTOTPRebind(context.Background(), user.ID, totpCode(t, setup.Secret)); err != nil {
+					t.Fatalf("ConfirmTOTPRebind() error = %v", err)
+				}
+				return token
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := newFakeRepos()
+			user := createTestUser(t, repos, "alice", nil)
+			token := rememberTestDevice(t, newTestAuthUseCaseWithDevices(repos), user)
+			if tt.invalidate != nil {
+				token = tt.invalidate(t, repos, user, token)
+			}
+			uc := newTestAuthUseCaseWithDevices(repos)
+			if tt.useCase != nil {
+				uc = tt.useCase(repos)
+			}
+
+			result := loginWithDevice(t, uc, "alice", token)
+			if !result.RequiresTOTP || result.Token != "" {
+				t.Fatalf("Login() = %+v, want TOTP required", result)
+			}
+		})
+	}
+}
+
+func TestRevokeTrustedDeviceErrors(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCaseWithDevices(repos)
+	user := createTestUser(t, repos, "alice", nil)
+	ctx := context.Background()
+
+	if err := uc.RevokeTrustedDevice(ctx, user.ID, "unknown"); !errors.Is(err, ErrTrustedDeviceNotFound) {
+		t.Fatalf("RevokeTrustedDevice(unknown device) error = %v, want %v", err, ErrTrustedDeviceNotFound)
+	}
+	if err := uc.RevokeTrustedDevice(ctx, "64b7f0000000000000000000", "unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("RevokeTrustedDevice(unknown user) error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestRememberDeviceIgnoredWhenDisabled(t *testing.T) {
+	repos := newFakeRepos()
+	uc := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	user := createTestUser(t, repos, "alice", nil)
+
+	result, err := uc.VerifyTOTP(context.Background(), user.ID, totpCode(t, *user.TOTPSecret), true, model.ClientInfo{})
+	if err != nil {
+		t.Fatalf("VerifyTOTP() error = %v", err)
+	}
+	if result.DeviceToken != "" {
+		t.Fatal("VerifyTOTP() returned a device token with remembering disabled")
+	}
+	if stored, _ := repos.users.GetByID(context.Background(), user.ID); len(stored.TrustedDevices) != 0 {
+		t.Fatalf("stored devices = %+v, want none", stored.TrustedDevices)
+	}
+}
+
+// restorePassword sets the stored password back to testPassword without
+// touching the rest of the account
+func restorePassword(t *testing.T, repos *fakeRepos, user *model.User) {
+	t.Helper()
+	_ = repos.users.update(user.ID, func(u *model.User) { u.Password = user.Password })
+}
@@ -1,38 +1,92 @@
 package usecase
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
-	"image/png"
+	"log"
+	"net/mail"
+	"net/netip"
+	"slices"
+	"strings"
 
 	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
 
 	"control_page/internal/adaptor"
 	"control_page/internal/model"
+	"control_page/internal/model/enum"
 )
 
 var _ adaptor.UserUseCase = (*UserUseCase)(nil)
 
+var (
+	ErrUsernamePasswordRequired = errors.New("username and password are required")
+	ErrInvalidRoleID            = errors.New("invalid role id")
+	ErrInvalidCIDR              = errors.New("invalid cidr")
+)
+
+// UnknownRolesError lists role IDs that are malformed or name no existing role
+type UnknownRolesError struct {
+	RoleIDs []string
+}
+
+func (e *UnknownRolesError) Error() string {
+	return fmt.Sprintf("unknown role ids: %s", strings.Join(e.RoleIDs, ", "))
+}
+
+func (e *UnknownRolesError) Unwrap() error {
+	return ErrInvalidRoleID
+}
+
+// InvalidCIDRError lists IP allowlist entries that are not CIDR ranges
+type InvalidCIDRError struct {
+	Entries []string
+}
+
+func (e *InvalidCIDRError) Error() string {
+	return fmt.Sprintf("invalid cidr entries: %s", strings.Join(e.Entries, ", "))
+}
+
+func (e *InvalidCIDRError) Unwrap() error {
+	return ErrInvalidCIDR
+}
+
 type UserUseCase struct {
 	userRepo     adaptor.UserRepository
 	roleRepo     adaptor.RoleRepository
 	userRoleRepo adaptor.UserRoleRepository
+	auditRepo    adaptor.AuditRepository
+
+	passwordPolicy model.PasswordPolicy
+	totpQRSize     int
 }
 
 func NewUserUseCase(
 	userRepo adaptor.UserRepository,
 	roleRepo adaptor.RoleRepository,
 	userRoleRepo adaptor.UserRoleRepository,
+	auditRepo adaptor.AuditRepository,
+	passwordPolicy model.PasswordPolicy,
+	totpQRSize int,
 ) *UserUseCase {
 	return &UserUseCase{
 		userRepo:     userRepo,
 		roleRepo:     roleRepo,
 		userRoleRepo: userRoleRepo,
+		auditRepo:    auditRepo,
+
+		passwordPolicy: passwordPolicy,
+		totpQRSize:     totpQRSize,
 	}
 }
 
+// ValidatePassword checks a plaintext password against the configured policy
+// before the caller hashes it for CreateUser
+func (uc *UserUseCase) ValidatePassword(password string) error {
+	return validatePassword(uc.passwordPolicy, password)
+}
+
 func (uc *UserUseCase) GetUser(ctx context.Context, id string) (*model.UserWithRoles, error) {
 	user, err := uc.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -64,16 +118,49 @@ func (uc *UserUseCase) ListUsers(ctx context.Context) ([]model.UserWithRoles, er
 		return nil, err
 	}
 
-	result := make([]model.UserWithRoles, len(users))
+	// Resolve roles and permissions for all users with a fixed number of
+	// queries instead of two per user
+	userIDs := make([]string, len(users))
 	for i, user := range users {
-		roles, err := uc.roleRepo.GetRolesByUserID(ctx, user.ID)
-		if err != nil {
-			return nil, err
-		}
+		userIDs[i] = user.ID
+	}
+	assignments, err := uc.userRoleRepo.GetRoleIDsForUsers(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		permissions, err := uc.userRoleRepo.GetUserPermissions(ctx, user.ID)
-		if err != nil {
-			return nil, err
+	allRoles, err := uc.roleRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rolesByID := make(map[string]model.Role, len(allRoles))
+	roleIDs := make([]string, 0, len(allRoles))
+	for _, role := range allRoles {
+		rolesByID[role.ID] = role
+		roleIDs = append(roleIDs, role.ID)
+	}
+	rolePermissions, err := uc.roleRepo.GetPermissionsForRoles(ctx, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.UserWithRoles, len(users))
+	for i, user := range users {
+		roles := make([]model.Role, 0, len(assignments[user.ID]))
+		permissions := make([]enum.Permission, 0)
+		seen := make(map[enum.Permission]bool)
+		for _, roleID := range assignments[user.ID] {
+			role, ok := rolesByID[roleID]
+			if !ok {
+				continue // assignment to a deleted role
+			}
+			roles = append(roles, role)
+			for _, permission := range rolePermissions[roleID] {
+				if !seen[permission] {
+					seen[permission] = true
+					permissions = append(permissions, permission)
+				}
+			}
 		}
 
 		result[i] = model.UserWithRoles{
@@ -88,7 +175,7 @@ func (uc *UserUseCase) ListUsers(ctx context.Context) ([]model.UserWithRoles, er
 
 func (uc *UserUseCase) CreateUser(ctx context.Context, user *model.User, roleIDs []string) (*model.UserWithRoles, error) {
 	if user.Username == "" || user.Password == "" {
-		return nil, fmt.Errorf("username and password are required")
+		return nil, ErrUsernamePasswordRequired
 	}
 
 	// Ensure username unique
@@ -97,18 +184,27 @@ func (uc *UserUseCase) CreateUser(ctx context.Context, user *model.User, roleIDs
 		return nil, err
 	}
 	if existing != nil {
-		return nil, fmt.Errorf("user already exists")
+		return nil, ErrUserAlreadyExists
+	}
+
+	if err := uc.checkEmail(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := uc.ValidateRoles(ctx, roleIDs); err != nil {
+		return nil, err
 	}
 
 	if err := uc.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
 
-	// Assign roles if provided
-	for _, roleID := range roleIDs {
-		if err := uc.userRoleRepo.AssignRole(ctx, user.ID, roleID); err != nil {
-			return nil, err
+	// Assign roles if provided; a failure leaves no half-created user behind
+	if err := uc.applyRoles(ctx, user.ID, roleIDs, nil); err != nil {
+		if delErr := uc.userRepo.Delete(ctx, user.ID); delErr != nil {
+			log.Printf("create user %s: roll back after role assignment failure: %v", user.ID, delErr)
 		}
+		return nil, err
 	}
 
 	roles, err := uc.roleRepo.GetRolesByUserID(ctx, user.ID)
@@ -128,21 +224,262 @@ func (uc *UserUseCase) CreateUser(ctx context.Context, user *model.User, roleIDs
 }
 
 func (uc *UserUseCase) UpdateUser(ctx context.Context, user *model.User) error {
+	if err := uc.checkEmail(ctx, user); err != nil {
+		return err
+	}
 	return uc.userRepo.Update(ctx, user)
 }
 
+// checkEmail normalizes user.Email and rejects malformed addresses or ones
+// already held by a different user; an empty email is allowed
+func (uc *UserUseCase) checkEmail(ctx context.Context, user *model.User) error {
+	email := strings.ToLower(strings.TrimSpace(user.Email))
+	user.Email = email
+	if email == "" {
+		return nil
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return ErrInvalidEmail
+	}
+
+	existing, err := uc.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != user.ID {
+		return ErrEmailAlreadyExists
+	}
+	return nil
+}
+
 func (uc *UserUseCase) DeleteUser(ctx context.Context, id string) error {
 	return uc.userRepo.Delete(ctx, id)
 }
 
 func (uc *UserUseCase) AssignRole(ctx context.Context, userID, roleID string) error {
+	if err := uc.ValidateRoles(ctx, []string{roleID}); err != nil {
+		return err
+	}
 	return uc.userRoleRepo.AssignRole(ctx, userID, roleID)
 }
 
+// ValidateRoles returns an *UnknownRolesError listing the IDs that are
+// malformed or do not name an existing role
+func (uc *UserUseCase) ValidateRoles(ctx context.Context, roleIDs []string) error {
+	var unknown []string
+	for _, roleID := range roleIDs {
+		role, err := uc.roleRepo.GetByID(ctx, roleID)
+		if err != nil && !errors.Is(err, ErrInvalidID) {
+			return err
+		}
+		if role == nil {
+			unknown = append(unknown, roleID)
+		}
+	}
+	if len(unknown) > 0 {
+		return &UnknownRolesError{RoleIDs: unknown}
+	}
+	return nil
+}
+
+// SetRoles replaces the user's roles with roleIDs. Every ID is validated
+// first, and the changes already applied are reverted if a later one fails.
+func (uc *UserUseCase) SetRoles(ctx context.Context, userID string, roleIDs []string) error {
+	if err := uc.ValidateRoles(ctx, roleIDs); err != nil {
+		return err
+	}
+
+	current, err := uc.roleRepo.GetRolesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	currentIDs := make([]string, len(current))
+	for i, role := range current {
+		currentIDs[i] = role.ID
+	}
+	return uc.applyRoles(ctx, userID, roleIDs, currentIDs)
+}
+
+// applyRoles moves the user from the current to the target role set, undoing
+// its own changes on failure
+func (uc *UserUseCase) applyRoles(ctx context.Context, userID string, target, current []string) error {
+	targetSet := make(map[string]bool, len(target))
+	for _, id := range target {
+		targetSet[id] = true
+	}
+	currentSet := make(map[string]bool, len(current))
+	for _, id := range current {
+		currentSet[id] = true
+	}
+
+	var assigned, removed []string
+	rollback := func() {
+		for _, id := range assigned {
+			if err := uc.userRoleRepo.RemoveRole(ctx, userID, id); err != nil {
+				log.Printf("roll back role %s of user %s: %v", id, userID, err)
+			}
+		}
+		for _, id := range removed {
+			if err := uc.userRoleRepo.AssignRole(ctx, userID, id); err != nil {
+				log.Printf("roll back role %s of user %s: %v", id, userID, err)
+			}
+		}
+	}
+
+	for _, id := range target {
+		if currentSet[id] {
+			continue
+		}
+		if err := uc.userRoleRepo.AssignRole(ctx, userID, id); err != nil {
+			rollback()
+			return fmt.Errorf("assign role %s: %w", id, err)
+		}
+		currentSet[id] = true
+		assigned = append(assigned, id)
+	}
+	for _, id := range current {
+		if targetSet[id] {
+			continue
+		}
+		if err := uc.userRoleRepo.RemoveRole(ctx, userID, id); err != nil {
+			rollback()
+			return fmt.Errorf("remove role %s: %w", id, err)
+		}
+		removed = append(removed, id)
+	}
+	return nil
+}
+
 func (uc *UserUseCase) RemoveRole(ctx context.Context, userID, roleID string) error {
 	return uc.userRoleRepo.RemoveRole(ctx, userID, roleID)
 }
 
+// UnlockUser clears a login lockout before its cooldown expires
+func (uc *UserUseCase) UnlockUser(ctx context.Context, actorID, userID string) (*model.UserWithRoles, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := uc.userRepo.ResetLockout(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	uc.audit(ctx, &model.AuditEntry{
+		ActorID:    actorID,
+		Action:     "user.unlock",
+		TargetType: "user",
+		TargetID:   userID,
+		Details: map[string]any{
+			"failed_attempts": user.FailedAttempts,
+			"locked_until":    user.LockedUntil,
+		},
+	})
+
+	return uc.GetUser(ctx, userID)
+}
+
+// ResetPassword replaces the user's password with a random temporary one and
+// flags the account so the user must change it after their next login. The
+// temporary password is returned to the caller once and never stored in
+// plaintext or sent anywhere else.
+func (uc *UserUseCase) ResetPassword(ctx context.Context, actorID, userID string) (string, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrUserNotFound
+	}
+
+	password, err := generateTemporaryPassword(uc.passwordPolicy)
+	if err != nil {
+		return "", err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	if err := uc.userRepo.SetTemporaryPassword(ctx, userID, string(hashedPassword)); err != nil {
+		return "", err
+	}
+	// Remembered devices would otherwise still skip TOTP for the reset account
+	if err := uc.userRepo.ClearTrustedDevices(ctx, userID); err != nil {
+		return "", err
+	}
+
+	uc.audit(ctx, &model.AuditEntry{
+		ActorID:    actorID,
+		Action:     "user.password_reset",
+		TargetType: "user",
+		TargetID:   userID,
+		Details: map[string]any{
+			"username": user.Username,
+		},
+	})
+
+	return password, nil
+}
+
+// SetIPAllowlist replaces the CIDR ranges the user may connect from. Entries
+// are normalized to their network address and deduplicated; an empty list
+// lifts the restriction.
+func (uc *UserUseCase) SetIPAllowlist(ctx context.Context, actorID, userID string, cidrs []string) (*model.UserWithRoles, error) {
+	normalized := make([]string, 0, len(cidrs))
+	var invalid []string
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			invalid = append(invalid, cidr)
+			continue
+		}
+		if entry := prefix.Masked().String(); !slices.Contains(normalized, entry) {
+			normalized = append(normalized, entry)
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, &InvalidCIDRError{Entries: invalid}
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := uc.userRepo.SetIPAllowlist(ctx, userID, normalized); err != nil {
+		return nil, err
+	}
+
+	uc.audit(ctx, &model.AuditEntry{
+		ActorID:    actorID,
+		Action:     "user.ip_allowlist_update",
+		TargetType: "user",
+		TargetID:   userID,
+		Details: map[string]any{
+			"before": user.IPAllowlist,
+			"after":  normalized,
+		},
+	})
+
+	return uc.GetUser(ctx, userID)
+}
+
+// audit records an entry; failures are logged rather than failing the action
+func (uc *UserUseCase) audit(ctx context.Context, entry *model.AuditEntry) {
+	if err := uc.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("failed to write audit entry %s for %s: %v", entry.Action, entry.TargetID, err)
+	}
+}
+
 func (uc *UserUseCase) ResetUserTOTP(ctx context.Context, userID string) (*model.TOTPSetup, error) {
 	user, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
@@ -167,21 +504,19 @@ func (uc *UserUseCase) ResetUserTOTP(ctx context.Context, userID string) (*model
 	if err := uc.userRepo.EnableTOTP(ctx, userID); err != nil {
 		return nil, err
 	}
-
-	img, err := key.Image(200, 200)
-	if err != nil {
+	// Devices remembered under the old secret must prove the new one
+	if err := uc.userRepo.ClearTrustedDevices(ctx, userID); err != nil {
 		return nil, err
 	}
 
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
+	qrCode, err := totpQRCode(key, uc.totpQRSize)
+	if err != nil {
 		return nil, err
 	}
 
-	qrCode := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
-
 	return &model.TOTPSetup{
-		Secret: secret,
-		QRCode: qrCode,
+		Secret:     secret,
+		QRCode:     qrCode,
+		OTPAuthURL: key.URL(),
 	}, nil
 }
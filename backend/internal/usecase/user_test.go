@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+func newTestUserUseCase(repos *fakeRepos) *UserUseCase {
+	return NewUserUseCase(repos.users, repos.roles, repos.userRoles, repos.audit, model.PasswordPolicy{MinLength: 6}, 128)
+}
+
+// addTestUsers creates n users, each assigned to every role in roleIDs
+func addTestUsers(tb testing.TB, repos *fakeRepos, n int, roleIDs []string) {
+	tb.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		user := &model.User{Username: fmt.Sprintf("user-%d", i), IsActive: true}
+		if err := repos.users.Create(ctx, user); err != nil {
+			tb.Fatalf("create user: %v", err)
+		}
+		for _, roleID := range roleIDs {
+			if err := repos.userRoles.AssignRole(ctx, user.ID, roleID); err != nil {
+				tb.Fatalf("assign role: %v", err)
+			}
+		}
+	}
+}
+
+func newCountingUserUseCase(repos *fakeRepos, queries *atomic.Int64) *UserUseCase {
+	return NewUserUseCase(repos.users, countingRoleRepo{repos.roles, queries}, countingUserRoleRepo{repos.userRoles, queries}, repos.audit, model.PasswordPolicy{MinLength: 6}, 128)
+}
+
+func TestListUsersQueriesDoNotGrowWithUsersOrRoles(t *testing.T) {
+	for _, n := range []int{1, 10, 100} {
+		repos := newFakeRepos()
+		addTestUsers(t, repos, n, addTestRoles(t, repos, n))
+		var queries atomic.Int64
+
+		users, err := newCountingUserUseCase(repos, &queries).ListUsers(context.Background())
+		if err != nil {
+			t.Fatalf("ListUsers() error = %v", err)
+		}
+		if len(users) != n {
+			t.Fatalf("ListUsers() = %d users, want %d", len(users), n)
+		}
+		for _, user := range users {
+			if len(user.Roles) != n || len(user.Permissions) != 2 {
+				t.Fatalf("user %s has %d roles and permissions %v, want %d roles and 2 permissions", user.Username, len(user.Roles), user.Permissions, n)
+			}
+		}
+		// Role assignments, roles and their permissions, one query each
+		if got := queries.Load(); got != 3 {
+			t.Fatalf("ListUsers() with %d users made %d role queries, want 3", n, got)
+		}
+	}
+}
+
+// BenchmarkListUsers reports the role and permission round trips per listing,
+// which stay the same however many users and roles there are
+func BenchmarkListUsers(b *testing.B) {
+	for _, n := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("users=%d", n), func(b *testing.B) {
+			repos := newFakeRepos()
+			addTestUsers(b, repos, n, addTestRoles(b, repos, 3))
+			var queries atomic.Int64
+			uc := newCountingUserUseCase(repos, &queries)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := uc.ListUsers(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+// staleEmailRepo misses every email lookup, like a check that ran before a
+// concurrent write took the email, so only the unique index can catch it
+type staleEmailRepo struct {
+	*fakeUserRepo
+}
+
+func (r staleEmailRepo) GetByEmail(context.Context, string) (*model.User, error) {
+	return nil, nil
+}
+
+func TestReRegistrationKeepsEmailUnique(t *testing.T) {
+	ctx := context.Background()
+	repos := newFakeRepos()
+	auth := newTestAuthUseCase(repos, model.LockoutPolicy{})
+	users := newTestUserUseCase(repos)
+
+	// A registration that never finished 2FA setup, given an email by an admin
+	first, err := auth.Register(ctx, "alice", testPassword)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	pending, _ := repos.users.GetByID(ctx, first.UserID)
+	pending.Email = " Alice@Example.com "
+	if err := users.UpdateUser(ctx, pending); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+
+	// Registering the inactive account again reuses it and keeps its email
+	again, err := auth.Register(ctx, "alice", testPassword)
+	if err != nil {
+		t.Fatalf("Register() again error = %v", err)
+	}
+	if again.UserID != first.UserID {
+		t.Fatalf("Register() again created user %s, want the pending user %s reused", again.UserID, first.UserID)
+	}
+	if owner, _ := repos.users.GetByEmail(ctx, "alice@example.com"); owner == nil || owner.ID != first.UserID {
+		t.Fatalf("alice@example.com belongs to %+v, want the pending user", owner)
+	}
+
+	// No other account may take the email, however it is spelled
+	_, err = users.CreateUser(ctx, &model.User{Username: "bob", Password: "hashed", Email: "ALICE@example.com"}, nil)
+	if !errors.Is(err, ErrEmailAlreadyExists) {
+		t.Fatalf("CreateUser() with a taken email error = %v, want %v", err, ErrEmailAlreadyExists)
+	}
+	bob, err := users.CreateUser(ctx, &model.User{Username: "bob", Password: "hashed"}, nil)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	bob.Email = "alice@example.com"
+	if err := users.UpdateUser(ctx, &bob.User); !errors.Is(err, ErrEmailAlreadyExists) {
+		t.Fatalf("UpdateUser() to a taken email error = %v, want %v", err, ErrEmailAlreadyExists)
+	}
+
+	// A lookup that misses a concurrent write still ends in the same error
+	racing := NewUserUseCase(staleEmailRepo{repos.users}, repos.roles, repos.userRoles, repos.audit, model.PasswordPolicy{MinLength: 6}, 128)
+	_, err = racing.CreateUser(ctx, &model.User{Username: "carol", Password: "hashed", Email: "alice@example.com"}, nil)
+	if !errors.Is(err, ErrEmailAlreadyExists) {
+		t.Fatalf("CreateUser() past a stale email check error = %v, want %v", err, ErrEmailAlreadyExists)
+	}
+}
@@ -0,0 +1,126 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"control_page/internal/model"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// APIError is the normalized error for non-2xx Binance responses
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       int    `json:"code"`
+	Msg        string `json:"msg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("binance: status %d, code %d: %s", e.StatusCode, e.Code, e.Msg)
+}
+
+// Client calls the Binance REST API with one API key
+type Client struct {
+	config     model.ExchangeConfig
+	apiKey     string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the environment described by config
+// (see model.ExchangeEndpoints for testnet/prod selection)
+func NewClient(config model.ExchangeConfig, apiKey, secret string) *Client {
+	return &Client{
+		config:     config,
+		apiKey:     apiKey,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// SignRequest adds a timestamp (unless already set) and returns the encoded
+// query string with its HMAC-SHA256 signature appended. The signature covers
+// the exact string returned, so parameter order cannot drift from what is sent.
+// params is left untouched, so a retry with the same values is stamped afresh.
+func SignRequest(secret string, params url.Values) string {
+	signed := maps.Clone(params)
+	if signed == nil {
+		signed = url.Values{}
+	}
+	if signed.Get("timestamp") == "" {
+		signed.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	}
+
+	query := signed.Encode()
+	return query + "&signature=" + sign(secret, query)
+}
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DoSigned sends a SIGNED (TRADE/USER_DATA) request and decodes the response into out
+func (c *Client) DoSigned(ctx context.Context, method, path string, params url.Values, out any) error {
+	return c.do(ctx, method, path, SignRequest(c.secret, params), out)
+}
+
+// DoPublic sends an unauthenticated (NONE security) request
+func (c *Client) DoPublic(ctx context.Context, method, path string, params url.Values, out any) error {
+	return c.do(ctx, method, path, params.Encode(), out)
+}
+
+// DoAPIKey sends a request that only needs the X-MBX-APIKEY header (e.g. USER_STREAM)
+func (c *Client) DoAPIKey(ctx context.Context, method, path string, params url.Values, out any) error {
+	return c.do(ctx, method, path, params.Encode(), out)
+}
+
+func (c *Client) do(ctx context.Context, method, path, query string, out any) error {
+	endpoint := c.config.BaseRESTURL + path
+	if query != "" {
+		endpoint += "?" + query
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("binance: new request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("binance: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		body, _ := io.ReadAll(resp.Body)
+		if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Msg == "" {
+			apiErr.Msg = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("binance: decode response: %w", err)
+	}
+	return nil
+}
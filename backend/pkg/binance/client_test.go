@@ -0,0 +1,57 @@
+package binance
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// The HMAC SHA256 example of Binance's SIGNED endpoint documentation
+const (
+	docSecret    = "NhqPtmdSJYdKjVHjA7PZj4Mge3R5YNiP1e3UZjInClVN65XAbvqqM6A7H5fATj0j"
+	docQuery     = "symbol=LTCBTC&side=BUY&type=LIMIT&timeInForce=GTC&quantity=1&price=0.1&recvWindow=5000&timestamp=1499827319559"
+	docSignature = "c8db56825ae71d6d79447849e617115f4a920fa2acdcab2b053c4b2838bd6b71"
+)
+
+func TestSignDocumentedExample(t *testing.T) {
+	if got := sign(docSecret, docQuery); got != docSignature {
+		t.Fatalf("sign() = %s, want %s", got, docSignature)
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	params, err := url.ParseQuery(docQuery)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+
+	// Encode sorts the parameters, so the documented values are signed in key
+	// order; the signature covers exactly the string sent
+	want := "price=0.1&quantity=1&recvWindow=5000&side=BUY&symbol=LTCBTC&timeInForce=GTC&timestamp=1499827319559&type=LIMIT" +
+		"&signature=70fd30433bc3a2e3b5ff17d075e50538dde3734841da6dc28d79113dd37fa9c7"
+	if got := SignRequest(docSecret, params); got != want {
+		t.Fatalf("SignRequest() = %q, want %q", got, want)
+	}
+}
+
+func TestSignRequestLeavesParamsUntouched(t *testing.T) {
+	params := url.Values{"symbol": {"LTCBTC"}}
+
+	first := SignRequest(docSecret, params)
+	if params.Has("timestamp") || len(params) != 1 {
+		t.Fatalf("SignRequest() changed the caller's params to %v", params)
+	}
+	if !strings.Contains(first, "timestamp=") {
+		t.Fatalf("SignRequest() = %q, want a timestamp added", first)
+	}
+
+	// A caller's own timestamp is kept
+	params.Set("timestamp", "1499827319559")
+	if got := SignRequest(docSecret, params); !strings.Contains(got, "timestamp=1499827319559&") {
+		t.Fatalf("SignRequest() = %q, want the given timestamp", got)
+	}
+
+	if got := SignRequest(docSecret, nil); !strings.HasPrefix(got, "timestamp=") {
+		t.Fatalf("SignRequest(nil) = %q, want only a timestamp and signature", got)
+	}
+}
@@ -0,0 +1,71 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// ErrUnknownSymbol means exchangeInfo has no entry for the requested symbol
+var ErrUnknownSymbol = errors.New("binance: unknown symbol")
+
+// Binance API error code for a symbol that does not exist
+const codeInvalidSymbol = -1121
+
+// IsUnknownSymbol reports whether err means the symbol is not listed
+func IsUnknownSymbol(err error) bool {
+	var apiErr *APIError
+	return errors.Is(err, ErrUnknownSymbol) || (errors.As(err, &apiErr) && apiErr.Code == codeInvalidSymbol)
+}
+
+// SymbolInfo is the subset of an exchangeInfo symbol entry we use
+type SymbolInfo struct {
+	Symbol     string `json:"symbol"`
+	Status     string `json:"status"` // TRADING when tradable
+	BaseAsset  string `json:"baseAsset"`
+	QuoteAsset string `json:"quoteAsset"`
+}
+
+// ExchangeInfo returns every symbol listed on the exchange
+func (c *Client) ExchangeInfo(ctx context.Context) ([]SymbolInfo, error) {
+	var result struct {
+		Symbols []SymbolInfo `json:"symbols"`
+	}
+	if err := c.DoPublic(ctx, http.MethodGet, "/v3/exchangeInfo", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Symbols, nil
+}
+
+// SymbolExchangeInfo returns the exchangeInfo entry of a single symbol
+func (c *Client) SymbolExchangeInfo(ctx context.Context, symbol string) (*SymbolInfo, error) {
+	var result struct {
+		Symbols []SymbolInfo `json:"symbols"`
+	}
+	if err := c.DoPublic(ctx, http.MethodGet, "/v3/exchangeInfo", url.Values{"symbol": {symbol}}, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Symbols) == 0 {
+		return nil, ErrUnknownSymbol
+	}
+	return &result.Symbols[0], nil
+}
+
+// Ticker24h is the subset of a /v3/ticker/24hr entry we use
+type Ticker24h struct {
+	Symbol    string `json:"symbol"`
+	LastPrice string `json:"lastPrice"`
+	HighPrice string `json:"highPrice"`
+	LowPrice  string `json:"lowPrice"`
+	Volume    string `json:"volume"` // base asset volume
+}
+
+// Ticker24h returns the rolling 24 hour statistics of a symbol
+func (c *Client) Ticker24h(ctx context.Context, symbol string) (*Ticker24h, error) {
+	var ticker Ticker24h
+	if err := c.DoPublic(ctx, http.MethodGet, "/v3/ticker/24hr", url.Values{"symbol": {symbol}}, &ticker); err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
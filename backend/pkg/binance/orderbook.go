@@ -0,0 +1,192 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"control_page/internal/model"
+)
+
+// maxBufferedDiffs bounds the diffs held while a snapshot is being fetched
+const maxBufferedDiffs = 1000
+
+var (
+	// ErrOrderBookGap means a diff did not follow the last applied update; the book must be re-synced
+	ErrOrderBookGap = errors.New("binance: order book sequence gap")
+	// ErrOrderBookNotSynced means the diff was buffered because no snapshot has been loaded yet
+	ErrOrderBookNotSynced = errors.New("binance: order book not synced")
+)
+
+// DepthSnapshot is the REST /v3/depth response
+type DepthSnapshot struct {
+	LastUpdateID int64       `json:"lastUpdateId"`
+	Bids         [][2]string `json:"bids"`
+	Asks         [][2]string `json:"asks"`
+}
+
+// DepthDiff is one diff depth stream event (depthUpdate)
+type DepthDiff struct {
+	FirstUpdateID int64       // U
+	FinalUpdateID int64       // u
+	Bids          [][2]string // b
+	Asks          [][2]string // a
+}
+
+// DepthSnapshot fetches the REST order book used to seed a maintained book
+func (c *Client) DepthSnapshot(ctx context.Context, symbol string, limit int) (*DepthSnapshot, error) {
+	params := url.Values{}
+	params.Set("symbol", strings.ToUpper(symbol))
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var snapshot DepthSnapshot
+	if err := c.DoPublic(ctx, "GET", "/v3/depth", params, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// OrderBook maintains a full local book from a REST snapshot plus diff events,
+// following Binance's "how to manage a local order book" procedure
+type OrderBook struct {
+	mu           sync.Mutex
+	synced       bool
+	lastUpdateID int64
+	bids         map[string]string
+	asks         map[string]string
+	buffer       []DepthDiff
+}
+
+func NewOrderBook() *OrderBook {
+	return &OrderBook{
+		bids: make(map[string]string),
+		asks: make(map[string]string),
+	}
+}
+
+// Synced reports whether the book has a snapshot and a gap-free diff sequence
+func (b *OrderBook) Synced() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.synced
+}
+
+// Reset drops the book back to the unsynced state; diffs are buffered until the next snapshot
+func (b *OrderBook) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+}
+
+func (b *OrderBook) reset() {
+	b.synced = false
+	b.lastUpdateID = 0
+	b.bids = make(map[string]string)
+	b.asks = make(map[string]string)
+	b.buffer = nil
+}
+
+// Apply applies a diff. While unsynced the diff is buffered and
+// ErrOrderBookNotSynced is returned; on a sequence gap the book resets itself
+// and returns ErrOrderBookGap.
+func (b *OrderBook) Apply(diff DepthDiff) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.synced {
+		if len(b.buffer) >= maxBufferedDiffs {
+			b.buffer = b.buffer[1:]
+		}
+		b.buffer = append(b.buffer, diff)
+		return ErrOrderBookNotSynced
+	}
+	return b.apply(diff)
+}
+
+func (b *OrderBook) apply(diff DepthDiff) error {
+	if diff.FinalUpdateID <= b.lastUpdateID {
+		return nil // already covered by the snapshot or an earlier diff
+	}
+	if diff.FirstUpdateID > b.lastUpdateID+1 {
+		b.reset()
+		return ErrOrderBookGap
+	}
+
+	applyLevels(b.bids, diff.Bids)
+	applyLevels(b.asks, diff.Asks)
+	b.lastUpdateID = diff.FinalUpdateID
+	return nil
+}
+
+// LoadSnapshot seeds the book and replays the buffered diffs. ErrOrderBookGap
+// means the snapshot is older than the buffered diffs and must be fetched again.
+func (b *OrderBook) LoadSnapshot(snapshot *DepthSnapshot) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	buffered := b.buffer
+	b.reset()
+	b.lastUpdateID = snapshot.LastUpdateID
+	applyLevels(b.bids, snapshot.Bids)
+	applyLevels(b.asks, snapshot.Asks)
+	b.synced = true
+
+	for _, diff := range buffered {
+		if err := b.apply(diff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Top returns up to n levels per side, bids descending and asks ascending,
+// plus the last applied update ID
+func (b *OrderBook) Top(n int) (bids, asks []model.OrderBookLevel, lastUpdateID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return topLevels(b.bids, n, true), topLevels(b.asks, n, false), b.lastUpdateID
+}
+
+func applyLevels(side map[string]string, levels [][2]string) {
+	for _, lv := range levels {
+		if qty, err := strconv.ParseFloat(lv[1], 64); err == nil && qty == 0 {
+			delete(side, lv[0])
+			continue
+		}
+		side[lv[0]] = lv[1]
+	}
+}
+
+type priceLevel struct {
+	price float64
+	level model.OrderBookLevel
+}
+
+func topLevels(side map[string]string, n int, descending bool) []model.OrderBookLevel {
+	levels := make([]priceLevel, 0, len(side))
+	for p, q := range side {
+		price, _ := strconv.ParseFloat(p, 64)
+		levels = append(levels, priceLevel{price: price, level: model.OrderBookLevel{Price: p, Quantity: q}})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].price > levels[j].price
+		}
+		return levels[i].price < levels[j].price
+	})
+	if n > 0 && len(levels) > n {
+		levels = levels[:n]
+	}
+
+	out := make([]model.OrderBookLevel, len(levels))
+	for i, lv := range levels {
+		out[i] = lv.level
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package binance
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"control_page/internal/model"
+)
+
+// depthDiff is a depth update covering update IDs first..final
+func depthDiff(first, final int64, bids, asks [][2]string) DepthDiff {
+	return DepthDiff{FirstUpdateID: first, FinalUpdateID: final, Bids: bids, Asks: asks}
+}
+
+// syncedBook is a book loaded from a snapshot at update 100
+func syncedBook(t *testing.T) *OrderBook {
+	t.Helper()
+	b := NewOrderBook()
+	err := b.LoadSnapshot(&DepthSnapshot{
+		LastUpdateID: 100,
+		Bids:         [][2]string{{"99.5", "1"}, {"100", "2"}},
+		Asks:         [][2]string{{"101", "3"}, {"100.5", "4"}},
+	})
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	return b
+}
+
+func TestOrderBookBuffersUntilSnapshot(t *testing.T) {
+	b := NewOrderBook()
+	if err := b.Apply(depthDiff(95, 99, [][2]string{{"98", "1"}}, nil)); !errors.Is(err, ErrOrderBookNotSynced) {
+		t.Fatalf("Apply() before the snapshot error = %v, want %v", err, ErrOrderBookNotSynced)
+	}
+	if err := b.Apply(depthDiff(100, 102, [][2]string{{"100", "5"}}, nil)); !errors.Is(err, ErrOrderBookNotSynced) {
+		t.Fatalf("Apply() before the snapshot error = %v, want %v", err, ErrOrderBookNotSynced)
+	}
+
+	// The diff already covered by the snapshot is skipped, the straddling one applied
+	if err := b.LoadSnapshot(&DepthSnapshot{LastUpdateID: 100, Bids: [][2]string{{"100", "2"}}}); err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	bids, _, lastUpdateID := b.Top(10)
+	want := []model.OrderBookLevel{{Price: "100", Quantity: "5"}}
+	if !b.Synced() || lastUpdateID != 102 || !reflect.DeepEqual(bids, want) {
+		t.Fatalf("book = synced %v at %d with bids %+v; want synced at 102 with %+v", b.Synced(), lastUpdateID, bids, want)
+	}
+}
+
+func TestOrderBookGapResets(t *testing.T) {
+	b := syncedBook(t)
+	if err := b.Apply(depthDiff(101, 105, [][2]string{{"100", "3"}}, nil)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// 106 is missing
+	if err := b.Apply(depthDiff(107, 110, [][2]string{{"100", "9"}}, nil)); !errors.Is(err, ErrOrderBookGap) {
+		t.Fatalf("Apply() after a gap error = %v, want %v", err, ErrOrderBookGap)
+	}
+	bids, asks, lastUpdateID := b.Top(10)
+	if b.Synced() || len(bids) != 0 || len(asks) != 0 || lastUpdateID != 0 {
+		t.Fatalf("book after a gap = synced %v at %d with %+v / %+v, want empty and unsynced", b.Synced(), lastUpdateID, bids, asks)
+	}
+
+	// Later diffs wait for the next snapshot
+	if err := b.Apply(depthDiff(111, 112, nil, nil)); !errors.Is(err, ErrOrderBookNotSynced) {
+		t.Fatalf("Apply() after the reset error = %v, want %v", err, ErrOrderBookNotSynced)
+	}
+}
+
+func TestOrderBookSnapshotOlderThanBuffer(t *testing.T) {
+	b := NewOrderBook()
+	b.Apply(depthDiff(150, 155, [][2]string{{"100", "1"}}, nil))
+
+	if err := b.LoadSnapshot(&DepthSnapshot{LastUpdateID: 100}); !errors.Is(err, ErrOrderBookGap) {
+		t.Fatalf("LoadSnapshot(older than the buffer) error = %v, want %v", err, ErrOrderBookGap)
+	}
+	if b.Synced() {
+		t.Fatal("book synced from a snapshot older than its diffs")
+	}
+
+	// A fresh snapshot after the reset syncs again
+	b.Apply(depthDiff(156, 160, [][2]string{{"100", "2"}}, nil))
+	if err := b.LoadSnapshot(&DepthSnapshot{LastUpdateID: 157}); err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if _, _, lastUpdateID := b.Top(1); !b.Synced() || lastUpdateID != 160 {
+		t.Fatalf("book = synced %v at %d, want synced at 160", b.Synced(), lastUpdateID)
+	}
+}
+
+func TestOrderBookBufferOverflow(t *testing.T) {
+	b := NewOrderBook()
+	for id := int64(1); id <= maxBufferedDiffs+1; id++ {
+		b.Apply(depthDiff(id, id, [][2]string{{"100", "1"}}, nil))
+	}
+
+	b.mu.Lock()
+	buffered, first := len(b.buffer), b.buffer[0].FirstUpdateID
+	b.mu.Unlock()
+	if buffered != maxBufferedDiffs || first != 2 {
+		t.Fatalf("buffer holds %d diffs from %d, want %d from 2", buffered, first, maxBufferedDiffs)
+	}
+
+	// The dropped diff leaves a gap behind a snapshot that needed it
+	if err := b.LoadSnapshot(&DepthSnapshot{LastUpdateID: 0}); !errors.Is(err, ErrOrderBookGap) {
+		t.Fatalf("LoadSnapshot() error = %v, want %v", err, ErrOrderBookGap)
+	}
+}
+
+func TestOrderBookZeroQuantityRemovesLevel(t *testing.T) {
+	b := syncedBook(t)
+	if err := b.Apply(depthDiff(101, 101, [][2]string{{"100", "0.00000000"}, {"98", "0"}}, [][2]string{{"101", "0"}})); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	bids, asks, _ := b.Top(10)
+	if want := []model.OrderBookLevel{{Price: "99.5", Quantity: "1"}}; !reflect.DeepEqual(bids, want) {
+		t.Fatalf("bids = %+v, want %+v", bids, want)
+	}
+	if want := []model.OrderBookLevel{{Price: "100.5", Quantity: "4"}}; !reflect.DeepEqual(asks, want) {
+		t.Fatalf("asks = %+v, want %+v", asks, want)
+	}
+}
+
+func TestOrderBookTop(t *testing.T) {
+	b := syncedBook(t)
+	b.Apply(depthDiff(101, 101, [][2]string{{"9.5", "1"}, {"100.25", "1"}}, [][2]string{{"1000", "1"}, {"100.75", "1"}}))
+
+	// Prices compare as numbers, not strings
+	bids, asks, lastUpdateID := b.Top(3)
+	wantBids := []model.OrderBookLevel{{Price: "100.25", Quantity: "1"}, {Price: "100", Quantity: "2"}, {Price: "99.5", Quantity: "1"}}
+	wantAsks := []model.OrderBookLevel{{Price: "100.5", Quantity: "4"}, {Price: "100.75", Quantity: "1"}, {Price: "101", Quantity: "3"}}
+	if !reflect.DeepEqual(bids, wantBids) || !reflect.DeepEqual(asks, wantAsks) || lastUpdateID != 101 {
+		t.Fatalf("Top(3) = %+v / %+v at %d, want %+v / %+v at 101", bids, asks, lastUpdateID, wantBids, wantAsks)
+	}
+
+	if bids, asks, _ := b.Top(0); len(bids) != 4 || len(asks) != 4 {
+		t.Fatalf("Top(0) = %d bids, %d asks; want every level", len(bids), len(asks))
+	}
+}
@@ -0,0 +1,20 @@
+package binance
+
+import (
+	"sort"
+	"strings"
+)
+
+// CombinedStreamURL returns the /stream?streams= endpoint for the given stream
+// names. Unlike the raw /ws endpoint, every message on it is wrapped in a
+// {"stream": ..., "data": ...} envelope, so the source stream is always known.
+// baseWSURL may point at either the raw (/ws) or the combined (/stream) path.
+func CombinedStreamURL(baseWSURL string, streams []string) string {
+	base := strings.TrimRight(baseWSURL, "/")
+	base = strings.TrimSuffix(base, "/ws")
+	base = strings.TrimSuffix(base, "/stream")
+
+	sorted := append([]string(nil), streams...)
+	sort.Strings(sorted)
+	return base + "/stream?streams=" + strings.Join(sorted, "/")
+}
@@ -0,0 +1,23 @@
+package binance
+
+import "testing"
+
+func TestCombinedStreamURL(t *testing.T) {
+	streams := []string{"btcusdt@kline_1m", "btcusdt@depth"}
+	want := "wss://stream.binance.com:9443/stream?streams=btcusdt@depth/btcusdt@kline_1m"
+
+	for _, base := range []string{
+		"wss://stream.binance.com:9443/ws",
+		"wss://stream.binance.com:9443/ws/",
+		"wss://stream.binance.com:9443/stream",
+		"wss://stream.binance.com:9443",
+	} {
+		if got := CombinedStreamURL(base, streams); got != want {
+			t.Errorf("CombinedStreamURL(%q) = %q, want %q", base, got, want)
+		}
+	}
+
+	if streams[0] != "btcusdt@kline_1m" {
+		t.Fatalf("CombinedStreamURL sorted the caller's slice: %v", streams)
+	}
+}
@@ -0,0 +1,145 @@
+package btcc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	MethodAccessIDAuth = "server.accessid_auth"
+	MethodPing         = "server.ping"
+	MethodKlineSub     = "kline.subscribe"
+	MethodDepthSub     = "depth.subscribe"
+	MethodPutLimit     = "order.put_limit"
+	MethodStateQuery   = "state.query"
+)
+
+// Order sides used by order.put_limit and order.update
+const (
+	SideSell = 1
+	SideBuy  = 2
+)
+
+const defaultDepthMerge = "0.00000001"
+
+var validDepthLimits = map[int]bool{5: true, 10: true, 20: true, 50: true}
+
+var (
+	ErrInvalidMarket   = errors.New("btcc: market is required")
+	ErrUnknownMarket   = errors.New("btcc: unknown market")
+	ErrInvalidInterval = errors.New("btcc: kline interval must be positive")
+	ErrInvalidDepth    = errors.New("btcc: depth limit must be one of 5, 10, 20, 50")
+	ErrInvalidSide     = errors.New("btcc: side must be SideSell or SideBuy")
+	ErrInvalidAmount   = errors.New("btcc: amount must be a positive decimal")
+	ErrInvalidPrice    = errors.New("btcc: price must be a positive decimal")
+)
+
+// Request represents a BTCC WebSocket request message
+type Request struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// Response represents a BTCC WebSocket response message
+type Response struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method,omitempty"`
+	Error  *Error          `json:"error"`
+	Result json.RawMessage `json:"result"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Error represents a BTCC error response
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("btcc: code %d: %s", e.Code, e.Message)
+}
+
+// RequestIDs allocates request IDs for one connection; the zero value is ready to use
+type RequestIDs struct {
+	last int64
+}
+
+// Next returns the next request ID, safe for concurrent use
+func (r *RequestIDs) Next() int64 {
+	return atomic.AddInt64(&r.last, 1)
+}
+
+// NewRequest builds a request with the next ID from ids
+func NewRequest(ids *RequestIDs, method string, params interface{}) Request {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return Request{ID: ids.Next(), Method: method, Params: params}
+}
+
+// SignAccessKey returns the SHA256 hex of the secret key used by server.accessid_auth
+func SignAccessKey(secretKey string) string {
+	hash := sha256.Sum256([]byte(secretKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// AuthParams builds the server.accessid_auth params: [access_id, sha256(secret)]
+func AuthParams(accessID, secretKey string) []interface{} {
+	return []interface{}{accessID, SignAccessKey(secretKey)}
+}
+
+// KlineSubscribeParams builds the kline.subscribe params: [market, interval seconds]
+func KlineSubscribeParams(market string, interval int) ([]interface{}, error) {
+	if market == "" {
+		return nil, ErrInvalidMarket
+	}
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+	return []interface{}{market, interval}, nil
+}
+
+// DepthSubscribeParams builds the depth.subscribe params: [market, limit, merge].
+// An empty merge uses the finest precision.
+func DepthSubscribeParams(market string, limit int, merge string) ([]interface{}, error) {
+	if market == "" {
+		return nil, ErrInvalidMarket
+	}
+	if !validDepthLimits[limit] {
+		return nil, ErrInvalidDepth
+	}
+	if merge == "" {
+		merge = defaultDepthMerge
+	} else if !isPositiveDecimal(merge) {
+		return nil, fmt.Errorf("btcc: invalid depth merge %q", merge)
+	}
+	return []interface{}{market, limit, merge}, nil
+}
+
+// PutLimitParams builds the order.put_limit params: [market, side, amount, price]
+func PutLimitParams(market string, side int, amount, price string) ([]interface{}, error) {
+	if market == "" {
+		return nil, ErrInvalidMarket
+	}
+	if side != SideSell && side != SideBuy {
+		return nil, ErrInvalidSide
+	}
+	if !isPositiveDecimal(amount) {
+		return nil, ErrInvalidAmount
+	}
+	if !isPositiveDecimal(price) {
+		return nil, ErrInvalidPrice
+	}
+	return []interface{}{market, side, amount, price}, nil
+}
+
+func isPositiveDecimal(s string) bool {
+	v, err := strconv.ParseFloat(s, 64)
+	return err == nil && v > 0
+}
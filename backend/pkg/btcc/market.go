@@ -0,0 +1,112 @@
+package btcc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	MarketListURLProd    = "https://spotapi2.btcccdn.com/btcc_api_trade/market/list"
+	MarketListURLTestnet = "https://spot.cryptouat.com:9910/btcc_api_trade/market/list"
+
+	MarketDetailURLProd    = "https://spotapi2.btcccdn.com/btcc_api_trade/market/detail"
+	MarketDetailURLTestnet = "https://spot.cryptouat.com:9910/btcc_api_trade/market/detail"
+)
+
+// MarketListURL returns the public market list endpoint for the environment
+func MarketListURL(isTestnet bool) string {
+	if isTestnet {
+		return MarketListURLTestnet
+	}
+	return MarketListURLProd
+}
+
+// MarketDetailURL returns the public single-market endpoint for the environment
+func MarketDetailURL(isTestnet bool) string {
+	if isTestnet {
+		return MarketDetailURLTestnet
+	}
+	return MarketDetailURLProd
+}
+
+// Market is one entry of the public market list
+type Market struct {
+	Name      string `json:"name"`
+	Money     string `json:"money"` // quote currency
+	Stock     string `json:"stock"` // base currency
+	MoneyPrec int    `json:"money_prec"`
+	StockPrec int    `json:"stock_prec"`
+	MinAmount string `json:"min_amount"`
+	Switch    bool   `json:"switch"`    // true when tradable
+	OpenTime  int64  `json:"open_time"` // Unix seconds the market reopens while Switch is false
+}
+
+// FetchMarkets loads the public market list
+func FetchMarkets(ctx context.Context, client *http.Client, isTestnet bool) ([]Market, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, MarketListURL(isTestnet), nil)
+	if err != nil {
+		return nil, fmt.Errorf("btcc: new request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("btcc: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("btcc: market list returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Error  *Error   `json:"error"`
+		Result []Market `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("btcc: decode market list: %w", err)
+	}
+	if result.Error != nil && result.Error.Code != 0 {
+		return nil, result.Error
+	}
+	return result.Result, nil
+}
+
+// FetchMarketDetail loads one entry of the public market list
+func FetchMarketDetail(ctx context.Context, client *http.Client, isTestnet bool, market string) (*Market, error) {
+	if market == "" {
+		return nil, ErrInvalidMarket
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, MarketDetailURL(isTestnet)+"?market="+url.QueryEscape(market), nil)
+	if err != nil {
+		return nil, fmt.Errorf("btcc: new request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("btcc: request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("btcc: market detail returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Error  *Error  `json:"error"`
+		Result *Market `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("btcc: decode market detail: %w", err)
+	}
+	if result.Error != nil && result.Error.Code != 0 {
+		return nil, result.Error
+	}
+	if result.Result == nil || result.Result.Name == "" {
+		return nil, ErrUnknownMarket
+	}
+	return result.Result, nil
+}
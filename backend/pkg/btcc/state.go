@@ -0,0 +1,187 @@
+package btcc
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"control_page/internal/model"
+)
+
+// DefaultStatePeriod is the statistics window requested by QueryState, in seconds
+const DefaultStatePeriod = 86400
+
+const stateQueryTimeout = 10 * time.Second
+
+// flexString accepts a JSON string or number, as BTCC sends both for decimals
+type flexString string
+
+func (f *flexString) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*f = flexString(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*f = flexString(n.String())
+	return nil
+}
+
+// stateFields is one market entry of state.update and the state.query result
+type stateFields struct {
+	Period flexString `json:"period"`
+	Last   flexString `json:"last"`
+	High   flexString `json:"high"`
+	Low    flexString `json:"low"`
+	Volume flexString `json:"volume"`
+	// Trading status; BTCC sends either the market list's switch flag or a status word
+	Switch   *bool      `json:"switch"`
+	Status   flexString `json:"status"`
+	OpenTime int64      `json:"open_time"`
+}
+
+func (f stateFields) toMarketState(market string) model.MarketState {
+	state := model.MarketState{
+		Symbol:   strings.ToUpper(market),
+		Status:   model.MarketStatusOpen,
+		Last:     string(f.Last),
+		High:     string(f.High),
+		Low:      string(f.Low),
+		Volume:   string(f.Volume),
+		Platform: model.PlatformBTCC,
+	}
+	state.Period, _ = strconv.ParseInt(string(f.Period), 10, 64)
+
+	// A market that reports neither flag is pushing live statistics, so it is open
+	halted := false
+	switch {
+	case f.Switch != nil:
+		halted = !*f.Switch
+	case f.Status != "":
+		switch strings.ToLower(string(f.Status)) {
+		case "halt", "halted", "close", "closed", "suspend", "suspended", "0", "false":
+			halted = true
+		}
+	}
+	if halted {
+		state.Status = model.MarketStatusHalted
+		if f.OpenTime > 0 {
+			state.ReopenTime = f.OpenTime * 1000
+		}
+	}
+	return state
+}
+
+// ParseStateUpdate normalizes state.update params. BTCC pushes either
+// [market, state] for a single market or {market: state, ...} for all pairs,
+// the latter optionally wrapped in an array.
+func ParseStateUpdate(params json.RawMessage) ([]model.MarketState, error) {
+	all := bytes.TrimSpace(params)
+	if bytes.HasPrefix(all, []byte("[")) {
+		var parts []json.RawMessage
+		if err := json.Unmarshal(all, &parts); err != nil {
+			return nil, fmt.Errorf("btcc: state params: %w", err)
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+
+		var market string
+		if err := json.Unmarshal(parts[0], &market); err == nil {
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("btcc: state update for %s has no data", market)
+			}
+			var fields stateFields
+			if err := json.Unmarshal(parts[1], &fields); err != nil {
+				return nil, fmt.Errorf("btcc: state for %s: %w", market, err)
+			}
+			return []model.MarketState{fields.toMarketState(market)}, nil
+		}
+		all = parts[0]
+	}
+
+	var markets map[string]stateFields
+	if err := json.Unmarshal(all, &markets); err != nil {
+		return nil, fmt.Errorf("btcc: state markets: %w", err)
+	}
+	states := make([]model.MarketState, 0, len(markets))
+	for market, fields := range markets {
+		states = append(states, fields.toMarketState(market))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Symbol < states[j].Symbol })
+	return states, nil
+}
+
+// QueryState asks the public WebSocket at wsURL for one market's state over
+// period seconds, using a short-lived connection
+func QueryState(ctx context.Context, wsURL, market string, period int) (*model.MarketState, error) {
+	if market == "" {
+		return nil, ErrInvalidMarket
+	}
+	if period <= 0 {
+		period = DefaultStatePeriod
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stateQueryTimeout)
+	defer cancel()
+
+	// BTCC requires per-message Deflate compression (RFC 7692)
+	dialer := websocket.Dialer{EnableCompression: true}
+	ws, _, err := dialer.DialContext(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("btcc: dial: %w", err)
+	}
+	defer ws.Close()
+
+	deadline, _ := ctx.Deadline()
+	_ = ws.SetReadDeadline(deadline)
+	_ = ws.SetWriteDeadline(deadline)
+
+	var ids RequestIDs
+	req := NewRequest(&ids, MethodStateQuery, []interface{}{market, period})
+	if err := ws.WriteJSON(req); err != nil {
+		return nil, fmt.Errorf("btcc: send %s: %w", MethodStateQuery, err)
+	}
+
+	for {
+		messageType, message, err := ws.ReadMessage()
+		if err != nil {
+			return nil, fmt.Errorf("btcc: read %s: %w", MethodStateQuery, err)
+		}
+		if messageType == websocket.BinaryMessage {
+			if message, err = io.ReadAll(flate.NewReader(bytes.NewReader(message))); err != nil {
+				return nil, fmt.Errorf("btcc: decompress: %w", err)
+			}
+		}
+
+		var resp Response
+		if err := json.Unmarshal(message, &resp); err != nil || resp.ID == nil || *resp.ID != req.ID {
+			continue
+		}
+		if resp.Error != nil && resp.Error.Code != 0 {
+			return nil, resp.Error
+		}
+
+		var fields stateFields
+		if err := json.Unmarshal(resp.Result, &fields); err != nil {
+			return nil, fmt.Errorf("btcc: decode %s: %w", MethodStateQuery, err)
+		}
+		state := fields.toMarketState(market)
+		return &state, nil
+	}
+}
@@ -0,0 +1,68 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Box encrypts short secrets with AES-256-GCM
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New creates a Box whose key is derived from the given passphrase
+func New(passphrase string) (*Box, error) {
+	if passphrase == "" {
+		return nil, errors.New("secretbox: empty passphrase")
+	}
+
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: new cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: new gcm: %w", err)
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext and returns base64(nonce || ciphertext)
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal
+func (b *Box) Open(encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonceSize := b.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", ErrInvalidCiphertext
+	}
+
+	plaintext, err := b.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
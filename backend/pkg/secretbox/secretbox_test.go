@@ -0,0 +1,72 @@
+package secretbox
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New("encryption-key")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	for _, plaintext := range []string{"binance-secret", "", "密鑰"} {
+		sealed, err := box.Seal(plaintext)
+		if err != nil {
+			t.Fatalf("Seal(%q) error = %v", plaintext, err)
+		}
+		if sealed == plaintext {
+			t.Fatalf("Seal(%q) returned the plaintext", plaintext)
+		}
+		opened, err := box.Open(sealed)
+		if err != nil || opened != plaintext {
+			t.Fatalf("Open(Seal(%q)) = %q, %v", plaintext, opened, err)
+		}
+	}
+
+	// A fresh nonce each time
+	first, _ := box.Seal("binance-secret")
+	second, _ := box.Seal("binance-secret")
+	if first == second {
+		t.Fatal("sealing the same secret twice gave the same ciphertext")
+	}
+}
+
+func TestOpenRejects(t *testing.T) {
+	box, err := New("encryption-key")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	sealed, err := box.Seal("binance-secret")
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	other, err := New("another-key")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := other.Open(sealed); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("Open() with the wrong key error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)-3] ^= 1
+	for name, value := range map[string]string{
+		"tampered":   string(tampered),
+		"not base64": "not base64!",
+		"too short":  "AAAA",
+		"plaintext":  "binance-secret",
+	} {
+		if _, err := box.Open(value); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Fatalf("Open(%s) error = %v, want %v", name, err, ErrInvalidCiphertext)
+		}
+	}
+}
+
+func TestNewRejectsEmptyPassphrase(t *testing.T) {
+	if _, err := New(""); err == nil {
+		t.Fatal("New(\"\") error = nil, want an error")
+	}
+}
@@ -1,5 +1,5 @@
 import { type Component, type JSX, Show } from 'solid-js';
-import { Navigate } from '@solidjs/router';
+import { Navigate, useLocation } from '@solidjs/router';
 import { authStore } from '../stores/auth';
 
 interface ProtectedRouteProps {
@@ -8,6 +8,11 @@ interface ProtectedRouteProps {
 }
 
 const ProtectedRoute: Component<ProtectedRouteProps> = (props) => {
+  const location = useLocation();
+  // After an admin password reset every other page is blocked by the API
+  const mustChangePassword = () =>
+    authStore.user()?.must_change_password === true && location.pathname !== '/settings';
+
   return (
     <Show
       when={!authStore.isLoading()}
@@ -21,16 +26,18 @@ const ProtectedRoute: Component<ProtectedRouteProps> = (props) => {
         when={authStore.isAuthenticated()}
         fallback={<Navigate href="/login" />}
       >
-        <Show
-          when={!props.permission || authStore.hasPermission(props.permission)}
-          fallback={
-            <div class="error-container">
-              <h2>Access Denied</h2>
-              <p>You don't have permission to view this page.</p>
-            </div>
-          }
-        >
-          {props.children}
+        <Show when={!mustChangePassword()} fallback={<Navigate href="/settings" />}>
+          <Show
+            when={!props.permission || authStore.hasPermission(props.permission)}
+            fallback={
+              <div class="error-container">
+                <h2>Access Denied</h2>
+                <p>You don't have permission to view this page.</p>
+              </div>
+            }
+          >
+            {props.children}
+          </Show>
         </Show>
       </Show>
 
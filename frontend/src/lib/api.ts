@@ -1,4 +1,7 @@
-const API_BASE = 'http://localhost:8887/api';
+import type { Order } from './websocket';
+
+// Override when the backend runs under server.base_path, e.g. https://host/admin/api
+const API_BASE = import.meta.env.VITE_API_BASE ?? 'http://localhost:8887/api';
 
 interface ApiResponse<T> {
   data?: T;
@@ -10,14 +13,17 @@ interface LoginResponse {
   requires_totp: boolean;
   requires_totp_setup: boolean;
   token?: string;
+  expires_in?: number;
   user?: User;
   temp_user_id?: string;
   totp_setup?: TOTPSetup;
+  device_token?: string;
 }
 
 export interface TOTPSetup {
   secret: string;
   qr_code: string;
+  otpauth_url: string;
 }
 
 interface RegisterResponse {
@@ -35,6 +41,7 @@ interface TOTPSetupResponse {
 
 export interface CreateUserRequest {
   username: string;
+  email?: string;
   password: string;
   roles?: string[];
   is_active?: boolean;
@@ -42,6 +49,7 @@ export interface CreateUserRequest {
 
 export interface UpdateUserRequest {
   username?: string;
+  email?: string;
   password?: string;
   is_active?: boolean;
   totp_enabled?: boolean;
@@ -51,18 +59,184 @@ export interface UpdateUserRequest {
 export interface User {
   id: string;
   username: string;
+  email: string;
   is_active: boolean;
   totp_enabled: boolean;
+  must_change_password: boolean;
   created_at: string;
   updated_at: string;
   roles: Role[];
   permissions: string[];
 }
 
+export interface Profile extends User {
+  pending_rebind: boolean;
+  password_changed_at?: string;
+  last_login_at?: string;
+}
+
+export interface UserPreferences {
+  user_id: string;
+  data: Record<string, unknown>;
+  version: number; // pass back to savePreferences; 0 before the first save
+  updated_at?: string;
+}
+
+export interface RouteInfo {
+  method: string;
+  path: string; // chi pattern, e.g. /api/rbac/users/{id}
+  authenticated: boolean;
+  permission?: string;
+}
+
+export interface OrderEvent {
+  id: string;
+  apiKeyId: string;
+  order: Order;
+  receivedAt: string;
+}
+
+export interface OrderEventQuery {
+  symbol?: string;
+  from?: string | number; // RFC 3339 or Unix milliseconds
+  to?: string | number;
+  limit?: number;
+  offset?: number;
+}
+
+export interface SymbolReport {
+  symbol: string;
+  fills: number;
+  boughtQty: number;
+  soldQty: number;
+  buyNotional: number;
+  sellNotional: number;
+  volume: number;
+  realizedPnl: number;
+  position: number;
+  avgCost: number;
+}
+
+export interface TradingReport {
+  apiKeyId: string;
+  date: string;
+  fills: number;
+  volume: number;
+  realizedPnl: number;
+  symbols: SymbolReport[];
+}
+
+export type MarketStatus = 'open' | 'halted';
+
+// Normalized market state, from "state" frames and GET /trading/{apiKeyId}/state
+export interface MarketState {
+  symbol: string;
+  status: MarketStatus;
+  last?: string;
+  high?: string;
+  low?: string;
+  volume?: string;
+  period?: number;
+  reopenTime?: number;
+  platform: string;
+}
+
+export interface TradingSubscription {
+  type: string;
+  symbol?: string;
+  interval?: string;
+}
+
+export interface TradingSession {
+  id: string;
+  remoteAddr: string;
+  userId?: string;
+  apiKeyId?: string;
+  platform?: string;
+  connectedAt: string;
+  durationMs: number;
+  tokenExpiresAt?: string;
+  subscriptions: TradingSubscription[];
+}
+
+export interface KlineSession {
+  remoteAddr: string;
+  connectedAt: string;
+  durationMs: number;
+  streams: string[];
+}
+
+export interface WebSocketSessions {
+  trading: TradingSession[];
+  kline: KlineSession[];
+}
+
+// Exactly one of sessionId and apiKeyId
+export interface TradingDisconnectRequest {
+  sessionId?: string;
+  apiKeyId?: string;
+}
+
+export interface TradingDisconnectResult {
+  closedClients: number;
+  exchangeClosed: boolean;
+}
+
+export type AlertCondition = 'spread_above' | 'price_outside';
+
+export interface AlertRule {
+  id: string;
+  api_key_id: string;
+  symbol: string;
+  condition: AlertCondition;
+  threshold?: number; // spread_above
+  lower?: number; // price_outside
+  upper?: number;
+  for_seconds: number;
+  enabled: boolean;
+  created_by?: string;
+  updated_by?: string;
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface AlertRuleRequest {
+  api_key_id: string;
+  symbol: string;
+  condition: AlertCondition;
+  threshold?: number;
+  lower?: number;
+  upper?: number;
+  for_seconds?: number;
+  enabled?: boolean;
+}
+
+export interface LoginEvent {
+  id: string;
+  user_id?: string;
+  username: string;
+  success: boolean;
+  reason?: string;
+  ip: string;
+  user_agent: string;
+  created_at: string;
+}
+
+// A device that skips TOTP after verify-totp was called with remember_device
+export interface TrustedDevice {
+  id: string;
+  ip: string;
+  user_agent: string;
+  created_at: string;
+  expires_at: string;
+  last_used_at?: string;
+}
+
 export interface Role {
   id: string;
   name: string;
   description: string;
+  max_token_ttl?: number; // seconds; unset uses the global token expiry
   created_at?: string;
   updated_at?: string;
   permissions?: string[];
@@ -82,6 +256,7 @@ export interface APIKeyResponse {
   is_active: boolean;
   created_at: string;
   updated_at: string;
+  default_subscriptions?: TradingSubscription[]; // applied on every trading connect
 }
 
 export interface CreateAPIKeyRequest {
@@ -90,6 +265,7 @@ export interface CreateAPIKeyRequest {
   api_key: string;
   api_secret: string;
   is_testnet: boolean;
+  default_subscriptions?: TradingSubscription[];
 }
 
 export interface UpdateAPIKeyRequest {
@@ -98,6 +274,7 @@ export interface UpdateAPIKeyRequest {
   api_secret?: string;
   is_testnet?: boolean;
   is_active?: boolean;
+  default_subscriptions?: TradingSubscription[]; // [] clears the bundle
 }
 
 // BTCC Market types
@@ -125,6 +302,10 @@ export interface SwitcherPair {
 export interface SwitcherResponse {
   id: string;
   pairs: Record<string, SwitcherPair>;
+  created_by?: string;
+  updated_by?: string;
+  created_at?: string;
+  updated_at?: string;
 }
 
 export interface UpdateSwitcherRequest {
@@ -138,6 +319,10 @@ export interface SettingResponse {
   quote: string;
   strategy: string;
   parameters: Record<string, any>;
+  created_by?: string;
+  updated_by?: string;
+  created_at?: string;
+  updated_at?: string;
 }
 
 export interface CreateSettingRequest {
@@ -219,7 +404,11 @@ class ApiClient {
   async login(username: string, password: string): Promise<LoginResponse> {
     const response = await this.request<LoginResponse>('/auth/login', {
       method: 'POST',
-      body: JSON.stringify({ username, password }),
+      body: JSON.stringify({
+        username,
+        password,
+        device_token: localStorage.getItem('device_token') ?? undefined,
+      }),
     });
     if (response.token) {
       this.setToken(response.token);
@@ -227,14 +416,18 @@ class ApiClient {
     return response;
   }
 
-  async verifyTOTP(userId: string, code: string): Promise<LoginResponse> {
+  // rememberDevice only takes effect when the server has auth.remember_device set
+  async verifyTOTP(userId: string, code: string, rememberDevice = false): Promise<LoginResponse> {
     const response = await this.request<LoginResponse>('/auth/verify-totp', {
       method: 'POST',
-      body: JSON.stringify({ user_id: userId, code }),
+      body: JSON.stringify({ user_id: userId, code, remember_device: rememberDevice }),
     });
     if (response.token) {
       this.setToken(response.token);
     }
+    if (response.device_token) {
+      localStorage.setItem('device_token', response.device_token);
+    }
     return response;
   }
 
@@ -242,10 +435,42 @@ class ApiClient {
     this.setToken(null);
   }
 
-  async getMe(): Promise<User> {
+  async getMe(): Promise<Profile> {
     return this.request('/auth/me');
   }
 
+  async getMyLogins(): Promise<ApiResponse<LoginEvent[]>> {
+    return this.request('/auth/me/logins');
+  }
+
+  async getMyDevices(): Promise<ApiResponse<TrustedDevice[]>> {
+    return this.request('/auth/me/devices');
+  }
+
+  async revokeMyDevice(deviceId: string): Promise<ApiResponse<void>> {
+    return this.request(`/auth/me/devices/${deviceId}`, {
+      method: 'DELETE',
+    });
+  }
+
+  // Routes the signed-in user may call, for deriving menus from the backend
+  async getRoutes(): Promise<ApiResponse<RouteInfo[]>> {
+    return this.request('/meta/routes');
+  }
+
+  async getPreferences(): Promise<ApiResponse<UserPreferences>> {
+    return this.request('/auth/me/preferences');
+  }
+
+  // Fails with "preferences were changed by another session" when version is stale
+  async savePreferences(data: Record<string, unknown>, version: number): Promise<ApiResponse<UserPreferences>> {
+    return this.request('/auth/me/preferences', {
+      method: 'PUT',
+      headers: { 'If-Match': `"${version}"` },
+      body: JSON.stringify(data),
+    });
+  }
+
   async changePassword(currentPassword: string, newPassword: string): Promise<ApiResponse<void>> {
     return this.request('/auth/change-password', {
       method: 'POST',
@@ -292,22 +517,26 @@ class ApiClient {
     return this.request(`/rbac/roles/${id}`);
   }
 
-  async createRole(name: string, description: string, permissions: string[]): Promise<ApiResponse<RoleWithPermissions>> {
+  async createRole(name: string, description: string, permissions: string[], maxTokenTTL = 0): Promise<ApiResponse<RoleWithPermissions>> {
     return this.request('/rbac/roles', {
       method: 'POST',
-      body: JSON.stringify({ name, description, permissions }),
+      body: JSON.stringify({ name, description, permissions, max_token_ttl: maxTokenTTL }),
     });
   }
 
-  async updateRole(id: string, name: string, description: string): Promise<ApiResponse<RoleWithPermissions>> {
+  async updateRole(id: string, name: string, description: string, maxTokenTTL?: number): Promise<ApiResponse<RoleWithPermissions>> {
     return this.request(`/rbac/roles/${id}`, {
       method: 'PUT',
-      body: JSON.stringify({ name, description }),
+      body: JSON.stringify({ name, description, max_token_ttl: maxTokenTTL }),
     });
   }
 
-  async deleteRole(id: string): Promise<ApiResponse<void>> {
-    return this.request(`/rbac/roles/${id}`, {
+  async getRoleUsers(id: string): Promise<ApiResponse<User[]>> {
+    return this.request(`/rbac/roles/${id}/users`);
+  }
+
+  async deleteRole(id: string, force = false): Promise<ApiResponse<User[]>> {
+    return this.request(`/rbac/roles/${id}${force ? '?force=true' : ''}`, {
       method: 'DELETE',
     });
   }
@@ -331,6 +560,20 @@ class ApiClient {
     return this.request(`/rbac/users/${id}`);
   }
 
+  async getUserLogins(id: string): Promise<ApiResponse<LoginEvent[]>> {
+    return this.request(`/rbac/users/${id}/logins`);
+  }
+
+  async getUserDevices(id: string): Promise<ApiResponse<TrustedDevice[]>> {
+    return this.request(`/rbac/users/${id}/devices`);
+  }
+
+  async revokeUserDevice(id: string, deviceId: string): Promise<ApiResponse<void>> {
+    return this.request(`/rbac/users/${id}/devices/${deviceId}`, {
+      method: 'DELETE',
+    });
+  }
+
   async assignRole(userId: string, roleId: string): Promise<ApiResponse<void>> {
     return this.request(`/rbac/users/${userId}/roles`, {
       method: 'POST',
@@ -371,6 +614,13 @@ class ApiClient {
     });
   }
 
+  // Issues a temporary password; it is only returned by this call
+  async resetUserPassword(id: string): Promise<ApiResponse<{ temporary_password: string }>> {
+    return this.request(`/rbac/users/${id}/reset-password`, {
+      method: 'POST',
+    });
+  }
+
   // API Keys endpoints
   async listAPIKeys(): Promise<ApiResponse<APIKeyResponse[]>> {
     return this.request('/api-keys/');
@@ -469,6 +719,75 @@ class ApiClient {
     });
   }
 
+  // Queues a test event to the notification webhook; 404 when none is configured
+  async testNotification(): Promise<ApiResponse<void>> {
+    return this.request('/settings/notifications/test', {
+      method: 'POST',
+    });
+  }
+
+  // Persisted order updates of an API key, newest first
+  async getOrderEvents(apiKeyId: string, query: OrderEventQuery = {}): Promise<ApiResponse<{ items: OrderEvent[]; total: number }>> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(query)) {
+      if (value !== undefined && value !== '') params.set(key, String(value));
+    }
+    const qs = params.toString();
+    return this.request(`/trading/${apiKeyId}/order-events${qs ? `?${qs}` : ''}`);
+  }
+
+  // Daily fill summary of an API key; date is YYYY-MM-DD (UTC), default today
+  async getTradingReport(apiKeyId: string, date?: string): Promise<ApiResponse<TradingReport>> {
+    return this.request(`/trading/${apiKeyId}/report${date ? `?date=${date}` : ''}`);
+  }
+
+  // Trading status and 24h statistics of a market on the API key's exchange
+  async getMarketState(apiKeyId: string, symbol: string): Promise<ApiResponse<MarketState>> {
+    return this.request(`/trading/${apiKeyId}/state?symbol=${encodeURIComponent(symbol)}`);
+  }
+
+  // Connected WebSocket clients (view:sessions)
+  async getWebSocketSessions(): Promise<ApiResponse<WebSocketSessions>> {
+    return this.request('/trading/sessions');
+  }
+
+  // Force-disconnect a session or every client of an API key (manage:sessions)
+  async disconnectTrading(req: TradingDisconnectRequest): Promise<ApiResponse<TradingDisconnectResult>> {
+    return this.request('/trading/disconnect', {
+      method: 'POST',
+      body: JSON.stringify(req),
+    });
+  }
+
+  // Alert rule endpoints
+  async listAlertRules(): Promise<ApiResponse<AlertRule[]>> {
+    return this.request('/trading/alerts/');
+  }
+
+  async getAlertRule(id: string): Promise<ApiResponse<AlertRule>> {
+    return this.request(`/trading/alerts/${id}`);
+  }
+
+  async createAlertRule(req: AlertRuleRequest): Promise<ApiResponse<AlertRule>> {
+    return this.request('/trading/alerts/', {
+      method: 'POST',
+      body: JSON.stringify(req),
+    });
+  }
+
+  async updateAlertRule(id: string, req: AlertRuleRequest): Promise<ApiResponse<AlertRule>> {
+    return this.request(`/trading/alerts/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(req),
+    });
+  }
+
+  async deleteAlertRule(id: string): Promise<ApiResponse<void>> {
+    return this.request(`/trading/alerts/${id}`, {
+      method: 'DELETE',
+    });
+  }
+
   // BTCC Proxy APIs
   async getBTCCMarkets(testnet: boolean = false): Promise<BTCCMarketListResponse> {
     const params = testnet ? '?testnet=true' : '';
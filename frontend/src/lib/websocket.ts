@@ -1,4 +1,5 @@
-const WS_BASE_URL = 'ws://localhost:8887/ws';
+// Override when the backend runs under server.base_path, e.g. wss://host/admin/ws
+const WS_BASE_URL = import.meta.env.VITE_WS_BASE ?? 'ws://localhost:8887/ws';
 
 export interface KlineData {
   s: string;  // Symbol
@@ -207,16 +208,38 @@ export interface OrderBook {
   timestamp: number;
 }
 
+// Canonical order vocabulary, the same for every platform
+export type OrderSide = 'BUY' | 'SELL';
+export type OrderStatus =
+  | 'NEW'
+  | 'PARTIALLY_FILLED'
+  | 'FILLED'
+  | 'CANCELED'
+  | 'PENDING_CANCEL'
+  | 'REJECTED'
+  | 'EXPIRED'
+  | 'UNKNOWN';
+export type OrderType =
+  | 'LIMIT'
+  | 'MARKET'
+  | 'STOP_LOSS'
+  | 'STOP_LOSS_LIMIT'
+  | 'TAKE_PROFIT'
+  | 'TAKE_PROFIT_LIMIT'
+  | 'LIMIT_MAKER';
+export type TimeInForce = 'GTC' | 'IOC' | 'FOK' | '';
+
 export interface Order {
   orderId: string;
   symbol: string;
-  side: 'BUY' | 'SELL';
-  type: string;
+  side: OrderSide;
+  type: OrderType;
   price: string;
   quantity: string;
   executedQty: string;
-  status: 'PLACED' | 'PARTIALLY_FILLED' | 'CANCELED' | 'FILLED' | 'UNKNOWN';
-  timeInForce: string;
+  executedQuote?: string; // cumulative quote amount filled
+  status: OrderStatus;
+  timeInForce: TimeInForce;
   createTime: number;
   updateTime: number;
   stopPrice?: string;
@@ -230,21 +253,49 @@ export interface SpreadRecord {
   bestAsk: string;
 }
 
+// Protocol version this client speaks; sent in the hello handshake
+export const TRADING_PROTOCOL_VERSION = 1;
+
 export interface TradingMessage {
-  action: 'connect' | 'subscribe' | 'unsubscribe' | 'ping';
-  type?: 'kline' | 'orderbook' | 'order';
+  action: 'hello' | 'connect' | 'subscribe' | 'unsubscribe' | 'ping';
+  type?: 'kline' | 'orderbook' | 'order' | 'balance' | 'state';
   apiKeyId?: string;
   symbol?: string;
   interval?: string;
+  version?: number;
+  mode?: 'partial' | 'diff' | 'book'; // orderbook only, defaults to partial
 }
 
 export interface TradingResponse {
-  type: 'connected' | 'kline' | 'orderbook' | 'order' | 'spread' | 'error';
+  type: 'hello' | 'ready' | 'connected' | 'kline' | 'orderbook' | 'order' | 'balance' | 'state' | 'spread' | 'alert' | 'error';
   data?: unknown;
   platform?: string;
   symbol?: string;
   timestamp: number;
   error?: string;
+  code?: string;
+  mode?: 'partial' | 'diff'; // orderbook frames: partial replaces the book, diff is merged into it
+}
+
+// One asset of a "balance" frame, the same shape for every platform
+export interface Balance {
+  asset: string;
+  free: string;
+  locked: string;
+}
+
+// Data of an "alert" frame, sent when an alert rule triggers or recovers
+export interface Alert {
+  ruleId: string;
+  apiKeyId: string;
+  symbol: string;
+  condition: 'spread_above' | 'price_outside';
+  state: 'triggered' | 'recovered';
+  value: number;
+  threshold?: number;
+  lower?: number;
+  upper?: number;
+  time: string;
 }
 
 export interface ConnectedData {
@@ -270,6 +321,8 @@ class TradingWebSocket {
   private currentApiKeyId: string | null = null;
   private isConnecting: boolean = false;
   private heartbeatTimer: number | null = null;
+  // Set once the server acknowledges our hello; messages queue until then
+  private ready: boolean = false;
 
   connect(token: string): void {
     this.token = token;
@@ -292,25 +345,31 @@ class TradingWebSocket {
 
     const wsUrl = `${WS_BASE_URL}/trading?token=${token}`;
     this.isConnecting = true;
+    this.ready = false;
     this.ws = new WebSocket(wsUrl);
 
     this.ws.onopen = () => {
       this.isConnecting = false;
       this.connectHandlers.forEach(handler => handler());
-      this.startHeartbeat();
-
-      // Send pending messages
-      if (this.pendingMessages.length > 0) {
-        const messages = [...this.pendingMessages];
-        this.pendingMessages = [];
-        messages.forEach(msg => this.send(msg));
-      }
     };
 
     this.ws.onmessage = (event) => {
       try {
         const response: TradingResponse = JSON.parse(event.data);
 
+        // Handshake: answer the server hello, then flush queued messages once ready
+        if (response.type === 'hello') {
+          this.ws?.send(JSON.stringify({ action: 'hello', version: TRADING_PROTOCOL_VERSION }));
+        } else if (response.type === 'ready') {
+          this.ready = true;
+          this.startHeartbeat();
+          if (this.pendingMessages.length > 0) {
+            const messages = [...this.pendingMessages];
+            this.pendingMessages = [];
+            messages.forEach(msg => this.send(msg));
+          }
+        }
+
         if (response.type === 'error' && response.error) {
           this.errorHandlers.forEach(handler => handler(response.error!));
         }
@@ -323,6 +382,7 @@ class TradingWebSocket {
 
     this.ws.onclose = () => {
       this.isConnecting = false;
+      this.ready = false;
       this.ws = null;
       this.currentApiKeyId = null;
       this.stopHeartbeat();
@@ -346,6 +406,7 @@ class TradingWebSocket {
     // Clear token to prevent auto-reconnect
     this.token = null;
     this.isConnecting = false;
+    this.ready = false;
 
     if (this.ws) {
       // Remove onclose handler to prevent triggering reconnect
@@ -373,7 +434,7 @@ class TradingWebSocket {
 
   private send(message: TradingMessage): void {
     const msgStr = JSON.stringify(message);
-    if (this.ws?.readyState === WebSocket.OPEN) {
+    if (this.ws?.readyState === WebSocket.OPEN && this.ready) {
       try {
         this.ws.send(msgStr);
       } catch (e) {
@@ -424,11 +485,12 @@ class TradingWebSocket {
     });
   }
 
-  subscribeOrderBook(symbol: string): void {
+  subscribeOrderBook(symbol: string, mode?: 'partial' | 'diff' | 'book'): void {
     this.send({
       action: 'subscribe',
       type: 'orderbook',
       symbol,
+      mode,
     });
   }
 
@@ -2,6 +2,7 @@ import { type Component, createSignal, Show, For } from 'solid-js';
 import { FiCheck, FiAlertCircle } from 'solid-icons/fi';
 import Layout from '../components/Layout';
 import { api } from '../lib/api';
+import { authStore } from '../stores/auth';
 
 const AccountSettings: Component = () => {
   // Change Password State
@@ -46,6 +47,7 @@ const AccountSettings: Component = () => {
     setPwdIsLoading(true);
     try {
       await api.changePassword(currentPassword(), newPassword());
+      authStore.passwordChanged();
       setPwdSuccess('Password changed successfully');
       setCurrentPassword('');
       setNewPassword('');
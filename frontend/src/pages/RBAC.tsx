@@ -22,6 +22,7 @@ const RBAC: Component = () => {
   const [editingRole, setEditingRole] = createSignal<RoleWithPermissions | null>(null);
   const [roleName, setRoleName] = createSignal('');
   const [roleDescription, setRoleDescription] = createSignal('');
+  const [roleTokenTTLMinutes, setRoleTokenTTLMinutes] = createSignal('');
   const [selectedPermissions, setSelectedPermissions] = createSignal<string[]>([]);
   const [roleError, setRoleError] = createSignal('');
 
@@ -32,6 +33,7 @@ const RBAC: Component = () => {
     setEditingRole(null);
     setRoleName('');
     setRoleDescription('');
+    setRoleTokenTTLMinutes('');
     setSelectedPermissions([]);
     setRoleError('');
     setShowRoleModal(true);
@@ -41,6 +43,7 @@ const RBAC: Component = () => {
     setEditingRole(role);
     setRoleName(role.name);
     setRoleDescription(role.description);
+    setRoleTokenTTLMinutes(role.max_token_ttl ? String(role.max_token_ttl / 60) : '');
     setSelectedPermissions(role.permissions || []);
     setRoleError('');
     setShowRoleModal(true);
@@ -52,13 +55,20 @@ const RBAC: Component = () => {
       return;
     }
 
+    const ttlMinutes = roleTokenTTLMinutes().trim() === '' ? 0 : Number(roleTokenTTLMinutes());
+    if (!Number.isFinite(ttlMinutes) || ttlMinutes < 0) {
+      setRoleError('Token lifetime must be a non-negative number of minutes');
+      return;
+    }
+    const maxTokenTTL = Math.round(ttlMinutes * 60);
+
     try {
       const existing = editingRole();
       if (existing) {
-        await api.updateRole(existing.id, roleName(), roleDescription());
+        await api.updateRole(existing.id, roleName(), roleDescription(), maxTokenTTL);
         await api.setRolePermissions(existing.id, selectedPermissions());
       } else {
-        await api.createRole(roleName(), roleDescription(), selectedPermissions());
+        await api.createRole(roleName(), roleDescription(), selectedPermissions(), maxTokenTTL);
       }
       setShowRoleModal(false);
       refetchRoles();
@@ -68,10 +78,15 @@ const RBAC: Component = () => {
   };
 
   const handleDeleteRole = async (id: string) => {
-    if (!confirm('Are you sure you want to delete this role?')) return;
-
     try {
-      await api.deleteRole(id);
+      const res = await api.getRoleUsers(id);
+      const users = res.data || [];
+      const message = users.length > 0
+        ? `This role is assigned to ${users.length} user(s): ${users.map(u => u.username).join(', ')}. They will lose it. Delete anyway?`
+        : 'Are you sure you want to delete this role?';
+      if (!confirm(message)) return;
+
+      await api.deleteRole(id, users.length > 0);
       refetchRoles();
     } catch (e) {
       alert(e instanceof Error ? e.message : 'Failed to delete role');
@@ -284,6 +299,16 @@ const RBAC: Component = () => {
                     rows={2}
                   />
                 </div>
+                <div class="form-field">
+                  <label>Max token lifetime (minutes)</label>
+                  <input
+                    type="number"
+                    min="0"
+                    value={roleTokenTTLMinutes()}
+                    onInput={(e) => setRoleTokenTTLMinutes(e.currentTarget.value)}
+                    placeholder="Empty uses the global expiry"
+                  />
+                </div>
                 <div class="form-field">
                   <label>Permissions</label>
                   <div class="checkbox-list">
@@ -1,6 +1,6 @@
 import { createEffect, createMemo, createResource, createSignal, For, onCleanup, onMount, Show, untrack, type Component } from 'solid-js';
 import Layout from '../components/Layout';
-import { api } from '../lib/api';
+import { api, type UserPreferences } from '../lib/api';
 import { tradingWs, type Order, type OrderBook, type TradingResponse } from '../lib/websocket';
 
 type Candle = {
@@ -123,6 +123,50 @@ const TradingBotMonitor: Component = () => {
         }
     });
 
+    // Server-side preferences follow the user across machines and take
+    // precedence over localStorage once loaded
+    const PREFS_KEY = 'tradingBotMonitor';
+    let prefs: UserPreferences | null = null;
+    let prefsSaveTimer: ReturnType<typeof setTimeout> | undefined;
+
+    onMount(async () => {
+        try {
+            const response = await api.getPreferences();
+            prefs = response.data ?? null;
+            const saved = prefs?.data[PREFS_KEY] as { symbol?: string; interval?: string } | undefined;
+            if (saved?.symbol) setSelectedSymbol(saved.symbol);
+            if (saved?.interval) setSelectedInterval(saved.interval);
+        } catch {
+            // keep the localStorage values
+        }
+    });
+
+    const savePrefs = async (symbol: string, interval: string, retry = true) => {
+        if (!prefs) return;
+        const data = { ...prefs.data, [PREFS_KEY]: { symbol, interval } };
+        try {
+            const response = await api.savePreferences(data, prefs.version);
+            prefs = response.data ?? prefs;
+        } catch {
+            // Another tab saved first: reload its version and write ours on top once
+            if (!retry) return;
+            try {
+                prefs = (await api.getPreferences()).data ?? prefs;
+                await savePrefs(symbol, interval, false);
+            } catch {
+                // ignore; localStorage still has the selection
+            }
+        }
+    };
+
+    createEffect(() => {
+        const symbol = selectedSymbol();
+        const interval = selectedInterval();
+        clearTimeout(prefsSaveTimer);
+        prefsSaveTimer = setTimeout(() => void savePrefs(symbol, interval), 1000);
+    });
+    onCleanup(() => clearTimeout(prefsSaveTimer));
+
     // Persist trading pair whenever it changes
     createEffect(() => {
         const symbol = selectedSymbol();
@@ -1,5 +1,5 @@
 import { A } from '@solidjs/router';
-import { FiCheck, FiEdit2, FiPlus, FiRefreshCw, FiTrash2, FiX } from 'solid-icons/fi';
+import { FiCheck, FiEdit2, FiKey, FiPlus, FiRefreshCw, FiTrash2, FiX } from 'solid-icons/fi';
 import { For, Show, createResource, createSignal, type Component } from 'solid-js';
 import Layout from '../components/Layout';
 import { api, type TOTPSetup, type User } from '../lib/api';
@@ -152,6 +152,18 @@ const UserAdmin: Component = () => {
     }
   };
 
+  const handleResetPassword = async (user: User) => {
+    if (!confirm(`Issue a temporary password for ${user.username}? Their current password stops working immediately.`)) return;
+    try {
+      const response = await api.resetUserPassword(user.id);
+      // Shown once; the user must change it at their next login
+      prompt('Temporary password (copy it now, it will not be shown again):', response.data?.temporary_password || '');
+      await refetchUsers();
+    } catch (e: any) {
+      alert(e?.message || 'Reset password failed.');
+    }
+  };
+
   return (
     <Layout>
       <div class="user-admin">
@@ -213,6 +225,9 @@ const UserAdmin: Component = () => {
                           <button class="btn-icon" onClick={() => handleResetTOTP(user.id)} title="Reset 2FA">
                             <FiRefreshCw />
                           </button>
+                          <button class="btn-icon" onClick={() => handleResetPassword(user)} title="Reset password">
+                            <FiKey />
+                          </button>
                           <button class="btn-icon danger" onClick={() => handleDelete(user.id)} title="Delete">
                             <FiTrash2 />
                           </button>
@@ -74,7 +74,7 @@ function createAuthStore() {
     }
   };
 
-  const verifyTOTP = async (code: string) => {
+  const verifyTOTP = async (code: string, rememberDevice = false) => {
     setError(null);
     const pending = pendingTOTP();
     if (!pending) {
@@ -82,7 +82,7 @@ function createAuthStore() {
       return false;
     }
     try {
-      const response = await api.verifyTOTP(pending.userId, code);
+      const response = await api.verifyTOTP(pending.userId, code, rememberDevice);
       if (response.user) {
         setUser(response.user);
       }
@@ -123,6 +123,12 @@ function createAuthStore() {
     setError(null);
   };
 
+  // Lifts the forced redirect to account settings after an admin password reset
+  const passwordChanged = () => {
+    const currentUser = user();
+    if (currentUser) setUser({ ...currentUser, must_change_password: false });
+  };
+
   const logout = async () => {
     await api.logout();
     setUser(null);
@@ -143,6 +149,7 @@ function createAuthStore() {
     activateTOTP,
     cancelTOTPSetup,
     logout,
+    passwordChanged,
     setError,
   };
 }